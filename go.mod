@@ -1,3 +1,3 @@
 module orwell
 
-go 1.23.2
+go 1.21.0
@@ -10,8 +10,10 @@ import (
 	"ip-generator/internal/application"
 	"ip-generator/internal/domain"
 	"ip-generator/internal/infrastructure/config"
+	"ip-generator/internal/infrastructure/database"
 	"ip-generator/internal/infrastructure/http"
 	"ip-generator/internal/infrastructure/queue"
+	"ip-generator/internal/infrastructure/source"
 	"ip-generator/pkg/log"
 
 	"go.uber.org/zap"
@@ -32,7 +34,9 @@ func main() {
 	ipGenerator := domain.NewIPGeneratorService()
 
 	// Initialize infrastructure
-	queuePublisher, err := queue.NewRabbitMQPublisher(cfg.RabbitMQ.URL, cfg.RabbitMQ.Queue)
+	confirmTimeout, _ := time.ParseDuration(cfg.RabbitMQ.ConfirmTimeout)
+	connectMaxWait, _ := time.ParseDuration(cfg.RabbitMQ.ConnectMaxWait)
+	queuePublisher, err := queue.NewRabbitMQPublisher(cfg.RabbitMQ.URL, cfg.RabbitMQ.Queue, cfg.RabbitMQ.MaxPriority, confirmTimeout, connectMaxWait)
 	if err != nil {
 		log.L().Fatal("Failed to create RabbitMQ publisher", zap.Error(err))
 	}
@@ -41,6 +45,45 @@ func main() {
 	// Initialize application service
 	appService := application.NewIPGenerationService(ipGenerator, queuePublisher)
 
+	// Register the file and database IP sources
+	appService.SetTargetLists(source.NewFileTargetStore(cfg.Sources.FileDir))
+	if cfg.MongoDB.Enabled {
+		rescanTargets, err := database.NewRescanTargetStore(
+			cfg.MongoDB.ConnectionString,
+			cfg.MongoDB.DatabaseName,
+			cfg.MongoDB.CollectionName,
+		)
+		if err != nil {
+			log.L().Error("Failed to connect to MongoDB", zap.Error(err))
+			log.L().Warn("Continuing without MongoDB - the database source is unavailable")
+		} else {
+			appService.SetRescanTargets(rescanTargets)
+			defer rescanTargets.Close()
+		}
+	}
+
+	// Pause publishing while the scanners fall behind
+	if cfg.Backpressure.Enabled {
+		checkInterval, _ := time.ParseDuration(cfg.Backpressure.CheckInterval)
+		maxWait, _ := time.ParseDuration(cfg.Backpressure.MaxWait)
+		depthReader := queue.NewManagementClient(
+			cfg.Backpressure.ManagementURL,
+			cfg.Backpressure.Username,
+			cfg.Backpressure.Password,
+			cfg.Backpressure.VHost,
+			cfg.RabbitMQ.Queue,
+		)
+		appService.SetBackpressure(application.NewBackpressure(
+			depthReader,
+			cfg.Backpressure.HighWaterMark,
+			cfg.Backpressure.LowWaterMark,
+			checkInterval,
+			maxWait,
+		))
+		log.L().Info("Queue backpressure enabled", zap.Int("high_water_mark", cfg.Backpressure.HighWaterMark),
+			zap.Int("low_water_mark", cfg.Backpressure.LowWaterMark))
+	}
+
 	// Initialize HTTP server
 	server := http.NewServer(cfg.Server.Port, appService)
 
@@ -0,0 +1,94 @@
+package application
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"ip-generator/internal/domain"
+	"ip-generator/pkg/log"
+
+	"go.uber.org/zap"
+)
+
+// Backpressure pauses publishing while the scan queue is above a high-water mark
+// and resumes once it drains below a low-water mark
+type Backpressure struct {
+	reader        domain.QueueDepthReader
+	highWater     int
+	lowWater      int
+	checkInterval time.Duration
+	maxWait       time.Duration
+
+	mu        sync.Mutex
+	depth     int
+	paused    bool
+	lastCheck time.Time
+}
+
+// NewBackpressure creates a backpressure gate over a queue depth reader
+func NewBackpressure(reader domain.QueueDepthReader, highWater, lowWater int, checkInterval, maxWait time.Duration) *Backpressure {
+	if checkInterval <= 0 {
+		checkInterval = time.Second
+	}
+	return &Backpressure{
+		reader:        reader,
+		highWater:     highWater,
+		lowWater:      lowWater,
+		checkInterval: checkInterval,
+		maxWait:       maxWait,
+	}
+}
+
+// Wait blocks while publishing is paused, returning ErrBackpressure if the queue
+// does not drain within the maximum wait
+func (b *Backpressure) Wait() error {
+	deadline := time.Now().Add(b.maxWait)
+	for {
+		depth, paused := b.refresh()
+		if !paused {
+			return nil
+		}
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("%w: %d messages waiting", domain.ErrBackpressure, depth)
+		}
+		time.Sleep(b.checkInterval)
+	}
+}
+
+// Status returns the last observed queue depth and whether publishing is paused
+func (b *Backpressure) Status() (int, bool) {
+	return b.refresh()
+}
+
+// refresh re-reads the queue depth at most once per check interval and applies the water marks.
+// If the depth can't be read the previous state is kept.
+func (b *Backpressure) refresh() (int, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if time.Since(b.lastCheck) < b.checkInterval {
+		return b.depth, b.paused
+	}
+	b.lastCheck = time.Now()
+
+	depth, err := b.reader.QueueDepth()
+	if err != nil {
+		log.L().Warn("Failed to read queue depth", zap.String("event", "queue_depth_failed"), zap.Error(err))
+		return b.depth, b.paused
+	}
+	b.depth = depth
+
+	switch {
+	case !b.paused && depth >= b.highWater:
+		b.paused = true
+		log.L().Warn("Scan queue above high-water mark, pausing generation", zap.String("event", "generation_paused"),
+			zap.Int("depth", depth), zap.Int("high_water_mark", b.highWater))
+	case b.paused && depth <= b.lowWater:
+		b.paused = false
+		log.L().Info("Scan queue below low-water mark, resuming generation", zap.String("event", "generation_resumed"),
+			zap.Int("depth", depth), zap.Int("low_water_mark", b.lowWater))
+	}
+
+	return b.depth, b.paused
+}
@@ -0,0 +1,107 @@
+package application
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ip-generator/internal/domain"
+	"ip-generator/internal/infrastructure/queue"
+	"ip-generator/pkg/log"
+)
+
+func TestMain(m *testing.M) {
+	log.InitLogger("application-test")
+	os.Exit(m.Run())
+}
+
+// managementAPI mocks the RabbitMQ management API, reporting a queue depth the test can change
+type managementAPI struct {
+	*httptest.Server
+	depth int64
+}
+
+func newManagementAPI(t *testing.T, depth int) *managementAPI {
+	api := &managementAPI{depth: int64(depth)}
+	api.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"messages": %d}`, atomic.LoadInt64(&api.depth))
+	}))
+	t.Cleanup(api.Close)
+	return api
+}
+
+func (a *managementAPI) setDepth(depth int) {
+	atomic.StoreInt64(&a.depth, int64(depth))
+}
+
+func (a *managementAPI) reader() domain.QueueDepthReader {
+	return queue.NewManagementClient(a.URL, "guest", "guest", "/", "ip_scan_queue")
+}
+
+func TestBackpressurePausesAboveHighWaterAndResumesBelowLowWater(t *testing.T) {
+	api := newManagementAPI(t, 100)
+	backpressure := NewBackpressure(api.reader(), 1000, 200, time.Millisecond, time.Second)
+
+	steps := []struct {
+		depth  int
+		paused bool
+	}{
+		{100, false},
+		{1000, true}, // Reaching the high-water mark pauses
+		{500, true},  // Between the marks the previous state holds
+		{200, false}, // Reaching the low-water mark resumes
+		{500, false},
+	}
+	for _, step := range steps {
+		api.setDepth(step.depth)
+		time.Sleep(2 * time.Millisecond)
+
+		depth, paused := backpressure.Status()
+		if depth != step.depth || paused != step.paused {
+			t.Errorf("at depth %d: Status() = %d, %v, want %d, %v", step.depth, depth, paused, step.depth, step.paused)
+		}
+	}
+}
+
+func TestBackpressureWaitBlocksUntilQueueDrains(t *testing.T) {
+	api := newManagementAPI(t, 5000)
+	backpressure := NewBackpressure(api.reader(), 1000, 200, time.Millisecond, 5*time.Second)
+	backpressure.Status()
+
+	time.AfterFunc(50*time.Millisecond, func() { api.setDepth(100) })
+	start := time.Now()
+	if err := backpressure.Wait(); err != nil {
+		t.Fatalf("Wait returned error: %v", err)
+	}
+	if waited := time.Since(start); waited < 50*time.Millisecond {
+		t.Errorf("Wait returned after %v, before the queue drained", waited)
+	}
+}
+
+func TestBackpressureWaitGivesUpAfterMaxWait(t *testing.T) {
+	api := newManagementAPI(t, 5000)
+	backpressure := NewBackpressure(api.reader(), 1000, 200, time.Millisecond, 20*time.Millisecond)
+
+	if err := backpressure.Wait(); !errors.Is(err, domain.ErrBackpressure) {
+		t.Errorf("Wait on a full queue returned %v, want ErrBackpressure", err)
+	}
+}
+
+func TestBackpressureKeepsStateWhenDepthCannotBeRead(t *testing.T) {
+	api := newManagementAPI(t, 5000)
+	backpressure := NewBackpressure(api.reader(), 1000, 200, time.Millisecond, time.Second)
+	if _, paused := backpressure.Status(); !paused {
+		t.Fatal("backpressure not paused above the high-water mark")
+	}
+
+	api.Close()
+	time.Sleep(2 * time.Millisecond)
+	if depth, paused := backpressure.Status(); depth != 5000 || !paused {
+		t.Errorf("Status() with the API down = %d, %v, want the last depth and still paused", depth, paused)
+	}
+}
@@ -2,7 +2,6 @@ package application
 
 import (
 	"fmt"
-	"net"
 	"time"
 
 	"ip-generator/internal/domain"
@@ -12,6 +11,9 @@ import (
 type IPGenerationService struct {
 	ipGenerator    domain.IPGenerator
 	queuePublisher domain.QueuePublisher
+	backpressure   *Backpressure
+	targetLists    domain.TargetListStore
+	rescanTargets  domain.RescanTargetStore
 }
 
 // NewIPGenerationService creates a new IP generation service
@@ -22,113 +24,193 @@ func NewIPGenerationService(ipGenerator domain.IPGenerator, queuePublisher domai
 	}
 }
 
-// GenerateAndPublishIPs generates IPs and publishes them to the queue
-func (s *IPGenerationService) GenerateAndPublishIPs(count int, batchSize int) error {
-	if count <= 0 {
-		return fmt.Errorf("count must be greater than 0")
-	}
+// SetBackpressure pauses publishing while the scan queue is too deep
+func (s *IPGenerationService) SetBackpressure(backpressure *Backpressure) {
+	s.backpressure = backpressure
+}
 
-	if batchSize <= 0 {
-		batchSize = 100 // default batch size
-	}
+// SetTargetLists enables the file source, reading target lists from the given store
+func (s *IPGenerationService) SetTargetLists(store domain.TargetListStore) {
+	s.targetLists = store
+}
 
-	// Calculate number of batches
-	numBatches := (count + batchSize - 1) / batchSize
-	batchID := generateBatchID()
+// SetRescanTargets enables the database source, re-queueing hosts from earlier scans
+func (s *IPGenerationService) SetRescanTargets(store domain.RescanTargetStore) {
+	s.rescanTargets = store
+}
 
-	var messages []*domain.QueueMessage
+// SourceSpec describes the source a generation request draws its addresses from
+type SourceSpec struct {
+	Kind    string // One of the domain.Source* kinds
+	Count   int    // Addresses to take; required for random and sequential, 0 takes all for the others
+	StartIP string // First address of a sequential source
+	CIDR    string // Block enumerated by a cidr source
+	File    string // Target list read by a file source
+	Rescan  domain.RescanFilter
+
+	Family   string   // Address family of a random source: domain.FamilyIPv4 (default) or domain.FamilyIPv6
+	Prefixes []string // IPv6 prefixes a random ipv6 source generates in
+}
 
-	for i := 0; i < numBatches; i++ {
-		currentBatchSize := batchSize
-		if i == numBatches-1 && count%batchSize != 0 {
-			currentBatchSize = count % batchSize
+// OpenSource opens the source described by a spec
+func (s *IPGenerationService) OpenSource(spec SourceSpec) (domain.IPSource, error) {
+	switch spec.Kind {
+	case domain.SourceRandom, "":
+		if spec.Count <= 0 {
+			return nil, fmt.Errorf("%w: count must be greater than 0", domain.ErrInvalidSource)
 		}
-
-		// Generate IPs for this batch
-		ips, err := s.ipGenerator.GenerateIPs(currentBatchSize)
+		return s.randomSource(spec.Family, spec.Prefixes, spec.Count)
+	case domain.SourceSequential:
+		if spec.Count <= 0 || spec.StartIP == "" {
+			return nil, fmt.Errorf("%w: start_ip and a count greater than 0 are required", domain.ErrInvalidSource)
+		}
+		return domain.NewSequentialSource(s.ipGenerator, spec.StartIP, spec.Count), nil
+	case domain.SourceCIDR:
+		source, err := domain.NewCIDRSource(spec.CIDR)
 		if err != nil {
-			return fmt.Errorf("failed to generate IPs for batch %d: %w", i, err)
+			return nil, err
 		}
-
-		// Convert domain IPs to strings
-		ipStrings := make([]string, len(ips))
-		for j, ip := range ips {
-			ipStrings[j] = ip.String()
+		return domain.LimitSource(source, spec.Count), nil
+	case domain.SourceFile:
+		if s.targetLists == nil {
+			return nil, fmt.Errorf("%w: %s", domain.ErrSourceUnavailable, spec.Kind)
 		}
+		source, err := s.targetLists.OpenTargetList(spec.File)
+		if err != nil {
+			return nil, err
+		}
+		return domain.LimitSource(source, spec.Count), nil
+	case domain.SourceDatabase:
+		if s.rescanTargets == nil {
+			return nil, fmt.Errorf("%w: %s", domain.ErrSourceUnavailable, spec.Kind)
+		}
+		source, err := s.rescanTargets.OpenRescanTargets(spec.Rescan)
+		if err != nil {
+			return nil, err
+		}
+		return domain.LimitSource(source, spec.Count), nil
+	default:
+		return nil, fmt.Errorf("%w: unknown source %q", domain.ErrInvalidSource, spec.Kind)
+	}
+}
 
-		// Create queue message
-		message := &domain.QueueMessage{
-			IPs:     ipStrings,
-			BatchID: fmt.Sprintf("%s-%d", batchID, i),
-			Count:   len(ipStrings),
+// randomSource opens a source of count random addresses of the given family
+func (s *IPGenerationService) randomSource(family string, prefixes []string, count int) (*domain.RandomSource, error) {
+	switch family {
+	case domain.FamilyIPv4, "":
+		if len(prefixes) > 0 {
+			return nil, fmt.Errorf("%w: prefixes only apply to ipv6 generation", domain.ErrInvalidSource)
 		}
+		return domain.NewRandomSource(s.ipGenerator, count), nil
+	case domain.FamilyIPv6:
+		return domain.NewRandomIPv6Source(s.ipGenerator, prefixes, count)
+	default:
+		return nil, fmt.Errorf("%w: unknown address family %q", domain.ErrInvalidSource, family)
+	}
+}
 
-		messages = append(messages, message)
+// BackpressureStatus returns the scan queue depth and whether publishing is paused;
+// ok is false when backpressure is disabled
+func (s *IPGenerationService) BackpressureStatus() (depth int, paused bool, ok bool) {
+	if s.backpressure == nil {
+		return 0, false, false
 	}
+	depth, paused = s.backpressure.Status()
+	return depth, paused, true
+}
 
-	// Publish all messages to queue
-	if err := s.queuePublisher.PublishBatch(messages); err != nil {
-		return fmt.Errorf("failed to publish messages to queue: %w", err)
+// GenerateAndPublishIPs generates IPv4 addresses and publishes them to the queue
+func (s *IPGenerationService) GenerateAndPublishIPs(count int, batchSize int, options *domain.BatchOptions) error {
+	return s.GenerateAndPublishFamily(domain.FamilyIPv4, nil, count, batchSize, options)
+}
+
+// GenerateAndPublishFamily generates addresses of a family and publishes them to the queue;
+// IPv6 addresses are generated inside prefixes
+func (s *IPGenerationService) GenerateAndPublishFamily(family string, prefixes []string, count int, batchSize int, options *domain.BatchOptions) error {
+	if count <= 0 {
+		return fmt.Errorf("count must be greater than 0")
 	}
 
-	return nil
+	source, err := s.randomSource(family, prefixes, count)
+	if err != nil {
+		return err
+	}
+	_, err = s.GenerateAndPublish(source, batchSize, options)
+	return err
 }
 
 // GenerateAndPublishSequentialIPs generates sequential IPs and publishes them to the queue
-func (s *IPGenerationService) GenerateAndPublishSequentialIPs(startIP string, count int, batchSize int) error {
+func (s *IPGenerationService) GenerateAndPublishSequentialIPs(startIP string, count int, batchSize int, options *domain.BatchOptions) error {
 	if count <= 0 {
 		return fmt.Errorf("count must be greater than 0")
 	}
 
+	_, err := s.GenerateAndPublish(domain.NewSequentialSource(s.ipGenerator, startIP, count), batchSize, options)
+	return err
+}
+
+// GenerateAndPublishCIDR publishes every host of a CIDR block, building and publishing one
+// batch at a time so memory use doesn't grow with the size of the block
+func (s *IPGenerationService) GenerateAndPublishCIDR(cidr string, batchSize int, options *domain.BatchOptions) (int, error) {
+	source, err := domain.NewCIDRSource(cidr)
+	if err != nil {
+		return 0, err
+	}
+	return s.GenerateAndPublish(source, batchSize, options)
+}
+
+// GenerateAndPublish drains a source, publishing each batch as soon as it is read, and
+// returns how many addresses were published. The source is closed when done.
+func (s *IPGenerationService) GenerateAndPublish(source domain.IPSource, batchSize int, options *domain.BatchOptions) (int, error) {
+	defer source.Close()
+
 	if batchSize <= 0 {
 		batchSize = 100 // default batch size
 	}
 
-	// Calculate number of batches
-	numBatches := (count + batchSize - 1) / batchSize
 	batchID := generateBatchID()
+	published := 0
 
-	var messages []*domain.QueueMessage
-	currentStartIP := startIP
-
-	for i := 0; i < numBatches; i++ {
-		currentBatchSize := batchSize
-		if i == numBatches-1 && count%batchSize != 0 {
-			currentBatchSize = count % batchSize
-		}
+	ips, err := source.Next(batchSize)
+	if err != nil {
+		return published, fmt.Errorf("failed to generate IPs for batch 0: %w", err)
+	}
 
-		// Generate sequential IPs for this batch
-		ips, err := s.ipGenerator.GenerateSequentialIPs(currentStartIP, currentBatchSize)
+	// Read one batch ahead so the last message can be marked final
+	for i := 0; len(ips) > 0; i++ {
+		next, err := source.Next(batchSize)
 		if err != nil {
-			return fmt.Errorf("failed to generate sequential IPs for batch %d: %w", i, err)
+			return published, fmt.Errorf("failed to generate IPs for batch %d: %w", i+1, err)
 		}
 
-		// Convert domain IPs to strings
-		ipStrings := make([]string, len(ips))
-		for j, ip := range ips {
-			ipStrings[j] = ip.String()
-		}
-
-		// Create queue message
-		message := &domain.QueueMessage{
-			IPs:     ipStrings,
-			BatchID: fmt.Sprintf("%s-%d", batchID, i),
-			Count:   len(ipStrings),
+		message := domain.NewQueueMessage(ips, fmt.Sprintf("%s-%d", batchID, i), options)
+		message.ParentBatchID = batchID
+		message.Sequence = i
+		message.Final = len(next) == 0
+		if err := s.publishMessages([]*domain.QueueMessage{message}); err != nil {
+			return published, fmt.Errorf("failed to publish messages to queue: %w", err)
 		}
+		published += len(ips)
+		ips = next
+	}
 
-		messages = append(messages, message)
+	return published, nil
+}
 
-		// Update start IP for next batch
-		if len(ips) > 0 {
-			currentStartIP = incrementIP(ips[len(ips)-1].String())
-		}
+// publishMessages publishes messages, waiting for the scan queue to drain between them when backpressure is enabled
+func (s *IPGenerationService) publishMessages(messages []*domain.QueueMessage) error {
+	if s.backpressure == nil {
+		return s.queuePublisher.PublishBatch(messages)
 	}
 
-	// Publish all messages to queue
-	if err := s.queuePublisher.PublishBatch(messages); err != nil {
-		return fmt.Errorf("failed to publish messages to queue: %w", err)
+	for _, message := range messages {
+		if err := s.backpressure.Wait(); err != nil {
+			return err
+		}
+		if err := s.queuePublisher.Publish(message); err != nil {
+			return err
+		}
 	}
-
 	return nil
 }
 
@@ -136,26 +218,3 @@ func (s *IPGenerationService) GenerateAndPublishSequentialIPs(startIP string, co
 func generateBatchID() string {
 	return fmt.Sprintf("batch-%d", time.Now().UnixNano())
 }
-
-// incrementIP increments an IP address by 1
-func incrementIP(ipStr string) string {
-	parsedIP := net.ParseIP(ipStr)
-	if parsedIP == nil {
-		return ipStr
-	}
-
-	ip := parsedIP.To4()
-	if ip == nil {
-		return ipStr
-	}
-
-	// Increment IP address
-	for j := 3; j >= 0; j-- {
-		ip[j]++
-		if ip[j] != 0 {
-			break
-		}
-	}
-
-	return ip.String()
-}
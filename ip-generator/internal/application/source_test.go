@@ -0,0 +1,197 @@
+package application
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"ip-generator/internal/domain"
+	"ip-generator/internal/infrastructure/source"
+)
+
+// recordingPublisher is a queue publisher that keeps every published message
+type recordingPublisher struct {
+	mu       sync.Mutex
+	messages []*domain.QueueMessage
+}
+
+func (p *recordingPublisher) Publish(message *domain.QueueMessage) error {
+	return p.PublishBatch([]*domain.QueueMessage{message})
+}
+
+func (p *recordingPublisher) PublishBatch(messages []*domain.QueueMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = append(p.messages, messages...)
+	return nil
+}
+
+// ips returns the published addresses in publishing order
+func (p *recordingPublisher) ips() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var ips []string
+	for _, message := range p.messages {
+		ips = append(ips, message.IPs...)
+	}
+	return ips
+}
+
+// sliceSource yields a fixed list of addresses
+type sliceSource struct {
+	ips    []string
+	closed bool
+}
+
+func (s *sliceSource) Next(max int) ([]string, error) {
+	n := min(max, len(s.ips))
+	ips := s.ips[:n]
+	s.ips = s.ips[n:]
+	return ips, nil
+}
+
+func (s *sliceSource) Close() error {
+	s.closed = true
+	return nil
+}
+
+// rescanStore returns the hosts of earlier scans and records the filter it was opened with
+type rescanStore struct {
+	filter domain.RescanFilter
+	source *sliceSource
+}
+
+func (s *rescanStore) OpenRescanTargets(filter domain.RescanFilter) (domain.IPSource, error) {
+	s.filter = filter
+	return s.source, nil
+}
+
+func newSourceService() (*IPGenerationService, *recordingPublisher) {
+	publisher := &recordingPublisher{}
+	return NewIPGenerationService(domain.NewIPGeneratorService(), publisher), publisher
+}
+
+// publishSpec opens a source and publishes it in batches of batchSize
+func publishSpec(t *testing.T, service *IPGenerationService, spec SourceSpec, batchSize int) int {
+	t.Helper()
+	source, err := service.OpenSource(spec)
+	if err != nil {
+		t.Fatalf("OpenSource(%+v) returned error: %v", spec, err)
+	}
+	published, err := service.GenerateAndPublish(source, batchSize, nil)
+	if err != nil {
+		t.Fatalf("GenerateAndPublish returned error: %v", err)
+	}
+	return published
+}
+
+func TestRandomSourcePublishesPublicAddresses(t *testing.T) {
+	service, publisher := newSourceService()
+	if published := publishSpec(t, service, SourceSpec{Kind: domain.SourceRandom, Count: 25}, 10); published != 25 {
+		t.Fatalf("published %d addresses, want 25", published)
+	}
+
+	ips := publisher.ips()
+	if len(ips) != 25 || len(publisher.messages) != 3 {
+		t.Fatalf("published %d addresses in %d messages, want 25 in 3", len(ips), len(publisher.messages))
+	}
+	for _, ip := range ips {
+		parsed := net.ParseIP(ip)
+		if parsed == nil || parsed.To4() == nil || parsed.IsPrivate() || parsed.IsLoopback() {
+			t.Errorf("random source published %q, want a public IPv4 address", ip)
+		}
+	}
+	if !publisher.messages[2].Final || publisher.messages[0].Final {
+		t.Error("only the last message should be marked final")
+	}
+}
+
+func TestSequentialSourcePublishesConsecutiveAddresses(t *testing.T) {
+	service, publisher := newSourceService()
+	publishSpec(t, service, SourceSpec{Kind: domain.SourceSequential, StartIP: "8.8.8.250", Count: 8}, 3)
+
+	want := []string{"8.8.8.250", "8.8.8.251", "8.8.8.252", "8.8.8.253", "8.8.8.254", "8.8.8.255", "8.8.9.0", "8.8.9.1"}
+	if got := publisher.ips(); !reflect.DeepEqual(got, want) {
+		t.Errorf("published %v, want %v", got, want)
+	}
+}
+
+func TestCIDRSourcePublishesBlockHosts(t *testing.T) {
+	service, publisher := newSourceService()
+	publishSpec(t, service, SourceSpec{Kind: domain.SourceCIDR, CIDR: "8.8.8.0/29"}, 4)
+
+	want := []string{"8.8.8.1", "8.8.8.2", "8.8.8.3", "8.8.8.4", "8.8.8.5", "8.8.8.6"}
+	if got := publisher.ips(); !reflect.DeepEqual(got, want) {
+		t.Errorf("published %v, want %v", got, want)
+	}
+
+	// A count takes only the first addresses of the block
+	service, publisher = newSourceService()
+	publishSpec(t, service, SourceSpec{Kind: domain.SourceCIDR, CIDR: "8.8.8.0/29", Count: 2}, 4)
+	if got := publisher.ips(); !reflect.DeepEqual(got, want[:2]) {
+		t.Errorf("published %v with count 2, want %v", got, want[:2])
+	}
+}
+
+func TestFileSourcePublishesTargetList(t *testing.T) {
+	dir := t.TempDir()
+	list := "# audit targets\n8.8.8.8\n\nnot-an-ip\n1.1.1.1\n9.9.9.9\n"
+	if err := os.WriteFile(filepath.Join(dir, "targets.txt"), []byte(list), 0o644); err != nil {
+		t.Fatalf("failed to write target list: %v", err)
+	}
+
+	service, publisher := newSourceService()
+	service.SetTargetLists(source.NewFileTargetStore(dir))
+	publishSpec(t, service, SourceSpec{Kind: domain.SourceFile, File: "targets.txt"}, 2)
+
+	if got, want := publisher.ips(), []string{"8.8.8.8", "1.1.1.1", "9.9.9.9"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("published %v, want %v", got, want)
+	}
+
+	if _, err := service.OpenSource(SourceSpec{Kind: domain.SourceFile, File: "../targets.txt"}); !errors.Is(err, domain.ErrInvalidSource) {
+		t.Errorf("OpenSource outside the list directory returned %v, want ErrInvalidSource", err)
+	}
+}
+
+func TestDatabaseSourcePublishesRescanTargets(t *testing.T) {
+	store := &rescanStore{source: &sliceSource{ips: []string{"8.8.8.8", "1.1.1.1", "9.9.9.9"}}}
+	service, publisher := newSourceService()
+	service.SetRescanTargets(store)
+
+	filter := domain.RescanFilter{BatchID: "batch-1", WithOpenPorts: true}
+	publishSpec(t, service, SourceSpec{Kind: domain.SourceDatabase, Rescan: filter}, 10)
+
+	if store.filter != filter {
+		t.Errorf("rescan targets opened with %+v, want %+v", store.filter, filter)
+	}
+	if got, want := publisher.ips(), []string{"8.8.8.8", "1.1.1.1", "9.9.9.9"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("published %v, want %v", got, want)
+	}
+	if !store.source.closed {
+		t.Error("source not closed after publishing")
+	}
+}
+
+func TestOpenSourceRejectsUnavailableAndInvalidSources(t *testing.T) {
+	service, _ := newSourceService()
+	tests := []struct {
+		spec SourceSpec
+		want error
+	}{
+		{SourceSpec{Kind: domain.SourceFile, File: "targets.txt"}, domain.ErrSourceUnavailable},
+		{SourceSpec{Kind: domain.SourceDatabase}, domain.ErrSourceUnavailable},
+		{SourceSpec{Kind: domain.SourceRandom}, domain.ErrInvalidSource},
+		{SourceSpec{Kind: domain.SourceSequential, Count: 5}, domain.ErrInvalidSource},
+		{SourceSpec{Kind: domain.SourceCIDR, CIDR: "not-a-cidr"}, domain.ErrInvalidSource},
+		{SourceSpec{Kind: "carrier-pigeon", Count: 5}, domain.ErrInvalidSource},
+	}
+	for _, tt := range tests {
+		if _, err := service.OpenSource(tt.spec); !errors.Is(err, tt.want) {
+			t.Errorf("OpenSource(%+v) returned %v, want %v", tt.spec, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// CIDRIterator enumerates the host addresses of an IPv4 CIDR block lazily, so a
+// block of any size is walked in constant memory. The network and broadcast
+// addresses are skipped for blocks larger than /31.
+type CIDRIterator struct {
+	next uint64
+	last uint64
+}
+
+// NewCIDRIterator creates an iterator over the hosts of an IPv4 CIDR block
+func NewCIDRIterator(cidr string) (*CIDRIterator, error) {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+	}
+	base := network.IP.To4()
+	if base == nil {
+		return nil, fmt.Errorf("invalid CIDR %q: only IPv4 is supported", cidr)
+	}
+
+	ones, bits := network.Mask.Size()
+	first := uint64(binary.BigEndian.Uint32(base))
+	last := first + (uint64(1) << uint(bits-ones)) - 1
+	if bits-ones > 1 {
+		first++
+		last--
+	}
+
+	return &CIDRIterator{next: first, last: last}, nil
+}
+
+// Remaining returns how many addresses the iterator has yet to yield
+func (it *CIDRIterator) Remaining() uint64 {
+	if it.next > it.last {
+		return 0
+	}
+	return it.last - it.next + 1
+}
+
+// Next returns the next host address; ok is false once the block is exhausted
+func (it *CIDRIterator) Next() (ip string, ok bool) {
+	if it.next > it.last {
+		return "", false
+	}
+
+	addr := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(addr, uint32(it.next))
+	it.next++
+	return addr.String(), true
+}
+
+// NextBatch returns up to size host addresses, or nil once the block is exhausted
+func (it *CIDRIterator) NextBatch(size int) []string {
+	if size <= 0 || it.Remaining() == 0 {
+		return nil
+	}
+
+	if remaining := it.Remaining(); uint64(size) > remaining {
+		size = int(remaining)
+	}
+
+	batch := make([]string, 0, size)
+	for len(batch) < size {
+		ip, ok := it.Next()
+		if !ok {
+			break
+		}
+		batch = append(batch, ip)
+	}
+	return batch
+}
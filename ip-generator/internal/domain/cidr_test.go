@@ -0,0 +1,99 @@
+package domain
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCIDRIteratorWalksSlash16(t *testing.T) {
+	it, err := NewCIDRIterator("10.1.0.0/16")
+	if err != nil {
+		t.Fatalf("NewCIDRIterator returned error: %v", err)
+	}
+	if got := it.Remaining(); got != 65534 {
+		t.Fatalf("Remaining() = %d, want 65534", got)
+	}
+
+	// Only the current address is kept, never the whole block
+	var first, last string
+	count := 0
+	for ip, ok := it.Next(); ok; ip, ok = it.Next() {
+		if count == 0 {
+			first = ip
+		}
+		last = ip
+		count++
+	}
+	if first != "10.1.0.1" || last != "10.1.255.254" || count != 65534 {
+		t.Errorf("walked %s..%s (%d hosts), want 10.1.0.1..10.1.255.254 (65534 hosts)", first, last, count)
+	}
+	if _, ok := it.Next(); ok || it.Remaining() != 0 {
+		t.Error("exhausted iterator still yields addresses")
+	}
+}
+
+func TestCIDRIteratorBatchesStayBounded(t *testing.T) {
+	it, err := NewCIDRIterator("10.1.0.0/16")
+	if err != nil {
+		t.Fatalf("NewCIDRIterator returned error: %v", err)
+	}
+
+	batches, count := 0, 0
+	var last string
+	for batch := it.NextBatch(1000); batch != nil; batch = it.NextBatch(1000) {
+		if len(batch) > 1000 || cap(batch) > 1000 {
+			t.Fatalf("batch of %d (cap %d) exceeds the requested 1000", len(batch), cap(batch))
+		}
+		batches++
+		count += len(batch)
+		last = batch[len(batch)-1]
+	}
+	if batches != 66 || count != 65534 || last != "10.1.255.254" {
+		t.Errorf("got %d batches, %d hosts ending at %s, want 66 batches, 65534 hosts ending at 10.1.255.254", batches, count, last)
+	}
+}
+
+func TestCIDRIteratorNextAllocatesPerAddressOnly(t *testing.T) {
+	it, err := NewCIDRIterator("10.0.0.0/8")
+	if err != nil {
+		t.Fatalf("NewCIDRIterator returned error: %v", err)
+	}
+
+	// Memory per address is constant, however large the block is
+	if allocs := testing.AllocsPerRun(1000, func() { it.Next() }); allocs > 2 {
+		t.Errorf("Next allocates %.0f times per address, want at most 2", allocs)
+	}
+	if got := it.Remaining(); got != 16777214-1001 {
+		t.Errorf("Remaining() = %d, want %d", got, 16777214-1001)
+	}
+}
+
+func TestCIDRIteratorSmallBlocks(t *testing.T) {
+	tests := map[string][]string{
+		"192.0.2.7/32":  {"192.0.2.7"},
+		"192.0.2.6/31":  {"192.0.2.6", "192.0.2.7"},
+		"192.0.2.4/30":  {"192.0.2.5", "192.0.2.6"},
+		"192.0.2.13/30": {"192.0.2.13", "192.0.2.14"},
+	}
+	for cidr, want := range tests {
+		it, err := NewCIDRIterator(cidr)
+		if err != nil {
+			t.Fatalf("NewCIDRIterator(%s) returned error: %v", cidr, err)
+		}
+		if got := it.NextBatch(10); !reflect.DeepEqual(got, want) {
+			t.Errorf("hosts of %s = %v, want %v", cidr, got, want)
+		}
+	}
+}
+
+func TestNewCIDRIteratorRejectsInvalidBlocks(t *testing.T) {
+	for _, cidr := range []string{"", "10.0.0.0", "10.0.0.0/33", "2001:db8::/64"} {
+		if _, err := NewCIDRIterator(cidr); err == nil {
+			t.Errorf("NewCIDRIterator(%q) returned no error", cidr)
+		}
+	}
+	if _, err := NewCIDRSource("not-a-cidr"); !errors.Is(err, ErrInvalidSource) {
+		t.Errorf("NewCIDRSource error = %v, want ErrInvalidSource", err)
+	}
+}
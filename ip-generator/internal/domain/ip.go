@@ -1,13 +1,17 @@
 package domain
 
 import (
+	"encoding/binary"
 	"fmt"
+	"math"
 	"math/rand"
 	"net"
 	"time"
+
+	"orwell/pkg/netutil"
 )
 
-// IPAddress represents an IPv4 address
+// IPAddress represents an IPv4 or IPv6 address
 type IPAddress struct {
 	Address string
 }
@@ -27,8 +31,8 @@ func (ip *IPAddress) String() string {
 
 // isValidIPv4 checks if the given string is a valid IPv4 address
 func isValidIPv4(address string) bool {
-	parsedIP := net.ParseIP(address)
-	return parsedIP != nil && parsedIP.To4() != nil
+	_, err := netutil.ParseAndValidate(address)
+	return err == nil
 }
 
 // IPGenerator defines the interface for generating IP addresses
@@ -36,6 +40,7 @@ type IPGenerator interface {
 	GenerateIPs(count int) ([]*IPAddress, error)
 	GenerateRandomIPs(count int) ([]*IPAddress, error)
 	GenerateSequentialIPs(startIP string, count int) ([]*IPAddress, error)
+	GenerateRandomIPv6(prefixes []string, count int) ([]*IPAddress, error)
 }
 
 // IPGeneratorService implements the IP generation logic with permutation-based randomization
@@ -75,47 +80,7 @@ func (p *IPPermutation) permute32(x uint32) uint32 {
 // isValidPublicIP checks if an IP address is valid for public use
 // Excludes private ranges and special purpose addresses
 func isValidPublicIP(ip net.IP) bool {
-	if ip == nil || ip.To4() == nil {
-		return false
-	}
-
-	ip4 := ip.To4()
-
-	// Convert to uint32 for easier range checking
-	ipUint := uint32(ip4[0])<<24 + uint32(ip4[1])<<16 + uint32(ip4[2])<<8 + uint32(ip4[3])
-
-	// Exclude ranges:
-	// 0.0.0.0/8 (0.0.0.0 - 0.255.255.255)
-	if ipUint <= 0x00FFFFFF {
-		return false
-	}
-
-	// 10.0.0.0/8 (10.0.0.0 - 10.255.255.255)
-	if ipUint >= 0x0A000000 && ipUint <= 0x0AFFFFFF {
-		return false
-	}
-
-	// 127.0.0.0/8 (127.0.0.0 - 127.255.255.255)
-	if ipUint >= 0x7F000000 && ipUint <= 0x7FFFFFFF {
-		return false
-	}
-
-	// 192.168.0.0/16 (192.168.0.0 - 192.168.255.255)
-	if ipUint >= 0xC0A80000 && ipUint <= 0xC0A8FFFF {
-		return false
-	}
-
-	// 224.0.0.0/4 (224.0.0.0 - 239.255.255.255) - Multicast
-	if ipUint >= 0xE0000000 && ipUint <= 0xEFFFFFFF {
-		return false
-	}
-
-	// 240.0.0.0/4 (240.0.0.0 - 255.255.255.255) - Reserved
-	if ipUint >= 0xF0000000 {
-		return false
-	}
-
-	return true
+	return netutil.IsPublicIP(ip)
 }
 
 // NewIPGeneratorService creates a new IP generator service
@@ -188,42 +153,39 @@ func (s *IPGeneratorService) GenerateRandomIPs(count int) ([]*IPAddress, error)
 	return ips, nil
 }
 
-// GenerateSequentialIPs generates sequential IPv4 addresses starting from the given IP
+// GenerateSequentialIPs generates count consecutive public IPv4 addresses in order, starting
+// from the given IP if it is public. Reserved blocks are skipped as a whole. It fails if the
+// address space ends before count addresses were found, rather than wrapping around to 0.0.0.0.
 func (s *IPGeneratorService) GenerateSequentialIPs(startIP string, count int) ([]*IPAddress, error) {
-	parsedIP := net.ParseIP(startIP)
-	if parsedIP == nil || parsedIP.To4() == nil {
+	ip, err := netutil.ParseAndValidate(startIP)
+	if err != nil {
 		return nil, fmt.Errorf("invalid starting IP address: %s", startIP)
 	}
 
-	var ips []*IPAddress
-	ip := parsedIP.To4()
+	ips := make([]*IPAddress, 0, count)
+	next := uint64(binary.BigEndian.Uint32(ip))
+	for len(ips) < count {
+		if next > math.MaxUint32 {
+			return nil, fmt.Errorf("address space exhausted after %d of %d addresses from %s", len(ips), count, startIP)
+		}
 
-	for i := 0; i < count; i++ {
-		// Skip invalid ranges
-		for !isValidPublicIP(ip) {
-			// Increment IP address
-			for j := 3; j >= 0; j-- {
-				ip[j]++
-				if ip[j] != 0 {
-					break
-				}
-			}
+		addr := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(addr, uint32(next))
+
+		// Continue right after the end of the reserved block holding the address
+		if block := netutil.ReservedBlock(addr); block != nil {
+			ones, bits := block.Mask.Size()
+			blockStart := uint64(binary.BigEndian.Uint32(block.IP.To4()))
+			next = blockStart + uint64(1)<<uint(bits-ones)
+			continue
 		}
 
-		ipAddress, err := NewIPAddress(ip.String())
+		ipAddress, err := NewIPAddress(addr.String())
 		if err != nil {
 			return nil, err
 		}
-
 		ips = append(ips, ipAddress)
-
-		// Increment IP address for next iteration
-		for j := 3; j >= 0; j-- {
-			ip[j]++
-			if ip[j] != 0 {
-				break
-			}
-		}
+		next++
 	}
 
 	return ips, nil
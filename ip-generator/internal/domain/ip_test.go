@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+)
+
+// addresses returns the string form of generated addresses
+func addresses(ips []*IPAddress) []string {
+	out := make([]string, len(ips))
+	for i, ip := range ips {
+		out[i] = ip.Address
+	}
+	return out
+}
+
+func TestGenerateSequentialIPsSkipsReservedBlocks(t *testing.T) {
+	tests := []struct {
+		start string
+		count int
+		want  []string
+	}{
+		{"9.255.255.254", 4, []string{"9.255.255.254", "9.255.255.255", "11.0.0.0", "11.0.0.1"}},
+		{"100.63.255.255", 2, []string{"100.63.255.255", "100.128.0.0"}},
+		{"126.255.255.255", 3, []string{"126.255.255.255", "128.0.0.0", "128.0.0.1"}},
+		{"172.15.255.255", 2, []string{"172.15.255.255", "172.32.0.0"}},
+		{"8.8.8.8", 3, []string{"8.8.8.8", "8.8.8.9", "8.8.8.10"}},
+	}
+	generator := NewIPGeneratorService()
+	for _, tt := range tests {
+		ips, err := generator.GenerateSequentialIPs(tt.start, tt.count)
+		if err != nil {
+			t.Errorf("GenerateSequentialIPs(%s, %d) returned error: %v", tt.start, tt.count, err)
+			continue
+		}
+		if got := addresses(ips); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GenerateSequentialIPs(%s, %d) = %v, want %v", tt.start, tt.count, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateSequentialIPsFailsAtEndOfAddressSpace(t *testing.T) {
+	// Everything from 224.0.0.0 up is multicast or reserved
+	if ips, err := NewIPGeneratorService().GenerateSequentialIPs("223.255.255.254", 3); err == nil {
+		t.Errorf("GenerateSequentialIPs past the end of the address space = %v, want an error", addresses(ips))
+	}
+}
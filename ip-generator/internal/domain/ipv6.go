@@ -0,0 +1,116 @@
+package domain
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"net"
+
+	"orwell/pkg/netutil"
+)
+
+// Address families selectable by generation requests
+const (
+	FamilyIPv4 = "ipv4"
+	FamilyIPv6 = "ipv6"
+)
+
+// ParseIPv6Prefixes parses the prefixes IPv6 addresses are generated in. The IPv6 space is too
+// sparse to sample blindly, so at least one prefix is required, and each must overlap the
+// public global unicast space.
+func ParseIPv6Prefixes(prefixes []string) ([]*net.IPNet, error) {
+	if len(prefixes) == 0 {
+		return nil, fmt.Errorf("%w: ipv6 generation requires at least one prefix", ErrInvalidSource)
+	}
+
+	networks := make([]*net.IPNet, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		_, network, err := net.ParseCIDR(prefix)
+		if err != nil || network.IP.To4() != nil {
+			return nil, fmt.Errorf("%w: invalid IPv6 prefix %q", ErrInvalidSource, prefix)
+		}
+		if !hasPublicIPv6(network) {
+			return nil, fmt.Errorf("%w: IPv6 prefix %q has no public addresses", ErrInvalidSource, prefix)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// hasPublicIPv6 reports whether a prefix contains public addresses, by checking its first
+// address and, for prefixes wider than a special-use block, a sample of its range
+func hasPublicIPv6(network *net.IPNet) bool {
+	if netutil.IsPublicIPv6(network.IP) {
+		return true
+	}
+	for i := 0; i < 16; i++ {
+		ip, err := randomIPv6In(network)
+		if err == nil && netutil.IsPublicIPv6(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// randomIPv6In returns a uniformly random address inside a prefix
+func randomIPv6In(network *net.IPNet) (net.IP, error) {
+	ones, bits := network.Mask.Size()
+	hostBits := bits - ones
+
+	ip := make(net.IP, net.IPv6len)
+	copy(ip, network.IP.To16())
+	if hostBits == 0 {
+		return ip, nil
+	}
+
+	host, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), uint(hostBits)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read random bytes: %w", err)
+	}
+	hostBytes := host.FillBytes(make([]byte, net.IPv6len))
+	for i := range ip {
+		ip[i] |= hostBytes[i]
+	}
+	return ip, nil
+}
+
+// GenerateRandomIPv6 generates random public IPv6 addresses inside the given prefixes, picking
+// a prefix at random for each address
+func (s *IPGeneratorService) GenerateRandomIPv6(prefixes []string, count int) ([]*IPAddress, error) {
+	networks, err := ParseIPv6Prefixes(prefixes)
+	if err != nil {
+		return nil, err
+	}
+
+	var ips []*IPAddress
+	generated := make(map[string]bool)
+
+	attempts := 0
+	maxAttempts := count * 100 // Prevent infinite loops on small or mostly reserved prefixes
+
+	for len(ips) < count && attempts < maxAttempts {
+		attempts++
+
+		ip, err := randomIPv6In(networks[s.rand.Intn(len(networks))])
+		if err != nil {
+			return nil, err
+		}
+		if !netutil.IsPublicIPv6(ip) {
+			continue
+		}
+
+		address := ip.String()
+		if generated[address] {
+			continue
+		}
+		generated[address] = true
+
+		ips = append(ips, &IPAddress{Address: address})
+	}
+
+	if len(ips) < count {
+		return nil, fmt.Errorf("could only generate %d valid IPs out of %d requested", len(ips), count)
+	}
+
+	return ips, nil
+}
@@ -0,0 +1,121 @@
+package domain
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"orwell/pkg/netutil"
+)
+
+func TestGenerateRandomIPv6StaysInsidePrefixes(t *testing.T) {
+	prefixes := []string{"2a00:1450::/32", "2606:4700:4700::/48"}
+	networks, err := ParseIPv6Prefixes(prefixes)
+	if err != nil {
+		t.Fatalf("ParseIPv6Prefixes returned error: %v", err)
+	}
+
+	ips, err := NewIPGeneratorService().GenerateRandomIPv6(prefixes, 500)
+	if err != nil {
+		t.Fatalf("GenerateRandomIPv6 returned error: %v", err)
+	}
+	if len(ips) != 500 {
+		t.Fatalf("generated %d addresses, want 500", len(ips))
+	}
+
+	seen := make(map[string]bool)
+	hits := make([]int, len(networks))
+	for _, address := range ips {
+		ip := net.ParseIP(address.Address)
+		if ip == nil || ip.To4() != nil {
+			t.Fatalf("%q is not a valid IPv6 address", address.Address)
+		}
+		if !ip.IsGlobalUnicast() || !netutil.IsPublicIPv6(ip) {
+			t.Errorf("%s is not a public global unicast address", ip)
+		}
+		if seen[address.Address] {
+			t.Errorf("%s generated twice", ip)
+		}
+		seen[address.Address] = true
+
+		inside := false
+		for i, network := range networks {
+			if network.Contains(ip) {
+				hits[i]++
+				inside = true
+			}
+		}
+		if !inside {
+			t.Errorf("%s is outside the requested prefixes %v", ip, prefixes)
+		}
+	}
+	for i, count := range hits {
+		if count == 0 {
+			t.Errorf("no address generated in %s", prefixes[i])
+		}
+	}
+}
+
+func TestGenerateRandomIPv6SkipsSpecialUseAddresses(t *testing.T) {
+	// 2001::/16 overlaps the special-use 2001::/23 and 2001:db8::/32 blocks
+	ips, err := NewIPGeneratorService().GenerateRandomIPv6([]string{"2001::/16"}, 200)
+	if err != nil {
+		t.Fatalf("GenerateRandomIPv6 returned error: %v", err)
+	}
+	for _, address := range ips {
+		if !netutil.IsPublicIPv6(net.ParseIP(address.Address)) {
+			t.Errorf("generated special-use address %s", address.Address)
+		}
+	}
+}
+
+func TestGenerateRandomIPv6FailsOnExhaustedPrefix(t *testing.T) {
+	if _, err := NewIPGeneratorService().GenerateRandomIPv6([]string{"2a00:1450:4001:81c::200e/128"}, 2); err == nil {
+		t.Error("generated 2 distinct addresses from a /128")
+	}
+}
+
+func TestParseIPv6PrefixesRejectsInvalidPrefixes(t *testing.T) {
+	invalid := [][]string{
+		nil,
+		{"not-a-prefix"},
+		{"2a00:1450::"},
+		{"203.0.113.0/24"},
+		{"fe80::/10"},
+		{"fc00::/7"},
+		{"2001:db8::/32"},
+		{"2a00:1450::/32", "::1/128"},
+	}
+	for _, prefixes := range invalid {
+		if _, err := ParseIPv6Prefixes(prefixes); !errors.Is(err, ErrInvalidSource) {
+			t.Errorf("ParseIPv6Prefixes(%v) = %v, want ErrInvalidSource", prefixes, err)
+		}
+	}
+}
+
+func TestRandomIPv6SourceYieldsRequestedCount(t *testing.T) {
+	source, err := NewRandomIPv6Source(NewIPGeneratorService(), []string{"2a00:1450::/32"}, 25)
+	if err != nil {
+		t.Fatalf("NewRandomIPv6Source returned error: %v", err)
+	}
+
+	total := 0
+	for batch, err := source.Next(10); len(batch) > 0; batch, err = source.Next(10) {
+		if err != nil {
+			t.Fatalf("Next returned error: %v", err)
+		}
+		for _, address := range batch {
+			if ip := net.ParseIP(address); ip == nil || ip.To4() != nil {
+				t.Errorf("source yielded %q, want an IPv6 address", address)
+			}
+		}
+		total += len(batch)
+	}
+	if total != 25 {
+		t.Errorf("source yielded %d addresses, want 25", total)
+	}
+
+	if _, err := NewRandomIPv6Source(NewIPGeneratorService(), nil, 25); !errors.Is(err, ErrInvalidSource) {
+		t.Errorf("NewRandomIPv6Source without prefixes = %v, want ErrInvalidSource", err)
+	}
+}
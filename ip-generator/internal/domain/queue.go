@@ -1,10 +1,90 @@
 package domain
 
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrBackpressure is returned when publishing is paused because the scan queue is too deep
+var ErrBackpressure = errors.New("scan queue is above its high-water mark")
+
 // QueueMessage represents a message to be sent to the queue
 type QueueMessage struct {
-	IPs     []string `json:"ips"`
-	BatchID string   `json:"batch_id"`
-	Count   int      `json:"count"`
+	IPs      []string          `json:"ips"`
+	BatchID  string            `json:"batch_id"`
+	Count    int               `json:"count"`
+	Tags     map[string]string `json:"tags,omitempty"`
+	Priority int               `json:"priority,omitempty"`
+
+	Retention string `json:"retention,omitempty"` // How long the scanner keeps results (e.g. "7d")
+
+	Ports []int `json:"ports,omitempty"` // Ports the scanner probes instead of its defaults
+
+	// The messages of a generation request share a parent batch ID and are numbered from 0,
+	// the last one marked final, so the scanner can tell when the whole batch was scanned
+	ParentBatchID string `json:"parent_batch_id,omitempty"`
+	Sequence      int    `json:"sequence,omitempty"`
+	Final         bool   `json:"final,omitempty"`
+}
+
+// BatchOptions carries optional context attached to every message of a generation request
+type BatchOptions struct {
+	Tags      map[string]string
+	Priority  int
+	Retention string // Empty keeps results indefinitely
+	Ports     []int  // Empty scans the scanner's default ports
+}
+
+// Validate checks that the options can be honoured by the scanner
+func (o *BatchOptions) Validate() error {
+	if o == nil {
+		return nil
+	}
+	for _, port := range o.Ports {
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("invalid port %d: expected 1-65535", port)
+		}
+	}
+	if o.Retention == "" {
+		return nil
+	}
+	retention, err := parseRetention(o.Retention)
+	if err != nil || retention <= 0 {
+		return fmt.Errorf("invalid retention %q: expected a positive duration such as \"12h\" or \"7d\"", o.Retention)
+	}
+	return nil
+}
+
+// parseRetention parses a duration that additionally accepts a day suffix (e.g. "30d"),
+// matching what the port scanner accepts
+func parseRetention(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// NewQueueMessage creates a queue message for a batch of IPs with optional context
+func NewQueueMessage(ips []string, batchID string, options *BatchOptions) *QueueMessage {
+	message := &QueueMessage{
+		IPs:     ips,
+		BatchID: batchID,
+		Count:   len(ips),
+	}
+	if options != nil {
+		message.Tags = options.Tags
+		message.Priority = options.Priority
+		message.Retention = options.Retention
+		message.Ports = options.Ports
+	}
+	return message
 }
 
 // QueuePublisher defines the interface for publishing messages to a queue
@@ -18,3 +98,8 @@ type QueueSubscriber interface {
 	Subscribe(handler func(*QueueMessage) error) error
 	Unsubscribe() error
 }
+
+// QueueDepthReader reports how many messages are waiting in the scan queue
+type QueueDepthReader interface {
+	QueueDepth() (int, error)
+}
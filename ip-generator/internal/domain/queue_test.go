@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestNewQueueMessageCarriesBatchOptions(t *testing.T) {
+	options := &BatchOptions{
+		Tags:      map[string]string{"campaign": "q3-audit", "source": "cidr"},
+		Priority:  7,
+		Retention: "7d",
+		Ports:     []int{22, 443},
+	}
+	message := NewQueueMessage([]string{"8.8.8.8", "1.1.1.1"}, "batch-1", options)
+
+	if message.Count != 2 || message.BatchID != "batch-1" {
+		t.Errorf("count/batch = %d/%s, want 2/batch-1", message.Count, message.BatchID)
+	}
+	if !reflect.DeepEqual(message.Tags, options.Tags) || message.Priority != 7 {
+		t.Errorf("tags/priority = %v/%d, want %v/7", message.Tags, message.Priority, options.Tags)
+	}
+	if message.Retention != "7d" || !reflect.DeepEqual(message.Ports, []int{22, 443}) {
+		t.Errorf("retention/ports = %s/%v, want 7d/[22 443]", message.Retention, message.Ports)
+	}
+
+	// The port scanner reads these JSON keys
+	body, err := json.Marshal(message)
+	if err != nil {
+		t.Fatalf("failed to marshal message: %v", err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		t.Fatalf("failed to decode message: %v", err)
+	}
+	tags, _ := fields["tags"].(map[string]interface{})
+	if tags["campaign"] != "q3-audit" || fields["priority"] != float64(7) {
+		t.Errorf("encoded tags/priority = %v/%v", fields["tags"], fields["priority"])
+	}
+}
+
+func TestNewQueueMessageWithoutOptions(t *testing.T) {
+	message := NewQueueMessage([]string{"8.8.8.8"}, "batch-1", nil)
+	body, err := json.Marshal(message)
+	if err != nil {
+		t.Fatalf("failed to marshal message: %v", err)
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		t.Fatalf("failed to decode message: %v", err)
+	}
+	for _, key := range []string{"tags", "priority", "retention", "ports"} {
+		if _, ok := fields[key]; ok {
+			t.Errorf("message without options encodes %q", key)
+		}
+	}
+}
+
+func TestBatchOptionsValidatePorts(t *testing.T) {
+	if err := (&BatchOptions{Ports: []int{1, 443, 65535}}).Validate(); err != nil {
+		t.Errorf("Validate returned error for valid ports: %v", err)
+	}
+	for _, port := range []int{0, -1, 65536} {
+		if err := (&BatchOptions{Ports: []int{22, port}}).Validate(); err == nil {
+			t.Errorf("Validate accepted port %d", port)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Source kinds selectable by generation requests
+const (
+	SourceRandom     = "random"
+	SourceSequential = "sequential"
+	SourceCIDR       = "cidr"
+	SourceFile       = "file"
+	SourceDatabase   = "database"
+)
+
+var (
+	// ErrInvalidSource is returned when a source is requested with missing or malformed parameters
+	ErrInvalidSource = errors.New("invalid IP source")
+
+	// ErrSourceUnavailable is returned when a source needs a store that isn't configured
+	ErrSourceUnavailable = errors.New("IP source not configured")
+)
+
+// IPSource yields the addresses published by a generation request
+type IPSource interface {
+	// Next returns up to max addresses; an empty result means the source is exhausted
+	Next(max int) ([]string, error)
+	Close() error
+}
+
+// TargetListStore opens named target lists, such as files of addresses to scan
+type TargetListStore interface {
+	OpenTargetList(name string) (IPSource, error)
+}
+
+// RescanFilter selects previously scanned hosts to queue again
+type RescanFilter struct {
+	BatchID       string // Only hosts from this scan batch; empty matches every batch
+	OnlyUp        bool   // Only hosts that answered the last scan
+	WithOpenPorts bool   // Only hosts that had at least one open port
+}
+
+// RescanTargetStore opens the hosts recorded by earlier scans as a source
+type RescanTargetStore interface {
+	OpenRescanTargets(filter RescanFilter) (IPSource, error)
+}
+
+// RandomSource yields a fixed number of random public addresses
+type RandomSource struct {
+	generator IPGenerator
+	remaining int
+	prefixes  []string // IPv6 prefixes to generate in; empty generates IPv4
+}
+
+// NewRandomSource creates a source of count random IPv4 addresses
+func NewRandomSource(generator IPGenerator, count int) *RandomSource {
+	return &RandomSource{generator: generator, remaining: count}
+}
+
+// NewRandomIPv6Source creates a source of count random IPv6 addresses inside the given prefixes
+func NewRandomIPv6Source(generator IPGenerator, prefixes []string, count int) (*RandomSource, error) {
+	if _, err := ParseIPv6Prefixes(prefixes); err != nil {
+		return nil, err
+	}
+	return &RandomSource{generator: generator, remaining: count, prefixes: prefixes}, nil
+}
+
+// Next generates up to max random addresses
+func (s *RandomSource) Next(max int) ([]string, error) {
+	n := min(max, s.remaining)
+	if n <= 0 {
+		return nil, nil
+	}
+
+	var ips []*IPAddress
+	var err error
+	if len(s.prefixes) > 0 {
+		ips, err = s.generator.GenerateRandomIPv6(s.prefixes, n)
+	} else {
+		ips, err = s.generator.GenerateIPs(n)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.remaining -= n
+	return ipStrings(ips), nil
+}
+
+// Close implements IPSource
+func (s *RandomSource) Close() error {
+	return nil
+}
+
+// SequentialSource yields a fixed number of consecutive public addresses
+type SequentialSource struct {
+	generator IPGenerator
+	next      string
+	remaining int
+}
+
+// NewSequentialSource creates a source of count consecutive addresses from startIP,
+// skipping addresses that aren't public
+func NewSequentialSource(generator IPGenerator, startIP string, count int) *SequentialSource {
+	return &SequentialSource{generator: generator, next: startIP, remaining: count}
+}
+
+// Next generates up to max consecutive addresses, continuing where the previous call stopped
+func (s *SequentialSource) Next(max int) ([]string, error) {
+	n := min(max, s.remaining)
+	if n <= 0 {
+		return nil, nil
+	}
+
+	ips, err := s.generator.GenerateSequentialIPs(s.next, n)
+	if err != nil {
+		return nil, err
+	}
+	if len(ips) > 0 {
+		s.next = incrementIP(ips[len(ips)-1].String())
+	}
+	s.remaining -= n
+	return ipStrings(ips), nil
+}
+
+// Close implements IPSource
+func (s *SequentialSource) Close() error {
+	return nil
+}
+
+// CIDRSource yields the host addresses of a CIDR block
+type CIDRSource struct {
+	iterator *CIDRIterator
+}
+
+// NewCIDRSource creates a source over the hosts of an IPv4 CIDR block
+func NewCIDRSource(cidr string) (*CIDRSource, error) {
+	iterator, err := NewCIDRIterator(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSource, err)
+	}
+	return &CIDRSource{iterator: iterator}, nil
+}
+
+// Next returns up to max addresses of the block
+func (s *CIDRSource) Next(max int) ([]string, error) {
+	return s.iterator.NextBatch(max), nil
+}
+
+// Close implements IPSource
+func (s *CIDRSource) Close() error {
+	return nil
+}
+
+// limitedSource stops another source after a number of addresses
+type limitedSource struct {
+	IPSource
+	remaining int
+}
+
+// LimitSource caps a source at limit addresses; limit <= 0 leaves it unbounded
+func LimitSource(source IPSource, limit int) IPSource {
+	if limit <= 0 {
+		return source
+	}
+	return &limitedSource{IPSource: source, remaining: limit}
+}
+
+// Next returns up to max addresses without exceeding the limit
+func (s *limitedSource) Next(max int) ([]string, error) {
+	n := min(max, s.remaining)
+	if n <= 0 {
+		return nil, nil
+	}
+
+	ips, err := s.IPSource.Next(n)
+	s.remaining -= len(ips)
+	return ips, err
+}
+
+// ipStrings converts domain addresses to their string form
+func ipStrings(ips []*IPAddress) []string {
+	out := make([]string, len(ips))
+	for i, ip := range ips {
+		out[i] = ip.String()
+	}
+	return out
+}
@@ -0,0 +1,126 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"ip-generator/internal/domain"
+	"ip-generator/pkg/log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// Ensure RescanTargetStore implements RescanTargetStore interface
+var _ domain.RescanTargetStore = (*RescanTargetStore)(nil)
+
+// RescanTargetStore reads previously scanned hosts from the port scanner's results collection
+type RescanTargetStore struct {
+	client     *mongo.Client
+	collection *mongo.Collection
+}
+
+// NewRescanTargetStore connects to the MongoDB database the port scanner writes its results to
+func NewRescanTargetStore(connectionString, databaseName, collectionName string) (*RescanTargetStore, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connectionString))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to MongoDB: %w", err)
+	}
+
+	if err := client.Ping(ctx, nil); err != nil {
+		return nil, fmt.Errorf("failed to ping MongoDB: %w", err)
+	}
+
+	log.L().Info("Connected to MongoDB", zap.String("database", databaseName), zap.String("collection", collectionName))
+
+	return &RescanTargetStore{
+		client:     client,
+		collection: client.Database(databaseName).Collection(collectionName),
+	}, nil
+}
+
+// OpenRescanTargets streams the distinct IPs of the scan results matching the filter
+func (s *RescanTargetStore) OpenRescanTargets(filter domain.RescanFilter) (domain.IPSource, error) {
+	query := bson.M{}
+	if filter.BatchID != "" {
+		query["batch_id"] = filter.BatchID
+	}
+	if filter.OnlyUp {
+		query["is_up"] = true
+	}
+	if filter.WithOpenPorts {
+		query["open_ports"] = bson.M{"$gt": 0}
+	}
+
+	// Sorting by IP uses the scanner's ip index and lets duplicates be dropped as they stream past
+	findOptions := options.Find().
+		SetProjection(bson.M{"ip": 1, "_id": 0}).
+		SetSort(bson.D{{Key: "ip", Value: 1}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := s.collection.Find(ctx, query, findOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rescan targets: %w", err)
+	}
+
+	return &rescanSource{cursor: cursor}, nil
+}
+
+// Close disconnects from MongoDB
+func (s *RescanTargetStore) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.client.Disconnect(ctx); err != nil {
+		return fmt.Errorf("failed to disconnect from MongoDB: %w", err)
+	}
+
+	log.L().Info("MongoDB connection closed")
+	return nil
+}
+
+// rescanSource yields each IP of a results cursor once
+type rescanSource struct {
+	cursor *mongo.Cursor
+	last   string
+}
+
+// Next reads up to max distinct IPs from the cursor
+func (s *rescanSource) Next(max int) ([]string, error) {
+	ctx := context.Background()
+
+	var ips []string
+	for len(ips) < max && s.cursor.Next(ctx) {
+		var doc struct {
+			IP string `bson:"ip"`
+		}
+		if err := s.cursor.Decode(&doc); err != nil {
+			return ips, fmt.Errorf("failed to decode rescan target: %w", err)
+		}
+		if doc.IP == "" || doc.IP == s.last {
+			continue
+		}
+		s.last = doc.IP
+		ips = append(ips, doc.IP)
+	}
+
+	if err := s.cursor.Err(); err != nil {
+		return ips, fmt.Errorf("failed to read rescan targets: %w", err)
+	}
+	return ips, nil
+}
+
+// Close releases the cursor
+func (s *rescanSource) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.cursor.Close(ctx)
+}
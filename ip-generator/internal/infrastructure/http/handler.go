@@ -1,10 +1,12 @@
 package http
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"ip-generator/internal/application"
+	"ip-generator/internal/domain"
 	"ip-generator/pkg/log"
 
 	"go.uber.org/zap"
@@ -26,15 +28,49 @@ func NewHandler(service *application.IPGenerationService) *Handler {
 
 // GenerateIPsRequest represents the request body for generating IPs
 type GenerateIPsRequest struct {
-	Count     int `json:"count" binding:"required,min=1"`
-	BatchSize int `json:"batch_size" binding:"min=1"`
+	Count     int               `json:"count" binding:"required,min=1"`
+	BatchSize int               `json:"batch_size" binding:"min=1"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	Priority  int               `json:"priority,omitempty" binding:"min=0,max=255"`
+	Retention string            `json:"retention,omitempty"`
+	Ports     []int             `json:"ports,omitempty"` // Scanned instead of the scanner's default ports
+
+	Family   string   `json:"family,omitempty" binding:"omitempty,oneof=ipv4 ipv6"`
+	Prefixes []string `json:"prefixes,omitempty"` // Required for ipv6: the prefixes addresses are generated in
 }
 
 // GenerateSequentialIPsRequest represents the request body for generating sequential IPs
 type GenerateSequentialIPsRequest struct {
-	StartIP   string `json:"start_ip" binding:"required"`
-	Count     int    `json:"count" binding:"required,min=1"`
-	BatchSize int    `json:"batch_size" binding:"min=1"`
+	StartIP   string            `json:"start_ip" binding:"required"`
+	Count     int               `json:"count" binding:"required,min=1"`
+	BatchSize int               `json:"batch_size" binding:"min=1"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	Priority  int               `json:"priority,omitempty" binding:"min=0,max=255"`
+	Retention string            `json:"retention,omitempty"`
+	Ports     []int             `json:"ports,omitempty"` // Scanned instead of the scanner's default ports
+}
+
+// GenerateFromSourceRequest represents the request body for generating IPs from a chosen source
+type GenerateFromSourceRequest struct {
+	Source    string            `json:"source" binding:"required,oneof=random sequential cidr file database"`
+	Count     int               `json:"count" binding:"min=0"` // Required for random and sequential; caps the other sources
+	StartIP   string            `json:"start_ip,omitempty"`
+	CIDR      string            `json:"cidr,omitempty"`
+	File      string            `json:"file,omitempty"`
+	BatchSize int               `json:"batch_size" binding:"min=0"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	Priority  int               `json:"priority,omitempty" binding:"min=0,max=255"`
+	Retention string            `json:"retention,omitempty"`
+	Ports     []int             `json:"ports,omitempty"` // Scanned instead of the scanner's default ports
+
+	// Database source filters
+	RescanBatchID string `json:"rescan_batch_id,omitempty"`
+	OnlyUp        bool   `json:"only_up,omitempty"`
+	WithOpenPorts bool   `json:"with_open_ports,omitempty"`
+
+	// Random source address family; ipv6 requires prefixes
+	Family   string   `json:"family,omitempty" binding:"omitempty,oneof=ipv4 ipv6"`
+	Prefixes []string `json:"prefixes,omitempty"`
 }
 
 // Response represents a generic API response
@@ -43,6 +79,8 @@ type Response struct {
 	Message string      `json:"message,omitempty"`
 	Data    interface{} `json:"data,omitempty"`
 	Error   string      `json:"error,omitempty"`
+
+	Fields []FieldError `json:"fields,omitempty"` // Rejected request body fields
 }
 
 // GenerateRandomIPs handles requests to generate random IP addresses
@@ -52,7 +90,8 @@ func (h *Handler) GenerateRandomIPs(c *gin.Context) {
 		log.L().Warn("Invalid IP generation request", zap.String("event", "generateip_invalid_request"), zap.Error(err))
 		c.JSON(http.StatusBadRequest, Response{
 			Success: false,
-			Error:   "Invalid request body: " + err.Error(),
+			Error:   "Invalid request body",
+			Fields:  bindingErrors(err),
 		})
 		return
 	}
@@ -64,10 +103,20 @@ func (h *Handler) GenerateRandomIPs(c *gin.Context) {
 		req.BatchSize = 100
 	}
 
-	err := h.service.GenerateAndPublishIPs(req.Count, req.BatchSize)
+	options := &domain.BatchOptions{Tags: req.Tags, Priority: req.Priority, Retention: req.Retention, Ports: req.Ports}
+	if err := options.Validate(); err != nil {
+		log.L().Warn("Invalid IP generation request", zap.String("event", "generateip_invalid_request"), zap.Error(err))
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	err := h.service.GenerateAndPublishFamily(req.Family, req.Prefixes, req.Count, req.BatchSize, options)
 	if err != nil {
 		log.L().Error("IP generation failed", zap.String("event", "generateip_failed"), zap.Error(err))
-		c.JSON(http.StatusInternalServerError, Response{
+		c.JSON(errorStatus(err), Response{
 			Success: false,
 			Error:   "Failed to generate and publish IPs: " + err.Error(),
 		})
@@ -82,10 +131,19 @@ func (h *Handler) GenerateRandomIPs(c *gin.Context) {
 		Data: gin.H{
 			"count":      req.Count,
 			"batch_size": req.BatchSize,
+			"family":     familyOrDefault(req.Family),
 		},
 	})
 }
 
+// familyOrDefault returns the address family a request generates, IPv4 unless set
+func familyOrDefault(family string) string {
+	if family == "" {
+		return domain.FamilyIPv4
+	}
+	return family
+}
+
 // GenerateSequentialIPs handles requests to generate sequential IP addresses
 func (h *Handler) GenerateSequentialIPs(c *gin.Context) {
 	var req GenerateSequentialIPsRequest
@@ -93,7 +151,8 @@ func (h *Handler) GenerateSequentialIPs(c *gin.Context) {
 		log.L().Warn("Invalid IP generation request", zap.String("event", "generatesequentialip_invalid_request"), zap.Error(err))
 		c.JSON(http.StatusBadRequest, Response{
 			Success: false,
-			Error:   "Invalid request body: " + err.Error(),
+			Error:   "Invalid request body",
+			Fields:  bindingErrors(err),
 		})
 		return
 	}
@@ -105,10 +164,20 @@ func (h *Handler) GenerateSequentialIPs(c *gin.Context) {
 		req.BatchSize = 100
 	}
 
-	err := h.service.GenerateAndPublishSequentialIPs(req.StartIP, req.Count, req.BatchSize)
+	options := &domain.BatchOptions{Tags: req.Tags, Priority: req.Priority, Retention: req.Retention, Ports: req.Ports}
+	if err := options.Validate(); err != nil {
+		log.L().Warn("Invalid IP generation request", zap.String("event", "generatesequentialip_invalid_request"), zap.Error(err))
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	err := h.service.GenerateAndPublishSequentialIPs(req.StartIP, req.Count, req.BatchSize, options)
 	if err != nil {
 		log.L().Error("IP generation failed", zap.String("event", "generatesequentialip_failed"), zap.Error(err))
-		c.JSON(http.StatusInternalServerError, Response{
+		c.JSON(errorStatus(err), Response{
 			Success: false,
 			Error:   "Failed to generate and publish sequential IPs: " + err.Error(),
 		})
@@ -128,6 +197,85 @@ func (h *Handler) GenerateSequentialIPs(c *gin.Context) {
 	})
 }
 
+// GenerateFromSource handles requests to publish IPs drawn from the source selected in the request
+func (h *Handler) GenerateFromSource(c *gin.Context) {
+	var req GenerateFromSourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.L().Warn("Invalid IP generation request", zap.String("event", "generatesourceip_invalid_request"), zap.Error(err))
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request body",
+			Fields:  bindingErrors(err),
+		})
+		return
+	}
+
+	log.L().Info("Received IP generation request", zap.String("event", "generatesourceip_request"), zap.Any("params", req))
+
+	// Set default batch size if not provided
+	if req.BatchSize <= 0 {
+		req.BatchSize = 100
+	}
+
+	options := &domain.BatchOptions{Tags: req.Tags, Priority: req.Priority, Retention: req.Retention, Ports: req.Ports}
+	if err := options.Validate(); err != nil {
+		log.L().Warn("Invalid IP generation request", zap.String("event", "generatesourceip_invalid_request"), zap.Error(err))
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	source, err := h.service.OpenSource(application.SourceSpec{
+		Kind:    req.Source,
+		Count:   req.Count,
+		StartIP: req.StartIP,
+		CIDR:    req.CIDR,
+		File:    req.File,
+		Rescan: domain.RescanFilter{
+			BatchID:       req.RescanBatchID,
+			OnlyUp:        req.OnlyUp,
+			WithOpenPorts: req.WithOpenPorts,
+		},
+		Family:   req.Family,
+		Prefixes: req.Prefixes,
+	})
+	if err != nil {
+		log.L().Warn("Failed to open IP source", zap.String("event", "generatesourceip_source_failed"), zap.String("source", req.Source), zap.Error(err))
+		c.JSON(errorStatus(err), Response{
+			Success: false,
+			Error:   "Failed to open IP source: " + err.Error(),
+		})
+		return
+	}
+
+	published, err := h.service.GenerateAndPublish(source, req.BatchSize, options)
+	if err != nil {
+		log.L().Error("IP generation failed", zap.String("event", "generatesourceip_failed"), zap.String("source", req.Source), zap.Int("published", published), zap.Error(err))
+		c.JSON(errorStatus(err), Response{
+			Success: false,
+			Error:   "Failed to generate and publish IPs: " + err.Error(),
+			Data: gin.H{
+				"published": published,
+			},
+		})
+		return
+	}
+
+	log.L().Info("IPs generated and published successfully", zap.String("event", "generatesourceip_success"), zap.String("source", req.Source), zap.Int("count", published), zap.Int("batch_size", req.BatchSize))
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "IPs generated and published successfully",
+		Data: gin.H{
+			"source":     req.Source,
+			"count":      published,
+			"batch_size": req.BatchSize,
+		},
+	})
+}
+
 // GenerateIPsWithQueryParams handles requests with query parameters
 func (h *Handler) GenerateIPsWithQueryParams(c *gin.Context) {
 	countStr := c.Query("count")
@@ -165,10 +313,10 @@ func (h *Handler) GenerateIPsWithQueryParams(c *gin.Context) {
 		}
 	}
 
-	err = h.service.GenerateAndPublishIPs(count, batchSize)
+	err = h.service.GenerateAndPublishIPs(count, batchSize, nil)
 	if err != nil {
 		log.L().Error("IP generation failed", zap.String("event", "generatequeryip_failed"), zap.Error(err))
-		c.JSON(http.StatusInternalServerError, Response{
+		c.JSON(errorStatus(err), Response{
 			Success: false,
 			Error:   "Failed to generate and publish IPs: " + err.Error(),
 		})
@@ -200,23 +348,45 @@ func (h *Handler) HealthCheck(c *gin.Context) {
 
 // GetServiceInfo returns information about the service
 func (h *Handler) GetServiceInfo(c *gin.Context) {
+	data := gin.H{
+		"service": "IP Generator Microservice",
+		"version": "1.0.0",
+		"endpoints": gin.H{
+			"health":              "/health",
+			"info":                "/api/v1/info",
+			"generate_random":     "/api/v1/ips/generate",
+			"generate_sequential": "/api/v1/ips/generate/sequential",
+			"generate_query":      "/api/v1/ips/generate/query",
+			"generate_source":     "/api/v1/ips/generate/source",
+		},
+	}
+
+	// Report the scan queue depth when backpressure is enabled
+	if depth, paused, ok := h.service.BackpressureStatus(); ok {
+		data["queue"] = gin.H{
+			"depth":  depth,
+			"paused": paused,
+		}
+	}
+
 	c.JSON(http.StatusOK, Response{
 		Success: true,
 		Message: "IP Generator Service Information",
-		Data: gin.H{
-			"service": "IP Generator Microservice",
-			"version": "1.0.0",
-			"endpoints": gin.H{
-				"health":              "/health",
-				"info":                "/api/v1/info",
-				"generate_random":     "/api/v1/ips/generate",
-				"generate_sequential": "/api/v1/ips/generate/sequential",
-				"generate_query":      "/api/v1/ips/generate/query",
-			},
-		},
+		Data:    data,
 	})
 }
 
+// errorStatus maps a generation error to an HTTP status code
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrBackpressure), errors.Is(err, domain.ErrSourceUnavailable):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, domain.ErrInvalidSource):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
 // SetupRoutes configures the Gin router with all the routes
 func (h *Handler) SetupRoutes(router *gin.Engine) {
 	// Health check
@@ -233,6 +403,7 @@ func (h *Handler) SetupRoutes(router *gin.Engine) {
 			ips.POST("/generate", h.GenerateRandomIPs)
 			ips.POST("/generate/sequential", h.GenerateSequentialIPs)
 			ips.GET("/generate/query", h.GenerateIPsWithQueryParams)
+			ips.POST("/generate/source", h.GenerateFromSource)
 		}
 	}
 }
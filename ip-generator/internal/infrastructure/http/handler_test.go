@@ -0,0 +1,95 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+
+	"ip-generator/internal/application"
+	"ip-generator/internal/domain"
+	"ip-generator/pkg/log"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMain(m *testing.M) {
+	log.InitLogger("http-test")
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// discardPublisher is a queue publisher that drops every message
+type discardPublisher struct{}
+
+func (discardPublisher) Publish(message *domain.QueueMessage) error         { return nil }
+func (discardPublisher) PublishBatch(messages []*domain.QueueMessage) error { return nil }
+
+// post sends a JSON body to the handler's routes and decodes the response
+func post(t *testing.T, path, body string) (int, Response) {
+	t.Helper()
+	service := application.NewIPGenerationService(domain.NewIPGeneratorService(), discardPublisher{})
+	router := gin.New()
+	NewHandler(service).SetupRoutes(router)
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(recorder, req)
+
+	var response Response
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid JSON response %q: %v", recorder.Body.String(), err)
+	}
+	return recorder.Code, response
+}
+
+func TestGenerateReportsStructuredFieldErrors(t *testing.T) {
+	tests := []struct {
+		path string
+		body string
+		want []FieldError
+	}{
+		{
+			"/api/v1/ips/generate", `{"batch_size": 10}`,
+			[]FieldError{{Field: "count", Rule: "required", Message: "count is required"}},
+		},
+		{
+			"/api/v1/ips/generate", `{"count": 10, "batch_size": 0, "priority": 300}`,
+			[]FieldError{
+				{Field: "batch_size", Rule: "min", Message: "batch_size must be at least 1"},
+				{Field: "priority", Rule: "max", Message: "priority must be at most 255"},
+			},
+		},
+		{
+			"/api/v1/ips/generate/sequential", `{"count": 10, "batch_size": 5}`,
+			[]FieldError{{Field: "start_ip", Rule: "required", Message: "start_ip is required"}},
+		},
+		{
+			"/api/v1/ips/generate/source", `{"source": "carrier-pigeon"}`,
+			[]FieldError{{Field: "source", Rule: "oneof", Message: "source must be one of: random, sequential, cidr, file, database"}},
+		},
+	}
+	for _, tt := range tests {
+		status, response := post(t, tt.path, tt.body)
+		if status != http.StatusBadRequest || response.Success {
+			t.Errorf("%s %s: status = %d, success = %v, want a 400 failure", tt.path, tt.body, status, response.Success)
+		}
+		if !reflect.DeepEqual(response.Fields, tt.want) {
+			t.Errorf("%s %s: fields = %+v, want %+v", tt.path, tt.body, response.Fields, tt.want)
+		}
+	}
+}
+
+func TestGenerateReportsWrongFieldType(t *testing.T) {
+	status, response := post(t, "/api/v1/ips/generate", `{"count": "ten"}`)
+	if status != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", status, http.StatusBadRequest)
+	}
+	if len(response.Fields) != 1 || response.Fields[0].Field != "count" || response.Fields[0].Rule != "type" {
+		t.Errorf("fields = %+v, want a type error on count", response.Fields)
+	}
+}
@@ -0,0 +1,168 @@
+package queue
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// AMQP 0-9-1 methods the fake broker answers, as class and method IDs
+const (
+	methodConnectionStartOk = 10<<16 | 11
+	methodConnectionOpen    = 10<<16 | 40
+	methodConnectionClose   = 10<<16 | 50
+	methodChannelOpen       = 20<<16 | 10
+	methodChannelClose      = 20<<16 | 40
+)
+
+// delayedBroker is a fake RabbitMQ that drops the first connections it receives, as a broker
+// still starting up does, then completes the AMQP handshake and opens channels
+type delayedBroker struct {
+	listener    net.Listener
+	unavailable int32
+	attempts    int32
+}
+
+func newDelayedBroker(t *testing.T, unavailable int) *delayedBroker {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	broker := &delayedBroker{listener: listener, unavailable: int32(unavailable)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			if atomic.AddInt32(&broker.attempts, 1) <= broker.unavailable {
+				conn.Close()
+				continue
+			}
+			go broker.serve(conn)
+		}
+	}()
+	return broker
+}
+
+func (b *delayedBroker) url() string {
+	return fmt.Sprintf("amqp://guest:guest@%s/", b.listener.Addr())
+}
+
+// serve answers the methods a client sends to connect, open a channel and close
+func (b *delayedBroker) serve(conn net.Conn) {
+	defer conn.Close()
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return
+	}
+
+	// connection.start: version 0-9, no server properties, PLAIN auth, en_US locale
+	start := []byte{0, 9, 0, 0, 0, 0}
+	start = appendLongString(start, "PLAIN")
+	start = appendLongString(start, "en_US")
+	writeMethod(conn, 0, 10, 10, start)
+
+	for {
+		channel, method, err := readMethod(conn)
+		if err != nil {
+			return
+		}
+		switch method {
+		case methodConnectionStartOk:
+			// connection.tune: no channel limit, 128KiB frames, no heartbeats
+			tune := binary.BigEndian.AppendUint16(nil, 0)
+			tune = binary.BigEndian.AppendUint32(tune, 131072)
+			tune = binary.BigEndian.AppendUint16(tune, 0)
+			writeMethod(conn, 0, 10, 30, tune)
+		case methodConnectionOpen:
+			writeMethod(conn, 0, 10, 41, []byte{0})
+		case methodChannelOpen:
+			writeMethod(conn, channel, 20, 11, []byte{0, 0, 0, 0})
+		case methodChannelClose:
+			writeMethod(conn, channel, 20, 41, nil)
+		case methodConnectionClose:
+			writeMethod(conn, 0, 10, 51, nil)
+			return
+		}
+	}
+}
+
+func appendLongString(b []byte, s string) []byte {
+	return append(binary.BigEndian.AppendUint32(b, uint32(len(s))), s...)
+}
+
+// writeMethod writes a method frame
+func writeMethod(w io.Writer, channel uint16, class, method uint16, args []byte) {
+	payload := binary.BigEndian.AppendUint16(nil, class)
+	payload = binary.BigEndian.AppendUint16(payload, method)
+	payload = append(payload, args...)
+
+	frame := []byte{1}
+	frame = binary.BigEndian.AppendUint16(frame, channel)
+	frame = binary.BigEndian.AppendUint32(frame, uint32(len(payload)))
+	frame = append(frame, payload...)
+	w.Write(append(frame, 0xCE))
+}
+
+// readMethod reads frames until a method frame and returns its channel and class<<16|method
+func readMethod(r io.Reader) (uint16, uint32, error) {
+	for {
+		header := make([]byte, 7)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return 0, 0, err
+		}
+		payload := make([]byte, binary.BigEndian.Uint32(header[3:])+1)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return 0, 0, err
+		}
+		if header[0] == 1 && len(payload) >= 5 {
+			return binary.BigEndian.Uint16(header[1:]), binary.BigEndian.Uint32(payload), nil
+		}
+	}
+}
+
+func TestConnectRetriesUntilBrokerIsAvailable(t *testing.T) {
+	broker := newDelayedBroker(t, 2)
+
+	conn, ch, err := connect(broker.url(), 10*time.Second)
+	if err != nil {
+		t.Fatalf("connect returned error: %v", err)
+	}
+	ch.Close()
+	conn.Close()
+
+	if attempts := atomic.LoadInt32(&broker.attempts); attempts != 3 {
+		t.Errorf("connected after %d attempts, want 3", attempts)
+	}
+}
+
+func TestConnectGivesUpAfterMaxWait(t *testing.T) {
+	broker := newDelayedBroker(t, 100)
+
+	if _, _, err := connect(broker.url(), 0); err == nil || !strings.Contains(err.Error(), "after 1 attempts") {
+		t.Errorf("connect without a max wait = %v, want a failure after 1 attempt", err)
+	}
+
+	start := time.Now()
+	_, _, err := connect(broker.url(), 700*time.Millisecond)
+	elapsed := time.Since(start)
+	if err == nil {
+		t.Fatal("connect succeeded against an unavailable broker")
+	}
+	if elapsed < 700*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("connect gave up after %v, want about the 700ms max wait", elapsed)
+	}
+	// One attempt, then retries after 500ms and after the remaining 200ms
+	if attempts := atomic.LoadInt32(&broker.attempts); attempts != 4 {
+		t.Errorf("broker saw %d attempts, want 1 without a max wait and 3 within it", attempts)
+	}
+}
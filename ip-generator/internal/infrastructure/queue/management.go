@@ -0,0 +1,64 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ManagementClient reads queue statistics from the RabbitMQ management HTTP API
+type ManagementClient struct {
+	baseURL  string
+	username string
+	password string
+	vhost    string
+	queue    string
+	client   *http.Client
+}
+
+// NewManagementClient creates a client for a queue's statistics on the management API
+func NewManagementClient(baseURL, username, password, vhost, queueName string) *ManagementClient {
+	if vhost == "" {
+		vhost = "/"
+	}
+	return &ManagementClient{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		username: username,
+		password: password,
+		vhost:    vhost,
+		queue:    queueName,
+		client:   &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// QueueDepth returns the number of ready and unacknowledged messages in the queue
+func (m *ManagementClient) QueueDepth() (int, error) {
+	endpoint := fmt.Sprintf("%s/api/queues/%s/%s", m.baseURL, url.PathEscape(m.vhost), url.PathEscape(m.queue))
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to build management request: %w", err)
+	}
+	req.SetBasicAuth(m.username, m.password)
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query queue depth: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("management API returned %s for queue %s", resp.Status, m.queue)
+	}
+
+	var stats struct {
+		Messages int `json:"messages"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return 0, fmt.Errorf("failed to decode queue stats: %w", err)
+	}
+
+	return stats.Messages, nil
+}
@@ -0,0 +1,38 @@
+package queue
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestQueueDepthReadsManagementAPI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "guest" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.EscapedPath() != "/api/queues/%2F/ip_scan_queue" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"name": "ip_scan_queue", "messages": 1234, "messages_ready": 1200}`)
+	}))
+	defer server.Close()
+
+	depth, err := NewManagementClient(server.URL+"/", "guest", "secret", "", "ip_scan_queue").QueueDepth()
+	if err != nil {
+		t.Fatalf("QueueDepth returned error: %v", err)
+	}
+	if depth != 1234 {
+		t.Errorf("QueueDepth() = %d, want 1234", depth)
+	}
+
+	if _, err := NewManagementClient(server.URL, "guest", "wrong", "/", "ip_scan_queue").QueueDepth(); err == nil {
+		t.Error("QueueDepth returned no error for a rejected request")
+	}
+	if _, err := NewManagementClient(server.URL, "guest", "secret", "/", "missing").QueueDepth(); err == nil {
+		t.Error("QueueDepth returned no error for an unknown queue")
+	}
+}
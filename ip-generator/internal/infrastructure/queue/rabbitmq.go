@@ -3,6 +3,7 @@ package queue
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"ip-generator/internal/domain"
 	"ip-generator/pkg/log"
@@ -11,34 +12,53 @@ import (
 	"go.uber.org/zap"
 )
 
+// Delays between connection attempts while the broker is unavailable, doubling up to the maximum
+const (
+	initialDialBackoff = 500 * time.Millisecond
+	maxDialBackoff     = 10 * time.Second
+)
+
 // RabbitMQPublisher implements the QueuePublisher interface using RabbitMQ
 type RabbitMQPublisher struct {
-	conn    *amqp.Connection
-	channel *amqp.Channel
-	queue   string
+	conn        *amqp.Connection
+	channel     *amqp.Channel
+	publisher   messagePublisher
+	queue       string
+	maxPriority int
 }
 
-// NewRabbitMQPublisher creates a new RabbitMQ publisher
-func NewRabbitMQPublisher(url, queueName string) (*RabbitMQPublisher, error) {
-	conn, err := amqp.Dial(url)
+// NewRabbitMQPublisher creates a new RabbitMQ publisher.
+// When maxPriority is greater than zero the queue is declared as a priority queue
+// and must be declared with the same value by every consumer. Each publish waits up to
+// confirmTimeout for the broker to confirm the message. While the broker is unreachable the
+// connection is retried with exponential backoff for up to connectMaxWait; zero tries once.
+func NewRabbitMQPublisher(url, queueName string, maxPriority int, confirmTimeout, connectMaxWait time.Duration) (*RabbitMQPublisher, error) {
+	conn, ch, err := connect(url, connectMaxWait)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+		return nil, err
 	}
 
-	ch, err := conn.Channel()
+	// Wait for broker confirmations so dropped messages fail the request instead of being lost
+	publisher, err := newConfirmPublisher(ch, confirmTimeout)
 	if err != nil {
+		ch.Close()
 		conn.Close()
-		return nil, fmt.Errorf("failed to open channel: %w", err)
+		return nil, err
 	}
 
 	// Declare the queue
+	var args amqp.Table
+	if maxPriority > 0 {
+		args = amqp.Table{"x-max-priority": int32(maxPriority)}
+	}
+
 	_, err = ch.QueueDeclare(
 		queueName, // name
 		true,      // durable
 		false,     // delete when unused
 		false,     // exclusive
 		false,     // no-wait
-		nil,       // arguments
+		args,      // arguments
 	)
 	if err != nil {
 		ch.Close()
@@ -47,12 +67,51 @@ func NewRabbitMQPublisher(url, queueName string) (*RabbitMQPublisher, error) {
 	}
 
 	return &RabbitMQPublisher{
-		conn:    conn,
-		channel: ch,
-		queue:   queueName,
+		conn:        conn,
+		channel:     ch,
+		publisher:   publisher,
+		queue:       queueName,
+		maxPriority: maxPriority,
 	}, nil
 }
 
+// connect opens a connection and channel, retrying with backoff until maxWait has passed so
+// the service can start alongside a broker that isn't accepting connections yet
+func connect(url string, maxWait time.Duration) (*amqp.Connection, *amqp.Channel, error) {
+	deadline := time.Now().Add(maxWait)
+	backoff := initialDialBackoff
+
+	for attempt := 1; ; attempt++ {
+		conn, err := amqp.Dial(url)
+		if err == nil {
+			ch, chErr := conn.Channel()
+			if chErr == nil {
+				if attempt > 1 {
+					log.L().Info("Connected to RabbitMQ", zap.String("event", "rabbitmq_connected"), zap.Int("attempts", attempt))
+				}
+				return conn, ch, nil
+			}
+			conn.Close()
+			err = fmt.Errorf("failed to open channel: %w", chErr)
+		} else {
+			err = fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, nil, fmt.Errorf("%w (after %d attempts)", err, attempt)
+		}
+		wait := min(backoff, remaining)
+		log.L().Warn("RabbitMQ not available, retrying",
+			zap.String("event", "rabbitmq_connect_retry"),
+			zap.Int("attempt", attempt),
+			zap.Duration("retry_in", wait),
+			zap.Error(err))
+		time.Sleep(wait)
+		backoff = min(backoff*2, maxDialBackoff)
+	}
+}
+
 // Publish publishes a single message to the queue
 func (r *RabbitMQPublisher) Publish(message *domain.QueueMessage) error {
 	body, err := json.Marshal(message)
@@ -60,14 +119,13 @@ func (r *RabbitMQPublisher) Publish(message *domain.QueueMessage) error {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
-	err = r.channel.Publish(
+	err = r.publisher.publish(
 		"",      // exchange
 		r.queue, // routing key
-		false,   // mandatory
-		false,   // immediate
 		amqp.Publishing{
 			ContentType: "application/json",
 			Body:        body,
+			Priority:    r.messagePriority(message.Priority),
 		})
 	if err != nil {
 		return fmt.Errorf("failed to publish message: %w", err)
@@ -77,6 +135,17 @@ func (r *RabbitMQPublisher) Publish(message *domain.QueueMessage) error {
 	return nil
 }
 
+// messagePriority clamps a requested priority to the queue's supported range
+func (r *RabbitMQPublisher) messagePriority(priority int) uint8 {
+	if r.maxPriority <= 0 || priority <= 0 {
+		return 0
+	}
+	if priority > r.maxPriority {
+		priority = r.maxPriority
+	}
+	return uint8(priority)
+}
+
 // PublishBatch publishes multiple messages to the queue
 func (r *RabbitMQPublisher) PublishBatch(messages []*domain.QueueMessage) error {
 	for _, message := range messages {
@@ -0,0 +1,114 @@
+package queue
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+
+	"ip-generator/internal/domain"
+	"ip-generator/pkg/log"
+
+	"github.com/streadway/amqp"
+)
+
+func TestMain(m *testing.M) {
+	log.InitLogger("queue-test")
+	os.Exit(m.Run())
+}
+
+// recordingPublisher stands in for the broker, keeping every published message in order
+type recordingPublisher struct {
+	mu       sync.Mutex
+	messages []amqp.Publishing
+	err      error
+}
+
+func (p *recordingPublisher) publish(exchange, key string, msg amqp.Publishing) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.messages = append(p.messages, msg)
+	return nil
+}
+
+// deliveryOrder returns the batch IDs of the published messages in the order a RabbitMQ
+// priority queue delivers them: highest priority first, publish order among equals
+func (p *recordingPublisher) deliveryOrder(t *testing.T) []string {
+	t.Helper()
+	p.mu.Lock()
+	queued := append([]amqp.Publishing(nil), p.messages...)
+	p.mu.Unlock()
+
+	sort.SliceStable(queued, func(i, j int) bool { return queued[i].Priority > queued[j].Priority })
+	order := make([]string, len(queued))
+	for i, msg := range queued {
+		var message domain.QueueMessage
+		if err := json.Unmarshal(msg.Body, &message); err != nil {
+			t.Fatalf("published body is not a queue message: %v", err)
+		}
+		order[i] = message.BatchID
+	}
+	return order
+}
+
+// newTestPublisher returns a publisher for a priority queue without a broker connection
+func newTestPublisher(maxPriority int) (*RabbitMQPublisher, *recordingPublisher) {
+	recorder := &recordingPublisher{}
+	return &RabbitMQPublisher{publisher: recorder, queue: "ips", maxPriority: maxPriority}, recorder
+}
+
+func TestHighPriorityMessageIsDeliveredFirst(t *testing.T) {
+	publisher, broker := newTestPublisher(10)
+
+	for _, message := range []*domain.QueueMessage{
+		domain.NewQueueMessage([]string{"8.8.8.8"}, "routine-1", nil),
+		domain.NewQueueMessage([]string{"8.8.4.4"}, "routine-2", &domain.BatchOptions{Priority: 1}),
+		domain.NewQueueMessage([]string{"1.1.1.1"}, "urgent", &domain.BatchOptions{Priority: 9}),
+		domain.NewQueueMessage([]string{"9.9.9.9"}, "routine-3", nil),
+	} {
+		if err := publisher.Publish(message); err != nil {
+			t.Fatalf("Publish returned error: %v", err)
+		}
+	}
+
+	want := []string{"urgent", "routine-2", "routine-1", "routine-3"}
+	got := broker.deliveryOrder(t)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("delivery order %v, want %v", got, want)
+		}
+	}
+}
+
+func TestMessagePriorityIsClampedToQueue(t *testing.T) {
+	publisher, _ := newTestPublisher(5)
+	tests := map[int]uint8{-3: 0, 0: 0, 3: 3, 5: 5, 9: 5}
+	for requested, want := range tests {
+		if got := publisher.messagePriority(requested); got != want {
+			t.Errorf("messagePriority(%d) = %d, want %d", requested, got, want)
+		}
+	}
+
+	// A queue declared without x-max-priority ignores priorities
+	plain, _ := newTestPublisher(0)
+	if got := plain.messagePriority(9); got != 0 {
+		t.Errorf("messagePriority on a plain queue = %d, want 0", got)
+	}
+}
+
+func TestPublishSendsJSONWithPriority(t *testing.T) {
+	publisher, broker := newTestPublisher(10)
+	message := domain.NewQueueMessage([]string{"8.8.8.8"}, "batch-1", &domain.BatchOptions{Priority: 4})
+	if err := publisher.Publish(message); err != nil {
+		t.Fatalf("Publish returned error: %v", err)
+	}
+
+	published := broker.messages[0]
+	if published.ContentType != "application/json" || published.Priority != 4 {
+		t.Errorf("published content type %q priority %d, want application/json priority 4", published.ContentType, published.Priority)
+	}
+}
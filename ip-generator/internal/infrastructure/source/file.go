@@ -0,0 +1,85 @@
+package source
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"ip-generator/internal/domain"
+	"ip-generator/pkg/log"
+	"orwell/pkg/netutil"
+
+	"go.uber.org/zap"
+)
+
+// Ensure FileTargetStore implements TargetListStore interface
+var _ domain.TargetListStore = (*FileTargetStore)(nil)
+
+// FileTargetStore opens target lists stored as files in a directory, one address per line.
+// Blank lines and lines starting with '#' are ignored.
+type FileTargetStore struct {
+	dir string
+}
+
+// NewFileTargetStore creates a store reading target lists from dir
+func NewFileTargetStore(dir string) *FileTargetStore {
+	return &FileTargetStore{dir: dir}
+}
+
+// OpenTargetList opens the named list; names are plain file names so requests can't read outside the directory
+func (s *FileTargetStore) OpenTargetList(name string) (domain.IPSource, error) {
+	if name == "" || name != filepath.Base(name) || name == "." || name == ".." {
+		return nil, fmt.Errorf("%w: invalid target list name %q", domain.ErrInvalidSource, name)
+	}
+
+	file, err := os.Open(filepath.Join(s.dir, name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: target list %q not found", domain.ErrInvalidSource, name)
+		}
+		return nil, fmt.Errorf("failed to open target list %q: %w", name, err)
+	}
+
+	return &fileSource{name: name, file: file, scanner: bufio.NewScanner(file)}, nil
+}
+
+// fileSource reads a target list lazily so large lists aren't loaded into memory
+type fileSource struct {
+	name    string
+	file    *os.File
+	scanner *bufio.Scanner
+	line    int
+}
+
+// Next reads up to max addresses, skipping lines that aren't valid IPv4 addresses
+func (s *fileSource) Next(max int) ([]string, error) {
+	var ips []string
+	for len(ips) < max && s.scanner.Scan() {
+		s.line++
+		entry := strings.TrimSpace(s.scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		ip, err := netutil.ParseAndValidate(entry)
+		if err != nil {
+			log.L().Warn("Skipping invalid target list entry", zap.String("event", "target_list_invalid_entry"),
+				zap.String("list", s.name), zap.Int("line", s.line), zap.Error(err))
+			continue
+		}
+		ips = append(ips, ip.String())
+	}
+
+	if err := s.scanner.Err(); err != nil {
+		return ips, fmt.Errorf("failed to read target list %q: %w", s.name, err)
+	}
+	return ips, nil
+}
+
+// Close closes the underlying file
+func (s *fileSource) Close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,130 @@
+// Package netutil holds the canonical IPv4 and IPv6 validation rules shared by the
+// ip-generator and port-scanner services. It lives in the root orwell module,
+// which both services pull in through a replace directive.
+package netutil
+
+import (
+	"fmt"
+	"net"
+)
+
+// specialUseBlocks lists the IPv4 special-purpose address blocks from RFC 6890
+// (plus the multicast range) that must never be treated as public targets
+var specialUseBlocks = mustParseCIDRs(
+	"0.0.0.0/8",          // "This host on this network" (RFC 1122)
+	"10.0.0.0/8",         // Private-Use (RFC 1918)
+	"100.64.0.0/10",      // Shared Address Space (RFC 6598)
+	"127.0.0.0/8",        // Loopback (RFC 1122)
+	"169.254.0.0/16",     // Link Local (RFC 3927)
+	"172.16.0.0/12",      // Private-Use (RFC 1918)
+	"192.0.0.0/24",       // IETF Protocol Assignments (RFC 6890)
+	"192.0.2.0/24",       // Documentation TEST-NET-1 (RFC 5737)
+	"192.88.99.0/24",     // 6to4 Relay Anycast (RFC 3068)
+	"192.168.0.0/16",     // Private-Use (RFC 1918)
+	"198.18.0.0/15",      // Benchmarking (RFC 2544)
+	"198.51.100.0/24",    // Documentation TEST-NET-2 (RFC 5737)
+	"203.0.113.0/24",     // Documentation TEST-NET-3 (RFC 5737)
+	"224.0.0.0/4",        // Multicast (RFC 5771)
+	"240.0.0.0/4",        // Reserved (RFC 1112)
+	"255.255.255.255/32", // Limited Broadcast (RFC 919)
+)
+
+// globalUnicastIPv6 is the IPv6 block currently allocated for global unicast (RFC 4291)
+var globalUnicastIPv6 = mustParseCIDRs("2000::/3")[0]
+
+// specialUseBlocksIPv6 lists the special-purpose blocks inside 2000::/3 from the IANA IPv6
+// special-purpose registry that must never be treated as public targets. Addresses outside
+// 2000::/3, such as ULA, link-local and multicast, are rejected by the global unicast check.
+var specialUseBlocksIPv6 = mustParseCIDRs(
+	"2001::/23",     // IETF Protocol Assignments, including Teredo and ORCHIDv2 (RFC 2928)
+	"2001:db8::/32", // Documentation (RFC 3849)
+	"2002::/16",     // 6to4 (RFC 3056)
+	"3fff::/20",     // Documentation (RFC 9637)
+)
+
+// ParseAndValidate parses a dotted-quad IPv4 address, returning an error if it is malformed
+func ParseAndValidate(address string) (net.IP, error) {
+	ip := net.ParseIP(address)
+	if ip == nil || ip.To4() == nil {
+		return nil, fmt.Errorf("invalid IPv4 address: %s", address)
+	}
+	return ip.To4(), nil
+}
+
+// IsReserved reports whether an IPv4 address falls in a special-use block
+func IsReserved(ip net.IP) bool {
+	return ReservedBlock(ip) != nil
+}
+
+// ReservedBlock returns the special-use block containing an IPv4 address, or nil if there is none
+func ReservedBlock(ip net.IP) *net.IPNet {
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return nil
+	}
+
+	for _, block := range specialUseBlocks {
+		if block.Contains(ip4) {
+			return block
+		}
+	}
+	return nil
+}
+
+// IsPublicIP reports whether an address is a valid, globally routable IPv4 address
+func IsPublicIP(ip net.IP) bool {
+	if ip == nil || ip.To4() == nil {
+		return false
+	}
+	return !IsReserved(ip)
+}
+
+// ParseAndValidateIPv6 parses an IPv6 address, returning an error if it is malformed or IPv4
+func ParseAndValidateIPv6(address string) (net.IP, error) {
+	ip := net.ParseIP(address)
+	if ip == nil || ip.To4() != nil {
+		return nil, fmt.Errorf("invalid IPv6 address: %s", address)
+	}
+	return ip, nil
+}
+
+// IsPublicIPv6 reports whether an address is a globally routable IPv6 unicast address
+func IsPublicIPv6(ip net.IP) bool {
+	if ip == nil || ip.To4() != nil || len(ip) != net.IPv6len {
+		return false
+	}
+	if !globalUnicastIPv6.Contains(ip) {
+		return false
+	}
+	for _, block := range specialUseBlocksIPv6 {
+		if block.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsPublicAddress reports whether an address string is a public IPv4 or IPv6 address,
+// applying the rules of its own family
+func IsPublicAddress(address string) bool {
+	if ip, err := ParseAndValidate(address); err == nil {
+		return IsPublicIP(ip)
+	}
+	if ip, err := ParseAndValidateIPv6(address); err == nil {
+		return IsPublicIPv6(ip)
+	}
+	return false
+}
+
+// mustParseCIDRs parses a list of CIDR blocks, panicking on malformed input
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	blocks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, block, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(fmt.Sprintf("netutil: invalid CIDR %q: %v", cidr, err))
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
@@ -0,0 +1,142 @@
+package netutil
+
+import (
+	"net"
+	"testing"
+)
+
+func TestIsPublicIPRFC6890(t *testing.T) {
+	tests := []struct {
+		address string
+		block   string
+		public  bool
+	}{
+		{"0.0.0.1", "0.0.0.0/8", false},
+		{"10.1.2.3", "10.0.0.0/8", false},
+		{"100.64.0.1", "100.64.0.0/10", false},
+		{"100.127.255.254", "100.64.0.0/10", false},
+		{"127.0.0.1", "127.0.0.0/8", false},
+		{"169.254.10.20", "169.254.0.0/16", false},
+		{"172.16.0.1", "172.16.0.0/12", false},
+		{"172.31.255.255", "172.16.0.0/12", false},
+		{"192.0.0.8", "192.0.0.0/24", false},
+		{"192.0.2.55", "192.0.2.0/24", false},
+		{"192.88.99.1", "192.88.99.0/24", false},
+		{"192.168.1.1", "192.168.0.0/16", false},
+		{"198.18.0.1", "198.18.0.0/15", false},
+		{"198.19.255.255", "198.18.0.0/15", false},
+		{"198.51.100.7", "198.51.100.0/24", false},
+		{"203.0.113.9", "203.0.113.0/24", false},
+		{"224.0.0.251", "224.0.0.0/4", false},
+		{"239.255.255.250", "224.0.0.0/4", false},
+		{"240.0.0.1", "240.0.0.0/4", false},
+		{"255.255.255.255", "240.0.0.0/4", false}, // matched before the /32 broadcast entry
+
+		// Addresses just outside the special-use blocks are public
+		{"1.1.1.1", "", true},
+		{"8.8.8.8", "", true},
+		{"11.0.0.1", "", true},
+		{"100.63.255.255", "", true},
+		{"100.128.0.0", "", true},
+		{"172.15.255.255", "", true},
+		{"172.32.0.0", "", true},
+		{"192.0.1.1", "", true},
+		{"192.169.0.1", "", true},
+		{"198.17.255.255", "", true},
+		{"198.20.0.0", "", true},
+		{"223.255.255.255", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			ip, err := ParseAndValidate(tt.address)
+			if err != nil {
+				t.Fatalf("ParseAndValidate(%q) returned error: %v", tt.address, err)
+			}
+
+			if got := IsPublicIP(ip); got != tt.public {
+				t.Errorf("IsPublicIP(%s) = %v, want %v", tt.address, got, tt.public)
+			}
+			if got := IsReserved(ip); got == tt.public {
+				t.Errorf("IsReserved(%s) = %v, want %v", tt.address, got, !tt.public)
+			}
+
+			block := ReservedBlock(ip)
+			if tt.block == "" {
+				if block != nil {
+					t.Errorf("ReservedBlock(%s) = %s, want nil", tt.address, block)
+				}
+				return
+			}
+			if block == nil {
+				t.Fatalf("ReservedBlock(%s) = nil, want %s", tt.address, tt.block)
+			}
+			if block.String() != tt.block {
+				t.Errorf("ReservedBlock(%s) = %s, want %s", tt.address, block, tt.block)
+			}
+		})
+	}
+}
+
+func TestParseAndValidate(t *testing.T) {
+	tests := []struct {
+		address string
+		valid   bool
+	}{
+		{"8.8.8.8", true},
+		{"::ffff:8.8.8.8", true},
+		{"", false},
+		{"8.8.8", false},
+		{"8.8.8.256", false},
+		{"example.com", false},
+		{"2001:4860:4860::8888", false},
+	}
+
+	for _, tt := range tests {
+		ip, err := ParseAndValidate(tt.address)
+		if tt.valid {
+			if err != nil {
+				t.Errorf("ParseAndValidate(%q) returned error: %v", tt.address, err)
+				continue
+			}
+			if len(ip) != net.IPv4len {
+				t.Errorf("ParseAndValidate(%q) returned %d-byte address, want %d", tt.address, len(ip), net.IPv4len)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("ParseAndValidate(%q) = %s, want error", tt.address, ip)
+		}
+	}
+}
+
+func TestIsPublicIPRejectsNonIPv4(t *testing.T) {
+	for _, ip := range []net.IP{nil, net.ParseIP("2001:4860:4860::8888")} {
+		if IsPublicIP(ip) {
+			t.Errorf("IsPublicIP(%v) = true, want false", ip)
+		}
+	}
+}
+
+func TestIsPublicAddress(t *testing.T) {
+	tests := map[string]bool{
+		"8.8.8.8":              true,
+		"10.0.0.1":             false,
+		"2001:4860:4860::8888": true,
+		"2a00:1450:4001::1":    true,
+		"fd00::1":              false, // ULA
+		"fe80::1":              false, // link-local
+		"ff02::1":              false, // multicast
+		"::1":                  false, // loopback
+		"2001:db8::1":          false, // documentation
+		"2002:c000:204::1":     false, // 6to4
+		"::ffff:10.0.0.1":      false, // IPv4-mapped private address
+		"":                     false,
+		"not-an-ip":            false,
+	}
+	for address, public := range tests {
+		if got := IsPublicAddress(address); got != public {
+			t.Errorf("IsPublicAddress(%q) = %v, want %v", address, got, public)
+		}
+	}
+}
@@ -11,9 +11,13 @@ import (
 
 	"port-scanner/internal/application"
 	"port-scanner/internal/domain"
+	"port-scanner/internal/infrastructure/archive"
 	"port-scanner/internal/infrastructure/banner"
 	"port-scanner/internal/infrastructure/config"
 	"port-scanner/internal/infrastructure/database"
+	"port-scanner/internal/infrastructure/dns"
+	"port-scanner/internal/infrastructure/egress"
+	"port-scanner/internal/infrastructure/elasticsearch"
 	httphandler "port-scanner/internal/infrastructure/http"
 	"port-scanner/internal/infrastructure/queue"
 	"port-scanner/pkg/log"
@@ -36,6 +40,38 @@ func main() {
 	// Create domain services
 	scanConfig := cfg.ToDomainScanConfig()
 	scanner := domain.NewScannerService(scanConfig)
+	if err := scanner.CheckTechnique(scanConfig.Technique); err != nil {
+		log.L().Fatal("Scan technique not available", zap.String("technique", scanConfig.Technique), zap.Error(err))
+	}
+
+	// Refuse scans against this host and protected addresses
+	if scanConfig.SelfScanGuard {
+		guard, err := domain.NewScanGuard(scanConfig.ProtectedTargets)
+		if err != nil {
+			log.L().Fatal("Failed to create self-scan guard", zap.Error(err))
+		}
+		scanner.SetScanGuard(guard)
+	}
+
+	// Enrich results before they are stored and published
+	enrichers, err := buildEnrichers(cfg)
+	if err != nil {
+		log.L().Fatal("Failed to create result enrichers", zap.Error(err))
+	}
+	if enrichers.Len() > 0 {
+		scanner.SetEnrichers(enrichers)
+		log.L().Info("Result enrichers enabled", zap.Strings("enrichers", cfg.Scan.Enrichers))
+	}
+
+	// Validate the ZGrab2 binary; banner grabbing falls back to plain TCP reads without it
+	if zgrabPath, zgrabVersion, err := banner.ResolveZGrabBinary(scanConfig.ZGrabPath); err != nil {
+		log.L().Warn("ZGrab2 not available, using fallback banner grabbing", zap.Error(err))
+	} else {
+		log.L().Info("Using ZGrab2", zap.String("path", zgrabPath), zap.String("version", zgrabVersion))
+	}
+
+	// Cap ZGrab2 processes across the optimized grabber and the fallback service together
+	banner.SetMaxZGrabProcesses(scanConfig.ZGrabConcurrency)
 
 	// Create and configure optimized banner grabber with worker pool
 	bannerGrabber := banner.NewBannerGrabber(
@@ -43,12 +79,40 @@ func main() {
 		scanConfig.BannerTimeout,
 		scanConfig.PriorityPorts,
 	)
+	bannerGrabber.SetModuleSettings(scanConfig.ZGrabModules)
+	bannerGrabber.SetBinaryPath(scanConfig.ZGrabPath)
+	bannerGrabber.SetMaxBannerLength(scanConfig.MaxBannerLength)
+	bannerGrabber.SetProbePayloads(scanConfig.BannerProbes)
+	bannerGrabber.SetHTTPProbe(scanConfig.HTTPProbe)
+	bannerGrabber.SetHostConcurrency(scanConfig.BannerConcurrencyPerHost)
 	scanner.SetBannerGrabber(bannerGrabber)
 
 	// Create and configure ZGrab2 banner service as fallback
 	bannerService := banner.NewZGrabBannerService(scanConfig.BannerTimeout)
+	bannerService.SetModuleSettings(scanConfig.ZGrabModules)
+	bannerService.SetBinaryPath(scanConfig.ZGrabPath)
+	bannerService.SetMaxBannerLength(scanConfig.MaxBannerLength)
+	bannerService.SetProbePayloads(scanConfig.BannerProbes)
+	bannerService.SetHTTPProbe(scanConfig.HTTPProbe)
+	bannerService.SetFallbackTimeouts(scanConfig.BannerConnectTimeout, scanConfig.BannerReadTimeout)
+	bannerService.SetHostConcurrency(scanConfig.BannerConcurrencyPerHost)
+	bannerService.SetConnCache(scanner.ConnCache())
 	scanner.SetBannerGrabber(bannerService)
 
+	// Create SNMP prober for UDP 161 if enabled
+	if scanConfig.EnableSNMP {
+		snmpProbe, err := banner.NewSNMPProbe(scanConfig.SNMPCommunities, scanConfig.SNMPVersion, scanConfig.BannerTimeout)
+		if err != nil {
+			log.L().Fatal("Failed to create SNMP probe", zap.Error(err))
+		}
+		scanner.SetSNMPProber(snmpProbe)
+	}
+
+	// Probe mail ports for STARTTLS and read the certificate offered after the upgrade
+	if scanConfig.EnableStartTLS {
+		scanner.SetStartTLSProber(banner.NewStartTLSProbe(scanConfig.StartTLSTimeout))
+	}
+
 	// Create MongoDB manager if enabled
 	var dbManager *database.MongoDBManager
 	if cfg.MongoDB.EnableDatabase {
@@ -61,6 +125,13 @@ func main() {
 			log.L().Error("Failed to connect to MongoDB", zap.Error(err))
 			log.L().Warn("Continuing without MongoDB - results will not be persisted")
 		} else {
+			dbManager.SetCompression(cfg.MongoDB.EnableCompression, cfg.MongoDB.CompressionMinSize)
+			dbManager.SetMaxBannerLength(scanConfig.MaxBannerLength)
+			if err := dbManager.SetWriteMode(cfg.MongoDB.WriteMode, cfg.MongoDB.UpdateFields); err != nil {
+				log.L().Fatal("Invalid MongoDB write mode", zap.Error(err))
+			}
+			dedupWindow, _ := time.ParseDuration(cfg.MongoDB.DedupWindow)
+			dbManager.SetDedupWindow(dedupWindow)
 			log.L().Info("MongoDB connected successfully",
 				zap.String("database", cfg.MongoDB.DatabaseName),
 				zap.String("collection", cfg.MongoDB.CollectionName))
@@ -69,27 +140,155 @@ func main() {
 	}
 
 	// Create queue manager
+	confirmTimeout, _ := time.ParseDuration(cfg.RabbitMQ.ConfirmTimeout)
 	queueManager, err := queue.NewRabbitMQManager(
 		cfg.RabbitMQ.URL,
 		cfg.RabbitMQ.IPQueue,
 		cfg.RabbitMQ.ScanResultQueue,
 		cfg.RabbitMQ.EnrichmentQueue,
 		cfg.RabbitMQ.ServiceAnalysisQueue,
+		queue.QueueOptions{
+			IPQueueMaxPriority: cfg.RabbitMQ.IPQueueMaxPriority,
+			PrefetchCount:      cfg.RabbitMQ.PrefetchCount,
+			MaxIPsPerMessage:   cfg.RabbitMQ.MaxIPsPerMessage,
+			OversizedAction:    cfg.RabbitMQ.OversizedAction,
+			DeadLetterQueue:    cfg.RabbitMQ.DeadLetterQueue,
+			ChangesQueue:       cfg.RabbitMQ.ChangesQueue,
+			BatchCompleteQueue: cfg.RabbitMQ.BatchCompleteQueue,
+			ConfirmTimeout:     confirmTimeout,
+			WorkerID:           cfg.RabbitMQ.WorkerID,
+			MessageFormat:      cfg.RabbitMQ.MessageFormat,
+
+			Exchange:                  cfg.RabbitMQ.Exchange,
+			ExchangeType:              cfg.RabbitMQ.ExchangeType,
+			ResultRoutingKey:          cfg.RabbitMQ.ResultRoutingKey,
+			EnrichmentRoutingKey:      cfg.RabbitMQ.EnrichmentRoutingKey,
+			ServiceAnalysisRoutingKey: cfg.RabbitMQ.ServiceAnalysisRoutingKey,
+		},
 	)
 	if err != nil {
 		log.L().Fatal("Failed to create queue manager", zap.Error(err))
 	}
 	defer queueManager.Close()
+	log.L().Info("Worker ID assigned", zap.String("worker_id", queueManager.WorkerID()))
 
-	// Configure queue manager with scan handler, config, and MongoDB
-	queueManager.SetScanHandler(scanner.ScanIP)
+	// Select the result sink
+	var resultSink domain.ResultSink
+	switch cfg.Sink.Type {
+	case "elasticsearch":
+		flushInterval, _ := time.ParseDuration(cfg.Elasticsearch.FlushInterval)
+		esSink, err := elasticsearch.NewSink(elasticsearch.Config{
+			Addresses:     cfg.Elasticsearch.Addresses,
+			Username:      cfg.Elasticsearch.Username,
+			Password:      cfg.Elasticsearch.Password,
+			Index:         cfg.Elasticsearch.Index,
+			FlushBytes:    cfg.Elasticsearch.FlushBytes,
+			FlushInterval: flushInterval,
+		})
+		if err != nil {
+			log.L().Fatal("Failed to create Elasticsearch sink", zap.Error(err))
+		}
+		defer esSink.Close()
+		resultSink = esSink
+	case "mongodb", "":
+		if dbManager != nil {
+			resultSink = dbManager
+		}
+	default:
+		log.L().Fatal("Unknown result sink", zap.String("sink", cfg.Sink.Type))
+	}
+
+	// Configure queue manager with config and result sink
 	queueManager.SetScanConfig(scanConfig)
-	if dbManager != nil {
-		queueManager.SetMongoDBManager(dbManager)
+	if resultSink != nil {
+		queueManager.SetResultSink(resultSink)
+	}
+	// Changes are detected against the previous result, so history must come from where results are saved
+	if dbManager != nil && resultSink == domain.ResultSink(dbManager) {
+		queueManager.SetScanHistory(dbManager)
+	}
+	if dbManager != nil && cfg.MongoDB.EnableBatchClaims {
+		claimStaleAfter, _ := time.ParseDuration(cfg.MongoDB.ClaimStaleAfter)
+		heartbeatInterval, _ := time.ParseDuration(cfg.MongoDB.ClaimHeartbeatInterval)
+		if heartbeatInterval <= 0 {
+			heartbeatInterval = 30 * time.Second
+		}
+		dbManager.SetClaimStaleAfter(claimStaleAfter)
+		queueManager.SetBatchClaimer(dbManager, heartbeatInterval)
+	}
+	outboxRetryInterval, _ := time.ParseDuration(cfg.RabbitMQ.OutboxRetryInterval)
+	if dbManager != nil && cfg.MongoDB.EnableOutbox {
+		outboxAdoptAfter, _ := time.ParseDuration(cfg.MongoDB.OutboxAdoptAfter)
+		dbManager.SetOutboxAdoptAfter(outboxAdoptAfter)
+		queueManager.SetOutboxStore(dbManager, outboxRetryInterval)
+	} else {
+		queueManager.SetOutboxRetryInterval(outboxRetryInterval)
+	}
+
+	// Serve deterministic synthetic results instead of scanning when the mock scanner is selected
+	var engineScanner domain.Scanner = scanner
+	if cfg.Scan.MockScanner.Enabled {
+		mockConfig, err := cfg.ToDomainMockScannerConfig()
+		if err != nil {
+			log.L().Fatal("Invalid mock scanner configuration", zap.Error(err))
+		}
+		mockScanner := domain.NewMockScanner(scanConfig, mockConfig)
+		mockScanner.SetEnrichers(enrichers)
+		engineScanner = mockScanner
+		log.L().Warn("Mock scanner enabled, results are synthetic and no packets are sent",
+			zap.Int64("seed", mockConfig.Seed), zap.Float64("open_ratio", mockConfig.OpenRatio))
 	}
 
 	// Create application services
-	scanEngine := application.NewScanEngineService(scanner, queueManager, scanConfig)
+	scanEngine := application.NewScanEngineService(engineScanner, queueManager, scanConfig)
+
+	// Scan queued IPs through the engine so their results can be looked up by status
+	queueManager.SetScanHandler(scanEngine.ScanIP)
+	// Queued scans of every delivery count against the engine's concurrency, like API scans
+	queueManager.SetScanSlots(scanEngine.AcquireScanSlot)
+
+	// Announce generated batches once all their messages were scanned. MongoDB lets workers
+	// sharing the IP queue track batches together; otherwise each worker tracks its own messages.
+	if queueManager.BatchCompleteQueue() != "" {
+		var tracker domain.BatchTracker
+		if dbManager != nil {
+			tracker = dbManager
+		} else {
+			trackingTTL, _ := time.ParseDuration(cfg.RabbitMQ.BatchTrackingTTL)
+			tracker = application.NewMemoryBatchTracker(trackingTTL)
+		}
+		scanEngine.SetBatchTracking(tracker, queueManager, queueManager.WorkerID())
+		queueManager.SetMessageHandler(scanEngine.CompleteMessage)
+	}
+
+	// Analyze the services found on each host
+	analyzers, err := buildAnalyzers(cfg)
+	if err != nil {
+		log.L().Fatal("Failed to create service analyzers", zap.Error(err))
+	}
+	if analyzers.Len() > 0 {
+		scanEngine.SetAnalyzers(analyzers)
+		if dbManager != nil {
+			scanEngine.SetAnalysisStore(dbManager)
+		}
+		log.L().Info("Service analyzers enabled", zap.Strings("analyzers", cfg.Scan.Analyzers))
+	}
+
+	// Spill results evicted from memory so their status can still be looked up
+	switch cfg.Scan.RecentResultsSpill {
+	case "disk":
+		diskArchive, err := archive.NewDiskArchive(cfg.Scan.RecentResultsSpillDir)
+		if err != nil {
+			log.L().Fatal("Failed to create result archive", zap.Error(err))
+		}
+		scanEngine.SetResultArchive(diskArchive)
+	case "mongodb":
+		// Results are already stored when MongoDB is the sink, so lookups read them back
+		if dbManager == nil || resultSink != domain.ResultSink(dbManager) {
+			log.L().Fatal("Spilling recent results to MongoDB requires the mongodb sink")
+		}
+		scanEngine.SetResultArchive(dbManager)
+	}
 
 	// Start the scanning engine
 	if err := scanEngine.StartScanning(); err != nil {
@@ -104,7 +303,21 @@ func main() {
 	router.Use(gin.Recovery())
 
 	// Create and register HTTP handlers
-	httpHandler := httphandler.NewHandler(scanEngine, scanner, dbManager)
+	var store httphandler.Store
+	if dbManager != nil {
+		store = dbManager
+	}
+	httpHandler := httphandler.NewHandler(scanEngine, engineScanner, store)
+	if cfg.Server.APIKey == "" {
+		log.L().Warn("No API key configured, maintenance endpoints are unprotected")
+	}
+	httpHandler.SetAPIKey(cfg.Server.APIKey)
+	httpHandler.SetResultReplayer(queueManager)
+	httpHandler.SetScanEnqueuer(queueManager)
+	httpHandler.SetWorkerID(queueManager.WorkerID())
+	scanCacheTTL, _ := time.ParseDuration(cfg.Server.ScanCacheTTL)
+	httpHandler.SetScanCacheTTL(scanCacheTTL)
+	httpHandler.SetMaxBatchResults(cfg.MongoDB.MaxBatchResults)
 	httpHandler.RegisterRoutes(router)
 
 	// Create HTTP server
@@ -142,3 +355,63 @@ func main() {
 
 	log.L().Info("Server exited")
 }
+
+// buildEnrichers creates the result enrichers named in scan.enrichers in the configured order
+func buildEnrichers(cfg *config.Config) (*domain.EnricherChain, error) {
+	var enrichers []domain.Enricher
+	for _, name := range cfg.Scan.Enrichers {
+		switch name {
+		case dns.ReverseDNSEnricherName:
+			timeout, _ := time.ParseDuration(cfg.Scan.ReverseDNSTimeout)
+			if timeout <= 0 {
+				timeout = 2 * time.Second
+			}
+			cacheTTL, _ := time.ParseDuration(cfg.Scan.ReverseDNSCacheTTL)
+			enrichers = append(enrichers, dns.NewReverseDNSEnricher(timeout, cfg.Scan.ReverseDNSConcurrency, cacheTTL))
+		case egress.SourceIPEnricherName:
+			resolver, err := egress.NewResolver(cfg.Scan.SourceIPMethod, cfg.Scan.SourceIPServer)
+			if err != nil {
+				return nil, err
+			}
+			timeout, _ := time.ParseDuration(cfg.Scan.SourceIPTimeout)
+			if timeout <= 0 {
+				timeout = 5 * time.Second
+			}
+			cacheTTL, _ := time.ParseDuration(cfg.Scan.SourceIPCacheTTL)
+			enricher := egress.NewSourceIPEnricher(resolver, timeout, cacheTTL)
+
+			// Look the address up at startup so a misconfigured service shows up right away
+			if ip, err := enricher.SourceIP(); err != nil {
+				log.L().Warn("External IP not found, will retry on later results", zap.String("event", "source_ip_failed"), zap.Error(err))
+			} else {
+				log.L().Info("External IP found", zap.String("event", "source_ip_found"), zap.String("source_ip", ip))
+			}
+			enrichers = append(enrichers, enricher)
+		case domain.HoneypotEnricherName:
+			enrichers = append(enrichers, domain.HoneypotEnricher{})
+		default:
+			return nil, fmt.Errorf("unknown enricher %q", name)
+		}
+	}
+	return domain.NewEnricherChain(enrichers...), nil
+}
+
+// buildAnalyzers registers the service analyzers named in scan.analyzers
+func buildAnalyzers(cfg *config.Config) (*application.AnalyzerRegistry, error) {
+	registry := application.NewAnalyzerRegistry()
+	for _, name := range cfg.Scan.Analyzers {
+		switch name {
+		case application.RedisNoAuthAnalyzerName:
+			registry.Register(application.RedisNoAuthAnalyzer{})
+		case application.OutdatedOpenSSHAnalyzerName:
+			analyzer, err := application.NewOutdatedOpenSSHAnalyzer(cfg.Scan.MinOpenSSHVersion)
+			if err != nil {
+				return nil, err
+			}
+			registry.Register(analyzer)
+		default:
+			return nil, fmt.Errorf("unknown analyzer %q", name)
+		}
+	}
+	return registry, nil
+}
@@ -0,0 +1,191 @@
+package application
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"port-scanner/internal/domain"
+)
+
+// Ensure the built-in analyzers implement ServiceAnalyzer interface
+var (
+	_ domain.ServiceAnalyzer = RedisNoAuthAnalyzer{}
+	_ domain.ServiceAnalyzer = (*OutdatedOpenSSHAnalyzer)(nil)
+)
+
+// Names used to enable the built-in analyzers in scan.analyzers
+const (
+	RedisNoAuthAnalyzerName     = "redis_no_auth"
+	OutdatedOpenSSHAnalyzerName = "outdated_openssh"
+)
+
+// AnalyzerRegistry runs the registered service analyzers against the open ports of a result
+type AnalyzerRegistry struct {
+	byService map[string][]domain.ServiceAnalyzer
+	all       []domain.ServiceAnalyzer
+}
+
+// NewAnalyzerRegistry creates an empty registry
+func NewAnalyzerRegistry() *AnalyzerRegistry {
+	return &AnalyzerRegistry{byService: make(map[string][]domain.ServiceAnalyzer)}
+}
+
+// Register adds an analyzer for the services it declares. Analyzers run in registration order.
+func (r *AnalyzerRegistry) Register(analyzer domain.ServiceAnalyzer) {
+	for _, service := range analyzer.Services() {
+		if service == "*" {
+			r.all = append(r.all, analyzer)
+			continue
+		}
+		service = strings.ToLower(service)
+		r.byService[service] = append(r.byService[service], analyzer)
+	}
+}
+
+// Len returns the number of registrations; a nil registry has none
+func (r *AnalyzerRegistry) Len() int {
+	if r == nil {
+		return 0
+	}
+	count := len(r.all)
+	for _, analyzers := range r.byService {
+		count += len(analyzers)
+	}
+	return count
+}
+
+// Analyze runs the analyzers matching each open port's service and records their findings on
+// the result. It is a no-op on a nil registry.
+func (r *AnalyzerRegistry) Analyze(result *domain.ScanResult) []domain.Finding {
+	if r == nil {
+		return nil
+	}
+
+	var findings []domain.Finding
+	for _, port := range result.GetOpenPorts() {
+		matching := r.byService[strings.ToLower(port.Service)]
+		analyzers := make([]domain.ServiceAnalyzer, 0, len(matching)+len(r.all))
+		analyzers = append(append(analyzers, matching...), r.all...)
+		for _, analyzer := range analyzers {
+			for _, finding := range analyzer.Analyze(result.IP, port) {
+				if finding.Analyzer == "" {
+					finding.Analyzer = analyzer.Name()
+				}
+				if finding.Port == 0 {
+					finding.Port = port.Number
+				}
+				if finding.Service == "" {
+					finding.Service = port.Service
+				}
+				findings = append(findings, finding)
+			}
+		}
+	}
+
+	result.Findings = findings
+	return findings
+}
+
+// portBanner returns everything the banner grab learned about a port, for text matching
+func portBanner(port *domain.Port) string {
+	banner := port.Banner
+	if port.BannerInfo != nil && port.BannerInfo.RawBanner != port.Banner {
+		banner += "\n" + port.BannerInfo.RawBanner
+	}
+	return banner
+}
+
+// RedisNoAuthAnalyzer flags Redis servers that answer commands without authentication
+type RedisNoAuthAnalyzer struct{}
+
+// Name implements ServiceAnalyzer
+func (RedisNoAuthAnalyzer) Name() string {
+	return RedisNoAuthAnalyzerName
+}
+
+// Services implements ServiceAnalyzer
+func (RedisNoAuthAnalyzer) Services() []string {
+	return []string{"redis"}
+}
+
+// Analyze reports a Redis server whose banner shows a command reply rather than an auth error
+func (RedisNoAuthAnalyzer) Analyze(ip string, port *domain.Port) []domain.Finding {
+	banner := strings.ToLower(portBanner(port))
+	if strings.Contains(banner, "noauth") || strings.Contains(banner, "authentication required") {
+		return nil
+	}
+	if !strings.Contains(banner, "pong") && !strings.Contains(banner, "redis_version") {
+		return nil
+	}
+
+	return []domain.Finding{{
+		Severity: domain.SeverityHigh,
+		Title:    "Redis accepts commands without authentication",
+		Detail:   "Anyone who can reach the port can read and modify data and may be able to write files on the host",
+	}}
+}
+
+// openSSHVersionPattern matches the OpenSSH release in an SSH identification string
+var openSSHVersionPattern = regexp.MustCompile(`(?i)OpenSSH[_ ]([0-9]+)\.([0-9]+)`)
+
+// OutdatedOpenSSHAnalyzer flags OpenSSH servers older than a minimum release
+type OutdatedOpenSSHAnalyzer struct {
+	minMajor, minMinor int
+}
+
+// NewOutdatedOpenSSHAnalyzer creates an analyzer flagging releases older than minVersion, e.g. "9.8"
+func NewOutdatedOpenSSHAnalyzer(minVersion string) (*OutdatedOpenSSHAnalyzer, error) {
+	major, minor, ok := parseMajorMinor(minVersion)
+	if !ok {
+		return nil, fmt.Errorf("invalid minimum OpenSSH version %q", minVersion)
+	}
+	return &OutdatedOpenSSHAnalyzer{minMajor: major, minMinor: minor}, nil
+}
+
+// Name implements ServiceAnalyzer
+func (a *OutdatedOpenSSHAnalyzer) Name() string {
+	return OutdatedOpenSSHAnalyzerName
+}
+
+// Services implements ServiceAnalyzer
+func (a *OutdatedOpenSSHAnalyzer) Services() []string {
+	return []string{"ssh"}
+}
+
+// Analyze reports an OpenSSH release below the minimum version
+func (a *OutdatedOpenSSHAnalyzer) Analyze(ip string, port *domain.Port) []domain.Finding {
+	match := openSSHVersionPattern.FindStringSubmatch(portBanner(port))
+	if match == nil {
+		return nil
+	}
+	major, _ := strconv.Atoi(match[1])
+	minor, _ := strconv.Atoi(match[2])
+	if major > a.minMajor || (major == a.minMajor && minor >= a.minMinor) {
+		return nil
+	}
+
+	return []domain.Finding{{
+		Severity: domain.SeverityMedium,
+		Title:    fmt.Sprintf("Outdated OpenSSH %d.%d", major, minor),
+		Detail:   fmt.Sprintf("Releases before %d.%d have published vulnerabilities", a.minMajor, a.minMinor),
+	}}
+}
+
+// parseMajorMinor parses a "major.minor" version
+func parseMajorMinor(version string) (int, int, bool) {
+	majorText, minorText, ok := strings.Cut(version, ".")
+	if !ok {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(majorText)
+	if err != nil {
+		return 0, 0, false
+	}
+	minor, err := strconv.Atoi(minorText)
+	if err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
@@ -0,0 +1,165 @@
+package application
+
+import (
+	"testing"
+
+	"port-scanner/internal/domain"
+	"port-scanner/internal/testutil"
+)
+
+// recordingAnalysisStore keeps the results passed to SaveServiceAnalysis
+type recordingAnalysisStore struct {
+	saved []*domain.ScanResult
+}
+
+func (s *recordingAnalysisStore) SaveServiceAnalysis(result *domain.ScanResult) error {
+	s.saved = append(s.saved, result)
+	return nil
+}
+
+// openPort returns an open port identified as service with the given banner
+func openPort(number int, service, banner string) *domain.Port {
+	port := domain.NewPort(number)
+	port.Status = domain.PortStatusOpen
+	port.Service = service
+	port.Banner = banner
+	return port
+}
+
+func newTestRegistry(t *testing.T) *AnalyzerRegistry {
+	t.Helper()
+	ssh, err := NewOutdatedOpenSSHAnalyzer("9.8")
+	if err != nil {
+		t.Fatalf("NewOutdatedOpenSSHAnalyzer returned error: %v", err)
+	}
+	registry := NewAnalyzerRegistry()
+	registry.Register(RedisNoAuthAnalyzer{})
+	registry.Register(ssh)
+	return registry
+}
+
+func TestAnalyzersFireForMatchingServices(t *testing.T) {
+	result := domain.NewScanResult("203.0.113.5", "batch-1", "worker-1")
+	result.AddPort(openPort(6379, "redis", "+PONG"))
+	result.AddPort(openPort(22, "ssh", "SSH-2.0-OpenSSH_7.4"))
+	result.AddPort(openPort(80, "http", "HTTP/1.1 200 OK\r\nServer: OpenSSH_7.4"))
+
+	findings := newTestRegistry(t).Analyze(result)
+	if len(findings) != 2 {
+		t.Fatalf("got %d findings, want 2: %+v", len(findings), findings)
+	}
+	if len(result.Findings) != 2 {
+		t.Errorf("result holds %d findings, want the 2 reported", len(result.Findings))
+	}
+
+	want := []struct {
+		analyzer, service, severity string
+		port                        int
+	}{
+		{RedisNoAuthAnalyzerName, "redis", domain.SeverityHigh, 6379},
+		{OutdatedOpenSSHAnalyzerName, "ssh", domain.SeverityMedium, 22},
+	}
+	for i, w := range want {
+		got := findings[i]
+		if got.Analyzer != w.analyzer || got.Service != w.service || got.Severity != w.severity || got.Port != w.port {
+			t.Errorf("finding %d = %+v, want %s on %s/%d with severity %s", i, got, w.analyzer, w.service, w.port, w.severity)
+		}
+		if got.Title == "" || got.Detail == "" {
+			t.Errorf("finding %d has no title or detail: %+v", i, got)
+		}
+	}
+}
+
+func TestAnalyzersIgnoreHealthyServices(t *testing.T) {
+	result := domain.NewScanResult("203.0.113.5", "batch-1", "worker-1")
+	result.AddPort(openPort(6379, "redis", "-NOAUTH Authentication required."))
+	result.AddPort(openPort(22, "ssh", "SSH-2.0-OpenSSH_9.8p1"))
+	result.AddPort(openPort(2222, "ssh", "SSH-2.0-dropbear_2022.83"))
+
+	closed := domain.NewPort(6380)
+	closed.Service = "redis"
+	closed.Banner = "+PONG"
+	result.AddPort(closed)
+
+	if findings := newTestRegistry(t).Analyze(result); len(findings) != 0 {
+		t.Errorf("got findings for healthy services: %+v", findings)
+	}
+}
+
+func TestOutdatedOpenSSHAnalyzerVersions(t *testing.T) {
+	analyzer, err := NewOutdatedOpenSSHAnalyzer("8.9")
+	if err != nil {
+		t.Fatalf("NewOutdatedOpenSSHAnalyzer returned error: %v", err)
+	}
+
+	tests := []struct {
+		banner  string
+		flagged bool
+	}{
+		{"SSH-2.0-OpenSSH_7.9p1 Debian-10", true},
+		{"SSH-2.0-OpenSSH_8.8", true},
+		{"SSH-2.0-OpenSSH_8.9p1 Ubuntu-3", false},
+		{"SSH-2.0-OpenSSH_10.0", false},
+		{"SSH-2.0-libssh_0.9.6", false},
+	}
+	for _, tt := range tests {
+		findings := analyzer.Analyze("203.0.113.5", openPort(22, "ssh", tt.banner))
+		if flagged := len(findings) > 0; flagged != tt.flagged {
+			t.Errorf("Analyze(%q) flagged = %v, want %v", tt.banner, flagged, tt.flagged)
+		}
+	}
+
+	for _, version := range []string{"", "9", "nine.eight", "9.x"} {
+		if _, err := NewOutdatedOpenSSHAnalyzer(version); err == nil {
+			t.Errorf("NewOutdatedOpenSSHAnalyzer(%q) accepted an invalid version", version)
+		}
+	}
+}
+
+func TestScanIPRunsAnalyzersAndStoresAnalysis(t *testing.T) {
+	scanner := testutil.NewFakeScanner()
+	canned := scanner.AddOpenPorts("203.0.113.5", 6379)
+	canned.Ports[0].Service = "redis"
+	canned.Ports[0].Banner = "+PONG"
+	scanner.AddOpenPorts("203.0.113.6")
+
+	store := &recordingAnalysisStore{}
+	engine := NewScanEngineService(scanner, nil, domain.NewDefaultScanConfig())
+	engine.SetAnalyzers(newTestRegistry(t))
+	engine.SetAnalysisStore(store)
+
+	result, err := engine.ScanIP("203.0.113.5", engine.ScanConfig(), "batch-1", "worker-1")
+	if err != nil {
+		t.Fatalf("ScanIP returned error: %v", err)
+	}
+	if len(result.Findings) != 1 || result.Findings[0].Analyzer != RedisNoAuthAnalyzerName {
+		t.Errorf("result findings = %+v, want the redis_no_auth finding", result.Findings)
+	}
+
+	// Hosts without open ports are neither analyzed nor stored
+	if _, err := engine.ScanIP("203.0.113.6", engine.ScanConfig(), "batch-1", "worker-1"); err != nil {
+		t.Fatalf("ScanIP returned error: %v", err)
+	}
+	if len(store.saved) != 1 || store.saved[0].IP != "203.0.113.5" {
+		t.Errorf("stored analysis for %d hosts, want only 203.0.113.5", len(store.saved))
+	}
+}
+
+func TestScanIPWithoutAnalyzersReportsNoFindings(t *testing.T) {
+	scanner := testutil.NewFakeScanner()
+	canned := scanner.AddOpenPorts("203.0.113.5", 6379)
+	canned.Ports[0].Service = "redis"
+	canned.Ports[0].Banner = "+PONG"
+
+	store := &recordingAnalysisStore{}
+	engine := NewScanEngineService(scanner, nil, domain.NewDefaultScanConfig())
+	engine.SetAnalysisStore(store)
+
+	result, err := engine.ScanIP("203.0.113.5", engine.ScanConfig(), "batch-1", "worker-1")
+	if err != nil {
+		t.Fatalf("ScanIP returned error: %v", err)
+	}
+	if len(result.Findings) != 0 || len(store.saved) != 0 {
+		t.Errorf("analyzers ran while disabled: %d findings, %d stored", len(result.Findings), len(store.saved))
+	}
+}
@@ -0,0 +1,156 @@
+package application
+
+import (
+	"sync"
+	"time"
+
+	"port-scanner/internal/domain"
+	"port-scanner/pkg/log"
+
+	"go.uber.org/zap"
+)
+
+// Ensure MemoryBatchTracker implements BatchTracker interface
+var _ domain.BatchTracker = (*MemoryBatchTracker)(nil)
+
+// batchState is the progress of one batch
+type batchState struct {
+	summary   domain.BatchCompleteMessage
+	sequences map[int]bool
+	final     int // Sequence of the final message; -1 until it is recorded
+	notified  bool
+	updated   time.Time
+}
+
+// MemoryBatchTracker tracks batch completion in memory. It only sees the messages this worker
+// processes, so it suits a single consumer; workers sharing a queue need a shared tracker.
+type MemoryBatchTracker struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	batches map[string]*batchState
+}
+
+// NewMemoryBatchTracker creates a tracker that forgets batches not updated for ttl, so batches
+// whose messages never all arrive don't accumulate
+func NewMemoryBatchTracker(ttl time.Duration) *MemoryBatchTracker {
+	return &MemoryBatchTracker{ttl: ttl, batches: make(map[string]*batchState)}
+}
+
+// RecordMessage implements BatchTracker
+func (t *MemoryBatchTracker) RecordMessage(progress *domain.BatchProgress) (*domain.BatchCompleteMessage, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	t.prune(now)
+
+	state, ok := t.batches[progress.BatchID]
+	if !ok {
+		state = &batchState{
+			summary:   domain.BatchCompleteMessage{BatchID: progress.BatchID, StartedAt: progress.StartedAt},
+			sequences: make(map[int]bool),
+			final:     -1,
+		}
+		t.batches[progress.BatchID] = state
+	}
+	state.updated = now
+
+	// A redelivered message was already counted
+	if state.notified || state.sequences[progress.Sequence] {
+		return nil, nil
+	}
+	state.sequences[progress.Sequence] = true
+	if progress.Final {
+		state.final = progress.Sequence
+	}
+
+	summary := &state.summary
+	summary.Messages++
+	summary.IPsScanned += progress.IPs
+	summary.HostsUp += progress.HostsUp
+	summary.Failed += progress.Failed
+	summary.OpenPorts += progress.OpenPorts
+	if progress.StartedAt.Before(summary.StartedAt) {
+		summary.StartedAt = progress.StartedAt
+	}
+	if progress.EndedAt.After(summary.CompletedAt) {
+		summary.CompletedAt = progress.EndedAt
+	}
+
+	if state.final < 0 || len(state.sequences) != state.final+1 {
+		return nil, nil
+	}
+
+	// Keep the batch as notified until it expires so late redeliveries don't announce it again
+	state.notified = true
+	state.sequences = nil
+	complete := *summary
+	return &complete, nil
+}
+
+// prune forgets batches that haven't been updated within the TTL
+func (t *MemoryBatchTracker) prune(now time.Time) {
+	if t.ttl <= 0 {
+		return
+	}
+	for batchID, state := range t.batches {
+		if now.Sub(state.updated) <= t.ttl {
+			continue
+		}
+		if !state.notified {
+			log.L().Warn("Dropping incomplete batch", zap.String("event", "batch_tracking_expired"),
+				zap.String("batch_id", batchID), zap.Int("messages", state.summary.Messages), zap.Int("final_sequence", state.final))
+		}
+		delete(t.batches, batchID)
+	}
+}
+
+// SetBatchTracking enables batch completion notifications: every processed queue message is
+// recorded in the tracker, and each completed batch is announced once through the publisher
+func (s *ScanEngineService) SetBatchTracking(tracker domain.BatchTracker, publisher domain.BatchCompletePublisher, workerID string) {
+	s.batches = tracker
+	s.batchPublisher = publisher
+	s.workerID = workerID
+}
+
+// CompleteMessage records a fully processed queue message with the results of its IPs, and
+// announces its batch if this message completes it. It is the queue manager's message handler.
+func (s *ScanEngineService) CompleteMessage(message *domain.QueueMessage, results []*domain.ScanResult, startedAt time.Time) {
+	if s.batches == nil {
+		return
+	}
+
+	progress := message.Progress()
+	progress.IPs = len(results)
+	progress.StartedAt = startedAt
+	progress.EndedAt = time.Now()
+	for _, result := range results {
+		if result.Status == domain.ScanStatusFailed {
+			progress.Failed++
+		}
+		if result.IsUp {
+			progress.HostsUp++
+		}
+		progress.OpenPorts += len(result.GetOpenPorts())
+	}
+
+	complete, err := s.batches.RecordMessage(progress)
+	if err != nil {
+		log.L().Error("Failed to record batch progress", zap.String("event", "batch_progress_failed"),
+			zap.String("batch_id", progress.BatchID), zap.Int("sequence", progress.Sequence), zap.Error(err))
+		return
+	}
+	if complete == nil {
+		return
+	}
+
+	complete.WorkerID = s.workerID
+	log.L().Info("Batch complete", zap.String("event", "batch_complete"), zap.String("batch_id", complete.BatchID),
+		zap.Int("messages", complete.Messages), zap.Int("ips_scanned", complete.IPsScanned), zap.Int("hosts_up", complete.HostsUp))
+	if s.batchPublisher != nil {
+		if err := s.batchPublisher.PublishBatchComplete(complete); err != nil {
+			log.L().Error("Failed to publish batch completion", zap.String("event", "publish_batch_complete_failed"),
+				zap.String("batch_id", complete.BatchID), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package application
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"port-scanner/internal/domain"
+	"port-scanner/internal/testutil"
+)
+
+// recordingBatchPublisher keeps the batch completions it is asked to publish
+type recordingBatchPublisher struct {
+	mu        sync.Mutex
+	completed []*domain.BatchCompleteMessage
+}
+
+func (p *recordingBatchPublisher) PublishBatchComplete(message *domain.BatchCompleteMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.completed = append(p.completed, message)
+	return nil
+}
+
+func (p *recordingBatchPublisher) published() []*domain.BatchCompleteMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*domain.BatchCompleteMessage(nil), p.completed...)
+}
+
+func newBatchTrackingEngine() (*ScanEngineService, *recordingBatchPublisher) {
+	engine := NewScanEngineService(testutil.NewFakeScanner(), nil, domain.NewDefaultScanConfig())
+	publisher := &recordingBatchPublisher{}
+	engine.SetBatchTracking(NewMemoryBatchTracker(time.Hour), publisher, "worker-1")
+	return engine, publisher
+}
+
+// upResults returns completed results for the IPs of a message, each with one open port
+func upResults(message *domain.QueueMessage) []*domain.ScanResult {
+	results := make([]*domain.ScanResult, 0, len(message.IPs))
+	for _, ip := range message.IPs {
+		result := domain.NewScanResult(ip, message.BatchID, "worker-1")
+		result.IsUp = true
+		port := domain.NewPort(443)
+		port.Status = domain.PortStatusOpen
+		result.AddPort(port)
+		result.SetCompleted()
+		results = append(results, result)
+	}
+	return results
+}
+
+func TestCompleteMessageAnnouncesBatchOnceAllMessagesFinish(t *testing.T) {
+	engine, publisher := newBatchTrackingEngine()
+	ips := []string{"8.8.8.1", "8.8.8.2", "8.8.8.3", "8.8.8.4", "8.8.8.5", "8.8.8.6", "8.8.8.7"}
+	messages := domain.NewBatchMessages("batch-1", ips, nil, 3)
+	if len(messages) != 3 {
+		t.Fatalf("split into %d messages, want 3", len(messages))
+	}
+
+	// The final message finishes before the others, which must not complete the batch
+	for _, i := range []int{2, 0} {
+		engine.CompleteMessage(messages[i], upResults(messages[i]), time.Now())
+		if got := publisher.published(); len(got) != 0 {
+			t.Fatalf("batch announced after message %d with messages outstanding: %+v", i, got[0])
+		}
+	}
+
+	engine.CompleteMessage(messages[1], upResults(messages[1]), time.Now())
+	got := publisher.published()
+	if len(got) != 1 {
+		t.Fatalf("batch announced %d times, want once", len(got))
+	}
+	complete := got[0]
+	if complete.BatchID != "batch-1" || complete.Messages != 3 || complete.IPsScanned != 7 ||
+		complete.HostsUp != 7 || complete.OpenPorts != 7 || complete.Failed != 0 || complete.WorkerID != "worker-1" {
+		t.Errorf("completion = %+v, want batch-1 with 3 messages, 7 IPs, hosts and open ports from worker-1", complete)
+	}
+
+	// Redeliveries of any message after completion don't announce the batch again
+	for _, message := range messages {
+		engine.CompleteMessage(message, upResults(message), time.Now())
+	}
+	if got := publisher.published(); len(got) != 1 {
+		t.Errorf("batch announced %d times after redeliveries, want once", len(got))
+	}
+}
+
+func TestCompleteMessageIgnoresRedeliveredSubBatch(t *testing.T) {
+	engine, publisher := newBatchTrackingEngine()
+	messages := domain.NewBatchMessages("batch-1", []string{"8.8.8.1", "8.8.8.2", "8.8.8.3"}, nil, 1)
+
+	// Message 0 is delivered twice before the rest, which must not stand in for message 1
+	engine.CompleteMessage(messages[0], upResults(messages[0]), time.Now())
+	engine.CompleteMessage(messages[0], upResults(messages[0]), time.Now())
+	engine.CompleteMessage(messages[2], upResults(messages[2]), time.Now())
+	if got := publisher.published(); len(got) != 0 {
+		t.Fatalf("batch announced before message 1 finished: %+v", got[0])
+	}
+
+	engine.CompleteMessage(messages[1], upResults(messages[1]), time.Now())
+	got := publisher.published()
+	if len(got) != 1 || got[0].Messages != 3 || got[0].IPsScanned != 3 {
+		t.Fatalf("completions = %+v, want one for 3 messages and 3 IPs", got)
+	}
+}
+
+func TestCompleteMessageAnnouncesConcurrentBatchOnce(t *testing.T) {
+	engine, publisher := newBatchTrackingEngine()
+	ips := make([]string, 0, 50)
+	for i := 1; i <= 50; i++ {
+		ips = append(ips, fmt.Sprintf("8.8.8.%d", i))
+	}
+	messages := domain.NewBatchMessages("batch-1", ips, nil, 1)
+
+	var wg sync.WaitGroup
+	for _, message := range messages {
+		for delivery := 0; delivery < 2; delivery++ {
+			wg.Add(1)
+			go func(message *domain.QueueMessage) {
+				defer wg.Done()
+				engine.CompleteMessage(message, upResults(message), time.Now())
+			}(message)
+		}
+	}
+	wg.Wait()
+
+	got := publisher.published()
+	if len(got) != 1 || got[0].Messages != 50 {
+		t.Fatalf("completions = %+v, want exactly one for 50 messages", got)
+	}
+}
+
+func TestCompleteMessageAnnouncesUnsplitMessage(t *testing.T) {
+	engine, publisher := newBatchTrackingEngine()
+	message := &domain.QueueMessage{IPs: []string{"8.8.8.8"}, BatchID: "single-1", Count: 1}
+
+	engine.CompleteMessage(message, upResults(message), time.Now())
+	got := publisher.published()
+	if len(got) != 1 || got[0].BatchID != "single-1" || got[0].Messages != 1 {
+		t.Fatalf("completions = %+v, want one for single-1", got)
+	}
+}
+
+func TestCompleteMessageCountsFailedScans(t *testing.T) {
+	engine, publisher := newBatchTrackingEngine()
+	message := &domain.QueueMessage{IPs: []string{"8.8.8.8", "1.1.1.1"}, BatchID: "single-1", Count: 2}
+
+	failed := domain.NewScanResult("1.1.1.1", "single-1", "worker-1")
+	failed.SetFailed("timeout")
+	results := append(upResults(&domain.QueueMessage{IPs: message.IPs[:1]}), failed)
+
+	engine.CompleteMessage(message, results, time.Now())
+	got := publisher.published()
+	if len(got) != 1 || got[0].Failed != 1 || got[0].HostsUp != 1 || got[0].IPsScanned != 2 {
+		t.Fatalf("completions = %+v, want one with 1 failed and 1 host up of 2", got)
+	}
+}
+
+func TestMemoryBatchTrackerForgetsStaleBatches(t *testing.T) {
+	tracker := NewMemoryBatchTracker(20 * time.Millisecond)
+	if _, err := tracker.RecordMessage(&domain.BatchProgress{BatchID: "batch-1", Sequence: 1, Final: true}); err != nil {
+		t.Fatalf("RecordMessage returned error: %v", err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if _, err := tracker.RecordMessage(&domain.BatchProgress{BatchID: "batch-2", Sequence: 0, Final: true}); err != nil {
+		t.Fatalf("RecordMessage returned error: %v", err)
+	}
+	if _, ok := tracker.batches["batch-1"]; ok {
+		t.Error("incomplete batch kept after its TTL")
+	}
+}
@@ -0,0 +1,61 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"port-scanner/internal/domain"
+	"port-scanner/internal/testutil"
+)
+
+// stubQueueManager is a QueueManager that consumes nothing but keeps the context it is given
+type stubQueueManager struct {
+	ctx context.Context
+}
+
+func (q *stubQueueManager) SetContext(ctx context.Context)                    { q.ctx = ctx }
+func (q *stubQueueManager) ConsumeIPs() error                                 { return nil }
+func (q *stubQueueManager) PublishScanResult(result *domain.ScanResult) error { return nil }
+func (q *stubQueueManager) PublishEnrichmentMessage(ip string, isUp bool, batchID string) error {
+	return nil
+}
+func (q *stubQueueManager) PublishServiceAnalysis(ip string, openPorts []*domain.Port, batchID string, findings []domain.Finding) error {
+	return nil
+}
+func (q *stubQueueManager) Close() error { return nil }
+
+func TestScanDeadlineStopsAcceptingWork(t *testing.T) {
+	config := domain.NewDefaultScanConfig()
+	config.Concurrency = 1
+	config.ScanDeadline = 50 * time.Millisecond
+	queue := &stubQueueManager{}
+	engine := NewScanEngineService(testutil.NewFakeScanner(), queue, config)
+
+	if err := engine.StartScanning(); err != nil {
+		t.Fatalf("StartScanning returned error: %v", err)
+	}
+	defer engine.StopScanning()
+
+	if _, ok := engine.Deadline(); !ok {
+		t.Fatal("Deadline() reports no deadline with ScanDeadline set")
+	}
+
+	// Before the deadline, work is accepted
+	release, err := engine.AcquireScanSlot(context.Background())
+	if err != nil {
+		t.Fatalf("AcquireScanSlot before the deadline returned error: %v", err)
+	}
+	release()
+
+	select {
+	case <-queue.ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("queue manager context was not cancelled by the deadline")
+	}
+
+	if _, err := engine.AcquireScanSlot(context.Background()); !errors.Is(err, domain.ErrEngineStopped) {
+		t.Fatalf("AcquireScanSlot after the deadline returned %v, want ErrEngineStopped", err)
+	}
+}
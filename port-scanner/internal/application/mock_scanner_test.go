@@ -0,0 +1,48 @@
+package application
+
+import (
+	"testing"
+	"time"
+
+	"port-scanner/internal/domain"
+)
+
+func TestEngineRunsPipelineOnMockScanner(t *testing.T) {
+	config := domain.NewDefaultScanConfig()
+	config.PortRange = []int{22, 80, 6379}
+	scanner := domain.NewMockScanner(config, domain.MockScannerConfig{
+		HostPorts: map[string][]int{"203.0.113.5": {22, 6379}},
+		Banners:   map[int]string{6379: "+PONG"},
+	})
+
+	engine := NewScanEngineService(scanner, nil, config)
+	engine.SetAnalyzers(newTestRegistry(t))
+	publisher := &recordingBatchPublisher{}
+	engine.SetBatchTracking(NewMemoryBatchTracker(time.Hour), publisher, "worker-1")
+
+	message := &domain.QueueMessage{IPs: []string{"203.0.113.5", "203.0.113.6"}, BatchID: "batch-1", Count: 2}
+	results := make([]*domain.ScanResult, 0, len(message.IPs))
+	for _, ip := range message.IPs {
+		result, err := engine.ScanIP(ip, config, message.BatchID, "worker-1")
+		if err != nil {
+			t.Fatalf("ScanIP(%s) returned error: %v", ip, err)
+		}
+		results = append(results, result)
+	}
+	engine.CompleteMessage(message, results, time.Now())
+
+	// The synthetic redis and OpenSSH 8.9 banners are flagged by the analyzers
+	stored, err := engine.GetScanStatus("203.0.113.5")
+	if err != nil {
+		t.Fatalf("GetScanStatus returned error: %v", err)
+	}
+	if len(stored.GetOpenPorts()) != 2 || len(stored.Findings) != 2 || stored.Metadata["mock"] != true {
+		t.Errorf("stored result has %d open ports, %d findings and metadata %v, want 2 of each from the mock",
+			len(stored.GetOpenPorts()), len(stored.Findings), stored.Metadata)
+	}
+
+	completed := publisher.published()
+	if len(completed) != 1 || completed[0].IPsScanned != 2 || completed[0].HostsUp != 2 || completed[0].OpenPorts != 2 {
+		t.Fatalf("completions = %+v, want one for 2 IPs, 2 hosts up and 2 open ports", completed)
+	}
+}
@@ -0,0 +1,139 @@
+package application
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"port-scanner/internal/domain"
+	"port-scanner/pkg/log"
+
+	"go.uber.org/zap"
+)
+
+// recentEntry is a completed result kept for status lookups
+type recentEntry struct {
+	ip      string
+	result  *domain.ScanResult
+	expires time.Time
+}
+
+// recentResults keeps the latest result of each recently scanned IP, bounded by entry count
+// and age so a long-running engine doesn't grow without limit. The least recently used
+// results are evicted first; with an archive they are spilled to it rather than dropped. It
+// is safe for concurrent use.
+type recentResults struct {
+	mu      sync.Mutex
+	limit   int
+	ttl     time.Duration
+	order   *list.List // Least recently used first
+	entries map[string]*list.Element
+
+	archive domain.ResultArchive // Set before use; nil drops evicted results
+}
+
+// newRecentResults keeps at most limit results for ttl each; a non-positive limit keeps none
+// and a non-positive ttl keeps results until they are evicted by newer ones
+func newRecentResults(limit int, ttl time.Duration) *recentResults {
+	return &recentResults{
+		limit:   limit,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// put records the latest result of an IP, evicting expired entries and then the least
+// recently used ones. Entries evicted for room are archived, outside the lock.
+func (r *recentResults) put(result *domain.ScanResult) {
+	if r.limit <= 0 {
+		r.spill([]*domain.ScanResult{result})
+		return
+	}
+
+	r.mu.Lock()
+	now := time.Now()
+	if element, ok := r.entries[result.IP]; ok {
+		r.order.Remove(element)
+		delete(r.entries, result.IP)
+	}
+
+	entry := &recentEntry{ip: result.IP, result: result}
+	if r.ttl > 0 {
+		entry.expires = now.Add(r.ttl)
+	}
+	r.entries[result.IP] = r.order.PushBack(entry)
+
+	var evicted []*domain.ScanResult
+	for front := r.order.Front(); front != nil; front = r.order.Front() {
+		oldest := front.Value.(*recentEntry)
+		expired := oldest.expired(now)
+		if len(r.entries) <= r.limit && !expired {
+			break
+		}
+		r.order.Remove(front)
+		delete(r.entries, oldest.ip)
+		if !expired {
+			evicted = append(evicted, oldest.result)
+		}
+	}
+	r.mu.Unlock()
+
+	r.spill(evicted)
+}
+
+// get returns the latest result of an IP unless it has expired, looking in the archive when
+// it is no longer in memory
+func (r *recentResults) get(ip string) (*domain.ScanResult, bool) {
+	r.mu.Lock()
+	element, ok := r.entries[ip]
+	if ok {
+		entry := element.Value.(*recentEntry)
+		if !entry.expired(time.Now()) {
+			r.order.MoveToBack(element)
+			r.mu.Unlock()
+			return entry.result, true
+		}
+		r.order.Remove(element)
+		delete(r.entries, ip)
+	}
+	r.mu.Unlock()
+
+	return r.archived(ip)
+}
+
+// spill archives evicted results; failures are logged, losing only the slower lookup
+func (r *recentResults) spill(results []*domain.ScanResult) {
+	if r.archive == nil {
+		return
+	}
+	for _, result := range results {
+		if err := r.archive.ArchiveResult(result); err != nil {
+			log.L().Warn("Failed to archive evicted result", zap.String("event", "result_archive_failed"),
+				zap.String("ip", result.IP), zap.Error(err))
+		}
+	}
+}
+
+// archived looks a result up in the archive, applying the same TTL as memory from the end of the scan
+func (r *recentResults) archived(ip string) (*domain.ScanResult, bool) {
+	if r.archive == nil {
+		return nil, false
+	}
+
+	result, found, err := r.archive.ArchivedResult(ip)
+	if err != nil {
+		log.L().Warn("Failed to read archived result", zap.String("event", "result_archive_read_failed"),
+			zap.String("ip", ip), zap.Error(err))
+		return nil, false
+	}
+	if !found || (r.ttl > 0 && time.Since(result.ScanEndTime) > r.ttl) {
+		return nil, false
+	}
+	return result, true
+}
+
+// expired reports whether the entry's TTL has passed
+func (e *recentEntry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && now.After(e.expires)
+}
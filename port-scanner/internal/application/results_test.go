@@ -0,0 +1,215 @@
+package application
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"port-scanner/internal/domain"
+	"port-scanner/internal/testutil"
+)
+
+func TestRecentResultsEvictsOldestBeyondLimit(t *testing.T) {
+	recent := newRecentResults(3, 0)
+	for i := 1; i <= 5; i++ {
+		recent.put(domain.NewScanResult(fmt.Sprintf("8.8.8.%d", i), "batch-1", "worker-1"))
+	}
+
+	if len(recent.entries) != 3 || recent.order.Len() != 3 {
+		t.Fatalf("kept %d entries (%d ordered), want the limit of 3", len(recent.entries), recent.order.Len())
+	}
+	for _, ip := range []string{"8.8.8.1", "8.8.8.2"} {
+		if _, ok := recent.get(ip); ok {
+			t.Errorf("%s still kept after newer results filled the limit", ip)
+		}
+	}
+	for _, ip := range []string{"8.8.8.3", "8.8.8.4", "8.8.8.5"} {
+		if _, ok := recent.get(ip); !ok {
+			t.Errorf("%s evicted, want it kept", ip)
+		}
+	}
+}
+
+func TestRecentResultsRescanRefreshesEntry(t *testing.T) {
+	recent := newRecentResults(2, 0)
+	recent.put(domain.NewScanResult("8.8.8.8", "batch-1", "worker-1"))
+	recent.put(domain.NewScanResult("1.1.1.1", "batch-1", "worker-1"))
+
+	// Scanning 8.8.8.8 again makes 1.1.1.1 the oldest entry
+	recent.put(domain.NewScanResult("8.8.8.8", "batch-2", "worker-1"))
+	recent.put(domain.NewScanResult("9.9.9.9", "batch-2", "worker-1"))
+
+	if _, ok := recent.get("1.1.1.1"); ok {
+		t.Error("1.1.1.1 kept, want it evicted as the oldest entry")
+	}
+	result, ok := recent.get("8.8.8.8")
+	if !ok || result.BatchID != "batch-2" {
+		t.Errorf("8.8.8.8 = %v, %v, want the result of its latest scan", result, ok)
+	}
+}
+
+func TestRecentResultsExpireAfterTTL(t *testing.T) {
+	recent := newRecentResults(10, 20*time.Millisecond)
+	recent.put(domain.NewScanResult("8.8.8.8", "batch-1", "worker-1"))
+	if _, ok := recent.get("8.8.8.8"); !ok {
+		t.Fatal("fresh result not found")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if _, ok := recent.get("8.8.8.8"); ok {
+		t.Error("result found after its TTL")
+	}
+	if len(recent.entries) != 0 {
+		t.Errorf("expired entry still held: %d entries", len(recent.entries))
+	}
+}
+
+func TestRecentResultsDisabled(t *testing.T) {
+	recent := newRecentResults(0, time.Minute)
+	recent.put(domain.NewScanResult("8.8.8.8", "batch-1", "worker-1"))
+	if _, ok := recent.get("8.8.8.8"); ok {
+		t.Error("result kept with a limit of 0")
+	}
+}
+
+func TestGetScanStatusForgetsEvictedResults(t *testing.T) {
+	config := domain.NewDefaultScanConfig()
+	config.RecentResultsLimit = 2
+	engine := NewScanEngineService(testutil.NewFakeScanner(), nil, config)
+
+	for _, ip := range []string{"8.8.8.8", "1.1.1.1", "9.9.9.9"} {
+		if _, err := engine.ScanIP(ip, engine.ScanConfig(), "batch-1", "worker-1"); err != nil {
+			t.Fatalf("ScanIP(%s) returned error: %v", ip, err)
+		}
+	}
+	if _, err := engine.GetScanStatus("8.8.8.8"); err == nil {
+		t.Error("GetScanStatus found a result evicted by newer scans")
+	}
+	if _, err := engine.GetScanStatus("9.9.9.9"); err != nil {
+		t.Errorf("GetScanStatus(9.9.9.9) returned error: %v", err)
+	}
+}
+
+// memoryArchive is a ResultArchive keeping spilled results in a map
+type memoryArchive struct {
+	mu       sync.Mutex
+	results  map[string]*domain.ScanResult
+	archived []string
+	err      error
+}
+
+func newMemoryArchive() *memoryArchive {
+	return &memoryArchive{results: make(map[string]*domain.ScanResult)}
+}
+
+func (a *memoryArchive) ArchiveResult(result *domain.ScanResult) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.err != nil {
+		return a.err
+	}
+	a.results[result.IP] = result
+	a.archived = append(a.archived, result.IP)
+	return nil
+}
+
+func (a *memoryArchive) ArchivedResult(ip string) (*domain.ScanResult, bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.err != nil {
+		return nil, false, a.err
+	}
+	result, ok := a.results[ip]
+	return result, ok, nil
+}
+
+func TestRecentResultsStayBoundedAndSpillEvicted(t *testing.T) {
+	recent := newRecentResults(100, 0)
+	archive := newMemoryArchive()
+	recent.archive = archive
+
+	for i := 0; i < 1000; i++ {
+		recent.put(domain.NewScanResult(fmt.Sprintf("10.0.%d.%d", i/256, i%256), "batch-1", "worker-1"))
+		if len(recent.entries) > 100 || recent.order.Len() > 100 {
+			t.Fatalf("after %d results, %d entries in memory, want at most 100", i+1, len(recent.entries))
+		}
+	}
+	if len(archive.archived) != 900 {
+		t.Errorf("archived %d results, want the 900 evicted", len(archive.archived))
+	}
+
+	// The oldest result is only in the archive, the newest only in memory
+	oldest, ok := recent.get("10.0.0.0")
+	if !ok || oldest.IP != "10.0.0.0" {
+		t.Errorf("evicted result = %v, %v, want it read back from the archive", oldest, ok)
+	}
+	if _, ok := recent.entries["10.0.3.231"]; !ok {
+		t.Error("newest result not kept in memory")
+	}
+	if _, ok := archive.results["10.0.3.231"]; ok {
+		t.Error("result archived before being evicted")
+	}
+}
+
+func TestRecentResultsEvictLeastRecentlyUsed(t *testing.T) {
+	recent := newRecentResults(2, 0)
+	archive := newMemoryArchive()
+	recent.archive = archive
+
+	recent.put(domain.NewScanResult("8.8.8.8", "batch-1", "worker-1"))
+	recent.put(domain.NewScanResult("1.1.1.1", "batch-1", "worker-1"))
+
+	// Looking 8.8.8.8 up makes 1.1.1.1 the least recently used
+	recent.get("8.8.8.8")
+	recent.put(domain.NewScanResult("9.9.9.9", "batch-1", "worker-1"))
+
+	if _, ok := recent.entries["8.8.8.8"]; !ok {
+		t.Error("recently read 8.8.8.8 was evicted")
+	}
+	if want := []string{"1.1.1.1"}; fmt.Sprint(archive.archived) != fmt.Sprint(want) {
+		t.Errorf("archived %v, want %v", archive.archived, want)
+	}
+}
+
+func TestRecentResultsArchivedLookupHonoursTTL(t *testing.T) {
+	recent := newRecentResults(1, time.Hour)
+	archive := newMemoryArchive()
+	recent.archive = archive
+
+	stale := domain.NewScanResult("8.8.8.8", "batch-1", "worker-1")
+	stale.ScanEndTime = time.Now().Add(-2 * time.Hour)
+	archive.results["8.8.8.8"] = stale
+	if _, ok := recent.get("8.8.8.8"); ok {
+		t.Error("archived result older than the TTL was returned")
+	}
+
+	// A failing archive loses the slower lookup, not the scan
+	archive.err = errors.New("disk full")
+	recent.put(domain.NewScanResult("1.1.1.1", "batch-1", "worker-1"))
+	recent.put(domain.NewScanResult("9.9.9.9", "batch-1", "worker-1"))
+	if _, ok := recent.get("1.1.1.1"); ok {
+		t.Error("result found although archiving it failed")
+	}
+	if _, ok := recent.get("9.9.9.9"); !ok {
+		t.Error("result in memory not found")
+	}
+}
+
+func TestGetScanStatusReadsSpilledResults(t *testing.T) {
+	config := domain.NewDefaultScanConfig()
+	config.RecentResultsLimit = 2
+	engine := NewScanEngineService(testutil.NewFakeScanner(), nil, config)
+	engine.SetResultArchive(newMemoryArchive())
+
+	for _, ip := range []string{"8.8.8.8", "1.1.1.1", "9.9.9.9"} {
+		if _, err := engine.ScanIP(ip, engine.ScanConfig(), "batch-1", "worker-1"); err != nil {
+			t.Fatalf("ScanIP(%s) returned error: %v", ip, err)
+		}
+	}
+	result, err := engine.GetScanStatus("8.8.8.8")
+	if err != nil || result.IP != "8.8.8.8" {
+		t.Errorf("GetScanStatus of an evicted result = %v, %v, want it from the archive", result, err)
+	}
+}
@@ -2,8 +2,10 @@ package application
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"port-scanner/internal/domain"
 	"port-scanner/pkg/log"
@@ -17,12 +19,28 @@ type ScanEngineService struct {
 	queueManager domain.QueueManager
 	config       *domain.ScanConfig
 	stats        *domain.ScanStats
+	statsMu      sync.Mutex
 	workerPool   chan struct{}
-	results      map[string]*domain.ScanResult
+	results      *recentResults
+	analyzers    *AnalyzerRegistry
+	analysis     domain.AnalysisStore
 	mu           sync.RWMutex
 	ctx          context.Context
 	cancel       context.CancelFunc
+	deadline     time.Time
 	isRunning    bool
+
+	batches        domain.BatchTracker
+	batchPublisher domain.BatchCompletePublisher
+	workerID       string
+}
+
+// Ensure ScanEngineService implements ScanEngine interface
+var _ domain.ScanEngine = (*ScanEngineService)(nil)
+
+// contextSetter is implemented by components that can be stopped via a context
+type contextSetter interface {
+	SetContext(ctx context.Context)
 }
 
 // NewScanEngineService creates a new scan engine service
@@ -35,12 +53,28 @@ func NewScanEngineService(scanner domain.Scanner, queueManager domain.QueueManag
 		config:       config,
 		stats:        domain.NewScanStats(),
 		workerPool:   make(chan struct{}, config.Concurrency),
-		results:      make(map[string]*domain.ScanResult),
+		results:      newRecentResults(config.RecentResultsLimit, config.RecentResultsTTL),
 		ctx:          ctx,
 		cancel:       cancel,
 	}
 }
 
+// SetAnalyzers sets the service analyzers run on every scanned host
+func (s *ScanEngineService) SetAnalyzers(analyzers *AnalyzerRegistry) {
+	s.analyzers = analyzers
+}
+
+// SetResultArchive sets where results evicted from memory are spilled so GetScanStatus can
+// still find them. It must be called before scanning starts.
+func (s *ScanEngineService) SetResultArchive(archive domain.ResultArchive) {
+	s.results.archive = archive
+}
+
+// SetAnalysisStore sets where the service analysis of hosts with open ports is stored
+func (s *ScanEngineService) SetAnalysisStore(store domain.AnalysisStore) {
+	s.analysis = store
+}
+
 // StartScanning starts the scanning engine
 func (s *ScanEngineService) StartScanning() error {
 	s.mu.Lock()
@@ -52,8 +86,24 @@ func (s *ScanEngineService) StartScanning() error {
 
 	log.L().Info("Starting port scanner engine", zap.String("event", "engine_start"))
 
-	// Start consuming messages
-	err := s.queueManager.ConsumeIPs(s.processMessage)
+	// Bound the whole run by the configured deadline
+	if s.config.ScanDeadline > 0 {
+		s.cancel()
+		s.deadline = time.Now().Add(s.config.ScanDeadline)
+		s.ctx, s.cancel = context.WithDeadline(context.Background(), s.deadline)
+		go s.watchDeadline(s.ctx)
+		log.L().Info("Scan deadline set", zap.String("event", "engine_deadline_set"), zap.Time("deadline", s.deadline))
+	}
+
+	// Share the engine context so cancellation reaches consumers and in-flight scans
+	for _, component := range []interface{}{s.scanner, s.queueManager} {
+		if setter, ok := component.(contextSetter); ok {
+			setter.SetContext(s.ctx)
+		}
+	}
+
+	// Start consuming messages; the queue manager scans them through its scan handler
+	err := s.queueManager.ConsumeIPs()
 	if err != nil {
 		log.L().Error("Failed to start consuming messages", zap.String("event", "engine_start_failed"), zap.Error(err))
 		return fmt.Errorf("failed to start consuming messages: %w", err)
@@ -75,77 +125,104 @@ func (s *ScanEngineService) StopScanning() {
 
 	log.L().Info("Stopping port scanner engine", zap.String("event", "engine_stop"))
 
-	// Stop consuming messages
+	// Stop consuming messages and abort in-flight scans
+	s.cancel()
 	s.queueManager.Close()
 
 	s.isRunning = false
 	log.L().Info("Port scanner engine stopped", zap.String("event", "engine_stopped"))
 }
 
-// processMessage handles incoming IP messages from the queue
-func (s *ScanEngineService) processMessage(message *domain.QueueMessage) error {
-	log.L().Info("Received IP batch", zap.String("event", "batch_received"), zap.String("batch_id", message.BatchID), zap.Int("ip_count", len(message.IPs)))
-
-	// Process each IP in the batch
-	var wg sync.WaitGroup
-	semaphore := make(chan struct{}, s.config.Concurrency)
-
-	for _, ip := range message.IPs {
-		wg.Add(1)
-		go func(ipAddr string) {
-			defer wg.Done()
-
-			// Acquire semaphore
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
-
-			// Scan the IP
-			result, err := s.scanner.ScanIP(ipAddr, s.config, message.BatchID, "")
-			if err != nil {
-				log.L().Error("Scan failed", zap.String("event", "scan_failed"), zap.String("ip", ipAddr), zap.Error(err))
-				return
-			}
-
-			// Update statistics
-			s.stats.UpdateStats(result)
-
-			// Publish scan result
-			err = s.queueManager.PublishScanResult(result)
-			if err != nil {
-				log.L().Error("Failed to publish scan result", zap.String("event", "publish_scan_result_failed"), zap.String("ip", result.IP), zap.Error(err))
-			}
-
-			// Publish enrichment message if host is up
-			if result.IsUp {
-				err = s.queueManager.PublishEnrichmentMessage(result.IP, true, message.BatchID)
-				if err != nil {
-					log.L().Error("Failed to publish enrichment message", zap.String("event", "publish_enrichment_failed"), zap.String("ip", result.IP), zap.Error(err))
-				}
-			}
-
-			// Publish service analysis if open ports found
-			openPorts := result.GetOpenPorts()
-			if len(openPorts) > 0 {
-				err = s.queueManager.PublishServiceAnalysis(result.IP, openPorts, message.BatchID)
-				if err != nil {
-					log.L().Error("Failed to publish service analysis", zap.String("event", "publish_service_analysis_failed"), zap.String("ip", result.IP), zap.Error(err))
-				}
-			}
-		}(ip)
+// watchDeadline logs when the engine deadline stops the run
+func (s *ScanEngineService) watchDeadline(ctx context.Context) {
+	<-ctx.Done()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		log.L().Warn("Scan deadline reached, no new work will be accepted", zap.String("event", "engine_deadline_reached"),
+			zap.Int64("total_scanned", s.stats.TotalScanned))
 	}
-
-	wg.Wait()
-
-	log.L().Info("Completed processing batch", zap.String("event", "batch_completed"), zap.String("batch_id", message.BatchID))
-	return nil
 }
 
-// GetScanStatus returns the scan status for a specific IP
-func (s *ScanEngineService) GetScanStatus(ip string) (*domain.ScanResult, error) {
+// Deadline returns the engine deadline, if one is configured
+func (s *ScanEngineService) Deadline() (time.Time, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
+	return s.deadline, !s.deadline.IsZero()
+}
+
+// ScanConfig returns a copy of the engine's scan configuration that callers may modify
+func (s *ScanEngineService) ScanConfig() *domain.ScanConfig {
+	config := *s.config
+	return &config
+}
+
+// AcquireScanSlot waits for an engine-wide scan slot, so scans started outside the queue (e.g.
+// from the API) count against the same concurrency as queued ones
+func (s *ScanEngineService) AcquireScanSlot(ctx context.Context) (func(), error) {
+	if s.ctx.Err() != nil {
+		return nil, domain.ErrEngineStopped
+	}
+
+	select {
+	case s.workerPool <- struct{}{}:
+		return s.releaseWorker, nil
+	case <-s.ctx.Done():
+		return nil, domain.ErrEngineStopped
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// releaseWorker frees an engine-wide scan slot
+func (s *ScanEngineService) releaseWorker() {
+	<-s.workerPool
+}
+
+// ActiveScans returns the number of scans currently in flight across all batches
+func (s *ScanEngineService) ActiveScans() int {
+	return len(s.workerPool)
+}
+
+// ScanIP scans an IP and keeps the result for GetScanStatus; it is the queue manager's scan
+// handler, so results of queued IPs can be looked up too. The caller must hold a scan slot
+// (see AcquireScanSlot).
+func (s *ScanEngineService) ScanIP(ip string, config *domain.ScanConfig, batchID string, workerID string) (*domain.ScanResult, error) {
+	result, err := s.scanner.ScanIP(ip, config, batchID, workerID)
+	if err != nil {
+		return nil, err
+	}
+	s.analyze(result)
+
+	s.RecordResult(result)
+
+	// The caller may still annotate the result, so keep a copy of it as scanned
+	recorded := *result
+	s.results.put(&recorded)
+	return result, nil
+}
+
+// analyze runs the service analyzers on a result and stores the analysis of hosts with open ports
+func (s *ScanEngineService) analyze(result *domain.ScanResult) {
+	if s.analyzers.Len() == 0 || len(result.GetOpenPorts()) == 0 {
+		return
+	}
+
+	findings := s.analyzers.Analyze(result)
+	if len(findings) > 0 {
+		log.L().Info("Service analyzers reported findings", zap.String("event", "service_findings"),
+			zap.String("ip", result.IP), zap.Int("findings", len(findings)))
+	}
 
-	result, exists := s.results[ip]
+	if s.analysis != nil {
+		if err := s.analysis.SaveServiceAnalysis(result); err != nil {
+			log.L().Error("Failed to save service analysis", zap.String("event", "service_analysis_save_failed"),
+				zap.String("ip", result.IP), zap.Error(err))
+		}
+	}
+}
+
+// GetScanStatus returns the latest recent scan result for a specific IP
+func (s *ScanEngineService) GetScanStatus(ip string) (*domain.ScanResult, error) {
+	result, exists := s.results.get(ip)
 	if !exists {
 		return nil, fmt.Errorf("no scan result found for IP: %s", ip)
 	}
@@ -153,18 +230,18 @@ func (s *ScanEngineService) GetScanStatus(ip string) (*domain.ScanResult, error)
 	return result, nil
 }
 
-// GetScanStats returns the current scan statistics
+// GetScanStats returns a copy of the current scan statistics
 func (s *ScanEngineService) GetScanStats() *domain.ScanStats {
-	return s.stats
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+
+	stats := *s.stats
+	return &stats
 }
 
-// ProcessIP manually processes a single IP (for testing/debugging)
-func (s *ScanEngineService) ProcessIP(ip string, batchID string) error {
-	// Create a mock message for single IP processing
-	message := &domain.QueueMessage{
-		IPs:     []string{ip},
-		BatchID: batchID,
-		Count:   1,
-	}
-	return s.processMessage(message)
+// RecordResult counts a scan result in the statistics; API and queued scans share them
+func (s *ScanEngineService) RecordResult(result *domain.ScanResult) {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+	s.stats.UpdateStats(result)
 }
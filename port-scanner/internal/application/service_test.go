@@ -0,0 +1,83 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"port-scanner/internal/domain"
+	"port-scanner/internal/testutil"
+	"port-scanner/pkg/log"
+)
+
+func TestMain(m *testing.M) {
+	log.InitLogger("application-test")
+	os.Exit(m.Run())
+}
+
+func newTestEngine(concurrency int) *ScanEngineService {
+	config := domain.NewDefaultScanConfig()
+	config.Concurrency = concurrency
+	return NewScanEngineService(testutil.NewFakeScanner(), nil, config)
+}
+
+func TestAcquireScanSlotBoundsActiveScans(t *testing.T) {
+	engine := newTestEngine(2)
+
+	var releases []func()
+	for i := 0; i < 2; i++ {
+		release, err := engine.AcquireScanSlot(context.Background())
+		if err != nil {
+			t.Fatalf("AcquireScanSlot returned error: %v", err)
+		}
+		releases = append(releases, release)
+	}
+	if got := engine.ActiveScans(); got != 2 {
+		t.Errorf("ActiveScans() = %d, want 2", got)
+	}
+
+	// Every slot is taken, so a third scan waits until its context gives up
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := engine.AcquireScanSlot(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("AcquireScanSlot with every slot taken returned %v, want deadline exceeded", err)
+	}
+
+	releases[0]()
+	release, err := engine.AcquireScanSlot(context.Background())
+	if err != nil {
+		t.Fatalf("AcquireScanSlot after a release returned error: %v", err)
+	}
+	release()
+	releases[1]()
+	if got := engine.ActiveScans(); got != 0 {
+		t.Errorf("ActiveScans() after releasing every slot = %d, want 0", got)
+	}
+}
+
+func TestAcquireScanSlotFailsOnceStopped(t *testing.T) {
+	engine := newTestEngine(1)
+	engine.cancel()
+
+	if _, err := engine.AcquireScanSlot(context.Background()); !errors.Is(err, domain.ErrEngineStopped) {
+		t.Fatalf("AcquireScanSlot on a stopped engine returned %v, want ErrEngineStopped", err)
+	}
+}
+
+func TestScanIPKeepsResultForStatus(t *testing.T) {
+	engine := newTestEngine(1)
+	engine.scanner.(*testutil.FakeScanner).AddOpenPorts("8.8.8.8", 53)
+
+	if _, err := engine.ScanIP("8.8.8.8", engine.ScanConfig(), "batch-1", "worker-1"); err != nil {
+		t.Fatalf("ScanIP returned error: %v", err)
+	}
+	result, err := engine.GetScanStatus("8.8.8.8")
+	if err != nil {
+		t.Fatalf("GetScanStatus returned error: %v", err)
+	}
+	if len(result.GetOpenPorts()) != 1 {
+		t.Errorf("stored result has %d open ports, want 1", len(result.GetOpenPorts()))
+	}
+}
@@ -0,0 +1,33 @@
+package domain
+
+// Finding severities, from least to most urgent
+const (
+	SeverityInfo     = "info"
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+// Finding is an issue a service analyzer spotted on an open port
+type Finding struct {
+	Analyzer string `json:"analyzer"`
+	Port     int    `json:"port"`
+	Service  string `json:"service"`
+	Severity string `json:"severity"`
+	Title    string `json:"title"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// ServiceAnalyzer inspects open ports running the services it handles and reports findings
+type ServiceAnalyzer interface {
+	Name() string
+	// Services lists the service names the analyzer inspects; "*" matches every service
+	Services() []string
+	Analyze(ip string, port *Port) []Finding
+}
+
+// AnalysisStore persists the service analysis of a scanned host
+type AnalysisStore interface {
+	SaveServiceAnalysis(result *ScanResult) error
+}
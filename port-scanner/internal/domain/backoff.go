@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RetryDelayFor returns how long to wait before retry number attempt (from 0) of a port:
+// RetryDelay grown by RetryBackoff per attempt, spread by up to RetryJitter of itself either
+// way and capped at RetryMaxDelay when set. random is a number in [0, 1) picking the jitter.
+func (c *ScanConfig) RetryDelayFor(attempt int, random float64) time.Duration {
+	delay := float64(c.RetryDelay)
+	if c.RetryBackoff > 1 {
+		delay *= math.Pow(c.RetryBackoff, float64(attempt))
+	}
+	if c.RetryJitter > 0 {
+		delay *= 1 + c.RetryJitter*(2*random-1)
+	}
+	if c.RetryMaxDelay > 0 && delay > float64(c.RetryMaxDelay) {
+		return c.RetryMaxDelay
+	}
+	return time.Duration(delay)
+}
+
+// jitterSource picks retry jitter; a fixed seed repeats the same sequence. It is safe for
+// concurrent use.
+type jitterSource struct {
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// newJitterSource creates a source seeded with seed, or from the clock when seed is 0
+func newJitterSource(seed int64) *jitterSource {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &jitterSource{rand: rand.New(rand.NewSource(seed))}
+}
+
+// Float64 returns a number in [0, 1)
+func (j *jitterSource) Float64() float64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.rand.Float64()
+}
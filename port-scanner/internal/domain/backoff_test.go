@@ -0,0 +1,92 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryDelayForGrowsWithinJitterBounds(t *testing.T) {
+	config := &ScanConfig{
+		RetryDelay:   100 * time.Millisecond,
+		RetryBackoff: 2,
+		RetryJitter:  0.2,
+	}
+	jitter := newJitterSource(42)
+
+	previous := time.Duration(0)
+	for attempt := 0; attempt < 6; attempt++ {
+		base := time.Duration(float64(config.RetryDelay) * float64(int(1)<<attempt))
+		low := time.Duration(float64(base) * (1 - config.RetryJitter))
+		high := time.Duration(float64(base) * (1 + config.RetryJitter))
+
+		delay := config.RetryDelayFor(attempt, jitter.Float64())
+		if delay < low || delay > high {
+			t.Errorf("attempt %d: delay %v outside jitter bounds [%v, %v]", attempt, delay, low, high)
+		}
+		// With a backoff of 2 and 20% jitter the ranges of successive attempts don't overlap
+		if delay <= previous {
+			t.Errorf("attempt %d: delay %v did not grow from %v", attempt, delay, previous)
+		}
+		previous = delay
+	}
+}
+
+func TestRetryDelayForExtremesAndCap(t *testing.T) {
+	config := &ScanConfig{
+		RetryDelay:    time.Second,
+		RetryBackoff:  3,
+		RetryJitter:   0.5,
+		RetryMaxDelay: 5 * time.Second,
+	}
+
+	if got := config.RetryDelayFor(0, 0); got != 500*time.Millisecond {
+		t.Errorf("lowest jitter delay = %v, want 500ms", got)
+	}
+	if got := config.RetryDelayFor(0, 0.5); got != time.Second {
+		t.Errorf("middle jitter delay = %v, want 1s", got)
+	}
+	if got := config.RetryDelayFor(1, 0.5); got != 3*time.Second {
+		t.Errorf("second attempt delay = %v, want 3s", got)
+	}
+	if got := config.RetryDelayFor(4, 0.99); got != config.RetryMaxDelay {
+		t.Errorf("capped delay = %v, want %v", got, config.RetryMaxDelay)
+	}
+}
+
+func TestRetryDelayForWithoutBackoffOrJitterIsFixed(t *testing.T) {
+	config := &ScanConfig{RetryDelay: 250 * time.Millisecond, RetryBackoff: 1}
+	for attempt := 0; attempt < 4; attempt++ {
+		if got := config.RetryDelayFor(attempt, 0.9); got != config.RetryDelay {
+			t.Errorf("attempt %d: delay %v, want fixed %v", attempt, got, config.RetryDelay)
+		}
+	}
+}
+
+func TestJitterSourceIsDeterministicWithSeed(t *testing.T) {
+	a, b := newJitterSource(7), newJitterSource(7)
+	for i := 0; i < 10; i++ {
+		x, y := a.Float64(), b.Float64()
+		if x != y {
+			t.Fatalf("value %d differs between sources with the same seed: %v != %v", i, x, y)
+		}
+		if x < 0 || x >= 1 {
+			t.Fatalf("value %d = %v, want [0, 1)", i, x)
+		}
+	}
+}
+
+func TestScanPortWithRetryWaitsBetweenAttempts(t *testing.T) {
+	scanner, config := newTimeoutScanner(2)
+	config.RetryDelay = 20 * time.Millisecond
+	config.RetryBackoff = 2
+
+	start := time.Now()
+	if _, err := scanner.scanPortWithRetry("127.0.0.1", 9, config, nil, nil); err != nil {
+		t.Fatalf("scanPortWithRetry returned error: %v", err)
+	}
+
+	// Two retries wait 20ms and then 40ms
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("retries took %v, want at least 60ms of backoff", elapsed)
+	}
+}
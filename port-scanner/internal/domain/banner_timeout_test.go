@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// slowAcceptPort returns a local port whose full listen backlog drops the first connection
+// attempt, so connecting takes about a second (one SYN retransmission). The listener then
+// accepts the connection and writes banner after bannerDelay.
+func slowAcceptPort(t *testing.T, banner string, bannerDelay time.Duration) int {
+	t.Helper()
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatalf("socket returned error: %v", err)
+	}
+	t.Cleanup(func() { syscall.Close(fd) })
+	if err := syscall.Bind(fd, &syscall.SockaddrInet4{Addr: [4]byte{127, 0, 0, 1}}); err != nil {
+		t.Fatalf("bind returned error: %v", err)
+	}
+	if err := syscall.Listen(fd, 0); err != nil {
+		t.Fatalf("listen returned error: %v", err)
+	}
+	addr, err := syscall.Getsockname(fd)
+	if err != nil {
+		t.Fatalf("getsockname returned error: %v", err)
+	}
+	port := addr.(*syscall.SockaddrInet4).Port
+
+	filler, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		t.Fatalf("failed to fill the backlog: %v", err)
+	}
+	t.Cleanup(func() { filler.Close() })
+
+	go func() {
+		// Free the backlog once the scanner's first SYN has been dropped
+		time.Sleep(200 * time.Millisecond)
+		for i := 0; i < 2; i++ {
+			nfd, _, err := syscall.Accept(fd)
+			if err != nil {
+				return
+			}
+			if i == 0 {
+				syscall.Close(nfd)
+				continue
+			}
+			time.Sleep(bannerDelay)
+			syscall.Write(nfd, []byte(banner+"\r\n"))
+			time.Sleep(100 * time.Millisecond)
+			syscall.Close(nfd)
+		}
+	}()
+	return port
+}
+
+func TestBasicBannerGrabReadsAfterSlowConnect(t *testing.T) {
+	scanner, config := newLocalScanner()
+	config.BannerConnectTimeout = 3 * time.Second
+	config.BannerReadTimeout = 500 * time.Millisecond
+	port := slowAcceptPort(t, "220 mail.example.com ESMTP", 300*time.Millisecond)
+
+	start := time.Now()
+	info, err := scanner.basicBannerGrab("127.0.0.1", port)
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatalf("basicBannerGrab returned error after %v: %v", elapsed, err)
+	}
+	if elapsed < config.BannerReadTimeout {
+		t.Skipf("connect took only %v; the slow connect was not reproduced", elapsed)
+	}
+	if info.RawBanner != "220 mail.example.com ESMTP" {
+		t.Errorf("banner = %q, want the one sent %v after a %v connect", info.RawBanner, 300*time.Millisecond, elapsed)
+	}
+}
+
+func TestBannerTimeoutsFallBackToBannerTimeout(t *testing.T) {
+	config := NewDefaultScanConfig()
+	config.BannerTimeout = 4 * time.Second
+
+	if connect, read := config.BannerTimeouts(); connect != 4*time.Second || read != 4*time.Second {
+		t.Errorf("BannerTimeouts() = %v, %v, want the banner timeout for both", connect, read)
+	}
+
+	config.BannerConnectTimeout = time.Second
+	config.BannerReadTimeout = 2 * time.Second
+	if connect, read := config.BannerTimeouts(); connect != time.Second || read != 2*time.Second {
+		t.Errorf("BannerTimeouts() = %v, %v, want 1s, 2s", connect, read)
+	}
+}
@@ -0,0 +1,41 @@
+package domain
+
+import "time"
+
+// BatchProgress summarizes one processed message of a generated batch
+type BatchProgress struct {
+	BatchID   string // Parent batch the message belongs to
+	Sequence  int
+	Final     bool // The generator marks the last message of a batch
+	IPs       int
+	HostsUp   int
+	Failed    int
+	OpenPorts int
+	StartedAt time.Time
+	EndedAt   time.Time
+}
+
+// BatchCompleteMessage is published once every message of a generated batch has been processed
+type BatchCompleteMessage struct {
+	BatchID     string    `json:"batch_id"`
+	Messages    int       `json:"messages"`
+	IPsScanned  int       `json:"ips_scanned"`
+	HostsUp     int       `json:"hosts_up"`
+	Failed      int       `json:"failed"`
+	OpenPorts   int       `json:"open_ports"`
+	StartedAt   time.Time `json:"started_at"`
+	CompletedAt time.Time `json:"completed_at"`
+	WorkerID    string    `json:"worker_id"`
+}
+
+// BatchTracker records the processed messages of batches. RecordMessage returns the batch
+// summary when the recorded message completes its batch, exactly once per batch even if
+// messages are redelivered; otherwise it returns nil.
+type BatchTracker interface {
+	RecordMessage(progress *BatchProgress) (*BatchCompleteMessage, error)
+}
+
+// BatchCompletePublisher announces completed batches
+type BatchCompletePublisher interface {
+	PublishBatchComplete(message *BatchCompleteMessage) error
+}
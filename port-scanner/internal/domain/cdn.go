@@ -0,0 +1,41 @@
+package domain
+
+// CDNUnknownProvider names a proxy or load balancer whose operator could not be identified
+const CDNUnknownProvider = "unknown"
+
+// CDNInfo records that a service answered through a CDN or load balancer, so its
+// banners describe the edge rather than the host behind it
+type CDNInfo struct {
+	Provider   string   `json:"provider" bson:"provider"`
+	Indicators []string `json:"indicators" bson:"indicators"` // Headers that gave it away, e.g. "server: cloudflare"
+}
+
+// SetCDN flags the banner as served by a CDN or load balancer in the metadata
+func (b *BannerInfo) SetCDN(cdn *CDNInfo) {
+	if b == nil || cdn == nil {
+		return
+	}
+	if b.Metadata == nil {
+		b.Metadata = make(map[string]interface{})
+	}
+	b.Metadata["cdn"] = cdn
+}
+
+// CDN returns the CDN detected on any of the result's ports, preferring an identified provider
+func (sr *ScanResult) CDN() *CDNInfo {
+	var detected *CDNInfo
+	for _, port := range sr.Ports {
+		if port.BannerInfo == nil {
+			continue
+		}
+		cdn, ok := port.BannerInfo.Metadata["cdn"].(*CDNInfo)
+		if !ok {
+			continue
+		}
+		if cdn.Provider != CDNUnknownProvider {
+			return cdn
+		}
+		detected = cdn
+	}
+	return detected
+}
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"sort"
+	"time"
+)
+
+// ScanHistory gives access to earlier scans of a host so new results can be compared
+type ScanHistory interface {
+	// LastOpenPorts returns the open ports, with their detected versions, from the most recent
+	// completed scan of ip; found is false when the IP has no completed scan on record
+	LastOpenPorts(ip string) (ports map[int]string, found bool, err error)
+}
+
+// VersionChange records a service version that differs from the previous scan
+type VersionChange struct {
+	Port     int    `json:"port"`
+	Previous string `json:"previous"`
+	Current  string `json:"current"`
+}
+
+// ScanChangeMessage is a compact description of how a host changed since its previous scan
+type ScanChangeMessage struct {
+	IP             string          `json:"ip"`
+	BatchID        string          `json:"batch_id"`
+	AddedPorts     []int           `json:"added_ports,omitempty"`
+	RemovedPorts   []int           `json:"removed_ports,omitempty"`
+	VersionChanges []VersionChange `json:"version_changes,omitempty"`
+	Timestamp      int64           `json:"timestamp"`
+}
+
+// DiffScanResult compares a result's open ports with those of the previous scan and
+// returns the changes, or nil when nothing changed
+func DiffScanResult(previous map[int]string, result *ScanResult) *ScanChangeMessage {
+	change := &ScanChangeMessage{
+		IP:        result.IP,
+		BatchID:   result.BatchID,
+		Timestamp: time.Now().Unix(),
+	}
+
+	current := make(map[int]string)
+	for _, port := range result.GetOpenPorts() {
+		current[port.Number] = port.Version
+	}
+
+	for port, version := range current {
+		previousVersion, ok := previous[port]
+		if !ok {
+			change.AddedPorts = append(change.AddedPorts, port)
+			continue
+		}
+		// An undetected version on either side isn't evidence of a change
+		if version != previousVersion && version != "" && previousVersion != "" {
+			change.VersionChanges = append(change.VersionChanges, VersionChange{Port: port, Previous: previousVersion, Current: version})
+		}
+	}
+	for port := range previous {
+		if _, ok := current[port]; !ok {
+			change.RemovedPorts = append(change.RemovedPorts, port)
+		}
+	}
+
+	if len(change.AddedPorts) == 0 && len(change.RemovedPorts) == 0 && len(change.VersionChanges) == 0 {
+		return nil
+	}
+
+	sort.Ints(change.AddedPorts)
+	sort.Ints(change.RemovedPorts)
+	sort.Slice(change.VersionChanges, func(i, j int) bool {
+		return change.VersionChanges[i].Port < change.VersionChanges[j].Port
+	})
+	return change
+}
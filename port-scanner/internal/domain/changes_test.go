@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+)
+
+// resultWithOpenPorts builds a completed result with the given open ports and versions
+func resultWithOpenPorts(versions map[int]string) *ScanResult {
+	result := &ScanResult{IP: "8.8.8.8", BatchID: "batch-2", Status: ScanStatusCompleted, IsUp: true}
+	for number, version := range versions {
+		result.Ports = append(result.Ports, &Port{Number: number, Status: PortStatusOpen, Version: version})
+	}
+	return result
+}
+
+func TestDiffScanResultReportsChanges(t *testing.T) {
+	previous := map[int]string{22: "OpenSSH 8.2", 80: "nginx 1.18", 443: ""}
+	result := resultWithOpenPorts(map[int]string{22: "OpenSSH 9.6", 443: "nginx 1.24", 3306: "", 8080: ""})
+
+	change := DiffScanResult(previous, result)
+	if change == nil {
+		t.Fatal("DiffScanResult returned nil for a changed host")
+	}
+	if change.IP != "8.8.8.8" || change.BatchID != "batch-2" {
+		t.Errorf("change for %s/%s, want 8.8.8.8/batch-2", change.IP, change.BatchID)
+	}
+	if !reflect.DeepEqual(change.AddedPorts, []int{3306, 8080}) {
+		t.Errorf("added ports = %v, want [3306 8080]", change.AddedPorts)
+	}
+	if !reflect.DeepEqual(change.RemovedPorts, []int{80}) {
+		t.Errorf("removed ports = %v, want [80]", change.RemovedPorts)
+	}
+	// 443 had no detected version before, which isn't a version change
+	want := []VersionChange{{Port: 22, Previous: "OpenSSH 8.2", Current: "OpenSSH 9.6"}}
+	if !reflect.DeepEqual(change.VersionChanges, want) {
+		t.Errorf("version changes = %v, want %v", change.VersionChanges, want)
+	}
+}
+
+func TestDiffScanResultUnchangedHost(t *testing.T) {
+	previous := map[int]string{22: "OpenSSH 8.2", 80: "nginx 1.18"}
+	result := resultWithOpenPorts(map[int]string{22: "OpenSSH 8.2", 80: ""})
+	result.Ports = append(result.Ports, &Port{Number: 25, Status: PortStatusClosed})
+
+	if change := DiffScanResult(previous, result); change != nil {
+		t.Errorf("DiffScanResult reported %+v for an unchanged host", change)
+	}
+}
@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+)
+
+// bannerCircuit stops banner grabbing for a host once too many grabs have timed out.
+// A nil circuit never opens.
+type bannerCircuit struct {
+	mu       sync.Mutex
+	limit    int
+	timeouts int
+}
+
+// newBannerCircuit creates a circuit that opens after limit timeouts; limit <= 0 disables it
+func newBannerCircuit(limit int) *bannerCircuit {
+	if limit <= 0 {
+		return nil
+	}
+	return &bannerCircuit{limit: limit}
+}
+
+// isOpen reports whether banner grabbing should be skipped
+func (c *bannerCircuit) isOpen() bool {
+	if c == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.timeouts >= c.limit
+}
+
+// record registers a banner grab outcome and reports whether this call opened the circuit
+func (c *bannerCircuit) record(timedOut bool) bool {
+	if c == nil || !timedOut {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timeouts++
+	return c.timeouts == c.limit
+}
+
+// isTimeout reports whether a banner grab error was caused by a timeout
+func isTimeout(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	// Banner grabbers wrap timeouts in plain errors
+	return strings.Contains(err.Error(), "timeout")
+}
@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+)
+
+// timeoutGrabber is a BannerGrabber whose grabs always time out, counting the attempts
+type timeoutGrabber struct {
+	mu       sync.Mutex
+	attempts int
+}
+
+func (g *timeoutGrabber) GetBanner(ip string, port int) (*BannerInfo, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.attempts++
+	return &BannerInfo{}, context.DeadlineExceeded
+}
+
+func (g *timeoutGrabber) count() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.attempts
+}
+
+// openLocalPorts returns n loopback ports that accept connections until the test ends
+func openLocalPorts(t *testing.T, n int) []int {
+	t.Helper()
+	ports := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("net.Listen returned error: %v", err)
+		}
+		t.Cleanup(func() { listener.Close() })
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				conn.Close()
+			}
+		}()
+		ports = append(ports, listener.Addr().(*net.TCPAddr).Port)
+	}
+	return ports
+}
+
+func TestBannerGrabsStopAfterTimeoutLimit(t *testing.T) {
+	config := NewDefaultScanConfig()
+	config.Concurrency = 1
+	config.MaxRetries = 0
+	config.RetryDelay = 0
+	config.BannerTimeoutLimit = 3
+	scanner := NewScannerService(config)
+	grabber := &timeoutGrabber{}
+	scanner.SetBannerGrabber(grabber)
+
+	ports := openLocalPorts(t, 6)
+	results, err := scanner.ScanPorts("127.0.0.1", ports)
+	if err != nil {
+		t.Fatalf("ScanPorts returned error: %v", err)
+	}
+	if got := grabber.count(); got != 3 {
+		t.Errorf("banner grab attempts = %d, want 3", got)
+	}
+	for _, port := range results {
+		if port.Status != PortStatusOpen {
+			t.Errorf("port %d status = %q, want open without a banner", port.Number, port.Status)
+		}
+	}
+
+	// The limit applies per scan, so the next scan of the host tries again
+	if _, err := scanner.ScanPorts("127.0.0.1", ports); err != nil {
+		t.Fatalf("ScanPorts returned error: %v", err)
+	}
+	if got := grabber.count(); got != 6 {
+		t.Errorf("banner grab attempts after a second scan = %d, want 6", got)
+	}
+}
@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"sort"
+	"strings"
+)
+
+// ServiceCluster is a service detected on several open ports of one host, such as a web
+// server on 80, 443 and 8080
+type ServiceCluster struct {
+	Service  string   `json:"service"`
+	Ports    []int    `json:"ports"`
+	Versions []string `json:"versions,omitempty"`
+	// VersionMismatch is set when the ports report different versions, which may mean some
+	// of them are served by a proxy or another instance rather than the same service
+	VersionMismatch bool `json:"version_mismatch"`
+}
+
+// ClusterServices groups the open ports by detected service, keeping the services found on
+// more than one port, ordered by service name. Plain and TLS variants of a service (e.g.
+// "http", "https" and "https-alt") form one cluster; unidentified ports are left out.
+func ClusterServices(ports []*Port) []ServiceCluster {
+	clusters := make(map[string]*ServiceCluster)
+	for _, port := range ports {
+		if port.Status != PortStatusOpen {
+			continue
+		}
+		service := serviceFamily(port.Service)
+		if service == "" {
+			continue
+		}
+
+		cluster, ok := clusters[service]
+		if !ok {
+			cluster = &ServiceCluster{Service: service}
+			clusters[service] = cluster
+		}
+		cluster.Ports = append(cluster.Ports, port.Number)
+
+		version := port.Version
+		if version == "" && port.BannerInfo != nil {
+			version = port.BannerInfo.Version
+		}
+		if version != "" && !containsString(cluster.Versions, version) {
+			cluster.Versions = append(cluster.Versions, version)
+		}
+	}
+
+	result := make([]ServiceCluster, 0, len(clusters))
+	for _, cluster := range clusters {
+		if len(cluster.Ports) < 2 {
+			continue
+		}
+		sort.Ints(cluster.Ports)
+		sort.Strings(cluster.Versions)
+		cluster.VersionMismatch = len(cluster.Versions) > 1
+		result = append(result, *cluster)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Service < result[j].Service })
+	return result
+}
+
+// serviceFamily names the service a port's service belongs to for clustering, folding TLS and
+// alternate-port variants into the base service; unidentified services return ""
+func serviceFamily(service string) string {
+	service = strings.ToLower(strings.TrimSpace(service))
+	if service == "" || service == "unknown" {
+		return ""
+	}
+
+	for _, suffix := range []string{"-alt", "-proxy"} {
+		service = strings.TrimSuffix(service, suffix)
+	}
+	switch service {
+	case "https":
+		return "http"
+	case "smtps", "submission":
+		return "smtp"
+	case "imaps":
+		return "imap"
+	case "pop3s":
+		return "pop3"
+	}
+	return service
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
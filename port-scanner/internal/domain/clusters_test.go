@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+)
+
+// openServicePort returns an open port identified as service, with an optional version
+func openServicePort(number int, service, version string) *Port {
+	return &Port{Number: number, Status: PortStatusOpen, Service: service, Version: version}
+}
+
+func TestClusterServicesGroupsPortsByService(t *testing.T) {
+	ports := []*Port{
+		openServicePort(8080, "http-alt", "nginx 1.24"),
+		openServicePort(22, "ssh", "OpenSSH 9.6"),
+		openServicePort(443, "https", "nginx 1.24"),
+		openServicePort(80, "http", ""),
+		openServicePort(2222, "SSH", "OpenSSH 9.6"),
+		openServicePort(3306, "mysql", "8.0.36"),
+		openServicePort(9000, "unknown", ""),
+		openServicePort(9001, "", ""),
+		{Number: 8443, Status: PortStatusClosed, Service: "https"},
+	}
+
+	want := []ServiceCluster{
+		{Service: "http", Ports: []int{80, 443, 8080}, Versions: []string{"nginx 1.24"}},
+		{Service: "ssh", Ports: []int{22, 2222}, Versions: []string{"OpenSSH 9.6"}},
+	}
+	if got := ClusterServices(ports); !reflect.DeepEqual(got, want) {
+		t.Errorf("ClusterServices = %+v, want %+v", got, want)
+	}
+}
+
+func TestClusterServicesFlagsVersionMismatch(t *testing.T) {
+	ports := []*Port{
+		openServicePort(80, "http", "Apache 2.4.58"),
+		openServicePort(8080, "http-proxy", "nginx 1.24"),
+		openServicePort(25, "smtp", "Postfix"),
+		openServicePort(465, "smtps", ""),
+	}
+	// The version may only be known from the banner information
+	ports[3].BannerInfo = &BannerInfo{Version: "Postfix"}
+
+	clusters := ClusterServices(ports)
+	if len(clusters) != 2 {
+		t.Fatalf("ClusterServices = %+v, want http and smtp clusters", clusters)
+	}
+	http, smtp := clusters[0], clusters[1]
+	if !http.VersionMismatch || !reflect.DeepEqual(http.Versions, []string{"Apache 2.4.58", "nginx 1.24"}) {
+		t.Errorf("http cluster = %+v, want a mismatch between Apache and nginx", http)
+	}
+	if smtp.VersionMismatch || !reflect.DeepEqual(smtp.Ports, []int{25, 465}) {
+		t.Errorf("smtp cluster = %+v, want 25 and 465 without a mismatch", smtp)
+	}
+}
+
+func TestClusterServicesSkipsSinglePortServices(t *testing.T) {
+	ports := []*Port{openServicePort(22, "ssh", ""), openServicePort(80, "http", "")}
+	if got := ClusterServices(ports); len(got) != 0 {
+		t.Errorf("ClusterServices = %+v, want no clusters", got)
+	}
+}
@@ -0,0 +1,100 @@
+package domain
+
+import "strings"
+
+// Weights of the confidence score signals; the score is their sum, kept within 0-100
+const (
+	confidenceZGrabWeight       = 40  // Identified by a ZGrab2 protocol module
+	confidenceBannerWeight      = 25  // Identified from a banner
+	confidencePortWeight        = 10  // Guessed from the port number
+	confidenceModuleWeight      = 20  // A module answered that is expected on the port or names the service
+	confidenceVersionWeight     = 15  // A version was extracted
+	confidenceShortBannerWeight = 5   // The banner has at least confidenceShortBanner bytes
+	confidenceLongBannerWeight  = 10  // The banner has at least confidenceLongBanner bytes
+	confidencePortMatchWeight   = 15  // The service is the one registered for the port
+	confidencePortConflict      = -10 // The port is registered for another service and no module confirmed it
+	confidenceShortBanner       = 8
+	confidenceLongBanner        = 32
+	confidenceUnknownServiceCap = 20 // Highest score of a port whose service wasn't identified
+)
+
+// ConfidenceScore rates from 0 to 100 how well a port's service identification is supported:
+// how it was identified (confidence), whether a ZGrab2 module expected on the port or named
+// after the service answered, whether a version was extracted, how much banner there is, and
+// whether the port's registered service agrees with the detected one
+func ConfidenceScore(info *BannerInfo, port int) int {
+	if info == nil {
+		return 0
+	}
+
+	score := confidencePortWeight
+	switch info.Confidence {
+	case "zgrab2":
+		score = confidenceZGrabWeight
+	case "banner":
+		score = confidenceBannerWeight
+	}
+
+	banner := strings.TrimSpace(info.RawBanner)
+	switch {
+	case len(banner) >= confidenceLongBanner:
+		score += confidenceLongBannerWeight
+	case len(banner) >= confidenceShortBanner:
+		score += confidenceShortBannerWeight
+	}
+
+	service := strings.ToLower(info.Service)
+	if service == "" || service == "unknown" {
+		return min(score, confidenceUnknownServiceCap)
+	}
+
+	moduleMatch := confidenceModuleMatch(info.Metadata, service, port)
+	if moduleMatch {
+		score += confidenceModuleWeight
+	}
+	if info.Version != "" {
+		score += confidenceVersionWeight
+	}
+
+	switch registered := KnownPorts.Service(port); {
+	case registered == service:
+		score += confidencePortMatchWeight
+	case registered != "unknown" && !moduleMatch:
+		score += confidencePortConflict
+	}
+
+	return max(0, min(score, 100))
+}
+
+// confidenceModuleMatch reports whether the metadata holds a successful ZGrab2 module result
+// for a module registered on the port or named after the service (e.g. "postgres" for
+// "postgresql")
+func confidenceModuleMatch(metadata map[string]interface{}, service string, port int) bool {
+	expected := make(map[string]bool)
+	for _, module := range KnownPorts.Modules(port) {
+		expected[module] = true
+	}
+
+	for module, data := range metadata {
+		moduleData, ok := data.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if status, ok := moduleData["status"].(string); ok && status != "success" {
+			continue
+		}
+		// The generic banner module answers anything, so it confirms nothing
+		if module != "banner" && (expected[module] || strings.HasPrefix(service, module)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ScoreConfidence sets the banner's ConfidenceScore for the port it was grabbed from
+func (b *BannerInfo) ScoreConfidence(port int) {
+	if b == nil {
+		return
+	}
+	b.ConfidenceScore = ConfidenceScore(b, port)
+}
@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfidenceScoreRanksRicherEvidenceHigher(t *testing.T) {
+	sshBanner := "SSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13.5"
+
+	// From the richest to the poorest evidence for the service on port 22
+	ladder := []struct {
+		name string
+		info *BannerInfo
+		want int
+	}{
+		{"ssh module with version and long banner", &BannerInfo{
+			Service: "ssh", Version: "OpenSSH_9.6p1", Confidence: "zgrab2", RawBanner: sshBanner,
+			Metadata: map[string]interface{}{"ssh": map[string]interface{}{"status": "success"}},
+		}, 100},
+		{"banner with version", &BannerInfo{Service: "ssh", Version: "OpenSSH_9.6p1", Confidence: "banner", RawBanner: "SSH-2.0-OpenSSH_9.6p1"}, 60},
+		{"banner without version", &BannerInfo{Service: "ssh", Confidence: "banner", RawBanner: "SSH-2.0-x"}, 45},
+		{"port number only", &BannerInfo{Service: "ssh", Confidence: "port"}, 25},
+		{"unidentified service", &BannerInfo{Service: "unknown", Confidence: "banner", RawBanner: strings.Repeat("?", 40)}, 20},
+	}
+
+	previous := 101
+	for _, step := range ladder {
+		got := ConfidenceScore(step.info, 22)
+		if got != step.want {
+			t.Errorf("%s: score = %d, want %d", step.name, got, step.want)
+		}
+		if got >= previous {
+			t.Errorf("%s: score %d is not below the richer evidence's %d", step.name, got, previous)
+		}
+		previous = got
+	}
+}
+
+func TestConfidenceScoreCrossChecksPortAndService(t *testing.T) {
+	// A banner claiming HTTP on the SSH port scores below the same banner on an unregistered port
+	conflict := ConfidenceScore(&BannerInfo{Service: "http", Confidence: "banner", RawBanner: "HTTP/1.1 200 OK"}, 22)
+	unregistered := ConfidenceScore(&BannerInfo{Service: "http", Confidence: "banner", RawBanner: "HTTP/1.1 200 OK"}, 31337)
+	if conflict >= unregistered {
+		t.Errorf("score on a conflicting port = %d, want below %d on an unregistered port", conflict, unregistered)
+	}
+
+	// A module named after the service confirms it even on a port registered for another
+	confirmed := ConfidenceScore(&BannerInfo{
+		Service: "postgresql", Confidence: "zgrab2",
+		Metadata: map[string]interface{}{"postgres": map[string]interface{}{"status": "success"}},
+	}, 22)
+	if confirmed != confidenceZGrabWeight+confidenceModuleWeight {
+		t.Errorf("confirmed score = %d, want %d without the port conflict penalty", confirmed, confidenceZGrabWeight+confidenceModuleWeight)
+	}
+
+	// Failed modules and the generic banner module confirm nothing
+	for _, metadata := range []map[string]interface{}{
+		{"ssh": map[string]interface{}{"status": "connection-timeout"}},
+		{"banner": map[string]interface{}{"status": "success"}},
+	} {
+		info := &BannerInfo{Service: "ssh", Confidence: "zgrab2", Metadata: metadata}
+		if got := ConfidenceScore(info, 22); got != confidenceZGrabWeight+confidencePortMatchWeight {
+			t.Errorf("score with metadata %v = %d, want no module match", metadata, got)
+		}
+	}
+}
+
+func TestConfidenceScoreBounds(t *testing.T) {
+	if got := ConfidenceScore(nil, 22); got != 0 {
+		t.Errorf("ConfidenceScore(nil) = %d, want 0", got)
+	}
+	if got := ConfidenceScore(&BannerInfo{Service: "http", Confidence: "port"}, 22); got != 0 {
+		t.Errorf("port guess conflicting with the port = %d, want it floored at 0", got)
+	}
+}
+
+func TestGetBannerSetsConfidenceScore(t *testing.T) {
+	scanner := NewScannerService(NewDefaultScanConfig())
+	scanner.SetBannerGrabber(staticGrabber{service: "ssh"})
+
+	info, err := scanner.GetBanner("192.0.2.1", 22)
+	if err != nil {
+		t.Fatalf("GetBanner returned error: %v", err)
+	}
+	if info.ConfidenceScore != ConfidenceScore(info, 22) || info.ConfidenceScore == 0 {
+		t.Errorf("ConfidenceScore = %d, want the port's score", info.ConfidenceScore)
+	}
+}
@@ -0,0 +1,153 @@
+package domain
+
+import (
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// connLivenessWait is how long a cached connection is read to check the peer hasn't closed it
+const connLivenessWait = 5 * time.Millisecond
+
+// ConnCache keeps connections opened by the port scan so the fallback banner grab can reuse
+// them instead of opening new ones. Only connections nothing has been written to are cached,
+// and each is closed once it has been idle for the idle timeout.
+type ConnCache struct {
+	mu          sync.Mutex
+	conns       map[string]*cachedConn
+	idleTimeout time.Duration
+	maxConns    int
+}
+
+// cachedConn is an idle connection waiting to be reused
+type cachedConn struct {
+	conn  net.Conn
+	timer *time.Timer
+}
+
+// NewConnCache creates a cache holding at most maxConns idle connections for idleTimeout each
+func NewConnCache(idleTimeout time.Duration, maxConns int) *ConnCache {
+	return &ConnCache{
+		conns:       make(map[string]*cachedConn),
+		idleTimeout: idleTimeout,
+		maxConns:    maxConns,
+	}
+}
+
+// Put hands an unused connection to the cache, closing it instead when the cache is full.
+// A connection already cached for the address is replaced.
+func (c *ConnCache) Put(addr string, conn net.Conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if previous, ok := c.conns[addr]; ok {
+		previous.timer.Stop()
+		previous.conn.Close()
+		delete(c.conns, addr)
+	}
+	if c.idleTimeout <= 0 || len(c.conns) >= c.maxConns {
+		conn.Close()
+		return
+	}
+
+	entry := &cachedConn{conn: conn}
+	entry.timer = time.AfterFunc(c.idleTimeout, func() { c.evict(addr, entry) })
+	c.conns[addr] = entry
+}
+
+// Take removes and returns the cached connection to an address; the caller owns it afterwards.
+// A nil cache holds nothing.
+func (c *ConnCache) Take(addr string) (net.Conn, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.conns[addr]
+	if !ok {
+		return nil, false
+	}
+	entry.timer.Stop()
+	delete(c.conns, addr)
+	return entry.conn, true
+}
+
+// Dial returns the cached connection to an address if the peer still holds it open, and
+// otherwise opens a new one. A nil cache always dials.
+func (c *ConnCache) Dial(addr string, timeout time.Duration) (net.Conn, error) {
+	if c != nil {
+		if conn, ok := c.Take(addr); ok {
+			if alive, ok := checkAlive(conn); ok {
+				return alive, nil
+			}
+			conn.Close()
+		}
+	}
+	return net.DialTimeout("tcp", addr, timeout)
+}
+
+// Discard closes the cached connection to an address, for callers that connect on their own
+func (c *ConnCache) Discard(addr string) {
+	if conn, ok := c.Take(addr); ok {
+		conn.Close()
+	}
+}
+
+// Close closes every cached connection
+func (c *ConnCache) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for addr, entry := range c.conns {
+		entry.timer.Stop()
+		entry.conn.Close()
+		delete(c.conns, addr)
+	}
+}
+
+// evict closes a connection whose idle timeout passed, unless it was taken in the meantime
+func (c *ConnCache) evict(addr string, entry *cachedConn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conns[addr] != entry {
+		return
+	}
+	delete(c.conns, addr)
+	entry.conn.Close()
+}
+
+// checkAlive reports whether the peer still holds a connection open. A read that times out
+// means nothing was sent or closed; data the peer sent first also means it is alive, and is
+// replayed by the returned connection so banner reads still see it.
+func checkAlive(conn net.Conn) (net.Conn, bool) {
+	conn.SetReadDeadline(time.Now().Add(connLivenessWait))
+	defer conn.SetReadDeadline(time.Time{})
+
+	var buf [1]byte
+	n, err := conn.Read(buf[:])
+	if n > 0 {
+		return &replayConn{Conn: conn, pending: buf[:n]}, true
+	}
+	return conn, errors.Is(err, os.ErrDeadlineExceeded)
+}
+
+// replayConn returns bytes consumed by the liveness check before reading from the connection
+type replayConn struct {
+	net.Conn
+	pending []byte
+}
+
+// Read implements net.Conn
+func (c *replayConn) Read(b []byte) (int, error) {
+	if len(c.pending) > 0 {
+		n := copy(b, c.pending)
+		c.pending = c.pending[n:]
+		return n, nil
+	}
+	return c.Conn.Read(b)
+}
@@ -0,0 +1,247 @@
+package domain
+
+import (
+	"net"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// acceptingPort listens locally, counting the connections it accepts and handing each to
+// serve; the returned address is the listener's
+func acceptingPort(t *testing.T, serve func(net.Conn)) (string, *atomic.Int32) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	accepted := &atomic.Int32{}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			accepted.Add(1)
+			go serve(conn)
+		}
+	}()
+	return listener.Addr().String(), accepted
+}
+
+// acceptedWithin waits up to a second for the server to accept want connections and returns
+// how many it accepted, since the accept is counted after the client's dial returns
+func acceptedWithin(accepted *atomic.Int32, want int32) int32 {
+	deadline := time.Now().Add(time.Second)
+	for accepted.Load() < want && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	return accepted.Load()
+}
+
+// holdOpen keeps a connection open until the peer closes it, reporting when it did
+func holdOpen(closed chan<- struct{}) func(net.Conn) {
+	return func(conn net.Conn) {
+		defer conn.Close()
+		var buf [64]byte
+		for {
+			if _, err := conn.Read(buf[:]); err != nil {
+				closed <- struct{}{}
+				return
+			}
+		}
+	}
+}
+
+func TestConnCacheReusesConnectionWithinIdleWindow(t *testing.T) {
+	addr, accepted := acceptingPort(t, holdOpen(make(chan struct{}, 4)))
+	cache := NewConnCache(time.Second, 10)
+	defer cache.Close()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	cache.Put(addr, conn)
+
+	reused, err := cache.Dial(addr, time.Second)
+	if err != nil {
+		t.Fatalf("Dial returned error: %v", err)
+	}
+	defer reused.Close()
+	if reused != conn {
+		t.Error("Dial opened a new connection, want the cached one")
+	}
+	if got := accepted.Load(); got != 1 {
+		t.Errorf("server accepted %d connections, want 1", got)
+	}
+
+	// A taken connection is no longer cached
+	if _, ok := cache.Take(addr); ok {
+		t.Error("connection still cached after Dial took it")
+	}
+}
+
+func TestConnCacheClosesConnectionAfterIdleWindow(t *testing.T) {
+	closed := make(chan struct{}, 4)
+	addr, accepted := acceptingPort(t, holdOpen(closed))
+	cache := NewConnCache(50*time.Millisecond, 10)
+	defer cache.Close()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	cache.Put(addr, conn)
+
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("cached connection still open long after the idle timeout")
+	}
+	if _, ok := cache.Take(addr); ok {
+		t.Error("connection still cached after the idle timeout")
+	}
+
+	fresh, err := cache.Dial(addr, time.Second)
+	if err != nil {
+		t.Fatalf("Dial returned error: %v", err)
+	}
+	fresh.Close()
+	if got := acceptedWithin(accepted, 2); got != 2 {
+		t.Errorf("server accepted %d connections, want a new one after eviction", got)
+	}
+}
+
+func TestConnCacheClosesConnectionsBeyondCapacity(t *testing.T) {
+	closed := make(chan struct{}, 4)
+	first, _ := acceptingPort(t, holdOpen(closed))
+	second, _ := acceptingPort(t, holdOpen(closed))
+	cache := NewConnCache(time.Second, 1)
+	defer cache.Close()
+
+	for _, addr := range []string{first, second} {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatalf("failed to dial: %v", err)
+		}
+		cache.Put(addr, conn)
+	}
+
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("connection beyond the cache size was kept open")
+	}
+	if _, ok := cache.Take(first); !ok {
+		t.Error("first connection not cached")
+	}
+	if _, ok := cache.Take(second); ok {
+		t.Error("second connection cached beyond the cache size")
+	}
+}
+
+func TestConnCacheDialSkipsConnectionClosedByPeer(t *testing.T) {
+	addr, accepted := acceptingPort(t, func(conn net.Conn) { conn.Close() })
+	cache := NewConnCache(time.Second, 10)
+	defer cache.Close()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	cache.Put(addr, conn)
+	time.Sleep(50 * time.Millisecond) // Let the server close its end
+
+	fresh, err := cache.Dial(addr, time.Second)
+	if err != nil {
+		t.Fatalf("Dial returned error: %v", err)
+	}
+	fresh.Close()
+	if fresh == conn || acceptedWithin(accepted, 2) != 2 {
+		t.Error("Dial reused a connection the peer had closed")
+	}
+}
+
+func TestConnCacheDialReplaysBannerSentFirst(t *testing.T) {
+	addr, _ := acceptingPort(t, func(conn net.Conn) {
+		defer conn.Close()
+		conn.Write([]byte("SSH-2.0-OpenSSH_9.6\r\n"))
+		time.Sleep(time.Second)
+	})
+	cache := NewConnCache(time.Second, 10)
+	defer cache.Close()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	cache.Put(addr, conn)
+	time.Sleep(50 * time.Millisecond) // Let the banner arrive before the liveness check
+
+	reused, err := cache.Dial(addr, time.Second)
+	if err != nil {
+		t.Fatalf("Dial returned error: %v", err)
+	}
+	defer reused.Close()
+
+	reused.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 64)
+	n, _ := reused.Read(buf)
+	if n == 0 || buf[0] != 'S' {
+		t.Errorf("first read = %q, want the banner from its first byte", buf[:n])
+	}
+}
+
+func TestScanPortLeavesConnectionForBannerGrab(t *testing.T) {
+	addr, accepted := acceptingPort(t, func(conn net.Conn) {
+		defer conn.Close()
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var buf [64]byte
+		if _, err := conn.Read(buf[:]); err == nil {
+			conn.Write([]byte("SSH-2.0-OpenSSH_9.6\r\n"))
+		}
+	})
+	_, portStr, _ := net.SplitHostPort(addr)
+	port, _ := strconv.Atoi(portStr)
+
+	scanner, config := newLocalScanner()
+	config.EnableBanner = true
+	config.ReuseConnections = true
+	scanner.conns = NewConnCache(time.Second, 10)
+	defer scanner.Shutdown()
+
+	result, err := scanner.scanPort("127.0.0.1", port, config, nil)
+	if err != nil {
+		t.Fatalf("scanPort returned error: %v", err)
+	}
+	if result.Banner != "SSH-2.0-OpenSSH_9.6" {
+		t.Errorf("banner = %q, want SSH-2.0-OpenSSH_9.6", result.Banner)
+	}
+	if got := accepted.Load(); got != 1 {
+		t.Errorf("server accepted %d connections, want the banner grab to reuse the scan's", got)
+	}
+}
+
+func TestScanPortClosesConnectionWithoutBannerGrab(t *testing.T) {
+	closed := make(chan struct{}, 4)
+	addr, _ := acceptingPort(t, holdOpen(closed))
+	_, portStr, _ := net.SplitHostPort(addr)
+	port, _ := strconv.Atoi(portStr)
+
+	scanner, config := newLocalScanner()
+	scanner.conns = NewConnCache(time.Minute, 10)
+	defer scanner.Shutdown()
+
+	if _, err := scanner.scanPort("127.0.0.1", port, config, nil); err != nil {
+		t.Fatalf("scanPort returned error: %v", err)
+	}
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Error("connection cached although no banner grab follows")
+	}
+}
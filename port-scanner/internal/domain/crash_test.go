@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"net"
+	"testing"
+)
+
+// staticGrabber is a BannerGrabber that identifies every port without connecting to it
+type staticGrabber struct {
+	service string
+}
+
+func (g staticGrabber) GetBanner(ip string, port int) (*BannerInfo, error) {
+	return &BannerInfo{Service: g.service, Protocol: "tcp", RawBanner: g.service}, nil
+}
+
+// newCrashCheckScanner returns a scanner that re-checks every port right after banner grabbing
+func newCrashCheckScanner() *ScannerService {
+	config := NewDefaultScanConfig()
+	config.MaxRetries = 0
+	config.RetryDelay = 0
+	config.CrashCheck = true
+	config.CrashCheckDelay = 0
+	config.CrashCheckServices = nil
+	scanner := NewScannerService(config)
+	scanner.SetBannerGrabber(staticGrabber{service: "modbus"})
+	return scanner
+}
+
+func TestCrashCheckFlagsPortThatStopsResponding(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen returned error: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	// The service survives a single probe, then goes away
+	go func() {
+		conn, err := listener.Accept()
+		listener.Close()
+		if err == nil {
+			conn.Close()
+		}
+	}()
+
+	result, err := newCrashCheckScanner().ScanPort("127.0.0.1", port)
+	if err != nil {
+		t.Fatalf("ScanPort returned error: %v", err)
+	}
+	if result.Status != PortStatusOpen {
+		t.Fatalf("port status = %q, want open", result.Status)
+	}
+	if result.BannerInfo == nil || result.BannerInfo.Metadata["possible_crash"] != true {
+		t.Fatalf("banner metadata = %v, want possible_crash set", result.BannerInfo)
+	}
+	if result.BannerInfo.Metadata["crash_check_error"] == "" {
+		t.Error("crash_check_error is empty")
+	}
+}
+
+func TestCrashCheckLeavesLivePortUnflagged(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen returned error: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	result, err := newCrashCheckScanner().ScanPort("127.0.0.1", listener.Addr().(*net.TCPAddr).Port)
+	if err != nil {
+		t.Fatalf("ScanPort returned error: %v", err)
+	}
+	if _, ok := result.BannerInfo.Metadata["possible_crash"]; ok {
+		t.Errorf("live port was flagged as crashed: %v", result.BannerInfo.Metadata)
+	}
+}
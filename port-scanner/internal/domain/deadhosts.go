@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"sync"
+	"time"
+)
+
+// DeadHostCache remembers hosts that recently failed the ping check, so overlapping sweeps can
+// skip re-pinging them until the TTL passes. A nil cache remembers nothing. It is safe for
+// concurrent use.
+type DeadHostCache struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	hosts     map[string]time.Time // IP -> when it was found down
+	lastSweep time.Time
+}
+
+// NewDeadHostCache creates a cache that trusts a down result for ttl
+func NewDeadHostCache(ttl time.Duration) *DeadHostCache {
+	return &DeadHostCache{
+		ttl:       ttl,
+		hosts:     make(map[string]time.Time),
+		lastSweep: time.Now(),
+	}
+}
+
+// DownSince returns when a host was found down, if that is within the TTL
+func (c *DeadHostCache) DownSince(ip string) (time.Time, bool) {
+	if c == nil {
+		return time.Time{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	since, ok := c.hosts[ip]
+	if !ok {
+		return time.Time{}, false
+	}
+	if time.Since(since) > c.ttl {
+		delete(c.hosts, ip)
+		return time.Time{}, false
+	}
+	return since, true
+}
+
+// MarkDown records that a host failed the ping check, sweeping out expired hosts at most once per TTL
+func (c *DeadHostCache) MarkDown(ip string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.hosts[ip] = now
+
+	if now.Sub(c.lastSweep) < c.ttl {
+		return
+	}
+	for host, since := range c.hosts {
+		if now.Sub(since) > c.ttl {
+			delete(c.hosts, host)
+		}
+	}
+	c.lastSweep = now
+}
+
+// MarkUp forgets a host that answered
+func (c *DeadHostCache) MarkUp(ip string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.hosts, ip)
+}
+
+// Len returns how many hosts are remembered as down, including expired ones not yet swept
+func (c *DeadHostCache) Len() int {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.hosts)
+}
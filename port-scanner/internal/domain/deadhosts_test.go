@@ -0,0 +1,156 @@
+package domain
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"port-scanner/internal/infrastructure/ping"
+)
+
+// stubPinger reports hosts as up or down without sending packets, counting the pings per host
+type stubPinger struct {
+	mu    sync.Mutex
+	up    map[string]bool
+	pings map[string]int
+}
+
+func newStubPinger() *stubPinger {
+	return &stubPinger{up: make(map[string]bool), pings: make(map[string]int)}
+}
+
+func (p *stubPinger) PingHost(ip string) (*ping.PingResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pings[ip]++
+	return &ping.PingResult{IsUp: p.up[ip], Duration: time.Millisecond}, nil
+}
+
+func (p *stubPinger) setUp(ip string, up bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.up[ip] = up
+}
+
+func (p *stubPinger) count(ip string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.pings[ip]
+}
+
+// newDeadHostScanner returns a scanner that pings through a stub and caches down hosts for ttl
+func newDeadHostScanner(ttl time.Duration) (*ScannerService, *ScanConfig, *stubPinger) {
+	config := NewDefaultScanConfig()
+	config.EnableBanner = false
+	config.MaxRetries = 0
+	config.RetryDelay = 0
+	config.EnablePing = true
+	config.DeadHostCache = true
+	config.DeadHostTTL = ttl
+
+	scanner := NewScannerService(config)
+	pinger := newStubPinger()
+	scanner.pingService = pinger
+	return scanner, config, pinger
+}
+
+func TestScanIPShortCircuitsDownHostWithinTTL(t *testing.T) {
+	scanner, config, pinger := newDeadHostScanner(time.Minute)
+
+	first, err := scanner.ScanIP("8.8.8.8", config, "batch-1", "worker-1")
+	if err != nil {
+		t.Fatalf("ScanIP returned error: %v", err)
+	}
+	if first.IsUp || first.Status != ScanStatusCompleted {
+		t.Fatalf("first scan = up %v, %s, want a completed down host", first.IsUp, first.Status)
+	}
+
+	second, err := scanner.ScanIP("8.8.8.8", config, "batch-2", "worker-1")
+	if err != nil {
+		t.Fatalf("ScanIP returned error: %v", err)
+	}
+	if pinger.count("8.8.8.8") != 1 {
+		t.Errorf("host pinged %d times, want once within the TTL", pinger.count("8.8.8.8"))
+	}
+	if second.IsUp || second.Status != ScanStatusCompleted || len(second.Ports) != 0 {
+		t.Errorf("cached scan = up %v, %s with %d ports, want a completed down host", second.IsUp, second.Status, len(second.Ports))
+	}
+	if _, ok := second.Metadata["down_since"].(time.Time); !ok {
+		t.Errorf("cached scan metadata = %v, want down_since", second.Metadata)
+	}
+}
+
+func TestScanIPReprobesDownHostAfterTTL(t *testing.T) {
+	scanner, config, pinger := newDeadHostScanner(30 * time.Millisecond)
+	config.PortRange = closedLocalPorts(t, 1)
+
+	if _, err := scanner.ScanIP("127.0.0.1", config, "batch-1", "worker-1"); err != nil {
+		t.Fatalf("ScanIP returned error: %v", err)
+	}
+	pinger.setUp("127.0.0.1", true)
+	time.Sleep(40 * time.Millisecond)
+
+	result, err := scanner.ScanIP("127.0.0.1", config, "batch-2", "worker-1")
+	if err != nil {
+		t.Fatalf("ScanIP returned error: %v", err)
+	}
+	if pinger.count("127.0.0.1") != 2 {
+		t.Errorf("host pinged %d times, want it re-probed after the TTL", pinger.count("127.0.0.1"))
+	}
+	if !result.IsUp {
+		t.Error("re-probed host reported down, want the fresh ping's answer")
+	}
+	if _, ok := result.Metadata["down_since"]; ok {
+		t.Error("re-probed result still carries down_since")
+	}
+	if scanner.deadHosts.Len() != 0 {
+		t.Errorf("%d hosts cached as down after the host answered, want 0", scanner.deadHosts.Len())
+	}
+}
+
+func TestScanIPPingsEveryTimeWithoutDeadHostCache(t *testing.T) {
+	scanner, config, pinger := newDeadHostScanner(time.Minute)
+	scanner.deadHosts = nil
+
+	for i := 0; i < 3; i++ {
+		if _, err := scanner.ScanIP("8.8.8.8", config, "batch-1", "worker-1"); err != nil {
+			t.Fatalf("ScanIP returned error: %v", err)
+		}
+	}
+	if pinger.count("8.8.8.8") != 3 {
+		t.Errorf("host pinged %d times, want every scan to ping without the cache", pinger.count("8.8.8.8"))
+	}
+}
+
+func TestDeadHostCacheExpiresEntries(t *testing.T) {
+	cache := NewDeadHostCache(20 * time.Millisecond)
+	cache.MarkDown("8.8.8.8")
+	if _, down := cache.DownSince("8.8.8.8"); !down {
+		t.Fatal("host not reported down right after MarkDown")
+	}
+
+	cache.MarkDown("1.1.1.1")
+	cache.MarkUp("1.1.1.1")
+	if _, down := cache.DownSince("1.1.1.1"); down {
+		t.Error("host still down after MarkUp")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if _, down := cache.DownSince("8.8.8.8"); down {
+		t.Error("host still down after the TTL")
+	}
+
+	// MarkDown sweeps out hosts expired since the last sweep
+	cache.MarkDown("9.9.9.9")
+	time.Sleep(30 * time.Millisecond)
+	cache.MarkDown("8.8.4.4")
+	if cache.Len() != 1 {
+		t.Errorf("cache holds %d hosts, want only the fresh one", cache.Len())
+	}
+
+	var disabled *DeadHostCache
+	disabled.MarkDown("8.8.8.8")
+	if _, down := disabled.DownSince("8.8.8.8"); down || disabled.Len() != 0 {
+		t.Error("nil cache remembered a host")
+	}
+}
@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"errors"
+	"net"
+	"os"
+	"sync/atomic"
+	"syscall"
+)
+
+// Categories of failed connection attempts, telling unreachable targets apart from scanner
+// resource exhaustion
+const (
+	DialErrorDNS                = "dns-failure"
+	DialErrorTimeout            = "timeout"
+	DialErrorRefused            = "refused"
+	DialErrorNetworkUnreachable = "network-unreachable"
+	DialErrorNoRoute            = "no-route"
+	DialErrorTooManyOpenFiles   = "too-many-open-files"
+	DialErrorOther              = "other"
+)
+
+// DialErrorCategories lists every category, in reporting order
+var DialErrorCategories = []string{
+	DialErrorDNS,
+	DialErrorTimeout,
+	DialErrorRefused,
+	DialErrorNetworkUnreachable,
+	DialErrorNoRoute,
+	DialErrorTooManyOpenFiles,
+	DialErrorOther,
+}
+
+// ErrRetryableDial marks a connection attempt that failed in a way a retry may fix, e.g. a
+// timeout, as opposed to an answer such as a refused connection
+var ErrRetryableDial = errors.New("retryable connection failure")
+
+// IsRetryableDialCategory reports whether a dial error category is worth retrying. Refused
+// connections and unreachable or unresolvable targets are answers; timeouts, exhausted file
+// descriptors and unknown failures may be transient.
+func IsRetryableDialCategory(category string) bool {
+	switch category {
+	case DialErrorTimeout, DialErrorTooManyOpenFiles, DialErrorOther:
+		return true
+	default:
+		return false
+	}
+}
+
+// ClassifyDialError returns the category of a dial error
+func ClassifyDialError(err error) string {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &dnsErr):
+		return DialErrorDNS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return DialErrorRefused
+	case errors.Is(err, syscall.ENETUNREACH):
+		return DialErrorNetworkUnreachable
+	case errors.Is(err, syscall.EHOSTUNREACH):
+		return DialErrorNoRoute
+	case errors.Is(err, syscall.EMFILE), errors.Is(err, syscall.ENFILE):
+		return DialErrorTooManyOpenFiles
+	case errors.Is(err, os.ErrDeadlineExceeded), errors.Is(err, syscall.ETIMEDOUT),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return DialErrorTimeout
+	default:
+		return DialErrorOther
+	}
+}
+
+// DialErrorCounter counts failed connection attempts by category. It is safe for concurrent use.
+type DialErrorCounter struct {
+	counts map[string]*atomic.Int64
+}
+
+// NewDialErrorCounter creates a counter with every category at zero
+func NewDialErrorCounter() *DialErrorCounter {
+	counts := make(map[string]*atomic.Int64, len(DialErrorCategories))
+	for _, category := range DialErrorCategories {
+		counts[category] = new(atomic.Int64)
+	}
+	return &DialErrorCounter{counts: counts}
+}
+
+// Record counts a dial error under its category and returns the category
+func (c *DialErrorCounter) Record(err error) string {
+	category := ClassifyDialError(err)
+	c.counts[category].Add(1)
+	return category
+}
+
+// Counts returns the current count of every category
+func (c *DialErrorCounter) Counts() map[string]int64 {
+	counts := make(map[string]int64, len(c.counts))
+	for category, count := range c.counts {
+		counts[category] = count.Load()
+	}
+	return counts
+}
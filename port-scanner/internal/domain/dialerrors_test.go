@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+)
+
+// dialError wraps a syscall error the way net.Dial reports it
+func dialError(errno syscall.Errno) error {
+	return &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", errno)}
+}
+
+func TestClassifyDialError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{&net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "nowhere.invalid", IsNotFound: true}}, DialErrorDNS},
+		{dialError(syscall.ECONNREFUSED), DialErrorRefused},
+		{dialError(syscall.ENETUNREACH), DialErrorNetworkUnreachable},
+		{dialError(syscall.EHOSTUNREACH), DialErrorNoRoute},
+		{dialError(syscall.EMFILE), DialErrorTooManyOpenFiles},
+		{&net.OpError{Op: "socket", Net: "tcp", Err: os.NewSyscallError("socket", syscall.ENFILE)}, DialErrorTooManyOpenFiles},
+		{dialError(syscall.ETIMEDOUT), DialErrorTimeout},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded}, DialErrorTimeout},
+		{fmt.Errorf("dial: %w", context.DeadlineExceeded), DialErrorTimeout},
+		{errors.New("something else"), DialErrorOther},
+	}
+	for _, tt := range tests {
+		if got := ClassifyDialError(tt.err); got != tt.want {
+			t.Errorf("ClassifyDialError(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestDialErrorCounterCountsByCategory(t *testing.T) {
+	counter := NewDialErrorCounter()
+	counter.Record(dialError(syscall.ECONNREFUSED))
+	counter.Record(dialError(syscall.ECONNREFUSED))
+	counter.Record(dialError(syscall.EMFILE))
+	counter.Record(&net.DNSError{Err: "server misbehaving", Name: "example.com"})
+
+	counts := counter.Counts()
+	if len(counts) != len(DialErrorCategories) {
+		t.Errorf("Counts() has %d categories, want all %d", len(counts), len(DialErrorCategories))
+	}
+	want := map[string]int64{DialErrorRefused: 2, DialErrorTooManyOpenFiles: 1, DialErrorDNS: 1}
+	for _, category := range DialErrorCategories {
+		if counts[category] != want[category] {
+			t.Errorf("count of %s = %d, want %d", category, counts[category], want[category])
+		}
+	}
+}
+
+func TestScanPortCountsRefusedConnections(t *testing.T) {
+	scanner, _ := newLocalScanner()
+	for _, port := range closedLocalPorts(t, 2) {
+		if _, err := scanner.ScanPort("127.0.0.1", port); err != nil {
+			t.Fatalf("ScanPort returned error: %v", err)
+		}
+	}
+
+	counts := scanner.DialErrorCounts()
+	if counts[DialErrorRefused] != 2 {
+		t.Errorf("refused count = %d, want 2: %v", counts[DialErrorRefused], counts)
+	}
+	if counts[DialErrorTimeout] != 0 || counts[DialErrorOther] != 0 {
+		t.Errorf("refused connections counted in other categories: %v", counts)
+	}
+}
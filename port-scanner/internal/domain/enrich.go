@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"port-scanner/pkg/log"
+
+	"go.uber.org/zap"
+)
+
+// Enricher adds context to a finished scan result, such as GeoIP, reverse DNS or
+// threat-intel data, by writing into the result's metadata
+type Enricher interface {
+	Name() string
+	Enrich(result *ScanResult) error
+}
+
+// EnricherChain runs enrichers in order. A failing enricher is logged and recorded
+// under "enrichment_errors" without stopping the others or failing the scan.
+type EnricherChain struct {
+	enrichers []Enricher
+}
+
+// NewEnricherChain creates a chain running the given enrichers in order
+func NewEnricherChain(enrichers ...Enricher) *EnricherChain {
+	return &EnricherChain{enrichers: enrichers}
+}
+
+// Len returns the number of enrichers in the chain
+func (c *EnricherChain) Len() int {
+	if c == nil {
+		return 0
+	}
+	return len(c.enrichers)
+}
+
+// Run applies every enricher to the result
+func (c *EnricherChain) Run(result *ScanResult) {
+	if c == nil {
+		return
+	}
+
+	for _, enricher := range c.enrichers {
+		if err := enricher.Enrich(result); err != nil {
+			log.L().Warn("Enricher failed", zap.String("event", "enrichment_failed"), zap.String("enricher", enricher.Name()),
+				zap.String("ip", result.IP), zap.Error(err))
+
+			errs, _ := result.Metadata["enrichment_errors"].(map[string]string)
+			if errs == nil {
+				errs = make(map[string]string)
+			}
+			errs[enricher.Name()] = err.Error()
+			result.SetMetadata("enrichment_errors", errs)
+		}
+	}
+}
+
+// SetMetadata records a value in the result's metadata
+func (sr *ScanResult) SetMetadata(key string, value interface{}) {
+	if sr.Metadata == nil {
+		sr.Metadata = make(map[string]interface{})
+	}
+	sr.Metadata[key] = value
+}
@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// fakeEnricher records the order enrichers ran in and writes its metadata into the result
+type fakeEnricher struct {
+	name     string
+	metadata map[string]interface{}
+	err      error
+	order    *[]string
+}
+
+func (e fakeEnricher) Name() string {
+	return e.name
+}
+
+func (e fakeEnricher) Enrich(result *ScanResult) error {
+	*e.order = append(*e.order, e.name)
+	for key, value := range e.metadata {
+		result.SetMetadata(key, value)
+	}
+	return e.err
+}
+
+func TestEnricherChainRunsInOrderAndMergesMetadata(t *testing.T) {
+	var order []string
+	chain := NewEnricherChain(
+		fakeEnricher{name: "geoip", metadata: map[string]interface{}{"country": "US", "source": "geoip"}, order: &order},
+		fakeEnricher{name: "asn", metadata: map[string]interface{}{"asn": 15169, "source": "asn"}, order: &order},
+	)
+
+	result := NewScanResult("8.8.8.8", "batch-1", "worker-1")
+	result.SetMetadata("scan_config", "kept")
+	chain.Run(result)
+
+	if !reflect.DeepEqual(order, []string{"geoip", "asn"}) {
+		t.Errorf("enrichers ran in order %v, want [geoip asn]", order)
+	}
+	want := map[string]interface{}{"scan_config": "kept", "country": "US", "asn": 15169, "source": "asn"}
+	if !reflect.DeepEqual(result.Metadata, want) {
+		t.Errorf("metadata = %v, want %v", result.Metadata, want)
+	}
+}
+
+func TestEnricherChainContinuesPastFailures(t *testing.T) {
+	var order []string
+	chain := NewEnricherChain(
+		fakeEnricher{name: "threat-intel", err: errors.New("lookup timed out"), order: &order},
+		fakeEnricher{name: "reverse-dns", metadata: map[string]interface{}{"ptr": "dns.google"}, order: &order},
+	)
+
+	result := NewScanResult("8.8.8.8", "batch-1", "worker-1")
+	chain.Run(result)
+
+	if len(order) != 2 || result.Metadata["ptr"] != "dns.google" {
+		t.Errorf("ran %v with metadata %v, want both enrichers to run", order, result.Metadata)
+	}
+	errs, _ := result.Metadata["enrichment_errors"].(map[string]string)
+	if errs["threat-intel"] != "lookup timed out" || len(errs) != 1 {
+		t.Errorf("enrichment_errors = %v, want the threat-intel failure only", result.Metadata["enrichment_errors"])
+	}
+}
+
+func TestScanIPRunsEnrichers(t *testing.T) {
+	scanner, config := newLocalScanner()
+	config.EnablePing = true
+	config.DeadHostCache = true
+
+	var order []string
+	scanner.SetEnrichers(NewEnricherChain(fakeEnricher{name: "geoip", metadata: map[string]interface{}{"country": "US"}, order: &order}))
+
+	// A host cached as down completes without any network access
+	scanner.deadHosts = NewDeadHostCache(config.DeadHostTTL)
+	scanner.deadHosts.MarkDown("8.8.8.8")
+
+	result, err := scanner.ScanIP("8.8.8.8", config, "batch-1", "worker-1")
+	if err != nil {
+		t.Fatalf("ScanIP returned error: %v", err)
+	}
+	if result.Status != ScanStatusCompleted || result.Metadata["country"] != "US" {
+		t.Errorf("result %s with metadata %v, want a completed, enriched result", result.Status, result.Metadata)
+	}
+}
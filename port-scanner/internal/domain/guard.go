@@ -0,0 +1,120 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ErrExcludedTarget is returned when a scan targets an address excluded by its scan options
+var ErrExcludedTarget = errors.New("target is excluded from this scan")
+
+// ErrProtectedTarget is returned when a scan targets loopback, the scanner host or a protected address
+var ErrProtectedTarget = errors.New("target is protected from scanning")
+
+// ScanGuard refuses scans against the scanner itself and operator-protected addresses
+type ScanGuard struct {
+	hostIPs   map[string]bool
+	protected []*net.IPNet
+}
+
+// NewScanGuard builds a guard from the host's interface addresses and a protect-list
+// of IP addresses or CIDR blocks
+func NewScanGuard(protectedTargets []string) (*ScanGuard, error) {
+	guard := &ScanGuard{
+		hostIPs: make(map[string]bool),
+	}
+
+	// Addresses assigned to this host; a public IP behind NAT must be added to the protect-list
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list interface addresses: %w", err)
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok {
+			guard.hostIPs[ipNet.IP.String()] = true
+		}
+	}
+
+	guard.protected, err = ParseTargets(protectedTargets)
+	if err != nil {
+		return nil, err
+	}
+
+	return guard, nil
+}
+
+// Check returns an error wrapping ErrProtectedTarget if the address must not be scanned
+func (g *ScanGuard) Check(address string) error {
+	if g == nil {
+		return nil
+	}
+
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return fmt.Errorf("invalid IP address: %s", address)
+	}
+
+	switch {
+	case ip.IsLoopback() || ip.IsUnspecified():
+		return fmt.Errorf("%w: %s is a loopback address", ErrProtectedTarget, address)
+	case g.hostIPs[ip.String()]:
+		return fmt.Errorf("%w: %s belongs to the scanner host", ErrProtectedTarget, address)
+	}
+
+	for _, block := range g.protected {
+		if block.Contains(ip) {
+			return fmt.Errorf("%w: %s is in protected range %s", ErrProtectedTarget, address, block)
+		}
+	}
+	return nil
+}
+
+// ParseTargets parses a list of IP addresses and CIDR blocks into networks
+func ParseTargets(targets []string) ([]*net.IPNet, error) {
+	var blocks []*net.IPNet
+	for _, target := range targets {
+		target = strings.TrimSpace(target)
+		if target == "" {
+			continue
+		}
+		if !strings.Contains(target, "/") {
+			ip := net.ParseIP(target)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid target: %s", target)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			blocks = append(blocks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, block, err := net.ParseCIDR(target)
+		if err != nil {
+			return nil, fmt.Errorf("invalid target %s: %w", target, err)
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+// checkExclusions returns an error wrapping ErrExcludedTarget if the address is in one of the excluded targets
+func checkExclusions(address string, exclusions []string) error {
+	if len(exclusions) == 0 {
+		return nil
+	}
+
+	ip := net.ParseIP(address)
+	blocks, err := ParseTargets(exclusions)
+	if ip == nil || err != nil {
+		return nil
+	}
+	for _, block := range blocks {
+		if block.Contains(ip) {
+			return fmt.Errorf("%w: %s is in excluded range %s", ErrExcludedTarget, address, block)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestScanGuardRefusesProtectedTargets(t *testing.T) {
+	guard, err := NewScanGuard([]string{"203.0.113.10", "198.51.100.0/24"})
+	if err != nil {
+		t.Fatalf("NewScanGuard returned error: %v", err)
+	}
+
+	for _, ip := range []string{"127.0.0.1", "127.8.9.10", "::1", "0.0.0.0", "203.0.113.10", "198.51.100.77"} {
+		if err := guard.Check(ip); !errors.Is(err, ErrProtectedTarget) {
+			t.Errorf("Check(%s) = %v, want ErrProtectedTarget", ip, err)
+		}
+	}
+	for _, ip := range []string{"203.0.113.11", "8.8.8.8"} {
+		if err := guard.Check(ip); err != nil {
+			t.Errorf("Check(%s) = %v, want it allowed", ip, err)
+		}
+	}
+
+	if _, err := NewScanGuard([]string{"not-an-ip"}); err == nil {
+		t.Error("NewScanGuard accepted an invalid protect-list entry")
+	}
+}
+
+func TestScanIPRefusesProtectedTargetsBeforeScanning(t *testing.T) {
+	guard, err := NewScanGuard([]string{"203.0.113.10"})
+	if err != nil {
+		t.Fatalf("NewScanGuard returned error: %v", err)
+	}
+	scanner, config := newLocalScanner()
+	scanner.SetScanGuard(guard)
+
+	for _, ip := range []string{"127.0.0.1", "203.0.113.10"} {
+		result, err := scanner.ScanIP(ip, config, "batch-1", "worker-1")
+		if !errors.Is(err, ErrProtectedTarget) {
+			t.Fatalf("ScanIP(%s) returned %v, want ErrProtectedTarget", ip, err)
+		}
+		if result.Status != ScanStatusFailed || len(result.Ports) != 0 {
+			t.Errorf("ScanIP(%s) result = %s with %d ports, want failed without probes", ip, result.Status, len(result.Ports))
+		}
+	}
+}
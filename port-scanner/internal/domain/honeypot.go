@@ -0,0 +1,158 @@
+package domain
+
+import (
+	"sort"
+	"strings"
+)
+
+// HoneypotEnricherName enables the honeypot score in scan.enrichers
+const HoneypotEnricherName = "honeypot_score"
+
+// Weights of the honeypot signals; the score is their sum, capped at 100
+const (
+	honeypotManyOpenPortsWeight   = 30
+	honeypotConflictingOSWeight   = 25
+	honeypotICSMixWeight          = 20
+	honeypotRepeatedBannerWeight  = 20
+	honeypotDecoyBannerWeight     = 40
+	honeypotManyOpenPorts         = 20  // Open ports beyond which a host looks like it answers everything
+	honeypotOpenRatio             = 0.5 // Share of scanned ports open beyond which a host looks the same
+	honeypotRepeatedBannerMinimum = 3   // Ports sharing one banner that suggest a generic responder
+)
+
+// honeypotDecoyBanners are default banners of common honeypots (Cowrie, Kippo, Dionaea, Conpot)
+var honeypotDecoyBanners = []string{
+	"SSH-2.0-OpenSSH_6.0p1 Debian-4+deb7u2",
+	"SSH-2.0-OpenSSH_5.1p1 Debian-5",
+	"220 DiskStation FTP server ready.",
+	"220 Welcome to the ftp service",
+	"Siemens, SIMATIC, S7-200",
+	"Technodrome",
+}
+
+// honeypotOSMarkers map banner substrings to the operating system they reveal
+var honeypotOSMarkers = map[string]string{
+	"ubuntu":    "ubuntu",
+	"debian":    "debian",
+	"centos":    "centos",
+	"red hat":   "redhat",
+	"fedora":    "fedora",
+	"freebsd":   "freebsd",
+	"windows":   "windows",
+	"microsoft": "windows",
+}
+
+// honeypotICSServices are industrial protocols rarely exposed next to each other or next to
+// ordinary IT services on a real device
+var honeypotICSServices = map[string]bool{
+	"modbus": true,
+	"s7":     true,
+	"dnp3":   true,
+	"bacnet": true,
+	"fox":    true,
+}
+
+// HoneypotEnricher scores how likely a host is a honeypot from signals in its open ports, so
+// deception hosts can be filtered out. It writes "honeypot_score" (0-100) and, when any signal
+// fired, "honeypot_signals" to the result metadata.
+type HoneypotEnricher struct{}
+
+// Ensure HoneypotEnricher implements Enricher interface
+var _ Enricher = HoneypotEnricher{}
+
+// Name implements Enricher
+func (HoneypotEnricher) Name() string {
+	return HoneypotEnricherName
+}
+
+// Enrich implements Enricher. Hosts without open ports are left alone.
+func (HoneypotEnricher) Enrich(result *ScanResult) error {
+	if len(result.GetOpenPorts()) == 0 {
+		return nil
+	}
+
+	score, signals := HoneypotScore(result)
+	result.SetMetadata("honeypot_score", score)
+	if len(signals) > 0 {
+		result.SetMetadata("honeypot_signals", signals)
+	}
+	return nil
+}
+
+// HoneypotScore combines the honeypot signals of a result into a score from 0 to 100 and
+// returns the signals that fired
+func HoneypotScore(result *ScanResult) (int, []string) {
+	open := result.GetOpenPorts()
+	score := 0
+	var signals []string
+	add := func(signal string, weight int) {
+		score += weight
+		signals = append(signals, signal)
+	}
+
+	// Too many open ports, absolutely or as a share of a meaningful number of scanned ports
+	if len(open) >= honeypotManyOpenPorts ||
+		(len(result.Ports) >= 10 && float64(len(open))/float64(len(result.Ports)) > honeypotOpenRatio) {
+		add("many_open_ports", honeypotManyOpenPortsWeight)
+	}
+
+	systems := make(map[string]bool)
+	banners := make(map[string]int)
+	ics := make(map[string]bool)
+	itServices := 0
+	decoy := false
+	for _, port := range open {
+		lower := strings.ToLower(port.Banner)
+		for marker, system := range honeypotOSMarkers {
+			if strings.Contains(lower, marker) {
+				systems[system] = true
+			}
+		}
+		if banner := strings.TrimSpace(port.Banner); banner != "" {
+			banners[banner]++
+		}
+		for _, known := range honeypotDecoyBanners {
+			if strings.Contains(port.Banner, known) {
+				decoy = true
+			}
+		}
+
+		service := port.Service
+		if service == "" || service == "unknown" {
+			service = KnownPorts.Service(port.Number)
+		}
+		if honeypotICSServices[service] {
+			ics[service] = true
+		} else if service != "" && service != "unknown" {
+			itServices++
+		}
+	}
+
+	// Banners of several operating systems on one host
+	if len(systems) > 1 {
+		add("conflicting_os_banners", honeypotConflictingOSWeight)
+	}
+
+	// Several industrial protocols, or industrial protocols next to a range of IT services
+	if len(ics) > 1 || (len(ics) == 1 && itServices >= 3) {
+		add("ics_with_unrelated_services", honeypotICSMixWeight)
+	}
+
+	// The same banner from services that should each have their own
+	for _, count := range banners {
+		if count >= honeypotRepeatedBannerMinimum {
+			add("repeated_banner", honeypotRepeatedBannerWeight)
+			break
+		}
+	}
+
+	if decoy {
+		add("decoy_banner", honeypotDecoyBannerWeight)
+	}
+
+	sort.Strings(signals)
+	if score > 100 {
+		score = 100
+	}
+	return score, signals
+}
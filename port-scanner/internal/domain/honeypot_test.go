@@ -0,0 +1,108 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+)
+
+// hostWith returns a result for an IP with the given open ports, keyed by number with their
+// banner, and closed ports up to total ports scanned
+func hostWith(ip string, open map[int]string, total int) *ScanResult {
+	result := NewScanResult(ip, "batch-1", "worker-1")
+	result.IsUp = true
+	for number, banner := range open {
+		port := NewPort(number)
+		port.Status = PortStatusOpen
+		port.Service = KnownPorts.Service(number)
+		port.Banner = banner
+		result.AddPort(port)
+	}
+	for number := 60000; len(result.Ports) < total; number++ {
+		result.AddPort(NewPort(number))
+	}
+	result.SetCompleted()
+	return result
+}
+
+func TestHoneypotScoreRanksDeceptionHostsAboveNormalOnes(t *testing.T) {
+	web := hostWith("203.0.113.1", map[int]string{
+		22:  "SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.6",
+		80:  "HTTP/1.1 200 OK\r\nServer: nginx/1.18.0 (Ubuntu)",
+		443: "HTTP/1.1 200 OK\r\nServer: nginx/1.18.0 (Ubuntu)",
+	}, 100)
+	mixedOS := hostWith("203.0.113.2", map[int]string{
+		22: "SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.6",
+		80: "HTTP/1.1 200 OK\r\nServer: Microsoft-IIS/10.0",
+	}, 100)
+	cowrie := hostWith("203.0.113.3", map[int]string{
+		22:   "SSH-2.0-OpenSSH_6.0p1 Debian-4+deb7u2",
+		23:   "login:",
+		80:   "HTTP/1.1 200 OK\r\nServer: Microsoft-IIS/7.5",
+		102:  "Siemens, SIMATIC, S7-200",
+		502:  "",
+		3389: "",
+		5900: "RFB 003.008",
+	}, 10)
+
+	scores := make([]int, 0, 3)
+	for _, result := range []*ScanResult{web, mixedOS, cowrie} {
+		score, _ := HoneypotScore(result)
+		scores = append(scores, score)
+	}
+	if scores[0] != 0 {
+		t.Errorf("ordinary web server scored %d, want 0", scores[0])
+	}
+	if !(scores[0] < scores[1] && scores[1] < scores[2]) {
+		t.Errorf("scores = %v, want web < mixed OS < honeypot", scores)
+	}
+
+	_, signals := HoneypotScore(cowrie)
+	want := []string{"conflicting_os_banners", "decoy_banner", "ics_with_unrelated_services", "many_open_ports"}
+	if !reflect.DeepEqual(signals, want) {
+		t.Errorf("honeypot signals = %v, want %v", signals, want)
+	}
+}
+
+func TestHoneypotScoreFlagsRepeatedBannersAndCaps(t *testing.T) {
+	open := make(map[int]string)
+	for port := 1000; port < 1030; port++ {
+		open[port] = "220 Welcome to the ftp service"
+	}
+	open[22] = "SSH-2.0-OpenSSH_8.9p1 Ubuntu"
+	open[3389] = "Microsoft Terminal Services"
+	open[102] = ""
+	open[502] = ""
+	score, signals := HoneypotScore(hostWith("203.0.113.4", open, 40))
+	if score != 100 {
+		t.Errorf("score = %d, want every signal capped at 100", score)
+	}
+	if len(signals) != 5 {
+		t.Errorf("signals = %v, want all five", signals)
+	}
+}
+
+func TestHoneypotEnricherWritesScore(t *testing.T) {
+	result := hostWith("203.0.113.3", map[int]string{22: "SSH-2.0-OpenSSH_5.1p1 Debian-5"}, 5)
+	if err := (HoneypotEnricher{}).Enrich(result); err != nil {
+		t.Fatalf("Enrich returned error: %v", err)
+	}
+	if result.Metadata["honeypot_score"] != honeypotDecoyBannerWeight {
+		t.Errorf("honeypot_score = %v, want %d", result.Metadata["honeypot_score"], honeypotDecoyBannerWeight)
+	}
+	if signals, _ := result.Metadata["honeypot_signals"].([]string); !reflect.DeepEqual(signals, []string{"decoy_banner"}) {
+		t.Errorf("honeypot_signals = %v, want [decoy_banner]", result.Metadata["honeypot_signals"])
+	}
+
+	// Hosts without open ports are left unscored, and clean hosts get no signals
+	down := hostWith("203.0.113.5", nil, 5)
+	clean := hostWith("203.0.113.6", map[int]string{443: "HTTP/1.1 200 OK"}, 5)
+	for _, result := range []*ScanResult{down, clean} {
+		(HoneypotEnricher{}).Enrich(result)
+	}
+	if _, ok := down.Metadata["honeypot_score"]; ok {
+		t.Error("host without open ports was scored")
+	}
+	if clean.Metadata["honeypot_score"] != 0 || clean.Metadata["honeypot_signals"] != nil {
+		t.Errorf("clean host metadata = %v, want a zero score without signals", clean.Metadata)
+	}
+}
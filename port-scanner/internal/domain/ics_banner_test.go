@@ -0,0 +1,128 @@
+package domain
+
+import (
+	"net"
+	"sync"
+	"testing"
+)
+
+// countingGrabber is a BannerGrabber that records the ports it is asked to grab
+type countingGrabber struct {
+	mu    sync.Mutex
+	ports []int
+}
+
+func (g *countingGrabber) GetBanner(ip string, port int) (*BannerInfo, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.ports = append(g.ports, port)
+	return &BannerInfo{Service: "unknown", Protocol: "tcp", RawBanner: "hello"}, nil
+}
+
+func (g *countingGrabber) grabbed() []int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]int(nil), g.ports...)
+}
+
+// listenOn accepts connections on a fixed loopback port, skipping the test if it is taken
+func listenOn(t *testing.T, port int) {
+	t.Helper()
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port})
+	if err != nil {
+		t.Skipf("port %d unavailable: %v", port, err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+}
+
+func newBannerScanner() (*ScannerService, *ScanConfig, *countingGrabber) {
+	config := NewDefaultScanConfig()
+	config.MaxRetries = 0
+	config.RetryDelay = 0
+	config.CrashCheck = false
+	scanner := NewScannerService(config)
+	grabber := &countingGrabber{}
+	scanner.SetBannerGrabber(grabber)
+	return scanner, config, grabber
+}
+
+func TestScanPortSkipsBannerGrabOfICSServices(t *testing.T) {
+	const dnp3Port = 20000
+	listenOn(t, dnp3Port)
+	scanner, _, grabber := newBannerScanner()
+
+	port, err := scanner.ScanPort("127.0.0.1", dnp3Port)
+	if err != nil {
+		t.Fatalf("ScanPort returned error: %v", err)
+	}
+	if port.Status != PortStatusOpen || port.Service != "dnp3" {
+		t.Errorf("port = %s/%q, want open dnp3 identified by port number", port.Status, port.Service)
+	}
+	if port.BannerInfo != nil || port.Banner != "" {
+		t.Errorf("denied port has banner %q", port.Banner)
+	}
+	if got := grabber.grabbed(); len(got) != 0 {
+		t.Errorf("banner grabbed on ports %v, want none", got)
+	}
+
+	// Other services are still banner-grabbed
+	other := openLocalPorts(t, 1)[0]
+	if _, err := scanner.ScanPort("127.0.0.1", other); err != nil {
+		t.Fatalf("ScanPort returned error: %v", err)
+	}
+	if got := grabber.grabbed(); len(got) != 1 || got[0] != other {
+		t.Errorf("banner grabbed on ports %v, want [%d]", got, other)
+	}
+}
+
+func TestScanPortGrabsBannerOfAllowedICSServices(t *testing.T) {
+	const dnp3Port = 20000
+	listenOn(t, dnp3Port)
+	scanner, config, grabber := newBannerScanner()
+	config.BannerAllowServices = []string{"DNP3"}
+
+	port, err := scanner.ScanPort("127.0.0.1", dnp3Port)
+	if err != nil {
+		t.Fatalf("ScanPort returned error: %v", err)
+	}
+	if port.Status != PortStatusOpen || port.BannerInfo == nil {
+		t.Errorf("port = %s with banner %v, want open and banner-grabbed", port.Status, port.BannerInfo)
+	}
+	if got := grabber.grabbed(); len(got) != 1 || got[0] != dnp3Port {
+		t.Errorf("banner grabbed on ports %v, want [%d]", got, dnp3Port)
+	}
+}
+
+func TestBannerAllowed(t *testing.T) {
+	scanner, config, _ := newBannerScanner()
+
+	for _, port := range []int{502, 1911, 20000, 47808} {
+		if scanner.bannerAllowed(port, config) {
+			t.Errorf("bannerAllowed(%d) = true, want ICS ports denied by default", port)
+		}
+	}
+	for _, port := range []int{22, 80, 443} {
+		if !scanner.bannerAllowed(port, config) {
+			t.Errorf("bannerAllowed(%d) = false, want it allowed", port)
+		}
+	}
+
+	config.BannerAllowServices = []string{"modbus"}
+	if !scanner.bannerAllowed(502, config) || scanner.bannerAllowed(47808, config) {
+		t.Error("opting in to modbus did not allow only modbus")
+	}
+
+	config.BannerDenyServices = nil
+	if !scanner.bannerAllowed(47808, config) {
+		t.Error("bannerAllowed(47808) = false with an empty denylist")
+	}
+}
@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// Scan intensity levels, modelled on nmap's T1-T5 timing templates
+const (
+	IntensitySneaky     = 1
+	IntensityPolite     = 2
+	IntensityNormal     = 3
+	IntensityAggressive = 4
+	IntensityInsane     = 5
+)
+
+// IntensitySettings are the coordinated timing options a scan intensity level sets
+type IntensitySettings struct {
+	ConnectTimeout time.Duration
+	Concurrency    int
+	MaxRetries     int
+	RetryDelay     time.Duration
+}
+
+// scanIntensities maps each level to its settings; IntensityNormal matches the default config
+var scanIntensities = map[int]IntensitySettings{
+	IntensitySneaky:     {ConnectTimeout: 10 * time.Second, Concurrency: 1, MaxRetries: 3, RetryDelay: 5 * time.Second},
+	IntensityPolite:     {ConnectTimeout: 5 * time.Second, Concurrency: 10, MaxRetries: 3, RetryDelay: 2 * time.Second},
+	IntensityNormal:     {ConnectTimeout: 3 * time.Second, Concurrency: 100, MaxRetries: 3, RetryDelay: 1 * time.Second},
+	IntensityAggressive: {ConnectTimeout: 1500 * time.Millisecond, Concurrency: 300, MaxRetries: 2, RetryDelay: 500 * time.Millisecond},
+	IntensityInsane:     {ConnectTimeout: 750 * time.Millisecond, Concurrency: 1000, MaxRetries: 1, RetryDelay: 250 * time.Millisecond},
+}
+
+// GetIntensitySettings returns the settings for a scan intensity level (1-5)
+func GetIntensitySettings(level int) (IntensitySettings, error) {
+	settings, ok := scanIntensities[level]
+	if !ok {
+		return IntensitySettings{}, fmt.Errorf("invalid scan intensity %d: must be between %d and %d", level, IntensitySneaky, IntensityInsane)
+	}
+	return settings, nil
+}
+
+// ApplyIntensity overrides the timeout, concurrency, retry and delay options with those of a level
+func (c *ScanConfig) ApplyIntensity(level int) error {
+	settings, err := GetIntensitySettings(level)
+	if err != nil {
+		return err
+	}
+
+	c.ConnectTimeout = settings.ConnectTimeout
+	c.Concurrency = settings.Concurrency
+	c.MaxRetries = settings.MaxRetries
+	c.RetryDelay = settings.RetryDelay
+	return nil
+}
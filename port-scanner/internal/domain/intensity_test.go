@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApplyIntensityMapsLevels(t *testing.T) {
+	tests := map[int]IntensitySettings{
+		IntensitySneaky:     {ConnectTimeout: 10 * time.Second, Concurrency: 1, MaxRetries: 3, RetryDelay: 5 * time.Second},
+		IntensityPolite:     {ConnectTimeout: 5 * time.Second, Concurrency: 10, MaxRetries: 3, RetryDelay: 2 * time.Second},
+		IntensityNormal:     {ConnectTimeout: 3 * time.Second, Concurrency: 100, MaxRetries: 3, RetryDelay: time.Second},
+		IntensityAggressive: {ConnectTimeout: 1500 * time.Millisecond, Concurrency: 300, MaxRetries: 2, RetryDelay: 500 * time.Millisecond},
+		IntensityInsane:     {ConnectTimeout: 750 * time.Millisecond, Concurrency: 1000, MaxRetries: 1, RetryDelay: 250 * time.Millisecond},
+	}
+	for level, want := range tests {
+		config := NewDefaultScanConfig()
+		if err := config.ApplyIntensity(level); err != nil {
+			t.Fatalf("ApplyIntensity(%d) returned error: %v", level, err)
+		}
+		got := IntensitySettings{ConnectTimeout: config.ConnectTimeout, Concurrency: config.Concurrency, MaxRetries: config.MaxRetries, RetryDelay: config.RetryDelay}
+		if got != want {
+			t.Errorf("intensity %d applied %+v, want %+v", level, got, want)
+		}
+	}
+}
+
+func TestNormalIntensityMatchesDefaults(t *testing.T) {
+	defaults := NewDefaultScanConfig()
+	config := NewDefaultScanConfig()
+	if err := config.ApplyIntensity(IntensityNormal); err != nil {
+		t.Fatalf("ApplyIntensity returned error: %v", err)
+	}
+	if config.ConnectTimeout != defaults.ConnectTimeout || config.Concurrency != defaults.Concurrency ||
+		config.MaxRetries != defaults.MaxRetries || config.RetryDelay != defaults.RetryDelay {
+		t.Errorf("normal intensity changed the default timing options")
+	}
+}
+
+func TestApplyIntensityRejectsUnknownLevels(t *testing.T) {
+	for _, level := range []int{0, 6, -1} {
+		config := NewDefaultScanConfig()
+		if err := config.ApplyIntensity(level); err == nil {
+			t.Errorf("ApplyIntensity(%d) returned no error", level)
+		}
+		if config.Concurrency != NewDefaultScanConfig().Concurrency {
+			t.Errorf("ApplyIntensity(%d) changed the config despite failing", level)
+		}
+	}
+}
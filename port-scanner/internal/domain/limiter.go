@@ -0,0 +1,109 @@
+package domain
+
+import "sync"
+
+const (
+	// limiterWindow is the number of connect samples evaluated per adjustment
+	limiterWindow = 50
+	// limiterHighErrorRate halves the limit when exceeded within a window
+	limiterHighErrorRate = 0.2
+	// limiterLowErrorRate grows the limit when not exceeded within a window
+	limiterLowErrorRate = 0.05
+	// limiterIncreaseStep is the additive increase applied on a healthy window
+	limiterIncreaseStep = 10
+)
+
+// AdaptiveLimiter is a resizable concurrency limiter that tunes its limit
+// from the observed connect error rate (additive increase, multiplicative decrease).
+type AdaptiveLimiter struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	limit   int
+	inUse   int
+	min     int
+	max     int
+	samples int
+	errors  int
+}
+
+// NewAdaptiveLimiter creates a limiter starting at initial, bounded by min and max
+func NewAdaptiveLimiter(initial, min, max int) *AdaptiveLimiter {
+	if min < 1 {
+		min = 1
+	}
+	if max < min {
+		max = min
+	}
+
+	l := &AdaptiveLimiter{
+		limit: clampInt(initial, min, max),
+		min:   min,
+		max:   max,
+	}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// Acquire blocks until a slot is available under the current limit
+func (l *AdaptiveLimiter) Acquire() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for l.inUse >= l.limit {
+		l.cond.Wait()
+	}
+	l.inUse++
+}
+
+// Release frees a slot acquired with Acquire
+func (l *AdaptiveLimiter) Release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inUse--
+	l.cond.Signal()
+}
+
+// Record registers the outcome of a connect attempt and adjusts the limit once per window
+func (l *AdaptiveLimiter) Record(failed bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.samples++
+	if failed {
+		l.errors++
+	}
+	if l.samples < limiterWindow {
+		return
+	}
+
+	errorRate := float64(l.errors) / float64(l.samples)
+	l.samples = 0
+	l.errors = 0
+
+	switch {
+	case errorRate > limiterHighErrorRate:
+		l.limit = clampInt(l.limit/2, l.min, l.max)
+	case errorRate < limiterLowErrorRate:
+		l.limit = clampInt(l.limit+limiterIncreaseStep, l.min, l.max)
+		l.cond.Broadcast()
+	}
+}
+
+// Limit returns the current concurrency limit
+func (l *AdaptiveLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// clampInt bounds value to the [min, max] range
+func clampInt(value, min, max int) int {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
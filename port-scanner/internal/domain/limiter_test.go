@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptiveLimiterReducesLimitOnHighErrorRate(t *testing.T) {
+	limiter := NewAdaptiveLimiter(40, 5, 100)
+
+	for i := 0; i < limiterWindow; i++ {
+		limiter.Record(i%2 == 0)
+	}
+	if got := limiter.Limit(); got != 20 {
+		t.Fatalf("Limit() after a window at 50%% errors = %d, want 20", got)
+	}
+
+	// Repeated failing windows never push the limit below its floor
+	for i := 0; i < 10*limiterWindow; i++ {
+		limiter.Record(true)
+	}
+	if got := limiter.Limit(); got != 5 {
+		t.Errorf("Limit() after failing windows = %d, want the minimum 5", got)
+	}
+}
+
+func TestAdaptiveLimiterRaisesLimitOnLowErrorRate(t *testing.T) {
+	limiter := NewAdaptiveLimiter(10, 5, 25)
+
+	for i := 0; i < 5*limiterWindow; i++ {
+		limiter.Record(false)
+	}
+	if got := limiter.Limit(); got != 25 {
+		t.Errorf("Limit() after healthy windows = %d, want the maximum 25", got)
+	}
+}
+
+func TestAdaptiveLimiterBlocksAtLimit(t *testing.T) {
+	limiter := NewAdaptiveLimiter(1, 1, 1)
+	limiter.Acquire()
+
+	acquired := make(chan struct{})
+	go func() {
+		limiter.Acquire()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("Acquire returned while the only slot was taken")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	limiter.Release()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("Acquire did not return after a release")
+	}
+	limiter.Release()
+}
+
+func TestScanPortsReducesAdaptiveConcurrencyOnFailingDials(t *testing.T) {
+	config := NewDefaultScanConfig()
+	config.EnableBanner = false
+	config.MaxRetries = 0
+	config.RetryDelay = 0
+	config.AdaptiveConcurrency = true
+	config.Concurrency = 40
+	config.MinConcurrency = 5
+	config.MaxConcurrency = 100
+	scanner := NewScannerService(config)
+
+	// Out-of-range ports fail to dial at once, simulating a host that drops every probe
+	ports := make([]int, 2*limiterWindow)
+	for i := range ports {
+		ports[i] = 70000 + i
+	}
+	if _, err := scanner.ScanPorts("127.0.0.1", ports); err != nil {
+		t.Fatalf("ScanPorts returned error: %v", err)
+	}
+
+	if got := scanner.ConcurrencyLimit(); got >= config.Concurrency {
+		t.Errorf("ConcurrencyLimit() after failing dials = %d, want below %d", got, config.Concurrency)
+	}
+}
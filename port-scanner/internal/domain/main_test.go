@@ -0,0 +1,13 @@
+package domain
+
+import (
+	"os"
+	"testing"
+
+	"port-scanner/pkg/log"
+)
+
+func TestMain(m *testing.M) {
+	log.InitLogger("domain-test")
+	os.Exit(m.Run())
+}
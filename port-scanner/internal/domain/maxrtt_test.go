@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"port-scanner/internal/infrastructure/ping"
+)
+
+// rttPinger reports every host as up after the given round-trip time, out of a longer ping
+type rttPinger struct {
+	rtt time.Duration
+}
+
+func (p rttPinger) PingHost(ip string) (*ping.PingResult, error) {
+	return &ping.PingResult{IsUp: true, Duration: time.Second, RTT: p.rtt}, nil
+}
+
+// newRTTScanner returns a scanner whose pings take rtt, skipping hosts slower than maxRTT
+func newRTTScanner(t *testing.T, rtt, maxRTT time.Duration) (*ScannerService, *ScanConfig) {
+	scanner, config := newLocalScanner()
+	config.EnablePing = true
+	config.MaxPingRTT = maxRTT
+	config.PortRange = openLocalPorts(t, 2)
+	scanner.pingService = rttPinger{rtt: rtt}
+	return scanner, config
+}
+
+func TestScanIPSkipsHostAboveMaxPingRTT(t *testing.T) {
+	scanner, config := newRTTScanner(t, 400*time.Millisecond, 200*time.Millisecond)
+
+	result, err := scanner.ScanIP("127.0.0.1", config, "batch-1", "worker-1")
+	if err != nil {
+		t.Fatalf("ScanIP returned error: %v", err)
+	}
+	if result.IsUp || result.Status != ScanStatusCompleted || len(result.Ports) != 0 {
+		t.Errorf("result = up %v, %s with %d ports, want a completed down host without port scans",
+			result.IsUp, result.Status, len(result.Ports))
+	}
+	if result.Metadata["ping_rtt_exceeded"] != true {
+		t.Errorf("metadata = %v, want ping_rtt_exceeded", result.Metadata)
+	}
+}
+
+func TestScanIPScansHostWithinMaxPingRTT(t *testing.T) {
+	// The ping took a second waiting for lost packets, but the replies came back quickly
+	scanner, config := newRTTScanner(t, 20*time.Millisecond, 200*time.Millisecond)
+
+	result, err := scanner.ScanIP("127.0.0.1", config, "batch-1", "worker-1")
+	if err != nil {
+		t.Fatalf("ScanIP returned error: %v", err)
+	}
+	if !result.IsUp || len(result.GetOpenPorts()) != 2 {
+		t.Errorf("result = up %v with %d open ports, want both ports scanned", result.IsUp, len(result.GetOpenPorts()))
+	}
+
+	// Without a threshold, slow hosts are scanned too
+	scanner, config = newRTTScanner(t, 400*time.Millisecond, 0)
+	if result, _ := scanner.ScanIP("127.0.0.1", config, "batch-1", "worker-1"); !result.IsUp || len(result.Ports) != 2 {
+		t.Errorf("without MaxPingRTT, result = up %v with %d ports, want the host scanned", result.IsUp, len(result.Ports))
+	}
+}
+
+func TestPingHostPrefersRoundTripTime(t *testing.T) {
+	scanner, _ := newLocalScanner()
+	scanner.pingService = rttPinger{rtt: 15 * time.Millisecond}
+	if _, elapsed, _ := scanner.PingHost("192.0.2.1"); elapsed != 15*time.Millisecond {
+		t.Errorf("PingHost time = %v, want the 15ms round trip", elapsed)
+	}
+
+	scanner.pingService = rttPinger{}
+	if _, elapsed, _ := scanner.PingHost("192.0.2.1"); elapsed != time.Second {
+		t.Errorf("PingHost time without an RTT = %v, want the 1s ping duration", elapsed)
+	}
+}
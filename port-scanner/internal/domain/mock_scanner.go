@@ -0,0 +1,181 @@
+package domain
+
+import (
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"port-scanner/pkg/log"
+
+	"go.uber.org/zap"
+)
+
+// MockScannerConfig describes the synthetic results of the mock scanner
+type MockScannerConfig struct {
+	Seed      int64            // Changes which ports are open on hosts without fixed ports
+	OpenRatio float64          // Share of scanned ports reported open on hosts without fixed ports
+	HostPorts map[string][]int // Fixed open ports per IP; other scanned ports are closed
+	Banners   map[int]string   // Banner per port; ports without one get a banner naming their service
+}
+
+// mockBanners are the banners of common services when the configuration sets none
+var mockBanners = map[int]string{
+	21:  "220 ProFTPD 1.3.5 Server ready.",
+	22:  "SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.1",
+	25:  "220 mail.example.com ESMTP Postfix",
+	80:  "HTTP/1.1 200 OK\r\nServer: nginx/1.18.0",
+	443: "HTTP/1.1 200 OK\r\nServer: nginx/1.18.0",
+}
+
+// MockScanner implements Scanner with deterministic synthetic results and never sends packets,
+// so the pipeline can run in CI and demos. The same seed, IP and ports always give the same
+// open ports and banners; results are marked with the "mock" metadata key.
+type MockScanner struct {
+	config    *ScanConfig
+	mock      MockScannerConfig
+	enrichers *EnricherChain
+}
+
+// Ensure MockScanner implements Scanner interface
+var _ Scanner = (*MockScanner)(nil)
+
+// NewMockScanner creates a mock scanner using config for the ports to scan
+func NewMockScanner(config *ScanConfig, mock MockScannerConfig) *MockScanner {
+	return &MockScanner{
+		config: config,
+		mock:   mock,
+	}
+}
+
+// SetEnrichers sets the enrichers run on every synthetic result
+func (m *MockScanner) SetEnrichers(chain *EnricherChain) {
+	m.enrichers = chain
+}
+
+// PingHost reports every host as up
+func (m *MockScanner) PingHost(ip string) (bool, time.Duration, error) {
+	return true, m.responseTime(ip, 0), nil
+}
+
+// ScanPort returns the synthetic state of a port
+func (m *MockScanner) ScanPort(ip string, port int) (*Port, error) {
+	result := NewPort(port)
+	result.ResponseTime = m.responseTime(ip, port)
+	if m.isOpen(ip, port) {
+		result.Status = PortStatusOpen
+		result.Service = KnownPorts.Service(port)
+	}
+	return result, nil
+}
+
+// ScanPorts scans each port with ScanPort
+func (m *MockScanner) ScanPorts(ip string, ports []int) ([]*Port, error) {
+	results := make([]*Port, 0, len(ports))
+	for _, port := range ports {
+		result, _ := m.ScanPort(ip, port)
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// GetBanner returns the synthetic banner of an open port
+func (m *MockScanner) GetBanner(ip string, port int) (*BannerInfo, error) {
+	if !m.isOpen(ip, port) {
+		return nil, fmt.Errorf("port %d is closed on %s", port, ip)
+	}
+
+	banner, ok := m.mock.Banners[port]
+	if !ok {
+		banner, ok = mockBanners[port]
+	}
+	if !ok {
+		banner = fmt.Sprintf("%s service ready", KnownPorts.Service(port))
+	}
+	info := &BannerInfo{
+		RawBanner:  banner,
+		Service:    KnownPorts.Service(port),
+		Protocol:   "tcp",
+		Confidence: "port",
+	}
+	info.ScoreConfidence(port)
+	return info, nil
+}
+
+// ScanIP builds the synthetic result of an IP from the ports the scan options name
+func (m *MockScanner) ScanIP(ip string, config *ScanConfig, batchID string, workerID string) (*ScanResult, error) {
+	if config == nil {
+		config = m.config
+	}
+
+	result := NewScanResult(ip, batchID, workerID)
+	if err := checkExclusions(ip, config.Exclusions); err != nil {
+		result.SetFailed(err.Error())
+		return result, err
+	}
+	if err := m.CheckTechnique(config.Technique); err != nil {
+		result.SetFailed(err.Error())
+		return result, err
+	}
+
+	result.IsUp = true
+	result.PingTime = m.responseTime(ip, 0)
+
+	portsToScan := config.DefaultPorts
+	if len(config.PortRange) > 0 {
+		portsToScan = config.PortRange
+	}
+	ports, _ := m.ScanPorts(ip, portsToScan)
+	for _, port := range ports {
+		if port.Status == PortStatusOpen && config.EnableBanner {
+			if info, err := m.GetBanner(ip, port.Number); err == nil {
+				port.Banner = info.RawBanner
+				port.BannerInfo = info
+			}
+		}
+		result.AddPort(port)
+	}
+
+	result.SetMetadata("mock", true)
+	result.SetMetadata("scan_config", config.Snapshot())
+	result.SetCompleted()
+	m.enrichers.Run(result)
+
+	log.L().Debug("Mock scan completed", zap.String("event", "mock_scan_completed"), zap.String("ip", ip), zap.Int("open_ports", len(result.GetOpenPorts())))
+	return result, nil
+}
+
+// CheckTechnique accepts every known technique, since none is actually used
+func (m *MockScanner) CheckTechnique(technique string) error {
+	switch technique {
+	case TechniqueConnect, TechniqueSYN, "":
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedTechnique, technique)
+	}
+}
+
+// isOpen reports whether a port is open on an IP: one of its fixed ports if it has any,
+// otherwise open for the configured share of ports as picked by the seed
+func (m *MockScanner) isOpen(ip string, port int) bool {
+	if fixed, ok := m.mock.HostPorts[ip]; ok {
+		for _, open := range fixed {
+			if open == port {
+				return true
+			}
+		}
+		return false
+	}
+	return float64(m.hash(ip, port)%10000) < m.mock.OpenRatio*10000
+}
+
+// responseTime returns a stable response time between 1ms and 50ms
+func (m *MockScanner) responseTime(ip string, port int) time.Duration {
+	return time.Duration(1+m.hash(ip, port)%50) * time.Millisecond
+}
+
+// hash mixes the seed, IP and port into a stable value
+func (m *MockScanner) hash(ip string, port int) uint64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d/%s/%d", m.mock.Seed, ip, port)
+	return h.Sum64()
+}
@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+)
+
+// mockOpenPorts scans ports with a mock scanner and returns the open ones with their banners
+func mockOpenPorts(t *testing.T, scanner *MockScanner, ip string, ports []int) map[int]string {
+	t.Helper()
+	config := NewDefaultScanConfig()
+	config.PortRange = ports
+	result, err := scanner.ScanIP(ip, config, "batch-1", "worker-1")
+	if err != nil {
+		t.Fatalf("ScanIP returned error: %v", err)
+	}
+	open := make(map[int]string)
+	for _, port := range result.GetOpenPorts() {
+		open[port.Number] = port.Banner
+	}
+	return open
+}
+
+func TestMockScannerIsDeterministic(t *testing.T) {
+	ports := make([]int, 0, 200)
+	for port := 1; port <= 200; port++ {
+		ports = append(ports, port)
+	}
+	mock := MockScannerConfig{Seed: 7, OpenRatio: 0.3}
+
+	first := mockOpenPorts(t, NewMockScanner(NewDefaultScanConfig(), mock), "203.0.113.5", ports)
+	second := mockOpenPorts(t, NewMockScanner(NewDefaultScanConfig(), mock), "203.0.113.5", ports)
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("same seed gave different results:\n%v\n%v", first, second)
+	}
+	if len(first) < 30 || len(first) > 90 {
+		t.Errorf("%d of 200 ports open, want about 30%%", len(first))
+	}
+
+	reseeded := mockOpenPorts(t, NewMockScanner(NewDefaultScanConfig(), MockScannerConfig{Seed: 8, OpenRatio: 0.3}), "203.0.113.5", ports)
+	if reflect.DeepEqual(first, reseeded) {
+		t.Error("a different seed gave the same open ports")
+	}
+}
+
+func TestMockScannerUsesFixedHostPortsAndBanners(t *testing.T) {
+	scanner := NewMockScanner(NewDefaultScanConfig(), MockScannerConfig{
+		OpenRatio: 1,
+		HostPorts: map[string][]int{"203.0.113.5": {22, 6379, 8443}},
+		Banners:   map[int]string{6379: "+PONG"},
+	})
+
+	open := mockOpenPorts(t, scanner, "203.0.113.5", []int{21, 22, 80, 6379, 8443})
+	want := map[int]string{
+		22:   mockBanners[22],
+		6379: "+PONG",
+		8443: KnownPorts.Service(8443) + " service ready",
+	}
+	if !reflect.DeepEqual(open, want) {
+		t.Errorf("open ports = %v, want %v", open, want)
+	}
+
+	// Hosts without fixed ports fall back to the open ratio
+	if open := mockOpenPorts(t, scanner, "203.0.113.6", []int{21, 80}); len(open) != 2 {
+		t.Errorf("open ports = %v, want both with an open ratio of 1", open)
+	}
+	if _, err := scanner.GetBanner("203.0.113.5", 21); err == nil {
+		t.Error("GetBanner returned a banner for a closed port")
+	}
+}
+
+func TestMockScannerMarksResults(t *testing.T) {
+	scanner := NewMockScanner(NewDefaultScanConfig(), MockScannerConfig{})
+	config := NewDefaultScanConfig()
+
+	result, err := scanner.ScanIP("203.0.113.5", config, "batch-1", "worker-1")
+	if err != nil {
+		t.Fatalf("ScanIP returned error: %v", err)
+	}
+	if result.Metadata["mock"] != true || !result.IsUp || result.Status != ScanStatusCompleted {
+		t.Errorf("result = up %v, %s, metadata %v, want a completed mock result", result.IsUp, result.Status, result.Metadata)
+	}
+	if len(result.Ports) != len(config.DefaultPorts) || len(result.GetOpenPorts()) != 0 {
+		t.Errorf("scanned %d ports with %d open, want the %d default ports closed", len(result.Ports), len(result.GetOpenPorts()), len(config.DefaultPorts))
+	}
+
+	config.Technique = "xmas"
+	if _, err := scanner.ScanIP("203.0.113.5", config, "batch-1", "worker-1"); err == nil {
+		t.Error("ScanIP accepted an unknown technique")
+	}
+}
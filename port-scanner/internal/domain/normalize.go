@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// NormalizeBanner cleans a banner for storage and search: ANSI escape sequences are removed,
+// other control characters and invalid UTF-8 bytes are escaped as \xNN, line endings become
+// "\n", runs of spaces and tabs become one space, lines are trimmed and consecutive blank
+// lines are collapsed into one
+func NormalizeBanner(banner string) string {
+	var cleaned strings.Builder
+	cleaned.Grow(len(banner))
+	for i := 0; i < len(banner); {
+		if banner[i] == 0x1b {
+			i += ansiSequenceLength(banner[i:])
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(banner[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			fmt.Fprintf(&cleaned, "\\x%02x", banner[i])
+		case r == '\r':
+			// CRLF and lone CR both end a line
+			if i+1 >= len(banner) || banner[i+1] != '\n' {
+				cleaned.WriteByte('\n')
+			}
+		case r == '\n' || r == '\t':
+			cleaned.WriteRune(r)
+		case unicode.IsControl(r):
+			fmt.Fprintf(&cleaned, "\\x%02x", r)
+		default:
+			cleaned.WriteRune(r)
+		}
+		i += size
+	}
+
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(cleaned.String(), "\n") {
+		line = strings.Join(strings.FieldsFunc(line, func(r rune) bool { return r == ' ' || r == '\t' }), " ")
+		if line == "" {
+			if blank || len(lines) == 0 {
+				continue
+			}
+			blank = true
+		} else {
+			blank = false
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// ansiSequenceLength returns the length of the escape sequence at the start of s, which
+// begins with ESC: a CSI sequence ("ESC [" ... final byte), an OSC string ("ESC ]" ... BEL
+// or "ESC \"), or a two-byte escape
+func ansiSequenceLength(s string) int {
+	if len(s) < 2 {
+		return len(s)
+	}
+
+	switch s[1] {
+	case '[':
+		for i := 2; i < len(s); i++ {
+			if s[i] >= 0x40 && s[i] <= 0x7e {
+				return i + 1
+			}
+		}
+		return len(s)
+	case ']':
+		for i := 2; i < len(s); i++ {
+			if s[i] == 0x07 {
+				return i + 1
+			}
+			if s[i] == 0x1b && i+1 < len(s) && s[i+1] == '\\' {
+				return i + 2
+			}
+		}
+		return len(s)
+	default:
+		return 2
+	}
+}
+
+// NormalizeRawBanner normalizes the raw banner in place with NormalizeBanner, keeping the
+// original under "raw_banner_original" in the metadata, cut to maxOriginalLength bytes
+// (0 keeps it whole). It reports whether the banner changed.
+func (b *BannerInfo) NormalizeRawBanner(maxOriginalLength int) bool {
+	if b == nil {
+		return false
+	}
+
+	normalized := NormalizeBanner(b.RawBanner)
+	if normalized == b.RawBanner {
+		return false
+	}
+
+	if b.Metadata == nil {
+		b.Metadata = make(map[string]interface{})
+	}
+	b.Metadata["raw_banner_original"], _ = TruncateBanner(b.RawBanner, maxOriginalLength)
+	b.RawBanner = normalized
+	return true
+}
@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+// rawGrabber is a BannerGrabber returning a fixed raw banner
+type rawGrabber string
+
+func (g rawGrabber) GetBanner(ip string, port int) (*BannerInfo, error) {
+	return &BannerInfo{Service: "telnet", Protocol: "tcp", RawBanner: string(g)}, nil
+}
+
+func TestNormalizeBannerStripsANSIAndControlCharacters(t *testing.T) {
+	cases := map[string]string{
+		"\x1b[1;32mWelcome\x1b[0m to  the\tserver\r\n": "Welcome to the server",
+		"login:\x00\x00 ":                                   `login:\x00\x00`,
+		"\x1b]0;router\x07Password:":                        "Password:",
+		"line one\r\n\r\n\r\n\r\n   line two  \rline three": "line one\n\nline two\nline three",
+		"bad \xff byte\x7f":                                 `bad \xff byte\x7f`,
+		"SSH-2.0-OpenSSH_9.6":                               "SSH-2.0-OpenSSH_9.6",
+	}
+	for raw, want := range cases {
+		if got := NormalizeBanner(raw); got != want {
+			t.Errorf("NormalizeBanner(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestNormalizeRawBannerKeepsOriginal(t *testing.T) {
+	raw := "\x1b[2J\x1b[HWelcome\x00 to\r\n\r\n\r\nBusyBox"
+	info := &BannerInfo{RawBanner: raw}
+	if !info.NormalizeRawBanner(0) {
+		t.Fatal("NormalizeRawBanner reported no change for a banner with ANSI codes")
+	}
+	if info.RawBanner != `Welcome\x00 to`+"\n\nBusyBox" {
+		t.Errorf("RawBanner = %q, want it cleaned", info.RawBanner)
+	}
+	if info.Metadata["raw_banner_original"] != raw {
+		t.Errorf("raw_banner_original = %q, want the untouched banner", info.Metadata["raw_banner_original"])
+	}
+
+	clean := &BannerInfo{RawBanner: "220 ftp ready"}
+	if clean.NormalizeRawBanner(0) || clean.Metadata != nil {
+		t.Errorf("clean banner was flagged: %v", clean.Metadata)
+	}
+
+	long := &BannerInfo{RawBanner: "\x1b[0m" + strings.Repeat("x", 50)}
+	long.NormalizeRawBanner(10)
+	if original := long.Metadata["raw_banner_original"].(string); !strings.HasPrefix(original, "\x1b[0m") || !strings.Contains(original, "[truncated") {
+		t.Errorf("raw_banner_original = %q, want the original cut to 10 bytes", original)
+	}
+}
+
+func TestGetBannerNormalizesWhenEnabled(t *testing.T) {
+	raw := "\x1b[1mUser Access Verification\x1b[0m\r\n\x00Username: "
+
+	for _, enabled := range []bool{false, true} {
+		config := NewDefaultScanConfig()
+		config.NormalizeBanners = enabled
+		scanner := NewScannerService(config)
+		scanner.SetBannerGrabber(rawGrabber(raw))
+
+		info, err := scanner.GetBanner("192.0.2.1", 23)
+		if err != nil {
+			t.Fatalf("GetBanner returned error: %v", err)
+		}
+		if !enabled {
+			if info.RawBanner != raw || info.Metadata["raw_banner_original"] != nil {
+				t.Errorf("with normalization off, banner = %q, metadata = %v, want it untouched", info.RawBanner, info.Metadata)
+			}
+			continue
+		}
+		if want := "User Access Verification\n\\x00Username:"; info.RawBanner != want {
+			t.Errorf("RawBanner = %q, want %q", info.RawBanner, want)
+		}
+		if info.Metadata["raw_banner_original"] != raw {
+			t.Errorf("raw_banner_original = %q, want the grabbed banner", info.Metadata["raw_banner_original"])
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ConnectTimeoutFor returns the connect timeout for a port, using its override when one is set
+func (c *ScanConfig) ConnectTimeoutFor(port int) time.Duration {
+	if timeout, ok := c.PortTimeouts[port]; ok && timeout > 0 {
+		return timeout
+	}
+	return c.ConnectTimeout
+}
+
+// BannerTimeouts returns the connect and read timeouts of a basic banner grab, each falling
+// back to BannerTimeout when unset
+func (c *ScanConfig) BannerTimeouts() (connect, read time.Duration) {
+	connect, read = c.BannerConnectTimeout, c.BannerReadTimeout
+	if connect <= 0 {
+		connect = c.BannerTimeout
+	}
+	if read <= 0 {
+		read = c.BannerTimeout
+	}
+	return connect, read
+}
+
+// ParsePortTimeouts decodes a port to connect timeout map as read from configuration
+func ParsePortTimeouts(timeouts map[string]string) (map[int]time.Duration, error) {
+	parsed := make(map[int]time.Duration, len(timeouts))
+	for portValue, value := range timeouts {
+		port, err := parsePortNumber(strings.TrimSpace(portValue))
+		if err != nil {
+			return nil, fmt.Errorf("invalid port timeout port %q: %w", portValue, err)
+		}
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid timeout %q for port %d: expected a positive duration", value, port)
+		}
+		parsed[port] = timeout
+	}
+	return parsed, nil
+}
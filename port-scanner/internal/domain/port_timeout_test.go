@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestConnectTimeoutForUsesOverrides(t *testing.T) {
+	config := NewDefaultScanConfig()
+	config.ConnectTimeout = 2 * time.Second
+	config.PortTimeouts = map[int]time.Duration{3389: 8 * time.Second, 1521: 0}
+
+	if got := config.ConnectTimeoutFor(3389); got != 8*time.Second {
+		t.Errorf("ConnectTimeoutFor(3389) = %v, want the 8s override", got)
+	}
+	if got := config.ConnectTimeoutFor(80); got != 2*time.Second {
+		t.Errorf("ConnectTimeoutFor(80) = %v, want the 2s default", got)
+	}
+	if got := config.ConnectTimeoutFor(1521); got != 2*time.Second {
+		t.Errorf("ConnectTimeoutFor(1521) with a zero override = %v, want the 2s default", got)
+	}
+}
+
+// unresponsiveLocalPort returns a local port whose listen backlog is full, so further
+// connection attempts get no answer and run into their deadline
+func unresponsiveLocalPort(t *testing.T) int {
+	t.Helper()
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatalf("socket returned error: %v", err)
+	}
+	t.Cleanup(func() { syscall.Close(fd) })
+	if err := syscall.Bind(fd, &syscall.SockaddrInet4{Addr: [4]byte{127, 0, 0, 1}}); err != nil {
+		t.Fatalf("bind returned error: %v", err)
+	}
+	if err := syscall.Listen(fd, 0); err != nil {
+		t.Fatalf("listen returned error: %v", err)
+	}
+	addr, err := syscall.Getsockname(fd)
+	if err != nil {
+		t.Fatalf("getsockname returned error: %v", err)
+	}
+	port := addr.(*syscall.SockaddrInet4).Port
+
+	// Fill the backlog; the listener never accepts
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		t.Fatalf("failed to fill the backlog: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return port
+}
+
+func TestScanPortWaitsForPortTimeoutOverride(t *testing.T) {
+	scanner, config := newLocalScanner()
+	slow, other := unresponsiveLocalPort(t), unresponsiveLocalPort(t)
+	config.ConnectTimeout = 50 * time.Millisecond
+	config.PortTimeouts = map[int]time.Duration{slow: 400 * time.Millisecond}
+
+	elapsed := func(port int) time.Duration {
+		start := time.Now()
+		if _, err := scanner.ScanPort("127.0.0.1", port); err != nil {
+			t.Fatalf("ScanPort(%d) returned error: %v", port, err)
+		}
+		return time.Since(start)
+	}
+
+	if d := elapsed(other); d >= 300*time.Millisecond {
+		t.Errorf("port without override took %v, want the 50ms default deadline", d)
+	}
+	if d := elapsed(slow); d < 350*time.Millisecond {
+		t.Errorf("port with a 400ms override gave up after %v", d)
+	}
+}
+
+func TestParsePortTimeouts(t *testing.T) {
+	parsed, err := ParsePortTimeouts(map[string]string{"3306": "5s", " 3389 ": "8s"})
+	if err != nil {
+		t.Fatalf("ParsePortTimeouts returned error: %v", err)
+	}
+	if parsed[3306] != 5*time.Second || parsed[3389] != 8*time.Second {
+		t.Errorf("ParsePortTimeouts = %v, want 3306:5s 3389:8s", parsed)
+	}
+
+	for _, invalid := range []map[string]string{{"70000": "5s"}, {"ssh": "5s"}, {"22": "soon"}, {"22": "-1s"}} {
+		if _, err := ParsePortTimeouts(invalid); err == nil {
+			t.Errorf("ParsePortTimeouts(%v) returned no error", invalid)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// Orders in which an IP's ports are scanned; the empty order keeps the requested order
+const (
+	PortOrderAsc           = "asc"            // Lowest port number first
+	PortOrderDesc          = "desc"           // Highest port number first
+	PortOrderRandom        = "random"         // Shuffled on every scan
+	PortOrderPriorityFirst = "priority-first" // PriorityPorts first, then the rest, each in requested order
+)
+
+// CheckPortOrder returns an error if order isn't a known port order
+func CheckPortOrder(order string) error {
+	switch order {
+	case "", PortOrderAsc, PortOrderDesc, PortOrderRandom, PortOrderPriorityFirst:
+		return nil
+	}
+	return fmt.Errorf("unknown port order %q", order)
+}
+
+// orderPorts reorders the indexes of ports to be scanned in the given order. Only the order
+// ports are handed to the workers changes: results are still stored at their index. With more
+// than one worker, probes overlap and may finish or even connect out of order, so the order is
+// exact on the wire only at a concurrency of 1.
+func orderPorts(ports []int, indexes []int, order string, priority []int) []int {
+	ordered := append([]int(nil), indexes...)
+	switch order {
+	case PortOrderAsc:
+		sort.SliceStable(ordered, func(i, j int) bool { return ports[ordered[i]] < ports[ordered[j]] })
+	case PortOrderDesc:
+		sort.SliceStable(ordered, func(i, j int) bool { return ports[ordered[i]] > ports[ordered[j]] })
+	case PortOrderRandom:
+		rand.Shuffle(len(ordered), func(i, j int) { ordered[i], ordered[j] = ordered[j], ordered[i] })
+	case PortOrderPriorityFirst:
+		first, rest := splitPorts(ports, ordered, priority)
+		ordered = append(first, rest...)
+	}
+	return ordered
+}
@@ -0,0 +1,126 @@
+package domain
+
+import (
+	"net"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// orderedPorts returns the port numbers of indexes in order
+func orderedPorts(ports []int, indexes []int) []int {
+	out := make([]int, len(indexes))
+	for i, index := range indexes {
+		out[i] = ports[index]
+	}
+	return out
+}
+
+// assertEachPortOnce fails the test unless got holds every port of want exactly once
+func assertEachPortOnce(t *testing.T, got []int, want []int) {
+	t.Helper()
+	sortedGot := append([]int(nil), got...)
+	sortedWant := append([]int(nil), want...)
+	sort.Ints(sortedGot)
+	sort.Ints(sortedWant)
+	if !reflect.DeepEqual(sortedGot, sortedWant) {
+		t.Errorf("ports %v, want each of %v exactly once", got, want)
+	}
+}
+
+func TestOrderPortsSequences(t *testing.T) {
+	ports := []int{443, 22, 8080, 80, 3306}
+	priority := []int{80, 443}
+	indexes := []int{0, 1, 2, 3, 4}
+
+	tests := []struct {
+		order string
+		want  []int
+	}{
+		{"", []int{443, 22, 8080, 80, 3306}},
+		{PortOrderAsc, []int{22, 80, 443, 3306, 8080}},
+		{PortOrderDesc, []int{8080, 3306, 443, 80, 22}},
+		{PortOrderPriorityFirst, []int{443, 80, 22, 8080, 3306}},
+	}
+	for _, tt := range tests {
+		got := orderedPorts(ports, orderPorts(ports, indexes, tt.order, priority))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("order %q = %v, want %v", tt.order, got, tt.want)
+		}
+	}
+
+	got := orderedPorts(ports, orderPorts(ports, indexes, PortOrderRandom, priority))
+	assertEachPortOnce(t, got, ports)
+
+	if !reflect.DeepEqual(indexes, []int{0, 1, 2, 3, 4}) {
+		t.Errorf("orderPorts modified its input: %v", indexes)
+	}
+}
+
+func TestCheckPortOrder(t *testing.T) {
+	for _, order := range []string{"", PortOrderAsc, PortOrderDesc, PortOrderRandom, PortOrderPriorityFirst} {
+		if err := CheckPortOrder(order); err != nil {
+			t.Errorf("CheckPortOrder(%q) returned error: %v", order, err)
+		}
+	}
+	if err := CheckPortOrder("sideways"); err == nil {
+		t.Error("CheckPortOrder accepted an unknown order")
+	}
+}
+
+// closedLocalPorts returns n loopback ports with nothing listening on them
+func closedLocalPorts(t *testing.T, n int) []int {
+	t.Helper()
+	ports := make([]int, 0, n)
+	for len(ports) < n {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("failed to reserve a port: %v", err)
+		}
+		ports = append(ports, listener.Addr().(*net.TCPAddr).Port)
+		listener.Close()
+	}
+	return ports
+}
+
+func TestScanPortsFollowsPortOrderWithOneWorker(t *testing.T) {
+	ports := closedLocalPorts(t, 6)
+	priority := []int{ports[4], ports[1]}
+
+	for _, order := range []string{"", PortOrderAsc, PortOrderDesc, PortOrderPriorityFirst} {
+		t.Run(order, func(t *testing.T) {
+			config := NewDefaultScanConfig()
+			config.Concurrency = 1
+			config.EnableBanner = false
+			config.MaxRetries = 0
+			config.PortOrder = order
+			config.PriorityPorts = priority
+			scanner := NewScannerService(config)
+
+			results, err := scanner.ScanPorts("127.0.0.1", ports)
+			if err != nil {
+				t.Fatalf("ScanPorts returned error: %v", err)
+			}
+
+			// Results come back in request order; their scan times give the order they ran in
+			for i, port := range results {
+				if port.Number != ports[i] {
+					t.Fatalf("result %d is port %d, want %d", i, port.Number, ports[i])
+				}
+			}
+			sequence := append([]*Port(nil), results...)
+			sort.SliceStable(sequence, func(i, j int) bool { return sequence[i].ScanTime.Before(sequence[j].ScanTime) })
+			got := make([]int, len(sequence))
+			for i, port := range sequence {
+				got[i] = port.Number
+			}
+
+			indexes := []int{0, 1, 2, 3, 4, 5}
+			want := orderedPorts(ports, orderPorts(ports, indexes, order, priority))
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("ports scanned in order %v, want %v", got, want)
+			}
+			assertEachPortOnce(t, got, ports)
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package domain
+
+import "sort"
+
+// PortInfo describes what is expected to listen on a well-known port
+type PortInfo struct {
+	Service  string   // Service name reported for the port
+	Modules  []string // ZGrab2 modules tried against the port, most specific first; empty uses the generic banner module
+	Default  bool     // Scanned when a request names no ports
+	Priority bool     // Banner grabs go ahead of other ports
+}
+
+// PortRegistry maps well-known ports to their service and ZGrab2 modules. It is the single
+// source for service identification, module selection and the default port list.
+type PortRegistry map[int]PortInfo
+
+// KnownPorts is the registry of ports the scanner and banner grabbers recognize
+var KnownPorts = PortRegistry{
+	21:    {Service: "ftp", Modules: []string{"ftp", "banner"}, Default: true, Priority: true},
+	22:    {Service: "ssh", Modules: []string{"ssh", "banner"}, Default: true, Priority: true},
+	23:    {Service: "telnet", Modules: []string{"telnet", "banner"}, Default: true},
+	25:    {Service: "smtp", Modules: []string{"smtp", "banner"}, Default: true, Priority: true},
+	53:    {Service: "dns", Modules: []string{"banner"}, Default: true}, // No DNS module in ZGrab2
+	80:    {Service: "http", Modules: []string{"http", "banner"}, Default: true, Priority: true},
+	102:   {Service: "s7"},
+	110:   {Service: "pop3", Modules: []string{"pop3", "banner"}, Default: true},
+	143:   {Service: "imap", Modules: []string{"imap", "banner"}, Default: true},
+	161:   {Service: "snmp"},
+	443:   {Service: "https", Modules: []string{"http", "tls", "banner"}, Default: true, Priority: true},
+	502:   {Service: "modbus"},
+	993:   {Service: "imaps", Modules: []string{"imap", "tls", "banner"}, Default: true},
+	995:   {Service: "pop3s", Modules: []string{"pop3", "tls", "banner"}, Default: true},
+	1433:  {Service: "mssql", Modules: []string{"mssql", "banner"}},
+	1434:  {Service: "mssql", Modules: []string{"mssql", "banner"}}, // SQL Server Browser
+	1521:  {Service: "oracle", Modules: []string{"oracle", "banner"}},
+	1526:  {Service: "oracle", Modules: []string{"oracle", "banner"}},
+	1911:  {Service: "fox"},
+	3306:  {Service: "mysql", Modules: []string{"mysql", "banner"}, Default: true, Priority: true},
+	3307:  {Service: "mysql", Modules: []string{"mysql", "banner"}},
+	3308:  {Service: "mysql", Modules: []string{"mysql", "banner"}},
+	3309:  {Service: "mysql", Modules: []string{"mysql", "banner"}},
+	3389:  {Service: "rdp", Modules: []string{"banner"}, Default: true}, // No RDP module in ZGrab2
+	5432:  {Service: "postgresql", Modules: []string{"postgres", "banner"}, Default: true, Priority: true},
+	5433:  {Service: "postgresql", Modules: []string{"postgres", "banner"}},
+	5434:  {Service: "postgresql", Modules: []string{"postgres", "banner"}},
+	5435:  {Service: "postgresql", Modules: []string{"postgres", "banner"}},
+	5984:  {Service: "couchdb", Modules: []string{"couchdb", "banner"}},
+	5985:  {Service: "couchdb", Modules: []string{"couchdb", "banner"}},
+	6378:  {Service: "redis", Modules: []string{"redis", "banner"}},
+	6379:  {Service: "redis", Modules: []string{"redis", "banner"}},
+	6380:  {Service: "redis", Modules: []string{"redis", "banner"}},
+	6381:  {Service: "redis", Modules: []string{"redis", "banner"}},
+	8080:  {Service: "http-proxy", Modules: []string{"http", "banner"}, Default: true},
+	8443:  {Service: "https-alt", Modules: []string{"http", "tls", "banner"}, Default: true},
+	9200:  {Service: "elasticsearch", Modules: []string{"elasticsearch", "banner"}},
+	9300:  {Service: "elasticsearch", Modules: []string{"elasticsearch", "banner"}}, // Cluster transport
+	11210: {Service: "memcached", Modules: []string{"memcached", "banner"}},
+	11211: {Service: "memcached", Modules: []string{"memcached", "banner"}},
+	20000: {Service: "dnp3"},
+	27017: {Service: "mongodb", Modules: []string{"mongodb", "banner"}},
+	27018: {Service: "mongodb", Modules: []string{"mongodb", "banner"}},
+	27019: {Service: "mongodb", Modules: []string{"mongodb", "banner"}},
+	27020: {Service: "mongodb", Modules: []string{"mongodb", "banner"}},
+	47808: {Service: "bacnet"},
+}
+
+// Service returns the service expected on a port, or "unknown" for unregistered ports
+func (r PortRegistry) Service(port int) string {
+	if info, ok := r[port]; ok {
+		return info.Service
+	}
+	return "unknown"
+}
+
+// Modules returns the ZGrab2 modules for a port; unregistered ports get the generic banner module
+func (r PortRegistry) Modules(port int) []string {
+	if info, ok := r[port]; ok && len(info.Modules) > 0 {
+		return info.Modules
+	}
+	return []string{"banner"}
+}
+
+// PlainHTTP reports whether a port serves HTTP without TLS, so a plaintext HTTP request
+// elicits a banner from it
+func (r PortRegistry) PlainHTTP(port int) bool {
+	httpModule, tlsModule := false, false
+	for _, module := range r[port].Modules {
+		httpModule = httpModule || module == "http"
+		tlsModule = tlsModule || module == "tls"
+	}
+	return httpModule && !tlsModule
+}
+
+// DefaultPorts returns the ports scanned when a request names none, in ascending order
+func (r PortRegistry) DefaultPorts() []int {
+	return r.ports(func(info PortInfo) bool { return info.Default })
+}
+
+// PriorityPorts returns the ports whose banner grabs go first, in ascending order
+func (r PortRegistry) PriorityPorts() []int {
+	return r.ports(func(info PortInfo) bool { return info.Priority })
+}
+
+// ports returns the registered ports matching a predicate, in ascending order
+func (r PortRegistry) ports(match func(PortInfo) bool) []int {
+	var ports []int
+	for port, info := range r {
+		if match(info) {
+			ports = append(ports, port)
+		}
+	}
+	sort.Ints(ports)
+	return ports
+}
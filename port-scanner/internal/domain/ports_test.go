@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPortRegistryEntriesAreComplete(t *testing.T) {
+	for port, info := range KnownPorts {
+		if port < 1 || port > 65535 {
+			t.Errorf("registered port %d is out of range", port)
+		}
+		if info.Service == "" || info.Service == "unknown" {
+			t.Errorf("port %d has no service", port)
+		}
+		if (info.Default || info.Priority) && len(info.Modules) == 0 {
+			t.Errorf("port %d is scanned by default but has no ZGrab2 modules", port)
+		}
+		for i, module := range info.Modules {
+			if module == "banner" && i != len(info.Modules)-1 {
+				t.Errorf("port %d tries the generic banner module before %v", port, info.Modules[i+1:])
+			}
+		}
+	}
+}
+
+func TestPortRegistryLookups(t *testing.T) {
+	if got := KnownPorts.Service(6379); got != "redis" {
+		t.Errorf("Service(6379) = %q, want redis", got)
+	}
+	if got := KnownPorts.Service(4444); got != "unknown" {
+		t.Errorf("Service(4444) = %q, want unknown", got)
+	}
+	if got := KnownPorts.Modules(6379); !reflect.DeepEqual(got, []string{"redis", "banner"}) {
+		t.Errorf("Modules(6379) = %v, want [redis banner]", got)
+	}
+	for _, port := range []int{4444, 502} {
+		if got := KnownPorts.Modules(port); !reflect.DeepEqual(got, []string{"banner"}) {
+			t.Errorf("Modules(%d) = %v, want the generic banner module", port, got)
+		}
+	}
+
+	for port, want := range map[int]bool{80: true, 8080: true, 443: false, 8443: false, 22: false, 4444: false} {
+		if got := KnownPorts.PlainHTTP(port); got != want {
+			t.Errorf("PlainHTTP(%d) = %v, want %v", port, got, want)
+		}
+	}
+}
+
+func TestPortRegistryPortLists(t *testing.T) {
+	wantDefault := []int{21, 22, 23, 25, 53, 80, 110, 143, 443, 993, 995, 3306, 3389, 5432, 8080, 8443}
+	if got := KnownPorts.DefaultPorts(); !reflect.DeepEqual(got, wantDefault) {
+		t.Errorf("DefaultPorts() = %v, want %v", got, wantDefault)
+	}
+	wantPriority := []int{21, 22, 25, 80, 443, 3306, 5432}
+	if got := KnownPorts.PriorityPorts(); !reflect.DeepEqual(got, wantPriority) {
+		t.Errorf("PriorityPorts() = %v, want %v", got, wantPriority)
+	}
+
+	config := NewDefaultScanConfig()
+	if !reflect.DeepEqual(config.DefaultPorts, wantDefault) || !reflect.DeepEqual(config.PriorityPorts, wantPriority) {
+		t.Errorf("default config ports %v / %v, want the registry's lists", config.DefaultPorts, config.PriorityPorts)
+	}
+}
+
+func TestIdentifyServiceMatchesPortRegistry(t *testing.T) {
+	scanner, _ := newLocalScanner()
+	for port, info := range KnownPorts {
+		if got := scanner.identifyService(port, ""); got != info.Service {
+			t.Errorf("identifyService(%d) = %q, want the registry's %q", port, got, info.Service)
+		}
+	}
+}
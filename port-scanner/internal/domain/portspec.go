@@ -0,0 +1,134 @@
+package domain
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParsePortSpec parses a port specification such as "22,80,443,8000-8100" into a
+// sorted list of unique ports
+func ParsePortSpec(spec string) ([]int, error) {
+	seen := make(map[int]bool)
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, fmt.Errorf("invalid port spec %q: empty entry", spec)
+		}
+
+		low, high := part, part
+		if i := strings.Index(part, "-"); i >= 0 {
+			low, high = strings.TrimSpace(part[:i]), strings.TrimSpace(part[i+1:])
+		}
+
+		start, err := parsePortNumber(low)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port spec entry %q: %w", part, err)
+		}
+		end, err := parsePortNumber(high)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port spec entry %q: %w", part, err)
+		}
+		if start > end {
+			return nil, fmt.Errorf("invalid port spec entry %q: range start is greater than its end", part)
+		}
+
+		for port := start; port <= end; port++ {
+			seen[port] = true
+		}
+	}
+
+	ports := make([]int, 0, len(seen))
+	for port := range seen {
+		ports = append(ports, port)
+	}
+	sort.Ints(ports)
+	return ports, nil
+}
+
+// ResolvePorts combines an explicit port list with a port spec into a sorted list of unique, valid ports
+func ResolvePorts(ports []int, spec string) ([]int, error) {
+	var combined []int
+	if strings.TrimSpace(spec) != "" {
+		parsed, err := ParsePortSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		combined = parsed
+	}
+
+	for _, port := range ports {
+		if port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid port: %d", port)
+		}
+		combined = append(combined, port)
+	}
+
+	sort.Ints(combined)
+	unique := combined[:0]
+	for i, port := range combined {
+		if i == 0 || port != combined[i-1] {
+			unique = append(unique, port)
+		}
+	}
+	return unique, nil
+}
+
+// parsePortNumber parses a single port number in the range 1-65535
+func parsePortNumber(value string) (int, error) {
+	port, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a port number", value)
+	}
+	if port < 1 || port > 65535 {
+		return 0, fmt.Errorf("port %d out of range 1-65535", port)
+	}
+	return port, nil
+}
+
+// ScanTarget is an IP and the exact ports to scan on it
+type ScanTarget struct {
+	IP    string
+	Ports []int
+}
+
+// ParseScanTargets parses "ip:port" entries (IPv6 as "[ip]:port") into one target per IP, in
+// the order the IPs first appear, with each IP's ports in request order and without duplicates
+func ParseScanTargets(entries []string) ([]ScanTarget, error) {
+	var targets []ScanTarget
+	index := make(map[string]int)
+	seen := make(map[string]bool)
+
+	for i, entry := range entries {
+		host, portValue, err := net.SplitHostPort(strings.TrimSpace(entry))
+		if err != nil {
+			return nil, fmt.Errorf("invalid target %d %q: %w", i, entry, err)
+		}
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid target %d %q: %q is not an IP address", i, entry, host)
+		}
+		port, err := parsePortNumber(portValue)
+		if err != nil {
+			return nil, fmt.Errorf("invalid target %d %q: %w", i, entry, err)
+		}
+
+		key := net.JoinHostPort(ip.String(), strconv.Itoa(port))
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		n, ok := index[ip.String()]
+		if !ok {
+			n = len(targets)
+			index[ip.String()] = n
+			targets = append(targets, ScanTarget{IP: ip.String()})
+		}
+		targets[n].Ports = append(targets[n].Ports, port)
+	}
+	return targets, nil
+}
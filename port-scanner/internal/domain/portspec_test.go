@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePortSpecExpandsListsAndRanges(t *testing.T) {
+	tests := map[string][]int{
+		"22":                      {22},
+		"22,80,443":               {22, 80, 443},
+		"8000-8003":               {8000, 8001, 8002, 8003},
+		"443, 22 ,8000 - 8002,80": {22, 80, 443, 8000, 8001, 8002},
+		"80,79-81,80":             {79, 80, 81},
+		"65534-65535,1":           {1, 65534, 65535},
+		"22,80,443,8000-8002,1-2": {1, 2, 22, 80, 443, 8000, 8001, 8002},
+	}
+	for spec, want := range tests {
+		got, err := ParsePortSpec(spec)
+		if err != nil {
+			t.Errorf("ParsePortSpec(%q) returned error: %v", spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ParsePortSpec(%q) = %v, want %v", spec, got, want)
+		}
+	}
+}
+
+func TestParsePortSpecRejectsMalformedSpecs(t *testing.T) {
+	for _, spec := range []string{"", "abc", "80-", "-80", "80,,443", "80,", "0", "65536", "100-90", "1-2-3", "22;80"} {
+		if ports, err := ParsePortSpec(spec); err == nil {
+			t.Errorf("ParsePortSpec(%q) = %v, want an error", spec, ports)
+		}
+	}
+}
+
+func TestResolvePortsMergesListAndSpec(t *testing.T) {
+	got, err := ResolvePorts([]int{443, 8080, 22}, "20-22,443")
+	if err != nil {
+		t.Fatalf("ResolvePorts returned error: %v", err)
+	}
+	if want := []int{20, 21, 22, 443, 8080}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ResolvePorts = %v, want %v", got, want)
+	}
+
+	if got, err := ResolvePorts([]int{80}, "  "); err != nil || !reflect.DeepEqual(got, []int{80}) {
+		t.Errorf("ResolvePorts without a spec = %v, %v, want [80]", got, err)
+	}
+	if _, err := ResolvePorts([]int{70000}, ""); err == nil {
+		t.Error("ResolvePorts accepted an out-of-range port")
+	}
+	if _, err := ResolvePorts(nil, "80-"); err == nil {
+		t.Error("ResolvePorts accepted a malformed spec")
+	}
+}
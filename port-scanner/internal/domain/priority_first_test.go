@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestScanPortsReportsPriorityPortsBeforeTheRest(t *testing.T) {
+	scanner, config := newLocalScanner()
+	open := openLocalPorts(t, 2)
+	slow := unresponsiveLocalPort(t)
+	config.ConnectTimeout = 500 * time.Millisecond
+	config.PriorityPorts = open
+	config.PriorityFirst = true
+
+	var mu sync.Mutex
+	var reported []*Port
+	var reportedAt time.Time
+	config.OnPriorityPorts = func(ip string, ports []*Port) {
+		mu.Lock()
+		defer mu.Unlock()
+		reportedAt = time.Now()
+		for _, port := range ports {
+			copied := *port
+			reported = append(reported, &copied)
+		}
+	}
+
+	start := time.Now()
+	results, err := scanner.scanPorts("127.0.0.1", []int{slow, open[0], open[1]}, config)
+	finished := time.Now()
+	if err != nil {
+		t.Fatalf("scanPorts returned error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reported) != 2 || reported[0].Number != open[0] || reported[1].Number != open[1] {
+		t.Fatalf("reported %d priority ports, want %v in request order", len(reported), open)
+	}
+	for _, port := range reported {
+		if port.Status != PortStatusOpen {
+			t.Errorf("priority port %d reported %s, want open", port.Number, port.Status)
+		}
+	}
+	if reportedAt.Sub(start) >= config.ConnectTimeout || finished.Sub(reportedAt) < config.ConnectTimeout/2 {
+		t.Errorf("priority ports reported %v after the start and %v before the end, want them before the slow port times out",
+			reportedAt.Sub(start), finished.Sub(reportedAt))
+	}
+
+	// Results stay in request order
+	if len(results) != 3 || results[0].Number != slow || results[0].Status == PortStatusOpen {
+		t.Errorf("results = %v, want the slow port first and not open", results)
+	}
+}
+
+func TestScanPortsWithoutPriorityFirstSkipsCallback(t *testing.T) {
+	scanner, config := newLocalScanner()
+	open := openLocalPorts(t, 1)
+	config.PriorityPorts = open
+	called := false
+	config.OnPriorityPorts = func(ip string, ports []*Port) { called = true }
+
+	if _, err := scanner.scanPorts("127.0.0.1", open, config); err != nil {
+		t.Fatalf("scanPorts returned error: %v", err)
+	}
+	if called {
+		t.Error("priority callback ran without PriorityFirst")
+	}
+}
+
+func TestSplitPortsKeepsIndexOrder(t *testing.T) {
+	ports := []int{8080, 22, 31337, 443}
+	in, out := splitPorts(ports, []int{3, 2, 1, 0}, []int{443, 22, 80})
+	if !reflect.DeepEqual(in, []int{3, 1}) || !reflect.DeepEqual(out, []int{2, 0}) {
+		t.Errorf("splitPorts = %v, %v, want [3 1], [2 0]", in, out)
+	}
+}
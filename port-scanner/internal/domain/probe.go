@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultBannerProbe is sent by the fallback banner grab on ports without a configured probe
+const DefaultBannerProbe = "\r\n"
+
+// hexProbePrefix marks a probe payload written as hex bytes
+const hexProbePrefix = "hex:"
+
+// ParseProbePayload decodes a banner probe payload. Payloads prefixed with "hex:" are
+// hex-encoded bytes; others may use Go escape sequences such as \r, \n and \x00.
+// An empty payload sends nothing and waits for the service to speak first.
+func ParseProbePayload(payload string) ([]byte, error) {
+	if strings.HasPrefix(payload, hexProbePrefix) {
+		encoded := strings.ReplaceAll(strings.TrimPrefix(payload, hexProbePrefix), " ", "")
+		decoded, err := hex.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex probe payload: %w", err)
+		}
+		return decoded, nil
+	}
+
+	// YAML double-quoted strings arrive with escapes already decoded
+	if !strings.Contains(payload, `\`) {
+		return []byte(payload), nil
+	}
+
+	unquoted, err := strconv.Unquote(`"` + strings.ReplaceAll(payload, `"`, `\"`) + `"`)
+	if err != nil {
+		return nil, fmt.Errorf("invalid probe payload %q: %w", payload, err)
+	}
+	return []byte(unquoted), nil
+}
+
+// ParseBannerProbes decodes a port to probe payload map as read from configuration
+func ParseBannerProbes(probes map[string]string) (map[int][]byte, error) {
+	parsed := make(map[int][]byte, len(probes))
+	for portValue, payload := range probes {
+		port, err := parsePortNumber(strings.TrimSpace(portValue))
+		if err != nil {
+			return nil, fmt.Errorf("invalid banner probe port %q: %w", portValue, err)
+		}
+		decoded, err := ParseProbePayload(payload)
+		if err != nil {
+			return nil, fmt.Errorf("invalid banner probe for port %d: %w", port, err)
+		}
+		parsed[port] = decoded
+	}
+	return parsed, nil
+}
+
+// DefaultHTTPUserAgent identifies the scanner in HTTP banner probes
+const DefaultHTTPUserAgent = "Mozilla/5.0 (compatible; port-scanner)"
+
+// HTTPProbe is the request sent to plaintext HTTP ports without a configured banner probe,
+// and the User-Agent and headers passed to ZGrab2's http module
+type HTTPProbe struct {
+	UserAgent string
+	Headers   map[string]string // Sent after User-Agent, in name order
+}
+
+// Validate rejects header names and values that would break the request
+func (p HTTPProbe) Validate() error {
+	if strings.ContainsAny(p.UserAgent, "\r\n") {
+		return fmt.Errorf("user agent must not contain line breaks")
+	}
+	for name, value := range p.Headers {
+		if name == "" || strings.ContainsAny(name, ": \t\r\n") {
+			return fmt.Errorf("invalid HTTP header name %q", name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("value of HTTP header %q must not contain line breaks", name)
+		}
+	}
+	return nil
+}
+
+// HeaderNames returns the configured header names in sorted order
+func (p HTTPProbe) HeaderNames() []string {
+	names := make([]string, 0, len(p.Headers))
+	for name := range p.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Request returns a GET / request for host with the probe's User-Agent and headers
+func (p HTTPProbe) Request(host string) []byte {
+	var request strings.Builder
+	request.WriteString("GET / HTTP/1.0\r\n")
+	request.WriteString("Host: " + host + "\r\n")
+	if p.UserAgent != "" {
+		request.WriteString("User-Agent: " + p.UserAgent + "\r\n")
+	}
+	for _, name := range p.HeaderNames() {
+		request.WriteString(textproto.CanonicalMIMEHeaderKey(name) + ": " + p.Headers[name] + "\r\n")
+	}
+	request.WriteString("\r\n")
+	return []byte(request.String())
+}
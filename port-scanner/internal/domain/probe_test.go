@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// echoHTTPOn serves HTTP on a local port, passing each request's headers on the returned channel
+func echoHTTPOn(t *testing.T, port int) <-chan http.Header {
+	t.Helper()
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port})
+	if err != nil {
+		t.Skipf("port %d unavailable: %v", port, err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	headers := make(chan http.Header, 1)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			req, err := http.ReadRequest(bufio.NewReader(conn))
+			if err == nil {
+				headers <- req.Header
+				conn.Write([]byte("HTTP/1.0 200 OK\r\nServer: echo\r\n\r\n"))
+			}
+			conn.Close()
+		}
+	}()
+	return headers
+}
+
+func TestBasicBannerGrabSendsConfiguredUserAgent(t *testing.T) {
+	headers := echoHTTPOn(t, 80)
+	scanner, config := newLocalScanner()
+	config.HTTPProbe = HTTPProbe{UserAgent: "orwell-test/1.0", Headers: map[string]string{"x-scan-contact": "security@example.com"}}
+
+	info, err := scanner.basicBannerGrab("127.0.0.1", 80)
+	if err != nil {
+		t.Fatalf("basicBannerGrab returned error: %v", err)
+	}
+	if !strings.HasPrefix(info.RawBanner, "HTTP/1.0 200 OK") {
+		t.Errorf("banner = %q, want the HTTP response", info.RawBanner)
+	}
+
+	select {
+	case got := <-headers:
+		if got.Get("User-Agent") != "orwell-test/1.0" || got.Get("X-Scan-Contact") != "security@example.com" {
+			t.Errorf("request headers = %v, want the configured User-Agent and X-Scan-Contact", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no HTTP request received")
+	}
+}
+
+func TestHTTPProbeRequest(t *testing.T) {
+	probe := HTTPProbe{UserAgent: "orwell-test/1.0", Headers: map[string]string{"x-b": "2", "accept": "*/*"}}
+	want := "GET / HTTP/1.0\r\nHost: 203.0.113.5\r\nUser-Agent: orwell-test/1.0\r\nAccept: */*\r\nX-B: 2\r\n\r\n"
+	if got := string(probe.Request("203.0.113.5")); got != want {
+		t.Errorf("Request() = %q, want %q", got, want)
+	}
+
+	for _, invalid := range []HTTPProbe{
+		{UserAgent: "agent\r\nX-Injected: 1"},
+		{Headers: map[string]string{"Bad Name": "value"}},
+		{Headers: map[string]string{"X-Ok": "line\nbreak"}},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("Validate(%+v) accepted a header that breaks the request", invalid)
+		}
+	}
+}
@@ -1,10 +1,101 @@
 package domain
 
+import (
+	"fmt"
+	"time"
+)
+
 // QueueMessage represents a message from the IP generator queue
 type QueueMessage struct {
-	IPs     []string `json:"ips"`
-	BatchID string   `json:"batch_id"`
-	Count   int      `json:"count"`
+	IPs      []string          `json:"ips"`
+	BatchID  string            `json:"batch_id"`
+	Count    int               `json:"count"`
+	Tags     map[string]string `json:"tags,omitempty"`
+	Priority int               `json:"priority,omitempty"`
+
+	Retention string `json:"retention,omitempty"` // How long results are kept (e.g. "7d"); empty keeps them indefinitely
+
+	Ports []int `json:"ports,omitempty"` // Ports the message's IPs are scanned on; empty uses the scanner's defaults
+
+	// A generated batch is split into messages numbered from 0 under a parent batch ID, the
+	// last one marked final. Messages without a parent are batches of their own.
+	ParentBatchID string `json:"parent_batch_id,omitempty"`
+	Sequence      int    `json:"sequence,omitempty"`
+	Final         bool   `json:"final,omitempty"`
+}
+
+// NewBatchMessages splits IPs into messages of at most size IPs, numbered from 0 under the
+// parent batch ID with the last one marked final, the way the IP generator publishes a batch
+func NewBatchMessages(batchID string, ips []string, ports []int, size int) []*QueueMessage {
+	var messages []*QueueMessage
+	for start := 0; start < len(ips); start += size {
+		end := min(start+size, len(ips))
+		messages = append(messages, &QueueMessage{
+			IPs:           ips[start:end],
+			BatchID:       fmt.Sprintf("%s-%d", batchID, len(messages)),
+			Count:         end - start,
+			Ports:         ports,
+			ParentBatchID: batchID,
+			Sequence:      len(messages),
+			Final:         end == len(ips),
+		})
+	}
+	return messages
+}
+
+// Progress starts the completion record of the message within its parent batch
+func (m *QueueMessage) Progress() *BatchProgress {
+	if m.ParentBatchID == "" {
+		return &BatchProgress{BatchID: m.BatchID, Final: true}
+	}
+	return &BatchProgress{BatchID: m.ParentBatchID, Sequence: m.Sequence, Final: m.Final}
+}
+
+// RemoveDuplicateIPs drops repeated IPs, keeping the first occurrence of each so every IP is
+// scanned once, and returns how many were removed
+func (m *QueueMessage) RemoveDuplicateIPs() int {
+	seen := make(map[string]struct{}, len(m.IPs))
+	unique := m.IPs[:0]
+	for _, ip := range m.IPs {
+		if _, ok := seen[ip]; ok {
+			continue
+		}
+		seen[ip] = struct{}{}
+		unique = append(unique, ip)
+	}
+
+	removed := len(m.IPs) - len(unique)
+	if removed > 0 {
+		m.IPs = unique
+		m.Count = len(unique)
+	}
+	return removed
+}
+
+// ApplyPorts makes a scan configuration scan the ports the message asks for, in place of the
+// configured defaults. Messages without ports leave the configuration unchanged.
+func (m *QueueMessage) ApplyPorts(config *ScanConfig) error {
+	if len(m.Ports) == 0 {
+		return nil
+	}
+	ports, err := ResolvePorts(m.Ports, "")
+	if err != nil {
+		return fmt.Errorf("invalid ports in message: %w", err)
+	}
+	config.PortRange = ports
+	return nil
+}
+
+// ApplyTo copies the message context (tags, priority, retention) into a scan result.
+// An invalid retention is ignored so the results are kept rather than lost early.
+func (m *QueueMessage) ApplyTo(result *ScanResult) {
+	if len(m.Tags) > 0 {
+		result.Tags = m.Tags
+	}
+	result.Priority = m.Priority
+	if retention, err := ParseRetention(m.Retention); m.Retention != "" && err == nil && retention > 0 {
+		result.Retention = retention
+	}
 }
 
 // ScanResultMessage represents a scan result message for output queues
@@ -24,10 +115,34 @@ type EnrichmentMessage struct {
 
 // ServiceAnalysisMessage represents a message for service analysis queue
 type ServiceAnalysisMessage struct {
-	IP        string  `json:"ip"`
-	OpenPorts []*Port `json:"open_ports"`
-	BatchID   string  `json:"batch_id"`
-	Timestamp int64   `json:"timestamp"`
+	IP        string    `json:"ip"`
+	OpenPorts []*Port   `json:"open_ports"`
+	BatchID   string    `json:"batch_id"`
+	Timestamp int64     `json:"timestamp"`
+	Findings  []Finding `json:"findings,omitempty"`
+}
+
+// OutboxMessage is a downstream queue message recorded so it is published
+// even if the first attempt fails, in the order it was recorded
+type OutboxMessage struct {
+	ID          string
+	WorkerID    string
+	Queue       string
+	Exchange    string // Empty publishes to Queue through the default exchange
+	RoutingKey  string // Key the message is published to Exchange with
+	ContentType string // Empty for messages recorded before formats were selectable, which are JSON
+	Body        []byte
+	CreatedAt   time.Time
+}
+
+// OutboxStore keeps downstream queue messages until they have been published
+type OutboxStore interface {
+	// Enqueue records messages in order
+	Enqueue(messages []*OutboxMessage) error
+	// Pending returns up to limit unpublished messages of a worker, oldest first
+	Pending(workerID string, limit int) ([]*OutboxMessage, error)
+	// MarkPublished removes a message once it has been published
+	MarkPublished(id string) error
 }
 
 // QueueConsumer defines the interface for consuming messages from queues
@@ -42,11 +157,24 @@ type QueuePublisher interface {
 	PublishBatch(messages []interface{}) error
 }
 
+// ResultReplayer publishes a stored scan result to the output queues again, for
+// downstream consumers that missed the original messages
+type ResultReplayer interface {
+	ReplayScanResult(result *ScanResult) error
+}
+
+// ScanEnqueuer queues IPs on the IP queue, to be scanned by whichever workers consume it, as
+// one batch split into messages. It returns how many messages were published.
+type ScanEnqueuer interface {
+	EnqueueScan(batchID string, ips []string, ports []int) (int, error)
+}
+
 // QueueManager defines the interface for managing multiple queues
 type QueueManager interface {
-	ConsumeIPs(handler func(*QueueMessage) error) error
+	// ConsumeIPs starts scanning the IPs of delivered messages with the manager's scan handler
+	ConsumeIPs() error
 	PublishScanResult(result *ScanResult) error
 	PublishEnrichmentMessage(ip string, isUp bool, batchID string) error
-	PublishServiceAnalysis(ip string, openPorts []*Port, batchID string) error
+	PublishServiceAnalysis(ip string, openPorts []*Port, batchID string, findings []Finding) error
 	Close() error
 }
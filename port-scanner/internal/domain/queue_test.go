@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRemoveDuplicateIPsKeepsFirstOccurrence(t *testing.T) {
+	message := &QueueMessage{IPs: []string{"8.8.8.8", "1.1.1.1", "8.8.8.8", "9.9.9.9", "1.1.1.1"}, Count: 5}
+
+	if removed := message.RemoveDuplicateIPs(); removed != 2 {
+		t.Errorf("RemoveDuplicateIPs() = %d, want 2", removed)
+	}
+	if want := []string{"8.8.8.8", "1.1.1.1", "9.9.9.9"}; !reflect.DeepEqual(message.IPs, want) {
+		t.Errorf("IPs = %v, want %v", message.IPs, want)
+	}
+	if message.Count != 3 {
+		t.Errorf("Count = %d, want 3", message.Count)
+	}
+}
+
+func TestRemoveDuplicateIPsWithoutDuplicates(t *testing.T) {
+	message := &QueueMessage{IPs: []string{"8.8.8.8", "1.1.1.1"}, Count: 2}
+
+	if removed := message.RemoveDuplicateIPs(); removed != 0 {
+		t.Errorf("RemoveDuplicateIPs() = %d, want 0", removed)
+	}
+	if len(message.IPs) != 2 || message.Count != 2 {
+		t.Errorf("message changed to %v (count %d)", message.IPs, message.Count)
+	}
+}
+
+func TestApplyPortsOverridesConfiguredPorts(t *testing.T) {
+	config := NewDefaultScanConfig()
+	config.PortRange = []int{22, 80, 443}
+
+	message := &QueueMessage{Ports: []int{8443, 22, 8443}}
+	if err := message.ApplyPorts(config); err != nil {
+		t.Fatalf("ApplyPorts returned error: %v", err)
+	}
+	if want := []int{22, 8443}; !reflect.DeepEqual(config.PortRange, want) {
+		t.Errorf("PortRange = %v, want %v", config.PortRange, want)
+	}
+
+	config.PortRange = []int{22, 80, 443}
+	if err := (&QueueMessage{}).ApplyPorts(config); err != nil || len(config.PortRange) != 3 {
+		t.Errorf("message without ports changed PortRange to %v (%v)", config.PortRange, err)
+	}
+
+	if err := (&QueueMessage{Ports: []int{22, 70000}}).ApplyPorts(config); err == nil {
+		t.Error("ApplyPorts accepted port 70000")
+	}
+	if len(config.PortRange) != 3 {
+		t.Errorf("invalid ports changed PortRange to %v", config.PortRange)
+	}
+}
@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ConcurrencyRamp raises effective concurrency linearly from 1 to a scan's maximum over a
+// duration, counted from the first scan, so a scanner starting up doesn't open its full
+// number of connections at once. A nil ramp allows full concurrency from the start.
+type ConcurrencyRamp struct {
+	duration time.Duration
+	mu       sync.Mutex
+	start    time.Time // Zero until the first scan
+}
+
+// NewConcurrencyRamp creates a ramp lasting duration; a non-positive duration returns nil
+func NewConcurrencyRamp(duration time.Duration) *ConcurrencyRamp {
+	if duration <= 0 {
+		return nil
+	}
+	return &ConcurrencyRamp{duration: duration}
+}
+
+// Limit returns the concurrency allowed now out of max; before the first scan it is 1
+func (r *ConcurrencyRamp) Limit(max int) int {
+	if r == nil || max <= 1 {
+		return max
+	}
+
+	r.mu.Lock()
+	start := r.start
+	r.mu.Unlock()
+	if start.IsZero() {
+		return 1
+	}
+
+	elapsed := time.Since(start)
+	if elapsed >= r.duration {
+		return max
+	}
+	return 1 + int(int64(max-1)*int64(elapsed)/int64(r.duration))
+}
+
+// Wait blocks until the ramp allows more than slot out of max concurrent workers, so worker
+// slot (numbered from 0) may start, or until ctx is done. It reports whether the slot may start.
+func (r *ConcurrencyRamp) Wait(ctx context.Context, slot, max int) bool {
+	if r == nil {
+		return true
+	}
+	elapsed := r.elapsed()
+	if slot == 0 || max <= 1 {
+		return true
+	}
+
+	// Limit exceeds slot once (max-1) * elapsed / duration reaches slot, rounded up
+	opensAt := time.Duration((int64(r.duration)*int64(slot) + int64(max-2)) / int64(max-1))
+	wait := opensAt - elapsed
+	if wait <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// elapsed returns the time since the ramp started, starting it on first call
+func (r *ConcurrencyRamp) elapsed() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.start.IsZero() {
+		r.start = time.Now()
+	}
+	return time.Since(r.start)
+}
@@ -0,0 +1,121 @@
+package domain
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyRampLimitGrowsToMax(t *testing.T) {
+	if got := (*ConcurrencyRamp)(nil).Limit(10); got != 10 {
+		t.Errorf("nil ramp Limit(10) = %d, want 10", got)
+	}
+	if NewConcurrencyRamp(0) != nil {
+		t.Error("NewConcurrencyRamp(0) returned a ramp, want nil")
+	}
+
+	ramp := NewConcurrencyRamp(200 * time.Millisecond)
+	if got := ramp.Limit(10); got != 1 {
+		t.Errorf("Limit before the first scan = %d, want 1", got)
+	}
+
+	ramp.elapsed()
+	time.Sleep(100 * time.Millisecond)
+	if got := ramp.Limit(10); got < 4 || got > 8 {
+		t.Errorf("Limit halfway through the ramp = %d, want about 5", got)
+	}
+	time.Sleep(120 * time.Millisecond)
+	if got := ramp.Limit(10); got != 10 {
+		t.Errorf("Limit after the ramp = %d, want 10", got)
+	}
+}
+
+func TestConcurrencyRampWaitOpensSlotsInTurn(t *testing.T) {
+	ramp := NewConcurrencyRamp(300 * time.Millisecond)
+
+	start := time.Now()
+	if !ramp.Wait(context.Background(), 0, 4) {
+		t.Fatal("first slot did not start")
+	}
+	if elapsed := time.Since(start); elapsed > 20*time.Millisecond {
+		t.Errorf("first slot waited %v, want it to start at once", elapsed)
+	}
+
+	// With 4 slots over 300ms, slot 1 opens after 100ms
+	if !ramp.Wait(context.Background(), 1, 4) {
+		t.Fatal("slot 1 did not start")
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond || elapsed > 200*time.Millisecond {
+		t.Errorf("slot 1 started after %v, want about 100ms", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if ramp.Wait(ctx, 3, 4) {
+		t.Error("slot 3 started before the ramp reached it, want it to give up with the context")
+	}
+}
+
+// concurrencyTimeline is a BannerGrabber whose grabs take a while, recording the peak number
+// running at once before and after a point in time
+type concurrencyTimeline struct {
+	mu        sync.Mutex
+	start     time.Time
+	split     time.Duration
+	active    int
+	earlyPeak int
+	latePeak  int
+}
+
+func (g *concurrencyTimeline) GetBanner(ip string, port int) (*BannerInfo, error) {
+	g.mu.Lock()
+	if g.start.IsZero() {
+		g.start = time.Now()
+	}
+	g.active++
+	if time.Since(g.start) < g.split {
+		g.earlyPeak = max(g.earlyPeak, g.active)
+	} else {
+		g.latePeak = max(g.latePeak, g.active)
+	}
+	g.mu.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	g.mu.Lock()
+	g.active--
+	g.mu.Unlock()
+	return &BannerInfo{Service: "unknown", Protocol: "tcp"}, nil
+}
+
+func TestScanPortsRampsUpConcurrency(t *testing.T) {
+	config := NewDefaultScanConfig()
+	config.Concurrency = 8
+	config.MaxRetries = 0
+	config.RetryDelay = 0
+	config.RampUpDuration = 400 * time.Millisecond
+	scanner := NewScannerService(config)
+	grabber := &concurrencyTimeline{split: 50 * time.Millisecond}
+	scanner.SetBannerGrabber(grabber)
+
+	if got := scanner.ConcurrencyLimit(); got != 1 {
+		t.Errorf("ConcurrencyLimit before scanning = %d, want 1", got)
+	}
+
+	ports := openLocalPorts(t, 120)
+	if _, err := scanner.ScanPorts("127.0.0.1", ports); err != nil {
+		t.Fatalf("ScanPorts returned error: %v", err)
+	}
+
+	// Only the first worker runs for the first ~57ms of the ramp; all 8 once it is over
+	if grabber.earlyPeak > 2 {
+		t.Errorf("peak concurrency in the first 50ms = %d, want at most 2", grabber.earlyPeak)
+	}
+	if grabber.latePeak < 6 {
+		t.Errorf("peak concurrency later = %d, want close to 8", grabber.latePeak)
+	}
+	if got := scanner.ConcurrencyLimit(); got != 8 {
+		t.Errorf("ConcurrencyLimit after the ramp = %d, want 8", got)
+	}
+}
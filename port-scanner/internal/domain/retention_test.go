@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRetentionAcceptsDays(t *testing.T) {
+	tests := map[string]time.Duration{
+		"1d":  24 * time.Hour,
+		"30d": 30 * 24 * time.Hour,
+		"12h": 12 * time.Hour,
+		"90m": 90 * time.Minute,
+	}
+	for value, want := range tests {
+		got, err := ParseRetention(value)
+		if err != nil || got != want {
+			t.Errorf("ParseRetention(%q) = %v, %v, want %v", value, got, err, want)
+		}
+	}
+
+	for _, value := range []string{"", "d", "xd", "1.5d", "soon"} {
+		if _, err := ParseRetention(value); err == nil {
+			t.Errorf("ParseRetention(%q) returned no error", value)
+		}
+	}
+}
+
+func TestQueueMessageAppliesRetention(t *testing.T) {
+	tests := map[string]time.Duration{
+		"7d":   7 * 24 * time.Hour,
+		"6h":   6 * time.Hour,
+		"":     0,
+		"soon": 0, // Invalid retentions keep the results
+		"-1d":  0,
+	}
+	for retention, want := range tests {
+		result := NewScanResult("10.0.0.1", "batch-1", "worker-1")
+		(&QueueMessage{Retention: retention}).ApplyTo(result)
+		if result.Retention != want {
+			t.Errorf("retention %q applied as %v, want %v", retention, result.Retention, want)
+		}
+	}
+}
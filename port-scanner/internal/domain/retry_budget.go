@@ -0,0 +1,32 @@
+package domain
+
+import "sync/atomic"
+
+// RetryBudget caps the port scan retries spent across many ports, so a sweep full of dead
+// hosts doesn't spend most of its time retrying. A nil budget is unlimited. It is safe for
+// concurrent use.
+type RetryBudget struct {
+	limit int64
+	used  atomic.Int64
+}
+
+// NewRetryBudget creates a budget allowing limit retries; a non-positive limit returns nil
+func NewRetryBudget(limit int) *RetryBudget {
+	if limit <= 0 {
+		return nil
+	}
+	return &RetryBudget{limit: int64(limit)}
+}
+
+// Take spends one retry, reporting false once the budget is exhausted
+func (b *RetryBudget) Take() bool {
+	if b == nil {
+		return true
+	}
+	return b.used.Add(1) <= b.limit
+}
+
+// Exhausted reports whether every retry of the budget has been spent
+func (b *RetryBudget) Exhausted() bool {
+	return b != nil && b.used.Load() >= b.limit
+}
@@ -0,0 +1,132 @@
+package domain
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// newTimeoutScanner returns a scanner whose connection attempts all time out at once, with
+// no delay between retries
+func newTimeoutScanner(maxRetries int) (*ScannerService, *ScanConfig) {
+	config := NewDefaultScanConfig()
+	config.ConnectTimeout = time.Nanosecond
+	config.MaxRetries = maxRetries
+	config.RetryDelay = 0
+	config.EnableBanner = false
+	return NewScannerService(config), config
+}
+
+func TestRetryBudgetLimitsRetries(t *testing.T) {
+	budget := NewRetryBudget(3)
+	for i := 0; i < 3; i++ {
+		if !budget.Take() {
+			t.Fatalf("Take() #%d = false, want true", i+1)
+		}
+	}
+	if budget.Take() {
+		t.Error("Take() past the limit = true, want false")
+	}
+	if !budget.Exhausted() {
+		t.Error("Exhausted() = false after spending the budget")
+	}
+
+	var unlimited *RetryBudget
+	if !unlimited.Take() || unlimited.Exhausted() {
+		t.Error("nil budget should be unlimited")
+	}
+	if NewRetryBudget(0) != nil {
+		t.Error("NewRetryBudget(0) should return an unlimited nil budget")
+	}
+}
+
+func TestScanPortRetriesTimeoutsUntilBatchBudgetIsSpent(t *testing.T) {
+	scanner, config := newTimeoutScanner(5)
+	config.RetryBudget = NewRetryBudget(4)
+
+	// The first port spends the whole budget: 1 attempt plus 4 of its 5 retries
+	port, err := scanner.scanPortWithRetry("127.0.0.1", 9, config, nil, nil)
+	if err != nil {
+		t.Fatalf("scanPortWithRetry returned error: %v", err)
+	}
+	if port.Status != PortStatusFiltered {
+		t.Errorf("port that kept timing out has status %s, want %s", port.Status, PortStatusFiltered)
+	}
+	if got := scanner.DialErrorCounts()[DialErrorTimeout]; got != 5 {
+		t.Errorf("first port made %d attempts, want 5", got)
+	}
+	if !config.RetryBudget.Exhausted() {
+		t.Fatal("batch retry budget not exhausted")
+	}
+
+	// Later ports of the batch get a single attempt
+	if _, err := scanner.scanPortWithRetry("127.0.0.1", 10, config, nil, nil); err != nil {
+		t.Fatalf("scanPortWithRetry returned error: %v", err)
+	}
+	if got := scanner.DialErrorCounts()[DialErrorTimeout]; got != 6 {
+		t.Errorf("made %d attempts in total, want 6 once the budget was spent", got)
+	}
+}
+
+func TestScanPortRetriesStopWhenHostBudgetIsSpent(t *testing.T) {
+	scanner, config := newTimeoutScanner(3)
+	hostBudget := NewRetryBudget(2)
+
+	for _, port := range []int{9, 10, 11} {
+		if _, err := scanner.scanPortWithRetry("127.0.0.1", port, config, nil, hostBudget); err != nil {
+			t.Fatalf("scanPortWithRetry returned error: %v", err)
+		}
+	}
+
+	// 3 first attempts plus the 2 retries the host budget allows
+	if got := scanner.DialErrorCounts()[DialErrorTimeout]; got != 5 {
+		t.Errorf("made %d attempts, want 5", got)
+	}
+}
+
+func TestScanPortDoesNotRetryRefusedConnections(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	config := NewDefaultScanConfig()
+	config.MaxRetries = 3
+	config.RetryDelay = 0
+	config.EnableBanner = false
+	config.RetryBudget = NewRetryBudget(10)
+	scanner := NewScannerService(config)
+
+	result, err := scanner.scanPortWithRetry("127.0.0.1", port, config, nil, nil)
+	if err != nil {
+		t.Fatalf("scanPortWithRetry returned error: %v", err)
+	}
+	if result.Status != PortStatusClosed {
+		t.Errorf("refused port has status %s, want %s", result.Status, PortStatusClosed)
+	}
+	if got := scanner.DialErrorCounts()[DialErrorRefused]; got != 1 {
+		t.Errorf("made %d attempts on a refused port, want 1", got)
+	}
+	if config.RetryBudget.Exhausted() {
+		t.Error("refused connection spent retry budget")
+	}
+}
+
+func TestIsRetryableDialCategory(t *testing.T) {
+	retryable := map[string]bool{
+		DialErrorTimeout:            true,
+		DialErrorTooManyOpenFiles:   true,
+		DialErrorOther:              true,
+		DialErrorRefused:            false,
+		DialErrorDNS:                false,
+		DialErrorNetworkUnreachable: false,
+		DialErrorNoRoute:            false,
+	}
+	for category, want := range retryable {
+		if got := IsRetryableDialCategory(category); got != want {
+			t.Errorf("IsRetryableDialCategory(%q) = %v, want %v", category, got, want)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"context"
+	"errors"
 	"time"
 )
 
@@ -22,8 +24,19 @@ const (
 	PortStatusOpen     PortStatus = "open"
 	PortStatusClosed   PortStatus = "closed"
 	PortStatusFiltered PortStatus = "filtered"
+	PortStatusError    PortStatus = "error"
+
+	// PortStatusOpenFiltered marks a UDP port that sent no reply: it is either
+	// open and ignoring the probe or filtered, and is not counted as open
+	PortStatusOpenFiltered PortStatus = "open|filtered"
 )
 
+// ErrNoResponse is returned by probes when the target sent no reply at all
+var ErrNoResponse = errors.New("no response from target")
+
+// ErrEngineStopped is returned when a scan can't start because the scanning engine has stopped
+var ErrEngineStopped = errors.New("scanning engine stopped")
+
 // IPAddress represents an IPv4 address to be scanned
 type IPAddress struct {
 	Address string
@@ -46,18 +59,21 @@ type BannerInfo struct {
 	Version    string                 `json:"version,omitempty"`
 	Confidence string                 `json:"confidence"` // "banner", "port", "zgrab2"
 	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+
+	ConfidenceScore int `json:"confidence_score"` // 0-100, see ConfidenceScore
 }
 
 // Port represents a network port
 type Port struct {
-	Number       int
-	Status       PortStatus
-	Service      string
-	Banner       string
-	Version      string
-	ScanTime     time.Time
-	ResponseTime time.Duration
-	BannerInfo   *BannerInfo `json:"banner_info,omitempty"`
+	Number       int           `json:"number"`
+	Status       PortStatus    `json:"status"`
+	Service      string        `json:"service"`
+	Banner       string        `json:"banner,omitempty"`
+	Version      string        `json:"version,omitempty"`
+	ScanTime     time.Time     `json:"scan_time"`
+	ResponseTime time.Duration `json:"response_time"`
+	BannerInfo   *BannerInfo   `json:"banner_info,omitempty"`
+	Error        string        `json:"error,omitempty"` // Set when Status is PortStatusError
 }
 
 // NewPort creates a new port
@@ -81,6 +97,16 @@ type ScanResult struct {
 	Error         string
 	BatchID       string
 	WorkerID      string
+	Tags          map[string]string
+	Priority      int
+
+	Retention time.Duration // Results expire this long after being stored; 0 keeps them
+
+	Metadata map[string]interface{} // Written by enrichers after the scan
+
+	ReverseDNS []string // PTR names of the IP, set by the reverse DNS enricher
+
+	Findings []Finding // Issues reported by service analyzers on the open ports
 }
 
 // NewScanResult creates a new scan result
@@ -124,6 +150,17 @@ func (sr *ScanResult) GetOpenPorts() []*Port {
 	return openPorts
 }
 
+// GetOpenFilteredPorts returns all ports that are open or filtered
+func (sr *ScanResult) GetOpenFilteredPorts() []*Port {
+	var ports []*Port
+	for _, port := range sr.Ports {
+		if port.Status == PortStatusOpenFiltered {
+			ports = append(ports, port)
+		}
+	}
+	return ports
+}
+
 // GetScanDuration returns the total scan duration
 func (sr *ScanResult) GetScanDuration() time.Duration {
 	if sr.ScanEndTime.IsZero() {
@@ -140,12 +177,143 @@ type ScanConfig struct {
 	MaxRetries       int
 	RetryDelay       time.Duration
 	Concurrency      int
-	ZGrabConcurrency int // Maximum concurrent ZGrab2 processes
+	ZGrabConcurrency int    // Maximum concurrent ZGrab2 processes
+	ZGrabPath        string // ZGrab2 executable name or path
 	PortRange        []int
 	DefaultPorts     []int
 	EnableBanner     bool
 	EnablePing       bool
-	PriorityPorts    []int // Ports that should get priority for banner grabbing
+	NativeICMP       bool                           // Use in-process ICMP echo instead of the ping binary
+	PriorityPorts    []int                          // Ports that should get priority for banner grabbing
+	ZGrabModules     map[string]ZGrabModuleSettings // Per-module ZGrab2 timeout/retry overrides
+	EnableSNMP       bool
+	SNMPCommunities  []string      // Community strings tried in order when probing UDP 161
+	SNMPVersion      string        // "1" or "2c"
+	ScanDeadline     time.Duration // Wall-clock limit for the whole engine run; 0 disables
+
+	// Adaptive concurrency tunes the per-IP port scan concurrency from the connect error rate
+	AdaptiveConcurrency bool
+	MinConcurrency      int
+	MaxConcurrency      int
+
+	// Crash check re-connects after banner grabbing to spot services the probe may have crashed
+	CrashCheck         bool
+	CrashCheckDelay    time.Duration
+	CrashCheckServices []string // Services to re-check; empty checks every open port
+
+	// Ports of services in BannerDenyServices, identified by port number, are reported open
+	// without a banner grab unless the service is also in BannerAllowServices (opt-in)
+	BannerDenyServices  []string
+	BannerAllowServices []string
+
+	// Banner grabbing stops for a host after this many banner timeouts; 0 disables
+	BannerTimeoutLimit int
+	MaxBannerLength    int  // Raw banners longer than this many bytes are truncated; 0 disables
+	NormalizeBanners   bool // Clean raw banners with NormalizeBanner, keeping the original in the metadata
+
+	// Refuse loopback, the scanner's own addresses and these IPs/CIDRs
+	SelfScanGuard    bool
+	ProtectedTargets []string
+
+	// Echo requests sent per host and replies needed to consider it up
+	PingCount            int
+	PingSuccessThreshold int
+
+	Exclusions []string // IPs/CIDRs this scan must skip (set from scan templates)
+
+	MaxBatchConcurrency int // Upper bound on IPs scanned at once by batch API requests
+
+	BannerProbes map[int][]byte // Per-port payloads sent by the fallback banner grab instead of DefaultBannerProbe
+
+	BannerConcurrencyPerHost int // Concurrent banner grabs allowed against one host; 0 disables the cap
+
+	// Re-connect to open ports after this delay and downgrade those that no longer accept
+	VerifyOpenPorts bool
+	VerifyDelay     time.Duration
+
+	PortTimeouts map[int]time.Duration // Per-port connect timeouts for slow services; other ports use ConnectTimeout
+
+	// The engine keeps the latest result of up to RecentResultsLimit IPs for RecentResultsTTL
+	// so their status can be looked up; a zero TTL keeps them until newer results evict them
+	RecentResultsLimit int
+	RecentResultsTTL   time.Duration
+
+	// Keep up to ConnCacheSize connections from the port scan open for ConnIdleTimeout so the
+	// fallback banner grab reuses them instead of connecting again. Open-port verification
+	// always connects again, since a cached connection only proves the port accepted once.
+	ReuseConnections bool
+	ConnIdleTimeout  time.Duration
+	ConnCacheSize    int
+
+	Technique string // TCP scan technique (TechniqueConnect or TechniqueSYN); empty means connect
+
+	// Hosts that fail the ping check are reported down without pinging again for DeadHostTTL
+	DeadHostCache bool
+	DeadHostTTL   time.Duration
+
+	// Split the fallback banner grab's budget between connecting and reading; zero uses BannerTimeout
+	BannerConnectTimeout time.Duration
+	BannerReadTimeout    time.Duration
+
+	// Ports probed for a STARTTLS upgrade after banner grabbing, with their protocol
+	EnableStartTLS  bool
+	StartTLSPorts   map[int]string
+	StartTLSTimeout time.Duration
+
+	HTTPProbe HTTPProbe // Request of the banner grab on plaintext HTTP ports
+
+	// Scan the requested ports found in PriorityPorts in a first wave and pass their results
+	// to OnPriorityPorts, if set, before the remaining ports are scanned
+	PriorityFirst   bool
+	OnPriorityPorts PortsCallback
+
+	// Triage mode: scan the requested ports found in TriggerPorts first and, when any of them
+	// is open, return without scanning the others
+	StopOnFirstTrigger bool
+	TriggerPorts       []int
+
+	// Scan results of a queued IP message wait in a buffer of ResultBufferSize for
+	// ResultPublishers goroutines to save and publish them; scans block once it is full
+	ResultBufferSize int
+	ResultPublishers int
+
+	// Total port retries allowed per IP scan and per queued batch message on top of MaxRetries'
+	// per-port limit; 0 leaves them unlimited. The engine sets RetryBudget for each batch.
+	HostRetryBudget  int
+	BatchRetryBudget int
+	RetryBudget      *RetryBudget
+
+	// Port scan concurrency grows from 1 to each scan's maximum over RampUpDuration after the
+	// scanner's first scan; 0 starts at full concurrency
+	RampUpDuration time.Duration
+
+	// Retry delays grow by RetryBackoff per attempt (1 keeps RetryDelay), vary randomly by up
+	// to RetryJitter (0-1) of themselves so retries of many ports don't line up, and are capped
+	// at RetryMaxDelay (0 for no cap). A non-zero RetrySeed makes the jitter repeatable.
+	RetryBackoff  float64
+	RetryJitter   float64
+	RetryMaxDelay time.Duration
+	RetrySeed     int64
+
+	// Hosts whose ping round-trip time exceeds MaxPingRTT are treated as down instead of
+	// having their ports scanned; 0 scans every host that answers
+	MaxPingRTT time.Duration
+
+	// Order the ports of an IP are handed to the scan workers in (a PortOrder constant); empty
+	// keeps the requested order. Trigger and priority waves keep it among their own ports. The
+	// order is best-effort when several workers run: it is only exact with a concurrency of 1
+	// and adaptive concurrency off.
+	PortOrder string
+}
+
+// PortsCallback receives the ports of an IP scanned so far. The ports are still part of the
+// running scan, so a callback must copy what it needs before returning.
+type PortsCallback func(ip string, ports []*Port)
+
+// ZGrabModuleSettings represents per-module ZGrab2 overrides
+type ZGrabModuleSettings struct {
+	Timeout time.Duration
+	Retries int
 }
 
 // NewDefaultScanConfig creates a default scan configuration
@@ -158,10 +326,57 @@ func NewDefaultScanConfig() *ScanConfig {
 		RetryDelay:       1 * time.Second,
 		Concurrency:      100,
 		ZGrabConcurrency: 20, // Limit ZGrab2 processes to avoid system overload
-		DefaultPorts:     []int{21, 22, 23, 25, 53, 80, 110, 143, 443, 993, 995, 3306, 3389, 5432, 8080, 8443},
-		PriorityPorts:    []int{80, 443, 22, 21, 25, 3306, 5432}, // High-priority ports for banner grabbing
+		ZGrabPath:        "zgrab2",
+		DefaultPorts:     KnownPorts.DefaultPorts(),
+		PriorityPorts:    KnownPorts.PriorityPorts(), // High-priority ports for banner grabbing
 		EnableBanner:     true,
 		EnablePing:       true,
+		NativeICMP:       true,
+		ZGrabModules: map[string]ZGrabModuleSettings{
+			"oracle": {Timeout: 10 * time.Second, Retries: 1}, // Slow TNS handshakes
+			"mssql":  {Timeout: 10 * time.Second, Retries: 1}, // Slow TDS pre-login
+		},
+		SNMPCommunities: []string{"public", "private"},
+		SNMPVersion:     "2c",
+		MinConcurrency:  10,
+		MaxConcurrency:  500,
+
+		CrashCheckDelay:    1 * time.Second,
+		CrashCheckServices: []string{"modbus", "s7", "dnp3", "bacnet", "fox"}, // Fragile ICS services
+
+		BannerDenyServices: []string{"modbus", "s7", "dnp3", "bacnet", "fox"}, // Probing can disrupt ICS devices
+
+		BannerTimeoutLimit: 3,
+		MaxBannerLength:    DefaultMaxBannerLength,
+
+		SelfScanGuard: true,
+
+		PingCount:            1,
+		PingSuccessThreshold: 1,
+
+		ResultBufferSize: 100,
+		ResultPublishers: 4,
+
+		MaxBatchConcurrency: 10,
+
+		BannerConcurrencyPerHost: 5,
+
+		VerifyDelay: 500 * time.Millisecond,
+
+		RecentResultsLimit: 10000,
+		RecentResultsTTL:   time.Hour,
+
+		ConnIdleTimeout: 5 * time.Second,
+		ConnCacheSize:   1000,
+
+		Technique: TechniqueConnect,
+
+		DeadHostTTL: 10 * time.Minute,
+
+		StartTLSPorts:   DefaultStartTLSPorts,
+		StartTLSTimeout: 5 * time.Second,
+
+		HTTPProbe: HTTPProbe{UserAgent: DefaultHTTPUserAgent},
 	}
 }
 
@@ -170,6 +385,38 @@ type BannerGrabber interface {
 	GetBanner(ip string, port int) (*BannerInfo, error)
 }
 
+// ResultSink defines the interface for persisting scan results
+type ResultSink interface {
+	SaveScanResult(result *ScanResult) error
+	Close() error
+}
+
+// ResultArchive keeps scan results the engine evicted from memory, so their status can still
+// be looked up, more slowly
+type ResultArchive interface {
+	ArchiveResult(result *ScanResult) error
+	// ArchivedResult returns the latest archived result of ip; found is false when there is none
+	ArchivedResult(ip string) (result *ScanResult, found bool, err error)
+}
+
+// BatchClaimer records which worker is processing a batch so multiple instances can be observed
+type BatchClaimer interface {
+	ClaimBatch(batchID, workerID string) (bool, error)
+	HeartbeatBatch(batchID, workerID string) error
+	ReleaseBatch(batchID, workerID string) error
+}
+
+// SNMPProber defines the interface for SNMP probing of UDP services
+type SNMPProber interface {
+	ProbeSNMP(ip string, port int) (*BannerInfo, error)
+}
+
+// StartTLSProber detects STARTTLS on plaintext services ("smtp", "pop3", "imap") and returns
+// banner metadata: "starttls" and, after a successful upgrade, the TLS session and certificate
+type StartTLSProber interface {
+	ProbeStartTLS(ip string, port int, protocol string) (map[string]interface{}, error)
+}
+
 // OptimizedBannerGrabber defines the interface for optimized banner grabbing operations
 type OptimizedBannerGrabber interface {
 	GetBanner(ip string, port int) (*BannerInfo, error)
@@ -184,15 +431,26 @@ type Scanner interface {
 	ScanPorts(ip string, ports []int) ([]*Port, error)
 	GetBanner(ip string, port int) (*BannerInfo, error)
 	ScanIP(ip string, config *ScanConfig, batchID string, workerID string) (*ScanResult, error)
+	// CheckTechnique returns ErrUnsupportedTechnique if the scanner can't scan with a technique
+	CheckTechnique(technique string) error
 }
 
 // ScanEngine defines the interface for the main scanning engine
 type ScanEngine interface {
-	StartScanning()
+	StartScanning() error
 	StopScanning()
-	ProcessIP(ip string, batchID string) error
 	GetScanStatus(ip string) (*ScanResult, error)
+	// GetScanStats returns a snapshot of the statistics; RecordResult counts a result scanned
+	// outside the engine, such as by the API
 	GetScanStats() *ScanStats
+	RecordResult(result *ScanResult)
+	ScanConfig() *ScanConfig
+	ActiveScans() int
+	Deadline() (time.Time, bool)
+	// AcquireScanSlot waits for one of the engine's scan slots, shared by queued and API scans,
+	// and returns the function releasing it. It fails with ErrEngineStopped once the engine has
+	// stopped, or with the context's error.
+	AcquireScanSlot(ctx context.Context) (func(), error)
 }
 
 // ScanStats represents scanning statistics
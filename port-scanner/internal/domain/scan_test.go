@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"encoding/json"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestPortJSONUsesSnakeCaseKeys(t *testing.T) {
+	port := NewPort(443)
+	port.Status = PortStatusOpen
+	port.Service = "https"
+	port.Banner = "HTTP/1.1 200 OK"
+	port.Version = "nginx/1.25"
+	port.ResponseTime = 3 * time.Millisecond
+	port.BannerInfo = &BannerInfo{Service: "https"}
+	port.Error = "connection reset"
+
+	data, err := json.Marshal(port)
+	if err != nil {
+		t.Fatalf("json.Marshal returned error: %v", err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("json.Unmarshal returned error: %v", err)
+	}
+
+	want := []string{"banner", "banner_info", "error", "number", "response_time", "scan_time", "service", "status", "version"}
+	var got []string
+	for key := range fields {
+		got = append(got, key)
+	}
+	sort.Strings(got)
+	if len(got) != len(want) {
+		t.Fatalf("serialized keys = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("serialized keys = %v, want %v", got, want)
+		}
+	}
+
+	// Optional fields are left out when empty
+	data, err = json.Marshal(NewPort(22))
+	if err != nil {
+		t.Fatalf("json.Marshal returned error: %v", err)
+	}
+	fields = nil
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("json.Unmarshal returned error: %v", err)
+	}
+	for _, key := range []string{"banner", "version", "banner_info", "error"} {
+		if _, ok := fields[key]; ok {
+			t.Errorf("empty %s was serialized", key)
+		}
+	}
+}
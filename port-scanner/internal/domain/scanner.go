@@ -2,11 +2,15 @@ package domain
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"net"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"port-scanner/internal/infrastructure/ping"
@@ -15,23 +19,71 @@ import (
 	"go.uber.org/zap"
 )
 
+// hostPinger checks whether a host is up; the scanner pings through ping.SafePingService
+type hostPinger interface {
+	PingHost(ip string) (*ping.PingResult, error)
+}
+
 // ScannerService implements the Scanner interface with high concurrency
 type ScannerService struct {
 	config           *ScanConfig
 	stats            *ScanStats
 	mu               sync.RWMutex
 	bannerGrabber    BannerGrabber
-	pingService      *ping.SafePingService
+	pingService      hostPinger
 	optimizedGrabber OptimizedBannerGrabber
+	snmpProber       SNMPProber
+	startTLSProber   StartTLSProber
+	ctx              context.Context
+	limiter          *AdaptiveLimiter
+	ramp             *ConcurrencyRamp
+	jitter           *jitterSource
+	guard            *ScanGuard
+	enrichers        *EnricherChain
+	conns            *ConnCache
+	dialErrors       *DialErrorCounter
+	deadHosts        *DeadHostCache
 }
 
+// snmpPort is the well-known SNMP agent port (UDP)
+const snmpPort = 161
+
 // NewScannerService creates a new scanner service
 func NewScannerService(config *ScanConfig) *ScannerService {
-	return &ScannerService{
+	pingService := ping.NewSafePingService(config.PingTimeout)
+	pingService.SetNativeICMP(config.NativeICMP)
+	pingService.SetPacketCount(config.PingCount, config.PingSuccessThreshold)
+
+	scanner := &ScannerService{
 		config:      config,
 		stats:       NewScanStats(),
-		pingService: ping.NewSafePingService(config.PingTimeout),
+		pingService: pingService,
+		ctx:         context.Background(),
+		dialErrors:  NewDialErrorCounter(),
+		ramp:        NewConcurrencyRamp(config.RampUpDuration),
+		jitter:      newJitterSource(config.RetrySeed),
+	}
+	if config.AdaptiveConcurrency {
+		scanner.limiter = NewAdaptiveLimiter(config.Concurrency, config.MinConcurrency, config.MaxConcurrency)
+	}
+	if config.ReuseConnections {
+		scanner.conns = NewConnCache(config.ConnIdleTimeout, config.ConnCacheSize)
 	}
+	if config.DeadHostCache && config.DeadHostTTL > 0 {
+		scanner.deadHosts = NewDeadHostCache(config.DeadHostTTL)
+	}
+
+	return scanner
+}
+
+// ConnCache returns the cache of port scan connections, or nil when reuse is disabled
+func (s *ScannerService) ConnCache() *ConnCache {
+	return s.conns
+}
+
+// SetEnrichers sets the enrichers run on every completed scan result
+func (s *ScannerService) SetEnrichers(chain *EnricherChain) {
+	s.enrichers = chain
 }
 
 // SetBannerGrabber sets the banner grabber implementation
@@ -44,6 +96,26 @@ func (s *ScannerService) SetOptimizedBannerGrabber(bg OptimizedBannerGrabber) {
 	s.optimizedGrabber = bg
 }
 
+// SetSNMPProber sets the SNMP prober used for UDP port 161
+func (s *ScannerService) SetSNMPProber(prober SNMPProber) {
+	s.snmpProber = prober
+}
+
+// SetStartTLSProber sets the prober run on ports configured for STARTTLS
+func (s *ScannerService) SetStartTLSProber(prober StartTLSProber) {
+	s.startTLSProber = prober
+}
+
+// SetScanGuard sets the guard that refuses scans against the scanner itself and protected addresses
+func (s *ScannerService) SetScanGuard(guard *ScanGuard) {
+	s.guard = guard
+}
+
+// SetContext sets the context whose cancellation stops pending port scans
+func (s *ScannerService) SetContext(ctx context.Context) {
+	s.ctx = ctx
+}
+
 // PingHost performs a ping to check if the host is up using safe ping service
 func (s *ScannerService) PingHost(ip string) (bool, time.Duration, error) {
 	result, err := s.pingService.PingHost(ip)
@@ -55,33 +127,101 @@ func (s *ScannerService) PingHost(ip string) (bool, time.Duration, error) {
 		return false, result.Duration, result.Error
 	}
 
+	// Prefer the round-trip time over how long the ping took, which includes waiting for lost packets
+	if result.RTT > 0 {
+		return result.IsUp, result.RTT, nil
+	}
 	return result.IsUp, result.Duration, nil
 }
 
 // ScanPort scans a single port using TCP connect
 func (s *ScannerService) ScanPort(ip string, port int) (*Port, error) {
+	return s.scanPortWithRetry(ip, port, s.config, nil, nil)
+}
+
+// scanPort scans a single port with the given scan options; banner grabbing is
+// skipped once the host's circuit is open
+func (s *ScannerService) scanPort(ip string, port int, config *ScanConfig, circuit *bannerCircuit) (*Port, error) {
 	portObj := NewPort(port)
 	start := time.Now()
 
 	log.L().Debug("Scanning port", zap.String("event", "scan_port"), zap.String("ip", ip), zap.Int("port", port))
 
+	// SNMP listens on UDP, so a TCP connect cannot detect it
+	udpSilent := false
+	if port == snmpPort && s.snmpProber != nil {
+		bannerInfo, err := s.snmpProber.ProbeSNMP(ip, port)
+		if err == nil {
+			portObj.Status = PortStatusOpen
+			portObj.ResponseTime = time.Since(start)
+			portObj.Banner = bannerInfo.RawBanner
+			portObj.Service = bannerInfo.Service
+			portObj.Version = bannerInfo.Version
+			portObj.BannerInfo = bannerInfo
+			log.L().Info("SNMP agent responded", zap.String("event", "snmp_open"), zap.String("ip", ip), zap.Int("port", port))
+			return portObj, nil
+		}
+		log.L().Debug("SNMP probe failed", zap.String("event", "snmp_failed"), zap.String("ip", ip), zap.Error(err))
+		udpSilent = errors.Is(err, ErrNoResponse)
+	}
+
 	// Try to connect with timeout
-	conn, err := net.DialTimeout("tcp", fmt.Sprintf("%s:%d", ip, port), s.config.ConnectTimeout)
+	addr := net.JoinHostPort(ip, strconv.Itoa(port))
+	conn, err := net.DialTimeout("tcp", addr, config.ConnectTimeoutFor(port))
+	if s.limiter != nil {
+		s.limiter.Record(isConnectFailure(err))
+	}
 	if err != nil {
-		portObj.Status = PortStatusClosed
 		portObj.ResponseTime = time.Since(start)
+		category := s.dialErrors.Record(err)
+		// A silent UDP probe can't tell an open port from a filtered one
+		if udpSilent {
+			portObj.Status = PortStatusOpenFiltered
+			log.L().Debug("Port open or filtered", zap.String("event", "port_open_filtered"), zap.String("ip", ip), zap.Int("port", port))
+			return portObj, nil
+		}
+		// Failures a retry may fix are returned so scanPortWithRetry can spend its retries on them
+		if IsRetryableDialCategory(category) {
+			portObj.Status = PortStatusFiltered
+			return portObj, fmt.Errorf("%w (%s): %w", ErrRetryableDial, category, err)
+		}
+		portObj.Status = PortStatusClosed
 		log.L().Debug("Port closed", zap.String("event", "port_closed"), zap.String("ip", ip), zap.Int("port", port))
 		return portObj, nil // Not an error, just closed port
 	}
-	defer conn.Close()
+	// Leave the connection to the banner grab when reuse is enabled and a grab follows
+	if s.conns != nil && config.EnableBanner && !circuit.isOpen() && s.bannerAllowed(port, config) {
+		s.conns.Put(addr, conn)
+	} else {
+		defer conn.Close()
+	}
 
 	portObj.Status = PortStatusOpen
 	portObj.ResponseTime = time.Since(start)
 	log.L().Info("Port open", zap.String("event", "port_open"), zap.String("ip", ip), zap.Int("port", port))
 
+	// Skip banner grabbing on hosts where grabs keep timing out
+	if config.EnableBanner && circuit.isOpen() {
+		portObj.Service = s.identifyService(port, "")
+		log.L().Debug("Skipping banner grab for slow host", zap.String("event", "banner_skipped"), zap.String("ip", ip), zap.Int("port", port))
+		return portObj, nil
+	}
+
+	// Fragile services are identified by port alone unless banner grabbing them was opted in
+	if config.EnableBanner && !s.bannerAllowed(port, config) {
+		portObj.Service = s.identifyService(port, "")
+		log.L().Debug("Skipping banner grab for denied service", zap.String("event", "banner_denied"), zap.String("ip", ip),
+			zap.Int("port", port), zap.String("service", portObj.Service))
+		return portObj, nil
+	}
+
 	// Get banner if enabled
-	if s.config.EnableBanner {
+	if config.EnableBanner {
 		bannerInfo, err := s.GetBanner(ip, port)
+		if circuit.record(isTimeout(err)) {
+			log.L().Warn("Banner grabs keep timing out, skipping banners for remaining ports", zap.String("event", "banner_circuit_open"),
+				zap.String("ip", ip), zap.Int("timeouts", circuit.limit))
+		}
 		if err == nil {
 			portObj.Banner = bannerInfo.RawBanner
 			portObj.Service = bannerInfo.Service
@@ -91,81 +231,393 @@ func (s *ScannerService) ScanPort(ip string, port int) (*Port, error) {
 		} else {
 			log.L().Warn("Failed to grab banner", zap.String("event", "banner_failed"), zap.String("ip", ip), zap.Int("port", port), zap.Error(err))
 		}
+
+		if protocol, ok := config.StartTLSPorts[port]; ok && config.EnableStartTLS && s.startTLSProber != nil {
+			s.probeStartTLS(ip, portObj, protocol)
+		}
+
+		if s.shouldCheckForCrash(portObj) {
+			s.checkForCrash(ip, portObj)
+		}
 	}
 
 	return portObj, nil
 }
 
-// ScanPorts scans multiple ports concurrently
-func (s *ScannerService) ScanPorts(ip string, ports []int) ([]*Port, error) {
-	var results []*Port
-	var mu sync.Mutex
-	var wg sync.WaitGroup
+// bannerAllowed reports whether a port may be banner-grabbed: its port-based service must not
+// be denied, or must be explicitly allowed
+func (s *ScannerService) bannerAllowed(port int, config *ScanConfig) bool {
+	service := s.identifyService(port, "")
+	return !containsFold(config.BannerDenyServices, service) || containsFold(config.BannerAllowServices, service)
+}
 
-	// Create a semaphore to limit concurrency
-	semaphore := make(chan struct{}, s.config.Concurrency)
+// containsFold reports whether a list contains a value, ignoring case
+func containsFold(values []string, value string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
 
+// shouldCheckForCrash reports whether a port should be re-checked after banner grabbing
+func (s *ScannerService) shouldCheckForCrash(port *Port) bool {
+	if !s.config.CrashCheck {
+		return false
+	}
+	if len(s.config.CrashCheckServices) == 0 {
+		return true
+	}
+
+	service := port.Service
+	if service == "" {
+		service = s.identifyService(port.Number, "")
+	}
+	return containsFold(s.config.CrashCheckServices, service)
+}
+
+// checkForCrash re-connects to a port after banner grabbing and flags it
+// in the banner metadata when it no longer accepts connections
+func (s *ScannerService) checkForCrash(ip string, port *Port) {
+	time.Sleep(s.config.CrashCheckDelay)
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(ip, strconv.Itoa(port.Number)), s.config.ConnectTimeoutFor(port.Number))
+	if err == nil {
+		conn.Close()
+		return
+	}
+
+	s.ensureBannerInfo(port)
+	port.BannerInfo.Metadata["possible_crash"] = true
+	port.BannerInfo.Metadata["crash_check_error"] = err.Error()
+
+	log.L().Warn("Port stopped responding after banner grab, service may have crashed", zap.String("event", "possible_service_crash"),
+		zap.String("ip", ip), zap.Int("port", port.Number), zap.String("service", port.BannerInfo.Service), zap.Error(err))
+}
+
+// ensureBannerInfo gives a port without banner information a port-based identification, and
+// banner information without metadata an empty map, so checks can record their findings
+func (s *ScannerService) ensureBannerInfo(port *Port) {
+	if port.BannerInfo == nil {
+		port.BannerInfo = &BannerInfo{
+			Service:    s.identifyService(port.Number, port.Banner),
+			Protocol:   "tcp",
+			Confidence: "port",
+		}
+		port.BannerInfo.ScoreConfidence(port.Number)
+	}
+	if port.BannerInfo.Metadata == nil {
+		port.BannerInfo.Metadata = make(map[string]interface{})
+	}
+}
+
+// verifyOpenPorts re-connects to every open port after a delay and downgrades those that
+// no longer accept connections to filtered, since a single accept may come from a SYN-flood
+// responder or a transient listener. Ports already flagged by the crash check are left alone.
+func (s *ScannerService) verifyOpenPorts(ip string, ports []*Port, config *ScanConfig) {
+	var open []*Port
 	for _, port := range ports {
+		if port.Status != PortStatusOpen {
+			continue
+		}
+		if port.BannerInfo != nil && port.BannerInfo.Metadata["possible_crash"] == true {
+			continue
+		}
+		open = append(open, port)
+	}
+	if len(open) == 0 {
+		return
+	}
+
+	time.Sleep(config.VerifyDelay)
+
+	workers := config.Concurrency
+	if workers > len(open) {
+		workers = len(open)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan *Port)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
 		wg.Add(1)
-		go func(p int) {
+		go func() {
 			defer wg.Done()
+			for port := range jobs {
+				s.verifyOpenPort(ip, port, config)
+			}
+		}()
+	}
+	for _, port := range open {
+		jobs <- port
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// verifyOpenPort re-connects to a single open port, downgrading it when the connection fails.
+// It always dials a new connection: a cached one only proves the port accepted once.
+func (s *ScannerService) verifyOpenPort(ip string, port *Port, config *ScanConfig) {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(ip, strconv.Itoa(port.Number)), config.ConnectTimeoutFor(port.Number))
+	if err == nil {
+		conn.Close()
+		return
+	}
 
-			// Acquire semaphore
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
+	port.Status = PortStatusFiltered
+	s.ensureBannerInfo(port)
+	port.BannerInfo.Metadata["verification_failed"] = true
+	port.BannerInfo.Metadata["verification_error"] = err.Error()
 
-			// Scan port with retries
-			portResult, err := s.scanPortWithRetry(ip, p)
-			if err != nil {
-				// Log error but continue with other ports
+	log.L().Info("Open port failed verification, downgraded to filtered", zap.String("event", "port_verification_failed"),
+		zap.String("ip", ip), zap.Int("port", port.Number), zap.Error(err))
+}
+
+// ScanPorts scans multiple ports concurrently
+func (s *ScannerService) ScanPorts(ip string, ports []int) ([]*Port, error) {
+	return s.scanPorts(ip, ports, s.config)
+}
+
+// scanPorts scans multiple ports concurrently with the given scan options
+func (s *ScannerService) scanPorts(ip string, ports []int, config *ScanConfig) ([]*Port, error) {
+	// Results are stored by index so they come back in the order the ports were requested
+	results := make([]*Port, len(ports))
+	if len(ports) == 0 {
+		return results, nil
+	}
+
+	// Banner timeouts and retries are tracked per host for the duration of this scan
+	circuit := newBannerCircuit(config.BannerTimeoutLimit)
+	retries := NewRetryBudget(config.HostRetryBudget)
+
+	indexes := make([]int, len(ports))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	// The waves below keep this order among the ports each of them scans
+	indexes = orderPorts(ports, indexes, config.PortOrder, config.PriorityPorts)
+
+	// Triage scans probe the trigger ports first and stop there if one is open
+	if config.StopOnFirstTrigger && len(config.TriggerPorts) > 0 {
+		var triggers []int
+		triggers, indexes = splitPorts(ports, indexes, config.TriggerPorts)
+		s.scanPortWave(ip, ports, triggers, results, config, circuit, retries)
+		if port := firstOpenPort(results, triggers); port != nil {
+			log.L().Debug("Trigger port open, skipping remaining ports", zap.String("event", "scan_stopped_on_trigger"),
+				zap.String("ip", ip), zap.Int("port", port.Number), zap.Int("skipped", len(indexes)))
+			scanned := make([]*Port, len(triggers))
+			for i, index := range triggers {
+				scanned[i] = results[index]
+			}
+			return scanned, nil
+		}
+	}
+
+	if !config.PriorityFirst {
+		s.scanPortWave(ip, ports, indexes, results, config, circuit, retries)
+		return results, nil
+	}
+
+	// Priority ports go first so their results are available before the long tail finishes
+	priority, rest := splitPorts(ports, indexes, config.PriorityPorts)
+	s.scanPortWave(ip, ports, priority, results, config, circuit, retries)
+	if config.OnPriorityPorts != nil && len(priority) > 0 && s.ctx.Err() == nil {
+		first := make([]*Port, len(priority))
+		for i, index := range priority {
+			first[i] = results[index]
+		}
+		config.OnPriorityPorts(ip, first)
+	}
+	s.scanPortWave(ip, ports, rest, results, config, circuit, retries)
+
+	return results, nil
+}
+
+// splitPorts splits the given port indexes into those of ports found in set and the others,
+// each in request order
+func splitPorts(ports []int, indexes []int, set []int) (in []int, out []int) {
+	inSet := make(map[int]bool, len(set))
+	for _, port := range set {
+		inSet[port] = true
+	}
+	for _, i := range indexes {
+		if inSet[ports[i]] {
+			in = append(in, i)
+		} else {
+			out = append(out, i)
+		}
+	}
+	return in, out
+}
+
+// firstOpenPort returns the first open port among the results at the given indexes, or nil
+func firstOpenPort(results []*Port, indexes []int) *Port {
+	for _, i := range indexes {
+		if results[i] != nil && results[i].Status == PortStatusOpen {
+			return results[i]
+		}
+	}
+	return nil
+}
+
+// scanPortWave scans the ports at the given indexes, storing each result at its index
+func (s *ScannerService) scanPortWave(ip string, ports []int, indexes []int, results []*Port, config *ScanConfig, circuit *bannerCircuit, retries *RetryBudget) {
+	if len(indexes) == 0 {
+		return
+	}
+
+	// A fixed pool of workers pulls port indexes off a channel, so a full-range
+	// scan creates at most one goroutine per concurrency slot instead of one per port
+	workers := config.Concurrency
+	if s.limiter != nil && config.Concurrency >= s.config.Concurrency {
+		// The adaptive limiter gates the workers; start enough to reach its ceiling
+		// unless this scan asked for less than the engine's concurrency
+		workers = s.config.MaxConcurrency
+	}
+	if workers > len(indexes) {
+		workers = len(indexes)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(slot int) {
+			defer wg.Done()
+			// While ramping up, later workers join as the allowed concurrency grows;
+			// the first one always runs, so every port is scanned
+			if !s.ramp.Wait(s.ctx, slot, workers) {
 				return
 			}
+			for i := range jobs {
+				results[i] = s.scanPortJob(ip, ports[i], config, circuit, retries)
+			}
+		}(w)
+	}
 
-			mu.Lock()
-			results = append(results, portResult)
-			mu.Unlock()
-		}(port)
+	for _, i := range indexes {
+		jobs <- i
 	}
+	close(jobs)
 
 	wg.Wait()
+}
 
-	return results, nil
+// scanPortJob scans a single port for ScanPorts, recording failures and cancellation on the port
+func (s *ScannerService) scanPortJob(ip string, port int, config *ScanConfig, circuit *bannerCircuit, retries *RetryBudget) *Port {
+	if s.limiter != nil {
+		s.limiter.Acquire()
+		defer s.limiter.Release()
+	}
+
+	// Don't start new probes once the scan has been cancelled
+	if err := s.ctx.Err(); err != nil {
+		portResult := NewPort(port)
+		portResult.Status = PortStatusError
+		portResult.Error = err.Error()
+		return portResult
+	}
+
+	// Scan port with retries
+	portResult, err := s.scanPortWithRetry(ip, port, config, circuit, retries)
+	if err != nil {
+		// Record the failure so the port is distinguishable from closed ones
+		portResult.Status = PortStatusError
+		portResult.Error = err.Error()
+		log.L().Warn("Port scan failed", zap.String("event", "port_error"), zap.String("ip", ip), zap.Int("port", port), zap.Error(err))
+	}
+
+	return portResult
+}
+
+// ConcurrencyLimit returns the current port scan concurrency limit
+func (s *ScannerService) ConcurrencyLimit() int {
+	if s.limiter != nil {
+		return s.limiter.Limit()
+	}
+	return s.ramp.Limit(s.config.Concurrency)
 }
 
-// scanPortWithRetry scans a port with retry logic
-func (s *ScannerService) scanPortWithRetry(ip string, port int) (*Port, error) {
+// DialErrorCounts returns how many port connection attempts failed in each error category
+func (s *ScannerService) DialErrorCounts() map[string]int64 {
+	return s.dialErrors.Counts()
+}
+
+// isConnectFailure reports whether a dial error indicates congestion rather than a closed port.
+// Refused connections are a normal answer from a live host and are not counted.
+func isConnectFailure(err error) bool {
+	return err != nil && !errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// scanPortWithRetry scans a port with retry logic. Each retry is taken from the host's budget
+// and from the configuration's batch budget, if any; without retries left the port isn't retried.
+// A port that kept timing out is reported as filtered; other failures are returned.
+func (s *ScannerService) scanPortWithRetry(ip string, port int, config *ScanConfig, circuit *bannerCircuit, retries *RetryBudget) (*Port, error) {
+	var lastResult *Port
 	var lastErr error
 
-	for attempt := 0; attempt <= s.config.MaxRetries; attempt++ {
-		portResult, err := s.ScanPort(ip, port)
+	for attempt := 0; attempt <= config.MaxRetries; attempt++ {
+		portResult, err := s.scanPort(ip, port, config, circuit)
 		if err == nil {
 			return portResult, nil
 		}
 
-		lastErr = err
+		lastResult, lastErr = portResult, err
+		if !errors.Is(err, ErrRetryableDial) {
+			break
+		}
 
-		if attempt < s.config.MaxRetries {
-			time.Sleep(s.config.RetryDelay)
+		if attempt < config.MaxRetries {
+			if !retries.Take() || !config.RetryBudget.Take() {
+				log.L().Debug("Retry budget exhausted, not retrying port", zap.String("event", "retry_budget_exhausted"),
+					zap.String("ip", ip), zap.Int("port", port))
+				break
+			}
+			time.Sleep(config.RetryDelayFor(attempt, s.jitter.Float64()))
 		}
 	}
 
+	// No answer on any attempt: a firewall is dropping the probes
+	if ClassifyDialError(lastErr) == DialErrorTimeout {
+		log.L().Debug("Port filtered", zap.String("event", "port_filtered"), zap.String("ip", ip), zap.Int("port", port))
+		return lastResult, nil
+	}
 	return NewPort(port), lastErr
 }
 
 // GetBanner retrieves the banner from an open port using the optimized banner grabber
 func (s *ScannerService) GetBanner(ip string, port int) (*BannerInfo, error) {
-	// Use optimized banner grabber if available
-	if s.optimizedGrabber != nil {
-		return s.optimizedGrabber.GetBanner(ip, port)
+	var bannerInfo *BannerInfo
+	var err error
+
+	switch {
+	case s.optimizedGrabber != nil:
+		// Use optimized banner grabber if available
+		bannerInfo, err = s.optimizedGrabber.GetBanner(ip, port)
+	case s.bannerGrabber != nil:
+		// Fallback to configured banner grabber
+		bannerInfo, err = s.bannerGrabber.GetBanner(ip, port)
+	default:
+		// Fallback to basic banner grabbing
+		bannerInfo, err = s.basicBannerGrab(ip, port)
 	}
 
-	// Fallback to configured banner grabber
-	if s.bannerGrabber != nil {
-		return s.bannerGrabber.GetBanner(ip, port)
+	// Clean up control characters and whitespace, keeping the original for forensics
+	if s.config.NormalizeBanners {
+		bannerInfo.NormalizeRawBanner(s.config.MaxBannerLength)
 	}
 
-	// Fallback to basic banner grabbing
-	return s.basicBannerGrab(ip, port)
+	// Keep oversized banners (e.g. full HTTP bodies) bounded regardless of the grabber
+	bannerInfo.TruncateRawBanner(s.config.MaxBannerLength)
+	bannerInfo.ScoreConfidence(port)
+	return bannerInfo, err
 }
 
 // GetBannerStats returns banner grabbing statistics
@@ -183,21 +635,29 @@ func (s *ScannerService) Shutdown() {
 	if s.optimizedGrabber != nil {
 		s.optimizedGrabber.Shutdown()
 	}
+	if s.conns != nil {
+		s.conns.Close()
+	}
 }
 
 // basicBannerGrab provides basic banner grabbing as fallback
 func (s *ScannerService) basicBannerGrab(ip string, port int) (*BannerInfo, error) {
-	conn, err := net.DialTimeout("tcp", fmt.Sprintf("%s:%d", ip, port), s.config.BannerTimeout)
+	connectTimeout, readTimeout := s.config.BannerTimeouts()
+	conn, err := s.conns.Dial(net.JoinHostPort(ip, strconv.Itoa(port)), connectTimeout)
 	if err != nil {
 		return nil, err
 	}
 	defer conn.Close()
 
-	// Set read deadline
-	conn.SetReadDeadline(time.Now().Add(s.config.BannerTimeout))
+	// The read budget starts once connected, however long the connect took
+	conn.SetReadDeadline(time.Now().Add(readTimeout))
 
-	// Send a simple probe
-	_, err = conn.Write([]byte("\r\n"))
+	// Send a simple probe, or an HTTP request to plaintext HTTP ports
+	probe := []byte(DefaultBannerProbe)
+	if KnownPorts.PlainHTTP(port) {
+		probe = s.config.HTTPProbe.Request(ip)
+	}
+	_, err = conn.Write(probe)
 	if err != nil {
 		return nil, err
 	}
@@ -250,27 +710,7 @@ func (s *ScannerService) extractVersionFromBanner(banner string) string {
 
 // identifyService identifies the service based on port and banner
 func (s *ScannerService) identifyService(port int, banner string) string {
-	// Common port to service mappings
-	portServices := map[int]string{
-		21:   "ftp",
-		22:   "ssh",
-		23:   "telnet",
-		25:   "smtp",
-		53:   "dns",
-		80:   "http",
-		110:  "pop3",
-		143:  "imap",
-		443:  "https",
-		993:  "imaps",
-		995:  "pop3s",
-		3306: "mysql",
-		3389: "rdp",
-		5432: "postgresql",
-		8080: "http-proxy",
-		8443: "https-alt",
-	}
-
-	if service, exists := portServices[port]; exists {
+	if service := KnownPorts.Service(port); service != "unknown" {
 		return service
 	}
 
@@ -301,9 +741,33 @@ func (s *ScannerService) ScanIP(ip string, config *ScanConfig, batchID string, w
 	}
 
 	result := NewScanResult(ip, batchID, workerID)
-	result.Status = ScanStatusRunning
 
-	// Step 1: Ping check (if enabled)
+	// Refuse to scan ourselves or protected addresses
+	if err := s.guard.Check(ip); err != nil {
+		log.L().Warn("Refusing to scan protected target", zap.String("event", "scan_refused"), zap.String("ip", ip), zap.Error(err))
+		result.SetFailed(err.Error())
+		return result, err
+	}
+	if err := checkExclusions(ip, config.Exclusions); err != nil {
+		result.SetFailed(err.Error())
+		return result, err
+	}
+	if err := s.CheckTechnique(config.Technique); err != nil {
+		result.SetFailed(err.Error())
+		return result, err
+	}
+
+	result.Status = ScanStatusRunning
+	result.SetMetadata("scan_config", config.Snapshot())
+
+	// Step 1: Ping check (if enabled), skipped for hosts found down within the dead-host TTL
+	if since, down := s.deadHosts.DownSince(ip); config.EnablePing && down {
+		log.L().Debug("Skipping recently down host", zap.String("event", "dead_host_cached"), zap.String("ip", ip), zap.Time("down_since", since))
+		result.SetMetadata("down_since", since)
+		result.SetCompleted()
+		s.enrichers.Run(result)
+		return result, nil
+	}
 	if config.EnablePing {
 		isUp, pingTime, err := s.PingHost(ip)
 		if err != nil {
@@ -315,7 +779,21 @@ func (s *ScannerService) ScanIP(ip string, config *ScanConfig, batchID string, w
 		result.PingTime = pingTime
 
 		if !isUp {
+			s.deadHosts.MarkDown(ip)
+			result.SetCompleted()
+			s.enrichers.Run(result)
+			return result, nil
+		}
+		s.deadHosts.MarkUp(ip)
+
+		// A host answering too slowly is treated as down rather than spending the scan on it
+		if config.MaxPingRTT > 0 && pingTime > config.MaxPingRTT {
+			log.L().Debug("Skipping slow host", zap.String("event", "slow_host_skipped"), zap.String("ip", ip),
+				zap.Duration("ping_time", pingTime), zap.Duration("max_ping_rtt", config.MaxPingRTT))
+			result.IsUp = false
+			result.SetMetadata("ping_rtt_exceeded", true)
 			result.SetCompleted()
+			s.enrichers.Run(result)
 			return result, nil
 		}
 	} else {
@@ -329,18 +807,38 @@ func (s *ScannerService) ScanIP(ip string, config *ScanConfig, batchID string, w
 		portsToScan = config.PortRange
 	}
 
-	ports, err := s.ScanPorts(ip, portsToScan)
+	ports, err := s.scanPorts(ip, portsToScan, config)
 	if err != nil {
 		result.SetFailed(fmt.Sprintf("port scan failed: %v", err))
 		return result, err
 	}
+	if len(ports) < len(portsToScan) {
+		// Only a triage scan stopped by an open trigger port returns fewer ports
+		result.SetMetadata("stopped_on_trigger", true)
+		result.SetMetadata("ports_skipped", len(portsToScan)-len(ports))
+	}
+
+	// Step 3: Re-check open ports to weed out transient accepts
+	if config.VerifyOpenPorts && s.ctx.Err() == nil {
+		s.verifyOpenPorts(ip, ports, config)
+	}
 
 	// Add ports to result
 	for _, port := range ports {
 		result.AddPort(port)
 	}
 
+	// Keep the partial result but flag that not every port was probed
+	if err := s.ctx.Err(); err != nil {
+		result.SetFailed(fmt.Sprintf("scan cancelled: %v", err))
+		return result, nil
+	}
+
 	result.SetCompleted()
+
+	// Step 4: Enrich the result before it is persisted and published
+	s.enrichers.Run(result)
+
 	return result, nil
 }
 
@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"context"
+	"testing"
+)
+
+// newLocalScanner returns a scanner for loopback ports that doesn't retry, wait or grab banners
+func newLocalScanner() (*ScannerService, *ScanConfig) {
+	config := NewDefaultScanConfig()
+	config.EnableBanner = false
+	config.MaxRetries = 0
+	config.RetryDelay = 0
+	return NewScannerService(config), config
+}
+
+func TestScanPortsRecordsErroredPorts(t *testing.T) {
+	scanner, _ := newLocalScanner()
+	closed := closedLocalPorts(t, 2)
+
+	// An out-of-range port makes the dial itself fail instead of being answered
+	ports := []int{closed[0], 70000, closed[1]}
+	results, err := scanner.ScanPorts("127.0.0.1", ports)
+	if err != nil {
+		t.Fatalf("ScanPorts returned error: %v", err)
+	}
+	if len(results) != len(ports) {
+		t.Fatalf("got %d results for %d ports; errored ports must not vanish", len(results), len(ports))
+	}
+
+	for i, port := range results {
+		if port == nil {
+			t.Fatalf("result %d is missing", i)
+		}
+		if port.Number != ports[i] {
+			t.Errorf("result %d is port %d, want %d", i, port.Number, ports[i])
+		}
+	}
+	errored := results[1]
+	if errored.Status != PortStatusError || errored.Error == "" {
+		t.Errorf("errored port has status %s and error %q, want %s with the error recorded", errored.Status, errored.Error, PortStatusError)
+	}
+	for _, port := range []*Port{results[0], results[2]} {
+		if port.Status != PortStatusClosed || port.Error != "" {
+			t.Errorf("port %d has status %s and error %q, want closed", port.Number, port.Status, port.Error)
+		}
+	}
+}
+
+func TestScanPortsMarksPortsOfCancelledScans(t *testing.T) {
+	scanner, _ := newLocalScanner()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	scanner.SetContext(ctx)
+
+	ports := closedLocalPorts(t, 3)
+	results, err := scanner.ScanPorts("127.0.0.1", ports)
+	if err != nil {
+		t.Fatalf("ScanPorts returned error: %v", err)
+	}
+	for _, port := range results {
+		if port.Status != PortStatusError || port.Error != context.Canceled.Error() {
+			t.Errorf("port %d has status %s and error %q, want an error noting the cancellation", port.Number, port.Status, port.Error)
+		}
+	}
+}
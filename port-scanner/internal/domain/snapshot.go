@@ -0,0 +1,27 @@
+package domain
+
+// Snapshot describes the options a scan runs with, recorded in the result's metadata under
+// "scan_config" so a stored result tells how it was produced and the scan can be repeated
+func (c *ScanConfig) Snapshot() map[string]interface{} {
+	ports := c.DefaultPorts
+	if len(c.PortRange) > 0 {
+		ports = c.PortRange
+	}
+	technique := c.Technique
+	if technique == "" {
+		technique = TechniqueConnect
+	}
+
+	return map[string]interface{}{
+		"port_count":      len(ports),
+		"ping_timeout":    c.PingTimeout.String(),
+		"connect_timeout": c.ConnectTimeout.String(),
+		"banner_timeout":  c.BannerTimeout.String(),
+		"max_retries":     c.MaxRetries,
+		"retry_delay":     c.RetryDelay.String(),
+		"concurrency":     c.Concurrency,
+		"enable_banner":   c.EnableBanner,
+		"enable_ping":     c.EnablePing,
+		"technique":       technique,
+	}
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSnapshotDescribesScanConfig(t *testing.T) {
+	config := NewDefaultScanConfig()
+	config.PortRange = []int{22, 80, 443}
+	config.PingTimeout = 2 * time.Second
+	config.ConnectTimeout = 1500 * time.Millisecond
+	config.BannerTimeout = 3 * time.Second
+	config.MaxRetries = 2
+	config.RetryDelay = 100 * time.Millisecond
+	config.Concurrency = 25
+	config.EnableBanner = false
+	config.EnablePing = true
+	config.Technique = ""
+
+	want := map[string]interface{}{
+		"port_count":      3,
+		"ping_timeout":    "2s",
+		"connect_timeout": "1.5s",
+		"banner_timeout":  "3s",
+		"max_retries":     2,
+		"retry_delay":     "100ms",
+		"concurrency":     25,
+		"enable_banner":   false,
+		"enable_ping":     true,
+		"technique":       TechniqueConnect,
+	}
+	if got := config.Snapshot(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Snapshot = %v, want %v", got, want)
+	}
+
+	// Without explicit ports the scan covers the defaults
+	config.PortRange = nil
+	if got := config.Snapshot()["port_count"]; got != len(config.DefaultPorts) {
+		t.Errorf("port_count without a port range = %v, want the %d default ports", got, len(config.DefaultPorts))
+	}
+}
+
+func TestScanIPStoresConfigSnapshot(t *testing.T) {
+	scanner, config := newLocalScanner()
+	config.EnablePing = false
+	config.Concurrency = 7
+	config.ConnectTimeout = 750 * time.Millisecond
+	config.PortRange = closedLocalPorts(t, 4)
+
+	result, err := scanner.ScanIP("127.0.0.1", config, "batch-1", "worker-1")
+	if err != nil {
+		t.Fatalf("ScanIP returned error: %v", err)
+	}
+	snapshot, ok := result.Metadata["scan_config"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("metadata = %v, want a scan_config snapshot", result.Metadata)
+	}
+	if snapshot["port_count"] != 4 || snapshot["concurrency"] != 7 || snapshot["connect_timeout"] != "750ms" ||
+		snapshot["enable_banner"] != false || snapshot["enable_ping"] != false {
+		t.Errorf("scan_config = %v, want the options the scan ran with", snapshot)
+	}
+	if len(result.Ports) != snapshot["port_count"] {
+		t.Errorf("scanned %d ports, snapshot says %v", len(result.Ports), snapshot["port_count"])
+	}
+}
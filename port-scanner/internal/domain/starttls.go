@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+
+	"port-scanner/pkg/log"
+
+	"go.uber.org/zap"
+)
+
+// Plaintext protocols whose STARTTLS upgrade can be probed
+const (
+	StartTLSSMTP = "smtp"
+	StartTLSPOP3 = "pop3"
+	StartTLSIMAP = "imap"
+)
+
+// DefaultStartTLSPorts are the mail ports probed for STARTTLS when none are configured
+var DefaultStartTLSPorts = map[int]string{
+	25:  StartTLSSMTP,
+	587: StartTLSSMTP,
+	110: StartTLSPOP3,
+	143: StartTLSIMAP,
+}
+
+// ParseStartTLSPorts decodes a port to STARTTLS protocol map as read from configuration
+func ParseStartTLSPorts(ports map[string]string) (map[int]string, error) {
+	parsed := make(map[int]string, len(ports))
+	for portValue, protocol := range ports {
+		port, err := parsePortNumber(strings.TrimSpace(portValue))
+		if err != nil {
+			return nil, fmt.Errorf("invalid STARTTLS port %q: %w", portValue, err)
+		}
+		protocol = strings.ToLower(strings.TrimSpace(protocol))
+		switch protocol {
+		case StartTLSSMTP, StartTLSPOP3, StartTLSIMAP:
+		default:
+			return nil, fmt.Errorf("unsupported STARTTLS protocol %q for port %d", protocol, port)
+		}
+		parsed[port] = protocol
+	}
+	return parsed, nil
+}
+
+// probeStartTLS records in a port's banner metadata whether the service offers STARTTLS and,
+// when the upgrade succeeds, the certificate it presented
+func (s *ScannerService) probeStartTLS(ip string, port *Port, protocol string) {
+	metadata, err := s.startTLSProber.ProbeStartTLS(ip, port.Number, protocol)
+	if err != nil {
+		log.L().Debug("STARTTLS probe failed", zap.String("event", "starttls_failed"), zap.String("ip", ip), zap.Int("port", port.Number), zap.Error(err))
+		return
+	}
+
+	s.ensureBannerInfo(port)
+	for key, value := range metadata {
+		port.BannerInfo.Metadata[key] = value
+	}
+
+	log.L().Debug("STARTTLS probed", zap.String("event", "starttls_probed"), zap.String("ip", ip), zap.Int("port", port.Number),
+		zap.String("protocol", protocol), zap.Any("starttls", metadata["starttls"]))
+}
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+)
+
+// stubStartTLSProber returns fixed metadata for every probe
+type stubStartTLSProber struct {
+	metadata map[string]interface{}
+}
+
+func (p stubStartTLSProber) ProbeStartTLS(ip string, port int, protocol string) (map[string]interface{}, error) {
+	return p.metadata, nil
+}
+
+func TestProbeStartTLSRecordsSupportInBannerMetadata(t *testing.T) {
+	scanner, _ := newLocalScanner()
+	scanner.SetStartTLSProber(stubStartTLSProber{map[string]interface{}{"starttls": true, "tls_cert_subject": "CN=mail.example.com"}})
+
+	port := NewPort(25)
+	port.Status = PortStatusOpen
+	port.Banner = "220 mail.example.com ESMTP"
+	port.BannerInfo = &BannerInfo{RawBanner: port.Banner, Metadata: map[string]interface{}{"greeting": "220"}}
+	scanner.probeStartTLS("127.0.0.1", port, StartTLSSMTP)
+
+	want := map[string]interface{}{"greeting": "220", "starttls": true, "tls_cert_subject": "CN=mail.example.com"}
+	if !reflect.DeepEqual(port.BannerInfo.Metadata, want) {
+		t.Errorf("banner metadata = %v, want %v", port.BannerInfo.Metadata, want)
+	}
+
+	// A port without a banner gets banner info for the metadata
+	bare := NewPort(587)
+	scanner.probeStartTLS("127.0.0.1", bare, StartTLSSMTP)
+	if bare.BannerInfo == nil || bare.BannerInfo.Metadata["starttls"] != true {
+		t.Errorf("banner info = %+v, want starttls recorded", bare.BannerInfo)
+	}
+}
+
+func TestParseStartTLSPorts(t *testing.T) {
+	parsed, err := ParseStartTLSPorts(map[string]string{"25": "SMTP", " 2143 ": "imap"})
+	if err != nil {
+		t.Fatalf("ParseStartTLSPorts returned error: %v", err)
+	}
+	if want := map[int]string{25: StartTLSSMTP, 2143: StartTLSIMAP}; !reflect.DeepEqual(parsed, want) {
+		t.Errorf("ParseStartTLSPorts = %v, want %v", parsed, want)
+	}
+
+	for _, invalid := range []map[string]string{{"smtp": "smtp"}, {"70000": "smtp"}, {"21": "ftp"}} {
+		if _, err := ParseStartTLSPorts(invalid); err == nil {
+			t.Errorf("ParseStartTLSPorts(%v) accepted invalid ports", invalid)
+		}
+	}
+}
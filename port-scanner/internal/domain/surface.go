@@ -0,0 +1,157 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Attack surface categories of open ports
+const (
+	SurfaceRemoteAccess = "remote-access"
+	SurfaceDatabase     = "database"
+	SurfaceWeb          = "web"
+	SurfaceMail         = "mail"
+	SurfaceLegacy       = "legacy" // Legacy or cleartext protocols
+	SurfaceOther        = "other"
+)
+
+// SurfaceAnalyzer names the findings of an attack surface summary
+const SurfaceAnalyzer = "attack-surface"
+
+// surfaceCategories maps service families to their attack surface category
+var surfaceCategories = map[string]string{
+	"ssh":           SurfaceRemoteAccess,
+	"rdp":           SurfaceRemoteAccess,
+	"vnc":           SurfaceRemoteAccess,
+	"winrm":         SurfaceRemoteAccess,
+	"mysql":         SurfaceDatabase,
+	"postgresql":    SurfaceDatabase,
+	"mssql":         SurfaceDatabase,
+	"oracle":        SurfaceDatabase,
+	"mongodb":       SurfaceDatabase,
+	"redis":         SurfaceDatabase,
+	"couchdb":       SurfaceDatabase,
+	"elasticsearch": SurfaceDatabase,
+	"memcached":     SurfaceDatabase,
+	"http":          SurfaceWeb,
+	"smtp":          SurfaceMail,
+	"imap":          SurfaceMail,
+	"pop3":          SurfaceMail,
+	"telnet":        SurfaceLegacy,
+	"ftp":           SurfaceLegacy,
+	"tftp":          SurfaceLegacy,
+	"rlogin":        SurfaceLegacy,
+	"rsh":           SurfaceLegacy,
+	"rexec":         SurfaceLegacy,
+	"snmp":          SurfaceLegacy,
+}
+
+// surfaceWeights is how much an open port of each category adds to the exposure score
+var surfaceWeights = map[string]int{
+	SurfaceLegacy:       25,
+	SurfaceDatabase:     20,
+	SurfaceRemoteAccess: 15,
+	SurfaceMail:         8,
+	SurfaceWeb:          5,
+	SurfaceOther:        3,
+}
+
+// SurfacePort is an open port in an attack surface summary
+type SurfacePort struct {
+	Port    int    `json:"port"`
+	Service string `json:"service"`
+	Version string `json:"version,omitempty"`
+}
+
+// AttackSurface summarizes what a host exposes: its open ports by risk category, an exposure
+// score from 0 to 100 and the notable findings among them
+type AttackSurface struct {
+	IP            string                   `json:"ip"`
+	OpenPorts     int                      `json:"open_ports"`
+	ExposureScore int                      `json:"exposure_score"`
+	Categories    map[string][]SurfacePort `json:"categories"`
+	Findings      []Finding                `json:"findings"`
+}
+
+// NewAttackSurface summarizes the attack surface of a scan result. Each open port adds its
+// category's weight to the exposure score, which is capped at 100.
+func NewAttackSurface(result *ScanResult) *AttackSurface {
+	surface := &AttackSurface{
+		IP:         result.IP,
+		Categories: make(map[string][]SurfacePort),
+		Findings:   []Finding{},
+	}
+
+	open := result.GetOpenPorts()
+	sort.Slice(open, func(i, j int) bool { return open[i].Number < open[j].Number })
+	for _, port := range open {
+		// Ports whose service wasn't identified are taken to run the one registered for them
+		service := strings.ToLower(port.Service)
+		if serviceFamily(service) == "" {
+			service = KnownPorts.Service(port.Number)
+		}
+		category, ok := surfaceCategories[serviceFamily(service)]
+		if !ok {
+			category = SurfaceOther
+		}
+
+		version := port.Version
+		if version == "" && port.BannerInfo != nil {
+			version = port.BannerInfo.Version
+		}
+		surface.Categories[category] = append(surface.Categories[category], SurfacePort{Port: port.Number, Service: service, Version: version})
+		surface.ExposureScore += surfaceWeights[category]
+
+		if finding, ok := surfaceFinding(category, service, port.Number); ok {
+			surface.Findings = append(surface.Findings, finding)
+		}
+	}
+	surface.OpenPorts = len(open)
+	surface.ExposureScore = min(surface.ExposureScore, 100)
+
+	// The same service answering with different versions may be a proxy or a forgotten instance
+	for _, cluster := range ClusterServices(open) {
+		if !cluster.VersionMismatch {
+			continue
+		}
+		surface.Findings = append(surface.Findings, Finding{
+			Analyzer: SurfaceAnalyzer,
+			Port:     cluster.Ports[0],
+			Service:  cluster.Service,
+			Severity: SeverityLow,
+			Title:    fmt.Sprintf("%s reports different versions on ports %s", cluster.Service, joinPorts(cluster.Ports)),
+			Detail:   strings.Join(cluster.Versions, ", "),
+		})
+	}
+
+	return surface
+}
+
+// surfaceFinding returns the finding an open port of a category is worth, if any
+func surfaceFinding(category, service string, port int) (Finding, bool) {
+	finding := Finding{Analyzer: SurfaceAnalyzer, Port: port, Service: service}
+	switch category {
+	case SurfaceLegacy:
+		finding.Severity = SeverityHigh
+		finding.Title = fmt.Sprintf("Legacy or cleartext service %s exposed", service)
+	case SurfaceDatabase:
+		finding.Severity = SeverityHigh
+		finding.Title = fmt.Sprintf("Database %s exposed", service)
+	case SurfaceRemoteAccess:
+		finding.Severity = SeverityMedium
+		finding.Title = fmt.Sprintf("Remote access service %s exposed", service)
+	default:
+		return Finding{}, false
+	}
+	return finding, true
+}
+
+// joinPorts formats port numbers as a comma-separated list
+func joinPorts(ports []int) string {
+	values := make([]string, len(ports))
+	for i, port := range ports {
+		values[i] = fmt.Sprint(port)
+	}
+	return strings.Join(values, ", ")
+}
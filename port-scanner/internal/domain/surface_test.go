@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+)
+
+// multiServiceHost returns the scan result of a host exposing a mix of services, two of them
+// web servers that disagree on their version
+func multiServiceHost() *ScanResult {
+	return &ScanResult{
+		IP: "192.0.2.10",
+		Ports: []*Port{
+			openServicePort(8080, "http-proxy", "nginx 1.24"),
+			openServicePort(22, "ssh", "OpenSSH 9.6"),
+			openServicePort(80, "http", "Apache 2.4.58"),
+			openServicePort(3306, "mysql", "8.0.36"),
+			openServicePort(25, "smtp", ""),
+			openServicePort(23, "", ""), // Unidentified, taken as the registered telnet
+			openServicePort(9999, "unknown", ""),
+			{Number: 5432, Status: PortStatusClosed, Service: "postgresql"},
+		},
+	}
+}
+
+func TestNewAttackSurfaceCategorizesOpenPorts(t *testing.T) {
+	surface := NewAttackSurface(multiServiceHost())
+
+	want := map[string][]SurfacePort{
+		SurfaceRemoteAccess: {{Port: 22, Service: "ssh", Version: "OpenSSH 9.6"}},
+		SurfaceLegacy:       {{Port: 23, Service: "telnet"}},
+		SurfaceMail:         {{Port: 25, Service: "smtp"}},
+		SurfaceWeb: {
+			{Port: 80, Service: "http", Version: "Apache 2.4.58"},
+			{Port: 8080, Service: "http-proxy", Version: "nginx 1.24"},
+		},
+		SurfaceDatabase: {{Port: 3306, Service: "mysql", Version: "8.0.36"}},
+		SurfaceOther:    {{Port: 9999, Service: "unknown"}},
+	}
+	if !reflect.DeepEqual(surface.Categories, want) {
+		t.Errorf("Categories = %+v, want %+v", surface.Categories, want)
+	}
+	if surface.IP != "192.0.2.10" || surface.OpenPorts != 7 {
+		t.Errorf("IP, OpenPorts = %s, %d, want 192.0.2.10, 7", surface.IP, surface.OpenPorts)
+	}
+
+	// ssh 15 + telnet 25 + smtp 8 + two web servers 5 each + mysql 20 + other 3
+	if surface.ExposureScore != 81 {
+		t.Errorf("ExposureScore = %d, want 81", surface.ExposureScore)
+	}
+}
+
+func TestNewAttackSurfaceReportsFindings(t *testing.T) {
+	surface := NewAttackSurface(multiServiceHost())
+
+	type finding struct {
+		port     int
+		service  string
+		severity string
+	}
+	want := []finding{
+		{22, "ssh", SeverityMedium},
+		{23, "telnet", SeverityHigh},
+		{3306, "mysql", SeverityHigh},
+		{80, "http", SeverityLow}, // The version mismatch between ports 80 and 8080
+	}
+	var got []finding
+	for _, f := range surface.Findings {
+		if f.Analyzer != SurfaceAnalyzer {
+			t.Errorf("finding %q has analyzer %q, want %q", f.Title, f.Analyzer, SurfaceAnalyzer)
+		}
+		got = append(got, finding{f.Port, f.Service, f.Severity})
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findings = %+v, want %+v", got, want)
+	}
+
+	mismatch := surface.Findings[len(surface.Findings)-1]
+	if mismatch.Title != "http reports different versions on ports 80, 8080" {
+		t.Errorf("mismatch title = %q", mismatch.Title)
+	}
+}
+
+func TestNewAttackSurfaceCapsScore(t *testing.T) {
+	result := &ScanResult{IP: "192.0.2.20"}
+	for _, number := range []int{21, 23, 69, 512, 513, 514} {
+		result.Ports = append(result.Ports, openServicePort(number, "telnet", ""))
+	}
+
+	if surface := NewAttackSurface(result); surface.ExposureScore != 100 {
+		t.Errorf("ExposureScore = %d, want it capped at 100", surface.ExposureScore)
+	}
+}
+
+func TestNewAttackSurfaceWithoutOpenPorts(t *testing.T) {
+	surface := NewAttackSurface(&ScanResult{IP: "192.0.2.30", Ports: []*Port{{Number: 22, Status: PortStatusClosed}}})
+
+	if surface.OpenPorts != 0 || surface.ExposureScore != 0 || len(surface.Categories) != 0 {
+		t.Errorf("surface = %+v, want nothing exposed", surface)
+	}
+	if surface.Findings == nil {
+		t.Error("Findings is nil, want an empty list so it encodes as []")
+	}
+}
+
+func TestNewAttackSurfaceUsesBannerVersion(t *testing.T) {
+	port := openServicePort(22, "ssh", "")
+	port.BannerInfo = &BannerInfo{Version: "OpenSSH 8.9"}
+
+	surface := NewAttackSurface(&ScanResult{Ports: []*Port{port}})
+	if got := surface.Categories[SurfaceRemoteAccess]; len(got) != 1 || got[0].Version != "OpenSSH 8.9" {
+		t.Errorf("remote access ports = %+v, want the banner's version", got)
+	}
+}
@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"fmt"
+	"net"
+)
+
+// MaxTargetListIPs caps the addresses a stored target list may expand to, so one list can't
+// queue an unbounded scan
+const MaxTargetListIPs = 65536
+
+// ExpandTargets expands IP addresses and CIDR blocks into the addresses they cover, in order
+// and without duplicates. It fails when they cover more than limit addresses.
+func ExpandTargets(targets []string, limit int) ([]string, error) {
+	blocks, err := ParseTargets(targets)
+	if err != nil {
+		return nil, err
+	}
+
+	var ips []string
+	seen := make(map[string]bool)
+	for _, block := range blocks {
+		ones, bits := block.Mask.Size()
+		if bits-ones >= 31 || 1<<(bits-ones) > limit-len(ips) {
+			return nil, fmt.Errorf("targets cover more than %d addresses", limit)
+		}
+
+		ip := append(net.IP(nil), block.IP.Mask(block.Mask)...)
+		for ; block.Contains(ip); incrementIP(ip) {
+			if address := ip.String(); !seen[address] {
+				seen[address] = true
+				ips = append(ips, address)
+			}
+			if ones == bits {
+				break
+			}
+		}
+	}
+	return ips, nil
+}
+
+// incrementIP advances ip to the next address in place
+func incrementIP(ip net.IP) {
+	for i := len(ip) - 1; i >= 0; i-- {
+		ip[i]++
+		if ip[i] != 0 {
+			return
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandTargets(t *testing.T) {
+	ips, err := ExpandTargets([]string{"10.0.0.6/31", "10.0.0.7", "192.0.2.1", "10.0.0.4/30"}, 100)
+	if err != nil {
+		t.Fatalf("ExpandTargets returned error: %v", err)
+	}
+	want := []string{"10.0.0.6", "10.0.0.7", "192.0.2.1", "10.0.0.4", "10.0.0.5"}
+	if !reflect.DeepEqual(ips, want) {
+		t.Errorf("ExpandTargets = %v, want %v", ips, want)
+	}
+
+	if _, err := ExpandTargets([]string{"10.0.0.0/24", "10.0.1.0/30"}, 256); err == nil {
+		t.Error("ExpandTargets returned 260 addresses with a limit of 256")
+	}
+	if _, err := ExpandTargets([]string{"0.0.0.0/0"}, MaxTargetListIPs); err == nil {
+		t.Error("ExpandTargets expanded the whole address space")
+	}
+	if _, err := ExpandTargets([]string{"10.0.0.300"}, 100); err == nil {
+		t.Error("ExpandTargets accepted an invalid address")
+	}
+}
+
+func TestNewBatchMessagesSplitsIPs(t *testing.T) {
+	ips := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5"}
+	messages := NewBatchMessages("list-dmz", ips, []int{22}, 2)
+	if len(messages) != 3 {
+		t.Fatalf("%d messages, want 3", len(messages))
+	}
+
+	for i, message := range messages {
+		if message.ParentBatchID != "list-dmz" || message.Sequence != i || message.Final != (i == 2) {
+			t.Errorf("message %d = parent %q, sequence %d, final %v", i, message.ParentBatchID, message.Sequence, message.Final)
+		}
+		if !reflect.DeepEqual(message.Ports, []int{22}) || message.Count != len(message.IPs) {
+			t.Errorf("message %d ports/count = %v/%d, want [22]/%d", i, message.Ports, message.Count, len(message.IPs))
+		}
+	}
+	if messages[1].BatchID != "list-dmz-1" || !reflect.DeepEqual(messages[2].IPs, []string{"10.0.0.5"}) {
+		t.Errorf("messages = %+v, want IPs split two at a time", messages)
+	}
+}
@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// TCP scan techniques selectable per scan
+const (
+	TechniqueConnect = "connect" // Full TCP handshake through the OS; needs no privileges
+	TechniqueSYN     = "syn"     // Half-open scan with raw packets; needs raw-socket capability
+)
+
+// ErrUnsupportedTechnique is returned when a scan asks for a technique this process can't run
+var ErrUnsupportedTechnique = errors.New("unsupported scan technique")
+
+// CheckTechnique reports whether this process can scan with a technique; empty means connect.
+// SYN scanning needs raw sockets (root or CAP_NET_RAW) and a half-open scanner, which this
+// build doesn't include yet, so it is always refused with the reason.
+func (s *ScannerService) CheckTechnique(technique string) error {
+	switch technique {
+	case TechniqueConnect, "":
+		return nil
+	case TechniqueSYN:
+		if err := checkRawSocket(); err != nil {
+			return fmt.Errorf("%w: syn scanning needs raw-socket capability (root or CAP_NET_RAW): %v", ErrUnsupportedTechnique, err)
+		}
+		return fmt.Errorf("%w: syn scanning is not available in this build, use %q", ErrUnsupportedTechnique, TechniqueConnect)
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedTechnique, technique)
+	}
+}
+
+// checkRawSocket opens and closes a raw TCP socket to test for the privilege SYN scans need
+func checkRawSocket() error {
+	conn, err := net.ListenPacket("ip4:tcp", "0.0.0.0")
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckTechnique(t *testing.T) {
+	scanner, _ := newLocalScanner()
+
+	for _, technique := range []string{TechniqueConnect, ""} {
+		if err := scanner.CheckTechnique(technique); err != nil {
+			t.Errorf("CheckTechnique(%q) = %v, want it supported", technique, err)
+		}
+	}
+
+	// SYN scans are refused whether or not the process has raw-socket capability
+	err := scanner.CheckTechnique(TechniqueSYN)
+	if !errors.Is(err, ErrUnsupportedTechnique) {
+		t.Fatalf("CheckTechnique(syn) = %v, want ErrUnsupportedTechnique", err)
+	}
+	if !strings.Contains(err.Error(), "syn scanning") {
+		t.Errorf("CheckTechnique(syn) = %q, want the reason", err)
+	}
+
+	if err := scanner.CheckTechnique("xmas"); !errors.Is(err, ErrUnsupportedTechnique) {
+		t.Errorf("CheckTechnique(xmas) = %v, want ErrUnsupportedTechnique", err)
+	}
+}
+
+func TestScanIPRefusesUnsupportedTechniqueBeforeScanning(t *testing.T) {
+	scanner, config := newLocalScanner()
+	config.Technique = TechniqueSYN
+	config.PortRange = openLocalPorts(t, 1)
+
+	result, err := scanner.ScanIP("127.0.0.1", config, "batch-1", "worker-1")
+	if !errors.Is(err, ErrUnsupportedTechnique) {
+		t.Fatalf("ScanIP returned %v, want ErrUnsupportedTechnique", err)
+	}
+	if result.Status != ScanStatusFailed || len(result.Ports) != 0 {
+		t.Errorf("result = %s with %d ports, want failed without probes", result.Status, len(result.Ports))
+	}
+}
@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScanPortsStopsOnOpenTriggerPort(t *testing.T) {
+	scanner, config := newLocalScanner()
+	trigger := openLocalPorts(t, 1)[0]
+	closed := closedLocalPorts(t, 1)[0]
+	slow := unresponsiveLocalPort(t)
+	config.ConnectTimeout = time.Second
+	config.StopOnFirstTrigger = true
+	config.TriggerPorts = []int{trigger, 3389}
+
+	start := time.Now()
+	results, err := scanner.scanPorts("127.0.0.1", []int{slow, closed, trigger}, config)
+	if err != nil {
+		t.Fatalf("scanPorts returned error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= config.ConnectTimeout/2 {
+		t.Errorf("scan took %v, want it to return before probing the slow port", elapsed)
+	}
+	if len(results) != 1 || results[0].Number != trigger || results[0].Status != PortStatusOpen {
+		t.Errorf("results = %v, want only the open trigger port", results)
+	}
+}
+
+func TestScanPortsContinuesWhenTriggerPortsClosed(t *testing.T) {
+	scanner, config := newLocalScanner()
+	closed := closedLocalPorts(t, 2)
+	open := openLocalPorts(t, 1)[0]
+	config.StopOnFirstTrigger = true
+	config.TriggerPorts = []int{closed[0]}
+
+	results, err := scanner.scanPorts("127.0.0.1", []int{open, closed[0], closed[1]}, config)
+	if err != nil {
+		t.Fatalf("scanPorts returned error: %v", err)
+	}
+	if len(results) != 3 || results[0].Number != open || results[0].Status != PortStatusOpen {
+		t.Errorf("results = %v, want every port scanned in request order", results)
+	}
+
+	// Without triage mode, an open trigger port doesn't stop the scan
+	config.StopOnFirstTrigger = false
+	config.TriggerPorts = []int{open}
+	if results, _ := scanner.scanPorts("127.0.0.1", []int{open, closed[0], closed[1]}, config); len(results) != 3 {
+		t.Errorf("scanned %d ports without triage mode, want 3", len(results))
+	}
+}
+
+func TestScanIPRecordsPortsSkippedByTrigger(t *testing.T) {
+	scanner, config := newLocalScanner()
+	trigger := openLocalPorts(t, 1)[0]
+	config.EnablePing = false
+	config.StopOnFirstTrigger = true
+	config.TriggerPorts = []int{trigger}
+	config.PortRange = append(closedLocalPorts(t, 3), trigger)
+
+	result, err := scanner.ScanIP("127.0.0.1", config, "batch-1", "worker-1")
+	if err != nil {
+		t.Fatalf("ScanIP returned error: %v", err)
+	}
+	if len(result.Ports) != 1 || result.Ports[0].Number != trigger {
+		t.Errorf("result ports = %v, want only the trigger port", result.Ports)
+	}
+	if result.Metadata["stopped_on_trigger"] != true || result.Metadata["ports_skipped"] != 3 {
+		t.Errorf("metadata = %v, want the scan marked stopped with 3 ports skipped", result.Metadata)
+	}
+}
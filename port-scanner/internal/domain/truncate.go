@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// DefaultMaxBannerLength is the banner size limit used when none is configured
+const DefaultMaxBannerLength = 4096
+
+// TruncateBanner cuts a banner to at most maxLength bytes (plus a marker noting how
+// much was dropped) without splitting a UTF-8 character. A maxLength of 0 disables truncation.
+func TruncateBanner(banner string, maxLength int) (string, bool) {
+	if maxLength <= 0 || len(banner) <= maxLength {
+		return banner, false
+	}
+
+	cut := maxLength
+	for cut > 0 && !utf8.RuneStart(banner[cut]) {
+		cut--
+	}
+
+	return fmt.Sprintf("%s...[truncated %d bytes]", banner[:cut], len(banner)-cut), true
+}
+
+// TruncateRawBanner truncates the raw banner in place and flags it in the metadata
+func (b *BannerInfo) TruncateRawBanner(maxLength int) bool {
+	if b == nil {
+		return false
+	}
+
+	originalLength := len(b.RawBanner)
+	banner, truncated := TruncateBanner(b.RawBanner, maxLength)
+	if !truncated {
+		return false
+	}
+
+	b.RawBanner = banner
+	if b.Metadata == nil {
+		b.Metadata = make(map[string]interface{})
+	}
+	b.Metadata["banner_truncated"] = true
+	b.Metadata["banner_original_length"] = originalLength
+	return true
+}
@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTruncateBannerCutsAndMarksLongBanners(t *testing.T) {
+	banner, truncated := TruncateBanner(strings.Repeat("a", 100), 10)
+	if !truncated {
+		t.Fatal("100-byte banner was not truncated at 10 bytes")
+	}
+	if want := strings.Repeat("a", 10) + "...[truncated 90 bytes]"; banner != want {
+		t.Errorf("truncated banner = %q, want %q", banner, want)
+	}
+
+	for _, maxLength := range []int{0, 100} {
+		if banner, truncated := TruncateBanner(strings.Repeat("a", 100), maxLength); truncated || len(banner) != 100 {
+			t.Errorf("TruncateBanner with limit %d = %q, %v, want the banner unchanged", maxLength, banner, truncated)
+		}
+	}
+}
+
+func TestTruncateBannerKeepsUTF8CharactersWhole(t *testing.T) {
+	// "é" is two bytes, so a limit of 3 falls inside the second one
+	banner, truncated := TruncateBanner("ééé", 3)
+	if !truncated || !strings.HasPrefix(banner, "é...") {
+		t.Errorf("TruncateBanner = %q, %v, want one whole character kept", banner, truncated)
+	}
+	if !strings.HasSuffix(banner, "[truncated 4 bytes]") {
+		t.Errorf("TruncateBanner = %q, want 4 dropped bytes reported", banner)
+	}
+}
+
+func TestTruncateRawBannerFlagsMetadata(t *testing.T) {
+	info := &BannerInfo{RawBanner: strings.Repeat("x", 50)}
+	if !info.TruncateRawBanner(20) {
+		t.Fatal("TruncateRawBanner did not truncate a 50-byte banner at 20 bytes")
+	}
+	if info.Metadata["banner_truncated"] != true || info.Metadata["banner_original_length"] != 50 {
+		t.Errorf("metadata = %v, want the banner flagged with its original length", info.Metadata)
+	}
+
+	short := &BannerInfo{RawBanner: "SSH-2.0-OpenSSH_9.6"}
+	if short.TruncateRawBanner(20) || short.Metadata != nil {
+		t.Errorf("short banner was flagged: %v", short.Metadata)
+	}
+}
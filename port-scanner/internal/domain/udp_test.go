@@ -0,0 +1,37 @@
+package domain
+
+import "testing"
+
+// silentSNMP is an SNMPProber whose probes never get a reply
+type silentSNMP struct{}
+
+func (silentSNMP) ProbeSNMP(ip string, port int) (*BannerInfo, error) {
+	return nil, ErrNoResponse
+}
+
+func TestSilentUDPPortIsOpenFiltered(t *testing.T) {
+	scanner, _ := newLocalScanner()
+	scanner.SetSNMPProber(silentSNMP{})
+
+	// Nothing answers on loopback's TCP 161 either, so only the silent UDP probe decides
+	port, err := scanner.ScanPort("127.0.0.1", snmpPort)
+	if err != nil {
+		t.Fatalf("ScanPort returned error: %v", err)
+	}
+	if port.Status != PortStatusOpenFiltered {
+		t.Fatalf("silent SNMP port status = %q, want %q", port.Status, PortStatusOpenFiltered)
+	}
+
+	result := NewScanResult("127.0.0.1", "batch-1", "worker-1")
+	open := NewPort(22)
+	open.Status = PortStatusOpen
+	result.AddPort(open)
+	result.AddPort(port)
+
+	if got := result.GetOpenPorts(); len(got) != 1 || got[0].Number != 22 {
+		t.Errorf("GetOpenPorts() = %v, want only port 22", got)
+	}
+	if got := result.GetOpenFilteredPorts(); len(got) != 1 || got[0].Number != snmpPort {
+		t.Errorf("GetOpenFilteredPorts() = %v, want only port %d", got, snmpPort)
+	}
+}
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// acceptOnce listens on a local port that accepts a single connection and then refuses
+func acceptOnce(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen returned error: %v", err)
+	}
+	go func() {
+		conn, err := listener.Accept()
+		listener.Close()
+		if err == nil {
+			conn.Close()
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestVerifyOpenPortsDowngradesFlappyPort(t *testing.T) {
+	scanner, config := newLocalScanner()
+	config.VerifyOpenPorts = true
+	// Leave the flappy listener time to close after its single accept
+	config.VerifyDelay = 50 * time.Millisecond
+
+	flappy := acceptOnce(t)
+	stable := openLocalPorts(t, 1)[0]
+
+	ports, err := scanner.ScanPorts("127.0.0.1", []int{flappy, stable})
+	if err != nil {
+		t.Fatalf("ScanPorts returned error: %v", err)
+	}
+	for _, port := range ports {
+		if port.Status != PortStatusOpen {
+			t.Fatalf("port %d = %q before verification, want open", port.Number, port.Status)
+		}
+	}
+
+	scanner.verifyOpenPorts("127.0.0.1", ports, config)
+
+	for _, port := range ports {
+		switch port.Number {
+		case flappy:
+			if port.Status != PortStatusFiltered {
+				t.Errorf("flappy port = %q after verification, want filtered", port.Status)
+			}
+			if port.BannerInfo == nil || port.BannerInfo.Metadata["verification_failed"] != true {
+				t.Errorf("flappy port banner = %v, want verification_failed set", port.BannerInfo)
+			}
+		case stable:
+			if port.Status != PortStatusOpen {
+				t.Errorf("stable port = %q after verification, want open", port.Status)
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package domain
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fullRange is the number of ports in a full-range scan
+const fullRange = 65535
+
+// goroutinePeak samples the number of goroutines until stopped and reports the highest seen
+type goroutinePeak struct {
+	peak int64
+	stop chan struct{}
+	done chan struct{}
+}
+
+func startGoroutinePeak() *goroutinePeak {
+	p := &goroutinePeak{stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(p.done)
+		for {
+			if n := int64(runtime.NumGoroutine()); n > atomic.LoadInt64(&p.peak) {
+				atomic.StoreInt64(&p.peak, n)
+			}
+			select {
+			case <-p.stop:
+				return
+			case <-time.After(100 * time.Microsecond):
+			}
+		}
+	}()
+	return p
+}
+
+func (p *goroutinePeak) Stop() int64 {
+	close(p.stop)
+	<-p.done
+	return atomic.LoadInt64(&p.peak)
+}
+
+// probe stands in for a port probe, yielding so queued goroutines pile up as they would on the network
+func probe(results []int, i int) {
+	runtime.Gosched()
+	results[i] = i
+}
+
+// perPortGoroutines is the previous ScanPorts pattern: one goroutine per port, gated by a semaphore
+func perPortGoroutines(ports, concurrency int) {
+	results := make([]int, ports)
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < ports; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			probe(results, i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// workerPool is the ScanPorts pattern: a fixed number of workers pulling port indexes off a channel
+func workerPool(ports, concurrency int) {
+	results := make([]int, ports)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				probe(results, i)
+			}
+		}()
+	}
+	for i := 0; i < ports; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+func benchmarkDispatch(b *testing.B, dispatch func(ports, concurrency int)) {
+	concurrency := NewDefaultScanConfig().Concurrency
+	b.ReportAllocs()
+
+	var peak int64
+	for n := 0; n < b.N; n++ {
+		sampler := startGoroutinePeak()
+		dispatch(fullRange, concurrency)
+		if got := sampler.Stop(); got > peak {
+			peak = got
+		}
+	}
+	b.ReportMetric(float64(peak), "peak-goroutines")
+}
+
+func BenchmarkFullRangePerPortGoroutines(b *testing.B) {
+	benchmarkDispatch(b, perPortGoroutines)
+}
+
+func BenchmarkFullRangeWorkerPool(b *testing.B) {
+	benchmarkDispatch(b, workerPool)
+}
+
+func BenchmarkScanPortsFullRange(b *testing.B) {
+	scanner, _ := newLocalScanner()
+	ports := make([]int, fullRange)
+	for i := range ports {
+		ports[i] = i + 1
+	}
+	b.ReportAllocs()
+
+	var peak int64
+	for n := 0; n < b.N; n++ {
+		sampler := startGoroutinePeak()
+		if _, err := scanner.ScanPorts("127.0.0.1", ports); err != nil {
+			b.Fatalf("ScanPorts returned error: %v", err)
+		}
+		if got := sampler.Stop(); got > peak {
+			peak = got
+		}
+	}
+	b.ReportMetric(float64(peak), "peak-goroutines")
+}
@@ -0,0 +1,96 @@
+package archive
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"port-scanner/internal/domain"
+)
+
+// DiskArchive spills scan results to a directory as one JSON file per IP, replaced by the
+// IP's next archived result
+type DiskArchive struct {
+	dir string
+}
+
+// Ensure DiskArchive implements ResultArchive interface
+var _ domain.ResultArchive = (*DiskArchive)(nil)
+
+// NewDiskArchive creates an archive in dir, creating the directory if needed
+func NewDiskArchive(dir string) (*DiskArchive, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create result archive directory %s: %w", dir, err)
+	}
+	return &DiskArchive{dir: dir}, nil
+}
+
+// ArchiveResult implements ResultArchive. The file is written aside and renamed into place so
+// a concurrent lookup never reads it half-written.
+func (a *DiskArchive) ArchiveResult(result *domain.ScanResult) error {
+	path, ok := a.path(result.IP)
+	if !ok {
+		return fmt.Errorf("invalid IP %q", result.IP)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal result of %s: %w", result.IP, err)
+	}
+
+	tmp, err := os.CreateTemp(a.dir, ".result-*")
+	if err != nil {
+		return fmt.Errorf("failed to archive result of %s: %w", result.IP, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to archive result of %s: %w", result.IP, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to archive result of %s: %w", result.IP, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to archive result of %s: %w", result.IP, err)
+	}
+	return nil
+}
+
+// ArchivedResult implements ResultArchive
+func (a *DiskArchive) ArchivedResult(ip string) (*domain.ScanResult, bool, error) {
+	path, ok := a.path(ip)
+	if !ok {
+		return nil, false, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read archived result of %s: %w", ip, err)
+	}
+
+	var result domain.ScanResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, false, fmt.Errorf("failed to decode archived result of %s: %w", ip, err)
+	}
+	return &result, true, nil
+}
+
+// path returns the file of an IP's result; only IP addresses are accepted, so a lookup
+// can't name a file outside the directory
+func (a *DiskArchive) path(ip string) (string, bool) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return "", false
+	}
+	name := strings.ReplaceAll(parsed.String(), ":", "_") + ".json"
+	return filepath.Join(a.dir, name), true
+}
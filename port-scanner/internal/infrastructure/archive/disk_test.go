@@ -0,0 +1,75 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"port-scanner/internal/domain"
+)
+
+func TestDiskArchiveRoundTrip(t *testing.T) {
+	archive, err := NewDiskArchive(filepath.Join(t.TempDir(), "results"))
+	if err != nil {
+		t.Fatalf("NewDiskArchive returned error: %v", err)
+	}
+
+	for _, ip := range []string{"8.8.8.8", "2001:4860:4860::8888"} {
+		result := domain.NewScanResult(ip, "batch-1", "worker-1")
+		port := domain.NewPort(443)
+		port.Status = domain.PortStatusOpen
+		result.AddPort(port)
+		result.SetCompleted()
+		if err := archive.ArchiveResult(result); err != nil {
+			t.Fatalf("ArchiveResult(%s) returned error: %v", ip, err)
+		}
+
+		got, found, err := archive.ArchivedResult(ip)
+		if err != nil || !found {
+			t.Fatalf("ArchivedResult(%s) = %v, %v, want the archived result", ip, found, err)
+		}
+		if got.IP != ip || got.BatchID != "batch-1" || len(got.GetOpenPorts()) != 1 {
+			t.Errorf("ArchivedResult(%s) = %+v, want the archived result", ip, got)
+		}
+	}
+}
+
+func TestDiskArchiveKeepsLatestResult(t *testing.T) {
+	archive, err := NewDiskArchive(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDiskArchive returned error: %v", err)
+	}
+
+	for _, batchID := range []string{"batch-1", "batch-2"} {
+		if err := archive.ArchiveResult(domain.NewScanResult("8.8.8.8", batchID, "worker-1")); err != nil {
+			t.Fatalf("ArchiveResult returned error: %v", err)
+		}
+	}
+	got, _, _ := archive.ArchivedResult("8.8.8.8")
+	if got == nil || got.BatchID != "batch-2" {
+		t.Errorf("ArchivedResult = %v, want the latest result", got)
+	}
+
+	// One file per IP, without leftover temporary files
+	entries, _ := os.ReadDir(archive.dir)
+	if len(entries) != 1 || entries[0].Name() != "8.8.8.8.json" {
+		t.Errorf("archive holds %v, want only 8.8.8.8.json", entries)
+	}
+}
+
+func TestDiskArchiveRejectsNonIPs(t *testing.T) {
+	archive, err := NewDiskArchive(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDiskArchive returned error: %v", err)
+	}
+
+	if _, found, err := archive.ArchivedResult("9.9.9.9"); found || err != nil {
+		t.Errorf("ArchivedResult of an unknown IP = %v, %v, want not found", found, err)
+	}
+	if _, found, err := archive.ArchivedResult("../../etc/passwd"); found || err != nil {
+		t.Errorf("ArchivedResult of a path = %v, %v, want not found", found, err)
+	}
+	if err := archive.ArchiveResult(domain.NewScanResult("../escape", "batch-1", "worker-1")); err == nil {
+		t.Error("ArchiveResult accepted a result whose IP is a path")
+	}
+}
@@ -0,0 +1,115 @@
+package banner
+
+import (
+	"fmt"
+	"strings"
+
+	"port-scanner/internal/domain"
+)
+
+// cdnSignature matches a response header identifying a CDN or load balancer.
+// An empty contains matches the header's presence alone.
+type cdnSignature struct {
+	provider string
+	header   string
+	contains string
+}
+
+// cdnSignatures lists headers set by well-known CDNs and edge proxies
+var cdnSignatures = []cdnSignature{
+	{provider: "cloudflare", header: "server", contains: "cloudflare"},
+	{provider: "cloudflare", header: "cf_ray"},
+	{provider: "cloudfront", header: "server", contains: "cloudfront"},
+	{provider: "cloudfront", header: "x_amz_cf_id"},
+	{provider: "cloudfront", header: "x_amz_cf_pop"},
+	{provider: "akamai", header: "server", contains: "akamaighost"},
+	{provider: "akamai", header: "x_akamai_transformed"},
+	{provider: "fastly", header: "x_fastly_request_id"},
+	{provider: "fastly", header: "x_served_by", contains: "cache-"},
+	{provider: "azure_front_door", header: "x_azure_ref"},
+	{provider: "sucuri", header: "x_sucuri_id"},
+	{provider: "sucuri", header: "server", contains: "sucuri"},
+	{provider: "edgecast", header: "server", contains: "ecacc"},
+	{provider: "google", header: "via", contains: "google"},
+	{provider: "incapsula", header: "x_iinfo"},
+	{provider: "incapsula", header: "x_cdn", contains: "incapsula"},
+}
+
+// proxyHeaders are set by caches and load balancers in general; they flag the
+// host as proxied without naming who runs the proxy
+var proxyHeaders = []string{"via", "x_cache", "x_cache_hits", "x_cdn", "x_varnish"}
+
+// detectCDN inspects ZGrab2 HTTP response headers for signs of a CDN or load balancer.
+// Known CDN ASNs are not checked since results carry no ASN data.
+func detectCDN(data map[string]interface{}) *domain.CDNInfo {
+	headers := httpResponseHeaders(data)
+	if len(headers) == 0 {
+		return nil
+	}
+
+	cdn := &domain.CDNInfo{}
+	matched := make(map[string]bool)
+	for _, signature := range cdnSignatures {
+		value, ok := headers[signature.header]
+		if !ok || matched[signature.header] || !strings.Contains(strings.ToLower(value), signature.contains) {
+			continue
+		}
+		if cdn.Provider == "" {
+			cdn.Provider = signature.provider
+		}
+		matched[signature.header] = true
+		cdn.Indicators = append(cdn.Indicators, headerIndicator(signature.header, value))
+	}
+
+	for _, header := range proxyHeaders {
+		if value, ok := headers[header]; ok && !matched[header] {
+			cdn.Indicators = append(cdn.Indicators, headerIndicator(header, value))
+		}
+	}
+
+	if len(cdn.Indicators) == 0 {
+		return nil
+	}
+	if cdn.Provider == "" {
+		cdn.Provider = domain.CDNUnknownProvider
+	}
+	return cdn
+}
+
+// httpResponseHeaders returns the HTTP response headers from ZGrab2 data with names
+// normalized to ZGrab2's lower_snake_case and multi-valued headers joined
+func httpResponseHeaders(data map[string]interface{}) map[string]string {
+	httpData, ok := data["http"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	response, ok := httpData["response"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	rawHeaders, ok := response["headers"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	headers := make(map[string]string, len(rawHeaders))
+	for name, value := range rawHeaders {
+		name = strings.ReplaceAll(strings.ToLower(name), "-", "_")
+		switch v := value.(type) {
+		case string:
+			headers[name] = v
+		case []interface{}:
+			values := make([]string, 0, len(v))
+			for _, item := range v {
+				values = append(values, fmt.Sprint(item))
+			}
+			headers[name] = strings.Join(values, ", ")
+		}
+	}
+	return headers
+}
+
+// headerIndicator formats a header as a detection indicator
+func headerIndicator(name, value string) string {
+	return fmt.Sprintf("%s: %s", strings.ReplaceAll(name, "_", "-"), value)
+}
@@ -0,0 +1,101 @@
+package banner
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"port-scanner/internal/domain"
+)
+
+// zgrabHTTP wraps response headers the way ZGrab2's http module reports them
+func zgrabHTTP(headers map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"http": map[string]interface{}{
+			"status": "success",
+			"response": map[string]interface{}{
+				"status_line": "200 OK",
+				"headers":     headers,
+			},
+		},
+	}
+}
+
+func TestDetectCDNFromHeaders(t *testing.T) {
+	tests := []struct {
+		name       string
+		headers    map[string]interface{}
+		provider   string
+		indicators []string
+	}{
+		{
+			name:       "cloudflare server and ray",
+			headers:    map[string]interface{}{"server": []interface{}{"cloudflare"}, "cf_ray": []interface{}{"8a1b2c3d4e5f-GRU"}},
+			provider:   "cloudflare",
+			indicators: []string{"server: cloudflare", "cf-ray: 8a1b2c3d4e5f-GRU"},
+		},
+		{
+			name:       "cloudfront through a cache",
+			headers:    map[string]interface{}{"X-Amz-Cf-Id": "abc==", "Via": "1.1 d111.cloudfront.net (CloudFront)", "X-Cache": "Hit from cloudfront"},
+			provider:   "cloudfront",
+			indicators: []string{"x-amz-cf-id: abc==", "via: 1.1 d111.cloudfront.net (CloudFront)", "x-cache: Hit from cloudfront"},
+		},
+		{
+			name:       "fastly cache node",
+			headers:    map[string]interface{}{"x_served_by": []interface{}{"cache-gru17921-GRU"}},
+			provider:   "fastly",
+			indicators: []string{"x-served-by: cache-gru17921-GRU"},
+		},
+		{
+			name:       "unidentified proxy",
+			headers:    map[string]interface{}{"server": "nginx", "via": "1.1 varnish", "x_varnish": "12345"},
+			provider:   domain.CDNUnknownProvider,
+			indicators: []string{"via: 1.1 varnish", "x-varnish: 12345"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cdn := detectCDN(zgrabHTTP(tt.headers))
+			if cdn == nil {
+				t.Fatal("detectCDN found no CDN")
+			}
+			if cdn.Provider != tt.provider {
+				t.Errorf("provider = %q, want %q", cdn.Provider, tt.provider)
+			}
+			if !reflect.DeepEqual(cdn.Indicators, tt.indicators) {
+				t.Errorf("indicators = %q, want %q", cdn.Indicators, tt.indicators)
+			}
+		})
+	}
+}
+
+func TestDetectCDNIgnoresDirectHosts(t *testing.T) {
+	direct := []map[string]interface{}{
+		zgrabHTTP(map[string]interface{}{"server": "Apache/2.4.58", "content_type": "text/html"}),
+		zgrabHTTP(map[string]interface{}{}),
+		{"ssh": map[string]interface{}{"server_banner": "SSH-2.0-OpenSSH_9.6"}},
+	}
+	for _, data := range direct {
+		if cdn := detectCDN(data); cdn != nil {
+			t.Errorf("detectCDN(%v) = %+v, want nil", data, cdn)
+		}
+	}
+}
+
+func TestAnalyzeZGrabResultFlagsCDN(t *testing.T) {
+	z := NewZGrabBannerService(time.Second)
+	info := z.analyzeZGrabResult(BannerResult{IP: "203.0.113.7", Data: zgrabHTTP(map[string]interface{}{"server": "cloudflare"})}, 443)
+
+	cdn, ok := info.Metadata["cdn"].(*domain.CDNInfo)
+	if !ok || cdn.Provider != "cloudflare" {
+		t.Errorf("banner metadata cdn = %v, want cloudflare", info.Metadata["cdn"])
+	}
+
+	result := domain.NewScanResult("203.0.113.7", "batch-1", "worker-1")
+	port := domain.NewPort(443)
+	port.BannerInfo = info
+	result.AddPort(port)
+	if got := result.CDN(); got != cdn {
+		t.Errorf("ScanResult.CDN() = %v, want the port's CDN", got)
+	}
+}
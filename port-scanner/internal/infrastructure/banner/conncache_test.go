@@ -0,0 +1,103 @@
+package banner
+
+import (
+	"net"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"port-scanner/internal/domain"
+)
+
+// answeringPort listens locally and answers the first write on each connection with banner,
+// counting the connections accepted and reporting those closed without a probe
+func answeringPort(t *testing.T, banner string) (int, *atomic.Int32, chan struct{}) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	accepted := &atomic.Int32{}
+	unused := make(chan struct{}, 4)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			accepted.Add(1)
+			go func() {
+				defer conn.Close()
+				conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+				var buf [256]byte
+				if _, err := conn.Read(buf[:]); err != nil {
+					unused <- struct{}{}
+					return
+				}
+				conn.Write([]byte(banner + "\r\n"))
+			}()
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port, accepted, unused
+}
+
+// cachedConn dials a port and hands the connection to a new cache, as the port scan does
+func cachedConn(t *testing.T, port int) (*domain.ConnCache, string) {
+	t.Helper()
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	cache := domain.NewConnCache(time.Second, 10)
+	t.Cleanup(cache.Close)
+	cache.Put(addr, conn)
+	return cache, addr
+}
+
+func TestFallbackBannerGrabReusesCachedConnection(t *testing.T) {
+	port, accepted, _ := answeringPort(t, "SSH-2.0-OpenSSH_9.6")
+	cache, addr := cachedConn(t, port)
+
+	z := NewZGrabBannerService(time.Second)
+	z.SetConnCache(cache)
+	info, err := z.FallbackBannerGrab("127.0.0.1", port)
+	if err != nil {
+		t.Fatalf("FallbackBannerGrab returned error: %v", err)
+	}
+	if info.RawBanner != "SSH-2.0-OpenSSH_9.6" {
+		t.Errorf("banner = %q, want SSH-2.0-OpenSSH_9.6", info.RawBanner)
+	}
+	if got := accepted.Load(); got != 1 {
+		t.Errorf("server accepted %d connections, want the cached one reused", got)
+	}
+	if _, ok := cache.Take(addr); ok {
+		t.Error("connection still cached after the grab used it")
+	}
+}
+
+func TestZGrabRunReleasesCachedConnection(t *testing.T) {
+	port, _, unused := answeringPort(t, "SSH-2.0-OpenSSH_9.6")
+	cache, addr := cachedConn(t, port)
+
+	// A missing binary makes the run fail, so the grab falls back to a new connection
+	z := NewZGrabBannerService(time.Second)
+	z.SetBinaryPath(filepath.Join(t.TempDir(), "zgrab2"))
+	z.SetConnCache(cache)
+	if _, err := z.GetBanner("127.0.0.1", port); err != nil {
+		t.Fatalf("GetBanner returned error: %v", err)
+	}
+
+	select {
+	case <-unused:
+	case <-time.After(2 * time.Second):
+		t.Error("cached connection left open while ZGrab2 ran")
+	}
+	if _, ok := cache.Take(addr); ok {
+		t.Error("connection still cached after ZGrab2 ran")
+	}
+}
@@ -0,0 +1,61 @@
+package banner
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// delayedBannerPort listens locally and writes banner on each connection after delay
+func delayedBannerPort(t *testing.T, banner string, delay time.Duration) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				time.Sleep(delay)
+				conn.Write([]byte(banner + "\r\n"))
+				time.Sleep(100 * time.Millisecond)
+			}()
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestFallbackBannerGrabGivesReadItsOwnBudget(t *testing.T) {
+	z := NewZGrabBannerService(5 * time.Second)
+	z.SetFallbackTimeouts(100*time.Millisecond, 600*time.Millisecond)
+
+	// The banner arrives after the connect timeout but within the read timeout
+	port := delayedBannerPort(t, "SSH-2.0-OpenSSH_9.6", 300*time.Millisecond)
+	info, err := z.FallbackBannerGrab("127.0.0.1", port)
+	if err != nil {
+		t.Fatalf("FallbackBannerGrab returned error: %v", err)
+	}
+	if info.RawBanner != "SSH-2.0-OpenSSH_9.6" {
+		t.Errorf("banner = %q, want SSH-2.0-OpenSSH_9.6", info.RawBanner)
+	}
+}
+
+func TestFallbackBannerGrabStopsAtReadTimeout(t *testing.T) {
+	z := NewZGrabBannerService(5 * time.Second)
+	z.SetFallbackTimeouts(time.Second, 150*time.Millisecond)
+
+	port := delayedBannerPort(t, "SSH-2.0-OpenSSH_9.6", 500*time.Millisecond)
+	start := time.Now()
+	if info, err := z.FallbackBannerGrab("127.0.0.1", port); err == nil && info != nil && info.RawBanner != "" {
+		t.Errorf("read banner %q sent after the read timeout", info.RawBanner)
+	}
+	if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+		t.Errorf("grab took %v, want it to stop at the 150ms read timeout", elapsed)
+	}
+}
@@ -13,6 +13,9 @@ type BannerGrabber struct {
 	timeout       time.Duration
 	mu            sync.RWMutex
 	stats         *BannerGrabStats
+	probes        map[int][]byte
+	httpProbe     domain.HTTPProbe
+	hostLimiter   *hostLimiter
 }
 
 // BannerGrabStats tracks banner grabbing statistics
@@ -39,11 +42,52 @@ func NewBannerGrabber(zgrabWorkers int, timeout time.Duration, priorityPorts []i
 		priorityPorts: priorityMap,
 		timeout:       timeout,
 		stats:         &BannerGrabStats{},
+		httpProbe:     domain.HTTPProbe{UserAgent: domain.DefaultHTTPUserAgent},
 	}
 }
 
+// SetModuleSettings sets per-module ZGrab2 timeout and retry overrides
+func (o *BannerGrabber) SetModuleSettings(settings map[string]domain.ZGrabModuleSettings) {
+	o.workerPool.SetModuleSettings(settings)
+}
+
+// SetBinaryPath sets the ZGrab2 executable used by the worker pool
+func (o *BannerGrabber) SetBinaryPath(path string) {
+	o.workerPool.SetBinaryPath(path)
+}
+
+// SetMaxBannerLength sets the raw banner size limit used by the worker pool
+func (o *BannerGrabber) SetMaxBannerLength(maxLength int) {
+	o.workerPool.SetMaxBannerLength(maxLength)
+}
+
+// SetProbePayloads sets the per-port payloads used by basic banner grabbing
+func (o *BannerGrabber) SetProbePayloads(probes map[int][]byte) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.probes = probes
+}
+
+// SetHTTPProbe sets the User-Agent and headers sent to HTTP services by ZGrab2 and basic grabbing
+func (o *BannerGrabber) SetHTTPProbe(probe domain.HTTPProbe) {
+	o.workerPool.SetHTTPProbe(probe)
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.httpProbe = probe
+}
+
+// SetHostConcurrency caps concurrent banner grabs against a single host; 0 disables the cap.
+// It must be called before grabbing starts.
+func (o *BannerGrabber) SetHostConcurrency(limit int) {
+	o.hostLimiter = newHostLimiter(limit)
+}
+
 // GetBanner retrieves banner information with optimization
 func (o *BannerGrabber) GetBanner(ip string, port int) (*domain.BannerInfo, error) {
+	// Wait for a slot on this host before taking one from the shared pool
+	release := o.hostLimiter.acquire(ip)
+	defer release()
+
 	start := time.Now()
 	defer func() {
 		o.updateStats(time.Since(start), nil)
@@ -104,6 +148,10 @@ func (o *BannerGrabber) getBannerWithZGrab(ip string, port int) (*domain.BannerI
 func (o *BannerGrabber) getBannerBasic(ip string, port int) (*domain.BannerInfo, error) {
 	// Use the basic banner grabber
 	basicGrabber := NewZGrabBannerService(o.timeout)
+	o.mu.RLock()
+	basicGrabber.SetProbePayloads(o.probes)
+	basicGrabber.SetHTTPProbe(o.httpProbe)
+	o.mu.RUnlock()
 	return basicGrabber.FallbackBannerGrab(ip, port)
 }
 
@@ -149,6 +197,7 @@ func (o *BannerGrabber) GetStats() map[string]interface{} {
 	defer o.stats.mu.RUnlock()
 
 	poolStats := o.workerPool.GetStats()
+	running, limit := ZGrabProcesses()
 
 	return map[string]interface{}{
 		"total_grabs":    o.stats.TotalGrabs,
@@ -159,6 +208,10 @@ func (o *BannerGrabber) GetStats() map[string]interface{} {
 		"errors":         o.stats.Errors,
 		"error_rate":     float64(o.stats.Errors) / float64(o.stats.TotalGrabs) * 100,
 		"worker_pool":    poolStats,
+		"zgrab_processes": map[string]interface{}{
+			"running": running,
+			"limit":   limit,
+		},
 	}
 }
 
@@ -0,0 +1,57 @@
+package banner
+
+import "sync"
+
+// hostLimiter caps how many banner grabs may run against a single host at once, so a
+// slow host can't hold every slot of the shared banner pool while other hosts wait
+type hostLimiter struct {
+	limit int
+	mu    sync.Mutex
+	hosts map[string]*hostSlots
+}
+
+// hostSlots is the semaphore of one host, dropped once no grab is using or waiting on it
+type hostSlots struct {
+	sem   chan struct{}
+	users int
+}
+
+// newHostLimiter creates a per-host limiter; a limit of 0 or less returns nil, which never blocks
+func newHostLimiter(limit int) *hostLimiter {
+	if limit <= 0 {
+		return nil
+	}
+	return &hostLimiter{
+		limit: limit,
+		hosts: make(map[string]*hostSlots),
+	}
+}
+
+// acquire blocks until the host has a free slot and returns the function that releases it
+func (l *hostLimiter) acquire(ip string) func() {
+	if l == nil {
+		return func() {}
+	}
+
+	l.mu.Lock()
+	slots, ok := l.hosts[ip]
+	if !ok {
+		slots = &hostSlots{sem: make(chan struct{}, l.limit)}
+		l.hosts[ip] = slots
+	}
+	slots.users++
+	l.mu.Unlock()
+
+	slots.sem <- struct{}{}
+
+	return func() {
+		<-slots.sem
+
+		l.mu.Lock()
+		slots.users--
+		if slots.users == 0 {
+			delete(l.hosts, ip)
+		}
+		l.mu.Unlock()
+	}
+}
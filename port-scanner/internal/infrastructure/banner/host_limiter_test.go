@@ -0,0 +1,77 @@
+package banner
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// grab takes a host slot and then a slot of the shared pool, as GetBanner does, and holds
+// both for the given duration
+func grab(limiter *hostLimiter, pool chan struct{}, ip string, duration time.Duration, active *atomic.Int32, peak *atomic.Int32) {
+	release := limiter.acquire(ip)
+	defer release()
+
+	pool <- struct{}{}
+	defer func() { <-pool }()
+
+	if active != nil {
+		n := active.Add(1)
+		for {
+			max := peak.Load()
+			if n <= max || peak.CompareAndSwap(max, n) {
+				break
+			}
+		}
+		defer active.Add(-1)
+	}
+	time.Sleep(duration)
+}
+
+func TestHostLimiterKeepsSlowHostFromStarvingOthers(t *testing.T) {
+	limiter := newHostLimiter(1)
+	pool := make(chan struct{}, 2)
+
+	// Queue many slow grabs against one host first
+	var slow sync.WaitGroup
+	var active, peak atomic.Int32
+	for i := 0; i < 5; i++ {
+		slow.Add(1)
+		go func() {
+			defer slow.Done()
+			grab(limiter, pool, "198.51.100.1", 100*time.Millisecond, &active, &peak)
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	start := time.Now()
+	grab(limiter, pool, "8.8.8.8", time.Millisecond, nil, nil)
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("fast host waited %v behind the slow host's grabs", elapsed)
+	}
+
+	slow.Wait()
+	if peak.Load() != 1 {
+		t.Errorf("slow host ran %d grabs at once, want the cap of 1", peak.Load())
+	}
+	if len(limiter.hosts) != 0 {
+		t.Errorf("limiter still tracks %d hosts after all grabs finished", len(limiter.hosts))
+	}
+}
+
+func TestHostLimiterDisabled(t *testing.T) {
+	limiter := newHostLimiter(0)
+	if limiter != nil {
+		t.Fatalf("newHostLimiter(0) = %v, want nil", limiter)
+	}
+
+	// A nil limiter never blocks, however many grabs run against a host
+	releases := make([]func(), 0, 10)
+	for i := 0; i < 10; i++ {
+		releases = append(releases, limiter.acquire("8.8.8.8"))
+	}
+	for _, release := range releases {
+		release()
+	}
+}
@@ -0,0 +1,70 @@
+package banner
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"port-scanner/internal/domain"
+)
+
+func TestFallbackBannerGrabSendsConfiguredUserAgent(t *testing.T) {
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8080})
+	if err != nil {
+		t.Skipf("port 8080 unavailable: %v", err)
+	}
+	defer listener.Close()
+
+	headers := make(chan http.Header, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if req, err := http.ReadRequest(bufio.NewReader(conn)); err == nil {
+			headers <- req.Header
+			conn.Write([]byte("HTTP/1.0 200 OK\r\n\r\n"))
+		}
+	}()
+
+	z := NewZGrabBannerService(2 * time.Second)
+	z.SetHTTPProbe(domain.HTTPProbe{UserAgent: "orwell-test/1.0", Headers: map[string]string{"X-Scan-Contact": "security@example.com"}})
+	info, err := z.FallbackBannerGrab("127.0.0.1", 8080)
+	if err != nil {
+		t.Fatalf("FallbackBannerGrab returned error: %v", err)
+	}
+	if info.RawBanner != "HTTP/1.0 200 OK" {
+		t.Errorf("banner = %q, want the HTTP status line", info.RawBanner)
+	}
+
+	select {
+	case got := <-headers:
+		if got.Get("User-Agent") != "orwell-test/1.0" || got.Get("X-Scan-Contact") != "security@example.com" {
+			t.Errorf("request headers = %v, want the configured User-Agent and X-Scan-Contact", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no HTTP request received")
+	}
+}
+
+func TestHTTPArgsPassProbeToZGrab(t *testing.T) {
+	z := NewZGrabBannerService(time.Second)
+	if got, want := z.httpArgs(), []string{"--http-user-agent", domain.DefaultHTTPUserAgent}; !reflect.DeepEqual(got, want) {
+		t.Errorf("default httpArgs() = %v, want %v", got, want)
+	}
+
+	z.SetHTTPProbe(domain.HTTPProbe{UserAgent: "orwell-test/1.0", Headers: map[string]string{"x-b": "a, b", "accept": "*/*"}})
+	want := []string{
+		"--http-user-agent", "orwell-test/1.0",
+		"--http-custom-headers-names", "Accept" + zgrabHeaderDelimiter + "X-B",
+		"--http-custom-headers-values", "*/*" + zgrabHeaderDelimiter + "a, b",
+		"--http-custom-headers-delimiter", zgrabHeaderDelimiter,
+	}
+	if got := z.httpArgs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("httpArgs() = %q, want %q", got, want)
+	}
+}
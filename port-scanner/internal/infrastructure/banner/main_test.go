@@ -0,0 +1,13 @@
+package banner
+
+import (
+	"os"
+	"testing"
+
+	"port-scanner/pkg/log"
+)
+
+func TestMain(m *testing.M) {
+	log.InitLogger("banner-test")
+	os.Exit(m.Run())
+}
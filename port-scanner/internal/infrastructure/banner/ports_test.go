@@ -0,0 +1,39 @@
+package banner
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"port-scanner/internal/domain"
+)
+
+func TestModuleSelectionMatchesServiceIdentification(t *testing.T) {
+	z := NewZGrabBannerService(time.Second)
+
+	for port, info := range domain.KnownPorts {
+		if got := z.IdentifyServiceByPort(port); got != info.Service {
+			t.Errorf("IdentifyServiceByPort(%d) = %q, want the registry's %q", port, got, info.Service)
+		}
+
+		modules := z.selectModulesForPort(port)
+		if !reflect.DeepEqual(modules, domain.KnownPorts.Modules(port)) {
+			t.Errorf("selectModulesForPort(%d) = %v, want the registry's %v", port, modules, domain.KnownPorts.Modules(port))
+		}
+
+		// The port's own module identifies the same service the port does; generic protocol
+		// modules (http, tls, banner) name the protocol rather than the port's service
+		if len(info.Modules) == 0 || containsModule(info.Modules, "tls") || info.Modules[0] == "http" || info.Modules[0] == "banner" {
+			continue
+		}
+		data := map[string]interface{}{info.Modules[0]: map[string]interface{}{"status": "success"}}
+		if got := z.identifyService(port, data); got != info.Service {
+			t.Errorf("port %d: module %s identifies %q, want %q", port, info.Modules[0], got, info.Service)
+		}
+	}
+
+	// Ports with a module but no entry in the old scanner map, such as Redis, are identified too
+	if got := z.IdentifyServiceByPort(6379); got != "redis" {
+		t.Errorf("IdentifyServiceByPort(6379) = %q, want redis", got)
+	}
+}
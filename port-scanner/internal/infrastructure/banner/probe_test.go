@@ -0,0 +1,85 @@
+package banner
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"port-scanner/internal/domain"
+)
+
+// probeRecorder is a service that replies with a banner once it has read what the client sent
+type probeRecorder struct {
+	listener net.Listener
+	received chan []byte
+}
+
+func newProbeRecorder(t *testing.T) *probeRecorder {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen returned error: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	r := &probeRecorder{listener: listener, received: make(chan []byte, 1)}
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		buf := make([]byte, 1024)
+		n, _ := conn.Read(buf)
+		r.received <- buf[:n]
+		conn.Write([]byte("READY\r\n"))
+	}()
+	return r
+}
+
+func (r *probeRecorder) port() int {
+	return r.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (r *probeRecorder) probe(t *testing.T) []byte {
+	t.Helper()
+	select {
+	case payload := <-r.received:
+		return payload
+	case <-time.After(time.Second):
+		t.Fatal("service received no probe")
+		return nil
+	}
+}
+
+func TestFallbackBannerGrabSendsConfiguredProbe(t *testing.T) {
+	service := newProbeRecorder(t)
+	z := NewZGrabBannerService(time.Second)
+	z.SetProbePayloads(map[int][]byte{service.port(): []byte("stats\r\n")})
+
+	info, err := z.FallbackBannerGrab("127.0.0.1", service.port())
+	if err != nil {
+		t.Fatalf("FallbackBannerGrab returned error: %v", err)
+	}
+	if got := service.probe(t); !bytes.Equal(got, []byte("stats\r\n")) {
+		t.Errorf("service received %q, want the configured probe", got)
+	}
+	if info.RawBanner != "READY" {
+		t.Errorf("banner = %q, want READY", info.RawBanner)
+	}
+}
+
+func TestFallbackBannerGrabSendsDefaultProbeOtherwise(t *testing.T) {
+	service := newProbeRecorder(t)
+	z := NewZGrabBannerService(time.Second)
+	z.SetProbePayloads(map[int][]byte{service.port() + 1: []byte("stats\r\n")})
+
+	if _, err := z.FallbackBannerGrab("127.0.0.1", service.port()); err != nil {
+		t.Fatalf("FallbackBannerGrab returned error: %v", err)
+	}
+	if got := service.probe(t); string(got) != domain.DefaultBannerProbe {
+		t.Errorf("service received %q, want the default probe", got)
+	}
+}
@@ -0,0 +1,147 @@
+package banner
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"port-scanner/internal/domain"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+const (
+	oidSysDescr = "1.3.6.1.2.1.1.1.0"
+	oidSysName  = "1.3.6.1.2.1.1.5.0"
+)
+
+// SNMPProbe queries SNMP agents for system identification using common community strings
+type SNMPProbe struct {
+	communities []string
+	version     gosnmp.SnmpVersion
+	timeout     time.Duration
+	parser      *ZGrabBannerService
+}
+
+// Ensure SNMPProbe implements SNMPProber interface
+var _ domain.SNMPProber = (*SNMPProbe)(nil)
+
+// NewSNMPProbe creates a new SNMP probe for the given communities and version ("1" or "2c")
+func NewSNMPProbe(communities []string, version string, timeout time.Duration) (*SNMPProbe, error) {
+	if len(communities) == 0 {
+		return nil, fmt.Errorf("at least one SNMP community is required")
+	}
+
+	snmpVersion, err := parseSNMPVersion(version)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SNMPProbe{
+		communities: communities,
+		version:     snmpVersion,
+		timeout:     timeout,
+		parser:      NewZGrabBannerService(timeout),
+	}, nil
+}
+
+// parseSNMPVersion converts a configured version string to a gosnmp version
+func parseSNMPVersion(version string) (gosnmp.SnmpVersion, error) {
+	switch strings.ToLower(version) {
+	case "1", "v1":
+		return gosnmp.Version1, nil
+	case "", "2c", "v2c":
+		return gosnmp.Version2c, nil
+	default:
+		return 0, fmt.Errorf("unsupported SNMP version: %s", version)
+	}
+}
+
+// ProbeSNMP tries each community string in order and returns the first agent response
+func (p *SNMPProbe) ProbeSNMP(ip string, port int) (*domain.BannerInfo, error) {
+	var lastErr error
+	for _, community := range p.communities {
+		bannerInfo, err := p.probeCommunity(ip, port, community)
+		if err == nil {
+			return bannerInfo, nil
+		}
+		lastErr = err
+	}
+
+	return nil, fmt.Errorf("no SNMP response from %s:%d: %w", ip, port, lastErr)
+}
+
+// probeCommunity issues a GET for sysDescr and sysName using a single community string
+func (p *SNMPProbe) probeCommunity(ip string, port int, community string) (*domain.BannerInfo, error) {
+	client := &gosnmp.GoSNMP{
+		Target:    ip,
+		Port:      uint16(port),
+		Transport: "udp",
+		Community: community,
+		Version:   p.version,
+		Timeout:   p.timeout,
+		Retries:   0,
+	}
+
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("snmp connect failed: %w", err)
+	}
+	defer client.Conn.Close()
+
+	packet, err := client.Get([]string{oidSysDescr, oidSysName})
+	if err != nil {
+		// gosnmp reports an unanswered request only through the error text
+		if strings.Contains(err.Error(), "timeout") {
+			return nil, fmt.Errorf("snmp get failed: %v: %w", err, domain.ErrNoResponse)
+		}
+		return nil, fmt.Errorf("snmp get failed: %w", err)
+	}
+	if packet.Error != gosnmp.NoError {
+		return nil, fmt.Errorf("snmp agent returned error: %s", packet.Error)
+	}
+
+	var sysDescr, sysName string
+	for _, variable := range packet.Variables {
+		value := snmpValueToString(variable)
+		switch strings.TrimPrefix(variable.Name, ".") {
+		case oidSysDescr:
+			sysDescr = value
+		case oidSysName:
+			sysName = value
+		}
+	}
+
+	if sysDescr == "" && sysName == "" {
+		return nil, fmt.Errorf("snmp agent returned no system information")
+	}
+
+	return &domain.BannerInfo{
+		RawBanner:  sysDescr,
+		Service:    "snmp",
+		Protocol:   "udp",
+		Version:    p.parser.extractVersionFromBanner(sysDescr),
+		Confidence: "banner",
+		Metadata: map[string]interface{}{
+			"snmp": map[string]interface{}{
+				"community": community,
+				"version":   p.version.String(),
+				"sys_descr": sysDescr,
+				"sys_name":  sysName,
+			},
+		},
+	}, nil
+}
+
+// snmpValueToString renders an SNMP variable value as a string
+func snmpValueToString(variable gosnmp.SnmpPDU) string {
+	switch value := variable.Value.(type) {
+	case []byte:
+		return strings.TrimSpace(string(value))
+	case string:
+		return strings.TrimSpace(value)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
@@ -0,0 +1,131 @@
+package banner
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"port-scanner/internal/domain"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// snmpAgent is a local SNMP agent simulator answering GET requests for sysDescr and sysName
+// sent with its community; requests with other communities go unanswered, like a real agent
+type snmpAgent struct {
+	conn      *net.UDPConn
+	community string
+	sysDescr  string
+	sysName   string
+}
+
+// startSNMPAgent starts an agent on a loopback UDP port, stopped when the test ends
+func startSNMPAgent(t *testing.T, community, sysDescr, sysName string) (*snmpAgent, int) {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	agent := &snmpAgent{conn: conn, community: community, sysDescr: sysDescr, sysName: sysName}
+	go agent.serve()
+	return agent, conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+func (a *snmpAgent) serve() {
+	decoder := *gosnmp.Default
+	buf := make([]byte, 4096)
+	for {
+		n, addr, err := a.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		request, err := decoder.SnmpDecodePacket(buf[:n])
+		if err != nil || request.Community != a.community || request.PDUType != gosnmp.GetRequest {
+			continue
+		}
+
+		response := *request
+		response.PDUType = gosnmp.GetResponse
+		response.Variables = nil
+		for _, variable := range request.Variables {
+			value := a.sysName
+			if variable.Name == "."+oidSysDescr || variable.Name == oidSysDescr {
+				value = a.sysDescr
+			}
+			response.Variables = append(response.Variables, gosnmp.SnmpPDU{Name: variable.Name, Type: gosnmp.OctetString, Value: value})
+		}
+		body, err := response.MarshalMsg()
+		if err != nil {
+			continue
+		}
+		a.conn.WriteToUDP(body, addr)
+	}
+}
+
+func TestSNMPProbeReadsSystemInfo(t *testing.T) {
+	_, port := startSNMPAgent(t, "private", "Linux router 5.10.0 OpenSSH_8.4", "edge-router-1")
+
+	probe, err := NewSNMPProbe([]string{"public", "private"}, "2c", 200*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewSNMPProbe returned error: %v", err)
+	}
+	info, err := probe.ProbeSNMP("127.0.0.1", port)
+	if err != nil {
+		t.Fatalf("ProbeSNMP returned error: %v", err)
+	}
+
+	if info.Service != "snmp" || info.Protocol != "udp" {
+		t.Errorf("service/protocol = %s/%s, want snmp/udp", info.Service, info.Protocol)
+	}
+	if info.RawBanner != "Linux router 5.10.0 OpenSSH_8.4" {
+		t.Errorf("raw banner = %q, want the sysDescr", info.RawBanner)
+	}
+	snmp, _ := info.Metadata["snmp"].(map[string]interface{})
+	if snmp["community"] != "private" {
+		t.Errorf("community = %v, want the second community to answer", snmp["community"])
+	}
+	if snmp["sys_name"] != "edge-router-1" || snmp["version"] != gosnmp.Version2c.String() {
+		t.Errorf("snmp metadata = %v", snmp)
+	}
+}
+
+func TestSNMPProbeSupportsVersion1(t *testing.T) {
+	_, port := startSNMPAgent(t, "public", "Cisco IOS Software", "core-switch")
+
+	probe, err := NewSNMPProbe([]string{"public"}, "1", 200*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewSNMPProbe returned error: %v", err)
+	}
+	info, err := probe.ProbeSNMP("127.0.0.1", port)
+	if err != nil {
+		t.Fatalf("ProbeSNMP returned error: %v", err)
+	}
+	if snmp, _ := info.Metadata["snmp"].(map[string]interface{}); snmp["version"] != gosnmp.Version1.String() {
+		t.Errorf("version = %v, want %s", snmp["version"], gosnmp.Version1)
+	}
+}
+
+func TestSNMPProbeReportsSilentAgents(t *testing.T) {
+	_, port := startSNMPAgent(t, "secret", "unreachable", "hidden")
+
+	probe, err := NewSNMPProbe([]string{"public", "private"}, "2c", 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewSNMPProbe returned error: %v", err)
+	}
+	_, err = probe.ProbeSNMP("127.0.0.1", port)
+	if !errors.Is(err, domain.ErrNoResponse) {
+		t.Errorf("ProbeSNMP error = %v, want ErrNoResponse", err)
+	}
+}
+
+func TestNewSNMPProbeValidatesSettings(t *testing.T) {
+	if _, err := NewSNMPProbe(nil, "2c", time.Second); err == nil {
+		t.Error("NewSNMPProbe accepted an empty community list")
+	}
+	if _, err := NewSNMPProbe([]string{"public"}, "3", time.Second); err == nil {
+		t.Error("NewSNMPProbe accepted an unsupported version")
+	}
+}
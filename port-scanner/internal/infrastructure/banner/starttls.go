@@ -0,0 +1,266 @@
+package banner
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"port-scanner/internal/domain"
+)
+
+// maxStartTLSLines bounds the reply lines read from a service, so a chatty or hostile
+// server can't keep the probe reading
+const maxStartTLSLines = 64
+
+// StartTLSProbe asks SMTP, POP3 and IMAP services for their capabilities and, when STARTTLS is
+// advertised, upgrades the connection to read the server certificate
+type StartTLSProbe struct {
+	timeout time.Duration
+}
+
+// Ensure StartTLSProbe implements StartTLSProber interface
+var _ domain.StartTLSProber = (*StartTLSProbe)(nil)
+
+// NewStartTLSProbe creates a probe whose whole exchange, handshake included, is bounded by timeout
+func NewStartTLSProbe(timeout time.Duration) *StartTLSProbe {
+	return &StartTLSProbe{timeout: timeout}
+}
+
+// ProbeStartTLS implements StartTLSProber. A service that doesn't advertise STARTTLS gets
+// "starttls": false; a refused upgrade or failed handshake is recorded as "starttls_error"
+// rather than returned.
+func (p *StartTLSProbe) ProbeStartTLS(ip string, port int, protocol string) (map[string]interface{}, error) {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(ip, strconv.Itoa(port)), p.timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(p.timeout))
+
+	session := &startTLSSession{conn: conn, reader: bufio.NewReader(conn)}
+	var offered bool
+	switch protocol {
+	case domain.StartTLSSMTP:
+		offered, err = session.smtp()
+	case domain.StartTLSPOP3:
+		offered, err = session.pop3()
+	case domain.StartTLSIMAP:
+		offered, err = session.imap()
+	default:
+		return nil, fmt.Errorf("unsupported STARTTLS protocol %q", protocol)
+	}
+	if err != nil && !offered {
+		return nil, fmt.Errorf("%s STARTTLS negotiation with %s:%d failed: %w", protocol, ip, port, err)
+	}
+
+	metadata := map[string]interface{}{"starttls": offered}
+	if !offered {
+		return metadata, nil
+	}
+	if err != nil {
+		metadata["starttls_error"] = fmt.Sprintf("upgrade refused: %v", err)
+		return metadata, nil
+	}
+
+	// Only the certificate is of interest, so any certificate is accepted
+	tlsConn := tls.Client(conn, &tls.Config{InsecureSkipVerify: true})
+	if err := tlsConn.Handshake(); err != nil {
+		metadata["starttls_error"] = err.Error()
+		return metadata, nil
+	}
+	addTLSMetadata(metadata, tlsConn.ConnectionState())
+	return metadata, nil
+}
+
+// addTLSMetadata records the negotiated session and the leaf certificate
+func addTLSMetadata(metadata map[string]interface{}, state tls.ConnectionState) {
+	metadata["tls_version"] = tls.VersionName(state.Version)
+	metadata["tls_cipher"] = tls.CipherSuiteName(state.CipherSuite)
+	if len(state.PeerCertificates) == 0 {
+		return
+	}
+
+	cert := state.PeerCertificates[0]
+	fingerprint := sha256.Sum256(cert.Raw)
+	metadata["tls_cert_subject"] = cert.Subject.String()
+	metadata["tls_cert_issuer"] = cert.Issuer.String()
+	metadata["tls_cert_not_before"] = cert.NotBefore.UTC().Format(time.RFC3339)
+	metadata["tls_cert_not_after"] = cert.NotAfter.UTC().Format(time.RFC3339)
+	metadata["tls_cert_sha256"] = hex.EncodeToString(fingerprint[:])
+	if len(cert.DNSNames) > 0 {
+		metadata["tls_cert_dns_names"] = cert.DNSNames
+	}
+}
+
+// startTLSSession is the plaintext exchange before the upgrade
+type startTLSSession struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// smtp reads the greeting, sends EHLO and, when STARTTLS is among the extensions, asks for it.
+// Like pop3 and imap it reports whether STARTTLS was offered, and an error if the upgrade was
+// refused.
+func (s *startTLSSession) smtp() (bool, error) {
+	if _, err := s.smtpReply("220"); err != nil {
+		return false, err
+	}
+	if err := s.send("EHLO scanner.invalid"); err != nil {
+		return false, err
+	}
+	extensions, err := s.smtpReply("250")
+	if err != nil {
+		return false, err
+	}
+
+	offered := false
+	for _, line := range extensions {
+		if len(line) > 4 && strings.EqualFold(strings.TrimSpace(line[4:]), "STARTTLS") {
+			offered = true
+		}
+	}
+	if !offered {
+		return false, nil
+	}
+
+	if err := s.send("STARTTLS"); err != nil {
+		return false, err
+	}
+	_, err = s.smtpReply("220")
+	return true, err
+}
+
+// smtpReply reads a possibly multi-line SMTP reply ("250-..." lines ending with "250 ...")
+// and checks its code
+func (s *startTLSSession) smtpReply(code string) ([]string, error) {
+	var lines []string
+	for len(lines) < maxStartTLSLines {
+		line, err := s.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(line, code) {
+			return nil, fmt.Errorf("unexpected reply %q", line)
+		}
+		lines = append(lines, line)
+		if len(line) == len(code) || line[len(code)] != '-' {
+			return lines, nil
+		}
+	}
+	return nil, fmt.Errorf("reply longer than %d lines", maxStartTLSLines)
+}
+
+// pop3 reads the greeting, lists capabilities with CAPA and, when STLS is listed, asks for it
+func (s *startTLSSession) pop3() (bool, error) {
+	if err := s.expectPrefix("+OK"); err != nil {
+		return false, err
+	}
+	if err := s.send("CAPA"); err != nil {
+		return false, err
+	}
+	if err := s.expectPrefix("+OK"); err != nil {
+		// Servers without CAPA can't advertise STLS
+		return false, nil
+	}
+
+	offered := false
+	for lines := 0; ; lines++ {
+		if lines == maxStartTLSLines {
+			return false, fmt.Errorf("capability list longer than %d lines", maxStartTLSLines)
+		}
+		line, err := s.readLine()
+		if err != nil {
+			return false, err
+		}
+		if line == "." {
+			break
+		}
+		if strings.EqualFold(strings.TrimSpace(line), "STLS") {
+			offered = true
+		}
+	}
+	if !offered {
+		return false, nil
+	}
+
+	if err := s.send("STLS"); err != nil {
+		return false, err
+	}
+	return true, s.expectPrefix("+OK")
+}
+
+// imap reads the greeting, asks for the CAPABILITY list and, when STARTTLS is listed, asks for it
+func (s *startTLSSession) imap() (bool, error) {
+	if err := s.expectPrefix("* OK"); err != nil {
+		return false, err
+	}
+	if err := s.send("a1 CAPABILITY"); err != nil {
+		return false, err
+	}
+
+	offered := false
+	for lines := 0; ; lines++ {
+		if lines == maxStartTLSLines {
+			return false, fmt.Errorf("capability reply longer than %d lines", maxStartTLSLines)
+		}
+		line, err := s.readLine()
+		if err != nil {
+			return false, err
+		}
+		if strings.HasPrefix(line, "a1 ") {
+			if !strings.HasPrefix(line, "a1 OK") {
+				return false, fmt.Errorf("unexpected reply %q", line)
+			}
+			break
+		}
+		if strings.HasPrefix(strings.ToUpper(line), "* CAPABILITY ") {
+			for _, capability := range strings.Fields(line)[2:] {
+				if strings.EqualFold(capability, "STARTTLS") {
+					offered = true
+				}
+			}
+		}
+	}
+	if !offered {
+		return false, nil
+	}
+
+	if err := s.send("a2 STARTTLS"); err != nil {
+		return false, err
+	}
+	return true, s.expectPrefix("a2 OK")
+}
+
+// expectPrefix reads a line and checks how it starts
+func (s *startTLSSession) expectPrefix(prefix string) error {
+	line, err := s.readLine()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, prefix) {
+		return fmt.Errorf("unexpected reply %q", line)
+	}
+	return nil
+}
+
+// send writes a command terminated by CRLF
+func (s *startTLSSession) send(command string) error {
+	_, err := s.conn.Write([]byte(command + "\r\n"))
+	return err
+}
+
+// readLine reads a reply line without its line ending; lines longer than the reader's
+// buffer fail with bufio.ErrBufferFull
+func (s *startTLSSession) readLine() (string, error) {
+	line, err := s.reader.ReadSlice('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
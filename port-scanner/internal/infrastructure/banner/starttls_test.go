@@ -0,0 +1,164 @@
+package banner
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"port-scanner/internal/domain"
+)
+
+// selfSignedCert returns a certificate for mail.example.com
+func selfSignedCert(t *testing.T) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "mail.example.com"},
+		DNSNames:     []string{"mail.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// mailServer serves one scripted plaintext exchange per connection: replies maps each
+// command to the lines sent back, and a reply to upgrade completes a TLS handshake after it
+func mailServer(t *testing.T, greeting string, replies map[string][]string, upgrade string) int {
+	t.Helper()
+	cert := selfSignedCert(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.SetDeadline(time.Now().Add(5 * time.Second))
+				reader := bufio.NewReader(conn)
+				conn.Write([]byte(greeting + "\r\n"))
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					command := strings.TrimRight(line, "\r\n")
+					for _, reply := range replies[command] {
+						conn.Write([]byte(reply + "\r\n"))
+					}
+					if command == upgrade {
+						tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{cert}}).Handshake()
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestStartTLSProbeUpgradesSMTP(t *testing.T) {
+	port := mailServer(t, "220 mail.example.com ESMTP", map[string][]string{
+		"EHLO scanner.invalid": {"250-mail.example.com", "250-PIPELINING", "250-STARTTLS", "250 8BITMIME"},
+		"STARTTLS":             {"220 Ready to start TLS"},
+	}, "STARTTLS")
+
+	metadata, err := NewStartTLSProbe(2*time.Second).ProbeStartTLS("127.0.0.1", port, domain.StartTLSSMTP)
+	if err != nil {
+		t.Fatalf("ProbeStartTLS returned error: %v", err)
+	}
+	if metadata["starttls"] != true || metadata["starttls_error"] != nil {
+		t.Fatalf("metadata = %v, want a successful STARTTLS upgrade", metadata)
+	}
+	if metadata["tls_cert_subject"] != "CN=mail.example.com" {
+		t.Errorf("tls_cert_subject = %v, want CN=mail.example.com", metadata["tls_cert_subject"])
+	}
+	if names, _ := metadata["tls_cert_dns_names"].([]string); len(names) != 1 || names[0] != "mail.example.com" {
+		t.Errorf("tls_cert_dns_names = %v, want [mail.example.com]", metadata["tls_cert_dns_names"])
+	}
+	if fingerprint, _ := metadata["tls_cert_sha256"].(string); len(fingerprint) != 64 {
+		t.Errorf("tls_cert_sha256 = %v, want a hex SHA-256", metadata["tls_cert_sha256"])
+	}
+	if metadata["tls_version"] == nil || metadata["tls_cipher"] == nil {
+		t.Errorf("metadata = %v, want the negotiated version and cipher", metadata)
+	}
+}
+
+func TestStartTLSProbeReportsSMTPWithoutStartTLS(t *testing.T) {
+	port := mailServer(t, "220 mail.example.com ESMTP", map[string][]string{
+		"EHLO scanner.invalid": {"250-mail.example.com", "250 8BITMIME"},
+	}, "")
+
+	metadata, err := NewStartTLSProbe(2*time.Second).ProbeStartTLS("127.0.0.1", port, domain.StartTLSSMTP)
+	if err != nil {
+		t.Fatalf("ProbeStartTLS returned error: %v", err)
+	}
+	if metadata["starttls"] != false || len(metadata) != 1 {
+		t.Errorf("metadata = %v, want only starttls false", metadata)
+	}
+}
+
+func TestStartTLSProbeRecordsRefusedUpgrade(t *testing.T) {
+	port := mailServer(t, "220 mail.example.com ESMTP", map[string][]string{
+		"EHLO scanner.invalid": {"250-mail.example.com", "250 STARTTLS"},
+		"STARTTLS":             {"454 TLS not available due to temporary reason"},
+	}, "")
+
+	metadata, err := NewStartTLSProbe(2*time.Second).ProbeStartTLS("127.0.0.1", port, domain.StartTLSSMTP)
+	if err != nil {
+		t.Fatalf("ProbeStartTLS returned error: %v", err)
+	}
+	if metadata["starttls"] != true || metadata["starttls_error"] == nil || metadata["tls_cert_subject"] != nil {
+		t.Errorf("metadata = %v, want STARTTLS offered with the refusal recorded", metadata)
+	}
+}
+
+func TestStartTLSProbeUpgradesPOP3AndIMAP(t *testing.T) {
+	pop3 := mailServer(t, "+OK POP3 ready", map[string][]string{
+		"CAPA": {"+OK Capability list follows", "USER", "STLS", "."},
+		"STLS": {"+OK Begin TLS negotiation"},
+	}, "STLS")
+	imap := mailServer(t, "* OK IMAP4rev1 ready", map[string][]string{
+		"a1 CAPABILITY": {"* CAPABILITY IMAP4rev1 STARTTLS LOGINDISABLED", "a1 OK CAPABILITY completed"},
+		"a2 STARTTLS":   {"a2 OK Begin TLS negotiation now"},
+	}, "a2 STARTTLS")
+
+	probe := NewStartTLSProbe(2 * time.Second)
+	for protocol, port := range map[string]int{domain.StartTLSPOP3: pop3, domain.StartTLSIMAP: imap} {
+		metadata, err := probe.ProbeStartTLS("127.0.0.1", port, protocol)
+		if err != nil {
+			t.Errorf("ProbeStartTLS(%s) returned error: %v", protocol, err)
+			continue
+		}
+		if metadata["starttls"] != true || metadata["tls_cert_subject"] != "CN=mail.example.com" {
+			t.Errorf("%s metadata = %v, want the upgraded certificate", protocol, metadata)
+		}
+	}
+
+	if _, err := probe.ProbeStartTLS("127.0.0.1", pop3, "ftp"); err == nil {
+		t.Error("ProbeStartTLS accepted an unsupported protocol")
+	}
+}
@@ -0,0 +1,58 @@
+package banner
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAnalyzeZGrabResultTruncatesDumpedData(t *testing.T) {
+	z := NewZGrabBannerService(time.Second)
+	z.SetMaxBannerLength(256)
+
+	// Without a known banner field, the whole data map is dumped as the banner
+	result := BannerResult{IP: "203.0.113.7", Data: map[string]interface{}{
+		"verbose": map[string]interface{}{"status": "success", "result": strings.Repeat("payload ", 1000)},
+	}}
+	info := z.analyzeZGrabResult(result, 9999)
+
+	if len(info.RawBanner) > 256+len("...[truncated 100000 bytes]") {
+		t.Errorf("raw banner is %d bytes, want it cut near 256", len(info.RawBanner))
+	}
+	if !strings.Contains(info.RawBanner, "...[truncated ") {
+		t.Errorf("raw banner %q has no truncation marker", info.RawBanner)
+	}
+	if info.Metadata["banner_truncated"] != true {
+		t.Errorf("banner_truncated = %v, want true", info.Metadata["banner_truncated"])
+	}
+}
+
+func TestFallbackBannerGrabTruncatesLongBanners(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen returned error: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte(strings.Repeat("B", 2000) + "\r\n"))
+	}()
+
+	z := NewZGrabBannerService(time.Second)
+	z.SetMaxBannerLength(100)
+	info, err := z.FallbackBannerGrab("127.0.0.1", listener.Addr().(*net.TCPAddr).Port)
+	if err != nil {
+		t.Fatalf("FallbackBannerGrab returned error: %v", err)
+	}
+	if want := strings.Repeat("B", 100) + "...[truncated 1900 bytes]"; info.RawBanner != want {
+		t.Errorf("raw banner = %q, want %q", info.RawBanner, want)
+	}
+	if info.Metadata["banner_original_length"] != 2000 {
+		t.Errorf("banner_original_length = %v, want 2000", info.Metadata["banner_original_length"])
+	}
+}
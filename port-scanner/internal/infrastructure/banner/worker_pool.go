@@ -110,13 +110,33 @@ func (p *ZGrabWorkerPool) SubmitJob(ip string, port int, priority int) (*BannerG
 	select {
 	case result := <-job.Result:
 		return result, nil
-	case <-time.After(p.timeout):
+	case <-time.After(p.zgrabService.CommandTimeout(port)):
 		return nil, fmt.Errorf("banner grab timeout for %s:%d", ip, port)
 	case <-p.ctx.Done():
 		return nil, fmt.Errorf("worker pool shutdown for %s:%d", ip, port)
 	}
 }
 
+// SetModuleSettings sets per-module ZGrab2 overrides for all workers
+func (p *ZGrabWorkerPool) SetModuleSettings(settings map[string]domain.ZGrabModuleSettings) {
+	p.zgrabService.SetModuleSettings(settings)
+}
+
+// SetBinaryPath sets the ZGrab2 executable used by all workers
+func (p *ZGrabWorkerPool) SetBinaryPath(path string) {
+	p.zgrabService.SetBinaryPath(path)
+}
+
+// SetHTTPProbe sets the User-Agent and headers all workers send to HTTP services
+func (p *ZGrabWorkerPool) SetHTTPProbe(probe domain.HTTPProbe) {
+	p.zgrabService.SetHTTPProbe(probe)
+}
+
+// SetMaxBannerLength sets the raw banner size limit used by all workers
+func (p *ZGrabWorkerPool) SetMaxBannerLength(maxLength int) {
+	p.zgrabService.SetMaxBannerLength(maxLength)
+}
+
 // Shutdown gracefully shuts down the worker pool
 func (p *ZGrabWorkerPool) Shutdown() {
 	p.cancel()
@@ -6,19 +6,46 @@ import (
 	"encoding/json"
 	"fmt"
 	"net"
+	"net/textproto"
 	"os/exec"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"port-scanner/internal/domain"
+	"port-scanner/pkg/log"
+
+	"go.uber.org/zap"
 )
 
 // ZGrabBannerService provides banner grabbing using ZGrab2
 type ZGrabBannerService struct {
-	timeout time.Duration
+	timeout        time.Duration
+	moduleSettings map[string]domain.ZGrabModuleSettings
+	binaryPath     string
+
+	connectTimeout time.Duration // Fallback grab dial limit
+	readTimeout    time.Duration // Fallback grab read limit, counted from the connect
+
+	maxBannerLength int
+	probes          map[int][]byte
+	httpProbe       domain.HTTPProbe
+	hostLimiter     *hostLimiter
+	conns           *domain.ConnCache
 }
 
+// zgrabWaitDelay bounds how long a killed ZGrab2 run is waited on for its output to close,
+// which a wrapper script's children can otherwise hold open long after the deadline
+const zgrabWaitDelay = 500 * time.Millisecond
+
+// zgrabHeaderDelimiter separates custom header names and values passed to ZGrab2; it can't
+// appear in a header, unlike the default comma
+const zgrabHeaderDelimiter = "\x1f"
+
+// defaultZGrabBinary is the ZGrab2 executable looked up on PATH when no path is configured
+const defaultZGrabBinary = "zgrab2"
+
 // Ensure ZGrabBannerService implements BannerGrabber interface
 var _ domain.BannerGrabber = (*ZGrabBannerService)(nil)
 
@@ -39,14 +66,136 @@ type ModuleConfig struct {
 // NewZGrabBannerService creates a new ZGrab2 banner service
 func NewZGrabBannerService(timeout time.Duration) *ZGrabBannerService {
 	return &ZGrabBannerService{
-		timeout: timeout,
+		timeout:        timeout,
+		moduleSettings: make(map[string]domain.ZGrabModuleSettings),
+		binaryPath:     defaultZGrabBinary,
+		connectTimeout: timeout,
+		readTimeout:    timeout,
+
+		maxBannerLength: domain.DefaultMaxBannerLength,
+		httpProbe:       domain.HTTPProbe{UserAgent: domain.DefaultHTTPUserAgent},
+	}
+}
+
+// SetBinaryPath sets the ZGrab2 executable used for banner grabbing
+func (z *ZGrabBannerService) SetBinaryPath(path string) {
+	if path == "" {
+		path = defaultZGrabBinary
+	}
+	z.binaryPath = path
+}
+
+// SetMaxBannerLength sets the size above which raw banners are truncated; 0 disables truncation
+func (z *ZGrabBannerService) SetMaxBannerLength(maxLength int) {
+	if maxLength >= 0 {
+		z.maxBannerLength = maxLength
+	}
+}
+
+// SetFallbackTimeouts sets the connect and read timeouts of the fallback banner grab; a zero
+// value keeps the banner timeout
+func (z *ZGrabBannerService) SetFallbackTimeouts(connect, read time.Duration) {
+	if connect > 0 {
+		z.connectTimeout = connect
+	}
+	if read > 0 {
+		z.readTimeout = read
+	}
+}
+
+// SetConnCache sets the cache of port scan connections the fallback grab reuses; nil always dials
+func (z *ZGrabBannerService) SetConnCache(cache *domain.ConnCache) {
+	z.conns = cache
+}
+
+// SetProbePayloads sets the per-port payloads sent by the fallback banner grab
+func (z *ZGrabBannerService) SetProbePayloads(probes map[int][]byte) {
+	z.probes = make(map[int][]byte, len(probes))
+	for port, payload := range probes {
+		z.probes[port] = payload
+	}
+}
+
+// SetHTTPProbe sets the User-Agent and headers sent to HTTP services, by the fallback grab on
+// plaintext HTTP ports and by ZGrab2's http module
+func (z *ZGrabBannerService) SetHTTPProbe(probe domain.HTTPProbe) {
+	z.httpProbe = probe
+}
+
+// probePayload returns the payload sent to elicit a banner from a port: its configured probe,
+// an HTTP request on plaintext HTTP ports, or the default probe
+func (z *ZGrabBannerService) probePayload(ip string, port int) []byte {
+	if payload, ok := z.probes[port]; ok {
+		return payload
+	}
+	if domain.KnownPorts.PlainHTTP(port) {
+		return z.httpProbe.Request(ip)
+	}
+	return []byte(domain.DefaultBannerProbe)
+}
+
+// SetHostConcurrency caps concurrent banner grabs against a single host; 0 disables the cap.
+// It must be called before grabbing starts.
+func (z *ZGrabBannerService) SetHostConcurrency(limit int) {
+	z.hostLimiter = newHostLimiter(limit)
+}
+
+// ResolveZGrabBinary validates that a ZGrab2 executable exists and returns its
+// absolute path and reported version ("unknown" if it cannot be determined)
+func ResolveZGrabBinary(path string) (string, string, error) {
+	if path == "" {
+		path = defaultZGrabBinary
+	}
+
+	resolved, err := exec.LookPath(path)
+	if err != nil {
+		return "", "", fmt.Errorf("zgrab2 binary not found at %q: %w", path, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, resolved, "--version").CombinedOutput()
+	version := strings.TrimSpace(strings.SplitN(string(output), "\n", 2)[0])
+	if err != nil || version == "" {
+		version = "unknown"
+	}
+
+	return resolved, version, nil
+}
+
+// SetModuleSettings sets per-module timeout and retry overrides
+func (z *ZGrabBannerService) SetModuleSettings(settings map[string]domain.ZGrabModuleSettings) {
+	z.moduleSettings = make(map[string]domain.ZGrabModuleSettings, len(settings))
+	for module, moduleSettings := range settings {
+		z.moduleSettings[module] = moduleSettings
+	}
+}
+
+// CommandTimeout returns the overall deadline for a ZGrab2 run against a port,
+// long enough to cover the slowest selected module including its retries
+func (z *ZGrabBannerService) CommandTimeout(port int) time.Duration {
+	timeout := z.timeout
+	for _, module := range z.selectModulesForPort(port) {
+		settings, ok := z.moduleSettings[module]
+		if !ok || settings.Timeout <= 0 {
+			continue
+		}
+		moduleTimeout := settings.Timeout * time.Duration(settings.Retries+1)
+		if moduleTimeout > timeout {
+			timeout = moduleTimeout
+		}
 	}
+	return timeout
 }
 
 // GetBanner retrieves comprehensive banner information using ZGrab2
 func (z *ZGrabBannerService) GetBanner(ip string, port int) (*domain.BannerInfo, error) {
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), z.timeout)
+	release := z.hostLimiter.acquire(ip)
+	defer release()
+
+	// Create context with timeout covering the slowest module
+	ctx, cancel := context.WithTimeout(context.Background(), z.CommandTimeout(port))
 	defer cancel()
 
 	// Select appropriate modules based on port
@@ -56,72 +205,31 @@ func (z *ZGrabBannerService) GetBanner(ip string, port int) (*domain.BannerInfo,
 		return z.FallbackBannerGrab(ip, port)
 	}
 
+	// ZGrab2 opens its own connections, so release the one left by the port scan
+	z.conns.Discard(net.JoinHostPort(ip, strconv.Itoa(port)))
+
 	// Build ZGrab2 command with selected modules
 	cmd := z.buildZGrabCommand(ctx, ip, port, modules)
 
 	// Execute command with proper error handling
-	output, err := z.executeZGrabCommand(cmd)
+	output, err := z.executeZGrabCommand(ctx, cmd)
 	if err != nil {
-		// Log the error and fall back to basic banner grabbing
+		// A run killed at its deadline may have written complete results before being cut off
+		if best := z.selectZGrabResult(output, ip, port); best != nil {
+			log.L().Debug("Using ZGrab2 output written before the run failed", zap.String("event", "zgrab_partial_output"),
+				zap.String("ip", ip), zap.Int("port", port), zap.Error(err))
+			return best, nil
+		}
 		return z.FallbackBannerGrab(ip, port)
 	}
 
 	// Parse ZGrab2 output with proper result selection
-	return z.parseZGrabOutput(output, port)
+	return z.parseZGrabOutput(output, ip, port)
 }
 
-// selectModulesForPort selects appropriate ZGrab2 modules based on port number
+// selectModulesForPort selects appropriate ZGrab2 modules from the port registry
 func (z *ZGrabBannerService) selectModulesForPort(port int) []string {
-	// Define port-to-module mappings with priorities
-	portModules := map[int][]string{
-		21:    {"ftp", "banner"},
-		22:    {"ssh", "banner"},
-		23:    {"telnet", "banner"},
-		25:    {"smtp", "banner"},
-		53:    {"banner"}, // DNS doesn't have a specific ZGrab2 module
-		80:    {"http", "banner"},
-		110:   {"pop3", "banner"},
-		143:   {"imap", "banner"},
-		443:   {"http", "tls", "banner"},
-		993:   {"imap", "tls", "banner"},
-		995:   {"pop3", "tls", "banner"},
-		3306:  {"mysql", "banner"},
-		3389:  {"banner"}, // RDP doesn't have a specific ZGrab2 module
-		5432:  {"postgres", "banner"},
-		6379:  {"redis", "banner"},
-		27017: {"mongodb", "banner"},
-		27018: {"mongodb", "banner"},
-		27019: {"mongodb", "banner"},
-		27020: {"mongodb", "banner"},
-		1521:  {"oracle", "banner"},        // Oracle Database
-		1526:  {"oracle", "banner"},        // Oracle Database (alternative)
-		1433:  {"mssql", "banner"},         // Microsoft SQL Server
-		1434:  {"mssql", "banner"},         // Microsoft SQL Server Browser
-		5433:  {"postgres", "banner"},      // PostgreSQL (alternative)
-		5434:  {"postgres", "banner"},      // PostgreSQL (alternative)
-		5435:  {"postgres", "banner"},      // PostgreSQL (alternative)
-		3307:  {"mysql", "banner"},         // MySQL (alternative)
-		3308:  {"mysql", "banner"},         // MySQL (alternative)
-		3309:  {"mysql", "banner"},         // MySQL (alternative)
-		6378:  {"redis", "banner"},         // Redis (alternative)
-		6380:  {"redis", "banner"},         // Redis (alternative)
-		6381:  {"redis", "banner"},         // Redis (alternative)
-		9200:  {"elasticsearch", "banner"}, // Elasticsearch
-		9300:  {"elasticsearch", "banner"}, // Elasticsearch (cluster)
-		11211: {"memcached", "banner"},     // Memcached
-		11210: {"memcached", "banner"},     // Memcached (alternative)
-		5984:  {"couchdb", "banner"},       // CouchDB
-		5985:  {"couchdb", "banner"},       // CouchDB (alternative)
-		8080:  {"http", "banner"},
-		8443:  {"http", "tls", "banner"},
-	}
-
-	if modules, exists := portModules[port]; exists {
-		return modules
-	}
-
-	// For unknown ports, use generic banner module
-	return []string{"banner"}
+	return domain.KnownPorts.Modules(port)
 }
 
 // buildZGrabCommand builds the ZGrab2 command with selected modules
@@ -133,47 +241,94 @@ func (z *ZGrabBannerService) buildZGrabCommand(ctx context.Context, ip string, p
 		"--timeout", fmt.Sprintf("%.0fs", z.timeout.Seconds()),
 	}
 
-	// Add selected modules
+	// Add selected modules with their per-module overrides
 	for _, module := range modules {
 		args = append(args, "--"+module)
+
+		settings, ok := z.moduleSettings[module]
+		if !ok {
+			continue
+		}
+		if settings.Timeout > 0 {
+			args = append(args, fmt.Sprintf("--%s-timeout", module), fmt.Sprintf("%.0fs", settings.Timeout.Seconds()))
+		}
+		if settings.Retries > 0 {
+			args = append(args, fmt.Sprintf("--%s-retries", module), fmt.Sprintf("%d", settings.Retries))
+		}
+	}
+	if containsModule(modules, "http") {
+		args = append(args, z.httpArgs()...)
 	}
 
-	return exec.CommandContext(ctx, "zgrab2", args...)
+	cmd := exec.CommandContext(ctx, z.binaryPath, args...)
+	cmd.WaitDelay = zgrabWaitDelay
+	return cmd
 }
 
-// executeZGrabCommand executes ZGrab2 command with proper error handling
-func (z *ZGrabBannerService) executeZGrabCommand(cmd *exec.Cmd) ([]byte, error) {
+// httpArgs returns the http module flags sending the configured User-Agent and headers
+func (z *ZGrabBannerService) httpArgs() []string {
+	var args []string
+	if z.httpProbe.UserAgent != "" {
+		args = append(args, "--http-user-agent", z.httpProbe.UserAgent)
+	}
+	if names := z.httpProbe.HeaderNames(); len(names) > 0 {
+		values := make([]string, len(names))
+		for i, name := range names {
+			values[i] = z.httpProbe.Headers[name]
+			names[i] = textproto.CanonicalMIMEHeaderKey(name)
+		}
+		args = append(args,
+			"--http-custom-headers-names", strings.Join(names, zgrabHeaderDelimiter),
+			"--http-custom-headers-values", strings.Join(values, zgrabHeaderDelimiter),
+			"--http-custom-headers-delimiter", zgrabHeaderDelimiter)
+	}
+	return args
+}
+
+// containsModule reports whether a module list includes a module
+func containsModule(modules []string, module string) bool {
+	for _, candidate := range modules {
+		if candidate == module {
+			return true
+		}
+	}
+	return false
+}
+
+// executeZGrabCommand executes ZGrab2 command with proper error handling. On failure it still
+// returns whatever the process wrote before it exited.
+func (z *ZGrabBannerService) executeZGrabCommand(ctx context.Context, cmd *exec.Cmd) ([]byte, error) {
 	// Set up command with proper environment
 	cmd.Stderr = nil // Suppress stderr to avoid noise
 
-	// Execute command
+	// Every ZGrab2 process counts against the global cap, whichever grabber starts it
+	release := acquireZGrabSlot()
 	output, err := cmd.Output()
+	release()
 	if err != nil {
 		// Check if ZGrab2 is not available
 		if strings.Contains(err.Error(), "executable file not found") {
 			return nil, fmt.Errorf("zgrab2 not available: %w", err)
 		}
 
-		// Check if it's a timeout error (context deadline exceeded)
-		if strings.Contains(err.Error(), "context deadline exceeded") {
-			return nil, fmt.Errorf("zgrab2 timeout: %w", err)
+		// A process killed at the deadline only reports the signal, so check the context
+		if ctx.Err() != nil {
+			return output, fmt.Errorf("zgrab2 timeout: %w: %v", ctx.Err(), err)
 		}
 
 		// Other execution errors
-		return nil, fmt.Errorf("zgrab2 execution failed: %w", err)
+		return output, fmt.Errorf("zgrab2 execution failed: %w", err)
 	}
 
 	return output, nil
 }
 
 // parseZGrabOutput parses the JSON output from ZGrab2 with proper result selection
-func (z *ZGrabBannerService) parseZGrabOutput(output []byte, port int) (*domain.BannerInfo, error) {
-	// Split output by lines (ZGrab2 outputs one JSON object per line)
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-
-	if len(lines) == 0 {
+func (z *ZGrabBannerService) parseZGrabOutput(output []byte, ip string, port int) (*domain.BannerInfo, error) {
+	bestResult := z.selectZGrabResult(output, ip, port)
+	if bestResult == nil {
 		return &domain.BannerInfo{
-			RawBanner:  "",
+			RawBanner:  string(output),
 			Service:    "unknown",
 			Protocol:   "tcp",
 			Version:    "",
@@ -181,18 +336,27 @@ func (z *ZGrabBannerService) parseZGrabOutput(output []byte, port int) (*domain.
 		}, nil
 	}
 
-	// Parse all results and select the best one
+	return bestResult, nil
+}
+
+// selectZGrabResult returns the best result among the complete JSON lines of ZGrab2 output,
+// or nil if there are none. Malformed lines, such as a last line cut off when the process
+// was killed mid-write, are skipped and counted.
+func (z *ZGrabBannerService) selectZGrabResult(output []byte, ip string, port int) *domain.BannerInfo {
 	var bestResult *domain.BannerInfo
 	highestPriority := -1
+	skipped := 0
 
-	for _, line := range lines {
+	// ZGrab2 outputs one JSON object per line
+	for _, line := range strings.Split(string(output), "\n") {
 		if strings.TrimSpace(line) == "" {
 			continue
 		}
 
 		var result BannerResult
 		if err := json.Unmarshal([]byte(line), &result); err != nil {
-			continue // Skip malformed lines
+			skipped++
+			continue
 		}
 
 		// Analyze this result
@@ -207,17 +371,11 @@ func (z *ZGrabBannerService) parseZGrabOutput(output []byte, port int) (*domain.
 		}
 	}
 
-	if bestResult == nil {
-		return &domain.BannerInfo{
-			RawBanner:  string(output),
-			Service:    "unknown",
-			Protocol:   "tcp",
-			Version:    "",
-			Confidence: "port",
-		}, nil
+	if skipped > 0 {
+		log.L().Debug("Skipped malformed ZGrab2 output lines", zap.String("event", "zgrab_lines_skipped"),
+			zap.String("ip", ip), zap.Int("port", port), zap.Int("skipped", skipped), zap.Bool("result_found", bestResult != nil))
 	}
-
-	return bestResult, nil
+	return bestResult
 }
 
 // analyzeZGrabResult analyzes a single ZGrab2 result
@@ -228,7 +386,7 @@ func (z *ZGrabBannerService) analyzeZGrabResult(result BannerResult, port int) *
 	version := z.extractVersion(result.Data)
 	confidence := z.determineConfidence(result.Data)
 
-	return &domain.BannerInfo{
+	bannerInfo := &domain.BannerInfo{
 		RawBanner:  rawBanner,
 		Service:    service,
 		Protocol:   "tcp",
@@ -236,6 +394,14 @@ func (z *ZGrabBannerService) analyzeZGrabResult(result BannerResult, port int) *
 		Confidence: confidence,
 		Metadata:   result.Data,
 	}
+	bannerInfo.TruncateRawBanner(z.maxBannerLength)
+
+	// Banners from a CDN edge describe the CDN, not the scanned host
+	if cdn := detectCDN(result.Data); cdn != nil {
+		bannerInfo.SetCDN(cdn)
+	}
+
+	return bannerInfo
 }
 
 // calculateResultPriority calculates the priority of a result based on confidence and module relevance
@@ -783,73 +949,28 @@ func (z *ZGrabBannerService) identifyService(port int, data map[string]interface
 	return z.IdentifyServiceByPort(port)
 }
 
-// IdentifyServiceByPort identifies service by common port numbers
+// IdentifyServiceByPort identifies service by common port numbers from the port registry
 func (z *ZGrabBannerService) IdentifyServiceByPort(port int) string {
-	portServices := map[int]string{
-		21:    "ftp",
-		22:    "ssh",
-		23:    "telnet",
-		25:    "smtp",
-		53:    "dns",
-		80:    "http",
-		110:   "pop3",
-		143:   "imap",
-		443:   "https",
-		993:   "imaps",
-		995:   "pop3s",
-		3306:  "mysql",
-		3307:  "mysql",
-		3308:  "mysql",
-		3309:  "mysql",
-		3389:  "rdp",
-		5432:  "postgresql",
-		5433:  "postgresql",
-		5434:  "postgresql",
-		5435:  "postgresql",
-		6378:  "redis",
-		6379:  "redis",
-		6380:  "redis",
-		6381:  "redis",
-		27017: "mongodb",
-		27018: "mongodb",
-		27019: "mongodb",
-		27020: "mongodb",
-		1521:  "oracle",
-		1526:  "oracle",
-		1433:  "mssql",
-		1434:  "mssql",
-		9200:  "elasticsearch",
-		9300:  "elasticsearch",
-		11210: "memcached",
-		11211: "memcached",
-		5984:  "couchdb",
-		5985:  "couchdb",
-		8080:  "http-proxy",
-		8443:  "https-alt",
-	}
-
-	if service, exists := portServices[port]; exists {
-		return service
-	}
-
-	return "unknown"
-}
-
-// FallbackBannerGrab provides a basic banner grab when ZGrab2 is not available
+	return domain.KnownPorts.Service(port)
+}
+
+// FallbackBannerGrab provides a basic banner grab when ZGrab2 is not available, reusing the
+// port scan's connection when one is cached
 func (z *ZGrabBannerService) FallbackBannerGrab(ip string, port int) (*domain.BannerInfo, error) {
-	conn, err := net.DialTimeout("tcp", fmt.Sprintf("%s:%d", ip, port), z.timeout)
+	conn, err := z.conns.Dial(net.JoinHostPort(ip, strconv.Itoa(port)), z.connectTimeout)
 	if err != nil {
 		return nil, err
 	}
 	defer conn.Close()
 
-	// Set read deadline
-	conn.SetReadDeadline(time.Now().Add(z.timeout))
+	// The read budget starts once connected, however long the connect took
+	conn.SetReadDeadline(time.Now().Add(z.readTimeout))
 
-	// Send a simple probe
-	_, err = conn.Write([]byte("\r\n"))
-	if err != nil {
-		return nil, err
+	// Send the port's probe; an empty probe waits for the service to speak first
+	if payload := z.probePayload(ip, port); len(payload) > 0 {
+		if _, err := conn.Write(payload); err != nil {
+			return nil, err
+		}
 	}
 
 	// Read response
@@ -858,13 +979,16 @@ func (z *ZGrabBannerService) FallbackBannerGrab(ip string, port int) (*domain.Ba
 		banner := strings.TrimSpace(scanner.Text())
 		version := z.extractVersionFromBanner(banner)
 
-		return &domain.BannerInfo{
+		bannerInfo := &domain.BannerInfo{
 			RawBanner:  banner,
 			Service:    z.IdentifyServiceByPort(port),
 			Protocol:   "tcp",
 			Version:    version,
 			Confidence: "banner",
-		}, nil
+		}
+		bannerInfo.TruncateRawBanner(z.maxBannerLength)
+
+		return bannerInfo, nil
 	}
 
 	return &domain.BannerInfo{
@@ -0,0 +1,58 @@
+package banner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// hangingZGrab writes a fake zgrab2 that prints one complete result and then waits on a child
+// process, which keeps the output pipe open after the script itself is killed
+func hangingZGrab(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "zgrab2")
+	script := "#!/bin/sh\nprintf '%s\\n' '" + sshLine + "'\nsleep 5\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatalf("failed to write fake zgrab2: %v", err)
+	}
+	return path
+}
+
+func TestGetBannerDoesNotWaitOnChildrenOfKilledZGrab(t *testing.T) {
+	z := NewZGrabBannerService(200 * time.Millisecond)
+	z.SetBinaryPath(hangingZGrab(t))
+
+	start := time.Now()
+	info, err := z.GetBanner("203.0.113.7", 22)
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatalf("GetBanner returned error: %v", err)
+	}
+	if limit := 200*time.Millisecond + zgrabWaitDelay + time.Second; elapsed > limit {
+		t.Errorf("GetBanner took %v, want at most %v despite the child holding the output open", elapsed, limit)
+	}
+	if info.Service != "ssh" || info.Metadata["ssh"] == nil {
+		t.Errorf("GetBanner = %s with metadata %v, want the ssh result written before the kill", info.Service, info.Metadata)
+	}
+}
+
+func TestExecuteZGrabCommandReportsTimeoutWithOutput(t *testing.T) {
+	z := NewZGrabBannerService(time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, hangingZGrab(t))
+	cmd.WaitDelay = zgrabWaitDelay
+	output, err := z.executeZGrabCommand(ctx, cmd)
+	if err == nil || !strings.Contains(err.Error(), "zgrab2 timeout") || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("executeZGrabCommand error = %v, want a zgrab2 timeout wrapping the deadline", err)
+	}
+	if !strings.Contains(string(output), sshLine) {
+		t.Errorf("output = %q, want the line written before the kill", output)
+	}
+}
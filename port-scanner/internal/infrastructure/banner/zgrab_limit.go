@@ -0,0 +1,45 @@
+package banner
+
+import "sync"
+
+// zgrabSlots caps the ZGrab2 processes running at once across every grabber in the process,
+// whichever pool or fallback path starts them; nil leaves them unbounded
+var (
+	zgrabSlotsMu sync.RWMutex
+	zgrabSlots   chan struct{}
+)
+
+// SetMaxZGrabProcesses caps the ZGrab2 processes running at once in this process; 0 or less
+// removes the cap. Processes already running keep the slots they hold under the old cap.
+func SetMaxZGrabProcesses(limit int) {
+	zgrabSlotsMu.Lock()
+	defer zgrabSlotsMu.Unlock()
+
+	if limit <= 0 {
+		zgrabSlots = nil
+		return
+	}
+	zgrabSlots = make(chan struct{}, limit)
+}
+
+// acquireZGrabSlot blocks until a ZGrab2 process may start and returns the function that
+// frees the slot once it exits
+func acquireZGrabSlot() func() {
+	zgrabSlotsMu.RLock()
+	slots := zgrabSlots
+	zgrabSlotsMu.RUnlock()
+
+	if slots == nil {
+		return func() {}
+	}
+	slots <- struct{}{}
+	return func() { <-slots }
+}
+
+// ZGrabProcesses returns how many ZGrab2 processes are running under the cap and the cap
+// itself; the cap is 0 when processes are unbounded
+func ZGrabProcesses() (running int, limit int) {
+	zgrabSlotsMu.RLock()
+	defer zgrabSlotsMu.RUnlock()
+	return len(zgrabSlots), cap(zgrabSlots)
+}
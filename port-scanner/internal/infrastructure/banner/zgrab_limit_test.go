@@ -0,0 +1,121 @@
+package banner
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingZGrab writes a fake zgrab2 that records how many copies of itself are running each
+// time one starts, and returns the script and the file the counts are appended to
+func countingZGrab(t *testing.T) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	running := filepath.Join(dir, "running")
+	if err := os.Mkdir(running, 0o755); err != nil {
+		t.Fatalf("failed to create running dir: %v", err)
+	}
+	counts := filepath.Join(dir, "counts")
+
+	path := filepath.Join(dir, "zgrab2")
+	script := "#!/bin/sh\n" +
+		"touch '" + running + "'/$$\n" +
+		"ls '" + running + "' | wc -l >> '" + counts + "'\n" +
+		"sleep 0.1\n" +
+		"rm '" + running + "'/$$\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatalf("failed to write fake zgrab2: %v", err)
+	}
+	return path, counts
+}
+
+func TestZGrabProcessesNeverExceedGlobalLimit(t *testing.T) {
+	const limit = 2
+	SetMaxZGrabProcesses(limit)
+	t.Cleanup(func() { SetMaxZGrabProcesses(0) })
+	path, countsFile := countingZGrab(t)
+
+	// The optimized grabber's pool and standalone fallback services all start ZGrab2
+	grabber := NewBannerGrabber(4, 5*time.Second, nil)
+	grabber.SetBinaryPath(path)
+	defer grabber.Shutdown()
+	services := make([]*ZGrabBannerService, 4)
+	for i := range services {
+		services[i] = NewZGrabBannerService(5 * time.Second)
+		services[i].SetBinaryPath(path)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			grabber.GetBanner("203.0.113.7", 22)
+		}()
+		go func(z *ZGrabBannerService) {
+			defer wg.Done()
+			z.GetBanner("203.0.113.8", 22)
+		}(services[i])
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(countsFile)
+	if err != nil {
+		t.Fatalf("fake zgrab2 never ran: %v", err)
+	}
+	starts, peak := 0, 0
+	for _, line := range strings.Fields(string(data)) {
+		count, err := strconv.Atoi(line)
+		if err != nil {
+			t.Fatalf("unexpected count %q", line)
+		}
+		starts++
+		peak = max(peak, count)
+	}
+	if starts != 8 {
+		t.Errorf("zgrab2 started %d times, want 8", starts)
+	}
+	if peak > limit {
+		t.Errorf("%d zgrab2 processes ran at once, want at most %d", peak, limit)
+	}
+
+	if running, gotLimit := ZGrabProcesses(); running != 0 || gotLimit != limit {
+		t.Errorf("ZGrabProcesses() = %d, %d, want 0 running under a limit of %d", running, gotLimit, limit)
+	}
+}
+
+func TestAcquireZGrabSlotBlocksAtLimit(t *testing.T) {
+	SetMaxZGrabProcesses(1)
+	t.Cleanup(func() { SetMaxZGrabProcesses(0) })
+
+	release := acquireZGrabSlot()
+	acquired := make(chan func())
+	go func() { acquired <- acquireZGrabSlot() }()
+
+	select {
+	case <-acquired:
+		t.Fatal("second slot acquired while the limit of 1 was taken")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release()
+	select {
+	case second := <-acquired:
+		second()
+	case <-time.After(time.Second):
+		t.Fatal("slot not handed over after release")
+	}
+
+	// Without a limit slots are never waited for
+	SetMaxZGrabProcesses(0)
+	for i := 0; i < 10; i++ {
+		acquireZGrabSlot()
+	}
+	if running, limit := ZGrabProcesses(); running != 0 || limit != 0 {
+		t.Errorf("ZGrabProcesses() = %d, %d without a limit, want 0, 0", running, limit)
+	}
+}
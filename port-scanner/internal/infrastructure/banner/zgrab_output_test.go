@@ -0,0 +1,72 @@
+package banner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// sshLine is one complete ZGrab2 output line for an SSH server
+const sshLine = `{"ip":"203.0.113.7","data":{"ssh":{"status":"success","protocol":"ssh","result":{"server_id":{"raw":"SSH-2.0-OpenSSH_9.6","version":"2.0","software":"OpenSSH_9.6"}}}}}`
+
+// truncatedLine is the start of a second result, cut off when ZGrab2 was killed mid-write
+const truncatedLine = `{"ip":"203.0.113.7","data":{"tls":{"status":"success","protocol":"tls","result":{"handsh`
+
+func TestParseZGrabOutputKeepsResultBeforeTruncatedLine(t *testing.T) {
+	z := NewZGrabBannerService(time.Second)
+
+	info, err := z.parseZGrabOutput([]byte(sshLine+"\n"+truncatedLine), "203.0.113.7", 22)
+	if err != nil {
+		t.Fatalf("parseZGrabOutput returned error: %v", err)
+	}
+	if info.Service != "ssh" || info.Confidence == "port" {
+		t.Errorf("parsed %s with confidence %s, want the complete ssh result", info.Service, info.Confidence)
+	}
+	if _, ok := info.Metadata["ssh"]; !ok {
+		t.Errorf("metadata = %v, want the ssh module data", info.Metadata)
+	}
+}
+
+func TestSelectZGrabResultWithoutCompleteLines(t *testing.T) {
+	z := NewZGrabBannerService(time.Second)
+
+	for _, output := range []string{"", "\n\n", truncatedLine, "not json\n" + truncatedLine} {
+		if info := z.selectZGrabResult([]byte(output), "203.0.113.7", 22); info != nil {
+			t.Errorf("selectZGrabResult(%q) = %+v, want nil", output, info)
+		}
+	}
+
+	// With nothing usable the raw output is kept at port confidence
+	info, err := z.parseZGrabOutput([]byte(truncatedLine), "203.0.113.7", 22)
+	if err != nil {
+		t.Fatalf("parseZGrabOutput returned error: %v", err)
+	}
+	if info.Confidence != "port" || info.RawBanner != truncatedLine {
+		t.Errorf("parsed %+v, want the raw output at port confidence", info)
+	}
+}
+
+func TestGetBannerUsesOutputOfKilledZGrab(t *testing.T) {
+	// The fake zgrab2 writes one result, starts a second and is killed at the deadline
+	path := filepath.Join(t.TempDir(), "zgrab2")
+	script := "#!/bin/sh\nprintf '%s\\n%s' '" + sshLine + "' '" + truncatedLine + "'\nexec sleep 5\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatalf("failed to write fake zgrab2: %v", err)
+	}
+
+	z := NewZGrabBannerService(200 * time.Millisecond)
+	z.SetBinaryPath(path)
+
+	start := time.Now()
+	info, err := z.GetBanner("203.0.113.7", 22)
+	if err != nil {
+		t.Fatalf("GetBanner returned error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("GetBanner took %v, want it cut off at the deadline", elapsed)
+	}
+	if info.Service != "ssh" || info.Metadata["ssh"] == nil {
+		t.Errorf("GetBanner = %s with metadata %v, want the ssh result written before the kill", info.Service, info.Metadata)
+	}
+}
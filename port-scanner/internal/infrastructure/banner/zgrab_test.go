@@ -0,0 +1,126 @@
+package banner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"port-scanner/internal/domain"
+)
+
+// flagValue returns the argument following flag in args, and whether flag was present
+func flagValue(args []string, flag string) (string, bool) {
+	for i, arg := range args {
+		if arg == flag && i+1 < len(args) {
+			return args[i+1], true
+		}
+	}
+	return "", false
+}
+
+func TestBuildZGrabCommandAddsPerModuleSettings(t *testing.T) {
+	z := NewZGrabBannerService(3 * time.Second)
+	z.SetModuleSettings(map[string]domain.ZGrabModuleSettings{
+		"oracle": {Timeout: 10 * time.Second, Retries: 2},
+		"mssql":  {Timeout: 12 * time.Second},
+		"http":   {Retries: 1},
+	})
+
+	cmd := z.buildZGrabCommand(context.Background(), "203.0.113.7", 1521, []string{"oracle", "mssql", "http", "banner"})
+	args := cmd.Args[1:]
+
+	want := map[string]string{
+		"--timeout":        "3s",
+		"--oracle-timeout": "10s",
+		"--oracle-retries": "2",
+		"--mssql-timeout":  "12s",
+		"--http-retries":   "1",
+		"--targets":        "203.0.113.7:1521",
+		"--port":           "1521",
+	}
+	for flag, value := range want {
+		if got, ok := flagValue(args, flag); !ok || got != value {
+			t.Errorf("%s = %q (present %v), want %q in %v", flag, got, ok, value, args)
+		}
+	}
+	for _, flag := range []string{"--mssql-retries", "--http-timeout", "--banner-timeout", "--banner-retries"} {
+		if _, ok := flagValue(args, flag); ok {
+			t.Errorf("unexpected %s for a module without that override: %v", flag, args)
+		}
+	}
+	for _, module := range []string{"--oracle", "--mssql", "--http", "--banner"} {
+		if !containsModule(args, module) {
+			t.Errorf("module flag %s missing from %v", module, args)
+		}
+	}
+
+	// Each module's overrides follow its own module flag
+	joined := strings.Join(args, " ")
+	if !strings.Contains(joined, "--oracle --oracle-timeout 10s --oracle-retries 2 --mssql --mssql-timeout 12s") {
+		t.Errorf("module overrides not grouped with their modules: %s", joined)
+	}
+}
+
+func TestCommandTimeoutCoversSlowestModuleRetries(t *testing.T) {
+	z := NewZGrabBannerService(3 * time.Second)
+	if got := z.CommandTimeout(1521); got != 3*time.Second {
+		t.Errorf("CommandTimeout without overrides = %v, want the banner timeout", got)
+	}
+
+	z.SetModuleSettings(map[string]domain.ZGrabModuleSettings{"oracle": {Timeout: 10 * time.Second, Retries: 1}})
+	if modules := z.selectModulesForPort(1521); !containsModule(modules, "oracle") {
+		t.Skipf("port 1521 is not mapped to the oracle module: %v", modules)
+	}
+	if got := z.CommandTimeout(1521); got != 20*time.Second {
+		t.Errorf("CommandTimeout = %v, want 20s for one oracle retry", got)
+	}
+	if got := z.CommandTimeout(80); got != 3*time.Second {
+		t.Errorf("CommandTimeout for an http port = %v, want 3s", got)
+	}
+}
+
+// fakeZGrab writes an executable standing in for zgrab2 that reports the given version
+func fakeZGrab(t *testing.T, version string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "zgrab2-custom")
+	script := "#!/bin/sh\necho '" + version + "'\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatalf("failed to write fake zgrab2: %v", err)
+	}
+	return path
+}
+
+func TestBuildZGrabCommandUsesConfiguredBinary(t *testing.T) {
+	path := fakeZGrab(t, "zgrab2 v0.1.8")
+	z := NewZGrabBannerService(3 * time.Second)
+	z.SetBinaryPath(path)
+
+	cmd := z.buildZGrabCommand(context.Background(), "203.0.113.7", 22, []string{"ssh"})
+	if cmd.Path != path || cmd.Args[0] != path {
+		t.Errorf("command runs %q (args[0] %q), want %q", cmd.Path, cmd.Args[0], path)
+	}
+
+	z.SetBinaryPath("")
+	if cmd := z.buildZGrabCommand(context.Background(), "203.0.113.7", 22, []string{"ssh"}); cmd.Args[0] != defaultZGrabBinary {
+		t.Errorf("command without a configured path runs %q, want %q", cmd.Args[0], defaultZGrabBinary)
+	}
+}
+
+func TestResolveZGrabBinaryReportsVersion(t *testing.T) {
+	path := fakeZGrab(t, "zgrab2 v0.1.8")
+
+	resolved, version, err := ResolveZGrabBinary(path)
+	if err != nil {
+		t.Fatalf("ResolveZGrabBinary returned error: %v", err)
+	}
+	if resolved != path || version != "zgrab2 v0.1.8" {
+		t.Errorf("ResolveZGrabBinary = %q, %q, want %q, %q", resolved, version, path, "zgrab2 v0.1.8")
+	}
+
+	if _, _, err := ResolveZGrabBinary(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("ResolveZGrabBinary accepted a missing binary")
+	}
+}
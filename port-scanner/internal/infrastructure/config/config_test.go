@@ -0,0 +1,119 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// loadConfig loads a config file with the given contents from a temporary directory
+func loadConfig(t *testing.T, contents string) (*Config, error) {
+	t.Helper()
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(contents), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return LoadConfig(dir)
+}
+
+// mustLoadConfig loads a config file and fails the test if it is rejected
+func mustLoadConfig(t *testing.T, contents string) *Config {
+	t.Helper()
+	cfg, err := loadConfig(t, contents)
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	return cfg
+}
+
+func TestAnalyzersDefaultToNone(t *testing.T) {
+	cfg := mustLoadConfig(t, "")
+	if len(cfg.Scan.Analyzers) != 0 {
+		t.Errorf("scan.analyzers defaults to %v, want none", cfg.Scan.Analyzers)
+	}
+
+	cfg = mustLoadConfig(t, "scan:\n  analyzers: [\"redis_no_auth\"]\n")
+	if len(cfg.Scan.Analyzers) != 1 || cfg.Scan.Analyzers[0] != "redis_no_auth" {
+		t.Errorf("scan.analyzers = %v, want [redis_no_auth]", cfg.Scan.Analyzers)
+	}
+}
+
+func TestShippedConfigDisablesOptionalFeatures(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	cfg, err := LoadConfig("../../..")
+	if err != nil {
+		t.Fatalf("failed to load the shipped config.yaml: %v", err)
+	}
+	if len(cfg.Scan.Analyzers) != 0 {
+		t.Errorf("config.yaml enables analyzers %v, want none", cfg.Scan.Analyzers)
+	}
+	if cfg.MongoDB.EnableBatchClaims {
+		t.Error("config.yaml enables batch claims, want them off")
+	}
+	if cfg.MongoDB.EnableOutbox {
+		t.Error("config.yaml enables the outbox, want it off")
+	}
+}
+
+func TestBatchClaimsDefaultOff(t *testing.T) {
+	cfg := mustLoadConfig(t, "")
+	if cfg.MongoDB.EnableBatchClaims {
+		t.Error("mongodb.enable_batch_claims defaults to true, want false")
+	}
+
+	cfg = mustLoadConfig(t, "mongodb:\n  enable_batch_claims: true\n")
+	if !cfg.MongoDB.EnableBatchClaims {
+		t.Error("mongodb.enable_batch_claims: true was not honoured")
+	}
+}
+
+func TestOutboxDefaultOff(t *testing.T) {
+	cfg := mustLoadConfig(t, "")
+	if cfg.MongoDB.EnableOutbox {
+		t.Error("mongodb.enable_outbox defaults to true, want false")
+	}
+	if cfg.MongoDB.OutboxAdoptAfter != "5m" {
+		t.Errorf("mongodb.outbox_adopt_after defaults to %q, want 5m", cfg.MongoDB.OutboxAdoptAfter)
+	}
+
+	cfg = mustLoadConfig(t, "mongodb:\n  enable_outbox: true\n")
+	if !cfg.MongoDB.EnableOutbox {
+		t.Error("mongodb.enable_outbox: true was not honoured")
+	}
+}
+
+func TestMockScannerConfig(t *testing.T) {
+	cfg := mustLoadConfig(t, "scan:\n  mock_scanner:\n    seed: 42\n    hosts:\n      - ip: 203.0.113.5\n        ports: [22, 80]\n    banners:\n      \"22\": SSH-2.0-mock\n")
+	if cfg.Scan.MockScanner.Enabled {
+		t.Error("mock scanner enabled by default")
+	}
+	mock, err := cfg.ToDomainMockScannerConfig()
+	if err != nil {
+		t.Fatalf("ToDomainMockScannerConfig returned error: %v", err)
+	}
+	if mock.Seed != 42 || mock.OpenRatio != 0.2 || len(mock.HostPorts["203.0.113.5"]) != 2 || mock.Banners[22] != "SSH-2.0-mock" {
+		t.Errorf("mock scanner config = %+v, want seed 42, the default ratio, one host and a banner on 22", mock)
+	}
+
+	t.Setenv("PORT_SCANNER_MOCK", "true")
+	if cfg := mustLoadConfig(t, ""); !cfg.Scan.MockScanner.Enabled {
+		t.Error("PORT_SCANNER_MOCK=true did not enable the mock scanner")
+	}
+
+	for _, invalid := range []string{
+		"scan:\n  mock_scanner:\n    open_ratio: 1.5\n",
+		"scan:\n  mock_scanner:\n    hosts:\n      - ip: not-an-ip\n",
+		"scan:\n  mock_scanner:\n    banners:\n      ssh: SSH-2.0-mock\n",
+	} {
+		if _, err := loadConfig(t, invalid); err == nil {
+			t.Errorf("LoadConfig accepted %q", invalid)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"port-scanner/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Ensure MongoDBManager implements AnalysisStore interface
+var _ domain.AnalysisStore = (*MongoDBManager)(nil)
+
+// FindingDocument represents the MongoDB document structure for an analyzer finding
+type FindingDocument struct {
+	Analyzer string `bson:"analyzer" json:"analyzer"`
+	Port     int    `bson:"port" json:"port"`
+	Service  string `bson:"service" json:"service"`
+	Severity string `bson:"severity" json:"severity"`
+	Title    string `bson:"title" json:"title"`
+	Detail   string `bson:"detail,omitempty" json:"detail,omitempty"`
+}
+
+// ServiceClusterDocument represents the MongoDB document structure for a service found on several ports
+type ServiceClusterDocument struct {
+	Service         string   `bson:"service" json:"service"`
+	Ports           []int    `bson:"ports" json:"ports"`
+	Versions        []string `bson:"versions,omitempty" json:"versions,omitempty"`
+	VersionMismatch bool     `bson:"version_mismatch" json:"version_mismatch"`
+}
+
+// analysisIndexes index service analyses by IP, newest first
+func analysisIndexes() []mongo.IndexModel {
+	return []mongo.IndexModel{{
+		Keys:    bson.D{{Key: "ip", Value: 1}, {Key: "created_at", Value: -1}},
+		Options: options.Index().SetName("ip_created_at_idx"),
+	}}
+}
+
+// SaveServiceAnalysis stores the open ports of a result with the findings of the service
+// analyzers and the services running on several ports
+func (m *MongoDBManager) SaveServiceAnalysis(result *domain.ScanResult) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var openPorts []PortDocument
+	for _, port := range m.convertScanResultToDocument(result).Ports {
+		if port.Status == string(domain.PortStatusOpen) {
+			openPorts = append(openPorts, port)
+		}
+	}
+
+	findings := make([]FindingDocument, 0, len(result.Findings))
+	for _, finding := range result.Findings {
+		findings = append(findings, FindingDocument(finding))
+	}
+
+	var clusters []ServiceClusterDocument
+	mismatches := 0
+	for _, cluster := range domain.ClusterServices(result.Ports) {
+		clusters = append(clusters, ServiceClusterDocument(cluster))
+		if cluster.VersionMismatch {
+			mismatches++
+		}
+	}
+
+	now := time.Now()
+	doc := ServiceAnalysisDocument{
+		IP:        result.IP,
+		OpenPorts: openPorts,
+		BatchID:   result.BatchID,
+		Timestamp: result.ScanEndTime,
+		CreatedAt: now,
+		Analysis: map[string]interface{}{
+			"findings":           findings,
+			"finding_count":      len(findings),
+			"service_clusters":   clusters,
+			"version_mismatches": mismatches,
+		},
+	}
+
+	if _, err := m.analysis.InsertOne(ctx, doc); err != nil {
+		return fmt.Errorf("failed to save service analysis of %s: %w", result.IP, err)
+	}
+	return nil
+}
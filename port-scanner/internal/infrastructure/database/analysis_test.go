@@ -0,0 +1,54 @@
+package database
+
+import (
+	"testing"
+
+	"port-scanner/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestSaveServiceAnalysisStoresServiceClusters(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("clusters", func(mt *mtest.T) {
+		m := newTestManager()
+		m.analysis = mt.Coll
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		result := domain.NewScanResult("8.8.8.8", "batch-1", "worker-1")
+		for _, port := range []*domain.Port{
+			{Number: 80, Status: domain.PortStatusOpen, Service: "http", Version: "Apache 2.4.58"},
+			{Number: 443, Status: domain.PortStatusOpen, Service: "https", Version: "nginx 1.24"},
+			{Number: 22, Status: domain.PortStatusOpen, Service: "ssh"},
+		} {
+			result.AddPort(port)
+		}
+		if err := m.SaveServiceAnalysis(result); err != nil {
+			mt.Fatalf("SaveServiceAnalysis returned error: %v", err)
+		}
+
+		event := mt.GetStartedEvent()
+		if event == nil || event.CommandName != "insert" {
+			mt.Fatalf("sent %v, want insert", event)
+		}
+		var doc struct {
+			Analysis struct {
+				Clusters   []ServiceClusterDocument `bson:"service_clusters"`
+				Mismatches int                      `bson:"version_mismatches"`
+			} `bson:"analysis"`
+		}
+		if err := bson.Unmarshal(event.Command.Lookup("documents").Array().Index(0).Value().Document(), &doc); err != nil {
+			mt.Fatalf("failed to decode inserted document: %v", err)
+		}
+
+		clusters := doc.Analysis.Clusters
+		if len(clusters) != 1 || clusters[0].Service != "http" || len(clusters[0].Ports) != 2 || !clusters[0].VersionMismatch {
+			mt.Errorf("service_clusters = %+v, want one mismatched http cluster on 80 and 443", clusters)
+		}
+		if doc.Analysis.Mismatches != 1 {
+			mt.Errorf("version_mismatches = %d, want 1", doc.Analysis.Mismatches)
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AnnotationDocument is an analyst note attached to a scanned IP
+type AnnotationDocument struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	IP        string             `bson:"ip" json:"ip"`
+	Author    string             `bson:"author" json:"author"`
+	Note      string             `bson:"note" json:"note"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// annotationIndexes index annotations by IP in creation order
+func annotationIndexes() []mongo.IndexModel {
+	return []mongo.IndexModel{{
+		Keys:    bson.D{{Key: "ip", Value: 1}, {Key: "created_at", Value: 1}},
+		Options: options.Index().SetName("ip_created_at_idx"),
+	}}
+}
+
+// AddAnnotation stores a note for an IP
+func (m *MongoDBManager) AddAnnotation(ip, author, note string) (*AnnotationDocument, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	annotation := &AnnotationDocument{
+		IP:        ip,
+		Author:    author,
+		Note:      note,
+		CreatedAt: time.Now(),
+	}
+
+	res, err := m.annotations.InsertOne(ctx, annotation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add annotation: %w", err)
+	}
+	if id, ok := res.InsertedID.(primitive.ObjectID); ok {
+		annotation.ID = id
+	}
+
+	return annotation, nil
+}
+
+// GetAnnotations returns the notes for an IP, oldest first
+func (m *MongoDBManager) GetAnnotations(ip string) ([]AnnotationDocument, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Sort on _id as a tie-breaker for notes added within the same millisecond
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := m.annotations.Find(ctx, bson.M{"ip": ip}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find annotations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	annotations := []AnnotationDocument{}
+	if err := cursor.All(ctx, &annotations); err != nil {
+		return nil, fmt.Errorf("failed to decode annotations: %w", err)
+	}
+
+	return annotations, nil
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"port-scanner/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Ensure MongoDBManager implements ResultArchive interface
+var _ domain.ResultArchive = (*MongoDBManager)(nil)
+
+// ArchiveResult implements ResultArchive. Only valid when MongoDB is the result sink: every
+// result is already saved, so there is nothing to spill.
+func (m *MongoDBManager) ArchiveResult(result *domain.ScanResult) error {
+	return nil
+}
+
+// ArchivedResult implements ResultArchive with the most recently stored result of an IP
+func (m *MongoDBManager) ArchivedResult(ip string) (*domain.ScanResult, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var doc ScanResultDocument
+	opts := options.FindOne().SetSort(bson.M{"created_at": -1})
+	err := m.collection.FindOne(ctx, bson.M{"ip": ip}, opts).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get archived result of %s: %w", ip, err)
+	}
+
+	if err := m.decompressDocument(&doc); err != nil {
+		return nil, false, fmt.Errorf("failed to decompress scan result: %w", err)
+	}
+	migrateDocument(&doc)
+	return doc.ToScanResult(), true, nil
+}
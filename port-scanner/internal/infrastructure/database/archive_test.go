@@ -0,0 +1,64 @@
+package database
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestArchivedResultReadsLatestStoredResult(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("found", func(mt *mtest.T) {
+		m := newTestManager()
+		m.collection = mt.Coll
+
+		ns := mt.DB.Name() + "." + mt.Coll.Name()
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{
+			{Key: "ip", Value: "8.8.8.8"},
+			{Key: "batch_id", Value: "batch-2"},
+			{Key: "ports", Value: bson.A{bson.D{{Key: "number", Value: int32(443)}, {Key: "status", Value: "open"}}}},
+		}))
+
+		result, found, err := m.ArchivedResult("8.8.8.8")
+		if err != nil || !found {
+			mt.Fatalf("ArchivedResult = %v, %v, want the stored result", found, err)
+		}
+		if result.IP != "8.8.8.8" || result.BatchID != "batch-2" || len(result.GetOpenPorts()) != 1 {
+			mt.Errorf("ArchivedResult = %+v, want the stored result", result)
+		}
+
+		event := mt.GetStartedEvent()
+		if event == nil || event.CommandName != "find" {
+			mt.Fatalf("sent %v, want find", event)
+		}
+		if sort := event.Command.Lookup("sort").Document().Lookup("created_at").AsInt64(); sort != -1 {
+			mt.Errorf("sort on created_at = %d, want -1 for the latest result", sort)
+		}
+	})
+
+	mt.Run("not found", func(mt *mtest.T) {
+		m := newTestManager()
+		m.collection = mt.Coll
+
+		ns := mt.DB.Name() + "." + mt.Coll.Name()
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch))
+
+		if result, found, err := m.ArchivedResult("9.9.9.9"); found || err != nil {
+			mt.Errorf("ArchivedResult of an unknown IP = %v, %v, %v, want not found", result, found, err)
+		}
+	})
+
+	mt.Run("archiving writes nothing", func(mt *mtest.T) {
+		m := newTestManager()
+		m.collection = mt.Coll
+
+		if err := m.ArchiveResult(scannedResult("batch-1", 22)); err != nil {
+			mt.Errorf("ArchiveResult returned error: %v", err)
+		}
+		if event := mt.GetStartedEvent(); event != nil {
+			mt.Errorf("ArchiveResult sent %s, want nothing since the sink already stored it", event.CommandName)
+		}
+	})
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestGetScanResultsByBatchLimitsOldestFirst(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("limit", func(mt *mtest.T) {
+		m := newTestManager()
+		m.collection = mt.Coll
+
+		ns := mt.DB.Name() + "." + mt.Coll.Name()
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch,
+			bson.D{{Key: "ip", Value: "8.8.8.8"}, {Key: "batch_id", Value: "batch-1"}},
+		))
+		if _, err := m.GetScanResultsByBatch("batch-1", nil, 6); err != nil {
+			mt.Fatalf("GetScanResultsByBatch returned error: %v", err)
+		}
+
+		command := mt.GetStartedEvent()
+		if command == nil || command.CommandName != "find" {
+			mt.Fatal("no find was sent")
+		}
+		if limit, ok := command.Command.Lookup("limit").AsInt64OK(); !ok || limit != 6 {
+			mt.Errorf("find limit = %v, want 6", command.Command.Lookup("limit"))
+		}
+		if order, ok := command.Command.Lookup("sort", "created_at").AsInt64OK(); !ok || order != 1 {
+			mt.Errorf("find sort = %v, want created_at ascending", command.Command.Lookup("sort"))
+		}
+
+		// Without a limit the whole batch is read in any order
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch))
+		if _, err := m.GetScanResultsByBatch("batch-1", nil, 0); err != nil {
+			mt.Fatalf("GetScanResultsByBatch returned error: %v", err)
+		}
+		command = mt.GetStartedEvent()
+		if _, err := command.Command.LookupErr("limit"); err == nil {
+			mt.Errorf("find without a limit sent %v", command.Command)
+		}
+	})
+}
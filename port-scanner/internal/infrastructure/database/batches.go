@@ -0,0 +1,111 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"port-scanner/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// batchProgressRetention is how long batch progress is kept after its last update
+const batchProgressRetention = 7 * 24 * time.Hour
+
+// Ensure MongoDBManager implements BatchTracker interface
+var _ domain.BatchTracker = (*MongoDBManager)(nil)
+
+// BatchProgressDocument records which messages of a batch have been processed, by any worker
+type BatchProgressDocument struct {
+	BatchID       string    `bson:"_id" json:"batch_id"`
+	Sequences     []int     `bson:"sequences" json:"sequences"`
+	FinalSequence *int      `bson:"final_sequence,omitempty" json:"final_sequence,omitempty"`
+	Messages      int       `bson:"messages" json:"messages"`
+	IPsScanned    int       `bson:"ips_scanned" json:"ips_scanned"`
+	HostsUp       int       `bson:"hosts_up" json:"hosts_up"`
+	Failed        int       `bson:"failed" json:"failed"`
+	OpenPorts     int       `bson:"open_ports" json:"open_ports"`
+	StartedAt     time.Time `bson:"started_at" json:"started_at"`
+	CompletedAt   time.Time `bson:"completed_at" json:"completed_at"`
+	Notified      bool      `bson:"notified" json:"notified"`
+	UpdatedAt     time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// batchProgressIndexes expire batch progress a while after its last update
+func batchProgressIndexes() []mongo.IndexModel {
+	return []mongo.IndexModel{{
+		Keys:    bson.D{{Key: "updated_at", Value: 1}},
+		Options: options.Index().SetName("updated_at_ttl_idx").SetExpireAfterSeconds(int32(batchProgressRetention.Seconds())),
+	}}
+}
+
+// RecordMessage implements BatchTracker, so workers sharing the IP queue track batches
+// together. A message is counted once, and the batch is handed to exactly one worker when
+// its last outstanding message is recorded.
+func (m *MongoDBManager) RecordMessage(progress *domain.BatchProgress) (*domain.BatchCompleteMessage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	set := bson.M{"updated_at": time.Now()}
+	if progress.Final {
+		set["final_sequence"] = progress.Sequence
+	}
+	update := bson.M{
+		"$addToSet":    bson.M{"sequences": progress.Sequence},
+		"$inc":         bson.M{"messages": 1, "ips_scanned": progress.IPs, "hosts_up": progress.HostsUp, "failed": progress.Failed, "open_ports": progress.OpenPorts},
+		"$min":         bson.M{"started_at": progress.StartedAt},
+		"$max":         bson.M{"completed_at": progress.EndedAt},
+		"$set":         set,
+		"$setOnInsert": bson.M{"notified": false},
+	}
+	filter := bson.M{"_id": progress.BatchID, "sequences": bson.M{"$ne": progress.Sequence}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var doc BatchProgressDocument
+	var err error
+	// The upsert conflicts when the message was already recorded, or when another worker
+	// created the batch's document first; a retry tells the two apart
+	for attempt := 0; attempt < 2; attempt++ {
+		err = m.batchProgress.FindOneAndUpdate(ctx, filter, update, opts).Decode(&doc)
+		if !mongo.IsDuplicateKeyError(err) {
+			break
+		}
+	}
+	switch {
+	case mongo.IsDuplicateKeyError(err):
+		// A redelivered message was already counted
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to record progress of batch %s: %w", progress.BatchID, err)
+	}
+
+	if doc.Notified || doc.FinalSequence == nil || len(doc.Sequences) != *doc.FinalSequence+1 {
+		return nil, nil
+	}
+
+	// Only the worker that flips the flag announces the batch
+	res, err := m.batchProgress.UpdateOne(ctx,
+		bson.M{"_id": progress.BatchID, "notified": false},
+		bson.M{"$set": bson.M{"notified": true, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark batch %s complete: %w", progress.BatchID, err)
+	}
+	if res.ModifiedCount == 0 {
+		return nil, nil
+	}
+
+	return &domain.BatchCompleteMessage{
+		BatchID:     doc.BatchID,
+		Messages:    doc.Messages,
+		IPsScanned:  doc.IPsScanned,
+		HostsUp:     doc.HostsUp,
+		Failed:      doc.Failed,
+		OpenPorts:   doc.OpenPorts,
+		StartedAt:   doc.StartedAt,
+		CompletedAt: doc.CompletedAt,
+	}, nil
+}
@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"port-scanner/internal/domain"
+	"port-scanner/pkg/log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+const (
+	claimStatusActive   = "active"
+	claimStatusReleased = "released"
+)
+
+// Ensure MongoDBManager implements BatchClaimer interface
+var _ domain.BatchClaimer = (*MongoDBManager)(nil)
+
+// BatchClaimDocument records which worker is processing a batch
+type BatchClaimDocument struct {
+	BatchID     string     `bson:"_id" json:"batch_id"`
+	WorkerID    string     `bson:"worker_id" json:"worker_id"`
+	Status      string     `bson:"status" json:"status"`
+	ClaimedAt   time.Time  `bson:"claimed_at" json:"claimed_at"`
+	HeartbeatAt time.Time  `bson:"heartbeat_at" json:"heartbeat_at"`
+	ReleasedAt  *time.Time `bson:"released_at,omitempty" json:"released_at,omitempty"`
+	Stale       bool       `bson:"-" json:"stale"`
+}
+
+// SetClaimStaleAfter sets how long an active claim may go without a heartbeat before it can be reclaimed
+func (m *MongoDBManager) SetClaimStaleAfter(staleAfter time.Duration) {
+	if staleAfter > 0 {
+		m.claimStaleAfter = staleAfter
+	}
+}
+
+// ClaimBatch claims a batch for a worker. It succeeds when the batch is unclaimed,
+// released, already held by the same worker, or held by a worker whose heartbeat is stale.
+func (m *MongoDBManager) ClaimBatch(batchID, workerID string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.M{
+		"_id": batchID,
+		"$or": []bson.M{
+			{"status": bson.M{"$ne": claimStatusActive}},
+			{"worker_id": workerID},
+			{"heartbeat_at": bson.M{"$lt": now.Add(-m.claimStaleAfter)}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"worker_id":    workerID,
+			"status":       claimStatusActive,
+			"claimed_at":   now,
+			"heartbeat_at": now,
+		},
+		"$unset": bson.M{"released_at": ""},
+	}
+
+	var previous BatchClaimDocument
+	err := m.claims.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetUpsert(true)).Decode(&previous)
+	switch {
+	case err == mongo.ErrNoDocuments:
+		// Upserted a new claim
+		return true, nil
+	case mongo.IsDuplicateKeyError(err):
+		// The batch exists but is actively held by another worker
+		return false, nil
+	case err != nil:
+		return false, fmt.Errorf("failed to claim batch %s: %w", batchID, err)
+	}
+
+	if previous.Status == claimStatusActive && previous.WorkerID != workerID {
+		log.L().Warn("Reclaimed stale batch", zap.String("event", "batch_reclaimed"), zap.String("batch_id", batchID),
+			zap.String("previous_worker", previous.WorkerID), zap.String("worker_id", workerID),
+			zap.Time("last_heartbeat", previous.HeartbeatAt))
+	}
+	return true, nil
+}
+
+// HeartbeatBatch refreshes the heartbeat of a batch claim held by a worker
+func (m *MongoDBManager) HeartbeatBatch(batchID, workerID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := m.claims.UpdateOne(ctx,
+		bson.M{"_id": batchID, "worker_id": workerID, "status": claimStatusActive},
+		bson.M{"$set": bson.M{"heartbeat_at": time.Now()}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to heartbeat batch %s: %w", batchID, err)
+	}
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("batch %s is not claimed by %s", batchID, workerID)
+	}
+	return nil
+}
+
+// ReleaseBatch marks a batch claim held by a worker as released
+func (m *MongoDBManager) ReleaseBatch(batchID, workerID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	_, err := m.claims.UpdateOne(ctx,
+		bson.M{"_id": batchID, "worker_id": workerID, "status": claimStatusActive},
+		bson.M{"$set": bson.M{"status": claimStatusReleased, "released_at": now, "heartbeat_at": now}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to release batch %s: %w", batchID, err)
+	}
+	return nil
+}
+
+// GetBatchClaim returns the claim record for a batch, flagging active claims with a stale heartbeat
+func (m *MongoDBManager) GetBatchClaim(batchID string) (*BatchClaimDocument, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var claim BatchClaimDocument
+	err := m.claims.FindOne(ctx, bson.M{"_id": batchID}).Decode(&claim)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("no claim found for batch: %s", batchID)
+		}
+		return nil, fmt.Errorf("failed to get batch claim: %w", err)
+	}
+
+	claim.Stale = claim.Status == claimStatusActive && time.Since(claim.HeartbeatAt) > m.claimStaleAfter
+	return &claim, nil
+}
@@ -0,0 +1,202 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// claimDocument is the stored claim of a batch, as returned by the mock server
+func claimDocument(batchID, workerID, status string, heartbeat time.Time) bson.D {
+	return bson.D{
+		{Key: "_id", Value: batchID},
+		{Key: "worker_id", Value: workerID},
+		{Key: "status", Value: status},
+		{Key: "claimed_at", Value: heartbeat},
+		{Key: "heartbeat_at", Value: heartbeat},
+	}
+}
+
+// findAndModifyResponse is a findAndModify reply returning the document before the update,
+// or no document when the update upserted one
+func findAndModifyResponse(previous interface{}) bson.D {
+	return mtest.CreateSuccessResponse(bson.E{Key: "value", Value: previous})
+}
+
+// claimCutoff returns the heartbeat_at cutoff a ClaimBatch filter reclaims claims below
+func claimCutoff(mt *mtest.T, command bson.Raw) time.Time {
+	clauses, err := command.Lookup("query", "$or").Array().Values()
+	if err != nil {
+		mt.Fatalf("claim filter %v has no $or: %v", command.Lookup("query"), err)
+	}
+	for _, clause := range clauses {
+		if heartbeat, err := clause.Document().LookupErr("heartbeat_at"); err == nil {
+			return heartbeat.Document().Lookup("$lt").Time()
+		}
+	}
+	mt.Fatalf("claim filter %v has no stale heartbeat clause", command.Lookup("query"))
+	return time.Time{}
+}
+
+func TestClaimBatchLifecycle(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("first claim", func(mt *mtest.T) {
+		m := newTestManager()
+		m.claims = mt.Coll
+		mt.AddMockResponses(findAndModifyResponse(nil))
+
+		claimed, err := m.ClaimBatch("batch-1", "worker-a")
+		if err != nil || !claimed {
+			mt.Fatalf("ClaimBatch = %v, %v, want the unclaimed batch claimed", claimed, err)
+		}
+
+		event := mt.GetStartedEvent()
+		if event == nil || event.CommandName != "findAndModify" {
+			mt.Fatalf("sent %v, want findAndModify", event)
+		}
+		if !event.Command.Lookup("upsert").Boolean() {
+			mt.Error("claim is not an upsert, so an unclaimed batch could never be claimed")
+		}
+		set := event.Command.Lookup("update", "$set").Document()
+		if set.Lookup("worker_id").StringValue() != "worker-a" || set.Lookup("status").StringValue() != claimStatusActive {
+			mt.Errorf("claim sets %v, want worker-a active", set)
+		}
+	})
+
+	mt.Run("held by another worker", func(mt *mtest.T) {
+		m := newTestManager()
+		m.claims = mt.Coll
+		// The filter misses the active claim, so the upsert collides with its _id
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    11000,
+			Name:    "DuplicateKey",
+			Message: "E11000 duplicate key error collection: orwell.batch_claims index: _id_",
+		}))
+
+		claimed, err := m.ClaimBatch("batch-1", "worker-b")
+		if err != nil || claimed {
+			mt.Errorf("ClaimBatch of a held batch = %v, %v, want not claimed without error", claimed, err)
+		}
+	})
+
+	mt.Run("heartbeat", func(mt *mtest.T) {
+		m := newTestManager()
+		m.claims = mt.Coll
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		before := time.Now()
+		if err := m.HeartbeatBatch("batch-1", "worker-a"); err != nil {
+			mt.Fatalf("HeartbeatBatch returned error: %v", err)
+		}
+
+		event := mt.GetStartedEvent()
+		update := event.Command.Lookup("updates").Array().Index(0).Value().Document()
+		query := update.Lookup("q").Document()
+		if query.Lookup("worker_id").StringValue() != "worker-a" || query.Lookup("status").StringValue() != claimStatusActive {
+			mt.Errorf("heartbeat filter = %v, want the active claim of worker-a", query)
+		}
+		heartbeat := update.Lookup("u", "$set", "heartbeat_at").Time()
+		if heartbeat.Before(before.Truncate(time.Millisecond)) {
+			mt.Errorf("heartbeat_at = %v, want it extended to now", heartbeat)
+		}
+	})
+
+	mt.Run("heartbeat of a lost claim", func(mt *mtest.T) {
+		m := newTestManager()
+		m.claims = mt.Coll
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+
+		if err := m.HeartbeatBatch("batch-1", "worker-a"); err == nil {
+			mt.Error("HeartbeatBatch of a claim held by another worker returned no error")
+		}
+	})
+
+	mt.Run("release", func(mt *mtest.T) {
+		m := newTestManager()
+		m.claims = mt.Coll
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		if err := m.ReleaseBatch("batch-1", "worker-a"); err != nil {
+			mt.Fatalf("ReleaseBatch returned error: %v", err)
+		}
+
+		event := mt.GetStartedEvent()
+		update := event.Command.Lookup("updates").Array().Index(0).Value().Document()
+		if owner := update.Lookup("q", "worker_id").StringValue(); owner != "worker-a" {
+			mt.Errorf("release filter owner = %q, want only worker-a's claim released", owner)
+		}
+		set := update.Lookup("u", "$set").Document()
+		if set.Lookup("status").StringValue() != claimStatusReleased {
+			mt.Errorf("release sets %v, want status released", set)
+		}
+		if _, err := set.LookupErr("released_at"); err != nil {
+			mt.Errorf("release sets %v, want released_at", set)
+		}
+	})
+
+	mt.Run("claim after release", func(mt *mtest.T) {
+		m := newTestManager()
+		m.claims = mt.Coll
+		mt.AddMockResponses(findAndModifyResponse(claimDocument("batch-1", "worker-a", claimStatusReleased, time.Now())))
+
+		if claimed, err := m.ClaimBatch("batch-1", "worker-b"); err != nil || !claimed {
+			mt.Errorf("ClaimBatch of a released batch = %v, %v, want it claimed", claimed, err)
+		}
+	})
+}
+
+func TestClaimBatchReclaimsStaleClaim(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("stale heartbeat", func(mt *mtest.T) {
+		m := newTestManager()
+		m.claims = mt.Coll
+		m.SetClaimStaleAfter(30 * time.Second)
+
+		// worker-a stopped heartbeating a minute ago, past the 30s claim_stale_after
+		lastHeartbeat := time.Now().Add(-time.Minute)
+		mt.AddMockResponses(findAndModifyResponse(claimDocument("batch-1", "worker-a", claimStatusActive, lastHeartbeat)))
+
+		before := time.Now()
+		claimed, err := m.ClaimBatch("batch-1", "worker-b")
+		if err != nil || !claimed {
+			mt.Fatalf("ClaimBatch of a stale claim = %v, %v, want it reclaimed", claimed, err)
+		}
+
+		event := mt.GetStartedEvent()
+		cutoff := claimCutoff(mt, event.Command)
+		if want := before.Add(-30 * time.Second); cutoff.Before(want.Add(-time.Second)) || cutoff.After(want.Add(time.Second)) {
+			mt.Errorf("claims reclaimed below heartbeat %v, want claim_stale_after before now (%v)", cutoff, want)
+		}
+		if !lastHeartbeat.Before(cutoff) {
+			mt.Errorf("last heartbeat %v is not below the cutoff %v", lastHeartbeat, cutoff)
+		}
+		if owner := event.Command.Lookup("update", "$set", "worker_id").StringValue(); owner != "worker-b" {
+			mt.Errorf("reclaim sets worker_id %q, want worker-b", owner)
+		}
+	})
+
+	mt.Run("claim reported stale", func(mt *mtest.T) {
+		m := newTestManager()
+		m.claims = mt.Coll
+		m.SetClaimStaleAfter(30 * time.Second)
+
+		ns := mt.DB.Name() + "." + mt.Coll.Name()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, claimDocument("batch-1", "worker-a", claimStatusActive, time.Now().Add(-time.Minute))),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, claimDocument("batch-2", "worker-a", claimStatusActive, time.Now())),
+		)
+
+		stale, err := m.GetBatchClaim("batch-1")
+		if err != nil || !stale.Stale {
+			mt.Errorf("GetBatchClaim of a claim past claim_stale_after = %+v, %v, want it stale", stale, err)
+		}
+		fresh, err := m.GetBatchClaim("batch-2")
+		if err != nil || fresh.Stale || fresh.WorkerID != "worker-a" {
+			mt.Errorf("GetBatchClaim of a heartbeated claim = %+v, %v, want it held by worker-a", fresh, err)
+		}
+	})
+}
@@ -0,0 +1,181 @@
+package database
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// compressString gzips a string value
+func compressString(value string) ([]byte, error) {
+	return compressBytes([]byte(value))
+}
+
+// decompressString reverses compressString
+func decompressString(data []byte) (string, error) {
+	raw, err := decompressBytes(data)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+// compressMetadata serializes metadata to JSON and gzips it
+func compressMetadata(metadata map[string]interface{}) ([]byte, error) {
+	raw, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+	return compressBytes(raw)
+}
+
+// decompressMetadata reverses compressMetadata
+func decompressMetadata(data []byte) (map[string]interface{}, error) {
+	raw, err := decompressBytes(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(raw, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+	}
+	return metadata, nil
+}
+
+// compressBytes gzips a byte slice
+func compressBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+
+	if _, err := writer.Write(data); err != nil {
+		writer.Close()
+		return nil, fmt.Errorf("failed to compress data: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finalize compressed data: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decompressBytes gunzips a byte slice
+func decompressBytes(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open compressed data: %w", err)
+	}
+	defer reader.Close()
+
+	raw, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress data: %w", err)
+	}
+	return raw, nil
+}
+
+// compressPortDocument compresses the banners and metadata of a port document in place.
+// Values smaller than the configured minimum size are stored as-is.
+func (m *MongoDBManager) compressPortDocument(doc *PortDocument) error {
+	if len(doc.Banner) >= m.compressionMinSize {
+		data, err := compressString(doc.Banner)
+		if err != nil {
+			return err
+		}
+		doc.CompressedBanner = data
+		doc.Banner = ""
+		doc.Compressed = true
+	}
+
+	if len(doc.Metadata) > 0 {
+		data, err := compressMetadata(doc.Metadata)
+		if err != nil {
+			return err
+		}
+		if len(data) >= m.compressionMinSize {
+			doc.CompressedMetadata = data
+			doc.Metadata = nil
+			doc.Compressed = true
+		}
+	}
+
+	if doc.BannerInfo != nil {
+		if len(doc.BannerInfo.RawBanner) >= m.compressionMinSize {
+			data, err := compressString(doc.BannerInfo.RawBanner)
+			if err != nil {
+				return err
+			}
+			doc.BannerInfo.CompressedRawBanner = data
+			doc.BannerInfo.RawBanner = ""
+			doc.Compressed = true
+		}
+
+		if len(doc.BannerInfo.Metadata) > 0 {
+			data, err := compressMetadata(doc.BannerInfo.Metadata)
+			if err != nil {
+				return err
+			}
+			if len(data) >= m.compressionMinSize {
+				doc.BannerInfo.CompressedMetadata = data
+				doc.BannerInfo.Metadata = nil
+				doc.Compressed = true
+			}
+		}
+	}
+
+	return nil
+}
+
+// decompressDocument restores compressed port fields of a scan result document in place
+func (m *MongoDBManager) decompressDocument(doc *ScanResultDocument) error {
+	for i := range doc.Ports {
+		port := &doc.Ports[i]
+		if !port.Compressed {
+			continue
+		}
+
+		if len(port.CompressedBanner) > 0 {
+			banner, err := decompressString(port.CompressedBanner)
+			if err != nil {
+				return fmt.Errorf("port %d banner: %w", port.Number, err)
+			}
+			port.Banner = banner
+			port.CompressedBanner = nil
+		}
+
+		if len(port.CompressedMetadata) > 0 {
+			metadata, err := decompressMetadata(port.CompressedMetadata)
+			if err != nil {
+				return fmt.Errorf("port %d metadata: %w", port.Number, err)
+			}
+			port.Metadata = metadata
+			port.CompressedMetadata = nil
+		}
+
+		if port.BannerInfo != nil {
+			if len(port.BannerInfo.CompressedRawBanner) > 0 {
+				rawBanner, err := decompressString(port.BannerInfo.CompressedRawBanner)
+				if err != nil {
+					return fmt.Errorf("port %d raw banner: %w", port.Number, err)
+				}
+				port.BannerInfo.RawBanner = rawBanner
+				port.BannerInfo.CompressedRawBanner = nil
+			}
+
+			if len(port.BannerInfo.CompressedMetadata) > 0 {
+				metadata, err := decompressMetadata(port.BannerInfo.CompressedMetadata)
+				if err != nil {
+					return fmt.Errorf("port %d banner metadata: %w", port.Number, err)
+				}
+				port.BannerInfo.Metadata = metadata
+				port.BannerInfo.CompressedMetadata = nil
+			}
+		}
+
+		port.Compressed = false
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package database
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"port-scanner/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// largeBannerResult returns a result with one open port carrying a large HTTP response banner
+func largeBannerResult() *domain.ScanResult {
+	banner := "HTTP/1.1 200 OK\r\nServer: nginx\r\n\r\n" + strings.Repeat("<p>Welcome to the example page</p>\n", 500)
+
+	result := domain.NewScanResult("203.0.113.7", "batch-1", "worker-1")
+	port := domain.NewPort(80)
+	port.Status = domain.PortStatusOpen
+	port.Service = "http"
+	port.Banner = banner
+	port.BannerInfo = &domain.BannerInfo{
+		RawBanner: banner,
+		Service:   "http",
+		Protocol:  "tcp",
+		Metadata: map[string]interface{}{
+			"headers": strings.Repeat("X-Padding: value\n", 200),
+			"status":  "200 OK",
+		},
+	}
+	result.AddPort(port)
+	result.SetCompleted()
+	return result
+}
+
+func TestCompressionRoundTripsLargeBanners(t *testing.T) {
+	result := largeBannerResult()
+
+	plain := newTestManager()
+	plain.SetMaxBannerLength(0)
+	uncompressed := plain.convertScanResultToDocument(result)
+
+	compressing := newTestManager()
+	compressing.SetMaxBannerLength(0)
+	compressing.SetCompression(true, 32)
+	stored := compressing.convertScanResultToDocument(result)
+
+	port := stored.Ports[0]
+	if !port.Compressed || port.Banner != "" || len(port.CompressedBanner) == 0 {
+		t.Fatal("large banner was not stored compressed")
+	}
+	if port.BannerInfo.RawBanner != "" || port.BannerInfo.CompressedMetadata == nil {
+		t.Fatal("large raw banner or metadata was not stored compressed")
+	}
+
+	plainSize, err := bson.Marshal(uncompressed)
+	if err != nil {
+		t.Fatalf("failed to encode uncompressed document: %v", err)
+	}
+	storedSize, err := bson.Marshal(stored)
+	if err != nil {
+		t.Fatalf("failed to encode compressed document: %v", err)
+	}
+	if len(storedSize) >= len(plainSize) {
+		t.Errorf("compressed document is %d bytes, uncompressed %d", len(storedSize), len(plainSize))
+	}
+
+	// Read back the stored document as GetScanResult does
+	var read ScanResultDocument
+	if err := bson.Unmarshal(storedSize, &read); err != nil {
+		t.Fatalf("failed to decode stored document: %v", err)
+	}
+	if err := compressing.decompressDocument(&read); err != nil {
+		t.Fatalf("decompressDocument returned error: %v", err)
+	}
+
+	original := result.Ports[0]
+	got := read.Ports[0]
+	if got.Compressed {
+		t.Error("read port is still flagged compressed")
+	}
+	if got.Banner != original.Banner {
+		t.Error("decompressed banner differs from the original")
+	}
+	if got.BannerInfo.RawBanner != original.BannerInfo.RawBanner {
+		t.Error("decompressed raw banner differs from the original")
+	}
+	if !reflect.DeepEqual(got.BannerInfo.Metadata, original.BannerInfo.Metadata) {
+		t.Errorf("decompressed metadata = %v, want %v", got.BannerInfo.Metadata, original.BannerInfo.Metadata)
+	}
+}
+
+func TestCompressionSkipsSmallValues(t *testing.T) {
+	m := newTestManager()
+	m.SetCompression(true, 1024)
+
+	doc := &PortDocument{
+		Number:     22,
+		Banner:     "SSH-2.0-OpenSSH_8.9p1",
+		BannerInfo: &BannerInfoDocument{RawBanner: "SSH-2.0-OpenSSH_8.9p1", Metadata: map[string]interface{}{"software": "OpenSSH"}},
+		Metadata:   map[string]interface{}{"note": "jump host"},
+	}
+	if err := m.compressPortDocument(doc); err != nil {
+		t.Fatalf("compressPortDocument returned error: %v", err)
+	}
+	if doc.Compressed || doc.CompressedBanner != nil || doc.BannerInfo.CompressedMetadata != nil || doc.CompressedMetadata != nil {
+		t.Error("values below the minimum size were compressed")
+	}
+	if doc.Banner != "SSH-2.0-OpenSSH_8.9p1" {
+		t.Errorf("banner = %q, want it unchanged", doc.Banner)
+	}
+}
+
+func TestDecompressRejectsCorruptData(t *testing.T) {
+	doc := &ScanResultDocument{Ports: []PortDocument{{Number: 80, Compressed: true, CompressedBanner: []byte("not gzip")}}}
+	if err := newTestManager().decompressDocument(doc); err == nil {
+		t.Error("decompressDocument accepted corrupt data")
+	}
+}
+
+func TestCompressionRoundTripsPortMetadata(t *testing.T) {
+	m := newTestManager()
+	m.SetCompression(true, 32)
+
+	metadata := map[string]interface{}{
+		"tls_chain": strings.Repeat("MIIDdzCCAl+gAwIBAgIEAgAAuTANBgkqhkiG9w0BAQUFADBa", 40),
+		"note":      "seen behind a load balancer",
+	}
+	doc := &ScanResultDocument{Ports: []PortDocument{{Number: 443, Status: "open", Metadata: metadata}}}
+	if err := m.compressPortDocument(&doc.Ports[0]); err != nil {
+		t.Fatalf("compressPortDocument returned error: %v", err)
+	}
+	if port := doc.Ports[0]; !port.Compressed || port.Metadata != nil || len(port.CompressedMetadata) == 0 {
+		t.Fatal("large port metadata was not stored compressed")
+	}
+
+	// Read back the stored document as GetScanResult does
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		t.Fatalf("failed to encode document: %v", err)
+	}
+	var read ScanResultDocument
+	if err := bson.Unmarshal(raw, &read); err != nil {
+		t.Fatalf("failed to decode stored document: %v", err)
+	}
+	if err := m.decompressDocument(&read); err != nil {
+		t.Fatalf("decompressDocument returned error: %v", err)
+	}
+
+	if got := read.Ports[0]; got.Compressed || got.CompressedMetadata != nil || !reflect.DeepEqual(got.Metadata, metadata) {
+		t.Errorf("decompressed port metadata = %v, want %v", got.Metadata, metadata)
+	}
+}
@@ -0,0 +1,146 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"port-scanner/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// dedupCollection applies write models to in-memory documents the way MongoDB applies an
+// upsert: the first document matching the filter is updated, otherwise one is inserted
+type dedupCollection struct {
+	docs []bson.M
+}
+
+func (c *dedupCollection) write(t *testing.T, model *mongo.UpdateOneModel) {
+	t.Helper()
+	filter := model.Filter.(bson.M)
+	for i, doc := range c.docs {
+		if matchesDedupFilter(t, doc, filter) {
+			c.docs[i] = applyUpdate(t, doc, model.Update)
+			return
+		}
+	}
+
+	operators := model.Update.(bson.M)
+	doc := bson.M{"ip": filter["ip"]}
+	for _, operator := range []string{"$setOnInsert", "$set"} {
+		fields, _ := operators[operator].(bson.M)
+		for field, value := range fields {
+			doc[field] = value
+		}
+	}
+	c.docs = append(c.docs, doc)
+}
+
+// matchesDedupFilter evaluates the IP and created_at $gte conditions of a dedup filter
+func matchesDedupFilter(t *testing.T, doc bson.M, filter bson.M) bool {
+	t.Helper()
+	if doc["ip"] != filter["ip"] {
+		return false
+	}
+	condition, ok := filter["created_at"].(bson.M)
+	if !ok {
+		return true
+	}
+	cutoff := condition["$gte"].(time.Time)
+	created := doc["created_at"].(primitive.DateTime).Time()
+	return !created.Before(cutoff)
+}
+
+func TestDedupWindowCollapsesRepeatedScans(t *testing.T) {
+	m := newTestManager()
+	m.SetDedupWindow(time.Minute)
+	collection := &dedupCollection{}
+
+	save := func(result *domain.ScanResult) {
+		model, err := m.writeModel(result)
+		if err != nil {
+			t.Fatalf("writeModel returned error: %v", err)
+		}
+		collection.write(t, model)
+	}
+
+	save(scannedResult("batch-1", 22))
+	save(scannedResult("batch-2", 22, 443))
+	if len(collection.docs) != 1 {
+		t.Fatalf("%d documents after two scans within the window, want 1", len(collection.docs))
+	}
+	if got := collection.docs[0]["open_ports"]; got != int32(2) {
+		t.Errorf("open_ports = %v, want the later scan's 2", got)
+	}
+
+	// Once the document is older than the window, the next scan adds another
+	collection.docs[0]["created_at"] = primitive.NewDateTimeFromTime(time.Now().Add(-2 * time.Minute))
+	save(scannedResult("batch-3", 22))
+	if len(collection.docs) != 2 {
+		t.Fatalf("%d documents after a scan beyond the window, want 2", len(collection.docs))
+	}
+
+	// Another IP within the window is its own document
+	other := scannedResult("batch-3", 80)
+	other.IP = "1.1.1.1"
+	save(other)
+	if len(collection.docs) != 3 {
+		t.Errorf("%d documents after scanning another IP, want 3", len(collection.docs))
+	}
+}
+
+func TestDedupWindowFilter(t *testing.T) {
+	m := newTestManager()
+	m.SetDedupWindow(10 * time.Minute)
+
+	before := time.Now().Add(-10 * time.Minute)
+	model, err := m.writeModel(scannedResult("batch-1", 22))
+	if err != nil {
+		t.Fatalf("writeModel returned error: %v", err)
+	}
+	filter := model.Filter.(bson.M)
+	cutoff := filter["created_at"].(bson.M)["$gte"].(time.Time)
+	if filter["ip"] != "8.8.8.8" || cutoff.Before(before) || cutoff.After(time.Now().Add(-10*time.Minute)) {
+		t.Errorf("filter = %v, want 8.8.8.8 created within the last 10 minutes", filter)
+	}
+	if !*model.Upsert {
+		t.Error("dedup write is not an upsert")
+	}
+
+	// Upsert mode keeps one document per IP regardless of the window
+	if err := m.SetWriteMode(WriteModeUpsert, nil); err != nil {
+		t.Fatalf("SetWriteMode returned error: %v", err)
+	}
+	model, _ = m.writeModel(scannedResult("batch-1", 22))
+	if !reflect.DeepEqual(model.Filter, bson.M{"ip": "8.8.8.8"}) {
+		t.Errorf("upsert filter = %v, want only the IP", model.Filter)
+	}
+}
+
+func TestSaveScanResultWithoutDedupWindowInserts(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("windows", func(mt *mtest.T) {
+		m := newTestManager()
+		m.collection = mt.Coll
+
+		for _, window := range []time.Duration{0, time.Minute} {
+			m.SetDedupWindow(window)
+			mt.AddMockResponses(mtest.CreateSuccessResponse())
+			if err := m.SaveScanResult(scannedResult("batch-1", 22)); err != nil {
+				mt.Fatalf("SaveScanResult returned error: %v", err)
+			}
+		}
+
+		want := []string{"insert", "update"}
+		for _, command := range want {
+			if event := mt.GetStartedEvent(); event == nil || event.CommandName != command {
+				mt.Errorf("sent %v, want %v for windows 0 and 1m", event, want)
+			}
+		}
+	})
+}
@@ -0,0 +1,174 @@
+package database
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"port-scanner/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestConvertScanResultStoresTagsAndPriority(t *testing.T) {
+	message := &domain.QueueMessage{
+		IPs:      []string{"8.8.8.8"},
+		BatchID:  "batch-1",
+		Tags:     map[string]string{"campaign": "q3-audit", "source": "cidr"},
+		Priority: 5,
+	}
+	result := domain.NewScanResult("8.8.8.8", message.BatchID, "worker-1")
+	message.ApplyTo(result)
+	result.SetCompleted()
+
+	doc := newTestManager().convertScanResultToDocument(result)
+	tags, ok := doc.Metadata["tags"].(map[string]string)
+	if !ok || tags["campaign"] != "q3-audit" || tags["source"] != "cidr" {
+		t.Errorf("stored tags = %v, want the message's tags", doc.Metadata["tags"])
+	}
+	if doc.Metadata["priority"] != 5 {
+		t.Errorf("stored priority = %v, want 5", doc.Metadata["priority"])
+	}
+
+	untagged := newTestManager().convertScanResultToDocument(domain.NewScanResult("1.1.1.1", "batch-1", "worker-1"))
+	if _, ok := untagged.Metadata["tags"]; ok {
+		t.Error("result without tags stored a tags field")
+	}
+	if _, ok := untagged.Metadata["priority"]; ok {
+		t.Error("result without priority stored a priority field")
+	}
+}
+
+func TestConvertScanResultTruncatesStoredBanners(t *testing.T) {
+	result := domain.NewScanResult("8.8.8.8", "batch-1", "worker-1")
+	port := domain.NewPort(80)
+	port.Status = domain.PortStatusOpen
+	port.Banner = strings.Repeat("h", 300)
+	port.BannerInfo = &domain.BannerInfo{RawBanner: strings.Repeat("h", 300), Metadata: map[string]interface{}{"module": "http"}}
+	result.AddPort(port)
+
+	manager := newTestManager()
+	manager.SetMaxBannerLength(100)
+	doc := manager.convertScanResultToDocument(result)
+
+	stored := doc.Ports[0]
+	if want := strings.Repeat("h", 100) + "...[truncated 200 bytes]"; stored.Banner != want || stored.BannerInfo.RawBanner != want {
+		t.Errorf("stored banners = %q, %q, want %q", stored.Banner, stored.BannerInfo.RawBanner, want)
+	}
+	if stored.BannerInfo.Metadata["banner_truncated"] != true || stored.BannerInfo.Metadata["module"] != "http" {
+		t.Errorf("stored banner metadata = %v, want it flagged and the module kept", stored.BannerInfo.Metadata)
+	}
+
+	// The scan result itself keeps the full banner
+	if len(port.BannerInfo.RawBanner) != 300 || port.BannerInfo.Metadata["banner_truncated"] != nil {
+		t.Errorf("domain banner was changed: %d bytes, metadata %v", len(port.BannerInfo.RawBanner), port.BannerInfo.Metadata)
+	}
+}
+
+func TestConvertScanResultCountsOpenFilteredPortsApart(t *testing.T) {
+	result := domain.NewScanResult("8.8.8.8", "batch-1", "worker-1")
+	for number, status := range map[int]domain.PortStatus{22: domain.PortStatusOpen, 161: domain.PortStatusOpenFiltered, 23: domain.PortStatusClosed} {
+		port := domain.NewPort(number)
+		port.Status = status
+		result.AddPort(port)
+	}
+
+	doc := newTestManager().convertScanResultToDocument(result)
+	if doc.OpenPorts != 1 || doc.OpenFilteredPorts != 1 || doc.TotalPorts != 3 {
+		t.Errorf("open, open|filtered, total = %d, %d, %d, want 1, 1, 3", doc.OpenPorts, doc.OpenFilteredPorts, doc.TotalPorts)
+	}
+	for _, port := range doc.Ports {
+		if port.Number == 161 && port.Status != "open|filtered" {
+			t.Errorf("stored status of port 161 = %q, want open|filtered", port.Status)
+		}
+	}
+}
+
+func TestConvertScanResultSetsExpiryFromRetention(t *testing.T) {
+	tests := map[string]time.Duration{
+		"transient": time.Hour,
+		"weekly":    7 * 24 * time.Hour,
+	}
+	for name, retention := range tests {
+		result := domain.NewScanResult("8.8.8.8", name, "worker-1")
+		result.Retention = retention
+
+		doc := newTestManager().convertScanResultToDocument(result)
+		if doc.ExpiresAt == nil {
+			t.Errorf("%s: expires_at not set for a %v retention", name, retention)
+			continue
+		}
+		if got := doc.ExpiresAt.Sub(doc.CreatedAt); got != retention {
+			t.Errorf("%s: expires_at is %v after created_at, want %v", name, got, retention)
+		}
+	}
+
+	kept := newTestManager().convertScanResultToDocument(domain.NewScanResult("8.8.8.8", "long-term", "worker-1"))
+	if kept.ExpiresAt != nil {
+		t.Errorf("expires_at = %v for a batch without retention, want none", kept.ExpiresAt)
+	}
+}
+
+func TestScanResultIndexesExpireDocumentsAtExpiresAt(t *testing.T) {
+	for _, index := range scanResultIndexes() {
+		keys, ok := index.Keys.(bson.D)
+		if !ok || len(keys) != 1 || keys[0].Key != "expires_at" {
+			continue
+		}
+		if index.Options.ExpireAfterSeconds == nil || *index.Options.ExpireAfterSeconds != 0 {
+			t.Errorf("expires_at index expires after %v seconds, want 0", index.Options.ExpireAfterSeconds)
+		}
+		return
+	}
+	t.Error("no TTL index on expires_at")
+}
+
+func TestConvertScanResultStoresWorkerID(t *testing.T) {
+	result := domain.NewScanResult("8.8.8.8", "batch-1", "scanner-eu-1")
+	result.SetCompleted()
+
+	doc := newTestManager().convertScanResultToDocument(result)
+	if doc.WorkerID != "scanner-eu-1" {
+		t.Errorf("stored worker_id = %q, want scanner-eu-1", doc.WorkerID)
+	}
+	if rebuilt := doc.ToScanResult(); rebuilt.WorkerID != "scanner-eu-1" {
+		t.Errorf("rebuilt worker ID = %q, want scanner-eu-1", rebuilt.WorkerID)
+	}
+}
+
+func TestConvertScanResultStoresConfigSnapshot(t *testing.T) {
+	config := domain.NewDefaultScanConfig()
+	config.PortRange = []int{22, 443}
+	config.Concurrency = 12
+	result := domain.NewScanResult("8.8.8.8", "batch-1", "worker-1")
+	result.SetMetadata("scan_config", config.Snapshot())
+	result.SetCompleted()
+
+	doc := newTestManager().convertScanResultToDocument(result)
+	if doc.Metadata["source"] != "port-scanner" {
+		t.Errorf("source = %v, want port-scanner alongside the snapshot", doc.Metadata["source"])
+	}
+
+	// The snapshot survives the round trip through BSON
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		t.Fatalf("failed to marshal document: %v", err)
+	}
+	var stored struct {
+		Metadata struct {
+			ScanConfig struct {
+				PortCount   int    `bson:"port_count"`
+				Concurrency int    `bson:"concurrency"`
+				Timeout     string `bson:"connect_timeout"`
+				Technique   string `bson:"technique"`
+			} `bson:"scan_config"`
+		} `bson:"metadata"`
+	}
+	if err := bson.Unmarshal(data, &stored); err != nil {
+		t.Fatalf("failed to unmarshal document: %v", err)
+	}
+	snapshot := stored.Metadata.ScanConfig
+	if snapshot.PortCount != 2 || snapshot.Concurrency != 12 || snapshot.Timeout != config.ConnectTimeout.String() || snapshot.Technique != domain.TechniqueConnect {
+		t.Errorf("stored scan_config = %+v, want 2 ports, concurrency 12, %v, connect", snapshot, config.ConnectTimeout)
+	}
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"port-scanner/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Ensure MongoDBManager implements ScanHistory interface
+var _ domain.ScanHistory = (*MongoDBManager)(nil)
+
+// LastOpenPorts returns the open ports and versions from the most recent completed scan of an IP
+func (m *MongoDBManager) LastOpenPorts(ip string) (map[int]string, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := options.FindOne().
+		SetSort(bson.M{"created_at": -1}).
+		SetProjection(bson.M{"ports.number": 1, "ports.status": 1, "ports.version": 1})
+
+	var doc ScanResultDocument
+	err := m.collection.FindOne(ctx, bson.M{"ip": ip, "status": string(domain.ScanStatusCompleted)}, opts).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get previous scan of %s: %w", ip, err)
+	}
+
+	ports := make(map[int]string)
+	for _, port := range doc.Ports {
+		if port.Status == string(domain.PortStatusOpen) {
+			ports[port.Number] = port.Version
+		}
+	}
+	return ports, true, nil
+}
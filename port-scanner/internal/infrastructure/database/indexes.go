@@ -0,0 +1,149 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"port-scanner/pkg/log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+)
+
+// MongoDB error codes of an index whose name or keys clash with an existing one, and of a
+// missing index
+const (
+	errCodeIndexOptionsConflict  = 85
+	errCodeIndexKeySpecsConflict = 86
+	errCodeIndexNotFound         = 27
+)
+
+// IndexKey is a field of an index with its order (1, -1) or type (e.g. "text")
+type IndexKey struct {
+	Field string      `json:"field"`
+	Order interface{} `json:"order"`
+}
+
+// IndexInfo describes an index present in the database
+type IndexInfo struct {
+	Collection         string     `json:"collection"`
+	Name               string     `json:"name"`
+	Keys               []IndexKey `json:"keys"`
+	Unique             bool       `json:"unique,omitempty"`
+	ExpireAfterSeconds *int32     `json:"expire_after_seconds,omitempty"`
+}
+
+// indexedCollection pairs a collection with the indexes the service creates on it
+type indexedCollection struct {
+	collection *mongo.Collection
+	indexes    []mongo.IndexModel
+}
+
+// indexedCollections returns the collections the service indexes
+func (m *MongoDBManager) indexedCollections() []indexedCollection {
+	return []indexedCollection{
+		{m.collection, scanResultIndexes()},
+		{m.annotations, annotationIndexes()},
+		{m.outbox, outboxIndexes()},
+		{m.analysis, analysisIndexes()},
+		{m.batchProgress, batchProgressIndexes()},
+	}
+}
+
+// ListIndexes returns the indexes present on the collections the service indexes, including
+// the _id index and any created outside the service
+func (m *MongoDBManager) ListIndexes() ([]IndexInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var indexes []IndexInfo
+	for _, indexed := range m.indexedCollections() {
+		specs, err := indexed.collection.Indexes().ListSpecifications(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list indexes of %s: %w", indexed.collection.Name(), err)
+		}
+
+		for _, spec := range specs {
+			var keys bson.D
+			if err := bson.Unmarshal(spec.KeysDocument, &keys); err != nil {
+				return nil, fmt.Errorf("failed to decode keys of index %s: %w", spec.Name, err)
+			}
+			info := IndexInfo{
+				Collection:         indexed.collection.Name(),
+				Name:               spec.Name,
+				Keys:               make([]IndexKey, 0, len(keys)),
+				Unique:             spec.Unique != nil && *spec.Unique,
+				ExpireAfterSeconds: spec.ExpireAfterSeconds,
+			}
+			for _, key := range keys {
+				info.Keys = append(info.Keys, IndexKey{Field: key.Key, Order: key.Value})
+			}
+			indexes = append(indexes, info)
+		}
+	}
+	return indexes, nil
+}
+
+// RebuildIndexes creates the service's indexes on every collection it indexes. Indexes that
+// already exist as defined are left alone, so rebuilding twice changes nothing; an index whose
+// definition changed is dropped and created again. It returns the collections whose indexes
+// were recreated.
+func (m *MongoDBManager) RebuildIndexes() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	recreated := []string{}
+	var errs []error
+	for _, indexed := range m.indexedCollections() {
+		name := indexed.collection.Name()
+
+		_, err := indexed.collection.Indexes().CreateMany(ctx, indexed.indexes)
+		if err == nil {
+			continue
+		}
+		if !isIndexConflict(err) {
+			errs = append(errs, fmt.Errorf("failed to create indexes of %s: %w", name, err))
+			continue
+		}
+
+		// An index differs from its definition: drop the service's indexes and create them again
+		if err := dropIndexes(ctx, indexed); err != nil {
+			errs = append(errs, fmt.Errorf("failed to drop indexes of %s: %w", name, err))
+			continue
+		}
+		if _, err := indexed.collection.Indexes().CreateMany(ctx, indexed.indexes); err != nil {
+			errs = append(errs, fmt.Errorf("failed to recreate indexes of %s: %w", name, err))
+			continue
+		}
+		recreated = append(recreated, name)
+		log.L().Info("Indexes recreated", zap.String("event", "indexes_recreated"), zap.String("collection", name))
+	}
+
+	return recreated, errors.Join(errs...)
+}
+
+// dropIndexes drops the service's indexes of a collection by name, skipping missing ones
+func dropIndexes(ctx context.Context, indexed indexedCollection) error {
+	for _, model := range indexed.indexes {
+		if model.Options == nil || model.Options.Name == nil {
+			continue
+		}
+		_, err := indexed.collection.Indexes().DropOne(ctx, *model.Options.Name)
+		var serverErr mongo.ServerError
+		if err != nil && !(errors.As(err, &serverErr) && serverErr.HasErrorCode(errCodeIndexNotFound)) {
+			return err
+		}
+	}
+	return nil
+}
+
+// isIndexConflict reports whether index creation failed because an index exists under the
+// same name or keys with a different definition
+func isIndexConflict(err error) bool {
+	var serverErr mongo.ServerError
+	return errors.As(err, &serverErr) &&
+		(serverErr.HasErrorCode(errCodeIndexOptionsConflict) || serverErr.HasErrorCode(errCodeIndexKeySpecsConflict))
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// newIndexedManager returns a test manager whose indexed collections live in the mock database
+func newIndexedManager(mt *mtest.T) *MongoDBManager {
+	m := newTestManager()
+	m.collection = mt.Coll
+	m.annotations = mt.DB.Collection("annotations")
+	m.outbox = mt.DB.Collection("outbox")
+	m.analysis = mt.DB.Collection("service_analysis")
+	m.batchProgress = mt.DB.Collection("batch_progress")
+	return m
+}
+
+// startedCommands returns the names of the commands sent since the last call
+func startedCommands(mt *mtest.T) []string {
+	var names []string
+	for event := mt.GetStartedEvent(); event != nil; event = mt.GetStartedEvent() {
+		names = append(names, event.CommandName)
+	}
+	return names
+}
+
+func TestListIndexesReportsNamesAndKeys(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("list", func(mt *mtest.T) {
+		m := newIndexedManager(mt)
+
+		responses := []bson.D{mtest.CreateCursorResponse(0, mt.DB.Name()+"."+mt.Coll.Name(), mtest.FirstBatch,
+			bson.D{{Key: "v", Value: int32(2)}, {Key: "key", Value: bson.D{{Key: "_id", Value: int32(1)}}}, {Key: "name", Value: "_id_"}},
+			bson.D{{Key: "v", Value: int32(2)}, {Key: "key", Value: bson.D{{Key: "ip", Value: int32(1)}, {Key: "created_at", Value: int32(-1)}}}, {Key: "name", Value: "ip_created_at_idx"}},
+		)}
+		for _, collection := range []string{"annotations", "outbox", "service_analysis", "batch_progress"} {
+			responses = append(responses, mtest.CreateCursorResponse(0, mt.DB.Name()+"."+collection, mtest.FirstBatch))
+		}
+		mt.AddMockResponses(responses...)
+
+		indexes, err := m.ListIndexes()
+		if err != nil {
+			mt.Fatalf("ListIndexes returned error: %v", err)
+		}
+		if len(indexes) != 2 {
+			mt.Fatalf("listed %d indexes, want 2: %+v", len(indexes), indexes)
+		}
+
+		got := indexes[1]
+		if got.Collection != mt.Coll.Name() || got.Name != "ip_created_at_idx" {
+			mt.Errorf("index = %s on %s, want ip_created_at_idx on %s", got.Name, got.Collection, mt.Coll.Name())
+		}
+		want := []IndexKey{{Field: "ip", Order: int32(1)}, {Field: "created_at", Order: int32(-1)}}
+		if !reflect.DeepEqual(got.Keys, want) {
+			mt.Errorf("keys = %+v, want %+v", got.Keys, want)
+		}
+		if commands := startedCommands(mt); len(commands) != 5 {
+			mt.Errorf("sent %v, want one listIndexes per indexed collection", commands)
+		}
+	})
+}
+
+func TestRebuildIndexesIsIdempotent(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("rebuild twice", func(mt *mtest.T) {
+		m := newIndexedManager(mt)
+
+		for rebuild := 0; rebuild < 2; rebuild++ {
+			for i := 0; i < 5; i++ {
+				mt.AddMockResponses(mtest.CreateSuccessResponse())
+			}
+			recreated, err := m.RebuildIndexes()
+			if err != nil {
+				mt.Fatalf("rebuild %d returned error: %v", rebuild, err)
+			}
+			if len(recreated) != 0 {
+				mt.Errorf("rebuild %d recreated %v, want nothing with indexes up to date", rebuild, recreated)
+			}
+			for _, command := range startedCommands(mt) {
+				if command != "createIndexes" {
+					mt.Errorf("rebuild %d sent %s, want only createIndexes", rebuild, command)
+				}
+			}
+		}
+	})
+}
+
+func TestRebuildIndexesRecreatesChangedIndexes(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("conflict", func(mt *mtest.T) {
+		m := newIndexedManager(mt)
+
+		// The results collection has an index whose definition changed
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code: errCodeIndexKeySpecsConflict, Name: "IndexKeySpecsConflict", Message: "index key specs conflict",
+		}))
+		drops := 0
+		for _, model := range scanResultIndexes() {
+			if model.Options != nil && model.Options.Name != nil {
+				mt.AddMockResponses(mtest.CreateSuccessResponse())
+				drops++
+			}
+		}
+		for i := 0; i < 5; i++ {
+			mt.AddMockResponses(mtest.CreateSuccessResponse())
+		}
+
+		recreated, err := m.RebuildIndexes()
+		if err != nil {
+			mt.Fatalf("RebuildIndexes returned error: %v", err)
+		}
+		if !reflect.DeepEqual(recreated, []string{mt.Coll.Name()}) {
+			mt.Errorf("recreated %v, want only %s", recreated, mt.Coll.Name())
+		}
+
+		counts := make(map[string]int)
+		for _, command := range startedCommands(mt) {
+			counts[command]++
+		}
+		if counts["dropIndexes"] != drops || counts["createIndexes"] != 6 {
+			mt.Errorf("sent %v, want %d dropIndexes and 6 createIndexes", counts, drops)
+		}
+	})
+}
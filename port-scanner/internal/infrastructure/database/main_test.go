@@ -0,0 +1,26 @@
+package database
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"port-scanner/internal/domain"
+	"port-scanner/pkg/log"
+)
+
+func TestMain(m *testing.M) {
+	log.InitLogger("database-test")
+	os.Exit(m.Run())
+}
+
+// newTestManager returns a manager with the defaults of NewMongoDBManager but no connection,
+// for exercising the document conversions
+func newTestManager() *MongoDBManager {
+	return &MongoDBManager{
+		compressionMinSize: 1024,
+		claimStaleAfter:    2 * time.Minute,
+		outboxAdoptAfter:   5 * time.Minute,
+		maxBannerLength:    domain.DefaultMaxBannerLength,
+	}
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"port-scanner/pkg/log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.uber.org/zap"
+)
+
+// CurrentSchemaVersion is the schema version written with new scan result documents
+const CurrentSchemaVersion = 2
+
+// schemaMigrations upgrades a document from version N (index) to N+1.
+// Documents written before versioning have no schema_version and are treated as version 1.
+var schemaMigrations = map[int]func(doc *ScanResultDocument){
+	1: migrateV1ToV2,
+}
+
+// migrateV1ToV2 fills fields that were added or derived after the initial schema
+func migrateV1ToV2(doc *ScanResultDocument) {
+	if doc.Metadata == nil {
+		doc.Metadata = map[string]interface{}{"source": "port-scanner"}
+	}
+
+	openPorts := 0
+	for i := range doc.Ports {
+		if doc.Ports[i].Status == "" {
+			doc.Ports[i].Status = "closed"
+		}
+		if doc.Ports[i].Status == "open" {
+			openPorts++
+		}
+	}
+	if doc.TotalPorts == 0 {
+		doc.TotalPorts = len(doc.Ports)
+	}
+	if doc.OpenPorts == 0 {
+		doc.OpenPorts = openPorts
+	}
+
+	if doc.ScanDuration == 0 && !doc.ScanEndTime.IsZero() {
+		doc.ScanDuration = doc.ScanEndTime.Sub(doc.ScanStartTime)
+	}
+	if doc.UpdatedAt.IsZero() {
+		doc.UpdatedAt = doc.CreatedAt
+	}
+}
+
+// migrateDocument upgrades a document to CurrentSchemaVersion in place.
+// It reports whether any migration was applied.
+func migrateDocument(doc *ScanResultDocument) bool {
+	if doc.SchemaVersion == 0 {
+		doc.SchemaVersion = 1
+	}
+
+	migrated := false
+	for doc.SchemaVersion < CurrentSchemaVersion {
+		migrate, ok := schemaMigrations[doc.SchemaVersion]
+		if !ok {
+			break
+		}
+		migrate(doc)
+		doc.SchemaVersion++
+		migrated = true
+	}
+
+	return migrated
+}
+
+// MigrateScanResults upgrades all stored scan results older than CurrentSchemaVersion
+// and returns the number of documents rewritten.
+func (m *MongoDBManager) MigrateScanResults() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	filter := bson.M{"$or": []bson.M{
+		{"schema_version": bson.M{"$exists": false}},
+		{"schema_version": bson.M{"$lt": CurrentSchemaVersion}},
+	}}
+
+	cursor, err := m.collection.Find(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find documents to migrate: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	migrated := 0
+	for cursor.Next(ctx) {
+		var doc ScanResultDocument
+		if err := cursor.Decode(&doc); err != nil {
+			return migrated, fmt.Errorf("failed to decode document: %w", err)
+		}
+
+		if !migrateDocument(&doc) {
+			continue
+		}
+
+		if _, err := m.collection.ReplaceOne(ctx, bson.M{"_id": doc.ID}, &doc); err != nil {
+			return migrated, fmt.Errorf("failed to update document %s: %w", doc.ID.Hex(), err)
+		}
+		migrated++
+	}
+	if err := cursor.Err(); err != nil {
+		return migrated, fmt.Errorf("failed to iterate documents: %w", err)
+	}
+
+	log.L().Info("Scan result schema migration completed", zap.String("event", "schema_migration_completed"),
+		zap.Int("migrated", migrated), zap.Int("schema_version", CurrentSchemaVersion))
+
+	return migrated, nil
+}
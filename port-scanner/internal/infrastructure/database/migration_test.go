@@ -0,0 +1,72 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMigrateDocumentAppliesDefaultsToV1Documents(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	created := start.Add(2 * time.Second)
+
+	// A document as written before schema_version, total_ports, metadata and port status existed
+	raw, err := bson.Marshal(bson.M{
+		"ip":              "10.0.0.1",
+		"is_up":           true,
+		"scan_start_time": start,
+		"scan_end_time":   start.Add(1500 * time.Millisecond),
+		"status":          "completed",
+		"batch_id":        "batch-1",
+		"worker_id":       "worker-1",
+		"ports": bson.A{
+			bson.M{"number": 22, "status": "open", "service": "ssh"},
+			bson.M{"number": 23, "service": "telnet"},
+		},
+		"created_at": created,
+	})
+	if err != nil {
+		t.Fatalf("bson.Marshal returned error: %v", err)
+	}
+
+	var doc ScanResultDocument
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		t.Fatalf("bson.Unmarshal returned error: %v", err)
+	}
+	if !migrateDocument(&doc) {
+		t.Fatal("migrateDocument reported no migration for a v1 document")
+	}
+
+	if doc.SchemaVersion != CurrentSchemaVersion {
+		t.Errorf("SchemaVersion = %d, want %d", doc.SchemaVersion, CurrentSchemaVersion)
+	}
+	if doc.Metadata["source"] != "port-scanner" {
+		t.Errorf("Metadata = %v, want the default source", doc.Metadata)
+	}
+	if doc.Ports[1].Status != "closed" {
+		t.Errorf("port without status migrated to %q, want closed", doc.Ports[1].Status)
+	}
+	if doc.TotalPorts != 2 || doc.OpenPorts != 1 {
+		t.Errorf("TotalPorts, OpenPorts = %d, %d, want 2, 1", doc.TotalPorts, doc.OpenPorts)
+	}
+	if doc.ScanDuration != 1500*time.Millisecond {
+		t.Errorf("ScanDuration = %v, want 1.5s", doc.ScanDuration)
+	}
+	if !doc.UpdatedAt.Equal(created) {
+		t.Errorf("UpdatedAt = %v, want CreatedAt %v", doc.UpdatedAt, created)
+	}
+}
+
+func TestMigrateDocumentLeavesCurrentDocumentsAlone(t *testing.T) {
+	doc := ScanResultDocument{
+		SchemaVersion: CurrentSchemaVersion,
+		Ports:         []PortDocument{{Number: 22}},
+	}
+	if migrateDocument(&doc) {
+		t.Error("migrateDocument reported a migration for a current document")
+	}
+	if doc.Metadata != nil || doc.TotalPorts != 0 || doc.Ports[0].Status != "" {
+		t.Errorf("current document was changed: %+v", doc)
+	}
+}
@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"fmt"
+	"math"
 	"time"
 
 	"port-scanner/internal/domain"
@@ -17,14 +18,33 @@ import (
 
 // MongoDBManager manages MongoDB operations for scan results
 type MongoDBManager struct {
-	client     *mongo.Client
-	database   *mongo.Database
-	collection *mongo.Collection
+	client             *mongo.Client
+	database           *mongo.Database
+	collection         *mongo.Collection
+	claims             *mongo.Collection
+	annotations        *mongo.Collection
+	templates          *mongo.Collection
+	targets            *mongo.Collection
+	outbox             *mongo.Collection
+	analysis           *mongo.Collection
+	batchProgress      *mongo.Collection
+	compressBanners    bool
+	compressionMinSize int
+	claimStaleAfter    time.Duration
+	outboxAdoptAfter   time.Duration
+	maxBannerLength    int
+	upsert             bool
+	updateFields       map[string]bool
+	dedupWindow        time.Duration
 }
 
+// Ensure MongoDBManager implements ResultSink interface
+var _ domain.ResultSink = (*MongoDBManager)(nil)
+
 // ScanResultDocument represents the MongoDB document structure for scan results
 type ScanResultDocument struct {
 	ID            primitive.ObjectID     `bson:"_id,omitempty" json:"id,omitempty"`
+	SchemaVersion int                    `bson:"schema_version" json:"schema_version"`
 	IP            string                 `bson:"ip" json:"ip"`
 	IsUp          bool                   `bson:"is_up" json:"is_up"`
 	PingTime      time.Duration          `bson:"ping_time" json:"ping_time"`
@@ -41,6 +61,12 @@ type ScanResultDocument struct {
 	CreatedAt     time.Time              `bson:"created_at" json:"created_at"`
 	UpdatedAt     time.Time              `bson:"updated_at" json:"updated_at"`
 	Metadata      map[string]interface{} `bson:"metadata,omitempty" json:"metadata,omitempty"`
+
+	OpenFilteredPorts int `bson:"open_filtered_ports" json:"open_filtered_ports"`
+
+	ExpiresAt *time.Time `bson:"expires_at,omitempty" json:"expires_at,omitempty"` // Removed by the TTL index once passed
+
+	ReverseDNS []string `bson:"reverse_dns,omitempty" json:"reverse_dns,omitempty"`
 }
 
 // PortDocument represents the MongoDB document structure for ports
@@ -54,6 +80,12 @@ type PortDocument struct {
 	ResponseTime time.Duration          `bson:"response_time" json:"response_time"`
 	BannerInfo   *BannerInfoDocument    `bson:"banner_info,omitempty" json:"banner_info,omitempty"`
 	Metadata     map[string]interface{} `bson:"metadata,omitempty" json:"metadata,omitempty"`
+	Error        string                 `bson:"error,omitempty" json:"error,omitempty"`
+
+	// Compression fields, populated only when banner compression is enabled
+	Compressed         bool   `bson:"compressed,omitempty" json:"-"`
+	CompressedBanner   []byte `bson:"compressed_banner,omitempty" json:"-"`
+	CompressedMetadata []byte `bson:"compressed_metadata,omitempty" json:"-"`
 }
 
 // BannerInfoDocument represents the MongoDB document structure for banner information
@@ -64,6 +96,12 @@ type BannerInfoDocument struct {
 	Version    string                 `bson:"version,omitempty" json:"version,omitempty"`
 	Confidence string                 `bson:"confidence" json:"confidence"`
 	Metadata   map[string]interface{} `bson:"metadata,omitempty" json:"metadata,omitempty"`
+
+	ConfidenceScore int `bson:"confidence_score" json:"confidence_score"`
+
+	// Compression fields, populated only when banner compression is enabled
+	CompressedRawBanner []byte `bson:"compressed_raw_banner,omitempty" json:"-"`
+	CompressedMetadata  []byte `bson:"compressed_metadata,omitempty" json:"-"`
 }
 
 // EnrichmentDocument represents the MongoDB document structure for enrichment data
@@ -110,65 +148,105 @@ func NewMongoDBManager(connectionString, databaseName, collectionName string) (*
 	}
 
 	database := client.Database(databaseName)
-	collection := database.Collection(collectionName)
 
-	// Create indexes for better performance
-	err = createIndexes(ctx, collection)
-	if err != nil {
-		log.L().Warn("Failed to create indexes", zap.Error(err))
+	log.L().Info("Connected to MongoDB", zap.String("database", databaseName), zap.String("collection", collectionName))
+
+	manager := &MongoDBManager{
+		client:             client,
+		database:           database,
+		collection:         database.Collection(collectionName),
+		claims:             database.Collection("batch_claims"),
+		annotations:        database.Collection("annotations"),
+		templates:          database.Collection("scan_templates"),
+		targets:            database.Collection("targets"),
+		outbox:             database.Collection("outbox"),
+		analysis:           database.Collection("service_analysis"),
+		batchProgress:      database.Collection("batch_progress"),
+		compressionMinSize: 1024,
+		claimStaleAfter:    2 * time.Minute,
+		outboxAdoptAfter:   5 * time.Minute,
+		maxBannerLength:    domain.DefaultMaxBannerLength,
 	}
 
-	log.L().Info("Connected to MongoDB", zap.String("database", databaseName), zap.String("collection", collectionName))
+	// Create indexes for better performance; a failure only slows queries down
+	for _, indexed := range manager.indexedCollections() {
+		if _, err := indexed.collection.Indexes().CreateMany(ctx, indexed.indexes); err != nil {
+			log.L().Warn("Failed to create indexes", zap.String("collection", indexed.collection.Name()), zap.Error(err))
+		}
+	}
+
+	return manager, nil
+}
 
-	return &MongoDBManager{
-		client:     client,
-		database:   database,
-		collection: collection,
-	}, nil
+// SetCompression enables gzip compression of banners and banner metadata larger than minSize bytes
+func (m *MongoDBManager) SetCompression(enabled bool, minSize int) {
+	m.compressBanners = enabled
+	if minSize > 0 {
+		m.compressionMinSize = minSize
+	}
+}
+
+// SetMaxBannerLength sets the size above which stored banners are truncated; 0 disables truncation
+func (m *MongoDBManager) SetMaxBannerLength(maxLength int) {
+	if maxLength >= 0 {
+		m.maxBannerLength = maxLength
+	}
 }
 
-// createIndexes creates necessary indexes for optimal performance
-func createIndexes(ctx context.Context, collection *mongo.Collection) error {
-	indexes := []mongo.IndexModel{
+// truncatePortDocument truncates oversized banners before storage, flagging them in the banner metadata
+func (m *MongoDBManager) truncatePortDocument(doc *PortDocument) {
+	doc.Banner, _ = domain.TruncateBanner(doc.Banner, m.maxBannerLength)
+
+	if doc.BannerInfo == nil {
+		return
+	}
+	rawBanner, truncated := domain.TruncateBanner(doc.BannerInfo.RawBanner, m.maxBannerLength)
+	if !truncated {
+		return
+	}
+
+	// Copy the metadata so the domain result is left untouched
+	metadata := make(map[string]interface{}, len(doc.BannerInfo.Metadata)+2)
+	for k, v := range doc.BannerInfo.Metadata {
+		metadata[k] = v
+	}
+	metadata["banner_truncated"] = true
+	metadata["banner_original_length"] = len(doc.BannerInfo.RawBanner)
+
+	doc.BannerInfo.RawBanner = rawBanner
+	doc.BannerInfo.Metadata = metadata
+}
+
+// scanResultIndexes are the indexes of the scan result collection. Compound keys are ordered
+// documents, so the key order is the same every time they are created.
+func scanResultIndexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
 		{
-			Keys: bson.M{
-				"ip":         1,
-				"created_at": -1,
-			},
+			Keys:    bson.D{{Key: "ip", Value: 1}, {Key: "created_at", Value: -1}},
 			Options: options.Index().SetName("ip_created_at_idx"),
 		},
 		{
-			Keys: bson.M{
-				"batch_id":   1,
-				"created_at": -1,
-			},
+			Keys:    bson.D{{Key: "batch_id", Value: 1}, {Key: "created_at", Value: -1}},
 			Options: options.Index().SetName("batch_id_created_at_idx"),
 		},
 		{
-			Keys: bson.M{
-				"status":     1,
-				"created_at": -1,
-			},
+			Keys:    bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: -1}},
 			Options: options.Index().SetName("status_created_at_idx"),
 		},
 		{
-			Keys: bson.M{
-				"worker_id":  1,
-				"created_at": -1,
-			},
+			Keys:    bson.D{{Key: "worker_id", Value: 1}, {Key: "created_at", Value: -1}},
 			Options: options.Index().SetName("worker_id_created_at_idx"),
 		},
 		{
-			Keys: bson.M{
-				"is_up":      1,
-				"open_ports": -1,
-			},
+			Keys:    bson.D{{Key: "is_up", Value: 1}, {Key: "open_ports", Value: -1}},
 			Options: options.Index().SetName("is_up_open_ports_idx"),
 		},
+		{
+			// Documents without expires_at are never removed
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetName("expires_at_ttl_idx").SetExpireAfterSeconds(0),
+		},
 	}
-
-	_, err := collection.Indexes().CreateMany(ctx, indexes)
-	return err
 }
 
 // SaveScanResult saves a scan result to MongoDB
@@ -176,11 +254,12 @@ func (m *MongoDBManager) SaveScanResult(result *domain.ScanResult) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	// Convert domain ScanResult to MongoDB document
-	doc := m.convertScanResultToDocument(result)
-
-	// Insert the document
-	_, err := m.collection.InsertOne(ctx, doc)
+	var err error
+	if m.upsert || m.dedupWindow > 0 {
+		err = m.upsertScanResults(ctx, []*domain.ScanResult{result})
+	} else {
+		_, err = m.collection.InsertOne(ctx, m.convertScanResultToDocument(result))
+	}
 	if err != nil {
 		log.L().Error("Failed to save scan result", zap.String("event", "save_failed"),
 			zap.String("ip", result.IP), zap.Error(err))
@@ -202,15 +281,17 @@ func (m *MongoDBManager) SaveScanResultBatch(results []*domain.ScanResult) error
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	// Convert domain ScanResults to MongoDB documents
-	var documents []interface{}
-	for _, result := range results {
-		doc := m.convertScanResultToDocument(result)
-		documents = append(documents, doc)
+	var err error
+	if m.upsert || m.dedupWindow > 0 {
+		err = m.upsertScanResults(ctx, results)
+	} else {
+		// Convert domain ScanResults to MongoDB documents
+		var documents []interface{}
+		for _, result := range results {
+			documents = append(documents, m.convertScanResultToDocument(result))
+		}
+		_, err = m.collection.InsertMany(ctx, documents)
 	}
-
-	// Insert documents in batch
-	_, err := m.collection.InsertMany(ctx, documents)
 	if err != nil {
 		log.L().Error("Failed to save scan results batch", zap.String("event", "batch_save_failed"),
 			zap.Int("count", len(results)), zap.Error(err))
@@ -223,13 +304,19 @@ func (m *MongoDBManager) SaveScanResultBatch(results []*domain.ScanResult) error
 	return nil
 }
 
-// GetScanResult retrieves a scan result by IP
-func (m *MongoDBManager) GetScanResult(ip string) (*ScanResultDocument, error) {
+// GetScanResult retrieves a scan result by IP. A projection limits the fields read; projected
+// documents are returned as stored, without schema migration.
+func (m *MongoDBManager) GetScanResult(ip string, projection *Projection) (*ScanResultDocument, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	opts := options.FindOne()
+	if projection != nil {
+		opts.SetProjection(projection.bsonProjection())
+	}
+
 	var doc ScanResultDocument
-	err := m.collection.FindOne(ctx, bson.M{"ip": ip}).Decode(&doc)
+	err := m.collection.FindOne(ctx, bson.M{"ip": ip}, opts).Decode(&doc)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, fmt.Errorf("no scan result found for IP: %s", ip)
@@ -237,15 +324,31 @@ func (m *MongoDBManager) GetScanResult(ip string) (*ScanResultDocument, error) {
 		return nil, fmt.Errorf("failed to get scan result: %w", err)
 	}
 
+	if err := m.decompressDocument(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decompress scan result: %w", err)
+	}
+	if projection == nil {
+		migrateDocument(&doc)
+	}
+
 	return &doc, nil
 }
 
-// GetScanResultsByBatch retrieves all scan results for a batch
-func (m *MongoDBManager) GetScanResultsByBatch(batchID string) ([]*ScanResultDocument, error) {
+// GetScanResultsByBatch retrieves the scan results of a batch, limited to the projected fields
+// when a projection is given. A positive limit returns at most that many results, oldest first.
+func (m *MongoDBManager) GetScanResultsByBatch(batchID string, projection *Projection, limit int) ([]*ScanResultDocument, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	cursor, err := m.collection.Find(ctx, bson.M{"batch_id": batchID})
+	opts := options.Find()
+	if projection != nil {
+		opts.SetProjection(projection.bsonProjection())
+	}
+	if limit > 0 {
+		opts.SetLimit(int64(limit)).SetSort(bson.D{{Key: "created_at", Value: 1}})
+	}
+
+	cursor, err := m.collection.Find(ctx, bson.M{"batch_id": batchID}, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get scan results by batch: %w", err)
 	}
@@ -256,9 +359,63 @@ func (m *MongoDBManager) GetScanResultsByBatch(batchID string) ([]*ScanResultDoc
 		return nil, fmt.Errorf("failed to decode scan results: %w", err)
 	}
 
+	for _, doc := range results {
+		if err := m.decompressDocument(doc); err != nil {
+			return nil, fmt.Errorf("failed to decompress scan result: %w", err)
+		}
+		if projection == nil {
+			migrateDocument(doc)
+		}
+	}
+
 	return results, nil
 }
 
+// CountByBatch returns how many scan results a batch has
+func (m *MongoDBManager) CountByBatch(batchID string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	count, err := m.collection.CountDocuments(ctx, bson.M{"batch_id": batchID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count scan results by batch: %w", err)
+	}
+	return count, nil
+}
+
+// DeleteByBatch deletes all scan results for a batch and returns the number removed
+func (m *MongoDBManager) DeleteByBatch(batchID string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	res, err := m.collection.DeleteMany(ctx, bson.M{"batch_id": batchID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete scan results by batch: %w", err)
+	}
+
+	log.L().Info("Deleted scan results by batch", zap.String("event", "delete_batch"),
+		zap.String("batch_id", batchID), zap.Int64("deleted", res.DeletedCount))
+
+	return res.DeletedCount, nil
+}
+
+// DeleteOlderThan deletes scan results created before now minus age and returns the number removed
+func (m *MongoDBManager) DeleteOlderThan(age time.Duration) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	cutoff := time.Now().Add(-age)
+	res, err := m.collection.DeleteMany(ctx, bson.M{"created_at": bson.M{"$lt": cutoff}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old scan results: %w", err)
+	}
+
+	log.L().Info("Deleted old scan results", zap.String("event", "delete_older_than"),
+		zap.Time("cutoff", cutoff), zap.Int64("deleted", res.DeletedCount))
+
+	return res.DeletedCount, nil
+}
+
 // GetScanStats retrieves scanning statistics
 func (m *MongoDBManager) GetScanStats() (map[string]interface{}, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -280,8 +437,9 @@ func (m *MongoDBManager) GetScanStats() (map[string]interface{}, error) {
 						"$cond": []interface{}{bson.M{"$eq": []string{"$status", "failed"}}, 1, 0},
 					},
 				},
-				"total_open_ports":  bson.M{"$sum": "$open_ports"},
-				"avg_scan_duration": bson.M{"$avg": "$scan_duration"},
+				"total_open_ports":          bson.M{"$sum": "$open_ports"},
+				"total_open_filtered_ports": bson.M{"$sum": "$open_filtered_ports"},
+				"avg_scan_duration":         bson.M{"$avg": "$scan_duration"},
 			},
 		},
 	}
@@ -297,19 +455,83 @@ func (m *MongoDBManager) GetScanStats() (map[string]interface{}, error) {
 		return nil, fmt.Errorf("failed to decode stats: %w", err)
 	}
 
+	buckets, err := m.scanDurationBuckets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	if len(results) == 0 {
 		return map[string]interface{}{
-			"total_scans":       0,
-			"successful_scans":  0,
-			"failed_scans":      0,
-			"total_open_ports":  0,
-			"avg_scan_duration": 0,
+			"total_scans":               0,
+			"successful_scans":          0,
+			"failed_scans":              0,
+			"total_open_ports":          0,
+			"total_open_filtered_ports": 0,
+			"avg_scan_duration":         0,
+			"scan_duration_buckets":     buckets,
 		}, nil
 	}
 
+	results[0]["scan_duration_buckets"] = buckets
 	return results[0], nil
 }
 
+// durationBuckets are the upper bounds and labels of the scan duration histogram
+var durationBuckets = []struct {
+	upTo  time.Duration
+	label string
+}{
+	{time.Second, "<1s"},
+	{5 * time.Second, "1-5s"},
+	{30 * time.Second, "5-30s"},
+	{time.Duration(math.MaxInt64), ">30s"},
+}
+
+// scanDurationBuckets counts scans per duration range so slow outliers stand out from the average
+func (m *MongoDBManager) scanDurationBuckets(ctx context.Context) (map[string]int64, error) {
+	boundaries := []interface{}{int64(0)}
+	for _, bucket := range durationBuckets {
+		boundaries = append(boundaries, int64(bucket.upTo))
+	}
+
+	pipeline := []bson.M{
+		{"$match": bson.M{"scan_duration": bson.M{"$gte": 0}}},
+		{"$bucket": bson.M{
+			"groupBy":    "$scan_duration",
+			"boundaries": boundaries,
+			"output":     bson.M{"count": bson.M{"$sum": 1}},
+		}},
+	}
+
+	cursor, err := m.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate scan duration buckets: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		LowerBound int64 `bson:"_id"`
+		Count      int64 `bson:"count"`
+	}
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, fmt.Errorf("failed to decode scan duration buckets: %w", err)
+	}
+
+	// Report every bucket, including empty ones, so the histogram keeps its shape
+	buckets := make(map[string]int64, len(durationBuckets))
+	lowerBound := int64(0)
+	for _, bucket := range durationBuckets {
+		buckets[bucket.label] = 0
+		for _, result := range results {
+			if result.LowerBound == lowerBound {
+				buckets[bucket.label] = result.Count
+			}
+		}
+		lowerBound = int64(bucket.upTo)
+	}
+	return buckets, nil
+}
+
 // convertScanResultToDocument converts domain ScanResult to MongoDB document
 func (m *MongoDBManager) convertScanResultToDocument(result *domain.ScanResult) *ScanResultDocument {
 	now := time.Now()
@@ -325,6 +547,7 @@ func (m *MongoDBManager) convertScanResultToDocument(result *domain.ScanResult)
 			Version:      port.Version,
 			ScanTime:     port.ScanTime,
 			ResponseTime: port.ResponseTime,
+			Error:        port.Error,
 		}
 
 		// Convert banner info if available
@@ -336,6 +559,18 @@ func (m *MongoDBManager) convertScanResultToDocument(result *domain.ScanResult)
 				Version:    port.BannerInfo.Version,
 				Confidence: port.BannerInfo.Confidence,
 				Metadata:   port.BannerInfo.Metadata,
+
+				ConfidenceScore: port.BannerInfo.ConfidenceScore,
+			}
+		}
+
+		m.truncatePortDocument(&portDoc)
+
+		// Compress large banners if enabled
+		if m.compressBanners {
+			if err := m.compressPortDocument(&portDoc); err != nil {
+				log.L().Warn("Failed to compress port banner, storing uncompressed", zap.String("event", "compress_failed"),
+					zap.String("ip", result.IP), zap.Int("port", port.Number), zap.Error(err))
 			}
 		}
 
@@ -345,7 +580,25 @@ func (m *MongoDBManager) convertScanResultToDocument(result *domain.ScanResult)
 	openPorts := result.GetOpenPorts()
 	scanDuration := result.GetScanDuration()
 
+	metadata := map[string]interface{}{
+		"source": "port-scanner",
+	}
+	if len(result.Tags) > 0 {
+		metadata["tags"] = result.Tags
+	}
+	if result.Priority != 0 {
+		metadata["priority"] = result.Priority
+	}
+	if cdn := result.CDN(); cdn != nil {
+		metadata["behind_cdn"] = true
+		metadata["cdn_provider"] = cdn.Provider
+	}
+	for key, value := range result.Metadata {
+		metadata[key] = value
+	}
+
 	return &ScanResultDocument{
+		SchemaVersion: CurrentSchemaVersion,
 		IP:            result.IP,
 		IsUp:          result.IsUp,
 		PingTime:      result.PingTime,
@@ -361,10 +614,22 @@ func (m *MongoDBManager) convertScanResultToDocument(result *domain.ScanResult)
 		ScanDuration:  scanDuration,
 		CreatedAt:     now,
 		UpdatedAt:     now,
-		Metadata: map[string]interface{}{
-			"source": "port-scanner",
-		},
+		Metadata:      metadata,
+
+		OpenFilteredPorts: len(result.GetOpenFilteredPorts()),
+		ExpiresAt:         expiresAt(now, result.Retention),
+
+		ReverseDNS: result.ReverseDNS,
+	}
+}
+
+// expiresAt returns when a document stored at now should expire, or nil to keep it
+func expiresAt(now time.Time, retention time.Duration) *time.Time {
+	if retention <= 0 {
+		return nil
 	}
+	expires := now.Add(retention)
+	return &expires
 }
 
 // Close closes the MongoDB connection
@@ -0,0 +1,160 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"port-scanner/internal/domain"
+	"port-scanner/pkg/log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// Ensure MongoDBManager implements OutboxStore interface
+var _ domain.OutboxStore = (*MongoDBManager)(nil)
+
+// OutboxDocument is a downstream queue message waiting to be published.
+// Messages belong to the worker that recorded them and are published in _id order. A worker
+// adopts messages left unpublished for longer than the adopt-after period by any other worker,
+// such as a previous process whose hostname-based worker ID didn't survive a restart.
+type OutboxDocument struct {
+	ID          primitive.ObjectID `bson:"_id"`
+	WorkerID    string             `bson:"worker_id"`
+	Queue       string             `bson:"queue"`
+	Exchange    string             `bson:"exchange,omitempty"`
+	RoutingKey  string             `bson:"routing_key,omitempty"`
+	ContentType string             `bson:"content_type,omitempty"`
+	Body        []byte             `bson:"body"`
+	CreatedAt   time.Time          `bson:"created_at"`
+}
+
+// outboxIndexes are used to read a worker's pending messages in order and to find stale ones
+func outboxIndexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "worker_id", Value: 1}, {Key: "_id", Value: 1}},
+			Options: options.Index().SetName("worker_id_idx"),
+		},
+		{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetName("created_at_idx"),
+		},
+	}
+}
+
+// SetOutboxAdoptAfter sets how long a message may stay unpublished before any worker may adopt it
+func (m *MongoDBManager) SetOutboxAdoptAfter(adoptAfter time.Duration) {
+	if adoptAfter > 0 {
+		m.outboxAdoptAfter = adoptAfter
+	}
+}
+
+// Enqueue records outbox messages in order
+func (m *MongoDBManager) Enqueue(messages []*domain.OutboxMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// ObjectIDs generated in sequence by one process are increasing, which preserves the order
+	docs := make([]interface{}, 0, len(messages))
+	for _, message := range messages {
+		id := primitive.NewObjectID()
+		message.ID = id.Hex()
+		docs = append(docs, OutboxDocument{
+			ID:          id,
+			WorkerID:    message.WorkerID,
+			Queue:       message.Queue,
+			Exchange:    message.Exchange,
+			RoutingKey:  message.RoutingKey,
+			ContentType: message.ContentType,
+			Body:        message.Body,
+			CreatedAt:   message.CreatedAt,
+		})
+	}
+
+	if _, err := m.outbox.InsertMany(ctx, docs); err != nil {
+		return fmt.Errorf("failed to record outbox messages: %w", err)
+	}
+	return nil
+}
+
+// Pending returns the oldest unpublished messages of a worker, after adopting the stale
+// messages of other workers
+func (m *MongoDBManager) Pending(workerID string, limit int) ([]*domain.OutboxMessage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := m.adoptStaleOutbox(ctx, workerID); err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.M{"_id": 1}).SetLimit(int64(limit))
+	cursor, err := m.outbox.Find(ctx, bson.M{"worker_id": workerID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var docs []OutboxDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode outbox messages: %w", err)
+	}
+
+	messages := make([]*domain.OutboxMessage, 0, len(docs))
+	for _, doc := range docs {
+		messages = append(messages, &domain.OutboxMessage{
+			ID:          doc.ID.Hex(),
+			WorkerID:    doc.WorkerID,
+			Queue:       doc.Queue,
+			Exchange:    doc.Exchange,
+			RoutingKey:  doc.RoutingKey,
+			ContentType: doc.ContentType,
+			Body:        doc.Body,
+			CreatedAt:   doc.CreatedAt,
+		})
+	}
+	return messages, nil
+}
+
+// adoptStaleOutbox moves messages another worker left unpublished for longer than the adopt-after
+// period to a worker. A worker that is alive but still retrying them may publish some again;
+// downstream consumers must tolerate duplicates.
+func (m *MongoDBManager) adoptStaleOutbox(ctx context.Context, workerID string) error {
+	filter := bson.M{
+		"worker_id":  bson.M{"$ne": workerID},
+		"created_at": bson.M{"$lt": time.Now().Add(-m.outboxAdoptAfter)},
+	}
+	result, err := m.outbox.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"worker_id": workerID}})
+	if err != nil {
+		return fmt.Errorf("failed to adopt stale outbox messages: %w", err)
+	}
+	if result.ModifiedCount > 0 {
+		log.L().Warn("Adopted stale outbox messages", zap.String("event", "outbox_adopted"),
+			zap.String("worker_id", workerID), zap.Int64("messages", result.ModifiedCount))
+	}
+	return nil
+}
+
+// MarkPublished removes a published outbox message
+func (m *MongoDBManager) MarkPublished(id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid outbox message id %s: %w", id, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := m.outbox.DeleteOne(ctx, bson.M{"_id": objectID}); err != nil {
+		return fmt.Errorf("failed to remove outbox message: %w", err)
+	}
+	return nil
+}
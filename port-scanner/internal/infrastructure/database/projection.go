@@ -0,0 +1,181 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// compressedCompanions are the stored fields a projected field is restored from when banner
+// compression is enabled
+var compressedCompanions = map[string][]string{
+	"ports.banner":                 {"ports.compressed", "ports.compressed_banner"},
+	"ports.banner_info":            {"ports.compressed"},
+	"ports.banner_info.raw_banner": {"ports.compressed", "ports.banner_info.compressed_raw_banner"},
+	"ports.banner_info.metadata":   {"ports.compressed", "ports.banner_info.compressed_metadata"},
+	"ports.metadata":               {"ports.compressed", "ports.compressed_metadata"},
+}
+
+// Projection limits the fields of scan result documents returned by a query. Fields are the
+// JSON names of ScanResultDocument, with dots selecting nested fields (e.g. "ports.number").
+type Projection struct {
+	fields []string
+}
+
+// ParseProjection parses a comma-separated field list; an empty list selects whole documents
+// and returns nil
+func ParseProjection(fields string) (*Projection, error) {
+	var paths []string
+	for _, field := range strings.Split(fields, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !knownField(reflect.TypeOf(ScanResultDocument{}), strings.Split(field, ".")) {
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+		paths = append(paths, field)
+	}
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	return &Projection{fields: withoutCovered(paths)}, nil
+}
+
+// knownField reports whether a dotted path names a field of a document type. Anything under a
+// map, such as metadata keys, is accepted.
+func knownField(docType reflect.Type, path []string) bool {
+	for docType.Kind() == reflect.Ptr || docType.Kind() == reflect.Slice {
+		docType = docType.Elem()
+	}
+	if len(path) == 0 {
+		return true
+	}
+	if docType.Kind() == reflect.Map {
+		return true
+	}
+	if docType.Kind() != reflect.Struct {
+		return false
+	}
+
+	for i := 0; i < docType.NumField(); i++ {
+		name, _, _ := strings.Cut(docType.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" && name == path[0] {
+			return knownField(docType.Field(i).Type, path[1:])
+		}
+	}
+	return false
+}
+
+// withoutCovered sorts paths and drops duplicates and paths inside another selected path,
+// which MongoDB rejects as a path collision
+func withoutCovered(paths []string) []string {
+	sort.Strings(paths)
+	var kept []string
+	for _, path := range paths {
+		covered := false
+		for _, parent := range kept {
+			if path == parent || strings.HasPrefix(path, parent+".") {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			kept = append(kept, path)
+		}
+	}
+	return kept
+}
+
+// bsonProjection returns the MongoDB projection of the selected fields, including the fields
+// compressed banners are restored from
+func (p *Projection) bsonProjection() bson.D {
+	paths := make([]string, 0, len(p.fields))
+	selectsID := false
+	for _, field := range p.fields {
+		if field == "id" {
+			selectsID = true
+			continue
+		}
+		paths = append(paths, field)
+		for compressed, companions := range compressedCompanions {
+			if field == compressed || strings.HasPrefix(field, compressed+".") {
+				paths = append(paths, companions...)
+			}
+		}
+	}
+
+	projection := bson.D{}
+	for _, path := range withoutCovered(paths) {
+		projection = append(projection, bson.E{Key: path, Value: 1})
+	}
+	if selectsID {
+		projection = append(projection, bson.E{Key: "_id", Value: 1})
+	} else {
+		projection = append(projection, bson.E{Key: "_id", Value: 0})
+	}
+	return projection
+}
+
+// Apply returns the selected fields of a document as it is rendered in JSON, leaving out the
+// zero values a projected document decodes with
+func (p *Projection) Apply(doc interface{}) (map[string]interface{}, error) {
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var full map[string]interface{}
+	if err := json.Unmarshal(raw, &full); err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string]interface{})
+	for _, field := range p.fields {
+		selectPath(full, selected, strings.Split(field, "."))
+	}
+	return selected, nil
+}
+
+// selectPath copies the value at path from src into dst, descending into arrays element by element
+func selectPath(src, dst map[string]interface{}, path []string) {
+	value, ok := src[path[0]]
+	if !ok {
+		return
+	}
+	if len(path) == 1 {
+		dst[path[0]] = value
+		return
+	}
+
+	switch nested := value.(type) {
+	case map[string]interface{}:
+		child, _ := dst[path[0]].(map[string]interface{})
+		if child == nil {
+			child = make(map[string]interface{})
+			dst[path[0]] = child
+		}
+		selectPath(nested, child, path[1:])
+	case []interface{}:
+		children, _ := dst[path[0]].([]interface{})
+		if children == nil {
+			children = make([]interface{}, len(nested))
+			dst[path[0]] = children
+		}
+		for i, element := range nested {
+			elementMap, ok := element.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			child, _ := children[i].(map[string]interface{})
+			if child == nil {
+				child = make(map[string]interface{})
+				children[i] = child
+			}
+			selectPath(elementMap, child, path[1:])
+		}
+	}
+}
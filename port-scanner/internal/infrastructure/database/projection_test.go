@@ -0,0 +1,143 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestParseProjectionRejectsUnknownFields(t *testing.T) {
+	for _, fields := range []string{"password", "ip,nope", "ports.nope", "ip.length", "ports.compressed", "ports.banner_info.compressed_raw_banner"} {
+		if _, err := ParseProjection(fields); err == nil {
+			t.Errorf("ParseProjection(%q) accepted an unknown field", fields)
+		}
+	}
+}
+
+func TestParseProjectionSelectsKnownFields(t *testing.T) {
+	if projection, err := ParseProjection(" , "); err != nil || projection != nil {
+		t.Errorf("ParseProjection of an empty list = %v, %v, want nil for whole documents", projection, err)
+	}
+
+	projection, err := ParseProjection("ports.number, ip, ports, metadata.source, ip")
+	if err != nil {
+		t.Fatalf("ParseProjection returned error: %v", err)
+	}
+	// Duplicates and fields inside another selected field collapse
+	if want := []string{"ip", "metadata.source", "ports"}; !reflect.DeepEqual(projection.fields, want) {
+		t.Errorf("fields = %v, want %v", projection.fields, want)
+	}
+}
+
+func TestBSONProjectionIncludesCompressedCompanions(t *testing.T) {
+	projection, err := ParseProjection("ip,ports.banner")
+	if err != nil {
+		t.Fatalf("ParseProjection returned error: %v", err)
+	}
+	want := bson.D{
+		{Key: "ip", Value: 1},
+		{Key: "ports.banner", Value: 1},
+		{Key: "ports.compressed", Value: 1},
+		{Key: "ports.compressed_banner", Value: 1},
+		{Key: "_id", Value: 0},
+	}
+	if got := projection.bsonProjection(); !reflect.DeepEqual(got, want) {
+		t.Errorf("bsonProjection() = %v, want %v", got, want)
+	}
+
+	metadata, _ := ParseProjection("ports.metadata")
+	wantMetadata := bson.D{
+		{Key: "ports.compressed", Value: 1},
+		{Key: "ports.compressed_metadata", Value: 1},
+		{Key: "ports.metadata", Value: 1},
+		{Key: "_id", Value: 0},
+	}
+	if got := metadata.bsonProjection(); !reflect.DeepEqual(got, wantMetadata) {
+		t.Errorf("bsonProjection() = %v, want %v", got, wantMetadata)
+	}
+
+	withID, _ := ParseProjection("id,ip")
+	if got := withID.bsonProjection(); got[len(got)-1] != (bson.E{Key: "_id", Value: 1}) {
+		t.Errorf("bsonProjection() = %v, want _id included when id is selected", got)
+	}
+}
+
+func TestProjectionApplyLimitsFields(t *testing.T) {
+	doc := &ScanResultDocument{
+		IP:      "203.0.113.5",
+		Status:  "completed",
+		BatchID: "batch-1",
+		Ports: []PortDocument{
+			{Number: 22, Status: "open", Banner: "SSH-2.0-OpenSSH_9.6"},
+			{Number: 443, Status: "open", BannerInfo: &BannerInfoDocument{RawBanner: "HTTP/1.1 200 OK"}},
+		},
+		Metadata: map[string]interface{}{"source": "stun", "large": "blob"},
+	}
+
+	projection, err := ParseProjection("ip,ports.number,metadata.source")
+	if err != nil {
+		t.Fatalf("ParseProjection returned error: %v", err)
+	}
+	got, err := projection.Apply(doc)
+	if err != nil {
+		t.Fatalf("Apply returned error: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"ip": "203.0.113.5",
+		"ports": []interface{}{
+			map[string]interface{}{"number": float64(22)},
+			map[string]interface{}{"number": float64(443)},
+		},
+		"metadata": map[string]interface{}{"source": "stun"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Apply() = %v, want %v", got, want)
+	}
+}
+
+func TestGetScanResultSendsProjection(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("projected", func(mt *mtest.T) {
+		m := newTestManager()
+		m.collection = mt.Coll
+
+		ns := mt.DB.Name() + "." + mt.Coll.Name()
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{
+			{Key: "ip", Value: "203.0.113.5"},
+			{Key: "ports", Value: bson.A{bson.D{{Key: "number", Value: int32(22)}}}},
+		}))
+
+		projection, err := ParseProjection("ip,ports.number")
+		if err != nil {
+			mt.Fatalf("ParseProjection returned error: %v", err)
+		}
+		doc, err := m.GetScanResult("203.0.113.5", projection)
+		if err != nil {
+			mt.Fatalf("GetScanResult returned error: %v", err)
+		}
+		if doc.IP != "203.0.113.5" || len(doc.Ports) != 1 || doc.Ports[0].Number != 22 {
+			mt.Errorf("document = %+v, want 203.0.113.5 with port 22", doc)
+		}
+		// Projected documents skip the schema migration, which would stamp the current version
+		if doc.SchemaVersion != 0 {
+			mt.Errorf("projected document migrated to schema version %d", doc.SchemaVersion)
+		}
+
+		command := mt.GetStartedEvent()
+		if command == nil || command.CommandName != "find" {
+			mt.Fatal("no find was sent")
+		}
+		var sent bson.D
+		if err := bson.Unmarshal(command.Command.Lookup("projection").Document(), &sent); err != nil {
+			mt.Fatalf("failed to decode the projection sent: %v", err)
+		}
+		want := bson.D{{Key: "ip", Value: int32(1)}, {Key: "ports.number", Value: int32(1)}, {Key: "_id", Value: int32(0)}}
+		if !reflect.DeepEqual(sent, want) {
+			mt.Errorf("projection sent = %v, want %v", sent, want)
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"fmt"
+
+	"port-scanner/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// derivedMetadataKeys are metadata entries computed from the scan result when it is stored,
+// rather than written by enrichers
+var derivedMetadataKeys = map[string]bool{
+	"source":       true,
+	"tags":         true,
+	"priority":     true,
+	"behind_cdn":   true,
+	"cdn_provider": true,
+}
+
+// ToScanResult reconstructs the domain scan result a document was stored from, so it can be
+// published again. The document must already be decompressed.
+func (doc *ScanResultDocument) ToScanResult() *domain.ScanResult {
+	result := &domain.ScanResult{
+		IP:            doc.IP,
+		IsUp:          doc.IsUp,
+		PingTime:      doc.PingTime,
+		ScanStartTime: doc.ScanStartTime,
+		ScanEndTime:   doc.ScanEndTime,
+		Ports:         make([]*domain.Port, 0, len(doc.Ports)),
+		Status:        domain.ScanStatus(doc.Status),
+		Error:         doc.Error,
+		BatchID:       doc.BatchID,
+		WorkerID:      doc.WorkerID,
+		ReverseDNS:    doc.ReverseDNS,
+	}
+
+	for _, portDoc := range doc.Ports {
+		port := &domain.Port{
+			Number:       portDoc.Number,
+			Status:       domain.PortStatus(portDoc.Status),
+			Service:      portDoc.Service,
+			Banner:       portDoc.Banner,
+			Version:      portDoc.Version,
+			ScanTime:     portDoc.ScanTime,
+			ResponseTime: portDoc.ResponseTime,
+			Error:        portDoc.Error,
+		}
+		if portDoc.BannerInfo != nil {
+			port.BannerInfo = &domain.BannerInfo{
+				RawBanner:  portDoc.BannerInfo.RawBanner,
+				Service:    portDoc.BannerInfo.Service,
+				Protocol:   portDoc.BannerInfo.Protocol,
+				Version:    portDoc.BannerInfo.Version,
+				Confidence: portDoc.BannerInfo.Confidence,
+				Metadata:   portDoc.BannerInfo.Metadata,
+
+				ConfidenceScore: portDoc.BannerInfo.ConfidenceScore,
+			}
+		}
+		result.AddPort(port)
+	}
+
+	for key, value := range doc.Metadata {
+		switch key {
+		case "tags":
+			result.Tags = stringMap(value)
+		case "priority":
+			result.Priority = intValue(value)
+		default:
+			if !derivedMetadataKeys[key] {
+				result.SetMetadata(key, value)
+			}
+		}
+	}
+
+	return result
+}
+
+// stringMap converts a decoded BSON sub-document to a string map
+func stringMap(value interface{}) map[string]string {
+	var fields map[string]interface{}
+	switch v := value.(type) {
+	case map[string]string:
+		return v
+	case primitive.M:
+		fields = v
+	case map[string]interface{}:
+		fields = v
+	case primitive.D:
+		fields = make(map[string]interface{}, len(v))
+		for _, element := range v {
+			fields[element.Key] = element.Value
+		}
+	default:
+		return nil
+	}
+
+	out := make(map[string]string, len(fields))
+	for k, v := range fields {
+		out[k] = fmt.Sprint(v)
+	}
+	return out
+}
+
+// intValue converts a decoded BSON number to an int
+func intValue(value interface{}) int {
+	switch v := value.(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return 0
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	"port-scanner/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestToScanResultRebuildsStoredResult(t *testing.T) {
+	result := domain.NewScanResult("8.8.8.8", "batch-1", "worker-1")
+	result.IsUp = true
+	result.Tags = map[string]string{"campaign": "q3-audit"}
+	result.Priority = 5
+	result.ReverseDNS = []string{"dns.google"}
+	result.SetMetadata("country", "US")
+	port := domain.NewPort(443)
+	port.Status = domain.PortStatusOpen
+	port.Service = "https"
+	port.Version = "nginx 1.24"
+	port.BannerInfo = &domain.BannerInfo{Service: "https", Protocol: "tcp", Version: "nginx 1.24", Confidence: "high", ConfidenceScore: 90}
+	result.AddPort(port)
+	result.AddPort(domain.NewPort(22))
+	result.SetCompleted()
+
+	// Round-trip through BSON so the document decodes as it does when read from MongoDB
+	raw, err := bson.Marshal(newTestManager().convertScanResultToDocument(result))
+	if err != nil {
+		t.Fatalf("failed to marshal document: %v", err)
+	}
+	var doc ScanResultDocument
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		t.Fatalf("failed to unmarshal document: %v", err)
+	}
+
+	rebuilt := doc.ToScanResult()
+	if rebuilt.IP != "8.8.8.8" || rebuilt.BatchID != "batch-1" || !rebuilt.IsUp || rebuilt.Status != domain.ScanStatusCompleted {
+		t.Errorf("rebuilt %s/%s up=%v status=%s, want the stored result", rebuilt.IP, rebuilt.BatchID, rebuilt.IsUp, rebuilt.Status)
+	}
+	if !reflect.DeepEqual(rebuilt.Tags, result.Tags) || rebuilt.Priority != 5 {
+		t.Errorf("rebuilt tags/priority = %v/%d, want %v/5", rebuilt.Tags, rebuilt.Priority, result.Tags)
+	}
+	if !reflect.DeepEqual(rebuilt.ReverseDNS, []string{"dns.google"}) || rebuilt.Metadata["country"] != "US" {
+		t.Errorf("rebuilt reverse DNS/metadata = %v/%v, want the enrichment data", rebuilt.ReverseDNS, rebuilt.Metadata)
+	}
+	for _, key := range []string{"tags", "priority", "source"} {
+		if _, ok := rebuilt.Metadata[key]; ok {
+			t.Errorf("rebuilt metadata carries the derived %q entry", key)
+		}
+	}
+
+	open := rebuilt.GetOpenPorts()
+	if len(rebuilt.Ports) != 2 || len(open) != 1 {
+		t.Fatalf("rebuilt %d ports with %d open, want 2 with 1 open", len(rebuilt.Ports), len(open))
+	}
+	if open[0].Number != 443 || open[0].Version != "nginx 1.24" || open[0].BannerInfo == nil || open[0].BannerInfo.ConfidenceScore != 90 {
+		t.Errorf("rebuilt open port = %+v, want 443 with its banner", open[0])
+	}
+}
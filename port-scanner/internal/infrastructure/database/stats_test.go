@@ -0,0 +1,62 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestGetScanStatsReportsDurationBuckets(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("buckets", func(mt *mtest.T) {
+		m := newTestManager()
+		m.collection = mt.Coll
+
+		// Stored scans of 200ms, 400ms, 2s and 90s, as the server groups them
+		ns := mt.Coll.Database().Name() + "." + mt.Coll.Name()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: nil}, {Key: "total_scans", Value: int32(4)}, {Key: "avg_scan_duration", Value: float64(92600 * time.Millisecond / 4)},
+			}),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: int64(0)}, {Key: "count", Value: int64(2)}},
+				bson.D{{Key: "_id", Value: int64(time.Second)}, {Key: "count", Value: int64(1)}},
+				bson.D{{Key: "_id", Value: int64(30 * time.Second)}, {Key: "count", Value: int64(1)}},
+			),
+		)
+
+		stats, err := m.GetScanStats()
+		if err != nil {
+			mt.Fatalf("GetScanStats returned error: %v", err)
+		}
+
+		// 5-30s has no entry in the server's answer and must still be reported
+		want := map[string]int64{"<1s": 2, "1-5s": 1, "5-30s": 0, ">30s": 1}
+		if got := stats["scan_duration_buckets"]; !reflect.DeepEqual(got, want) {
+			mt.Errorf("scan_duration_buckets = %v, want %v", got, want)
+		}
+
+		// The first aggregation computes the totals, the second the buckets
+		mt.GetStartedEvent()
+		command := mt.GetStartedEvent()
+		if command == nil || command.CommandName != "aggregate" {
+			mt.Fatal("no bucket aggregation was sent")
+		}
+		boundaries := command.Command.Lookup("pipeline", "1", "$bucket", "boundaries").Array()
+		values, err := boundaries.Values()
+		if err != nil {
+			mt.Fatalf("failed to read bucket boundaries: %v", err)
+		}
+		got := make([]time.Duration, 0, len(values))
+		for _, value := range values {
+			got = append(got, time.Duration(value.Int64()))
+		}
+		if !reflect.DeepEqual(got[:4], []time.Duration{0, time.Second, 5 * time.Second, 30 * time.Second}) {
+			mt.Errorf("bucket boundaries = %v, want 0, 1s, 5s, 30s and an open upper bound", got)
+		}
+	})
+}
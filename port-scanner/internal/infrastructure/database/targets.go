@@ -0,0 +1,146 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"port-scanner/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrTargetListNotFound is returned when no target list has the requested name
+	ErrTargetListNotFound = errors.New("target list not found")
+	// ErrTargetListExists is returned when creating a target list whose name is taken
+	ErrTargetListExists = errors.New("target list already exists")
+)
+
+// TargetListDocument is a named list of IP addresses and CIDR blocks maintained for scanning
+type TargetListDocument struct {
+	Name        string    `bson:"_id" json:"name"`
+	Description string    `bson:"description,omitempty" json:"description,omitempty"`
+	Targets     []string  `bson:"targets" json:"targets"`
+	Ports       []int     `bson:"ports,omitempty" json:"ports,omitempty"` // Empty scans the scanner's default ports
+	CreatedAt   time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// Validate checks that the list's targets and ports can be scanned
+func (t *TargetListDocument) Validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("target list name is required")
+	}
+	if len(t.Targets) == 0 {
+		return fmt.Errorf("target list has no targets")
+	}
+	if _, err := t.IPs(); err != nil {
+		return err
+	}
+	for _, port := range t.Ports {
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("invalid port: %d", port)
+		}
+	}
+	return nil
+}
+
+// IPs returns the addresses the list's targets cover
+func (t *TargetListDocument) IPs() ([]string, error) {
+	return domain.ExpandTargets(t.Targets, domain.MaxTargetListIPs)
+}
+
+// CreateTargetList stores a new target list
+func (m *MongoDBManager) CreateTargetList(list *TargetListDocument) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	list.CreatedAt = now
+	list.UpdatedAt = now
+
+	_, err := m.targets.InsertOne(ctx, list)
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("%w: %s", ErrTargetListExists, list.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create target list: %w", err)
+	}
+	return nil
+}
+
+// GetTargets returns the target list with the given name
+func (m *MongoDBManager) GetTargets(listName string) (*TargetListDocument, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var list TargetListDocument
+	err := m.targets.FindOne(ctx, bson.M{"_id": listName}).Decode(&list)
+	if err == mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("%w: %s", ErrTargetListNotFound, listName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get target list: %w", err)
+	}
+	return &list, nil
+}
+
+// ListTargetLists returns all target lists ordered by name
+func (m *MongoDBManager) ListTargetLists() ([]TargetListDocument, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := m.targets.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list target lists: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	lists := []TargetListDocument{}
+	if err := cursor.All(ctx, &lists); err != nil {
+		return nil, fmt.Errorf("failed to decode target lists: %w", err)
+	}
+	return lists, nil
+}
+
+// UpdateTargetList replaces the targets of an existing list, keeping its creation time
+func (m *MongoDBManager) UpdateTargetList(list *TargetListDocument) error {
+	existing, err := m.GetTargets(list.Name)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	list.CreatedAt = existing.CreatedAt
+	list.UpdatedAt = time.Now()
+
+	res, err := m.targets.ReplaceOne(ctx, bson.M{"_id": list.Name}, list)
+	if err != nil {
+		return fmt.Errorf("failed to update target list: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("%w: %s", ErrTargetListNotFound, list.Name)
+	}
+	return nil
+}
+
+// DeleteTargetList removes a target list
+func (m *MongoDBManager) DeleteTargetList(name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := m.targets.DeleteOne(ctx, bson.M{"_id": name})
+	if err != nil {
+		return fmt.Errorf("failed to delete target list: %w", err)
+	}
+	if res.DeletedCount == 0 {
+		return fmt.Errorf("%w: %s", ErrTargetListNotFound, name)
+	}
+	return nil
+}
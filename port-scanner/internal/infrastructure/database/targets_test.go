@@ -0,0 +1,70 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestGetTargetsReadsSeededList(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("found", func(mt *mtest.T) {
+		m := newTestManager()
+		m.targets = mt.Coll
+
+		ns := mt.DB.Name() + "." + mt.Coll.Name()
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: "dmz"},
+			{Key: "targets", Value: bson.A{"198.51.100.10", "203.0.113.0/30"}},
+			{Key: "ports", Value: bson.A{int32(22)}},
+		}))
+
+		list, err := m.GetTargets("dmz")
+		if err != nil {
+			mt.Fatalf("GetTargets returned error: %v", err)
+		}
+		ips, err := list.IPs()
+		if err != nil || len(ips) != 5 || list.Name != "dmz" || len(list.Ports) != 1 {
+			mt.Errorf("list = %+v covering %v (%v), want dmz with 5 IPs on port 22", list, ips, err)
+		}
+
+		event := mt.GetStartedEvent()
+		if id := event.Command.Lookup("filter", "_id").StringValue(); id != "dmz" {
+			mt.Errorf("filter _id = %q, want dmz", id)
+		}
+	})
+
+	mt.Run("not found", func(mt *mtest.T) {
+		m := newTestManager()
+		m.targets = mt.Coll
+
+		ns := mt.DB.Name() + "." + mt.Coll.Name()
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch))
+
+		if _, err := m.GetTargets("missing"); !errors.Is(err, ErrTargetListNotFound) {
+			mt.Errorf("GetTargets of a missing list returned %v, want ErrTargetListNotFound", err)
+		}
+	})
+}
+
+func TestTargetListValidate(t *testing.T) {
+	valid := &TargetListDocument{Name: "dmz", Targets: []string{"198.51.100.0/24"}, Ports: []int{22}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate returned error for a valid list: %v", err)
+	}
+
+	for _, list := range []*TargetListDocument{
+		{Targets: []string{"198.51.100.1"}},
+		{Name: "empty"},
+		{Name: "bad-target", Targets: []string{"host.example"}},
+		{Name: "too-big", Targets: []string{"10.0.0.0/8"}},
+		{Name: "bad-port", Targets: []string{"198.51.100.1"}, Ports: []int{65536}},
+	} {
+		if err := list.Validate(); err == nil {
+			t.Errorf("Validate accepted %+v", list)
+		}
+	}
+}
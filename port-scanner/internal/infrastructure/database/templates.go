@@ -0,0 +1,172 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"port-scanner/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrTemplateNotFound is returned when no scan template has the requested name
+	ErrTemplateNotFound = errors.New("scan template not found")
+	// ErrTemplateExists is returned when creating a scan template whose name is taken
+	ErrTemplateExists = errors.New("scan template already exists")
+)
+
+// ScanTemplateDocument is a named, reusable set of scan options
+type ScanTemplateDocument struct {
+	Name           string    `bson:"_id" json:"name"`
+	Description    string    `bson:"description,omitempty" json:"description,omitempty"`
+	Ports          []int     `bson:"ports,omitempty" json:"ports,omitempty"`
+	ConnectTimeout string    `bson:"connect_timeout,omitempty" json:"connect_timeout,omitempty"`
+	MaxRetries     *int      `bson:"max_retries,omitempty" json:"max_retries,omitempty"`
+	EnableBanner   *bool     `bson:"enable_banner,omitempty" json:"enable_banner,omitempty"`
+	EnablePing     *bool     `bson:"enable_ping,omitempty" json:"enable_ping,omitempty"`
+	Exclusions     []string  `bson:"exclusions,omitempty" json:"exclusions,omitempty"`
+	CreatedAt      time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// Validate checks that the template's options can be applied to a scan
+func (t *ScanTemplateDocument) Validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("template name is required")
+	}
+	for _, port := range t.Ports {
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("invalid port: %d", port)
+		}
+	}
+	if t.ConnectTimeout != "" {
+		if d, err := time.ParseDuration(t.ConnectTimeout); err != nil || d <= 0 {
+			return fmt.Errorf("invalid connect timeout: %s", t.ConnectTimeout)
+		}
+	}
+	if t.MaxRetries != nil && *t.MaxRetries < 0 {
+		return fmt.Errorf("max retries must not be negative")
+	}
+	if _, err := domain.ParseTargets(t.Exclusions); err != nil {
+		return fmt.Errorf("invalid exclusion: %w", err)
+	}
+	return nil
+}
+
+// ApplyTo overrides scan options with the values set in the template
+func (t *ScanTemplateDocument) ApplyTo(config *domain.ScanConfig) {
+	if len(t.Ports) > 0 {
+		config.PortRange = t.Ports
+	}
+	if d, err := time.ParseDuration(t.ConnectTimeout); err == nil && d > 0 {
+		config.ConnectTimeout = d
+	}
+	if t.MaxRetries != nil {
+		config.MaxRetries = *t.MaxRetries
+	}
+	if t.EnableBanner != nil {
+		config.EnableBanner = *t.EnableBanner
+	}
+	if t.EnablePing != nil {
+		config.EnablePing = *t.EnablePing
+	}
+	if len(t.Exclusions) > 0 {
+		config.Exclusions = t.Exclusions
+	}
+}
+
+// CreateTemplate stores a new scan template
+func (m *MongoDBManager) CreateTemplate(template *ScanTemplateDocument) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	template.CreatedAt = now
+	template.UpdatedAt = now
+
+	_, err := m.templates.InsertOne(ctx, template)
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("%w: %s", ErrTemplateExists, template.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create template: %w", err)
+	}
+	return nil
+}
+
+// GetTemplate returns the scan template with the given name
+func (m *MongoDBManager) GetTemplate(name string) (*ScanTemplateDocument, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var template ScanTemplateDocument
+	err := m.templates.FindOne(ctx, bson.M{"_id": name}).Decode(&template)
+	if err == mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+	return &template, nil
+}
+
+// ListTemplates returns all scan templates ordered by name
+func (m *MongoDBManager) ListTemplates() ([]ScanTemplateDocument, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := m.templates.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	templates := []ScanTemplateDocument{}
+	if err := cursor.All(ctx, &templates); err != nil {
+		return nil, fmt.Errorf("failed to decode templates: %w", err)
+	}
+	return templates, nil
+}
+
+// UpdateTemplate replaces the options of an existing scan template, keeping its creation time
+func (m *MongoDBManager) UpdateTemplate(template *ScanTemplateDocument) error {
+	existing, err := m.GetTemplate(template.Name)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	template.CreatedAt = existing.CreatedAt
+	template.UpdatedAt = time.Now()
+
+	res, err := m.templates.ReplaceOne(ctx, bson.M{"_id": template.Name}, template)
+	if err != nil {
+		return fmt.Errorf("failed to update template: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("%w: %s", ErrTemplateNotFound, template.Name)
+	}
+	return nil
+}
+
+// DeleteTemplate removes a scan template
+func (m *MongoDBManager) DeleteTemplate(name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := m.templates.DeleteOne(ctx, bson.M{"_id": name})
+	if err != nil {
+		return fmt.Errorf("failed to delete template: %w", err)
+	}
+	if res.DeletedCount == 0 {
+		return fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	return nil
+}
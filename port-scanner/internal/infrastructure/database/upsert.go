@@ -0,0 +1,155 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"port-scanner/internal/domain"
+	"port-scanner/pkg/log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+)
+
+// Write modes for scan results
+const (
+	WriteModeInsert = "insert" // Every scan adds a document, keeping the history of each IP
+	WriteModeUpsert = "upsert" // Each IP has one document, updated in place by later scans
+)
+
+// creationOnlyFields are written when an IP's document is created and never updated
+var creationOnlyFields = map[string]bool{"_id": true, "created_at": true}
+
+// SetWriteMode selects how scan results are written. In upsert mode, updateFields limits the
+// fields later scans rewrite (updated_at is always bumped); the other fields keep their value
+// from the scan that created the document. An empty list rewrites every field but created_at.
+func (m *MongoDBManager) SetWriteMode(mode string, updateFields []string) error {
+	switch mode {
+	case WriteModeInsert, "":
+		m.upsert = false
+		m.updateFields = nil
+		return nil
+	case WriteModeUpsert:
+	default:
+		return fmt.Errorf("unknown write mode %q", mode)
+	}
+
+	known := documentFields()
+	fields := make(map[string]bool, len(updateFields)+1)
+	for _, field := range updateFields {
+		if !known[field] || creationOnlyFields[field] {
+			return fmt.Errorf("field %q can't be updated", field)
+		}
+		fields[field] = true
+	}
+	if len(fields) > 0 {
+		fields["updated_at"] = true
+	}
+
+	m.upsert = true
+	m.updateFields = fields
+	return nil
+}
+
+// SetDedupWindow makes insert mode update an IP's document created within window instead of
+// adding another, so repeated scans of an IP in quick succession (retries, overlapping
+// schedules) keep one document. The window counts from the document's creation, so scans
+// repeated indefinitely still add a document per window. 0 disables; upsert mode ignores it.
+func (m *MongoDBManager) SetDedupWindow(window time.Duration) {
+	m.dedupWindow = window
+}
+
+// documentFields returns the top-level field names of a scan result document
+func documentFields() map[string]bool {
+	fields := make(map[string]bool)
+	docType := reflect.TypeOf(ScanResultDocument{})
+	for i := 0; i < docType.NumField(); i++ {
+		name, _, _ := strings.Cut(docType.Field(i).Tag.Get("bson"), ",")
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+	return fields
+}
+
+// writeModel builds the write of a result in upsert mode, or in insert mode with a dedup window:
+// the update of the IP's document created within the window, or a new document
+func (m *MongoDBManager) writeModel(result *domain.ScanResult) (*mongo.UpdateOneModel, error) {
+	if m.upsert {
+		return m.upsertModel(result, bson.M{"ip": result.IP})
+	}
+	cutoff := time.Now().Add(-m.dedupWindow)
+	return m.upsertModel(result, bson.M{"ip": result.IP, "created_at": bson.M{"$gte": cutoff}})
+}
+
+// upsertModel builds the update of the document matching filter: $setOnInsert for creation-only
+// fields and fields excluded from updates, $set for the updated fields, and $unset for updated
+// fields the new result leaves empty so stale values (e.g. a previous error) don't survive
+func (m *MongoDBManager) upsertModel(result *domain.ScanResult, filter bson.M) (*mongo.UpdateOneModel, error) {
+	raw, err := bson.Marshal(m.convertScanResultToDocument(result))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode scan result: %w", err)
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("failed to encode scan result: %w", err)
+	}
+
+	set := bson.M{}
+	setOnInsert := bson.M{}
+	unset := bson.M{}
+	for field := range documentFields() {
+		value, present := doc[field]
+		switch {
+		case field == "ip":
+			// Set from the filter on insert
+		case creationOnlyFields[field] || (len(m.updateFields) > 0 && !m.updateFields[field]):
+			if present {
+				setOnInsert[field] = value
+			}
+		case present:
+			set[field] = value
+		default:
+			unset[field] = ""
+		}
+	}
+
+	update := bson.M{"$set": set}
+	if len(setOnInsert) > 0 {
+		update["$setOnInsert"] = setOnInsert
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	return mongo.NewUpdateOneModel().
+		SetFilter(filter).
+		SetUpdate(update).
+		SetUpsert(true), nil
+}
+
+// upsertScanResults writes results in upsert mode or with a dedup window, in order so the last
+// result of an IP wins
+func (m *MongoDBManager) upsertScanResults(ctx context.Context, results []*domain.ScanResult) error {
+	models := make([]mongo.WriteModel, 0, len(results))
+	for _, result := range results {
+		model, err := m.writeModel(result)
+		if err != nil {
+			return err
+		}
+		models = append(models, model)
+	}
+
+	res, err := m.collection.BulkWrite(ctx, models)
+	if err != nil {
+		return err
+	}
+
+	log.L().Debug("Scan results upserted", zap.String("event", "upsert_success"),
+		zap.Int64("inserted", res.UpsertedCount), zap.Int64("updated", res.ModifiedCount))
+	return nil
+}
@@ -0,0 +1,148 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"port-scanner/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// applyUpdate applies an upsert's update operators to an existing document the way MongoDB
+// does for a matched document: $setOnInsert is ignored, $set and $unset are applied
+func applyUpdate(t *testing.T, existing bson.M, update interface{}) bson.M {
+	t.Helper()
+	operators, ok := update.(bson.M)
+	if !ok {
+		t.Fatalf("update is %T, want bson.M", update)
+	}
+
+	out := bson.M{}
+	for field, value := range existing {
+		out[field] = value
+	}
+	if set, ok := operators["$set"].(bson.M); ok {
+		for field, value := range set {
+			out[field] = value
+		}
+	}
+	if unset, ok := operators["$unset"].(bson.M); ok {
+		for field := range unset {
+			delete(out, field)
+		}
+	}
+	return out
+}
+
+// scannedResult is a completed scan of 8.8.8.8 with the given open ports
+func scannedResult(batchID string, ports ...int) *domain.ScanResult {
+	result := domain.NewScanResult("8.8.8.8", batchID, "worker-1")
+	result.IsUp = true
+	for _, number := range ports {
+		port := domain.NewPort(number)
+		port.Status = domain.PortStatusOpen
+		result.AddPort(port)
+	}
+	result.SetCompleted()
+	return result
+}
+
+func TestUpsertModelUpdatesOnlyConfiguredFields(t *testing.T) {
+	m := newTestManager()
+	if err := m.SetWriteMode(WriteModeUpsert, []string{"open_ports", "ports", "scan_end_time"}); err != nil {
+		t.Fatalf("SetWriteMode returned error: %v", err)
+	}
+
+	created := time.Now().Add(-24 * time.Hour).UTC().Truncate(time.Millisecond)
+	existing := bson.M{
+		"_id": "existing-id", "ip": "8.8.8.8", "batch_id": "batch-1", "worker_id": "worker-0",
+		"open_ports": 1, "created_at": created, "updated_at": created, "status": "completed",
+	}
+
+	model, err := m.upsertModel(scannedResult("batch-2", 22, 443), bson.M{"ip": "8.8.8.8"})
+	if err != nil {
+		t.Fatalf("upsertModel returned error: %v", err)
+	}
+	if !*model.Upsert || !reflect.DeepEqual(model.Filter, bson.M{"ip": "8.8.8.8"}) {
+		t.Errorf("model filter = %v, upsert = %v, want an upsert of 8.8.8.8", model.Filter, *model.Upsert)
+	}
+
+	operators := model.Update.(bson.M)
+	set := operators["$set"].(bson.M)
+	var setFields []string
+	for field := range set {
+		setFields = append(setFields, field)
+	}
+	want := map[string]bool{"open_ports": true, "ports": true, "scan_end_time": true, "updated_at": true}
+	if len(set) != len(want) {
+		t.Errorf("$set fields = %v, want only %v", setFields, want)
+	}
+	for field := range set {
+		if !want[field] {
+			t.Errorf("$set rewrites %q, which isn't configured for updates", field)
+		}
+	}
+	setOnInsert := operators["$setOnInsert"].(bson.M)
+	for _, field := range []string{"created_at", "batch_id", "worker_id"} {
+		if _, ok := setOnInsert[field]; !ok {
+			t.Errorf("$setOnInsert lacks %q, which the creating scan should write", field)
+		}
+	}
+
+	updated := applyUpdate(t, existing, model.Update)
+	if updated["open_ports"] != int32(2) {
+		t.Errorf("open_ports = %v, want the new count of 2", updated["open_ports"])
+	}
+	for _, field := range []string{"_id", "batch_id", "worker_id", "created_at", "status"} {
+		if !reflect.DeepEqual(updated[field], existing[field]) {
+			t.Errorf("%s = %v after the update, want it preserved as %v", field, updated[field], existing[field])
+		}
+	}
+	if updated["updated_at"] == existing["updated_at"] {
+		t.Error("updated_at not bumped")
+	}
+}
+
+func TestUpsertModelRewritesEverythingButCreationFields(t *testing.T) {
+	m := newTestManager()
+	if err := m.SetWriteMode(WriteModeUpsert, nil); err != nil {
+		t.Fatalf("SetWriteMode returned error: %v", err)
+	}
+
+	model, err := m.upsertModel(scannedResult("batch-2", 22), bson.M{"ip": "8.8.8.8"})
+	if err != nil {
+		t.Fatalf("upsertModel returned error: %v", err)
+	}
+	operators := model.Update.(bson.M)
+	set := operators["$set"].(bson.M)
+	for _, field := range []string{"batch_id", "worker_id", "open_ports", "ports", "status"} {
+		if _, ok := set[field]; !ok {
+			t.Errorf("$set lacks %q", field)
+		}
+	}
+	for _, field := range []string{"_id", "created_at", "ip"} {
+		if _, ok := set[field]; ok {
+			t.Errorf("$set rewrites the creation-only field %q", field)
+		}
+	}
+
+	// A field the new result leaves empty is removed rather than left stale
+	existing := bson.M{"ip": "8.8.8.8", "error": "connection refused", "status": "failed"}
+	if updated := applyUpdate(t, existing, model.Update); updated["error"] != nil {
+		t.Errorf("error = %v after a successful scan, want it unset", updated["error"])
+	}
+}
+
+func TestSetWriteModeRejectsUnknownAndCreationFields(t *testing.T) {
+	m := newTestManager()
+	for _, fields := range [][]string{{"not_a_field"}, {"created_at"}, {"_id"}} {
+		if err := m.SetWriteMode(WriteModeUpsert, fields); err == nil {
+			t.Errorf("SetWriteMode accepted update fields %v", fields)
+		}
+	}
+	if err := m.SetWriteMode("replace", nil); err == nil {
+		t.Error("SetWriteMode accepted an unknown mode")
+	}
+}
@@ -0,0 +1,137 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"port-scanner/internal/domain"
+)
+
+// ReverseDNSEnricherName is the name used to enable the enricher in scan.enrichers
+const ReverseDNSEnricherName = "reverse_dns"
+
+// maxCacheEntries bounds the PTR cache; expired entries are pruned when it fills up
+const maxCacheEntries = 10000
+
+// Ensure ReverseDNSEnricher implements Enricher interface
+var _ domain.Enricher = (*ReverseDNSEnricher)(nil)
+
+// cacheEntry holds the PTR names of an IP; names is empty when the IP has no PTR record
+type cacheEntry struct {
+	names   []string
+	expires time.Time
+}
+
+// ReverseDNSEnricher attaches the PTR names of each scanned IP to its result
+type ReverseDNSEnricher struct {
+	lookupAddr func(ctx context.Context, addr string) ([]string, error)
+	timeout    time.Duration
+	slots      chan struct{}
+	cacheTTL   time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+// NewReverseDNSEnricher creates an enricher that runs at most concurrency lookups at once,
+// each bounded by timeout. Answers, including missing PTR records, are cached for cacheTTL;
+// a zero TTL disables the cache.
+func NewReverseDNSEnricher(timeout time.Duration, concurrency int, cacheTTL time.Duration) *ReverseDNSEnricher {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &ReverseDNSEnricher{
+		lookupAddr: net.DefaultResolver.LookupAddr,
+		timeout:    timeout,
+		slots:      make(chan struct{}, concurrency),
+		cacheTTL:   cacheTTL,
+		cache:      make(map[string]cacheEntry),
+	}
+}
+
+// Name implements Enricher
+func (e *ReverseDNSEnricher) Name() string {
+	return ReverseDNSEnricherName
+}
+
+// Enrich looks up the PTR names of the result's IP. A missing PTR record is not an error.
+func (e *ReverseDNSEnricher) Enrich(result *domain.ScanResult) error {
+	names, err := e.lookup(result.IP)
+	if err != nil {
+		return err
+	}
+	result.ReverseDNS = names
+	return nil
+}
+
+// lookup returns the PTR names of an IP, from the cache when possible
+func (e *ReverseDNSEnricher) lookup(ip string) ([]string, error) {
+	if names, ok := e.cached(ip); ok {
+		return names, nil
+	}
+
+	e.slots <- struct{}{}
+	defer func() { <-e.slots }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	addrs, err := e.lookupAddr(ctx, ip)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+			return nil, err
+		}
+		addrs = nil
+	}
+
+	names := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		names = append(names, strings.TrimSuffix(addr, "."))
+	}
+	e.store(ip, names)
+	return names, nil
+}
+
+// cached returns the cached names of an IP if they haven't expired
+func (e *ReverseDNSEnricher) cached(ip string) ([]string, bool) {
+	if e.cacheTTL <= 0 {
+		return nil, false
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	entry, ok := e.cache[ip]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.names, true
+}
+
+// store caches the names of an IP, pruning expired entries when the cache is full
+func (e *ReverseDNSEnricher) store(ip string, names []string) {
+	if e.cacheTTL <= 0 {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	if len(e.cache) >= maxCacheEntries {
+		for cachedIP, entry := range e.cache {
+			if now.After(entry.expires) {
+				delete(e.cache, cachedIP)
+			}
+		}
+		if len(e.cache) >= maxCacheEntries {
+			return
+		}
+	}
+	e.cache[ip] = cacheEntry{names: names, expires: now.Add(e.cacheTTL)}
+}
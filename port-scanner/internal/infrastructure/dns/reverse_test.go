@@ -0,0 +1,106 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"port-scanner/internal/domain"
+)
+
+// stubResolver answers PTR lookups from a fixed table and counts them
+type stubResolver struct {
+	names   map[string][]string
+	lookups atomic.Int32
+}
+
+func (r *stubResolver) lookupAddr(ctx context.Context, addr string) ([]string, error) {
+	r.lookups.Add(1)
+	switch addr {
+	case "203.0.113.9":
+		return nil, &net.DNSError{Err: "server misbehaving", Name: addr, IsTemporary: true}
+	case "203.0.113.10":
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	names, ok := r.names[addr]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: addr, IsNotFound: true}
+	}
+	return names, nil
+}
+
+func newStubEnricher(cacheTTL time.Duration) (*ReverseDNSEnricher, *stubResolver) {
+	resolver := &stubResolver{names: map[string][]string{"8.8.8.8": {"dns.google."}}}
+	enricher := NewReverseDNSEnricher(20*time.Millisecond, 2, cacheTTL)
+	enricher.lookupAddr = resolver.lookupAddr
+	return enricher, resolver
+}
+
+func TestReverseDNSEnricherAttachesPTRNames(t *testing.T) {
+	enricher, _ := newStubEnricher(time.Minute)
+
+	result := domain.NewScanResult("8.8.8.8", "batch-1", "worker-1")
+	if err := enricher.Enrich(result); err != nil {
+		t.Fatalf("Enrich returned error: %v", err)
+	}
+	if !reflect.DeepEqual(result.ReverseDNS, []string{"dns.google"}) {
+		t.Errorf("reverse_dns = %v, want [dns.google]", result.ReverseDNS)
+	}
+}
+
+func TestReverseDNSEnricherDegradesGracefully(t *testing.T) {
+	enricher, _ := newStubEnricher(time.Minute)
+
+	// A missing PTR record isn't an error
+	result := domain.NewScanResult("1.1.1.1", "batch-1", "worker-1")
+	if err := enricher.Enrich(result); err != nil || len(result.ReverseDNS) != 0 {
+		t.Errorf("Enrich without a PTR record = %v, %v, want no names and no error", result.ReverseDNS, err)
+	}
+
+	// Resolver failures and timeouts are reported, leaving the result unchanged
+	for _, ip := range []string{"203.0.113.9", "203.0.113.10"} {
+		result := domain.NewScanResult(ip, "batch-1", "worker-1")
+		start := time.Now()
+		err := enricher.Enrich(result)
+		if err == nil || result.ReverseDNS != nil {
+			t.Errorf("Enrich(%s) = %v, %v, want an error and no names", ip, result.ReverseDNS, err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Enrich(%s) took %v, want it bounded by the lookup timeout", ip, elapsed)
+		}
+	}
+
+	if _, err := enricher.lookup("203.0.113.10"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("lookup past the timeout returned %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestReverseDNSEnricherCachesAnswers(t *testing.T) {
+	enricher, resolver := newStubEnricher(time.Minute)
+	for i := 0; i < 3; i++ {
+		enricher.Enrich(domain.NewScanResult("8.8.8.8", "batch-1", "worker-1"))
+		enricher.Enrich(domain.NewScanResult("1.1.1.1", "batch-1", "worker-1"))
+	}
+	if n := resolver.lookups.Load(); n != 2 {
+		t.Errorf("resolver queried %d times, want once per IP including the missing PTR record", n)
+	}
+
+	// Failures aren't cached
+	enricher.Enrich(domain.NewScanResult("203.0.113.9", "batch-1", "worker-1"))
+	enricher.Enrich(domain.NewScanResult("203.0.113.9", "batch-1", "worker-1"))
+	if n := resolver.lookups.Load(); n != 4 {
+		t.Errorf("resolver queried %d times, want failed lookups retried", n)
+	}
+
+	uncached, resolver := newStubEnricher(0)
+	uncached.Enrich(domain.NewScanResult("8.8.8.8", "batch-1", "worker-1"))
+	uncached.Enrich(domain.NewScanResult("8.8.8.8", "batch-1", "worker-1"))
+	if n := resolver.lookups.Load(); n != 2 {
+		t.Errorf("resolver queried %d times with the cache disabled, want 2", n)
+	}
+}
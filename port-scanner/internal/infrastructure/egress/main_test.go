@@ -0,0 +1,13 @@
+package egress
+
+import (
+	"os"
+	"testing"
+
+	"port-scanner/pkg/log"
+)
+
+func TestMain(m *testing.M) {
+	log.InitLogger("egress-test")
+	os.Exit(m.Run())
+}
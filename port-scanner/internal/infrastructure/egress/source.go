@@ -0,0 +1,147 @@
+// Package egress finds the public address the scanner's traffic leaves from, which differs
+// from the local address when the scanner runs behind NAT
+package egress
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"port-scanner/internal/domain"
+)
+
+// SourceIPEnricherName is the name used to enable the enricher in scan.enrichers
+const SourceIPEnricherName = "source_ip"
+
+// Methods of finding the external address
+const (
+	MethodSTUN = "stun"
+	MethodHTTP = "http"
+)
+
+// retryInterval is how long a failed lookup is reused before the next attempt, so an
+// unreachable service isn't queried for every result
+const retryInterval = 30 * time.Second
+
+// Ensure SourceIPEnricher implements Enricher interface
+var _ domain.Enricher = (*SourceIPEnricher)(nil)
+
+// Resolver returns the external address of this host
+type Resolver func(ctx context.Context) (net.IP, error)
+
+// NewResolver returns the resolver for a method: a STUN server as host:port, or the URL of
+// an echo service answering with the caller's address in plain text
+func NewResolver(method, server string) (Resolver, error) {
+	switch method {
+	case MethodSTUN:
+		return NewSTUNResolver(server), nil
+	case MethodHTTP:
+		return NewHTTPResolver(server), nil
+	default:
+		return nil, fmt.Errorf("unknown external IP method %q", method)
+	}
+}
+
+// NewHTTPResolver returns a resolver reading our address from an echo service such as
+// https://api.ipify.org
+func NewHTTPResolver(url string) Resolver {
+	return func(ctx context.Context) (net.IP, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query %s: %w", url, err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%s answered %s", url, resp.Status)
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 256))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", url, err)
+		}
+		ip := net.ParseIP(strings.TrimSpace(string(body)))
+		if ip == nil {
+			return nil, fmt.Errorf("%s answered with no IP address", url)
+		}
+		return ip, nil
+	}
+}
+
+// SourceIPEnricher stamps the scanner's external address on each result as
+// "scanner_source_ip", so results can be attributed to the address targets saw
+type SourceIPEnricher struct {
+	resolve  Resolver
+	timeout  time.Duration
+	cacheTTL time.Duration
+
+	mu      sync.Mutex
+	ip      string
+	err     error
+	expires time.Time
+}
+
+// NewSourceIPEnricher creates an enricher looking the address up with resolve, each lookup
+// bounded by timeout. The address is reused for cacheTTL; a zero TTL keeps the first one found.
+func NewSourceIPEnricher(resolve Resolver, timeout, cacheTTL time.Duration) *SourceIPEnricher {
+	return &SourceIPEnricher{
+		resolve:  resolve,
+		timeout:  timeout,
+		cacheTTL: cacheTTL,
+	}
+}
+
+// Name implements Enricher
+func (e *SourceIPEnricher) Name() string {
+	return SourceIPEnricherName
+}
+
+// Enrich records the external address in the result's metadata
+func (e *SourceIPEnricher) Enrich(result *domain.ScanResult) error {
+	ip, err := e.SourceIP()
+	if err != nil {
+		return err
+	}
+	result.SetMetadata("scanner_source_ip", ip)
+	return nil
+}
+
+// SourceIP returns the external address, looking it up when the cached one has expired.
+// Failures are cached for a short while too.
+func (e *SourceIPEnricher) SourceIP() (string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	if (e.ip != "" || e.err != nil) && (e.expires.IsZero() || now.Before(e.expires)) {
+		return e.ip, e.err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	ip, err := e.resolve(ctx)
+	if err != nil {
+		e.expires = now.Add(retryInterval)
+		// Keep serving the last address found while the service is unreachable
+		if e.ip == "" {
+			e.err = fmt.Errorf("failed to find external IP: %w", err)
+		}
+		return e.ip, e.err
+	}
+
+	e.ip, e.err = ip.String(), nil
+	e.expires = time.Time{}
+	if e.cacheTTL > 0 {
+		e.expires = now.Add(e.cacheTTL)
+	}
+	return e.ip, nil
+}
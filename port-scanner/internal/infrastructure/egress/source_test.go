@@ -0,0 +1,124 @@
+package egress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"port-scanner/internal/domain"
+)
+
+// stubResolver returns canned addresses or errors in order, counting the lookups
+type stubResolver struct {
+	mu      sync.Mutex
+	answers []interface{} // net.IP or error
+	calls   int
+}
+
+func (s *stubResolver) resolve(ctx context.Context) (net.IP, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	answer := s.answers[min(s.calls, len(s.answers)-1)]
+	s.calls++
+	if err, ok := answer.(error); ok {
+		return nil, err
+	}
+	return answer.(net.IP), nil
+}
+
+func TestSourceIPEnricherStampsResults(t *testing.T) {
+	stub := &stubResolver{answers: []interface{}{net.ParseIP("203.0.113.50")}}
+	enricher := NewSourceIPEnricher(stub.resolve, time.Second, time.Hour)
+
+	for _, ip := range []string{"8.8.8.8", "1.1.1.1"} {
+		result := domain.NewScanResult(ip, "batch-1", "worker-1")
+		if err := enricher.Enrich(result); err != nil {
+			t.Fatalf("Enrich returned error: %v", err)
+		}
+		if got := result.Metadata["scanner_source_ip"]; got != "203.0.113.50" {
+			t.Errorf("scanner_source_ip = %v, want 203.0.113.50", got)
+		}
+	}
+	if stub.calls != 1 {
+		t.Errorf("resolver called %d times, want the address cached after the first lookup", stub.calls)
+	}
+}
+
+func TestSourceIPEnricherRefreshesAfterTTL(t *testing.T) {
+	stub := &stubResolver{answers: []interface{}{net.ParseIP("203.0.113.50"), net.ParseIP("203.0.113.51")}}
+	enricher := NewSourceIPEnricher(stub.resolve, time.Second, 20*time.Millisecond)
+
+	if ip, _ := enricher.SourceIP(); ip != "203.0.113.50" {
+		t.Fatalf("SourceIP() = %q, want 203.0.113.50", ip)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if ip, _ := enricher.SourceIP(); ip != "203.0.113.51" {
+		t.Errorf("SourceIP() after the TTL = %q, want the new address 203.0.113.51", ip)
+	}
+}
+
+func TestSourceIPEnricherCachesFailures(t *testing.T) {
+	stub := &stubResolver{answers: []interface{}{errors.New("stun server unreachable")}}
+	enricher := NewSourceIPEnricher(stub.resolve, time.Second, time.Hour)
+
+	result := domain.NewScanResult("8.8.8.8", "batch-1", "worker-1")
+	if err := enricher.Enrich(result); err == nil {
+		t.Fatal("Enrich succeeded without an external IP")
+	}
+	if _, ok := result.Metadata["scanner_source_ip"]; ok {
+		t.Error("scanner_source_ip set without an external IP")
+	}
+	enricher.SourceIP()
+	if stub.calls != 1 {
+		t.Errorf("resolver called %d times, want the failure reused until the retry interval", stub.calls)
+	}
+}
+
+func TestSourceIPEnricherKeepsLastAddressWhileUnreachable(t *testing.T) {
+	stub := &stubResolver{answers: []interface{}{net.ParseIP("203.0.113.50"), errors.New("timeout")}}
+	enricher := NewSourceIPEnricher(stub.resolve, time.Second, 10*time.Millisecond)
+
+	enricher.SourceIP()
+	time.Sleep(20 * time.Millisecond)
+	ip, err := enricher.SourceIP()
+	if err != nil || ip != "203.0.113.50" {
+		t.Errorf("SourceIP() = %q, %v, want the last address found", ip, err)
+	}
+}
+
+func TestHTTPResolverReadsEchoedAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "198.51.100.23")
+	}))
+	defer server.Close()
+
+	ip, err := NewHTTPResolver(server.URL)(context.Background())
+	if err != nil || !ip.Equal(net.ParseIP("198.51.100.23")) {
+		t.Errorf("resolver = %v, %v, want 198.51.100.23", ip, err)
+	}
+
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html>not an address</html>")
+	}))
+	defer broken.Close()
+	if _, err := NewHTTPResolver(broken.URL)(context.Background()); err == nil {
+		t.Error("resolver accepted a body without an address")
+	}
+}
+
+func TestNewResolverRejectsUnknownMethod(t *testing.T) {
+	for _, method := range []string{MethodSTUN, MethodHTTP} {
+		if _, err := NewResolver(method, "example.com:3478"); err != nil {
+			t.Errorf("NewResolver(%q) returned error: %v", method, err)
+		}
+	}
+	if _, err := NewResolver("carrier-pigeon", ""); err == nil {
+		t.Error("NewResolver accepted an unknown method")
+	}
+}
@@ -0,0 +1,135 @@
+package egress
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// STUN message fields (RFC 5389)
+const (
+	stunBindingRequest  = 0x0001
+	stunBindingResponse = 0x0101
+	stunMagicCookie     = 0x2112A442
+	stunHeaderLength    = 20
+
+	stunAttrMappedAddress    = 0x0001
+	stunAttrXorMappedAddress = 0x0020
+)
+
+// errNoMappedAddress is returned when a STUN response carries no mapped address
+var errNoMappedAddress = errors.New("STUN response has no mapped address")
+
+// NewSTUNResolver returns a resolver asking a STUN server (host:port) which address our
+// UDP packets come from
+func NewSTUNResolver(server string) Resolver {
+	return func(ctx context.Context) (net.IP, error) {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "udp", server)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reach STUN server %s: %w", server, err)
+		}
+		defer conn.Close()
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(deadline)
+		}
+
+		request := make([]byte, stunHeaderLength)
+		binary.BigEndian.PutUint16(request[0:2], stunBindingRequest)
+		binary.BigEndian.PutUint32(request[4:8], stunMagicCookie)
+		if _, err := rand.Read(request[8:20]); err != nil {
+			return nil, fmt.Errorf("failed to create STUN transaction ID: %w", err)
+		}
+		if _, err := conn.Write(request); err != nil {
+			return nil, fmt.Errorf("failed to send STUN request to %s: %w", server, err)
+		}
+
+		response := make([]byte, 1500)
+		n, err := conn.Read(response)
+		if err != nil {
+			return nil, fmt.Errorf("no STUN response from %s: %w", server, err)
+		}
+		return parseSTUNResponse(response[:n], request[8:20])
+	}
+}
+
+// parseSTUNResponse extracts the mapped address from a binding response to the given
+// transaction, preferring XOR-MAPPED-ADDRESS over the legacy MAPPED-ADDRESS
+func parseSTUNResponse(response, transactionID []byte) (net.IP, error) {
+	if len(response) < stunHeaderLength ||
+		binary.BigEndian.Uint16(response[0:2]) != stunBindingResponse ||
+		binary.BigEndian.Uint32(response[4:8]) != stunMagicCookie ||
+		string(response[8:20]) != string(transactionID) {
+		return nil, errors.New("invalid STUN binding response")
+	}
+
+	length := int(binary.BigEndian.Uint16(response[2:4]))
+	if stunHeaderLength+length > len(response) {
+		return nil, errors.New("truncated STUN response")
+	}
+	attributes := response[stunHeaderLength : stunHeaderLength+length]
+
+	var mapped net.IP
+	for len(attributes) >= 4 {
+		attrType := binary.BigEndian.Uint16(attributes[0:2])
+		attrLength := int(binary.BigEndian.Uint16(attributes[2:4]))
+		if 4+attrLength > len(attributes) {
+			break
+		}
+		value := attributes[4 : 4+attrLength]
+
+		switch attrType {
+		case stunAttrXorMappedAddress:
+			if ip := stunAddress(value, response[4:20]); ip != nil {
+				return ip, nil
+			}
+		case stunAttrMappedAddress:
+			mapped = stunAddress(value, nil)
+		}
+
+		// Attributes are padded to a multiple of 4 bytes
+		padded := (attrLength + 3) &^ 3
+		if 4+padded > len(attributes) {
+			break
+		}
+		attributes = attributes[4+padded:]
+	}
+
+	if mapped == nil {
+		return nil, errNoMappedAddress
+	}
+	return mapped, nil
+}
+
+// stunAddress decodes an address attribute; a non-nil xorKey (magic cookie followed by the
+// transaction ID) undoes the XOR of XOR-MAPPED-ADDRESS
+func stunAddress(value, xorKey []byte) net.IP {
+	if len(value) < 4 {
+		return nil
+	}
+
+	var size int
+	switch value[1] {
+	case 0x01:
+		size = net.IPv4len
+	case 0x02:
+		size = net.IPv6len
+	default:
+		return nil
+	}
+	if len(value) < 4+size {
+		return nil
+	}
+
+	ip := make(net.IP, size)
+	copy(ip, value[4:4+size])
+	if xorKey != nil {
+		for i := range ip {
+			ip[i] ^= xorKey[i]
+		}
+	}
+	return ip
+}
@@ -0,0 +1,94 @@
+package egress
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+// stunServer answers binding requests on a loopback UDP port, reporting mapped as the
+// client's address in an XOR-MAPPED-ADDRESS attribute
+func stunServer(t *testing.T, mapped net.IP) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		request := make([]byte, 1500)
+		for {
+			n, addr, err := conn.ReadFrom(request)
+			if err != nil {
+				return
+			}
+			if n >= stunHeaderLength {
+				conn.WriteTo(stunResponse(request[8:20], mapped), addr)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+// stunResponse builds a binding response to a transaction carrying an XOR-MAPPED-ADDRESS
+func stunResponse(transactionID []byte, mapped net.IP) []byte {
+	ip := mapped.To4()
+	cookie := make([]byte, 4)
+	binary.BigEndian.PutUint32(cookie, stunMagicCookie)
+	value := make([]byte, 4+len(ip))
+	value[1] = 0x01
+	binary.BigEndian.PutUint16(value[2:4], 3478^uint16(stunMagicCookie>>16))
+	for i := range ip {
+		value[4+i] = ip[i] ^ cookie[i]
+	}
+
+	response := make([]byte, stunHeaderLength+4+len(value))
+	binary.BigEndian.PutUint16(response[0:2], stunBindingResponse)
+	binary.BigEndian.PutUint16(response[2:4], uint16(4+len(value)))
+	binary.BigEndian.PutUint32(response[4:8], stunMagicCookie)
+	copy(response[8:20], transactionID)
+	binary.BigEndian.PutUint16(response[20:22], stunAttrXorMappedAddress)
+	binary.BigEndian.PutUint16(response[22:24], uint16(len(value)))
+	copy(response[24:], value)
+	return response
+}
+
+func TestSTUNResolverReadsMappedAddress(t *testing.T) {
+	server := stunServer(t, net.ParseIP("203.0.113.77"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	ip, err := NewSTUNResolver(server)(ctx)
+	if err != nil {
+		t.Fatalf("resolver returned error: %v", err)
+	}
+	if !ip.Equal(net.ParseIP("203.0.113.77")) {
+		t.Errorf("resolver = %v, want 203.0.113.77", ip)
+	}
+}
+
+func TestParseSTUNResponseRejectsInvalidResponses(t *testing.T) {
+	transactionID := []byte("0123456789ab")
+	valid := stunResponse(transactionID, net.ParseIP("203.0.113.77"))
+
+	if ip, err := parseSTUNResponse(valid, transactionID); err != nil || !ip.Equal(net.ParseIP("203.0.113.77")) {
+		t.Fatalf("parseSTUNResponse = %v, %v, want 203.0.113.77", ip, err)
+	}
+
+	otherTransaction := stunResponse([]byte("ba9876543210"), net.ParseIP("203.0.113.77"))
+	noAttributes := append([]byte(nil), valid[:stunHeaderLength]...)
+	binary.BigEndian.PutUint16(noAttributes[2:4], 0)
+	for name, response := range map[string][]byte{
+		"short":             valid[:10],
+		"other transaction": otherTransaction,
+		"truncated":         valid[:len(valid)-4],
+		"no address":        noAttributes,
+	} {
+		if _, err := parseSTUNResponse(response, transactionID); err == nil {
+			t.Errorf("%s: parseSTUNResponse accepted an invalid response", name)
+		}
+	}
+}
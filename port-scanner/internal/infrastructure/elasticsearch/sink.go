@@ -0,0 +1,257 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"port-scanner/internal/domain"
+	"port-scanner/pkg/log"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esutil"
+	"go.uber.org/zap"
+)
+
+// indexMapping maps scan results so ports can be queried as nested objects
+const indexMapping = `{
+  "mappings": {
+    "properties": {
+      "ip":               {"type": "ip"},
+      "is_up":            {"type": "boolean"},
+      "status":           {"type": "keyword"},
+      "error":            {"type": "text"},
+      "batch_id":         {"type": "keyword"},
+      "worker_id":        {"type": "keyword"},
+      "scan_start_time":  {"type": "date"},
+      "scan_end_time":    {"type": "date"},
+      "scan_duration_ms": {"type": "long"},
+      "ping_time_ms":     {"type": "long"},
+      "open_ports":       {"type": "integer"},
+      "open_filtered_ports": {"type": "integer"},
+      "total_ports":      {"type": "integer"},
+      "tags":             {"type": "flattened"},
+      "priority":         {"type": "integer"},
+      "metadata":         {"type": "flattened"},
+      "reverse_dns":      {"type": "keyword"},
+      "ports": {
+        "type": "nested",
+        "properties": {
+          "number":           {"type": "integer"},
+          "status":           {"type": "keyword"},
+          "service":          {"type": "keyword"},
+          "version":          {"type": "keyword"},
+          "banner":           {"type": "text"},
+          "confidence":       {"type": "keyword"},
+          "response_time_ms": {"type": "long"}
+        }
+      }
+    }
+  }
+}`
+
+// Config holds the Elasticsearch sink settings
+type Config struct {
+	Addresses     []string
+	Username      string
+	Password      string
+	Index         string
+	FlushBytes    int
+	FlushInterval time.Duration
+
+	// Transport replaces the client's HTTP transport when set
+	Transport http.RoundTripper
+}
+
+// Sink bulk-indexes scan results into Elasticsearch
+type Sink struct {
+	indexer esutil.BulkIndexer
+	index   string
+}
+
+// Ensure Sink implements ResultSink interface
+var _ domain.ResultSink = (*Sink)(nil)
+
+// scanResultDocument is the indexed representation of a scan result
+type scanResultDocument struct {
+	IP             string            `json:"ip"`
+	IsUp           bool              `json:"is_up"`
+	Status         string            `json:"status"`
+	Error          string            `json:"error,omitempty"`
+	BatchID        string            `json:"batch_id"`
+	WorkerID       string            `json:"worker_id"`
+	ScanStartTime  time.Time         `json:"scan_start_time"`
+	ScanEndTime    time.Time         `json:"scan_end_time"`
+	ScanDurationMs int64             `json:"scan_duration_ms"`
+	PingTimeMs     int64             `json:"ping_time_ms"`
+	OpenPorts      int               `json:"open_ports"`
+	TotalPorts     int               `json:"total_ports"`
+	Tags           map[string]string `json:"tags,omitempty"`
+	Priority       int               `json:"priority,omitempty"`
+	Ports          []portDocument    `json:"ports"`
+
+	OpenFilteredPorts int `json:"open_filtered_ports"`
+
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+	ReverseDNS []string               `json:"reverse_dns,omitempty"`
+}
+
+// portDocument is the indexed representation of a scanned port
+type portDocument struct {
+	Number          int    `json:"number"`
+	Status          string `json:"status"`
+	Service         string `json:"service,omitempty"`
+	Version         string `json:"version,omitempty"`
+	Banner          string `json:"banner,omitempty"`
+	Confidence      string `json:"confidence,omitempty"`
+	ConfidenceScore int    `json:"confidence_score"`
+	ResponseTimeMs  int64  `json:"response_time_ms"`
+}
+
+// NewSink connects to Elasticsearch, ensures the index exists and starts the bulk indexer
+func NewSink(config Config) (*Sink, error) {
+	client, err := elasticsearch.NewClient(elasticsearch.Config{
+		Addresses: config.Addresses,
+		Username:  config.Username,
+		Password:  config.Password,
+		Transport: config.Transport,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Elasticsearch client: %w", err)
+	}
+
+	if err := ensureIndex(client, config.Index); err != nil {
+		return nil, err
+	}
+
+	indexer, err := esutil.NewBulkIndexer(esutil.BulkIndexerConfig{
+		Client:        client,
+		Index:         config.Index,
+		FlushBytes:    config.FlushBytes,
+		FlushInterval: config.FlushInterval,
+		OnError: func(ctx context.Context, err error) {
+			log.L().Error("Elasticsearch bulk request failed", zap.String("event", "es_bulk_failed"), zap.Error(err))
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bulk indexer: %w", err)
+	}
+
+	log.L().Info("Elasticsearch sink ready", zap.String("index", config.Index))
+
+	return &Sink{
+		indexer: indexer,
+		index:   config.Index,
+	}, nil
+}
+
+// ensureIndex creates the index with the scan result mapping if it doesn't exist
+func ensureIndex(client *elasticsearch.Client, index string) error {
+	res, err := client.Indices.Exists([]string{index})
+	if err != nil {
+		return fmt.Errorf("failed to check index %s: %w", index, err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	res, err = client.Indices.Create(index, client.Indices.Create.WithBody(strings.NewReader(indexMapping)))
+	if err != nil {
+		return fmt.Errorf("failed to create index %s: %w", index, err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("failed to create index %s: %s", index, res.String())
+	}
+	return nil
+}
+
+// SaveScanResult queues a scan result for bulk indexing
+func (s *Sink) SaveScanResult(result *domain.ScanResult) error {
+	body, err := json.Marshal(convertScanResult(result))
+	if err != nil {
+		return fmt.Errorf("failed to marshal scan result: %w", err)
+	}
+
+	err = s.indexer.Add(context.Background(), esutil.BulkIndexerItem{
+		Action: "index",
+		Body:   bytes.NewReader(body),
+		OnFailure: func(ctx context.Context, item esutil.BulkIndexerItem, res esutil.BulkIndexerResponseItem, err error) {
+			if err == nil {
+				err = fmt.Errorf("%s: %s", res.Error.Type, res.Error.Reason)
+			}
+			log.L().Error("Failed to index scan result", zap.String("event", "es_index_failed"),
+				zap.String("ip", result.IP), zap.Error(err))
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to queue scan result: %w", err)
+	}
+
+	return nil
+}
+
+// Close flushes pending results and stops the bulk indexer
+func (s *Sink) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := s.indexer.Close(ctx); err != nil {
+		return fmt.Errorf("failed to flush bulk indexer: %w", err)
+	}
+
+	stats := s.indexer.Stats()
+	log.L().Info("Elasticsearch sink closed", zap.String("index", s.index),
+		zap.Uint64("indexed", stats.NumIndexed), zap.Uint64("failed", stats.NumFailed))
+	return nil
+}
+
+// convertScanResult converts a domain scan result to its indexed representation
+func convertScanResult(result *domain.ScanResult) *scanResultDocument {
+	ports := make([]portDocument, 0, len(result.Ports))
+	for _, port := range result.Ports {
+		doc := portDocument{
+			Number:         port.Number,
+			Status:         string(port.Status),
+			Service:        port.Service,
+			Version:        port.Version,
+			Banner:         port.Banner,
+			ResponseTimeMs: port.ResponseTime.Milliseconds(),
+		}
+		if port.BannerInfo != nil {
+			doc.Confidence = port.BannerInfo.Confidence
+			doc.ConfidenceScore = port.BannerInfo.ConfidenceScore
+		}
+		ports = append(ports, doc)
+	}
+
+	return &scanResultDocument{
+		IP:             result.IP,
+		IsUp:           result.IsUp,
+		Status:         string(result.Status),
+		Error:          result.Error,
+		BatchID:        result.BatchID,
+		WorkerID:       result.WorkerID,
+		ScanStartTime:  result.ScanStartTime,
+		ScanEndTime:    result.ScanEndTime,
+		ScanDurationMs: result.GetScanDuration().Milliseconds(),
+		PingTimeMs:     result.PingTime.Milliseconds(),
+		OpenPorts:      len(result.GetOpenPorts()),
+		TotalPorts:     len(result.Ports),
+		Tags:           result.Tags,
+		Priority:       result.Priority,
+		Ports:          ports,
+
+		OpenFilteredPorts: len(result.GetOpenFilteredPorts()),
+
+		Metadata:   result.Metadata,
+		ReverseDNS: result.ReverseDNS,
+	}
+}
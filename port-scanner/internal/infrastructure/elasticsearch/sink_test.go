@@ -0,0 +1,186 @@
+package elasticsearch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"port-scanner/internal/domain"
+	"port-scanner/pkg/log"
+)
+
+func TestMain(m *testing.M) {
+	log.InitLogger("elasticsearch-test")
+	os.Exit(m.Run())
+}
+
+// recordedRequest is a request seen by mockTransport
+type recordedRequest struct {
+	method string
+	path   string
+	body   []byte
+}
+
+// mockTransport answers Elasticsearch requests without a cluster and records them
+type mockTransport struct {
+	mu          sync.Mutex
+	requests    []recordedRequest
+	indexExists bool
+}
+
+func (m *mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+	}
+	m.mu.Lock()
+	m.requests = append(m.requests, recordedRequest{method: req.Method, path: req.URL.Path, body: body})
+	m.mu.Unlock()
+
+	status, response := http.StatusOK, `{"acknowledged": true}`
+	switch {
+	case req.Method == http.MethodHead && !m.indexExists:
+		status, response = http.StatusNotFound, ""
+	case strings.HasSuffix(req.URL.Path, "/_bulk"):
+		// Acknowledge every action of the bulk request
+		var items []string
+		for i := 0; i < bytes.Count(body, []byte("\n"))/2; i++ {
+			items = append(items, `{"index": {"status": 201}}`)
+		}
+		response = `{"errors": false, "items": [` + strings.Join(items, ",") + `]}`
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("X-Elastic-Product", "Elasticsearch")
+	return &http.Response{
+		StatusCode: status,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(response)),
+		Request:    req,
+	}, nil
+}
+
+// find returns the recorded requests matching method whose path ends with suffix
+func (m *mockTransport) find(method, suffix string) []recordedRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var found []recordedRequest
+	for _, req := range m.requests {
+		if req.method == method && strings.HasSuffix(req.path, suffix) {
+			found = append(found, req)
+		}
+	}
+	return found
+}
+
+func newTestSink(t *testing.T, transport *mockTransport) *Sink {
+	t.Helper()
+	sink, err := NewSink(Config{
+		Addresses: []string{"http://elasticsearch.test:9200"},
+		Index:     "scan-results",
+		Transport: transport,
+	})
+	if err != nil {
+		t.Fatalf("NewSink returned error: %v", err)
+	}
+	return sink
+}
+
+func TestNewSinkCreatesIndexWithMapping(t *testing.T) {
+	transport := &mockTransport{}
+	newTestSink(t, transport).Close()
+
+	created := transport.find(http.MethodPut, "/scan-results")
+	if len(created) != 1 {
+		t.Fatalf("got %d index creation requests, want 1", len(created))
+	}
+	var mapping struct {
+		Mappings struct {
+			Properties map[string]struct {
+				Type string `json:"type"`
+			} `json:"properties"`
+		} `json:"mappings"`
+	}
+	if err := json.Unmarshal(created[0].body, &mapping); err != nil {
+		t.Fatalf("index mapping is not JSON: %v", err)
+	}
+	for field, want := range map[string]string{"ip": "ip", "ports": "nested", "batch_id": "keyword"} {
+		if got := mapping.Mappings.Properties[field].Type; got != want {
+			t.Errorf("mapping of %s = %q, want %q", field, got, want)
+		}
+	}
+
+	// An existing index is left alone
+	existing := &mockTransport{indexExists: true}
+	newTestSink(t, existing).Close()
+	if created := existing.find(http.MethodPut, "/scan-results"); len(created) != 0 {
+		t.Errorf("existing index was created again: %d requests", len(created))
+	}
+}
+
+func TestSinkBulkIndexesResults(t *testing.T) {
+	transport := &mockTransport{indexExists: true}
+	sink := newTestSink(t, transport)
+
+	for _, ip := range []string{"192.0.2.10", "192.0.2.11"} {
+		result := domain.NewScanResult(ip, "batch-1", "worker-1")
+		port := domain.NewPort(22)
+		port.Status = domain.PortStatusOpen
+		port.Service = "ssh"
+		port.Version = "OpenSSH_9.6"
+		result.AddPort(port)
+		result.SetCompleted()
+		if err := sink.SaveScanResult(result); err != nil {
+			t.Fatalf("SaveScanResult returned error: %v", err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	bulks := transport.find(http.MethodPost, "/_bulk")
+	if len(bulks) != 1 {
+		t.Fatalf("got %d bulk requests, want 1", len(bulks))
+	}
+	if !strings.HasPrefix(bulks[0].path, "/scan-results/") {
+		t.Errorf("bulk request path = %q, want it under the scan-results index", bulks[0].path)
+	}
+
+	// The body is NDJSON: an action line followed by the document, for each result
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(bulks[0].body))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if len(lines) != 4 {
+		t.Fatalf("bulk body has %d lines, want 4:\n%s", len(lines), bulks[0].body)
+	}
+	for i, ip := range []string{"192.0.2.10", "192.0.2.11"} {
+		var action map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(lines[2*i]), &action); err != nil {
+			t.Fatalf("action line %q is not JSON: %v", lines[2*i], err)
+		}
+		if _, ok := action["index"]; !ok {
+			t.Errorf("action line %q is not an index action", lines[2*i])
+		}
+
+		var doc scanResultDocument
+		if err := json.Unmarshal([]byte(lines[2*i+1]), &doc); err != nil {
+			t.Fatalf("document line %q is not JSON: %v", lines[2*i+1], err)
+		}
+		if doc.IP != ip || doc.BatchID != "batch-1" || doc.OpenPorts != 1 {
+			t.Errorf("document %d = %+v, want %s with one open port", i, doc, ip)
+		}
+		if len(doc.Ports) != 1 || doc.Ports[0].Service != "ssh" || doc.Ports[0].Version != "OpenSSH_9.6" {
+			t.Errorf("document %d ports = %+v, want the ssh port", i, doc.Ports)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"port-scanner/internal/infrastructure/database"
+	"port-scanner/internal/testutil"
+)
+
+// annotationStore is a Store keeping annotations in memory in the order they were added
+type annotationStore struct {
+	Store
+	annotations []database.AnnotationDocument
+}
+
+// AddAnnotation stores a note for an IP
+func (s *annotationStore) AddAnnotation(ip, author, note string) (*database.AnnotationDocument, error) {
+	annotation := database.AnnotationDocument{IP: ip, Author: author, Note: note, CreatedAt: time.Now()}
+	s.annotations = append(s.annotations, annotation)
+	return &annotation, nil
+}
+
+// GetAnnotations returns the notes for an IP, oldest first
+func (s *annotationStore) GetAnnotations(ip string) ([]database.AnnotationDocument, error) {
+	annotations := []database.AnnotationDocument{}
+	for _, annotation := range s.annotations {
+		if annotation.IP == ip {
+			annotations = append(annotations, annotation)
+		}
+	}
+	return annotations, nil
+}
+
+func TestAnnotationsAreReturnedInOrder(t *testing.T) {
+	h := NewHandler(testutil.NewFakeScanEngine(nil), testutil.NewFakeScanner(), &annotationStore{})
+
+	notes := []struct{ author, note string }{
+		{"alice", "false positive"},
+		{"bob", "confirmed vuln"},
+		{"alice", "patched, rescan pending"},
+	}
+	for _, n := range notes {
+		body := `{"author": "` + n.author + `", "note": "` + n.note + `"}`
+		status, response := postScan(t, h, "/api/v1/db/result/10.0.0.1/annotations", body)
+		if status != http.StatusCreated {
+			t.Fatalf("status = %d, want %d: %v", status, http.StatusCreated, response)
+		}
+		if response["author"] != n.author || response["note"] != n.note || response["created_at"] == nil {
+			t.Errorf("created annotation = %v, want %s's note with a timestamp", response, n.author)
+		}
+	}
+	postScan(t, h, "/api/v1/db/result/10.0.0.2/annotations", `{"author": "carol", "note": "other host"}`)
+
+	recorder := serve(h, http.MethodGet, "/api/v1/db/result/10.0.0.1/annotations", "")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+	var response struct {
+		IP          string                        `json:"ip"`
+		Count       int                           `json:"count"`
+		Annotations []database.AnnotationDocument `json:"annotations"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid JSON response %q: %v", recorder.Body.String(), err)
+	}
+	if response.IP != "10.0.0.1" || response.Count != len(notes) || len(response.Annotations) != len(notes) {
+		t.Fatalf("response = %+v, want the 3 notes of 10.0.0.1", response)
+	}
+	for i, n := range notes {
+		got := response.Annotations[i]
+		if got.Author != n.author || got.Note != n.note {
+			t.Errorf("annotation %d = %s: %q, want %s: %q", i, got.Author, got.Note, n.author, n.note)
+		}
+		if i > 0 && got.CreatedAt.Before(response.Annotations[i-1].CreatedAt) {
+			t.Errorf("annotation %d was created before the one preceding it", i)
+		}
+	}
+}
+
+func TestAddAnnotationRequiresAuthorAndNote(t *testing.T) {
+	store := &annotationStore{}
+	h := NewHandler(testutil.NewFakeScanEngine(nil), testutil.NewFakeScanner(), store)
+
+	for _, body := range []string{`{"author": "alice"}`, `{"note": "orphan"}`, `not json`} {
+		if status, _ := postScan(t, h, "/api/v1/db/result/10.0.0.1/annotations", body); status != http.StatusBadRequest {
+			t.Errorf("POST %s status = %d, want %d", body, status, http.StatusBadRequest)
+		}
+	}
+	if len(store.annotations) != 0 {
+		t.Errorf("%d annotations stored from invalid requests, want 0", len(store.annotations))
+	}
+}
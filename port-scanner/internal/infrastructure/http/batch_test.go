@@ -0,0 +1,81 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"port-scanner/internal/domain"
+	"port-scanner/internal/testutil"
+)
+
+// concurrencyScanner is a FakeScanner whose scans take a while, recording how many ran at once
+type concurrencyScanner struct {
+	*testutil.FakeScanner
+	active int64
+	peak   int64
+}
+
+func (s *concurrencyScanner) ScanIP(ip string, config *domain.ScanConfig, batchID string, workerID string) (*domain.ScanResult, error) {
+	active := atomic.AddInt64(&s.active, 1)
+	for {
+		peak := atomic.LoadInt64(&s.peak)
+		if active <= peak || atomic.CompareAndSwapInt64(&s.peak, peak, active) {
+			break
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	atomic.AddInt64(&s.active, -1)
+	return s.FakeScanner.ScanIP(ip, config, batchID, workerID)
+}
+
+// batchBody returns a batch request for n IPs with the given IP-level concurrency
+func batchBody(n, concurrency int) string {
+	ips := make([]string, n)
+	for i := range ips {
+		ips[i] = fmt.Sprintf(`"10.0.0.%d"`, i+1)
+	}
+	return fmt.Sprintf(`{"ips": [%s], "batch_id": "batch-1", "batch_concurrency": %d}`, strings.Join(ips, ", "), concurrency)
+}
+
+func TestScanBatchRespectsBatchConcurrency(t *testing.T) {
+	config := domain.NewDefaultScanConfig()
+	config.MaxBatchConcurrency = 4
+
+	tests := []struct {
+		name      string
+		requested int
+		want      int64
+	}{
+		{"requested below the server max", 2, 2},
+		{"default to the server max", 0, 4},
+		{"capped by the server max", 50, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := &concurrencyScanner{FakeScanner: testutil.NewFakeScanner()}
+			h := NewHandler(testutil.NewFakeScanEngine(config), scanner, nil)
+
+			status, response := postScan(t, h, "/api/v1/scan/batch", batchBody(12, tt.requested))
+			if status != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %v", status, http.StatusOK, response)
+			}
+			if got := len(scanner.Calls); got != 12 {
+				t.Errorf("scanned %d IPs, want 12", got)
+			}
+			if got := atomic.LoadInt64(&scanner.peak); got != tt.want {
+				t.Errorf("peak concurrent IP scans = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanBatchRejectsNegativeBatchConcurrency(t *testing.T) {
+	h, _ := newTestHandler()
+	if status, _ := postScan(t, h, "/api/v1/scan/batch", batchBody(2, -1)); status != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", status, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,76 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"port-scanner/internal/infrastructure/database"
+	"port-scanner/internal/testutil"
+)
+
+// batchStore is a Store holding one batch of results, returned oldest first up to the limit
+type batchStore struct {
+	Store
+	docs  []*database.ScanResultDocument
+	limit int
+}
+
+func (s *batchStore) GetScanResultsByBatch(batchID string, projection *database.Projection, limit int) ([]*database.ScanResultDocument, error) {
+	s.limit = limit
+	if limit > 0 && limit < len(s.docs) {
+		return s.docs[:limit], nil
+	}
+	return s.docs, nil
+}
+
+func (s *batchStore) CountByBatch(batchID string) (int64, error) {
+	return int64(len(s.docs)), nil
+}
+
+func newBatchStore(size int) *batchStore {
+	store := &batchStore{}
+	for i := 0; i < size; i++ {
+		store.docs = append(store.docs, &database.ScanResultDocument{IP: fmt.Sprintf("8.8.8.%d", i), BatchID: "batch-1", Status: "completed"})
+	}
+	return store
+}
+
+func TestBatchResultsAreCappedWithTruncationFlag(t *testing.T) {
+	store := newBatchStore(12)
+	h := NewHandler(testutil.NewFakeScanEngine(nil), testutil.NewFakeScanner(), store)
+	h.SetMaxBatchResults(5)
+
+	status, response := requestWithKey(t, h, http.MethodGet, "/api/v1/db/batch/batch-1", "")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %v", status, http.StatusOK, response)
+	}
+	if store.limit != 6 {
+		t.Errorf("store asked for %d results, want one past the cap of 5", store.limit)
+	}
+	results, _ := response["results"].([]interface{})
+	if len(results) != 5 || response["count"] != float64(5) {
+		t.Errorf("returned %d results with count %v, want the cap of 5", len(results), response["count"])
+	}
+	if response["truncated"] != true || response["total"] != float64(12) {
+		t.Errorf("truncated/total = %v/%v, want true/12", response["truncated"], response["total"])
+	}
+	if message, _ := response["message"].(string); !strings.Contains(message, "fields=") {
+		t.Errorf("message = %q, want a pointer to requesting fewer fields", message)
+	}
+}
+
+func TestBatchResultsUnderCapAreNotTruncated(t *testing.T) {
+	for _, max := range []int{5, 0} {
+		store := newBatchStore(5)
+		h := NewHandler(testutil.NewFakeScanEngine(nil), testutil.NewFakeScanner(), store)
+		h.SetMaxBatchResults(max)
+
+		_, response := requestWithKey(t, h, http.MethodGet, "/api/v1/db/batch/batch-1", "")
+		if response["truncated"] != false || response["count"] != float64(5) || response["total"] != nil {
+			t.Errorf("cap %d: truncated/count/total = %v/%v/%v, want all 5 results untruncated",
+				max, response["truncated"], response["count"], response["total"])
+		}
+	}
+}
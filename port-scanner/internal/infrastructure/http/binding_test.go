@@ -0,0 +1,70 @@
+package http
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// responseFields returns the field errors of a 400 response body
+func responseFields(t *testing.T, response map[string]interface{}) []map[string]interface{} {
+	t.Helper()
+	raw, ok := response["fields"].([]interface{})
+	if !ok {
+		t.Fatalf("response %v has no field errors", response)
+	}
+	fields := make([]map[string]interface{}, 0, len(raw))
+	for _, field := range raw {
+		fields = append(fields, field.(map[string]interface{}))
+	}
+	return fields
+}
+
+func TestScanIPReportsMissingRequiredField(t *testing.T) {
+	h, _ := newTestHandler()
+	status, response := postScan(t, h, "/api/v1/scan", `{"ports": [80]}`)
+	if status != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", status, http.StatusBadRequest)
+	}
+
+	want := []map[string]interface{}{{"field": "ip", "rule": "required", "message": "ip is required"}}
+	if got := responseFields(t, response); !reflect.DeepEqual(got, want) {
+		t.Errorf("fields = %v, want %v", got, want)
+	}
+}
+
+func TestScanBatchReportsMinViolation(t *testing.T) {
+	h, _ := newTestHandler()
+	status, response := postScan(t, h, "/api/v1/scan/batch", `{"ips": ["8.8.8.8"], "batch_concurrency": -2}`)
+	if status != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", status, http.StatusBadRequest)
+	}
+
+	want := []map[string]interface{}{{"field": "batch_concurrency", "rule": "min", "message": "batch_concurrency must be at least 0"}}
+	if got := responseFields(t, response); !reflect.DeepEqual(got, want) {
+		t.Errorf("fields = %v, want %v", got, want)
+	}
+}
+
+func TestScanIPReportsMalformedBodies(t *testing.T) {
+	h, _ := newTestHandler()
+	tests := []struct {
+		body  string
+		field string
+		rule  string
+	}{
+		{`{"ip": "8.8.8.8", "ports": "80"}`, "ports", "type"},
+		{`{"ip": "8.8.8.8"`, "", "json"},
+	}
+	for _, tt := range tests {
+		status, response := postScan(t, h, "/api/v1/scan", tt.body)
+		if status != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", tt.body, status, http.StatusBadRequest)
+			continue
+		}
+		fields := responseFields(t, response)
+		if len(fields) != 1 || fields[0]["rule"] != tt.rule || (tt.field != "" && fields[0]["field"] != tt.field) {
+			t.Errorf("%s: fields = %v, want one %s error on %q", tt.body, fields, tt.rule, tt.field)
+		}
+	}
+}
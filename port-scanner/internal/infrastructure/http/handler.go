@@ -1,11 +1,14 @@
 package http
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"sync"
 	"time"
 
-	"port-scanner/internal/application"
 	"port-scanner/internal/domain"
 	"port-scanner/internal/infrastructure/database"
 	"port-scanner/pkg/log"
@@ -17,13 +20,21 @@ import (
 
 // Handler handles HTTP requests for the port scanner
 type Handler struct {
-	scanEngine *application.ScanEngineService
+	scanEngine domain.ScanEngine
 	scanner    domain.Scanner
-	dbManager  *database.MongoDBManager
+	dbManager  Store
+	apiKey     string
+	replayer   domain.ResultReplayer
+	enqueuer   domain.ScanEnqueuer
+	workerID   string
+	scanCache  *scanCache
+
+	maxBatchResults int
 }
 
-// NewHandler creates a new HTTP handler
-func NewHandler(scanEngine *application.ScanEngineService, scanner domain.Scanner, dbManager *database.MongoDBManager) *Handler {
+// NewHandler creates a new HTTP handler. dbManager may be nil when MongoDB is disabled; pass an
+// untyped nil rather than a nil *database.MongoDBManager.
+func NewHandler(scanEngine domain.ScanEngine, scanner domain.Scanner, dbManager Store) *Handler {
 	return &Handler{
 		scanEngine: scanEngine,
 		scanner:    scanner,
@@ -31,8 +42,41 @@ func NewHandler(scanEngine *application.ScanEngineService, scanner domain.Scanne
 	}
 }
 
+// SetAPIKey sets the API key required by maintenance endpoints
+func (h *Handler) SetAPIKey(apiKey string) {
+	h.apiKey = apiKey
+}
+
+// SetWorkerID sets the worker ID recorded on results of scans requested through the API
+func (h *Handler) SetWorkerID(workerID string) {
+	h.workerID = workerID
+}
+
+// SetScanCacheTTL makes POST /scan return the result of the same scan (IP, technique and
+// ports) made within ttl instead of scanning again; zero disables the cache
+func (h *Handler) SetScanCacheTTL(ttl time.Duration) {
+	h.scanCache = newScanCache(ttl)
+}
+
+// SetMaxBatchResults caps the results returned by GET /db/batch/:batch_id; zero returns all
+func (h *Handler) SetMaxBatchResults(max int) {
+	h.maxBatchResults = max
+}
+
+// SetResultReplayer enables the replay endpoints, which publish stored results again
+func (h *Handler) SetResultReplayer(replayer domain.ResultReplayer) {
+	h.replayer = replayer
+}
+
+// SetScanEnqueuer enables POST /scan/list/:name, which queues a stored target list for the workers
+func (h *Handler) SetScanEnqueuer(enqueuer domain.ScanEnqueuer) {
+	h.enqueuer = enqueuer
+}
+
 // RegisterRoutes registers all HTTP routes
 func (h *Handler) RegisterRoutes(router *gin.Engine) {
+	router.GET("/metrics", h.GetMetrics)
+
 	api := router.Group("/api/v1")
 	{
 		api.GET("/health", h.HealthCheck)
@@ -41,13 +85,44 @@ func (h *Handler) RegisterRoutes(router *gin.Engine) {
 		api.GET("/status/:ip", h.GetScanStatus)
 		api.POST("/scan", h.ScanIP)
 		api.POST("/scan/batch", h.ScanBatch)
+		api.POST("/scan/batch/stream", h.ScanBatchStream)
+		api.POST("/scan/targets", h.ScanTargets)
+		api.POST("/scan/list/:name", h.ScanTargetList)
 		api.GET("/ports/:ip", h.GetOpenPorts)
 
 		// MongoDB endpoints
 		api.GET("/db/stats", h.GetDatabaseStats)
 		api.GET("/db/result/:ip", h.GetDatabaseResult)
+		api.GET("/db/result/:ip/surface", h.GetAttackSurface)
+		api.GET("/db/result/:ip/annotations", h.GetAnnotations)
+		api.POST("/db/result/:ip/annotations", h.AddAnnotation)
 		api.GET("/db/batch/:batch_id", h.GetDatabaseBatchResults)
+		api.GET("/db/batch/:batch_id/claim", h.GetBatchClaim)
 		api.GET("/db/search", h.SearchDatabaseResults)
+		api.GET("/db/indexes", h.ListDatabaseIndexes)
+
+		// Scan template endpoints
+		api.GET("/templates", h.ListTemplates)
+		api.POST("/templates", h.CreateTemplate)
+		api.GET("/templates/:name", h.GetTemplate)
+		api.PUT("/templates/:name", h.UpdateTemplate)
+		api.DELETE("/templates/:name", h.DeleteTemplate)
+
+		// Target list endpoints
+		api.GET("/targets", h.ListTargetLists)
+		api.POST("/targets", h.CreateTargetList)
+		api.GET("/targets/:name", h.GetTargetList)
+		api.PUT("/targets/:name", h.UpdateTargetList)
+		api.DELETE("/targets/:name", h.DeleteTargetList)
+
+		// Maintenance endpoints
+		maintenance := api.Group("/db", apiKeyMiddleware(h.apiKey))
+		maintenance.POST("/migrate", h.MigrateDatabase)
+		maintenance.POST("/indexes/rebuild", h.RebuildDatabaseIndexes)
+		maintenance.DELETE("/batch/:batch_id", h.DeleteDatabaseBatch)
+		maintenance.DELETE("/results", h.DeleteDatabaseResults)
+		maintenance.POST("/result/:ip/replay", h.ReplayDatabaseResult)
+		maintenance.POST("/batch/:batch_id/replay", h.ReplayDatabaseBatch)
 	}
 }
 
@@ -81,6 +156,24 @@ func (h *Handler) GetStats(c *gin.Context) {
 		"start_time":        stats.StartTime.Unix(),
 		"last_scan_time":    stats.LastScanTime.Unix(),
 		"uptime":            time.Since(stats.StartTime).String(),
+		"active_scans":      h.scanEngine.ActiveScans(),
+	}
+
+	// Add the effective port scan concurrency (changes when adaptive tuning is enabled) and
+	// the failed connection attempts by category
+	if scannerService, ok := h.scanner.(*domain.ScannerService); ok {
+		response["concurrency_limit"] = scannerService.ConcurrencyLimit()
+		response["dial_errors"] = scannerService.DialErrorCounts()
+	}
+
+	// Add deadline information if the engine run is time-boxed
+	if deadline, ok := h.scanEngine.Deadline(); ok {
+		remaining := time.Until(deadline)
+		if remaining < 0 {
+			remaining = 0
+		}
+		response["deadline"] = deadline.Unix()
+		response["remaining_time"] = remaining.String()
 	}
 
 	// Add database stats if available
@@ -121,16 +214,18 @@ func (h *Handler) GetScanStatus(c *gin.Context) {
 		return
 	}
 
+	times := requestTimeFormat(c)
 	c.JSON(http.StatusOK, gin.H{
 		"ip":            result.IP,
 		"status":        result.Status,
 		"is_up":         result.IsUp,
 		"ping_time":     result.PingTime.String(),
-		"scan_start":    result.ScanStartTime.Unix(),
-		"scan_end":      result.ScanEndTime.Unix(),
+		"scan_start":    times.format(result.ScanStartTime),
+		"scan_end":      times.format(result.ScanEndTime),
 		"scan_duration": result.GetScanDuration().String(),
 		"total_ports":   len(result.Ports),
 		"open_ports":    len(result.GetOpenPorts()),
+		"open_filtered": len(result.GetOpenFilteredPorts()),
 		"batch_id":      result.BatchID,
 		"error":         result.Error,
 	})
@@ -138,9 +233,63 @@ func (h *Handler) GetScanStatus(c *gin.Context) {
 
 // ScanIPRequest represents a single IP scan request
 type ScanIPRequest struct {
-	IP      string `json:"ip" binding:"required"`
-	Ports   []int  `json:"ports,omitempty"`
-	BatchID string `json:"batch_id,omitempty"`
+	IP       string `json:"ip" binding:"required"`
+	Ports    []int  `json:"ports,omitempty"`
+	PortSpec string `json:"port_spec,omitempty"` // e.g. "22,80,443,8000-8100"; combined with ports
+	BatchID  string `json:"batch_id,omitempty"`
+	Template string `json:"template,omitempty"`
+
+	Intensity int `json:"intensity,omitempty" binding:"min=0,max=5"` // 1 (sneaky) to 5 (insane); template settings still take precedence
+
+	Technique string `json:"technique,omitempty" binding:"omitempty,oneof=connect syn"` // Defaults to the server's scan technique
+
+	PortOrder string `json:"port_order,omitempty" binding:"omitempty,oneof=asc desc random priority-first"` // Defaults to the server's port order
+}
+
+// resolveScanConfig builds the options for an API scan: the engine config, overridden by the
+// intensity level, the named scan template and then by explicitly requested ports, port spec,
+// technique and port order. A technique the scanner can't run in this process is refused up front.
+func (h *Handler) resolveScanConfig(templateName string, ports []int, portSpec string, intensity int, technique, portOrder string) (*domain.ScanConfig, int, error) {
+	ports, err := domain.ResolvePorts(ports, portSpec)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	if err := h.scanner.CheckTechnique(technique); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	config := h.scanEngine.ScanConfig()
+
+	if intensity != 0 {
+		if err := config.ApplyIntensity(intensity); err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+	}
+
+	if templateName != "" {
+		if h.dbManager == nil {
+			return nil, http.StatusServiceUnavailable, fmt.Errorf("scan templates require MongoDB")
+		}
+		template, err := h.dbManager.GetTemplate(templateName)
+		if errors.Is(err, database.ErrTemplateNotFound) {
+			return nil, http.StatusBadRequest, err
+		}
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		template.ApplyTo(config)
+	}
+
+	if len(ports) > 0 {
+		config.PortRange = ports
+	}
+	if technique != "" {
+		config.Technique = technique
+	}
+	if portOrder != "" {
+		config.PortOrder = portOrder
+	}
+	return config, http.StatusOK, nil
 }
 
 // ScanIP scans a single IP address
@@ -148,71 +297,139 @@ func (h *Handler) ScanIP(c *gin.Context) {
 	var req ScanIPRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		log.L().Warn("Invalid scan request", zap.String("event", "scanip_invalid_request"), zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "fields": bindingErrors(err)})
 		return
 	}
 
 	log.L().Info("Received scan request", zap.String("event", "scanip_request"), zap.String("ip", req.IP), zap.Any("ports", req.Ports))
 
-	// Create custom config if ports are specified
-	config := domain.NewDefaultScanConfig()
-	if len(req.Ports) > 0 {
-		config.PortRange = req.Ports
-	}
-
-	// Perform the scan
-	result, err := h.scanner.ScanIP(req.IP, config, req.BatchID, "")
+	// Build the scan options from the engine config, intensity, an optional template and the requested ports and technique
+	config, status, err := h.resolveScanConfig(req.Template, req.Ports, req.PortSpec, req.Intensity, req.Technique, req.PortOrder)
 	if err != nil {
-		log.L().Error("Scan failed", zap.String("event", "scanip_failed"), zap.String("ip", req.IP), zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
-	log.L().Info("Scan completed", zap.String("event", "scanip_completed"), zap.String("ip", req.IP), zap.Int("open_ports", len(result.GetOpenPorts())))
+	// Reuse a recent result of the same scan unless the caller forces a new one
+	cacheKey := scanCacheKey(req.IP, config)
+	var result *domain.ScanResult
+	cached := false
+	if c.Query("force") != "true" {
+		result, cached = h.scanCache.get(cacheKey)
+	}
 
-	// Update statistics
-	h.scanEngine.GetScanStats().UpdateStats(result)
+	if cached {
+		log.L().Info("Returning cached scan result", zap.String("event", "scanip_cached"), zap.String("ip", req.IP))
+	} else {
+		// Perform the scan
+		result, err = h.scanIP(c.Request.Context(), req.IP, config, req.BatchID)
+		if errors.Is(err, domain.ErrProtectedTarget) || errors.Is(err, domain.ErrExcludedTarget) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, domain.ErrEngineStopped) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			log.L().Error("Scan failed", zap.String("event", "scanip_failed"), zap.String("ip", req.IP), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
 
+		log.L().Info("Scan completed", zap.String("event", "scanip_completed"), zap.String("ip", req.IP), zap.Int("open_ports", len(result.GetOpenPorts())))
+
+		// Update statistics
+		h.scanEngine.RecordResult(result)
+		h.scanCache.put(cacheKey, result)
+	}
+
+	times := requestTimeFormat(c)
 	c.JSON(http.StatusOK, gin.H{
 		"ip":            result.IP,
 		"status":        result.Status,
 		"is_up":         result.IsUp,
 		"ping_time":     result.PingTime.String(),
+		"scan_start":    times.format(result.ScanStartTime),
+		"scan_end":      times.format(result.ScanEndTime),
 		"scan_duration": result.GetScanDuration().String(),
 		"total_ports":   len(result.Ports),
 		"open_ports":    len(result.GetOpenPorts()),
-		"ports":         h.formatPortsForResponse(result.Ports),
+		"open_filtered": len(result.GetOpenFilteredPorts()),
+		"ports":         h.formatPortsForResponse(result.Ports, times),
 		"batch_id":      result.BatchID,
+		"metadata":      result.Metadata,
+		"reverse_dns":   result.ReverseDNS,
+		"cached":        cached,
 	})
 }
 
+// scanIP scans an IP within one of the engine's scan slots, so API scans share the concurrency
+// of queued scans instead of adding to it
+func (h *Handler) scanIP(ctx context.Context, ip string, config *domain.ScanConfig, batchID string) (*domain.ScanResult, error) {
+	release, err := h.scanEngine.AcquireScanSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return h.scanner.ScanIP(ip, config, batchID, h.workerID)
+}
+
 // ScanBatchRequest represents a batch scan request
 type ScanBatchRequest struct {
-	IPs     []string `json:"ips" binding:"required"`
-	Ports   []int    `json:"ports,omitempty"`
-	BatchID string   `json:"batch_id,omitempty"`
+	IPs      []string `json:"ips" binding:"required"`
+	Ports    []int    `json:"ports,omitempty"`
+	PortSpec string   `json:"port_spec,omitempty"` // e.g. "22,80,443,8000-8100"; combined with ports
+	BatchID  string   `json:"batch_id,omitempty"`
+	Template string   `json:"template,omitempty"`
+
+	Intensity int `json:"intensity,omitempty" binding:"min=0,max=5"` // 1 (sneaky) to 5 (insane); template settings still take precedence
+
+	Technique string `json:"technique,omitempty" binding:"omitempty,oneof=connect syn"` // Defaults to the server's scan technique
+
+	PortOrder string `json:"port_order,omitempty" binding:"omitempty,oneof=asc desc random priority-first"` // Defaults to the server's port order
+
+	// IPs scanned at once; defaults to and is capped by the server's max_batch_concurrency
+	BatchConcurrency int `json:"batch_concurrency,omitempty" binding:"min=0"`
+
+	// Scan priority ports first and stream their results as a "partial" entry per IP before
+	// its final entry; only used by the stream endpoint
+	Partial bool `json:"partial,omitempty"`
+}
+
+// batchConcurrency returns how many IPs of a batch request may scan at once
+func batchConcurrency(requested, limit int) int {
+	if limit < 1 {
+		limit = 1
+	}
+	if requested < 1 || requested > limit {
+		return limit
+	}
+	return requested
 }
 
 // ScanBatch scans multiple IP addresses
 func (h *Handler) ScanBatch(c *gin.Context) {
 	var req ScanBatchRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "fields": bindingErrors(err)})
 		return
 	}
 
-	// Create custom config if ports are specified
-	config := domain.NewDefaultScanConfig()
-	if len(req.Ports) > 0 {
-		config.PortRange = req.Ports
+	// Build the scan options from the engine config, intensity, an optional template and the requested ports and technique
+	config, status, err := h.resolveScanConfig(req.Template, req.Ports, req.PortSpec, req.Intensity, req.Technique, req.PortOrder)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
 	}
 
 	var results []gin.H
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 
-	// Limit concurrency
-	semaphore := make(chan struct{}, config.Concurrency)
+	// Limit how many IPs scan at once; each IP still scans its ports with the port concurrency
+	semaphore := make(chan struct{}, batchConcurrency(req.BatchConcurrency, config.MaxBatchConcurrency))
 
 	for _, ip := range req.IPs {
 		wg.Add(1)
@@ -224,26 +441,10 @@ func (h *Handler) ScanBatch(c *gin.Context) {
 			defer func() { <-semaphore }()
 
 			// Perform the scan
-			result, err := h.scanner.ScanIP(ipAddr, config, req.BatchID, "")
+			result, err := h.scanIP(c.Request.Context(), ipAddr, config, req.BatchID)
 
 			mu.Lock()
-			if err != nil {
-				results = append(results, gin.H{
-					"ip":     ipAddr,
-					"status": "failed",
-					"error":  err.Error(),
-				})
-			} else {
-				results = append(results, gin.H{
-					"ip":            result.IP,
-					"status":        result.Status,
-					"is_up":         result.IsUp,
-					"ping_time":     result.PingTime.String(),
-					"scan_duration": result.GetScanDuration().String(),
-					"total_ports":   len(result.Ports),
-					"open_ports":    len(result.GetOpenPorts()),
-				})
-			}
+			results = append(results, h.formatBatchEntry(ipAddr, result, err))
 			mu.Unlock()
 		}(ip)
 	}
@@ -257,6 +458,197 @@ func (h *Handler) ScanBatch(c *gin.Context) {
 	})
 }
 
+// ScanBatchStream scans multiple IP addresses and streams each result as
+// newline-delimited JSON as soon as its scan completes
+func (h *Handler) ScanBatchStream(c *gin.Context) {
+	var req ScanBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "fields": bindingErrors(err)})
+		return
+	}
+
+	// Build the scan options from the engine config, intensity, an optional template and the requested ports and technique
+	config, status, err := h.resolveScanConfig(req.Template, req.Ports, req.PortSpec, req.Intensity, req.Technique, req.PortOrder)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	entries := make(chan gin.H)
+	var wg sync.WaitGroup
+
+	if req.Partial {
+		config.PriorityFirst = true
+		config.OnPriorityPorts = func(ip string, ports []*domain.Port) {
+			entries <- formatPartialEntry(ip, ports)
+		}
+	}
+
+	// Limit how many IPs scan at once; each IP still scans its ports with the port concurrency
+	semaphore := make(chan struct{}, batchConcurrency(req.BatchConcurrency, config.MaxBatchConcurrency))
+
+	for _, ip := range req.IPs {
+		wg.Add(1)
+		go func(ipAddr string) {
+			defer wg.Done()
+
+			// Acquire semaphore
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			// Perform the scan
+			result, err := h.scanIP(c.Request.Context(), ipAddr, config, req.BatchID)
+			entries <- h.formatBatchEntry(ipAddr, result, err)
+		}(ip)
+	}
+
+	go func() {
+		wg.Wait()
+		close(entries)
+	}()
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(c.Writer)
+	for entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			// Client went away; drain remaining results so scan goroutines can finish
+			log.L().Warn("Failed to stream batch result", zap.String("event", "scanbatch_stream_failed"), zap.Error(err))
+			for range entries {
+			}
+			return
+		}
+		c.Writer.Flush()
+	}
+}
+
+// ScanTargetsRequest represents a request to scan known ip:port pairs
+type ScanTargetsRequest struct {
+	Targets  []string `json:"targets" binding:"required"` // e.g. ["1.2.3.4:80", "[2001:db8::1]:443"]
+	BatchID  string   `json:"batch_id,omitempty"`
+	Template string   `json:"template,omitempty"`
+
+	Intensity int `json:"intensity,omitempty" binding:"min=0,max=5"` // 1 (sneaky) to 5 (insane); template settings still take precedence
+
+	Technique string `json:"technique,omitempty" binding:"omitempty,oneof=connect syn"` // Defaults to the server's scan technique
+
+	PortOrder string `json:"port_order,omitempty" binding:"omitempty,oneof=asc desc random priority-first"` // Defaults to the server's port order
+
+	// IPs scanned at once; defaults to and is capped by the server's max_batch_concurrency
+	BatchConcurrency int `json:"batch_concurrency,omitempty" binding:"min=0"`
+}
+
+// ScanTargets scans exactly the given ip:port pairs, with banner grabbing, instead of a port list.
+// Targets are already known to be live, so hosts aren't pinged first.
+func (h *Handler) ScanTargets(c *gin.Context) {
+	var req ScanTargetsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "fields": bindingErrors(err)})
+		return
+	}
+
+	targets, err := domain.ParseScanTargets(req.Targets)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(targets) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no targets to scan"})
+		return
+	}
+
+	// Build the scan options from the engine config, intensity, an optional template and the requested technique
+	base, status, err := h.resolveScanConfig(req.Template, nil, "", req.Intensity, req.Technique, req.PortOrder)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	base.EnablePing = false
+	base.EnableBanner = true
+
+	times := requestTimeFormat(c)
+	results := make([]gin.H, len(targets))
+	var wg sync.WaitGroup
+
+	// Limit how many IPs scan at once; each IP still scans its ports with the port concurrency
+	semaphore := make(chan struct{}, batchConcurrency(req.BatchConcurrency, base.MaxBatchConcurrency))
+
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target domain.ScanTarget) {
+			defer wg.Done()
+
+			// Acquire semaphore
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			config := *base
+			config.PortRange = target.Ports
+			result, err := h.scanIP(c.Request.Context(), target.IP, &config, req.BatchID)
+
+			entry := h.formatBatchEntry(target.IP, result, err)
+			if err == nil {
+				entry["ports"] = h.formatPortsForResponse(result.Ports, times)
+			}
+			results[i] = entry
+		}(i, target)
+	}
+
+	wg.Wait()
+
+	c.JSON(http.StatusOK, gin.H{
+		"batch_id":      req.BatchID,
+		"total_ips":     len(targets),
+		"total_targets": len(req.Targets),
+		"results":       results,
+	})
+}
+
+// formatPartialEntry formats the priority ports of an IP scanned before the rest of its ports
+func formatPartialEntry(ip string, ports []*domain.Port) gin.H {
+	open := []gin.H{}
+	for _, port := range ports {
+		if port.Status != domain.PortStatusOpen {
+			continue
+		}
+		open = append(open, gin.H{
+			"number":  port.Number,
+			"service": port.Service,
+			"banner":  port.Banner,
+		})
+	}
+	return gin.H{
+		"ip":          ip,
+		"status":      "partial",
+		"total_ports": len(ports),
+		"open_ports":  len(open),
+		"ports":       open,
+	}
+}
+
+// formatBatchEntry formats a single IP outcome of a batch scan
+func (h *Handler) formatBatchEntry(ipAddr string, result *domain.ScanResult, err error) gin.H {
+	if err != nil {
+		return gin.H{
+			"ip":     ipAddr,
+			"status": "failed",
+			"error":  err.Error(),
+		}
+	}
+
+	return gin.H{
+		"ip":            result.IP,
+		"status":        result.Status,
+		"is_up":         result.IsUp,
+		"ping_time":     result.PingTime.String(),
+		"scan_duration": result.GetScanDuration().String(),
+		"total_ports":   len(result.Ports),
+		"open_ports":    len(result.GetOpenPorts()),
+		"open_filtered": len(result.GetOpenFilteredPorts()),
+	}
+}
+
 // GetOpenPorts returns open ports for a specific IP
 func (h *Handler) GetOpenPorts(c *gin.Context) {
 	ip := c.Param("ip")
@@ -265,6 +657,13 @@ func (h *Handler) GetOpenPorts(c *gin.Context) {
 		return
 	}
 
+	// Raw ZGrab2 output can expose more than the summary fields, so it requires the API key
+	raw := c.Query("raw") == "true"
+	if raw && !hasValidAPIKey(c, h.apiKey) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or missing API key"})
+		return
+	}
+
 	result, err := h.scanEngine.GetScanStatus(ip)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
@@ -274,6 +673,7 @@ func (h *Handler) GetOpenPorts(c *gin.Context) {
 	openPorts := result.GetOpenPorts()
 	var ports []gin.H
 
+	times := requestTimeFormat(c)
 	for _, port := range openPorts {
 		portInfo := gin.H{
 			"number":        port.Number,
@@ -281,12 +681,16 @@ func (h *Handler) GetOpenPorts(c *gin.Context) {
 			"banner":        port.Banner,
 			"version":       port.Version,
 			"response_time": port.ResponseTime.String(),
-			"scan_time":     port.ScanTime.Unix(),
+			"scan_time":     times.format(port.ScanTime),
 		}
 
 		// Add confidence information if available
 		if port.BannerInfo != nil {
 			portInfo["confidence"] = port.BannerInfo.Confidence
+			portInfo["confidence_score"] = port.BannerInfo.ConfidenceScore
+			if raw {
+				addRawMetadata(portInfo, port.BannerInfo.Metadata)
+			}
 		}
 
 		ports = append(ports, portInfo)
@@ -329,14 +733,47 @@ func (h *Handler) GetDatabaseResult(c *gin.Context) {
 		return
 	}
 
-	result, err := h.dbManager.GetScanResult(ip)
+	projection, err := database.ParseProjection(c.Query("fields"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.dbManager.GetScanResult(ip, projection)
 	if err != nil {
 		log.L().Error("Failed to get database result", zap.String("event", "db_result_failed"), zap.String("ip", ip), zap.Error(err))
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, result)
+	if projection == nil {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+	projected, err := projection.Apply(result)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, projected)
+}
+
+// GetAttackSurface summarizes the stored scan result of an IP as its attack surface: open
+// ports by risk category, an exposure score and notable findings
+func (h *Handler) GetAttackSurface(c *gin.Context) {
+	if h.dbManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "MongoDB not available"})
+		return
+	}
+
+	ip := c.Param("ip")
+	doc, err := h.dbManager.GetScanResult(ip, nil)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.NewAttackSurface(doc.ToScanResult()))
 }
 
 // GetDatabaseBatchResults returns all scan results for a batch from MongoDB
@@ -352,17 +789,441 @@ func (h *Handler) GetDatabaseBatchResults(c *gin.Context) {
 		return
 	}
 
-	results, err := h.dbManager.GetScanResultsByBatch(batchID)
+	projection, err := database.ParseProjection(c.Query("fields"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Read one result past the cap to tell whether the batch was cut short
+	limit := 0
+	if h.maxBatchResults > 0 {
+		limit = h.maxBatchResults + 1
+	}
+	results, err := h.dbManager.GetScanResultsByBatch(batchID, projection, limit)
 	if err != nil {
 		log.L().Error("Failed to get batch results", zap.String("event", "db_batch_failed"), zap.String("batch_id", batchID), zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	truncated := h.maxBatchResults > 0 && len(results) > h.maxBatchResults
+	if truncated {
+		results = results[:h.maxBatchResults]
+	}
+
+	response := gin.H{
+		"batch_id":  batchID,
+		"count":     len(results),
+		"results":   results,
+		"truncated": truncated,
+	}
+	if truncated {
+		total, err := h.dbManager.CountByBatch(batchID)
+		if err != nil {
+			log.L().Warn("Failed to count batch results", zap.String("event", "db_batch_count_failed"), zap.String("batch_id", batchID), zap.Error(err))
+		} else {
+			response["total"] = total
+		}
+		response["message"] = fmt.Sprintf("batch has more than %d results, only the oldest %d are returned; "+
+			"request fewer fields with ?fields= or raise mongodb.max_batch_results", h.maxBatchResults, h.maxBatchResults)
+		log.L().Warn("Batch results truncated", zap.String("event", "db_batch_truncated"), zap.String("batch_id", batchID), zap.Int("max_results", h.maxBatchResults))
+	}
+	if projection != nil {
+		projected := make([]map[string]interface{}, 0, len(results))
+		for _, result := range results {
+			fields, err := projection.Apply(result)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			projected = append(projected, fields)
+		}
+		response["results"] = projected
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetBatchClaim returns which worker is handling a batch and whether its claim is stale
+func (h *Handler) GetBatchClaim(c *gin.Context) {
+	if h.dbManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "MongoDB not available"})
+		return
+	}
+
+	batchID := c.Param("batch_id")
+	claim, err := h.dbManager.GetBatchClaim(batchID)
+	if err != nil {
+		log.L().Error("Failed to get batch claim", zap.String("event", "db_claim_failed"), zap.String("batch_id", batchID), zap.Error(err))
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, claim)
+}
+
+// AnnotationRequest represents a request to annotate a scanned IP
+type AnnotationRequest struct {
+	Author string `json:"author" binding:"required"`
+	Note   string `json:"note" binding:"required"`
+}
+
+// AddAnnotation attaches an analyst note to a scanned IP
+func (h *Handler) AddAnnotation(c *gin.Context) {
+	if h.dbManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "MongoDB not available"})
+		return
+	}
+
+	var req AnnotationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "fields": bindingErrors(err)})
+		return
+	}
+
+	ip := c.Param("ip")
+	annotation, err := h.dbManager.AddAnnotation(ip, req.Author, req.Note)
+	if err != nil {
+		log.L().Error("Failed to add annotation", zap.String("event", "db_annotation_failed"), zap.String("ip", ip), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, annotation)
+}
+
+// GetAnnotations returns the analyst notes for a scanned IP in the order they were added
+func (h *Handler) GetAnnotations(c *gin.Context) {
+	if h.dbManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "MongoDB not available"})
+		return
+	}
+
+	ip := c.Param("ip")
+	annotations, err := h.dbManager.GetAnnotations(ip)
+	if err != nil {
+		log.L().Error("Failed to get annotations", zap.String("event", "db_annotations_failed"), zap.String("ip", ip), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ip":          ip,
+		"count":       len(annotations),
+		"annotations": annotations,
+	})
+}
+
+// templateErrorStatus maps a scan template error to an HTTP status code
+func templateErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, database.ErrTemplateNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, database.ErrTemplateExists):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// CreateTemplate saves a named scan template
+func (h *Handler) CreateTemplate(c *gin.Context) {
+	if h.dbManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "MongoDB not available"})
+		return
+	}
+
+	var template database.ScanTemplateDocument
+	if err := c.ShouldBindJSON(&template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "fields": bindingErrors(err)})
+		return
+	}
+	if err := template.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.dbManager.CreateTemplate(&template); err != nil {
+		log.L().Error("Failed to create scan template", zap.String("event", "template_create_failed"), zap.String("template", template.Name), zap.Error(err))
+		c.JSON(templateErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, template)
+}
+
+// ListTemplates returns all scan templates
+func (h *Handler) ListTemplates(c *gin.Context) {
+	if h.dbManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "MongoDB not available"})
+		return
+	}
+
+	templates, err := h.dbManager.ListTemplates()
+	if err != nil {
+		log.L().Error("Failed to list scan templates", zap.String("event", "template_list_failed"), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count":     len(templates),
+		"templates": templates,
+	})
+}
+
+// GetTemplate returns a scan template by name
+func (h *Handler) GetTemplate(c *gin.Context) {
+	if h.dbManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "MongoDB not available"})
+		return
+	}
+
+	template, err := h.dbManager.GetTemplate(c.Param("name"))
+	if err != nil {
+		c.JSON(templateErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// UpdateTemplate replaces the options of an existing scan template
+func (h *Handler) UpdateTemplate(c *gin.Context) {
+	if h.dbManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "MongoDB not available"})
+		return
+	}
+
+	var template database.ScanTemplateDocument
+	if err := c.ShouldBindJSON(&template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "fields": bindingErrors(err)})
+		return
+	}
+	template.Name = c.Param("name")
+	if err := template.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.dbManager.UpdateTemplate(&template); err != nil {
+		log.L().Error("Failed to update scan template", zap.String("event", "template_update_failed"), zap.String("template", template.Name), zap.Error(err))
+		c.JSON(templateErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// DeleteTemplate removes a scan template
+func (h *Handler) DeleteTemplate(c *gin.Context) {
+	if h.dbManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "MongoDB not available"})
+		return
+	}
+
+	name := c.Param("name")
+	if err := h.dbManager.DeleteTemplate(name); err != nil {
+		log.L().Error("Failed to delete scan template", zap.String("event", "template_delete_failed"), zap.String("template", name), zap.Error(err))
+		c.JSON(templateErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"name":    name,
+		"deleted": true,
+	})
+}
+
+// MigrateDatabase upgrades stored scan results to the current schema version
+func (h *Handler) MigrateDatabase(c *gin.Context) {
+	if h.dbManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "MongoDB not available"})
+		return
+	}
+
+	migrated, err := h.dbManager.MigrateScanResults()
+	if err != nil {
+		log.L().Error("Failed to migrate scan results", zap.String("event", "db_migrate_failed"), zap.Int("migrated", migrated), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "migrated": migrated})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"migrated":       migrated,
+		"schema_version": database.CurrentSchemaVersion,
+	})
+}
+
+// ListDatabaseIndexes returns the indexes of the collections the service indexes
+func (h *Handler) ListDatabaseIndexes(c *gin.Context) {
+	if h.dbManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "MongoDB not available"})
+		return
+	}
+
+	indexes, err := h.dbManager.ListIndexes()
+	if err != nil {
+		log.L().Error("Failed to list indexes", zap.String("event", "db_indexes_failed"), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"indexes": indexes,
+		"count":   len(indexes),
+	})
+}
+
+// RebuildDatabaseIndexes creates missing indexes and recreates changed ones
+func (h *Handler) RebuildDatabaseIndexes(c *gin.Context) {
+	if h.dbManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "MongoDB not available"})
+		return
+	}
+
+	recreated, err := h.dbManager.RebuildIndexes()
+	if err != nil {
+		log.L().Error("Failed to rebuild indexes", zap.String("event", "db_index_rebuild_failed"), zap.Strings("recreated", recreated), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "recreated": recreated})
+		return
+	}
+
+	indexes, err := h.dbManager.ListIndexes()
+	if err != nil {
+		log.L().Error("Failed to list indexes", zap.String("event", "db_indexes_failed"), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "recreated": recreated})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"recreated": recreated,
+		"indexes":   indexes,
+	})
+}
+
+// DeleteDatabaseBatch deletes all scan results for a batch from MongoDB
+func (h *Handler) DeleteDatabaseBatch(c *gin.Context) {
+	if h.dbManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "MongoDB not available"})
+		return
+	}
+
+	batchID := c.Param("batch_id")
+	if batchID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Batch ID is required"})
+		return
+	}
+
+	deleted, err := h.dbManager.DeleteByBatch(batchID)
+	if err != nil {
+		log.L().Error("Failed to delete batch results", zap.String("event", "db_delete_batch_failed"), zap.String("batch_id", batchID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"batch_id": batchID,
+		"deleted":  deleted,
+	})
+}
+
+// ReplayDatabaseResult publishes the stored scan result of an IP to the output queues again
+func (h *Handler) ReplayDatabaseResult(c *gin.Context) {
+	if h.dbManager == nil || h.replayer == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "MongoDB not available"})
+		return
+	}
+
+	ip := c.Param("ip")
+	doc, err := h.dbManager.GetScanResult(ip, nil)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.replayer.ReplayScanResult(doc.ToScanResult()); err != nil {
+		log.L().Error("Failed to replay scan result", zap.String("event", "db_replay_failed"), zap.String("ip", ip), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ip":       ip,
+		"replayed": 1,
+	})
+}
+
+// ReplayDatabaseBatch publishes every stored scan result of a batch to the output queues again
+func (h *Handler) ReplayDatabaseBatch(c *gin.Context) {
+	if h.dbManager == nil || h.replayer == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "MongoDB not available"})
+		return
+	}
+
+	batchID := c.Param("batch_id")
+	if batchID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Batch ID is required"})
+		return
+	}
+
+	docs, err := h.dbManager.GetScanResultsByBatch(batchID, nil, 0)
+	if err != nil {
+		log.L().Error("Failed to get batch results", zap.String("event", "db_batch_failed"), zap.String("batch_id", batchID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(docs) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no scan results found for batch: " + batchID})
+		return
+	}
+
+	replayed := 0
+	for _, doc := range docs {
+		if err := h.replayer.ReplayScanResult(doc.ToScanResult()); err != nil {
+			log.L().Error("Failed to replay batch", zap.String("event", "db_replay_failed"), zap.String("batch_id", batchID),
+				zap.Int("replayed", replayed), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "replayed": replayed})
+			return
+		}
+		replayed++
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"batch_id": batchID,
-		"count":    len(results),
-		"results":  results,
+		"replayed": replayed,
+	})
+}
+
+// DeleteDatabaseResults deletes scan results older than the older_than query parameter (e.g. 30d, 12h)
+func (h *Handler) DeleteDatabaseResults(c *gin.Context) {
+	if h.dbManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "MongoDB not available"})
+		return
+	}
+
+	olderThan := c.Query("older_than")
+	if olderThan == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "older_than is required"})
+		return
+	}
+
+	age, err := domain.ParseRetention(olderThan)
+	if err != nil || age <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid older_than value: %s", olderThan)})
+		return
+	}
+
+	deleted, err := h.dbManager.DeleteOlderThan(age)
+	if err != nil {
+		log.L().Error("Failed to delete old results", zap.String("event", "db_delete_old_failed"), zap.String("older_than", olderThan), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"older_than": olderThan,
+		"deleted":    deleted,
 	})
 }
 
@@ -390,21 +1251,51 @@ func (h *Handler) SearchDatabaseResults(c *gin.Context) {
 	})
 }
 
-func (h *Handler) formatPortsForResponse(ports []*domain.Port) []gin.H {
+// maxRawMetadataSize caps the raw ZGrab2 JSON returned per port
+const maxRawMetadataSize = 64 * 1024
+
+// addRawMetadata adds the raw ZGrab2 output for a port, truncating it when it is too large
+func addRawMetadata(portInfo gin.H, metadata map[string]interface{}) {
+	if len(metadata) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		portInfo["raw_error"] = err.Error()
+		return
+	}
+
+	if truncated, ok := domain.TruncateBanner(string(data), maxRawMetadataSize); ok {
+		// A truncated document is no longer valid JSON, so return it as a string
+		portInfo["raw"] = truncated
+		portInfo["raw_truncated"] = true
+		return
+	}
+	portInfo["raw"] = json.RawMessage(data)
+}
+
+// formatPortsForResponse formats the ports of a scan result with timestamps in the requested format
+func (h *Handler) formatPortsForResponse(ports []*domain.Port, times timeFormatter) []gin.H {
 	var formattedPorts []gin.H
 	for _, port := range ports {
 		portInfo := gin.H{
 			"number":        port.Number,
+			"status":        port.Status,
 			"service":       port.Service,
 			"banner":        port.Banner,
 			"version":       port.Version,
 			"response_time": port.ResponseTime.String(),
-			"scan_time":     port.ScanTime.Unix(),
+			"scan_time":     times.format(port.ScanTime),
 		}
 
 		// Add confidence information if available
 		if port.BannerInfo != nil {
 			portInfo["confidence"] = port.BannerInfo.Confidence
+			portInfo["confidence_score"] = port.BannerInfo.ConfidenceScore
+		}
+		if port.Error != "" {
+			portInfo["error"] = port.Error
 		}
 
 		formattedPorts = append(formattedPorts, portInfo)
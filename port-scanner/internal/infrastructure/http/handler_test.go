@@ -0,0 +1,430 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"port-scanner/internal/domain"
+	"port-scanner/internal/infrastructure/database"
+	"port-scanner/internal/testutil"
+	"port-scanner/pkg/log"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMain(m *testing.M) {
+	log.InitLogger("http-test")
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// templateStore is a Store that only serves scan templates; other methods are not used
+type templateStore struct {
+	Store
+	templates map[string]*database.ScanTemplateDocument
+}
+
+// GetTemplate returns a template registered on the store
+func (s *templateStore) GetTemplate(name string) (*database.ScanTemplateDocument, error) {
+	template, ok := s.templates[name]
+	if !ok {
+		return nil, database.ErrTemplateNotFound
+	}
+	return template, nil
+}
+
+// CreateTemplate registers a template unless its name is taken
+func (s *templateStore) CreateTemplate(template *database.ScanTemplateDocument) error {
+	if _, ok := s.templates[template.Name]; ok {
+		return database.ErrTemplateExists
+	}
+	s.templates[template.Name] = template
+	return nil
+}
+
+// UpdateTemplate replaces a registered template
+func (s *templateStore) UpdateTemplate(template *database.ScanTemplateDocument) error {
+	if _, ok := s.templates[template.Name]; !ok {
+		return database.ErrTemplateNotFound
+	}
+	s.templates[template.Name] = template
+	return nil
+}
+
+// DeleteTemplate removes a registered template
+func (s *templateStore) DeleteTemplate(name string) error {
+	if _, ok := s.templates[name]; !ok {
+		return database.ErrTemplateNotFound
+	}
+	delete(s.templates, name)
+	return nil
+}
+
+// newTestHandler returns a handler backed by the fake engine and scanner, without MongoDB
+func newTestHandler() (*Handler, *testutil.FakeScanner) {
+	scanner := testutil.NewFakeScanner()
+	return NewHandler(testutil.NewFakeScanEngine(nil), scanner, nil), scanner
+}
+
+// serve sends a request with a JSON body through the handler's routes
+func serve(h *Handler, method, path, body string) *httptest.ResponseRecorder {
+	router := gin.New()
+	h.RegisterRoutes(router)
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+// postScan sends a POST request to the handler and decodes the JSON response
+func postScan(t *testing.T, h *Handler, path string, body string) (int, map[string]interface{}) {
+	t.Helper()
+	recorder := serve(h, http.MethodPost, path, body)
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid JSON response %q: %v", recorder.Body.String(), err)
+	}
+	return recorder.Code, response
+}
+
+func TestScanIPReturnsScannerResult(t *testing.T) {
+	h, scanner := newTestHandler()
+	h.SetWorkerID("worker-1")
+	scanner.AddOpenPorts("8.8.8.8", 53, 443)
+
+	status, response := postScan(t, h, "/api/v1/scan", `{"ip": "8.8.8.8", "ports": [53, 443], "batch_id": "batch-1", "port_order": "desc"}`)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %v", status, http.StatusOK, response)
+	}
+	if response["ip"] != "8.8.8.8" || response["batch_id"] != "batch-1" {
+		t.Errorf("response ip/batch_id = %v/%v, want 8.8.8.8/batch-1", response["ip"], response["batch_id"])
+	}
+	if response["open_ports"] != float64(2) || response["total_ports"] != float64(2) {
+		t.Errorf("open_ports/total_ports = %v/%v, want 2/2", response["open_ports"], response["total_ports"])
+	}
+	if response["status"] != string(domain.ScanStatusCompleted) {
+		t.Errorf("status = %v, want %s", response["status"], domain.ScanStatusCompleted)
+	}
+	if response["cached"] != false {
+		t.Errorf("cached = %v, want false", response["cached"])
+	}
+
+	if !reflect.DeepEqual(scanner.Calls, []string{"8.8.8.8"}) {
+		t.Fatalf("scanner calls = %v, want [8.8.8.8]", scanner.Calls)
+	}
+	if !reflect.DeepEqual(scanner.WorkerIDs, []string{"worker-1"}) {
+		t.Errorf("scanned as %v, want the handler's worker-1", scanner.WorkerIDs)
+	}
+	config := scanner.Configs[0]
+	if !reflect.DeepEqual(config.PortRange, []int{53, 443}) {
+		t.Errorf("scanned ports %v, want [53 443]", config.PortRange)
+	}
+	if config.PortOrder != domain.PortOrderDesc {
+		t.Errorf("port order %q, want %q", config.PortOrder, domain.PortOrderDesc)
+	}
+}
+
+func TestScanIPReportsOpenFilteredPortsApart(t *testing.T) {
+	h, scanner := newTestHandler()
+	result := scanner.AddOpenPorts("8.8.8.8", 22)
+	silent := domain.NewPort(161)
+	silent.Status = domain.PortStatusOpenFiltered
+	result.AddPort(silent)
+
+	status, response := postScan(t, h, "/api/v1/scan", `{"ip": "8.8.8.8", "ports": [22, 161]}`)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %v", status, http.StatusOK, response)
+	}
+	if response["open_ports"] != float64(1) || response["open_filtered"] != float64(1) {
+		t.Errorf("open_ports/open_filtered = %v/%v, want 1/1", response["open_ports"], response["open_filtered"])
+	}
+	ports := response["ports"].([]interface{})
+	if got := ports[1].(map[string]interface{})["status"]; got != "open|filtered" {
+		t.Errorf("status of port 161 = %v, want open|filtered", got)
+	}
+}
+
+func TestScanIPRejectsInvalidRequests(t *testing.T) {
+	h, scanner := newTestHandler()
+	scanner.TechniqueErrors[domain.TechniqueSYN] = errors.New("SYN scans require raw socket privileges")
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"missing ip", `{"ports": [80]}`},
+		{"bad json", `{"ip": `},
+		{"intensity out of range", `{"ip": "8.8.8.8", "intensity": 9}`},
+		{"unknown port order", `{"ip": "8.8.8.8", "port_order": "sideways"}`},
+		{"bad port spec", `{"ip": "8.8.8.8", "port_spec": "80-"}`},
+		{"unsupported technique", `{"ip": "8.8.8.8", "technique": "syn"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, response := postScan(t, h, "/api/v1/scan", tt.body)
+			if status != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d: %v", status, http.StatusBadRequest, response)
+			}
+			if response["error"] == nil {
+				t.Errorf("response has no error: %v", response)
+			}
+		})
+	}
+	if len(scanner.Calls) != 0 {
+		t.Errorf("invalid requests were scanned: %v", scanner.Calls)
+	}
+}
+
+func TestScanIPMapsScanErrorsToStatus(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{domain.ErrProtectedTarget, http.StatusForbidden},
+		{domain.ErrExcludedTarget, http.StatusForbidden},
+		{domain.ErrEngineStopped, http.StatusServiceUnavailable},
+		{errors.New("scan exploded"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			h, scanner := newTestHandler()
+			scanner.Errors["8.8.8.8"] = tt.err
+
+			status, response := postScan(t, h, "/api/v1/scan", `{"ip": "8.8.8.8"}`)
+			if status != tt.status {
+				t.Errorf("status = %d, want %d", status, tt.status)
+			}
+			if response["error"] != tt.err.Error() {
+				t.Errorf("error = %v, want %q", response["error"], tt.err.Error())
+			}
+		})
+	}
+}
+
+func TestScanIPReusesCachedResults(t *testing.T) {
+	h, scanner := newTestHandler()
+	h.SetScanCacheTTL(time.Minute)
+	scanner.AddOpenPorts("8.8.8.8", 443)
+
+	body := `{"ip": "8.8.8.8", "ports": [443]}`
+	if _, response := postScan(t, h, "/api/v1/scan", body); response["cached"] != false {
+		t.Errorf("first scan cached = %v, want false", response["cached"])
+	}
+	if _, response := postScan(t, h, "/api/v1/scan", body); response["cached"] != true {
+		t.Errorf("repeated scan cached = %v, want true", response["cached"])
+	}
+	if len(scanner.Calls) != 1 {
+		t.Errorf("scanner called %d times, want 1", len(scanner.Calls))
+	}
+
+	if _, response := postScan(t, h, "/api/v1/scan?force=true", body); response["cached"] != false {
+		t.Errorf("forced scan cached = %v, want false", response["cached"])
+	}
+	if len(scanner.Calls) != 2 {
+		t.Errorf("scanner called %d times after a forced scan, want 2", len(scanner.Calls))
+	}
+}
+
+func TestScanIPAppliesTemplates(t *testing.T) {
+	h, _ := newTestHandler()
+	status, _ := postScan(t, h, "/api/v1/scan", `{"ip": "8.8.8.8", "template": "web"}`)
+	if status != http.StatusServiceUnavailable {
+		t.Errorf("template without MongoDB: status = %d, want %d", status, http.StatusServiceUnavailable)
+	}
+
+	scanner := testutil.NewFakeScanner()
+	store := &templateStore{templates: map[string]*database.ScanTemplateDocument{
+		"web": {Name: "web", Ports: []int{80, 443, 8080}},
+	}}
+	h = NewHandler(testutil.NewFakeScanEngine(nil), scanner, store)
+
+	status, response := postScan(t, h, "/api/v1/scan", `{"ip": "8.8.8.8", "template": "web"}`)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %v", status, http.StatusOK, response)
+	}
+	if got := scanner.Configs[0].PortRange; !reflect.DeepEqual(got, []int{80, 443, 8080}) {
+		t.Errorf("template ports %v, want [80 443 8080]", got)
+	}
+
+	// Explicit ports override the template's
+	postScan(t, h, "/api/v1/scan", `{"ip": "8.8.8.8", "template": "web", "ports": [22]}`)
+	if got := scanner.Configs[1].PortRange; !reflect.DeepEqual(got, []int{22}) {
+		t.Errorf("requested ports %v, want [22]", got)
+	}
+
+	status, _ = postScan(t, h, "/api/v1/scan", `{"ip": "8.8.8.8", "template": "missing"}`)
+	if status != http.StatusBadRequest {
+		t.Errorf("unknown template: status = %d, want %d", status, http.StatusBadRequest)
+	}
+}
+
+func TestScanIPAppliesIntensity(t *testing.T) {
+	scanner := testutil.NewFakeScanner()
+	maxRetries := 0
+	store := &templateStore{templates: map[string]*database.ScanTemplateDocument{
+		"careful": {Name: "careful", ConnectTimeout: "8s", MaxRetries: &maxRetries},
+	}}
+	h := NewHandler(testutil.NewFakeScanEngine(nil), scanner, store)
+
+	if status, response := postScan(t, h, "/api/v1/scan", `{"ip": "8.8.8.8", "intensity": 5}`); status != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %v", status, http.StatusOK, response)
+	}
+	insane := scanner.Configs[0]
+	if insane.ConnectTimeout != 750*time.Millisecond || insane.Concurrency != 1000 || insane.MaxRetries != 1 || insane.RetryDelay != 250*time.Millisecond {
+		t.Errorf("intensity 5 scanned with timeout %v, concurrency %d, retries %d, delay %v",
+			insane.ConnectTimeout, insane.Concurrency, insane.MaxRetries, insane.RetryDelay)
+	}
+
+	// Settings the template sets explicitly override the intensity's
+	postScan(t, h, "/api/v1/scan?force=true", `{"ip": "8.8.8.8", "intensity": 5, "template": "careful"}`)
+	overridden := scanner.Configs[1]
+	if overridden.ConnectTimeout != 8*time.Second || overridden.MaxRetries != 0 {
+		t.Errorf("template settings not applied over the intensity: timeout %v, retries %d", overridden.ConnectTimeout, overridden.MaxRetries)
+	}
+	if overridden.Concurrency != 1000 || overridden.RetryDelay != 250*time.Millisecond {
+		t.Errorf("intensity settings the template leaves unset were lost: concurrency %d, delay %v", overridden.Concurrency, overridden.RetryDelay)
+	}
+
+	for _, body := range []string{`{"ip": "8.8.8.8", "intensity": 6}`, `{"ip": "8.8.8.8", "intensity": -1}`} {
+		if status, _ := postScan(t, h, "/api/v1/scan", body); status != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", body, status, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestScanIPAppliesTechnique(t *testing.T) {
+	h, scanner := newTestHandler()
+
+	for _, body := range []string{`{"ip": "8.8.8.8"}`, `{"ip": "8.8.8.8", "technique": "connect"}`, `{"ip": "8.8.8.8", "technique": "syn"}`} {
+		if status, response := postScan(t, h, "/api/v1/scan?force=true", body); status != http.StatusOK {
+			t.Fatalf("%s: status = %d, want %d: %v", body, status, http.StatusOK, response)
+		}
+	}
+	for i, want := range []string{domain.TechniqueConnect, domain.TechniqueConnect, domain.TechniqueSYN} {
+		if got := scanner.Configs[i].Technique; got != want {
+			t.Errorf("scan %d used technique %q, want %q", i, got, want)
+		}
+	}
+
+	// A technique the process can't run is refused up front, on every scan endpoint
+	scanner.TechniqueErrors[domain.TechniqueSYN] = fmt.Errorf("%w: syn scanning needs raw-socket capability", domain.ErrUnsupportedTechnique)
+	for _, tt := range []struct{ path, body string }{
+		{"/api/v1/scan", `{"ip": "8.8.8.8", "technique": "syn"}`},
+		{"/api/v1/scan/batch", `{"ips": ["8.8.8.8"], "technique": "syn"}`},
+		{"/api/v1/scan", `{"ip": "8.8.8.8", "technique": "xmas"}`},
+	} {
+		status, response := postScan(t, h, tt.path, tt.body)
+		if status != http.StatusBadRequest {
+			t.Errorf("%s %s: status = %d, want %d", tt.path, tt.body, status, http.StatusBadRequest)
+		}
+		if response["error"] == nil {
+			t.Errorf("%s %s: response has no error: %v", tt.path, tt.body, response)
+		}
+	}
+	if got := serve(h, http.MethodPost, "/api/v1/scan", `{"ip": "8.8.8.8", "technique": "syn"}`).Body.String(); !strings.Contains(got, "raw-socket capability") {
+		t.Errorf("unsupported technique error %s does not give the reason", got)
+	}
+	if len(scanner.Calls) != 3 {
+		t.Errorf("scanner called %d times, want only the 3 supported scans", len(scanner.Calls))
+	}
+}
+
+func TestTemplateLifecycle(t *testing.T) {
+	scanner := testutil.NewFakeScanner()
+	store := &templateStore{templates: map[string]*database.ScanTemplateDocument{}}
+	h := NewHandler(testutil.NewFakeScanEngine(nil), scanner, store)
+
+	status, response := postScan(t, h, "/api/v1/templates", `{"name": "web", "ports": [80, 443], "connect_timeout": "2s"}`)
+	if status != http.StatusCreated {
+		t.Fatalf("create: status = %d, want %d: %v", status, http.StatusCreated, response)
+	}
+	if status, _ := postScan(t, h, "/api/v1/templates", `{"name": "web", "ports": [8080]}`); status != http.StatusConflict {
+		t.Errorf("duplicate create: status = %d, want %d", status, http.StatusConflict)
+	}
+	for _, body := range []string{`{"ports": [80]}`, `{"name": "bad", "ports": [70000]}`, `{"name": "bad", "connect_timeout": "soon"}`} {
+		if status, _ := postScan(t, h, "/api/v1/templates", body); status != http.StatusBadRequest {
+			t.Errorf("create %s: status = %d, want %d", body, status, http.StatusBadRequest)
+		}
+	}
+
+	postScan(t, h, "/api/v1/scan", `{"ip": "8.8.8.8", "template": "web"}`)
+	if got := scanner.Configs[0]; !reflect.DeepEqual(got.PortRange, []int{80, 443}) || got.ConnectTimeout != 2*time.Second {
+		t.Errorf("scan with the created template used ports %v and timeout %v", got.PortRange, got.ConnectTimeout)
+	}
+
+	recorder := serve(h, http.MethodPut, "/api/v1/templates/web", `{"ports": [8443]}`)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("update: status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body)
+	}
+	postScan(t, h, "/api/v1/scan?force=true", `{"ip": "8.8.8.8", "template": "web"}`)
+	if got := scanner.Configs[len(scanner.Configs)-1].PortRange; !reflect.DeepEqual(got, []int{8443}) {
+		t.Errorf("scan with the updated template used ports %v, want [8443]", got)
+	}
+	if recorder := serve(h, http.MethodPut, "/api/v1/templates/missing", `{"ports": [22]}`); recorder.Code != http.StatusNotFound {
+		t.Errorf("update of an unknown template: status = %d, want %d", recorder.Code, http.StatusNotFound)
+	}
+
+	if recorder := serve(h, http.MethodDelete, "/api/v1/templates/web", ""); recorder.Code != http.StatusOK {
+		t.Fatalf("delete: status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body)
+	}
+	if recorder := serve(h, http.MethodDelete, "/api/v1/templates/web", ""); recorder.Code != http.StatusNotFound {
+		t.Errorf("second delete: status = %d, want %d", recorder.Code, http.StatusNotFound)
+	}
+	if status, _ := postScan(t, h, "/api/v1/scan?force=true", `{"ip": "8.8.8.8", "template": "web"}`); status != http.StatusBadRequest {
+		t.Errorf("scan with a deleted template: status = %d, want %d", status, http.StatusBadRequest)
+	}
+}
+
+func TestScanBatchStreamWritesOneLinePerIP(t *testing.T) {
+	h, scanner := newTestHandler()
+	scanner.AddOpenPorts("8.8.8.8", 53)
+	scanner.AddOpenPorts("1.1.1.1", 53, 443)
+	scanner.Errors["9.9.9.9"] = errors.New("host unreachable")
+
+	recorder := serve(h, http.MethodPost, "/api/v1/scan/batch/stream", `{"ips": ["8.8.8.8", "1.1.1.1", "9.9.9.9"], "batch_concurrency": 2}`)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+	if got := recorder.Header().Get("Content-Type"); got != "application/x-ndjson" {
+		t.Errorf("content type = %q, want application/x-ndjson", got)
+	}
+	if !recorder.Flushed {
+		t.Error("streamed results were not flushed")
+	}
+
+	entries := make(map[string]map[string]interface{})
+	lines := bufio.NewScanner(recorder.Body)
+	for lines.Scan() {
+		var entry map[string]interface{}
+		if err := json.Unmarshal(lines.Bytes(), &entry); err != nil {
+			t.Fatalf("line %q is not a JSON object: %v", lines.Text(), err)
+		}
+		ip, _ := entry["ip"].(string)
+		if _, seen := entries[ip]; seen {
+			t.Errorf("IP %s streamed more than once", ip)
+		}
+		entries[ip] = entry
+	}
+	if len(entries) != 3 {
+		t.Fatalf("streamed %d entries, want one per IP: %v", len(entries), entries)
+	}
+	if got := entries["1.1.1.1"]["open_ports"]; got != float64(2) {
+		t.Errorf("1.1.1.1 open_ports = %v, want 2", got)
+	}
+	if got := entries["9.9.9.9"]; got["status"] != "failed" || got["error"] != "host unreachable" {
+		t.Errorf("failed IP entry = %v", got)
+	}
+}
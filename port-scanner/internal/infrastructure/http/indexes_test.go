@@ -0,0 +1,83 @@
+package http
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"port-scanner/internal/infrastructure/database"
+	"port-scanner/internal/testutil"
+)
+
+func TestIndexEndpointsWithoutMongoDB(t *testing.T) {
+	h, _ := newTestHandler()
+	h.SetAPIKey("secret")
+
+	if status, _ := requestWithKey(t, h, http.MethodGet, "/api/v1/db/indexes", ""); status != http.StatusServiceUnavailable {
+		t.Errorf("listing indexes without MongoDB: status = %d, want %d", status, http.StatusServiceUnavailable)
+	}
+	if status, _ := requestWithKey(t, h, http.MethodPost, "/api/v1/db/indexes/rebuild", ""); status != http.StatusUnauthorized {
+		t.Errorf("rebuilding indexes without the API key: status = %d, want %d", status, http.StatusUnauthorized)
+	}
+	if status, _ := requestWithKey(t, h, http.MethodPost, "/api/v1/db/indexes/rebuild", "secret"); status != http.StatusServiceUnavailable {
+		t.Errorf("rebuilding indexes without MongoDB: status = %d, want %d", status, http.StatusServiceUnavailable)
+	}
+}
+
+// indexStore is a Store holding indexes; rebuilding adds the missing ones
+type indexStore struct {
+	Store
+	indexes  []database.IndexInfo
+	missing  []database.IndexInfo
+	rebuilds int
+}
+
+func (s *indexStore) ListIndexes() ([]database.IndexInfo, error) {
+	return s.indexes, nil
+}
+
+func (s *indexStore) RebuildIndexes() ([]string, error) {
+	s.rebuilds++
+	s.indexes = append(s.indexes, s.missing...)
+	s.missing = nil
+	return []string{}, nil
+}
+
+func TestIndexEndpointsListAndRebuild(t *testing.T) {
+	store := &indexStore{
+		indexes: []database.IndexInfo{
+			{Collection: "scan_results", Name: "_id_", Keys: []database.IndexKey{{Field: "_id", Order: 1}}},
+		},
+		missing: []database.IndexInfo{
+			{Collection: "scan_results", Name: "ip_created_at_idx", Keys: []database.IndexKey{{Field: "ip", Order: 1}, {Field: "created_at", Order: -1}}},
+		},
+	}
+	h := NewHandler(testutil.NewFakeScanEngine(nil), testutil.NewFakeScanner(), store)
+	h.SetAPIKey("secret")
+
+	status, response := requestWithKey(t, h, http.MethodGet, "/api/v1/db/indexes", "")
+	if status != http.StatusOK || response["count"] != float64(1) {
+		t.Fatalf("listing indexes = %d, %v, want the _id index", status, response)
+	}
+	index := response["indexes"].([]interface{})[0].(map[string]interface{})
+	if index["name"] != "_id_" || index["collection"] != "scan_results" {
+		t.Errorf("index = %v, want _id_ on scan_results", index)
+	}
+
+	// Rebuilding twice leaves the same indexes
+	var listed [2][]interface{}
+	for i := range listed {
+		status, response = requestWithKey(t, h, http.MethodPost, "/api/v1/db/indexes/rebuild", "secret")
+		if status != http.StatusOK {
+			t.Fatalf("rebuild %d status = %d, want %d: %v", i, status, http.StatusOK, response)
+		}
+		listed[i], _ = response["indexes"].([]interface{})
+	}
+	if store.rebuilds != 2 || len(listed[0]) != 2 || !reflect.DeepEqual(listed[0], listed[1]) {
+		t.Errorf("rebuilds listed %v then %v, want the same 2 indexes", listed[0], listed[1])
+	}
+	keys := listed[1][1].(map[string]interface{})["keys"].([]interface{})
+	if len(keys) != 2 || keys[0].(map[string]interface{})["field"] != "ip" {
+		t.Errorf("keys = %v, want ip then created_at", keys)
+	}
+}
@@ -0,0 +1,39 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"port-scanner/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetMetrics exposes scan counters in the Prometheus text format
+func (h *Handler) GetMetrics(c *gin.Context) {
+	var b strings.Builder
+
+	stats := h.scanEngine.GetScanStats()
+	writeMetric(&b, "port_scanner_scans_total", "counter", "Completed IP scans by outcome")
+	fmt.Fprintf(&b, "port_scanner_scans_total{status=\"successful\"} %d\n", stats.SuccessfulScans)
+	fmt.Fprintf(&b, "port_scanner_scans_total{status=\"failed\"} %d\n", stats.FailedScans)
+
+	writeMetric(&b, "port_scanner_active_scans", "gauge", "IP scans in flight")
+	fmt.Fprintf(&b, "port_scanner_active_scans %d\n", h.scanEngine.ActiveScans())
+
+	if scannerService, ok := h.scanner.(*domain.ScannerService); ok {
+		counts := scannerService.DialErrorCounts()
+		writeMetric(&b, "port_scanner_dial_errors_total", "counter", "Failed port connection attempts by error category")
+		for _, category := range domain.DialErrorCategories {
+			fmt.Fprintf(&b, "port_scanner_dial_errors_total{category=%q} %d\n", category, counts[category])
+		}
+	}
+
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
+}
+
+// writeMetric writes the HELP and TYPE lines that introduce a metric
+func writeMetric(b *strings.Builder, name, metricType, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
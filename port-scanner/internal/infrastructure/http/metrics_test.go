@@ -0,0 +1,82 @@
+package http
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"port-scanner/internal/domain"
+	"port-scanner/internal/testutil"
+)
+
+// newDialingHandler returns a handler around a real scanner that has been refused once
+func newDialingHandler(t *testing.T) *Handler {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to reserve a port: %v", err)
+	}
+	closed := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	config := domain.NewDefaultScanConfig()
+	config.EnableBanner = false
+	config.MaxRetries = 0
+	scanner := domain.NewScannerService(config)
+	if _, err := scanner.ScanPort("127.0.0.1", closed); err != nil {
+		t.Fatalf("ScanPort returned error: %v", err)
+	}
+	return NewHandler(testutil.NewFakeScanEngine(config), scanner, nil)
+}
+
+func TestGetStatsReportsDialErrorsByCategory(t *testing.T) {
+	recorder := serve(newDialingHandler(t), http.MethodGet, "/api/v1/stats", "")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+
+	var response struct {
+		DialErrors map[string]int64 `json:"dial_errors"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid JSON response %q: %v", recorder.Body.String(), err)
+	}
+	if len(response.DialErrors) != len(domain.DialErrorCategories) {
+		t.Errorf("dial_errors = %v, want every category", response.DialErrors)
+	}
+	if response.DialErrors[domain.DialErrorRefused] != 1 || response.DialErrors[domain.DialErrorTimeout] != 0 {
+		t.Errorf("dial_errors = %v, want one refused connection", response.DialErrors)
+	}
+}
+
+func TestGetMetricsReportsDialErrorsByCategory(t *testing.T) {
+	recorder := serve(newDialingHandler(t), http.MethodGet, "/metrics", "")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+
+	body := recorder.Body.String()
+	for _, line := range []string{
+		"# TYPE port_scanner_dial_errors_total counter",
+		`port_scanner_dial_errors_total{category="refused"} 1`,
+		`port_scanner_dial_errors_total{category="too-many-open-files"} 0`,
+		`port_scanner_dial_errors_total{category="dns-failure"} 0`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("metrics missing %q:\n%s", line, body)
+		}
+	}
+}
+
+func TestGetMetricsOmitsDialErrorsWithoutScannerService(t *testing.T) {
+	h, _ := newTestHandler()
+	body := serve(h, http.MethodGet, "/metrics", "").Body.String()
+	if !strings.Contains(body, `port_scanner_scans_total{status="successful"} 0`) {
+		t.Errorf("metrics missing scan counters:\n%s", body)
+	}
+	if strings.Contains(body, "port_scanner_dial_errors_total") {
+		t.Errorf("metrics report dial errors the scanner doesn't count:\n%s", body)
+	}
+}
@@ -0,0 +1,36 @@
+package http
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiKeyMiddleware rejects requests that don't carry the configured API key in the
+// X-API-Key header or as a bearer token. An empty key disables the check.
+func apiKeyMiddleware(apiKey string) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		if !hasValidAPIKey(c, apiKey) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or missing API key"})
+			return
+		}
+
+		c.Next()
+	})
+}
+
+// hasValidAPIKey reports whether a request carries the configured API key; always true when no key is set
+func hasValidAPIKey(c *gin.Context, apiKey string) bool {
+	if apiKey == "" {
+		return true
+	}
+
+	provided := c.GetHeader("X-API-Key")
+	if provided == "" {
+		provided = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	}
+
+	return subtle.ConstantTimeCompare([]byte(provided), []byte(apiKey)) == 1
+}
@@ -0,0 +1,59 @@
+package http
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestScanBatchStreamSendsPriorityPortsFirst(t *testing.T) {
+	h, scanner := newTestHandler()
+	scanner.AddOpenPorts("8.8.8.8", 443, 31337)
+
+	recorder := serve(h, http.MethodPost, "/api/v1/scan/batch/stream", `{"ips": ["8.8.8.8"], "partial": true}`)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+
+	var entries []map[string]interface{}
+	lines := bufio.NewScanner(recorder.Body)
+	for lines.Scan() {
+		var entry map[string]interface{}
+		if err := json.Unmarshal(lines.Bytes(), &entry); err != nil {
+			t.Fatalf("line %q is not a JSON object: %v", lines.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("streamed %d entries, want a partial and a final one: %v", len(entries), entries)
+	}
+
+	partial, final := entries[0], entries[1]
+	if partial["status"] != "partial" || partial["open_ports"] != float64(1) {
+		t.Errorf("first entry = %v, want the partial result with the priority port", partial)
+	}
+	if ports, _ := partial["ports"].([]interface{}); len(ports) != 1 || ports[0].(map[string]interface{})["number"] != float64(443) {
+		t.Errorf("partial ports = %v, want only 443", partial["ports"])
+	}
+	if final["status"] == "partial" || final["open_ports"] != float64(2) {
+		t.Errorf("second entry = %v, want the final result with both ports", final)
+	}
+	if !scanner.Configs[0].PriorityFirst {
+		t.Error("partial stream scanned without PriorityFirst")
+	}
+}
+
+func TestScanBatchStreamWithoutPartialSendsFinalOnly(t *testing.T) {
+	h, scanner := newTestHandler()
+	scanner.AddOpenPorts("8.8.8.8", 443, 31337)
+
+	recorder := serve(h, http.MethodPost, "/api/v1/scan/batch/stream", `{"ips": ["8.8.8.8"]}`)
+	entries := 0
+	for lines := bufio.NewScanner(recorder.Body); lines.Scan(); {
+		entries++
+	}
+	if entries != 1 || scanner.Configs[0].PriorityFirst {
+		t.Errorf("streamed %d entries with PriorityFirst %v, want only the final entry", entries, scanner.Configs[0].PriorityFirst)
+	}
+}
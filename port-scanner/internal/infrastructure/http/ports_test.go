@@ -0,0 +1,78 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"port-scanner/internal/domain"
+	"port-scanner/internal/testutil"
+)
+
+// newPortsHandler returns a handler whose engine knows 10.0.0.1 with an open port carrying raw ZGrab2 data
+func newPortsHandler(metadata map[string]interface{}) *Handler {
+	engine := testutil.NewFakeScanEngine(nil)
+	result := domain.NewScanResult("10.0.0.1", "batch-1", "worker-1")
+	port := domain.NewPort(22)
+	port.Status = domain.PortStatusOpen
+	port.Service = "ssh"
+	port.BannerInfo = &domain.BannerInfo{Service: "ssh", Confidence: "high", Metadata: metadata}
+	result.AddPort(port)
+	result.SetCompleted()
+	engine.AddResult(result)
+	return NewHandler(engine, testutil.NewFakeScanner(), nil)
+}
+
+// openPort returns the single open port of a GetOpenPorts response
+func openPort(t *testing.T, response map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	ports, ok := response["open_ports"].([]interface{})
+	if !ok || len(ports) != 1 {
+		t.Fatalf("open_ports = %v, want one port", response["open_ports"])
+	}
+	return ports[0].(map[string]interface{})
+}
+
+func TestGetOpenPortsIncludesRawMetadataOnlyWhenRequested(t *testing.T) {
+	h := newPortsHandler(map[string]interface{}{"ssh": map[string]interface{}{"status": "success"}})
+	h.SetAPIKey("secret")
+
+	status, response := requestWithKey(t, h, http.MethodGet, "/api/v1/ports/10.0.0.1", "")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %v", status, http.StatusOK, response)
+	}
+	if _, ok := openPort(t, response)["raw"]; ok {
+		t.Error("raw metadata returned without raw=true")
+	}
+
+	status, response = requestWithKey(t, h, http.MethodGet, "/api/v1/ports/10.0.0.1?raw=true", "secret")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %v", status, http.StatusOK, response)
+	}
+	raw, ok := openPort(t, response)["raw"].(map[string]interface{})
+	if !ok || raw["ssh"].(map[string]interface{})["status"] != "success" {
+		t.Errorf("raw = %v, want the ZGrab2 data", openPort(t, response)["raw"])
+	}
+
+	// Raw output is behind the API key
+	if status, _ := requestWithKey(t, h, http.MethodGet, "/api/v1/ports/10.0.0.1?raw=true", ""); status != http.StatusUnauthorized {
+		t.Errorf("raw request without the API key status = %d, want %d", status, http.StatusUnauthorized)
+	}
+}
+
+func TestGetOpenPortsTruncatesLargeRawMetadata(t *testing.T) {
+	h := newPortsHandler(map[string]interface{}{"body": strings.Repeat("x", 2*maxRawMetadataSize)})
+
+	status, response := requestWithKey(t, h, http.MethodGet, "/api/v1/ports/10.0.0.1?raw=true", "")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %v", status, http.StatusOK, response)
+	}
+	port := openPort(t, response)
+	raw, ok := port["raw"].(string)
+	if !ok || port["raw_truncated"] != true {
+		t.Fatalf("raw_truncated = %v, want large metadata returned as a truncated string", port["raw_truncated"])
+	}
+	if len(raw) > maxRawMetadataSize+64 {
+		t.Errorf("raw is %d bytes, want it cut near %d", len(raw), maxRawMetadataSize)
+	}
+}
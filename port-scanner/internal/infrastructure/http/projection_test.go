@@ -0,0 +1,87 @@
+package http
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"port-scanner/internal/infrastructure/database"
+	"port-scanner/internal/testutil"
+)
+
+// projectionStore is a Store returning one full document and recording the projections asked for
+type projectionStore struct {
+	Store
+	doc         *database.ScanResultDocument
+	projections []*database.Projection
+}
+
+func (s *projectionStore) GetScanResult(ip string, projection *database.Projection) (*database.ScanResultDocument, error) {
+	s.projections = append(s.projections, projection)
+	return s.doc, nil
+}
+
+func (s *projectionStore) GetScanResultsByBatch(batchID string, projection *database.Projection, limit int) ([]*database.ScanResultDocument, error) {
+	s.projections = append(s.projections, projection)
+	return []*database.ScanResultDocument{s.doc}, nil
+}
+
+func newProjectionHandler() (*Handler, *projectionStore) {
+	store := &projectionStore{doc: &database.ScanResultDocument{
+		IP:      "8.8.8.8",
+		BatchID: "batch-1",
+		Status:  "completed",
+		Ports: []database.PortDocument{
+			{Number: 53, Status: "open", Service: "dns", Banner: "large banner"},
+		},
+	}}
+	return NewHandler(testutil.NewFakeScanEngine(nil), testutil.NewFakeScanner(), store), store
+}
+
+func TestDatabaseResultProjectsRequestedFields(t *testing.T) {
+	h, store := newProjectionHandler()
+
+	status, response := requestWithKey(t, h, http.MethodGet, "/api/v1/db/result/8.8.8.8?fields=ip,ports.number", "")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %v", status, http.StatusOK, response)
+	}
+	want := map[string]interface{}{
+		"ip":    "8.8.8.8",
+		"ports": []interface{}{map[string]interface{}{"number": float64(53)}},
+	}
+	if !reflect.DeepEqual(response, want) {
+		t.Errorf("response = %v, want %v", response, want)
+	}
+	if len(store.projections) != 1 || store.projections[0] == nil {
+		t.Errorf("store asked for projections %v, want the parsed fields", store.projections)
+	}
+
+	_, batch := requestWithKey(t, h, http.MethodGet, "/api/v1/db/batch/batch-1?fields=ports.number", "")
+	results, _ := batch["results"].([]interface{})
+	if len(results) != 1 || !reflect.DeepEqual(results[0], map[string]interface{}{"ports": want["ports"]}) {
+		t.Errorf("batch results = %v, want only the port numbers", batch["results"])
+	}
+
+	// Without fields the whole document is returned
+	_, full := requestWithKey(t, h, http.MethodGet, "/api/v1/db/result/8.8.8.8", "")
+	if full["status"] != "completed" || full["batch_id"] != "batch-1" {
+		t.Errorf("response without fields = %v, want the whole document", full)
+	}
+}
+
+func TestDatabaseResultRejectsUnknownFields(t *testing.T) {
+	h, store := newProjectionHandler()
+
+	for _, path := range []string{
+		"/api/v1/db/result/8.8.8.8?fields=ip,password",
+		"/api/v1/db/batch/batch-1?fields=ports.secret",
+	} {
+		status, response := requestWithKey(t, h, http.MethodGet, path, "")
+		if status != http.StatusBadRequest || response["error"] == nil {
+			t.Errorf("GET %s = %d, %v, want 400 with an error", path, status, response)
+		}
+	}
+	if len(store.projections) != 0 {
+		t.Errorf("store queried %d times for invalid fields, want none", len(store.projections))
+	}
+}
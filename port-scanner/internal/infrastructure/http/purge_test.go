@@ -0,0 +1,136 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"port-scanner/internal/infrastructure/database"
+	"port-scanner/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// purgeStore is a Store holding scan result documents in memory that only supports deleting them
+type purgeStore struct {
+	Store
+	docs []*database.ScanResultDocument
+}
+
+// DeleteByBatch removes the documents of a batch
+func (s *purgeStore) DeleteByBatch(batchID string) (int64, error) {
+	return s.deleteWhere(func(doc *database.ScanResultDocument) bool { return doc.BatchID == batchID }), nil
+}
+
+// DeleteOlderThan removes the documents created before now minus age
+func (s *purgeStore) DeleteOlderThan(age time.Duration) (int64, error) {
+	cutoff := time.Now().Add(-age)
+	return s.deleteWhere(func(doc *database.ScanResultDocument) bool { return doc.CreatedAt.Before(cutoff) }), nil
+}
+
+func (s *purgeStore) deleteWhere(match func(doc *database.ScanResultDocument) bool) int64 {
+	var kept []*database.ScanResultDocument
+	for _, doc := range s.docs {
+		if !match(doc) {
+			kept = append(kept, doc)
+		}
+	}
+	deleted := int64(len(s.docs) - len(kept))
+	s.docs = kept
+	return deleted
+}
+
+func (s *purgeStore) ips() []string {
+	var ips []string
+	for _, doc := range s.docs {
+		ips = append(ips, doc.IP)
+	}
+	return ips
+}
+
+// newPurgeHandler returns a handler over seeded documents that requires the API key "secret"
+func newPurgeHandler() (*Handler, *purgeStore) {
+	now := time.Now()
+	store := &purgeStore{docs: []*database.ScanResultDocument{
+		{IP: "10.0.0.1", BatchID: "batch-1", CreatedAt: now.Add(-40 * 24 * time.Hour)},
+		{IP: "10.0.0.2", BatchID: "batch-1", CreatedAt: now},
+		{IP: "10.0.0.3", BatchID: "batch-2", CreatedAt: now.Add(-31 * 24 * time.Hour)},
+		{IP: "10.0.0.4", BatchID: "batch-2", CreatedAt: now.Add(-time.Hour)},
+	}}
+	h := NewHandler(testutil.NewFakeScanEngine(nil), testutil.NewFakeScanner(), store)
+	h.SetAPIKey("secret")
+	return h, store
+}
+
+// requestWithKey sends a request carrying an API key and decodes the JSON response
+func requestWithKey(t *testing.T, h *Handler, method, path, apiKey string) (int, map[string]interface{}) {
+	t.Helper()
+	router := gin.New()
+	h.RegisterRoutes(router)
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, nil)
+	if apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+	router.ServeHTTP(recorder, req)
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid JSON response %q: %v", recorder.Body.String(), err)
+	}
+	return recorder.Code, response
+}
+
+func TestDeleteDatabaseBatchRemovesOnlyThatBatch(t *testing.T) {
+	h, store := newPurgeHandler()
+
+	status, response := requestWithKey(t, h, http.MethodDelete, "/api/v1/db/batch/batch-1", "secret")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %v", status, http.StatusOK, response)
+	}
+	if response["deleted"] != float64(2) || response["batch_id"] != "batch-1" {
+		t.Errorf("response = %v, want 2 deleted from batch-1", response)
+	}
+	if got := store.ips(); len(got) != 2 || got[0] != "10.0.0.3" || got[1] != "10.0.0.4" {
+		t.Errorf("remaining documents = %v, want the batch-2 ones", got)
+	}
+}
+
+func TestDeleteDatabaseResultsRemovesOlderResults(t *testing.T) {
+	h, store := newPurgeHandler()
+
+	status, response := requestWithKey(t, h, http.MethodDelete, "/api/v1/db/results?older_than=30d", "secret")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %v", status, http.StatusOK, response)
+	}
+	if response["deleted"] != float64(2) || response["older_than"] != "30d" {
+		t.Errorf("response = %v, want 2 deleted older than 30d", response)
+	}
+	if got := store.ips(); len(got) != 2 || got[0] != "10.0.0.2" || got[1] != "10.0.0.4" {
+		t.Errorf("remaining documents = %v, want the recent ones", got)
+	}
+
+	for _, path := range []string{"/api/v1/db/results", "/api/v1/db/results?older_than=soon", "/api/v1/db/results?older_than=0d"} {
+		if status, _ := requestWithKey(t, h, http.MethodDelete, path, "secret"); status != http.StatusBadRequest {
+			t.Errorf("DELETE %s status = %d, want %d", path, status, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestDeleteEndpointsRequireAPIKey(t *testing.T) {
+	h, store := newPurgeHandler()
+
+	for _, path := range []string{"/api/v1/db/batch/batch-1", "/api/v1/db/results?older_than=1d"} {
+		for _, key := range []string{"", "wrong"} {
+			if status, _ := requestWithKey(t, h, http.MethodDelete, path, key); status != http.StatusUnauthorized {
+				t.Errorf("DELETE %s with key %q status = %d, want %d", path, key, status, http.StatusUnauthorized)
+			}
+		}
+	}
+	if len(store.docs) != 4 {
+		t.Errorf("%d documents left after unauthorized deletes, want 4", len(store.docs))
+	}
+}
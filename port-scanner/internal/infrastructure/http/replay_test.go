@@ -0,0 +1,115 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"port-scanner/internal/domain"
+	"port-scanner/internal/infrastructure/database"
+	"port-scanner/internal/testutil"
+)
+
+// replayStore is a Store serving stored scan result documents by IP and batch
+type replayStore struct {
+	Store
+	docs []*database.ScanResultDocument
+}
+
+func (s *replayStore) GetScanResult(ip string, projection *database.Projection) (*database.ScanResultDocument, error) {
+	for _, doc := range s.docs {
+		if doc.IP == ip {
+			return doc, nil
+		}
+	}
+	return nil, errors.New("scan result not found")
+}
+
+func (s *replayStore) GetScanResultsByBatch(batchID string, projection *database.Projection, limit int) ([]*database.ScanResultDocument, error) {
+	var docs []*database.ScanResultDocument
+	for _, doc := range s.docs {
+		if doc.BatchID == batchID {
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+// recordingReplayer keeps the results it was asked to publish again
+type recordingReplayer struct {
+	results []*domain.ScanResult
+}
+
+func (r *recordingReplayer) ReplayScanResult(result *domain.ScanResult) error {
+	r.results = append(r.results, result)
+	return nil
+}
+
+func (r *recordingReplayer) ips() []string {
+	var ips []string
+	for _, result := range r.results {
+		ips = append(ips, result.IP)
+	}
+	return ips
+}
+
+func newReplayHandler() (*Handler, *recordingReplayer) {
+	store := &replayStore{docs: []*database.ScanResultDocument{
+		{IP: "8.8.8.8", BatchID: "batch-1", Status: "completed", IsUp: true, Ports: []database.PortDocument{{Number: 53, Status: "open", Service: "dns"}}},
+		{IP: "1.1.1.1", BatchID: "batch-1", Status: "completed", IsUp: true},
+		{IP: "9.9.9.9", BatchID: "batch-2", Status: "completed"},
+	}}
+	replayer := &recordingReplayer{}
+	h := NewHandler(testutil.NewFakeScanEngine(nil), testutil.NewFakeScanner(), store)
+	h.SetAPIKey("secret")
+	h.SetResultReplayer(replayer)
+	return h, replayer
+}
+
+func TestReplayDatabaseResultRepublishesStoredResult(t *testing.T) {
+	h, replayer := newReplayHandler()
+
+	status, response := requestWithKey(t, h, http.MethodPost, "/api/v1/db/result/8.8.8.8/replay", "secret")
+	if status != http.StatusOK || response["replayed"] != float64(1) {
+		t.Fatalf("status = %d, response = %v, want one result replayed", status, response)
+	}
+	if len(replayer.results) != 1 {
+		t.Fatalf("replayed %d results, want 1", len(replayer.results))
+	}
+	result := replayer.results[0]
+	if result.IP != "8.8.8.8" || result.BatchID != "batch-1" || len(result.GetOpenPorts()) != 1 || result.Ports[0].Service != "dns" {
+		t.Errorf("replayed %+v, want the stored result with its open DNS port", result)
+	}
+
+	if status, _ := requestWithKey(t, h, http.MethodPost, "/api/v1/db/result/4.4.4.4/replay", "secret"); status != http.StatusNotFound {
+		t.Errorf("replaying an unknown IP: status = %d, want %d", status, http.StatusNotFound)
+	}
+}
+
+func TestReplayDatabaseBatchRepublishesEveryResult(t *testing.T) {
+	h, replayer := newReplayHandler()
+
+	status, response := requestWithKey(t, h, http.MethodPost, "/api/v1/db/batch/batch-1/replay", "secret")
+	if status != http.StatusOK || response["replayed"] != float64(2) {
+		t.Fatalf("status = %d, response = %v, want two results replayed", status, response)
+	}
+	if got, want := replayer.ips(), []string{"8.8.8.8", "1.1.1.1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("replayed %v, want %v", got, want)
+	}
+
+	if status, _ := requestWithKey(t, h, http.MethodPost, "/api/v1/db/batch/batch-9/replay", "secret"); status != http.StatusNotFound {
+		t.Errorf("replaying an unknown batch: status = %d, want %d", status, http.StatusNotFound)
+	}
+}
+
+func TestReplayRequiresAPIKey(t *testing.T) {
+	h, replayer := newReplayHandler()
+
+	if status, _ := requestWithKey(t, h, http.MethodPost, "/api/v1/db/batch/batch-1/replay", ""); status != http.StatusUnauthorized {
+		t.Errorf("status without API key = %d, want %d", status, http.StatusUnauthorized)
+	}
+	if len(replayer.results) != 0 {
+		t.Errorf("replayed %d results without an API key", len(replayer.results))
+	}
+}
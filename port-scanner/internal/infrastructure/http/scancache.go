@@ -0,0 +1,97 @@
+package http
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"port-scanner/internal/domain"
+)
+
+// cachedScan is a completed result of a scan requested through the API
+type cachedScan struct {
+	result  *domain.ScanResult
+	expires time.Time
+}
+
+// scanCache keeps recent results of API scans so repeated requests for the same IP and ports
+// don't scan again within the TTL. A nil cache keeps nothing. It is safe for concurrent use.
+type scanCache struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	entries   map[string]cachedScan
+	lastSweep time.Time
+}
+
+// newScanCache creates a cache keeping results for ttl; a non-positive ttl returns nil
+func newScanCache(ttl time.Duration) *scanCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &scanCache{
+		ttl:       ttl,
+		entries:   make(map[string]cachedScan),
+		lastSweep: time.Now(),
+	}
+}
+
+// scanCacheKey identifies a scan by IP, technique and the set of ports scanned
+func scanCacheKey(ip string, config *domain.ScanConfig) string {
+	ports := config.DefaultPorts
+	if len(config.PortRange) > 0 {
+		ports = config.PortRange
+	}
+	sorted := append([]int(nil), ports...)
+	sort.Ints(sorted)
+
+	h := fnv.New64a()
+	for _, port := range sorted {
+		fmt.Fprintf(h, "%d,", port)
+	}
+	return fmt.Sprintf("%s|%s|%x", ip, config.Technique, h.Sum64())
+}
+
+// get returns the cached result of a scan while it is fresh
+func (c *scanCache) get(key string) (*domain.ScanResult, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.result, true
+}
+
+// put records a completed result, sweeping out expired entries at most once per TTL
+func (c *scanCache) put(key string, result *domain.ScanResult) {
+	if c == nil || result.Status != domain.ScanStatusCompleted {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.entries[key] = cachedScan{result: result, expires: now.Add(c.ttl)}
+
+	if now.Sub(c.lastSweep) < c.ttl {
+		return
+	}
+	for k, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.lastSweep = now
+}
@@ -0,0 +1,97 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"port-scanner/internal/domain"
+)
+
+func TestScanIPReturnsCachedResultWithinTTL(t *testing.T) {
+	h, scanner := newTestHandler()
+	h.SetScanCacheTTL(time.Minute)
+	scanner.AddOpenPorts("8.8.8.8", 53, 443)
+
+	_, first := postScan(t, h, "/api/v1/scan", `{"ip": "8.8.8.8", "ports": [53, 443]}`)
+	if first["cached"] != false {
+		t.Fatalf("first scan cached = %v, want false", first["cached"])
+	}
+
+	// The same port set in another order is the same scan
+	status, second := postScan(t, h, "/api/v1/scan", `{"ip": "8.8.8.8", "ports": [443, 53]}`)
+	if status != http.StatusOK || second["cached"] != true {
+		t.Fatalf("second scan = %d, cached %v, want the cached result", status, second["cached"])
+	}
+	if len(scanner.Calls) != 1 {
+		t.Errorf("scanner called %d times, want once within the TTL", len(scanner.Calls))
+	}
+	if second["scan_start_time"] != first["scan_start_time"] || second["open_ports"] != float64(2) {
+		t.Errorf("cached response = %v, want the first scan's result", second)
+	}
+
+	// Another port set or IP is a different scan
+	postScan(t, h, "/api/v1/scan", `{"ip": "8.8.8.8", "ports": [53]}`)
+	postScan(t, h, "/api/v1/scan", `{"ip": "8.8.4.4", "ports": [53, 443]}`)
+	if len(scanner.Calls) != 3 {
+		t.Errorf("scanner called %d times, want other port sets and IPs scanned", len(scanner.Calls))
+	}
+}
+
+func TestScanIPForceBypassesCache(t *testing.T) {
+	h, scanner := newTestHandler()
+	h.SetScanCacheTTL(time.Minute)
+	scanner.AddOpenPorts("8.8.8.8", 53)
+
+	postScan(t, h, "/api/v1/scan", `{"ip": "8.8.8.8", "ports": [53]}`)
+	_, forced := postScan(t, h, "/api/v1/scan?force=true", `{"ip": "8.8.8.8", "ports": [53]}`)
+	if forced["cached"] != false || len(scanner.Calls) != 2 {
+		t.Errorf("forced scan cached = %v after %d scans, want a fresh scan", forced["cached"], len(scanner.Calls))
+	}
+}
+
+func TestScanIPRescansAfterTTL(t *testing.T) {
+	h, scanner := newTestHandler()
+	h.SetScanCacheTTL(20 * time.Millisecond)
+	scanner.AddOpenPorts("8.8.8.8", 53)
+
+	postScan(t, h, "/api/v1/scan", `{"ip": "8.8.8.8", "ports": [53]}`)
+	time.Sleep(30 * time.Millisecond)
+	_, again := postScan(t, h, "/api/v1/scan", `{"ip": "8.8.8.8", "ports": [53]}`)
+	if again["cached"] != false || len(scanner.Calls) != 2 {
+		t.Errorf("scan after the TTL cached = %v after %d scans, want a fresh scan", again["cached"], len(scanner.Calls))
+	}
+}
+
+func TestScanIPWithoutCacheTTLAlwaysScans(t *testing.T) {
+	h, scanner := newTestHandler()
+	h.SetScanCacheTTL(0)
+	scanner.AddOpenPorts("8.8.8.8", 53)
+
+	for i := 0; i < 2; i++ {
+		postScan(t, h, "/api/v1/scan", `{"ip": "8.8.8.8", "ports": [53]}`)
+	}
+	if len(scanner.Calls) != 2 {
+		t.Errorf("scanner called %d times, want every request scanned without a cache", len(scanner.Calls))
+	}
+}
+
+func TestScanCacheSkipsIncompleteResults(t *testing.T) {
+	cache := newScanCache(time.Minute)
+	config := domain.NewDefaultScanConfig()
+	key := scanCacheKey("8.8.8.8", config)
+
+	failed := domain.NewScanResult("8.8.8.8", "", "worker-1")
+	failed.SetFailed("timeout")
+	cache.put(key, failed)
+	if _, ok := cache.get(key); ok {
+		t.Error("failed result was cached")
+	}
+
+	completed := domain.NewScanResult("8.8.8.8", "", "worker-1")
+	completed.SetCompleted()
+	cache.put(key, completed)
+	if got, ok := cache.get(key); !ok || got != completed {
+		t.Error("completed result was not cached")
+	}
+}
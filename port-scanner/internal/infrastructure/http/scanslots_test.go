@@ -0,0 +1,101 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"port-scanner/internal/application"
+	"port-scanner/internal/domain"
+	"port-scanner/internal/testutil"
+)
+
+// newSlotHandler returns a handler whose scans run within the slots of a real engine allowing
+// concurrency scans at once
+func newSlotHandler(concurrency int) (*Handler, *application.ScanEngineService, *concurrencyScanner) {
+	config := domain.NewDefaultScanConfig()
+	config.Concurrency = concurrency
+	config.MaxBatchConcurrency = 16
+	scanner := &concurrencyScanner{FakeScanner: testutil.NewFakeScanner()}
+	engine := application.NewScanEngineService(scanner, nil, config)
+	return NewHandler(engine, scanner, nil), engine, scanner
+}
+
+func TestScanBatchSharesEngineConcurrency(t *testing.T) {
+	h, _, scanner := newSlotHandler(2)
+
+	status, response := postScan(t, h, "/api/v1/scan/batch", batchBody(8, 8))
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %v", status, http.StatusOK, response)
+	}
+	if got := len(scanner.Calls); got != 8 {
+		t.Errorf("scanned %d IPs, want 8", got)
+	}
+	if got := atomic.LoadInt64(&scanner.peak); got != 2 {
+		t.Errorf("peak concurrent scans = %d, want the engine's 2", got)
+	}
+}
+
+func TestConcurrentScanRequestsShareEngineConcurrency(t *testing.T) {
+	h, _, scanner := newSlotHandler(3)
+
+	// A burst of batch requests, each allowed to scan all its IPs at once
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			recorder := serve(h, http.MethodPost, "/api/v1/scan/batch", batchBody(4, 4))
+			if recorder.Code != http.StatusOK {
+				t.Errorf("request %d: status = %d, want %d", i, recorder.Code, http.StatusOK)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt64(&scanner.peak); got != 3 {
+		t.Errorf("peak concurrent scans across requests = %d, want the engine's 3", got)
+	}
+}
+
+func TestAPIScansWaitForSlotsHeldByQueuedScans(t *testing.T) {
+	h, engine, scanner := newSlotHandler(2)
+
+	// A queued scan holds one of the two slots for the whole batch
+	release, err := engine.AcquireScanSlot(context.Background())
+	if err != nil {
+		t.Fatalf("AcquireScanSlot returned error: %v", err)
+	}
+	defer release()
+
+	if status, response := postScan(t, h, "/api/v1/scan/batch", batchBody(4, 4)); status != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %v", status, http.StatusOK, response)
+	}
+	if got := atomic.LoadInt64(&scanner.peak); got != 1 {
+		t.Errorf("peak concurrent API scans = %d, want 1 with the other slot taken", got)
+	}
+}
+
+// stoppedEngine is a FakeScanEngine that has stopped handing out scan slots
+type stoppedEngine struct {
+	*testutil.FakeScanEngine
+}
+
+func (e stoppedEngine) AcquireScanSlot(ctx context.Context) (func(), error) {
+	return nil, domain.ErrEngineStopped
+}
+
+func TestScanIPUnavailableOnceEngineStopped(t *testing.T) {
+	scanner := testutil.NewFakeScanner()
+	h := NewHandler(stoppedEngine{testutil.NewFakeScanEngine(nil)}, scanner, nil)
+
+	status, response := postScan(t, h, "/api/v1/scan", `{"ip": "8.8.8.8", "ports": [22]}`)
+	if status != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d: %v", status, http.StatusServiceUnavailable, response)
+	}
+	if len(scanner.Calls) != 0 {
+		t.Errorf("scanned %v after the engine stopped", scanner.Calls)
+	}
+}
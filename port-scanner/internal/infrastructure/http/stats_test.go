@@ -0,0 +1,57 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"port-scanner/internal/domain"
+)
+
+func TestAPIAndQueuedScansShareStats(t *testing.T) {
+	h, engine, _ := newSlotHandler(4)
+	config := domain.NewDefaultScanConfig()
+
+	// API scans, queued scans and stats reads all at once
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(3)
+		go func(i int) {
+			defer wg.Done()
+			body := fmt.Sprintf(`{"ip": "10.0.1.%d", "ports": [80]}`, i+1)
+			if recorder := serve(h, http.MethodPost, "/api/v1/scan", body); recorder.Code != http.StatusOK {
+				t.Errorf("API scan %d: status = %d, want %d", i, recorder.Code, http.StatusOK)
+			}
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := engine.ScanIP(fmt.Sprintf("10.0.2.%d", i+1), config, "batch-1", "worker-1"); err != nil {
+				t.Errorf("queued scan %d: %v", i, err)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			serve(h, http.MethodGet, "/api/v1/stats", "")
+		}()
+	}
+	wg.Wait()
+
+	if stats := engine.GetScanStats(); stats.TotalScanned != 16 {
+		t.Errorf("TotalScanned = %d, want the 8 API and 8 queued scans", stats.TotalScanned)
+	}
+}
+
+func TestGetScanStatsReturnsSnapshot(t *testing.T) {
+	_, engine, _ := newSlotHandler(1)
+
+	stats := engine.GetScanStats()
+	engine.RecordResult(&domain.ScanResult{IP: "10.0.0.1", Status: domain.ScanStatusCompleted})
+
+	if stats.TotalScanned != 0 {
+		t.Errorf("earlier snapshot changed to %d scans", stats.TotalScanned)
+	}
+	if got := engine.GetScanStats().TotalScanned; got != 1 {
+		t.Errorf("TotalScanned = %d, want 1", got)
+	}
+}
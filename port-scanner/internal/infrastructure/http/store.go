@@ -0,0 +1,41 @@
+package http
+
+import (
+	"time"
+
+	"port-scanner/internal/infrastructure/database"
+)
+
+// Store is the MongoDB access the handlers use: stored results, annotations, scan templates,
+// target lists and maintenance. *database.MongoDBManager implements it.
+type Store interface {
+	GetScanStats() (map[string]interface{}, error)
+	GetScanResult(ip string, projection *database.Projection) (*database.ScanResultDocument, error)
+	GetScanResultsByBatch(batchID string, projection *database.Projection, limit int) ([]*database.ScanResultDocument, error)
+	CountByBatch(batchID string) (int64, error)
+	GetBatchClaim(batchID string) (*database.BatchClaimDocument, error)
+
+	AddAnnotation(ip, author, note string) (*database.AnnotationDocument, error)
+	GetAnnotations(ip string) ([]database.AnnotationDocument, error)
+
+	CreateTemplate(template *database.ScanTemplateDocument) error
+	ListTemplates() ([]database.ScanTemplateDocument, error)
+	GetTemplate(name string) (*database.ScanTemplateDocument, error)
+	UpdateTemplate(template *database.ScanTemplateDocument) error
+	DeleteTemplate(name string) error
+
+	CreateTargetList(list *database.TargetListDocument) error
+	ListTargetLists() ([]database.TargetListDocument, error)
+	GetTargets(listName string) (*database.TargetListDocument, error)
+	UpdateTargetList(list *database.TargetListDocument) error
+	DeleteTargetList(name string) error
+
+	MigrateScanResults() (int, error)
+	ListIndexes() ([]database.IndexInfo, error)
+	RebuildIndexes() ([]string, error)
+	DeleteByBatch(batchID string) (int64, error)
+	DeleteOlderThan(age time.Duration) (int64, error)
+}
+
+// Ensure MongoDBManager implements Store interface
+var _ Store = (*database.MongoDBManager)(nil)
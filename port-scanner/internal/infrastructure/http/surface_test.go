@@ -0,0 +1,66 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"port-scanner/internal/infrastructure/database"
+	"port-scanner/internal/testutil"
+)
+
+// surfaceStore is a Store holding the stored result of one multi-service host
+type surfaceStore struct {
+	Store
+	doc *database.ScanResultDocument
+}
+
+func (s *surfaceStore) GetScanResult(ip string, projection *database.Projection) (*database.ScanResultDocument, error) {
+	if s.doc == nil || ip != s.doc.IP {
+		return nil, errors.New("scan result not found")
+	}
+	return s.doc, nil
+}
+
+func TestGetAttackSurfaceSummarizesStoredResult(t *testing.T) {
+	store := &surfaceStore{doc: &database.ScanResultDocument{
+		IP:     "192.0.2.10",
+		Status: "completed",
+		Ports: []database.PortDocument{
+			{Number: 22, Status: "open", Service: "ssh", Version: "OpenSSH 9.6"},
+			{Number: 23, Status: "open", Service: "telnet"},
+			{Number: 80, Status: "open", Service: "http"},
+			{Number: 6379, Status: "open", Service: "redis"},
+			{Number: 443, Status: "closed", Service: "https"},
+		},
+	}}
+	h := NewHandler(testutil.NewFakeScanEngine(nil), testutil.NewFakeScanner(), store)
+
+	status, response := requestWithKey(t, h, http.MethodGet, "/api/v1/db/result/192.0.2.10/surface", "")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %v", status, http.StatusOK, response)
+	}
+
+	// ssh 15 + telnet 25 + http 5 + redis 20
+	if response["exposure_score"] != float64(65) || response["open_ports"] != float64(4) {
+		t.Errorf("exposure_score, open_ports = %v, %v, want 65, 4", response["exposure_score"], response["open_ports"])
+	}
+	categories, _ := response["categories"].(map[string]interface{})
+	for category, port := range map[string]float64{"remote-access": 22, "legacy": 23, "web": 80, "database": 6379} {
+		ports, _ := categories[category].([]interface{})
+		if len(ports) != 1 || ports[0].(map[string]interface{})["port"] != port {
+			t.Errorf("category %s = %v, want port %v", category, categories[category], port)
+		}
+	}
+	if findings, _ := response["findings"].([]interface{}); len(findings) != 3 {
+		t.Errorf("findings = %v, want ssh, telnet and redis", response["findings"])
+	}
+}
+
+func TestGetAttackSurfaceUnknownIP(t *testing.T) {
+	h := NewHandler(testutil.NewFakeScanEngine(nil), testutil.NewFakeScanner(), &surfaceStore{})
+
+	if status, _ := requestWithKey(t, h, http.MethodGet, "/api/v1/db/result/192.0.2.99/surface", ""); status != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", status, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,182 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"port-scanner/internal/infrastructure/database"
+)
+
+// targetStore keeps target lists in memory
+type targetStore struct {
+	Store
+	lists map[string]database.TargetListDocument
+}
+
+func newTargetStore(lists ...database.TargetListDocument) *targetStore {
+	store := &targetStore{lists: make(map[string]database.TargetListDocument)}
+	for _, list := range lists {
+		store.lists[list.Name] = list
+	}
+	return store
+}
+
+func (s *targetStore) CreateTargetList(list *database.TargetListDocument) error {
+	if _, ok := s.lists[list.Name]; ok {
+		return fmt.Errorf("%w: %s", database.ErrTargetListExists, list.Name)
+	}
+	s.lists[list.Name] = *list
+	return nil
+}
+
+func (s *targetStore) GetTargets(listName string) (*database.TargetListDocument, error) {
+	list, ok := s.lists[listName]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", database.ErrTargetListNotFound, listName)
+	}
+	return &list, nil
+}
+
+func (s *targetStore) UpdateTargetList(list *database.TargetListDocument) error {
+	if _, ok := s.lists[list.Name]; !ok {
+		return fmt.Errorf("%w: %s", database.ErrTargetListNotFound, list.Name)
+	}
+	s.lists[list.Name] = *list
+	return nil
+}
+
+func (s *targetStore) DeleteTargetList(name string) error {
+	if _, ok := s.lists[name]; !ok {
+		return fmt.Errorf("%w: %s", database.ErrTargetListNotFound, name)
+	}
+	delete(s.lists, name)
+	return nil
+}
+
+// recordingEnqueuer records the scans queued through it
+type recordingEnqueuer struct {
+	batchID string
+	ips     []string
+	ports   []int
+	err     error
+}
+
+func (e *recordingEnqueuer) EnqueueScan(batchID string, ips []string, ports []int) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	e.batchID, e.ips, e.ports = batchID, ips, ports
+	return 1, nil
+}
+
+// newTargetListHandler returns a handler over a store seeded with the "dmz" list
+func newTargetListHandler() (*Handler, *targetStore, *recordingEnqueuer) {
+	store := newTargetStore(database.TargetListDocument{
+		Name:    "dmz",
+		Targets: []string{"198.51.100.10", "203.0.113.0/30", "198.51.100.10"},
+		Ports:   []int{22, 443},
+	})
+	h, _ := newTestHandler()
+	h.dbManager = store
+	enqueuer := &recordingEnqueuer{}
+	h.SetScanEnqueuer(enqueuer)
+	return h, store, enqueuer
+}
+
+func TestScanTargetListEnqueuesListIPs(t *testing.T) {
+	h, _, enqueuer := newTargetListHandler()
+
+	status, response := postScan(t, h, "/api/v1/scan/list/dmz", `{"batch_id": "dmz-weekly"}`)
+	if status != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d: %v", status, http.StatusAccepted, response)
+	}
+
+	want := []string{"198.51.100.10", "203.0.113.0", "203.0.113.1", "203.0.113.2", "203.0.113.3"}
+	if !reflect.DeepEqual(enqueuer.ips, want) {
+		t.Errorf("enqueued %v, want %v", enqueuer.ips, want)
+	}
+	if enqueuer.batchID != "dmz-weekly" || !reflect.DeepEqual(enqueuer.ports, []int{22, 443}) {
+		t.Errorf("enqueued batch %q on ports %v, want dmz-weekly on the list's [22 443]", enqueuer.batchID, enqueuer.ports)
+	}
+	if response["total_ips"] != float64(5) || response["list"] != "dmz" {
+		t.Errorf("response = %v, want the list's 5 IPs", response)
+	}
+}
+
+func TestScanTargetListWithoutBody(t *testing.T) {
+	h, _, enqueuer := newTargetListHandler()
+
+	recorder := serve(h, http.MethodPost, "/api/v1/scan/list/dmz", "")
+	if recorder.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusAccepted, recorder.Body.String())
+	}
+	if len(enqueuer.ips) != 5 || enqueuer.batchID == "" {
+		t.Errorf("enqueued batch %q with %d IPs, want a generated batch ID and 5 IPs", enqueuer.batchID, len(enqueuer.ips))
+	}
+
+	// Ports in the request override the list's
+	if status, _ := postScan(t, h, "/api/v1/scan/list/dmz", `{"ports": [3389]}`); status != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d", status, http.StatusAccepted)
+	}
+	if !reflect.DeepEqual(enqueuer.ports, []int{3389}) {
+		t.Errorf("enqueued ports %v, want the requested [3389]", enqueuer.ports)
+	}
+}
+
+func TestScanTargetListErrors(t *testing.T) {
+	h, _, enqueuer := newTargetListHandler()
+	if status, _ := postScan(t, h, "/api/v1/scan/list/unknown", `{}`); status != http.StatusNotFound {
+		t.Errorf("unknown list: status = %d, want %d", status, http.StatusNotFound)
+	}
+	if status, _ := postScan(t, h, "/api/v1/scan/list/dmz", `{"ports": [0]}`); status != http.StatusBadRequest {
+		t.Errorf("invalid port: status = %d, want %d", status, http.StatusBadRequest)
+	}
+	if enqueuer.ips != nil {
+		t.Errorf("enqueued %v on failed requests", enqueuer.ips)
+	}
+
+	enqueuer.err = errors.New("broker unavailable")
+	if status, _ := postScan(t, h, "/api/v1/scan/list/dmz", `{}`); status != http.StatusInternalServerError {
+		t.Errorf("failed enqueue: status = %d, want %d", status, http.StatusInternalServerError)
+	}
+
+	h.SetScanEnqueuer(nil)
+	if status, _ := postScan(t, h, "/api/v1/scan/list/dmz", `{}`); status != http.StatusServiceUnavailable {
+		t.Errorf("without a queue: status = %d, want %d", status, http.StatusServiceUnavailable)
+	}
+}
+
+func TestTargetListCRUD(t *testing.T) {
+	h, store, _ := newTargetListHandler()
+
+	if status, response := postScan(t, h, "/api/v1/targets", `{"name": "lab", "targets": ["192.0.2.0/31"]}`); status != http.StatusCreated {
+		t.Fatalf("create: status = %d, want %d: %v", status, http.StatusCreated, response)
+	}
+	if status, _ := postScan(t, h, "/api/v1/targets", `{"name": "lab", "targets": ["192.0.2.1"]}`); status != http.StatusConflict {
+		t.Errorf("duplicate create: status = %d, want %d", status, http.StatusConflict)
+	}
+	if status, _ := postScan(t, h, "/api/v1/targets", `{"name": "bad", "targets": ["not-an-ip"]}`); status != http.StatusBadRequest {
+		t.Errorf("invalid targets: status = %d, want %d", status, http.StatusBadRequest)
+	}
+
+	if recorder := serve(h, http.MethodGet, "/api/v1/targets/lab", ""); recorder.Code != http.StatusOK {
+		t.Errorf("get: status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+
+	if recorder := serve(h, http.MethodPut, "/api/v1/targets/lab", `{"targets": ["192.0.2.9"]}`); recorder.Code != http.StatusOK {
+		t.Errorf("update: status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body.String())
+	}
+	if got := store.lists["lab"].Targets; !reflect.DeepEqual(got, []string{"192.0.2.9"}) {
+		t.Errorf("targets after update = %v, want [192.0.2.9]", got)
+	}
+
+	if recorder := serve(h, http.MethodDelete, "/api/v1/targets/lab", ""); recorder.Code != http.StatusOK {
+		t.Errorf("delete: status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+	if recorder := serve(h, http.MethodGet, "/api/v1/targets/lab", ""); recorder.Code != http.StatusNotFound {
+		t.Errorf("get after delete: status = %d, want %d", recorder.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,207 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"port-scanner/internal/infrastructure/database"
+	"port-scanner/pkg/log"
+
+	"go.uber.org/zap"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ScanTargetListRequest represents the optional options of a target list scan
+type ScanTargetListRequest struct {
+	BatchID string `json:"batch_id,omitempty"` // Defaults to one derived from the list name
+	Ports   []int  `json:"ports,omitempty"`    // Overrides the list's ports
+}
+
+// targetListErrorStatus maps a target list error to an HTTP status code
+func targetListErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, database.ErrTargetListNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, database.ErrTargetListExists):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ScanTargetList queues every address of a stored target list on the IP queue, to be scanned
+// by the workers like a generated batch
+func (h *Handler) ScanTargetList(c *gin.Context) {
+	if h.dbManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "MongoDB not available"})
+		return
+	}
+	if h.enqueuer == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "scan queue not available"})
+		return
+	}
+
+	// The body is optional
+	var req ScanTargetListRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "fields": bindingErrors(err)})
+		return
+	}
+
+	name := c.Param("name")
+	list, err := h.dbManager.GetTargets(name)
+	if err != nil {
+		c.JSON(targetListErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ports := list.Ports
+	if len(req.Ports) > 0 {
+		ports = req.Ports
+	}
+	for _, port := range ports {
+		if port < 1 || port > 65535 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid port: %d", port)})
+			return
+		}
+	}
+
+	ips, err := list.IPs()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	batchID := req.BatchID
+	if batchID == "" {
+		batchID = fmt.Sprintf("list-%s-%d", name, time.Now().UnixNano())
+	}
+
+	messages, err := h.enqueuer.EnqueueScan(batchID, ips, ports)
+	if err != nil {
+		log.L().Error("Failed to enqueue target list", zap.String("event", "target_list_enqueue_failed"),
+			zap.String("list", name), zap.String("batch_id", batchID), zap.Int("messages", messages), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "batch_id": batchID, "messages": messages})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"list":      name,
+		"batch_id":  batchID,
+		"total_ips": len(ips),
+		"messages":  messages,
+	})
+}
+
+// CreateTargetList saves a named target list
+func (h *Handler) CreateTargetList(c *gin.Context) {
+	if h.dbManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "MongoDB not available"})
+		return
+	}
+
+	var list database.TargetListDocument
+	if err := c.ShouldBindJSON(&list); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "fields": bindingErrors(err)})
+		return
+	}
+	if err := list.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.dbManager.CreateTargetList(&list); err != nil {
+		log.L().Error("Failed to create target list", zap.String("event", "target_list_create_failed"), zap.String("list", list.Name), zap.Error(err))
+		c.JSON(targetListErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, list)
+}
+
+// ListTargetLists returns all target lists
+func (h *Handler) ListTargetLists(c *gin.Context) {
+	if h.dbManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "MongoDB not available"})
+		return
+	}
+
+	lists, err := h.dbManager.ListTargetLists()
+	if err != nil {
+		log.L().Error("Failed to list target lists", zap.String("event", "target_list_list_failed"), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count": len(lists),
+		"lists": lists,
+	})
+}
+
+// GetTargetList returns a target list by name
+func (h *Handler) GetTargetList(c *gin.Context) {
+	if h.dbManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "MongoDB not available"})
+		return
+	}
+
+	list, err := h.dbManager.GetTargets(c.Param("name"))
+	if err != nil {
+		c.JSON(targetListErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// UpdateTargetList replaces the targets of an existing target list
+func (h *Handler) UpdateTargetList(c *gin.Context) {
+	if h.dbManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "MongoDB not available"})
+		return
+	}
+
+	var list database.TargetListDocument
+	if err := c.ShouldBindJSON(&list); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "fields": bindingErrors(err)})
+		return
+	}
+	list.Name = c.Param("name")
+	if err := list.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.dbManager.UpdateTargetList(&list); err != nil {
+		log.L().Error("Failed to update target list", zap.String("event", "target_list_update_failed"), zap.String("list", list.Name), zap.Error(err))
+		c.JSON(targetListErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// DeleteTargetList removes a target list
+func (h *Handler) DeleteTargetList(c *gin.Context) {
+	if h.dbManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "MongoDB not available"})
+		return
+	}
+
+	name := c.Param("name")
+	if err := h.dbManager.DeleteTargetList(name); err != nil {
+		log.L().Error("Failed to delete target list", zap.String("event", "target_list_delete_failed"), zap.String("list", name), zap.Error(err))
+		c.JSON(targetListErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"name":    name,
+		"deleted": true,
+	})
+}
@@ -0,0 +1,88 @@
+package http
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestScanTargetsScansExactlyTheGivenPairs(t *testing.T) {
+	h, scanner := newTestHandler()
+	scanner.AddOpenPorts("8.8.8.8", 53)
+	scanner.AddOpenPorts("1.1.1.1", 443)
+
+	status, response := postScan(t, h, "/api/v1/scan/targets",
+		`{"targets": ["8.8.8.8:53", "1.1.1.1:443", "8.8.8.8:853", "8.8.8.8:53"], "batch_id": "recon-1"}`)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %v", status, http.StatusOK, response)
+	}
+	if response["total_ips"] != float64(2) || response["total_targets"] != float64(4) {
+		t.Errorf("total_ips/total_targets = %v/%v, want 2/4", response["total_ips"], response["total_targets"])
+	}
+
+	scanned := make(map[string][]int)
+	for i, ip := range scanner.Calls {
+		config := scanner.Configs[i]
+		scanned[ip] = config.PortRange
+		if !config.EnableBanner || config.EnablePing {
+			t.Errorf("%s scanned with banner %v and ping %v, want banners without ping", ip, config.EnableBanner, config.EnablePing)
+		}
+	}
+	want := map[string][]int{"8.8.8.8": {53, 853}, "1.1.1.1": {443}}
+	if !reflect.DeepEqual(scanned, want) {
+		t.Errorf("scanned %v, want %v", scanned, want)
+	}
+
+	results, _ := response["results"].([]interface{})
+	ips := make([]string, 0, len(results))
+	for _, result := range results {
+		entry := result.(map[string]interface{})
+		ips = append(ips, entry["ip"].(string))
+		if entry["ports"] == nil {
+			t.Errorf("result %v has no ports", entry)
+		}
+	}
+	sort.Strings(ips)
+	if !reflect.DeepEqual(ips, []string{"1.1.1.1", "8.8.8.8"}) {
+		t.Errorf("results for %v, want one per IP", ips)
+	}
+}
+
+func TestScanTargetsRejectsInvalidEntries(t *testing.T) {
+	tests := []struct {
+		body, contains string
+	}{
+		{`{"targets": ["8.8.8.8:70000"]}`, `"8.8.8.8:70000"`},
+		{`{"targets": ["8.8.8.8:0"]}`, `"8.8.8.8:0"`},
+		{`{"targets": ["8.8.8.8:53", "8.8.8.8"]}`, `target 1 "8.8.8.8"`},
+		{`{"targets": ["example.com:80"]}`, "not an IP address"},
+		{`{"targets": ["2001:db8::1:443"]}`, `"2001:db8::1:443"`},
+		{`{"targets": []}`, "no targets"},
+	}
+	for _, tt := range tests {
+		h, scanner := newTestHandler()
+		status, response := postScan(t, h, "/api/v1/scan/targets", tt.body)
+		if status != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", tt.body, status, http.StatusBadRequest)
+		}
+		if message, _ := response["error"].(string); !strings.Contains(message, tt.contains) {
+			t.Errorf("%s: error = %q, want it to mention %s", tt.body, message, tt.contains)
+		}
+		if len(scanner.Calls) != 0 {
+			t.Errorf("%s: scanned %v despite an invalid target", tt.body, scanner.Calls)
+		}
+	}
+}
+
+func TestScanTargetsAcceptsBracketedIPv6(t *testing.T) {
+	h, scanner := newTestHandler()
+
+	if status, response := postScan(t, h, "/api/v1/scan/targets", `{"targets": ["[2001:4860:4860::8888]:443"]}`); status != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %v", status, http.StatusOK, response)
+	}
+	if len(scanner.Calls) != 1 || scanner.Calls[0] != "2001:4860:4860::8888" || !reflect.DeepEqual(scanner.Configs[0].PortRange, []int{443}) {
+		t.Errorf("scanned %v with %v, want 2001:4860:4860::8888 on 443", scanner.Calls, scanner.Configs)
+	}
+}
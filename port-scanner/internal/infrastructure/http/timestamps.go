@@ -0,0 +1,32 @@
+package http
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// timeFormatUnix selects epoch-second timestamps, as responses used before RFC3339
+const timeFormatUnix = "unix"
+
+// timeFormatter renders response timestamps as RFC3339 in UTC with sub-second precision, or as
+// Unix seconds for clients that ask for them with ?time_format=unix
+type timeFormatter struct {
+	unix bool
+}
+
+// requestTimeFormat returns the timestamp format a request asked for
+func requestTimeFormat(c *gin.Context) timeFormatter {
+	return timeFormatter{unix: c.Query("time_format") == timeFormatUnix}
+}
+
+// format renders a timestamp; an unset time is null in RFC3339 responses
+func (f timeFormatter) format(t time.Time) interface{} {
+	if f.unix {
+		return t.Unix()
+	}
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,86 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// assertRFC3339 fails unless a response field is an RFC3339 timestamp in UTC
+func assertRFC3339(t *testing.T, field string, value interface{}) {
+	t.Helper()
+	text, ok := value.(string)
+	if !ok {
+		t.Errorf("%s = %v (%T), want an RFC3339 string", field, value, value)
+		return
+	}
+	parsed, err := time.Parse(time.RFC3339Nano, text)
+	if err != nil {
+		t.Errorf("%s = %q is not RFC3339: %v", field, text, err)
+		return
+	}
+	if parsed.Location() != time.UTC {
+		t.Errorf("%s = %q, want UTC", field, text)
+	}
+}
+
+// assertUnix fails unless a response field is an epoch-second timestamp
+func assertUnix(t *testing.T, field string, value interface{}) {
+	t.Helper()
+	if _, ok := value.(float64); !ok {
+		t.Errorf("%s = %v (%T), want Unix seconds", field, value, value)
+	}
+}
+
+func TestScanIPReturnsRFC3339Timestamps(t *testing.T) {
+	h, scanner := newTestHandler()
+	scanner.AddOpenPorts("8.8.8.8", 443)
+
+	_, response := postScan(t, h, "/api/v1/scan", `{"ip": "8.8.8.8", "ports": [443]}`)
+	assertRFC3339(t, "scan_start", response["scan_start"])
+	assertRFC3339(t, "scan_end", response["scan_end"])
+	port := response["ports"].([]interface{})[0].(map[string]interface{})
+	assertRFC3339(t, "ports[0].scan_time", port["scan_time"])
+
+	_, response = postScan(t, h, "/api/v1/scan?force=true&time_format=unix", `{"ip": "8.8.8.8", "ports": [443]}`)
+	assertUnix(t, "scan_start", response["scan_start"])
+	assertUnix(t, "scan_end", response["scan_end"])
+	port = response["ports"].([]interface{})[0].(map[string]interface{})
+	assertUnix(t, "ports[0].scan_time", port["scan_time"])
+}
+
+func TestGetScanStatusReturnsRFC3339Timestamps(t *testing.T) {
+	h := newPortsHandler(nil)
+
+	_, response := requestWithKey(t, h, http.MethodGet, "/api/v1/status/10.0.0.1", "")
+	assertRFC3339(t, "scan_start", response["scan_start"])
+	assertRFC3339(t, "scan_end", response["scan_end"])
+
+	_, response = requestWithKey(t, h, http.MethodGet, "/api/v1/status/10.0.0.1?time_format=unix", "")
+	assertUnix(t, "scan_start", response["scan_start"])
+	assertUnix(t, "scan_end", response["scan_end"])
+}
+
+func TestGetOpenPortsReturnsRFC3339Timestamps(t *testing.T) {
+	h := newPortsHandler(nil)
+
+	_, response := requestWithKey(t, h, http.MethodGet, "/api/v1/ports/10.0.0.1", "")
+	assertRFC3339(t, "scan_time", openPort(t, response)["scan_time"])
+
+	_, response = requestWithKey(t, h, http.MethodGet, "/api/v1/ports/10.0.0.1?time_format=unix", "")
+	assertUnix(t, "scan_time", openPort(t, response)["scan_time"])
+}
+
+func TestTimeFormatterRendersUnsetTimeAsNull(t *testing.T) {
+	if got := (timeFormatter{}).format(time.Time{}); got != nil {
+		t.Errorf("format(zero) = %v, want nil", got)
+	}
+
+	local := time.Date(2024, 5, 1, 12, 30, 0, 250_000_000, time.FixedZone("CEST", 2*60*60))
+	if got := (timeFormatter{}).format(local); got != "2024-05-01T10:30:00.25Z" {
+		t.Errorf("format = %v, want 2024-05-01T10:30:00.25Z", got)
+	}
+	if got := (timeFormatter{unix: true}).format(local); got != local.Unix() {
+		t.Errorf("unix format = %v, want %d", got, local.Unix())
+	}
+}
@@ -0,0 +1,184 @@
+package ping
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// icmpFamily holds the socket networks and message types of one ICMP version
+type icmpFamily struct {
+	rawNetwork string
+	udpNetwork string
+	listenAddr string
+	protocol   int // IANA protocol number used to parse replies
+	echo       icmp.Type
+	echoReply  icmp.Type
+}
+
+var (
+	icmpV4 = icmpFamily{"ip4:icmp", "udp4", "0.0.0.0", 1, ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply}
+	icmpV6 = icmpFamily{"ip6:ipv6-icmp", "udp6", "::", 58, ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply}
+)
+
+// errICMPUnavailable is returned when the process may not open ICMP sockets
+var errICMPUnavailable = errors.New("icmp sockets not permitted")
+
+// icmpSeq provides unique echo sequence numbers across concurrent pings
+var icmpSeq uint32
+
+// nativePing sends up to count ICMP echo requests and reports the host as up once
+// threshold replies arrive. It stops early when the outcome can no longer change.
+func (s *SafePingService) nativePing(ip string) (*PingResult, error) {
+	start := time.Now()
+	received := 0
+	var totalRTT time.Duration
+	var lastErr error
+
+	for sent := 1; sent <= s.count; sent++ {
+		result, err := s.nativeEcho(ip)
+		if err != nil {
+			return nil, err
+		}
+		if result.IsUp {
+			received++
+			totalRTT += result.RTT
+		} else if result.Error != nil {
+			lastErr = result.Error
+		}
+
+		if received >= s.threshold {
+			return &PingResult{IsUp: true, Duration: time.Since(start), RTT: totalRTT / time.Duration(received)}, nil
+		}
+		if received+s.count-sent < s.threshold {
+			break
+		}
+	}
+
+	// Report send/receive failures only when no reply arrived at all
+	if received > 0 {
+		lastErr = nil
+	}
+	return &PingResult{IsUp: false, Duration: time.Since(start), Error: lastErr}, nil
+}
+
+// nativeEcho sends a single ICMP or ICMPv6 echo request and waits for the matching reply.
+// It prefers a raw socket and falls back to an unprivileged datagram socket;
+// if neither can be opened errICMPUnavailable is returned so the caller can
+// fall back to the ping binary.
+func (s *SafePingService) nativeEcho(ip string) (*PingResult, error) {
+	target := net.ParseIP(ip)
+	if target == nil {
+		return nil, fmt.Errorf("invalid IP address format: %s", ip)
+	}
+	family := icmpV6
+	if target4 := target.To4(); target4 != nil {
+		target, family = target4, icmpV4
+	}
+
+	conn, privileged, err := listenICMP(family)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errICMPUnavailable, err)
+	}
+	defer conn.Close()
+
+	var dst net.Addr = &net.IPAddr{IP: target}
+	if !privileged {
+		dst = &net.UDPAddr{IP: target}
+	}
+
+	id := os.Getpid() & 0xffff
+	seq := int(atomic.AddUint32(&icmpSeq, 1) & 0xffff)
+
+	request := icmp.Message{
+		Type: family.echo,
+		Code: 0,
+		Body: &icmp.Echo{ID: id, Seq: seq, Data: []byte("port-scanner")},
+	}
+	payload, err := request.Marshal(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build icmp echo: %w", err)
+	}
+
+	start := time.Now()
+	if err := conn.SetDeadline(start.Add(s.timeout)); err != nil {
+		return nil, fmt.Errorf("failed to set icmp deadline: %w", err)
+	}
+	if _, err := conn.WriteTo(payload, dst); err != nil {
+		return &PingResult{
+			IsUp:     false,
+			Duration: time.Since(start),
+			Error:    fmt.Errorf("icmp send failed: %w", err),
+		}, nil
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				// No reply within the timeout: host is down, not an error
+				return &PingResult{IsUp: false, Duration: time.Since(start)}, nil
+			}
+			return &PingResult{
+				IsUp:     false,
+				Duration: time.Since(start),
+				Error:    fmt.Errorf("icmp receive failed: %w", err),
+			}, nil
+		}
+
+		if !sameHost(peer, target) {
+			continue
+		}
+
+		reply, err := icmp.ParseMessage(family.protocol, buf[:n])
+		if err != nil || reply.Type != family.echoReply {
+			continue
+		}
+
+		echo, ok := reply.Body.(*icmp.Echo)
+		if !ok || echo.Seq != seq {
+			continue
+		}
+		// Unprivileged sockets have their ID rewritten by the kernel
+		if privileged && echo.ID != id {
+			continue
+		}
+
+		rtt := time.Since(start)
+		return &PingResult{IsUp: true, Duration: rtt, RTT: rtt}, nil
+	}
+}
+
+// listenICMP opens a raw ICMP socket of a family, falling back to an unprivileged one
+func listenICMP(family icmpFamily) (*icmp.PacketConn, bool, error) {
+	if conn, err := icmp.ListenPacket(family.rawNetwork, family.listenAddr); err == nil {
+		return conn, true, nil
+	}
+
+	conn, err := icmp.ListenPacket(family.udpNetwork, family.listenAddr)
+	if err != nil {
+		return nil, false, err
+	}
+	return conn, false, nil
+}
+
+// sameHost reports whether a packet source address matches the ping target
+func sameHost(peer net.Addr, target net.IP) bool {
+	switch addr := peer.(type) {
+	case *net.IPAddr:
+		return addr.IP.Equal(target)
+	case *net.UDPAddr:
+		return addr.IP.Equal(target)
+	default:
+		return false
+	}
+}
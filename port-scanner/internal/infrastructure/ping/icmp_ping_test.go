@@ -0,0 +1,87 @@
+package ping
+
+import (
+	"testing"
+	"time"
+)
+
+// requireICMP skips the test unless the process may open an ICMP socket of the family
+func requireICMP(t *testing.T, family icmpFamily) {
+	t.Helper()
+	conn, _, err := listenICMP(family)
+	if err != nil {
+		t.Skipf("ICMP sockets not permitted: %v", err)
+	}
+	conn.Close()
+}
+
+func TestNativePingReportsLoopbackUp(t *testing.T) {
+	requireICMP(t, icmpV4)
+
+	s := NewSafePingService(time.Second)
+	s.SetNativeICMP(true)
+	result, err := s.nativePing("127.0.0.1")
+	if err != nil {
+		t.Fatalf("nativePing returned error: %v", err)
+	}
+	if !result.IsUp {
+		t.Fatalf("loopback reported down: %v", result.Error)
+	}
+	if result.RTT <= 0 {
+		t.Errorf("RTT = %v, want a measured round trip", result.RTT)
+	}
+
+	result, err = s.PingHost("127.0.0.1")
+	if err != nil || !result.IsUp {
+		t.Errorf("PingHost(loopback) = %+v, %v; want up", result, err)
+	}
+}
+
+func TestNativePingReportsLoopbackUpOverIPv6(t *testing.T) {
+	requireICMP(t, icmpV6)
+
+	s := NewSafePingService(time.Second)
+	result, err := s.nativePing("::1")
+	if err != nil {
+		t.Fatalf("nativePing returned error: %v", err)
+	}
+	if !result.IsUp {
+		t.Skipf("no IPv6 loopback reply: %v", result.Error)
+	}
+}
+
+func TestNativePingReportsSilentHostDown(t *testing.T) {
+	requireICMP(t, icmpV4)
+
+	// TEST-NET-2 addresses are never assigned, so the echo goes unanswered or can't be sent
+	s := NewSafePingService(200 * time.Millisecond)
+	s.SetPacketCount(2, 1)
+	result, err := s.nativePing("198.51.100.1")
+	if err != nil {
+		t.Fatalf("nativePing returned error: %v", err)
+	}
+	if result.IsUp {
+		t.Error("unassigned address reported up")
+	}
+}
+
+func TestNativePingNeedsThresholdReplies(t *testing.T) {
+	requireICMP(t, icmpV4)
+
+	s := NewSafePingService(time.Second)
+	s.SetPacketCount(3, 3)
+	result, err := s.nativePing("127.0.0.1")
+	if err != nil {
+		t.Fatalf("nativePing returned error: %v", err)
+	}
+	if !result.IsUp {
+		t.Errorf("loopback answering every echo reported down: %v", result.Error)
+	}
+}
+
+func TestNativeEchoRejectsInvalidAddresses(t *testing.T) {
+	s := NewSafePingService(time.Second)
+	if _, err := s.nativeEcho("not-an-ip"); err == nil {
+		t.Error("nativeEcho accepted an invalid address")
+	}
+}
@@ -2,31 +2,73 @@ package ping
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os/exec"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
+
+	"orwell/pkg/netutil"
 )
 
 // SafePingService provides a sandboxed ping implementation
 type SafePingService struct {
-	timeout time.Duration
+	timeout    time.Duration
+	nativeICMP bool
+	count      int
+	threshold  int
+}
+
+// pingSummaryPatterns extract the transmitted and received packet counts from ping output
+var pingSummaryPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(\d+) packets transmitted, (\d+) (?:packets )?received`), // Linux, macOS, BSD
+	regexp.MustCompile(`Sent = (\d+), Received = (\d+)`),                         // Windows
+}
+
+// pingRTTPatterns extract the average round-trip time, in milliseconds, from ping output
+var pingRTTPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?:rtt|round-trip) min/avg/max/(?:mdev|stddev) = [\d.]+/([\d.]+)/`), // Linux, macOS, BSD
+	regexp.MustCompile(`Average = (\d+)ms`),                                                 // Windows
 }
 
 // PingResult represents the result of a ping operation
 type PingResult struct {
 	IsUp     bool
 	Duration time.Duration
+	RTT      time.Duration // Average round-trip time of the replies; zero when none arrived or it is unknown
 	Error    error
 }
 
 // NewSafePingService creates a new safe ping service
 func NewSafePingService(timeout time.Duration) *SafePingService {
 	return &SafePingService{
-		timeout: timeout,
+		timeout:   timeout,
+		count:     1,
+		threshold: 1,
+	}
+}
+
+// SetPacketCount sets how many echo requests are sent per host and how many
+// replies are needed to consider it up. The threshold is capped at the count.
+func (s *SafePingService) SetPacketCount(count, threshold int) {
+	if count < 1 {
+		count = 1
+	}
+	if threshold < 1 {
+		threshold = 1
 	}
+	if threshold > count {
+		threshold = count
+	}
+	s.count = count
+	s.threshold = threshold
+}
+
+// SetNativeICMP enables in-process ICMP echo instead of spawning the ping binary
+func (s *SafePingService) SetNativeICMP(enabled bool) {
+	s.nativeICMP = enabled
 }
 
 // PingHost performs a safe ping to check if the host is up
@@ -40,8 +82,19 @@ func (s *SafePingService) PingHost(ip string) (*PingResult, error) {
 		}, nil
 	}
 
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	// Prefer native ICMP; fall back to the ping binary when sockets aren't permitted
+	if s.nativeICMP {
+		result, err := s.nativePing(ip)
+		if err == nil {
+			return result, nil
+		}
+		if !errors.Is(err, errICMPUnavailable) {
+			return &PingResult{IsUp: false, Duration: 0, Error: err}, nil
+		}
+	}
+
+	// Create context with timeout; ping waits up to the timeout for each packet
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout*time.Duration(s.count)+time.Second)
 	defer cancel()
 
 	// Build ping command with proper arguments
@@ -49,47 +102,43 @@ func (s *SafePingService) PingHost(ip string) (*PingResult, error) {
 
 	// Execute command with proper error handling
 	start := time.Now()
-	err := cmd.Run()
+	output, err := cmd.Output()
 	duration := time.Since(start)
 
 	// Analyze the result
-	result := s.analyzePingResult(err, duration, ctx)
+	result := s.analyzePingResult(string(output), err, duration, ctx)
 
 	return result, nil
 }
 
 // isValidIP validates IP address format
 func (s *SafePingService) isValidIP(ip string) bool {
-	// Basic IP validation regex
-	ipRegex := regexp.MustCompile(`^(\d{1,3}\.){3}\d{1,3}$`)
-	if !ipRegex.MatchString(ip) {
-		return false
-	}
-
-	// Check each octet
-	parts := strings.Split(ip, ".")
-	for _, part := range parts {
-		if num, err := strconv.Atoi(part); err != nil || num < 0 || num > 255 {
-			return false
-		}
+	if _, err := netutil.ParseAndValidate(ip); err == nil {
+		return true
 	}
-
-	return true
+	_, err := netutil.ParseAndValidateIPv6(ip)
+	return err == nil
 }
 
 // buildPingCommand builds a safe ping command
 func (s *SafePingService) buildPingCommand(ctx context.Context, ip string) *exec.Cmd {
 	// Use different ping commands based on OS
-	var cmd *exec.Cmd
+	var args []string
 
 	// Try to detect OS and use appropriate ping command
 	if s.isWindows() {
-		cmd = exec.CommandContext(ctx, "ping", "-n", "1", "-w", fmt.Sprintf("%d", s.timeout.Milliseconds()), ip)
+		args = []string{"-n", strconv.Itoa(s.count), "-w", fmt.Sprintf("%d", s.timeout.Milliseconds())}
 	} else {
 		// Unix-like systems (Linux, macOS, etc.)
-		cmd = exec.CommandContext(ctx, "ping", "-c", "1", "-W", fmt.Sprintf("%d", int(s.timeout.Seconds())), ip)
+		args = []string{"-c", strconv.Itoa(s.count), "-W", fmt.Sprintf("%d", int(s.timeout.Seconds()))}
 	}
 
+	// Both ping flavours need to be told to use ICMPv6 for IPv6 targets
+	if _, err := netutil.ParseAndValidateIPv6(ip); err == nil {
+		args = append([]string{"-6"}, args...)
+	}
+	cmd := exec.CommandContext(ctx, "ping", append(args, ip)...)
+
 	// Set up command environment
 	cmd.Stderr = nil // Suppress stderr to avoid noise
 
@@ -102,8 +151,45 @@ func (s *SafePingService) isWindows() bool {
 	return false // Assuming Linux/Unix for now
 }
 
+// parsePingSummary extracts the transmitted and received packet counts from ping output
+func parsePingSummary(output string) (int, int, bool) {
+	for _, pattern := range pingSummaryPatterns {
+		if match := pattern.FindStringSubmatch(output); match != nil {
+			transmitted, _ := strconv.Atoi(match[1])
+			received, _ := strconv.Atoi(match[2])
+			return transmitted, received, true
+		}
+	}
+	return 0, 0, false
+}
+
+// parsePingRTT extracts the average round-trip time from ping output
+func parsePingRTT(output string) (time.Duration, bool) {
+	for _, pattern := range pingRTTPatterns {
+		if match := pattern.FindStringSubmatch(output); match != nil {
+			ms, err := strconv.ParseFloat(match[1], 64)
+			if err != nil {
+				return 0, false
+			}
+			return time.Duration(ms * float64(time.Millisecond)), true
+		}
+	}
+	return 0, false
+}
+
 // analyzePingResult analyzes the ping command result
-func (s *SafePingService) analyzePingResult(err error, duration time.Duration, ctx context.Context) *PingResult {
+func (s *SafePingService) analyzePingResult(output string, err error, duration time.Duration, ctx context.Context) *PingResult {
+	// The packet-loss summary decides up/down; ping exits non-zero when some or all packets are lost
+	if transmitted, received, ok := parsePingSummary(output); ok && transmitted > 0 {
+		rtt, _ := parsePingRTT(output)
+		return &PingResult{
+			IsUp:     received >= s.threshold,
+			Duration: duration,
+			RTT:      rtt,
+			Error:    nil,
+		}
+	}
+
 	if err != nil {
 		// Check for specific error types
 		if ctxErr := ctx.Err(); ctxErr != nil {
@@ -0,0 +1,147 @@
+package ping
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIsValidIPAcceptsBothFamilies(t *testing.T) {
+	s := NewSafePingService(time.Second)
+
+	tests := map[string]bool{
+		"8.8.8.8":              true,
+		"2001:4860:4860::8888": true,
+		"::1":                  true,
+		"":                     false,
+		"8.8.8":                false,
+		"example.com":          false,
+		"8.8.8.8; rm -rf /":    false,
+	}
+	for ip, valid := range tests {
+		if got := s.isValidIP(ip); got != valid {
+			t.Errorf("isValidIP(%q) = %v, want %v", ip, got, valid)
+		}
+	}
+}
+
+func TestBuildPingCommandSelectsFamily(t *testing.T) {
+	s := NewSafePingService(2 * time.Second)
+
+	v4 := s.buildPingCommand(context.Background(), "8.8.8.8").Args
+	if len(v4) < 2 || v4[1] == "-6" {
+		t.Errorf("IPv4 ping args = %v, want no -6 flag", v4)
+	}
+	if v4[len(v4)-1] != "8.8.8.8" {
+		t.Errorf("IPv4 ping args = %v, want target last", v4)
+	}
+
+	v6 := s.buildPingCommand(context.Background(), "2001:4860:4860::8888").Args
+	if len(v6) < 2 || v6[1] != "-6" {
+		t.Errorf("IPv6 ping args = %v, want -6 flag first", v6)
+	}
+	if v6[len(v6)-1] != "2001:4860:4860::8888" {
+		t.Errorf("IPv6 ping args = %v, want target last", v6)
+	}
+}
+
+// Sample outputs of a three-packet ping
+const (
+	linuxOneOfThree = `PING 192.0.2.10 (192.0.2.10) 56(84) bytes of data.
+64 bytes from 192.0.2.10: icmp_seq=2 ttl=64 time=0.412 ms
+
+--- 192.0.2.10 ping statistics ---
+3 packets transmitted, 1 received, 66.6667% packet loss, time 2031ms
+rtt min/avg/max/mdev = 0.412/0.412/0.412/0.000 ms
+`
+	linuxNoneOfThree = `PING 192.0.2.10 (192.0.2.10) 56(84) bytes of data.
+
+--- 192.0.2.10 ping statistics ---
+3 packets transmitted, 0 received, 100% packet loss, time 2047ms
+`
+	macOSTwoOfThree = `PING 192.0.2.10 (192.0.2.10): 56 data bytes
+64 bytes from 192.0.2.10: icmp_seq=0 ttl=64 time=1.101 ms
+64 bytes from 192.0.2.10: icmp_seq=2 ttl=64 time=1.305 ms
+
+--- 192.0.2.10 ping statistics ---
+3 packets transmitted, 2 packets received, 33.3% packet loss
+round-trip min/avg/max/stddev = 1.101/1.203/1.305/0.102 ms
+`
+	windowsThreeOfThree = `Pinging 192.0.2.10 with 32 bytes of data:
+Reply from 192.0.2.10: bytes=32 time=3ms TTL=64
+Reply from 192.0.2.10: bytes=32 time=2ms TTL=64
+Reply from 192.0.2.10: bytes=32 time=4ms TTL=64
+
+Ping statistics for 192.0.2.10:
+    Packets: Sent = 3, Received = 3, Lost = 0 (0% loss),
+Approximate round trip times in milli-seconds:
+    Minimum = 2ms, Maximum = 4ms, Average = 3ms
+`
+)
+
+func TestAnalyzePingResultHonorsThreshold(t *testing.T) {
+	// ping exits non-zero whenever packets were lost
+	lossErr := errors.New("exit status 1")
+
+	tests := []struct {
+		name      string
+		output    string
+		err       error
+		threshold int
+		up        bool
+		rtt       time.Duration
+	}{
+		{"one of three, threshold 1", linuxOneOfThree, lossErr, 1, true, 412 * time.Microsecond},
+		{"one of three, threshold 2", linuxOneOfThree, lossErr, 2, false, 412 * time.Microsecond},
+		{"none of three", linuxNoneOfThree, lossErr, 1, false, 0},
+		{"two of three, threshold 2", macOSTwoOfThree, lossErr, 2, true, 1203 * time.Microsecond},
+		{"two of three, threshold 3", macOSTwoOfThree, lossErr, 3, false, 1203 * time.Microsecond},
+		{"three of three, threshold 3", windowsThreeOfThree, nil, 3, true, 3 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSafePingService(time.Second)
+			s.SetPacketCount(3, tt.threshold)
+
+			result := s.analyzePingResult(tt.output, tt.err, time.Second, context.Background())
+			if result.Error != nil {
+				t.Fatalf("analyzePingResult returned error: %v", result.Error)
+			}
+			if result.IsUp != tt.up {
+				t.Errorf("IsUp = %v, want %v", result.IsUp, tt.up)
+			}
+			if result.RTT != tt.rtt {
+				t.Errorf("RTT = %v, want %v", result.RTT, tt.rtt)
+			}
+		})
+	}
+}
+
+func TestSetPacketCountBoundsThreshold(t *testing.T) {
+	s := NewSafePingService(time.Second)
+	s.SetPacketCount(3, 5)
+	if s.count != 3 || s.threshold != 3 {
+		t.Errorf("count, threshold = %d, %d, want the threshold capped at 3", s.count, s.threshold)
+	}
+
+	s.SetPacketCount(3, 0)
+	if s.threshold != 1 {
+		t.Errorf("threshold = %d, want at least 1", s.threshold)
+	}
+
+	args := s.buildPingCommand(context.Background(), "8.8.8.8").Args
+	if count, ok := argAfter(args, "-c"); !ok || count != "3" {
+		t.Errorf("ping args = %v, want -c 3", args)
+	}
+}
+
+// argAfter returns the argument following flag in args
+func argAfter(args []string, flag string) (string, bool) {
+	for i, arg := range args {
+		if arg == flag && i+1 < len(args) {
+			return args[i+1], true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,76 @@
+package queue
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"port-scanner/internal/domain"
+)
+
+// staticHistory is a scan history holding the previous open ports of each IP
+type staticHistory map[string]map[int]string
+
+func (h staticHistory) LastOpenPorts(ip string) (map[int]string, bool, error) {
+	ports, found := h[ip]
+	return ports, found, nil
+}
+
+// changeMessages returns the change messages recorded in the manager's outbox
+func changeMessages(t *testing.T, r *RabbitMQManager) []*domain.ScanChangeMessage {
+	t.Helper()
+	pending, err := r.outboxStore.Pending(r.workerID, 100)
+	if err != nil {
+		t.Fatalf("Pending returned error: %v", err)
+	}
+	var changes []*domain.ScanChangeMessage
+	for _, message := range pending {
+		if message.Queue != r.changesQueue {
+			continue
+		}
+		var change domain.ScanChangeMessage
+		if err := json.Unmarshal(message.Body, &change); err != nil {
+			t.Fatalf("failed to decode change message: %v", err)
+		}
+		changes = append(changes, &change)
+	}
+	return changes
+}
+
+func openResult(ip string, ports ...int) *domain.ScanResult {
+	result := &domain.ScanResult{IP: ip, BatchID: "batch-2", Status: domain.ScanStatusCompleted, IsUp: true}
+	for _, port := range ports {
+		result.Ports = append(result.Ports, &domain.Port{Number: port, Status: domain.PortStatusOpen})
+	}
+	return result
+}
+
+func TestPersistResultPublishesChanges(t *testing.T) {
+	r := newTestManager()
+	r.changesQueue = "changes"
+	r.SetScanHistory(staticHistory{"8.8.8.8": {22: "", 80: ""}})
+
+	r.persistResult(openResult("8.8.8.8", 22, 443))
+
+	changes := changeMessages(t, r)
+	if len(changes) != 1 {
+		t.Fatalf("recorded %d change messages, want 1", len(changes))
+	}
+	if !reflect.DeepEqual(changes[0].AddedPorts, []int{443}) || !reflect.DeepEqual(changes[0].RemovedPorts, []int{80}) {
+		t.Errorf("change added %v and removed %v, want [443] and [80]", changes[0].AddedPorts, changes[0].RemovedPorts)
+	}
+}
+
+func TestPersistResultSkipsUnchangedAndFirstScans(t *testing.T) {
+	r := newTestManager()
+	r.changesQueue = "changes"
+	r.SetScanHistory(staticHistory{"8.8.8.8": {22: "", 80: ""}})
+
+	// An unchanged re-scan, and a host without a previous scan to compare with
+	r.persistResult(openResult("8.8.8.8", 22, 80))
+	r.persistResult(openResult("1.1.1.1", 53))
+
+	if changes := changeMessages(t, r); len(changes) != 0 {
+		t.Errorf("recorded change messages %+v, want none", changes)
+	}
+}
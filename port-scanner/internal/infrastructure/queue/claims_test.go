@@ -0,0 +1,111 @@
+package queue
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"port-scanner/internal/domain"
+)
+
+// memoryClaimer is a BatchClaimer holding claims in memory and recording the batches released
+type memoryClaimer struct {
+	mu       sync.Mutex
+	owners   map[string]string
+	released []string
+	err      error
+}
+
+func newMemoryClaimer() *memoryClaimer {
+	return &memoryClaimer{owners: make(map[string]string)}
+}
+
+func (c *memoryClaimer) ClaimBatch(batchID, workerID string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return false, c.err
+	}
+	if owner, ok := c.owners[batchID]; ok && owner != workerID {
+		return false, nil
+	}
+	c.owners[batchID] = workerID
+	return true, nil
+}
+
+func (c *memoryClaimer) HeartbeatBatch(batchID, workerID string) error {
+	return nil
+}
+
+func (c *memoryClaimer) ReleaseBatch(batchID, workerID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.owners[batchID] == workerID {
+		delete(c.owners, batchID)
+	}
+	c.released = append(c.released, batchID)
+	return nil
+}
+
+func TestHandleMessageSkipsBatchHeldByAnotherWorker(t *testing.T) {
+	r := newTestManager()
+	scanner := &recordingScanner{}
+	r.SetScanHandler(scanner.scan)
+	claimer := newMemoryClaimer()
+	claimer.owners["batch-1"] = "worker-2"
+	r.SetBatchClaimer(claimer, time.Minute)
+
+	ack := &fakeAcknowledger{}
+	message := &domain.QueueMessage{BatchID: "batch-1", IPs: []string{"8.8.8.8", "1.1.1.1"}}
+	if err := r.handleMessage(newDelivery(t, message, ack)); err != nil {
+		t.Fatalf("handleMessage returned error: %v", err)
+	}
+
+	if len(scanner.ips) != 0 {
+		t.Errorf("scanned %v although worker-2 holds the batch", scanner.ips)
+	}
+	if ack.acks != 1 || ack.nacks != 0 {
+		t.Errorf("acks/nacks = %d/%d, want the duplicate delivery acknowledged", ack.acks, ack.nacks)
+	}
+	if claimer.owners["batch-1"] != "worker-2" || len(claimer.released) != 0 {
+		t.Errorf("claim of worker-2 changed: owners %v, released %v", claimer.owners, claimer.released)
+	}
+}
+
+func TestHandleMessageClaimsAndReleasesBatch(t *testing.T) {
+	r := newTestManager()
+	scanner := &recordingScanner{}
+	r.SetScanHandler(scanner.scan)
+	claimer := newMemoryClaimer()
+	r.SetBatchClaimer(claimer, time.Minute)
+
+	message := &domain.QueueMessage{BatchID: "batch-1", IPs: []string{"8.8.8.8"}}
+	if err := r.handleMessage(newDelivery(t, message, &fakeAcknowledger{})); err != nil {
+		t.Fatalf("handleMessage returned error: %v", err)
+	}
+
+	if len(scanner.ips) != 1 {
+		t.Errorf("scanned %v, want the claimed batch scanned", scanner.ips)
+	}
+	if len(claimer.released) != 1 || len(claimer.owners) != 0 {
+		t.Errorf("released %v with owners %v left, want the claim released once scanned", claimer.released, claimer.owners)
+	}
+}
+
+func TestHandleMessageScansWhenClaimStoreFails(t *testing.T) {
+	r := newTestManager()
+	scanner := &recordingScanner{}
+	r.SetScanHandler(scanner.scan)
+	claimer := newMemoryClaimer()
+	claimer.err = errors.New("mongodb unavailable")
+	r.SetBatchClaimer(claimer, time.Minute)
+
+	message := &domain.QueueMessage{BatchID: "batch-1", IPs: []string{"8.8.8.8"}}
+	if err := r.handleMessage(newDelivery(t, message, &fakeAcknowledger{})); err != nil {
+		t.Fatalf("handleMessage returned error: %v", err)
+	}
+	if len(scanner.ips) != 1 {
+		t.Errorf("scanned %v, want the batch scanned without a claim", scanner.ips)
+	}
+}
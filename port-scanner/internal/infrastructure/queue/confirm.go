@@ -0,0 +1,88 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// defaultConfirmTimeout bounds the wait for a broker confirmation when none is configured
+const defaultConfirmTimeout = 5 * time.Second
+
+// errPublishNacked is returned when the broker refuses a message, e.g. because the
+// queue is full or a disk alarm is raised
+var errPublishNacked = errors.New("broker nacked the message")
+
+// messagePublisher sends a message to an exchange and reports whether the broker took it
+type messagePublisher interface {
+	publish(exchange, key string, msg amqp.Publishing) error
+}
+
+// publishChannel is the part of an AMQP channel used to publish
+type publishChannel interface {
+	Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+}
+
+// confirmPublisher publishes on a channel in confirm mode and waits for the broker to
+// acknowledge each message, so messages the broker drops surface as errors
+type confirmPublisher struct {
+	mu       sync.Mutex
+	channel  publishChannel
+	confirms chan amqp.Confirmation
+	lastTag  uint64
+	timeout  time.Duration
+}
+
+// newConfirmPublisher puts a channel in confirm mode
+func newConfirmPublisher(ch *amqp.Channel, timeout time.Duration) (*confirmPublisher, error) {
+	if err := ch.Confirm(false); err != nil {
+		return nil, fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+	if timeout <= 0 {
+		timeout = defaultConfirmTimeout
+	}
+
+	return &confirmPublisher{
+		channel:  ch,
+		confirms: ch.NotifyPublish(make(chan amqp.Confirmation, 16)),
+		timeout:  timeout,
+	}, nil
+}
+
+// publish sends a message and blocks until the broker acks it. Publishes are serialized so
+// each confirmation can be matched to its message by delivery tag.
+func (p *confirmPublisher) publish(exchange, key string, msg amqp.Publishing) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.channel.Publish(exchange, key, false, false, msg); err != nil {
+		return err
+	}
+	p.lastTag++
+	tag := p.lastTag
+
+	timer := time.NewTimer(p.timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case confirm, ok := <-p.confirms:
+			if !ok {
+				return errors.New("channel closed before the broker confirmed the message")
+			}
+			if confirm.DeliveryTag < tag {
+				// Late confirmation of an earlier publish that timed out
+				continue
+			}
+			if !confirm.Ack {
+				return errPublishNacked
+			}
+			return nil
+		case <-timer.C:
+			return fmt.Errorf("broker did not confirm the message within %s", p.timeout)
+		}
+	}
+}
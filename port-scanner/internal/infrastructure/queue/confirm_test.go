@@ -0,0 +1,88 @@
+package queue
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// confirmingChannel stands in for a channel in confirm mode, answering each publish with
+// the next confirmation of replies; a publish past the end of replies is never confirmed
+type confirmingChannel struct {
+	confirms chan amqp.Confirmation
+	replies  []bool
+	tag      uint64
+}
+
+func (c *confirmingChannel) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	c.tag++
+	if int(c.tag) <= len(c.replies) {
+		c.confirms <- amqp.Confirmation{DeliveryTag: c.tag, Ack: c.replies[c.tag-1]}
+	}
+	return nil
+}
+
+func newTestConfirmPublisher(timeout time.Duration, replies ...bool) *confirmPublisher {
+	confirms := make(chan amqp.Confirmation, len(replies))
+	return &confirmPublisher{
+		channel:  &confirmingChannel{confirms: confirms, replies: replies},
+		confirms: confirms,
+		timeout:  timeout,
+	}
+}
+
+func TestConfirmPublisherReportsNacks(t *testing.T) {
+	publisher := newTestConfirmPublisher(time.Second, true, false, true)
+
+	if err := publisher.publish("", "results", amqp.Publishing{}); err != nil {
+		t.Errorf("acked publish returned error: %v", err)
+	}
+	if err := publisher.publish("", "results", amqp.Publishing{}); !errors.Is(err, errPublishNacked) {
+		t.Errorf("nacked publish returned %v, want errPublishNacked", err)
+	}
+	if err := publisher.publish("", "results", amqp.Publishing{}); err != nil {
+		t.Errorf("acked publish after a nack returned error: %v", err)
+	}
+}
+
+func TestConfirmPublisherTimesOutWithoutConfirmation(t *testing.T) {
+	publisher := newTestConfirmPublisher(20 * time.Millisecond)
+
+	start := time.Now()
+	err := publisher.publish("", "results", amqp.Publishing{})
+	if err == nil || !strings.Contains(err.Error(), "did not confirm") {
+		t.Fatalf("unconfirmed publish returned %v, want a confirmation timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("publish gave up after %v, before the confirm timeout", elapsed)
+	}
+}
+
+func TestConfirmPublisherSkipsLateConfirmations(t *testing.T) {
+	confirms := make(chan amqp.Confirmation, 2)
+	publisher := &confirmPublisher{
+		channel:  &confirmingChannel{confirms: confirms, tag: 1},
+		confirms: confirms,
+		lastTag:  1,
+		timeout:  time.Second,
+	}
+
+	// The ack of an earlier publish that timed out arrives ahead of this one's nack
+	confirms <- amqp.Confirmation{DeliveryTag: 1, Ack: true}
+	confirms <- amqp.Confirmation{DeliveryTag: 2, Ack: false}
+	if err := publisher.publish("", "results", amqp.Publishing{}); !errors.Is(err, errPublishNacked) {
+		t.Errorf("publish returned %v, want the nack of its own delivery tag", err)
+	}
+}
+
+func TestConfirmPublisherReportsClosedChannel(t *testing.T) {
+	publisher := newTestConfirmPublisher(time.Second)
+	close(publisher.confirms)
+
+	if err := publisher.publish("", "results", amqp.Publishing{}); err == nil {
+		t.Error("publish on a closed channel returned no error")
+	}
+}
@@ -0,0 +1,47 @@
+package queue
+
+import (
+	"encoding/json"
+	"testing"
+
+	"port-scanner/internal/domain"
+)
+
+func TestEnqueueScanPublishesBatchMessages(t *testing.T) {
+	r := newTestManager()
+	r.maxIPsPerMessage = 2
+	publisher := &flakyPublisher{}
+	r.publisher = publisher
+
+	ips := []string{"198.51.100.1", "198.51.100.2", "198.51.100.3"}
+	count, err := r.EnqueueScan("list-dmz", ips, []int{443})
+	if err != nil || count != 2 {
+		t.Fatalf("EnqueueScan = %d, %v, want 2 messages", count, err)
+	}
+
+	var queued []string
+	for i, body := range publisher.bodies() {
+		var message domain.QueueMessage
+		if err := json.Unmarshal([]byte(body), &message); err != nil {
+			t.Fatalf("message %d is not a queue message: %v", i, err)
+		}
+		if message.ParentBatchID != "list-dmz" || len(message.Ports) != 1 || message.Ports[0] != 443 {
+			t.Errorf("message %d = %+v, want part of list-dmz on port 443", i, message)
+		}
+		queued = append(queued, message.IPs...)
+	}
+	if len(queued) != 3 {
+		t.Errorf("queued %v, want %v", queued, ips)
+	}
+}
+
+func TestEnqueueScanReportsPublishedMessagesOnFailure(t *testing.T) {
+	r := newTestManager()
+	r.maxIPsPerMessage = 1
+	r.publisher = &flakyPublisher{failAt: map[int]bool{2: true}}
+
+	count, err := r.EnqueueScan("list-dmz", []string{"198.51.100.1", "198.51.100.2", "198.51.100.3"}, nil)
+	if err == nil || count != 1 {
+		t.Errorf("EnqueueScan = %d, %v, want an error after 1 published message", count, err)
+	}
+}
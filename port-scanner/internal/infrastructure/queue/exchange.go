@@ -0,0 +1,143 @@
+package queue
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"port-scanner/internal/domain"
+
+	"github.com/streadway/amqp"
+)
+
+// Exchange types results can be published through
+const (
+	ExchangeDirect = "direct"
+	ExchangeTopic  = "topic"
+	ExchangeFanout = "fanout"
+)
+
+// routingPlaceholder matches a placeholder of a routing key template
+var routingPlaceholder = regexp.MustCompile(`\{[a-z_]+\}`)
+
+// routingValues are what a routing key template can refer to:
+//
+//	{queue}     the queue the message is bound to
+//	{ip}        the scanned IP
+//	{batch_id}  the batch the IP belongs to
+//	{status}    "up" or "down"
+//	{services}  the distinct services on open ports, sorted and joined with "."
+//
+// Empty values render as "none", so topic keys keep every word.
+type routingValues struct {
+	queue    string
+	ip       string
+	batchID  string
+	isUp     bool
+	services string
+}
+
+// newRoutingValues collects the routing values of a host's message
+func newRoutingValues(queue, ip, batchID string, isUp bool, openPorts []*domain.Port) routingValues {
+	seen := make(map[string]bool)
+	var services []string
+	for _, port := range openPorts {
+		service := strings.ToLower(strings.TrimSpace(port.Service))
+		if service == "" {
+			service = "unknown"
+		}
+		// Dots separate topic words, so they can't appear inside one
+		service = strings.ReplaceAll(service, ".", "_")
+		if !seen[service] {
+			seen[service] = true
+			services = append(services, service)
+		}
+	}
+	sort.Strings(services)
+
+	return routingValues{queue: queue, ip: ip, batchID: batchID, isUp: isUp, services: strings.Join(services, ".")}
+}
+
+// renderRoutingKey fills a routing key template; an empty template routes by queue name
+func renderRoutingKey(template string, values routingValues) string {
+	if template == "" {
+		return values.queue
+	}
+
+	status := "down"
+	if values.isUp {
+		status = "up"
+	}
+	orNone := func(value string) string {
+		if value == "" {
+			return "none"
+		}
+		return value
+	}
+
+	return strings.NewReplacer(
+		"{queue}", values.queue,
+		"{ip}", orNone(values.ip),
+		"{batch_id}", orNone(values.batchID),
+		"{status}", status,
+		"{services}", orNone(values.services),
+	).Replace(template)
+}
+
+// bindingKey returns the key a queue is bound with for its routing key template. Topic
+// exchanges bind every placeholder but {queue} as "#", since IPs and service lists span
+// several words; other exchange types bind the template as it is, so direct exchanges only
+// accept templates without them.
+func bindingKey(exchangeType, template, queue string) (string, error) {
+	if template == "" {
+		return queue, nil
+	}
+	template = strings.ReplaceAll(template, "{queue}", queue)
+
+	switch exchangeType {
+	case ExchangeTopic:
+		return routingPlaceholder.ReplaceAllString(template, "#"), nil
+	case ExchangeDirect:
+		if routingPlaceholder.MatchString(template) {
+			return "", fmt.Errorf("routing key %q has placeholders a direct exchange can't bind", template)
+		}
+	}
+	return template, nil
+}
+
+// declareExchange declares the results exchange and binds each queue to it with the key its
+// routing key template produces
+func declareExchange(ch *amqp.Channel, exchange, exchangeType string, bindings map[string]string) error {
+	if err := ch.ExchangeDeclare(
+		exchange,     // name
+		exchangeType, // type
+		true,         // durable
+		false,        // auto-deleted
+		false,        // internal
+		false,        // no-wait
+		nil,          // arguments
+	); err != nil {
+		return fmt.Errorf("failed to declare exchange %s: %w", exchange, err)
+	}
+
+	for queueName, template := range bindings {
+		key, err := bindingKey(exchangeType, template, queueName)
+		if err != nil {
+			return err
+		}
+		if err := ch.QueueBind(queueName, key, exchange, false, nil); err != nil {
+			return fmt.Errorf("failed to bind queue %s to exchange %s: %w", queueName, exchange, err)
+		}
+	}
+	return nil
+}
+
+// route returns the exchange and routing key a message for queue is published with: the
+// configured exchange and the rendered template, or the default exchange and the queue name
+func (r *RabbitMQManager) route(template string, values routingValues) (string, string) {
+	if r.exchange == "" {
+		return "", values.queue
+	}
+	return r.exchange, renderRoutingKey(template, values)
+}
@@ -0,0 +1,143 @@
+package queue
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"port-scanner/internal/domain"
+
+	"github.com/streadway/amqp"
+)
+
+// routedMessage is where a message was published to
+type routedMessage struct {
+	exchange string
+	key      string
+}
+
+// routingPublisher records the exchange and routing key of every published message
+type routingPublisher struct {
+	mu        sync.Mutex
+	published []routedMessage
+}
+
+func (p *routingPublisher) publish(exchange, key string, msg amqp.Publishing) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.published = append(p.published, routedMessage{exchange: exchange, key: key})
+	return nil
+}
+
+// routedResult returns a result for 8.8.8.8 in batch-7 with the given services open
+func routedResult(services ...string) *domain.ScanResult {
+	result := &domain.ScanResult{IP: "8.8.8.8", BatchID: "batch-7", IsUp: true, Status: domain.ScanStatusCompleted}
+	for i, service := range services {
+		port := domain.NewPort(1000 + i)
+		port.Status = domain.PortStatusOpen
+		port.Service = service
+		result.AddPort(port)
+	}
+	return result
+}
+
+// publishResult enqueues a result's messages and publishes them, returning where they went
+func publishResult(t *testing.T, r *RabbitMQManager, result *domain.ScanResult) []routedMessage {
+	t.Helper()
+	publisher := &routingPublisher{}
+	r.publisher = publisher
+	if err := r.enqueueResultMessages(result, nil); err != nil {
+		t.Fatalf("enqueueResultMessages returned error: %v", err)
+	}
+	r.drainOutbox()
+	return publisher.published
+}
+
+func TestResultsPublishedToConfiguredExchange(t *testing.T) {
+	r := newTestManager()
+	r.exchange = "scan-events"
+	r.resultRoutingKey = "results.{status}.{batch_id}"
+	r.enrichmentRoutingKey = "{queue}.{services}"
+	r.serviceAnalysisRoutingKey = ""
+
+	got := publishResult(t, r, routedResult("HTTP", "ssh", "http"))
+	want := []routedMessage{
+		{exchange: "scan-events", key: "results.up.batch-7"},
+		{exchange: "scan-events", key: "enrichment.http.ssh"},
+		{exchange: "scan-events", key: "analysis"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("published to %+v, want %+v", got, want)
+	}
+}
+
+func TestResultsPublishedToDefaultExchangeWithoutOne(t *testing.T) {
+	r := newTestManager()
+	r.resultRoutingKey = "results.{status}"
+
+	got := publishResult(t, r, routedResult("ssh"))
+	want := []routedMessage{{key: "results"}, {key: "enrichment"}, {key: "analysis"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("published to %+v, want the queues through the default exchange", got)
+	}
+}
+
+func TestRenderRoutingKey(t *testing.T) {
+	tests := []struct {
+		template string
+		values   routingValues
+		want     string
+	}{
+		{"", routingValues{queue: "results"}, "results"},
+		{"{queue}.{status}", routingValues{queue: "results"}, "results.down"},
+		{"hosts.{ip}.{batch_id}", routingValues{ip: "10.0.0.1", isUp: true}, "hosts.10.0.0.1.none"},
+		{"svc.{services}", routingValues{}, "svc.none"},
+	}
+	for _, tt := range tests {
+		if got := renderRoutingKey(tt.template, tt.values); got != tt.want {
+			t.Errorf("renderRoutingKey(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+
+	values := newRoutingValues("results", "8.8.8.8", "", true, routedResult("ssh", "", "my.service", "SSH").Ports)
+	if values.services != "my_service.ssh.unknown" {
+		t.Errorf("services = %q, want distinct, lowercased, sorted words", values.services)
+	}
+}
+
+func TestBindingKey(t *testing.T) {
+	tests := []struct {
+		exchangeType string
+		template     string
+		want         string
+	}{
+		{ExchangeTopic, "", "results"},
+		{ExchangeTopic, "{queue}.{status}.{services}", "results.#.#"},
+		{ExchangeDirect, "{queue}.scans", "results.scans"},
+		{ExchangeFanout, "{status}", "{status}"},
+	}
+	for _, tt := range tests {
+		got, err := bindingKey(tt.exchangeType, tt.template, "results")
+		if err != nil || got != tt.want {
+			t.Errorf("bindingKey(%s, %q) = %q, %v, want %q", tt.exchangeType, tt.template, got, err, tt.want)
+		}
+	}
+
+	if _, err := bindingKey(ExchangeDirect, "results.{status}", "results"); err == nil {
+		t.Error("direct exchange accepted a template with placeholders")
+	}
+}
+
+func TestNewRabbitMQManagerRejectsInvalidExchange(t *testing.T) {
+	for _, options := range []QueueOptions{
+		{Exchange: "scan-events", ExchangeType: "headers"},
+		{Exchange: "scan-events", ExchangeType: ExchangeDirect, ResultRoutingKey: "results.{batch_id}"},
+	} {
+		// Validation fails before any connection is attempted
+		_, err := NewRabbitMQManager("amqp://127.0.0.1:1/", "ips", "results", "enrichment", "analysis", options)
+		if err == nil || strings.Contains(err.Error(), "connect") {
+			t.Errorf("NewRabbitMQManager with %+v returned %v, want a validation error", options, err)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package queue
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"port-scanner/internal/domain"
+	"port-scanner/pkg/log"
+
+	"go.uber.org/zap"
+)
+
+// outboxBatchSize is how many pending messages are read from the store at a time
+const outboxBatchSize = 100
+
+// memoryOutboxStore keeps outbox messages in process memory; they are lost on restart
+type memoryOutboxStore struct {
+	mu       sync.Mutex
+	messages []*domain.OutboxMessage
+	nextID   int64
+}
+
+// Ensure memoryOutboxStore implements OutboxStore interface
+var _ domain.OutboxStore = (*memoryOutboxStore)(nil)
+
+// newMemoryOutboxStore creates an empty in-memory outbox store
+func newMemoryOutboxStore() *memoryOutboxStore {
+	return &memoryOutboxStore{}
+}
+
+// Enqueue appends messages in order
+func (s *memoryOutboxStore) Enqueue(messages []*domain.OutboxMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, message := range messages {
+		s.nextID++
+		message.ID = strconv.FormatInt(s.nextID, 10)
+		s.messages = append(s.messages, message)
+	}
+	return nil
+}
+
+// Pending returns the oldest unpublished messages of a worker
+func (s *memoryOutboxStore) Pending(workerID string, limit int) ([]*domain.OutboxMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var pending []*domain.OutboxMessage
+	for _, message := range s.messages {
+		if message.WorkerID != workerID {
+			continue
+		}
+		pending = append(pending, message)
+		if len(pending) == limit {
+			break
+		}
+	}
+	return pending, nil
+}
+
+// MarkPublished removes a published message
+func (s *memoryOutboxStore) MarkPublished(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, message := range s.messages {
+		if message.ID == id {
+			s.messages = append(s.messages[:i], s.messages[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// SetOutboxStore sets where downstream messages are recorded before publishing.
+// It must be called before ConsumeIPs.
+func (r *RabbitMQManager) SetOutboxStore(store domain.OutboxStore, retryInterval time.Duration) {
+	r.outboxStore = store
+	r.SetOutboxRetryInterval(retryInterval)
+}
+
+// SetOutboxRetryInterval sets how often unpublished messages are retried
+func (r *RabbitMQManager) SetOutboxRetryInterval(retryInterval time.Duration) {
+	if retryInterval > 0 {
+		r.outboxRetryInterval = retryInterval
+	}
+}
+
+// startOutbox starts the background publisher that drains the outbox
+func (r *RabbitMQManager) startOutbox() {
+	r.outboxOnce.Do(func() {
+		r.outboxDone = make(chan struct{})
+		go r.runOutbox()
+	})
+}
+
+// stopOutbox stops the background publisher after a final drain
+func (r *RabbitMQManager) stopOutbox() {
+	if r.outboxDone == nil {
+		return
+	}
+	r.outboxStopOnce.Do(func() {
+		close(r.outboxStop)
+		<-r.outboxDone
+	})
+}
+
+// runOutbox publishes pending outbox messages whenever new ones are recorded,
+// retrying failed publishes every retry interval
+func (r *RabbitMQManager) runOutbox() {
+	defer close(r.outboxDone)
+
+	ticker := time.NewTicker(r.outboxRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.outboxStop:
+			r.drainOutbox()
+			return
+		case <-r.outboxWake:
+		case <-ticker.C:
+		}
+		r.drainOutbox()
+	}
+}
+
+// wakeOutbox asks the background publisher to drain the outbox
+func (r *RabbitMQManager) wakeOutbox() {
+	select {
+	case r.outboxWake <- struct{}{}:
+	default:
+	}
+}
+
+// drainOutbox publishes pending messages oldest first. It stops at the first
+// failure so later messages never overtake one that is being retried.
+func (r *RabbitMQManager) drainOutbox() {
+	for {
+		pending, err := r.outboxStore.Pending(r.workerID, outboxBatchSize)
+		if err != nil {
+			log.L().Error("Failed to read outbox", zap.String("event", "outbox_read_failed"), zap.Error(err))
+			return
+		}
+		if len(pending) == 0 {
+			return
+		}
+
+		for _, message := range pending {
+			exchange, key := message.Exchange, message.RoutingKey
+			if exchange == "" {
+				key = message.Queue
+			}
+			if err := r.publishRouted(exchange, key, message.ContentType, message.Body); err != nil {
+				log.L().Warn("Failed to publish outbox message, will retry", zap.String("event", "outbox_publish_failed"),
+					zap.String("queue", message.Queue), zap.Duration("retry_in", r.outboxRetryInterval), zap.Error(err))
+				return
+			}
+			if err := r.outboxStore.MarkPublished(message.ID); err != nil {
+				// The message will be published again; downstream consumers must tolerate duplicates
+				log.L().Error("Failed to mark outbox message published", zap.String("event", "outbox_mark_failed"),
+					zap.String("queue", message.Queue), zap.Error(err))
+				return
+			}
+		}
+	}
+}
+
+// enqueueResultMessages records the scan result, enrichment, (when ports are open)
+// service analysis and (when the host changed) change messages for a result, in that order
+func (r *RabbitMQManager) enqueueResultMessages(result *domain.ScanResult, change *domain.ScanChangeMessage) error {
+	resultBody, contentType, err := r.scanResultBody(result)
+	if err != nil {
+		return err
+	}
+	enrichmentBody, _, err := r.enrichmentBody(result.IP, result.IsUp, result.BatchID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	openPorts := result.GetOpenPorts()
+	routed := func(queue, template string, body []byte) *domain.OutboxMessage {
+		exchange, key := r.route(template, newRoutingValues(queue, result.IP, result.BatchID, result.IsUp, openPorts))
+		return &domain.OutboxMessage{WorkerID: r.workerID, Queue: queue, Exchange: exchange, RoutingKey: key,
+			ContentType: contentType, Body: body, CreatedAt: now}
+	}
+	messages := []*domain.OutboxMessage{
+		routed(r.scanResultQueue, r.resultRoutingKey, resultBody),
+		routed(r.enrichmentQueue, r.enrichmentRoutingKey, enrichmentBody),
+	}
+
+	if len(openPorts) > 0 {
+		analysisBody, _, err := r.serviceAnalysisBody(result.IP, openPorts, result.BatchID, result.Findings)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, routed(r.serviceAnalysisQueue, r.serviceAnalysisRoutingKey, analysisBody))
+	}
+
+	if change != nil {
+		body, err := changeBody(change)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, &domain.OutboxMessage{WorkerID: r.workerID, Queue: r.changesQueue, ContentType: ContentTypeJSON, Body: body, CreatedAt: now})
+	}
+
+	if err := r.outboxStore.Enqueue(messages); err != nil {
+		return err
+	}
+	r.wakeOutbox()
+	return nil
+}
@@ -0,0 +1,123 @@
+package queue
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"port-scanner/internal/domain"
+
+	"github.com/streadway/amqp"
+)
+
+// flakyPublisher records published message bodies and fails the publishes listed in failAt,
+// counting every attempt from 1
+type flakyPublisher struct {
+	mu        sync.Mutex
+	attempts  int
+	failAt    map[int]bool
+	published []string
+}
+
+func (p *flakyPublisher) publish(exchange, key string, msg amqp.Publishing) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.attempts++
+	if p.failAt[p.attempts] {
+		return errors.New("broker unavailable")
+	}
+	p.published = append(p.published, string(msg.Body))
+	return nil
+}
+
+func (p *flakyPublisher) bodies() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.published...)
+}
+
+func enqueueBodies(t *testing.T, r *RabbitMQManager, bodies ...string) {
+	t.Helper()
+	messages := make([]*domain.OutboxMessage, 0, len(bodies))
+	for _, body := range bodies {
+		messages = append(messages, &domain.OutboxMessage{WorkerID: r.workerID, Queue: "results", Body: []byte(body), CreatedAt: time.Now()})
+	}
+	if err := r.outboxStore.Enqueue(messages); err != nil {
+		t.Fatalf("Enqueue returned error: %v", err)
+	}
+}
+
+func TestDrainOutboxStopsAtFailureAndKeepsOrder(t *testing.T) {
+	r := newTestManager()
+	publisher := &flakyPublisher{failAt: map[int]bool{2: true}}
+	r.publisher = publisher
+	enqueueBodies(t, r, "result", "enrichment", "analysis")
+
+	// The failed enrichment message must not be overtaken by the analysis message
+	r.drainOutbox()
+	if got, want := publisher.bodies(), []string{"result"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("after a failed publish, published %v, want %v", got, want)
+	}
+	pending, _ := r.outboxStore.Pending(r.workerID, 10)
+	if len(pending) != 2 {
+		t.Fatalf("%d messages pending after a failed publish, want 2", len(pending))
+	}
+
+	r.drainOutbox()
+	if got, want := publisher.bodies(), []string{"result", "enrichment", "analysis"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("after retrying, published %v, want %v", got, want)
+	}
+	if pending, _ := r.outboxStore.Pending(r.workerID, 10); len(pending) != 0 {
+		t.Errorf("%d messages still pending after retrying", len(pending))
+	}
+}
+
+func TestOutboxRetriesTransientFailuresInBackground(t *testing.T) {
+	r := newTestManager()
+	r.SetOutboxRetryInterval(10 * time.Millisecond)
+	publisher := &flakyPublisher{failAt: map[int]bool{1: true, 2: true, 4: true}}
+	r.publisher = publisher
+
+	r.startOutbox()
+	defer r.stopOutbox()
+
+	enqueueBodies(t, r, "result-1", "enrichment-1")
+	r.wakeOutbox()
+	enqueueBodies(t, r, "result-2", "enrichment-2")
+	r.wakeOutbox()
+
+	want := []string{"result-1", "enrichment-1", "result-2", "enrichment-2"}
+	if !waitFor(t, 2*time.Second, func() bool { return len(publisher.bodies()) == len(want) }) {
+		t.Fatalf("published %v before timing out, want %v", publisher.bodies(), want)
+	}
+	if got := publisher.bodies(); !reflect.DeepEqual(got, want) {
+		t.Errorf("published %v, want %v in order", got, want)
+	}
+}
+
+func TestEnqueueResultMessagesOrder(t *testing.T) {
+	r := newTestManager()
+	r.changesQueue = "changes"
+
+	result := &domain.ScanResult{IP: "8.8.8.8", IsUp: true, Status: domain.ScanStatusCompleted}
+	port := domain.NewPort(443)
+	port.Status = domain.PortStatusOpen
+	result.AddPort(port)
+
+	change := &domain.ScanChangeMessage{IP: "8.8.8.8", AddedPorts: []int{443}}
+	if err := r.enqueueResultMessages(result, change); err != nil {
+		t.Fatalf("enqueueResultMessages returned error: %v", err)
+	}
+
+	pending, _ := r.outboxStore.Pending(r.workerID, 10)
+	var queues []string
+	for _, message := range pending {
+		queues = append(queues, message.Queue)
+	}
+	if want := []string{"results", "enrichment", "analysis", "changes"}; !reflect.DeepEqual(queues, want) {
+		t.Errorf("recorded messages for %v, want %v", queues, want)
+	}
+}
@@ -0,0 +1,106 @@
+package queue
+
+import (
+	"fmt"
+	"testing"
+
+	"port-scanner/internal/domain"
+)
+
+// oversizedMessage builds a message carrying n distinct public IPs
+func oversizedMessage(n int) *domain.QueueMessage {
+	ips := make([]string, n)
+	for i := range ips {
+		ips[i] = fmt.Sprintf("8.8.%d.%d", i/200+1, i%200+1)
+	}
+	return &domain.QueueMessage{BatchID: "batch-1", IPs: ips, Count: n}
+}
+
+func TestHandleMessageDeadLettersOversizedMessage(t *testing.T) {
+	r := newTestManager()
+	r.maxIPsPerMessage = 5
+	r.oversizedAction = OversizedReject
+	r.deadLetterQueue = "ips.dlq"
+	publisher := &flakyPublisher{}
+	r.publisher = publisher
+	scanner := &recordingScanner{}
+	r.SetScanHandler(scanner.scan)
+
+	ack := &fakeAcknowledger{}
+	delivery := newDelivery(t, oversizedMessage(8), ack)
+	if err := r.handleMessage(delivery); err != nil {
+		t.Fatalf("handleMessage returned error: %v", err)
+	}
+
+	if got := scanner.scanned(); len(got) != 0 {
+		t.Errorf("scanned %v from a rejected message", got)
+	}
+	if bodies := publisher.bodies(); len(bodies) != 1 || bodies[0] != string(delivery.Body) {
+		t.Errorf("dead-lettered %v, want the original message", bodies)
+	}
+	if ack.acks != 1 || ack.nacks != 0 {
+		t.Errorf("acks/nacks = %d/%d, want the message acked once dead-lettered", ack.acks, ack.nacks)
+	}
+}
+
+func TestHandleMessageDropsOversizedMessageWithoutDeadLetterQueue(t *testing.T) {
+	r := newTestManager()
+	r.maxIPsPerMessage = 5
+	scanner := &recordingScanner{}
+	r.SetScanHandler(scanner.scan)
+
+	ack := &fakeAcknowledger{}
+	if err := r.handleMessage(newDelivery(t, oversizedMessage(8), ack)); err != nil {
+		t.Fatalf("handleMessage returned error: %v", err)
+	}
+	if got := scanner.scanned(); len(got) != 0 {
+		t.Errorf("scanned %v from a rejected message", got)
+	}
+	if ack.nacks != 1 || ack.requeue {
+		t.Errorf("nacks = %d (requeue %v), want one nack without requeue", ack.nacks, ack.requeue)
+	}
+}
+
+func TestHandleMessageTruncatesOversizedMessage(t *testing.T) {
+	r := newTestManager()
+	r.maxIPsPerMessage = 5
+	r.oversizedAction = OversizedTruncate
+	scanner := &recordingScanner{}
+	r.SetScanHandler(scanner.scan)
+
+	message := oversizedMessage(8)
+	ack := &fakeAcknowledger{}
+	if err := r.handleMessage(newDelivery(t, message, ack)); err != nil {
+		t.Fatalf("handleMessage returned error: %v", err)
+	}
+
+	scanned := make(map[string]bool)
+	for _, ip := range scanner.scanned() {
+		scanned[ip] = true
+	}
+	if len(scanned) != 5 {
+		t.Fatalf("scanned %d IPs, want the cap of 5", len(scanned))
+	}
+	for _, ip := range message.IPs[:5] {
+		if !scanned[ip] {
+			t.Errorf("%s within the cap was not scanned", ip)
+		}
+	}
+	if ack.acks != 1 {
+		t.Errorf("message acked %d times, want 1", ack.acks)
+	}
+}
+
+func TestHandleMessageScansMessageWithinCap(t *testing.T) {
+	r := newTestManager()
+	r.maxIPsPerMessage = 5
+	scanner := &recordingScanner{}
+	r.SetScanHandler(scanner.scan)
+
+	if err := r.handleMessage(newDelivery(t, oversizedMessage(5), &fakeAcknowledger{})); err != nil {
+		t.Fatalf("handleMessage returned error: %v", err)
+	}
+	if got := scanner.scanned(); len(got) != 5 {
+		t.Errorf("scanned %d IPs, want all 5", len(got))
+	}
+}
@@ -0,0 +1,58 @@
+package queue
+
+import (
+	"sync"
+
+	"port-scanner/internal/domain"
+)
+
+// resultPipeline saves and publishes the results of one IP message on dedicated goroutines,
+// so a slow result sink or broker doesn't hold up scanning. Results wait in a bounded buffer;
+// once it is full, scans block until a publisher catches up, which limits scanning to the
+// pace of publishing.
+type resultPipeline struct {
+	results chan *domain.ScanResult
+	wg      sync.WaitGroup
+
+	mu        sync.Mutex
+	published []*domain.ScanResult
+}
+
+// startResultPipeline starts the publishers of a message's results
+func (r *RabbitMQManager) startResultPipeline(bufferSize, publishers int) *resultPipeline {
+	if bufferSize < 0 {
+		bufferSize = 0
+	}
+	if publishers < 1 {
+		publishers = 1
+	}
+
+	p := &resultPipeline{results: make(chan *domain.ScanResult, bufferSize)}
+	for i := 0; i < publishers; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for result := range p.results {
+				r.persistResult(result)
+
+				p.mu.Lock()
+				p.published = append(p.published, result)
+				p.mu.Unlock()
+			}
+		}()
+	}
+	return p
+}
+
+// put hands a result to the publishers, blocking while the buffer is full
+func (p *resultPipeline) put(result *domain.ScanResult) {
+	p.results <- result
+}
+
+// close waits until every result was saved and published and returns them, in the order
+// they were published
+func (p *resultPipeline) close() []*domain.ScanResult {
+	close(p.results)
+	p.wg.Wait()
+	return p.published
+}
@@ -0,0 +1,135 @@
+package queue
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"port-scanner/internal/domain"
+)
+
+// blockingSink is a result sink that holds every save until released
+type blockingSink struct {
+	release chan struct{}
+
+	mu    sync.Mutex
+	saved []string
+}
+
+func (s *blockingSink) SaveScanResult(result *domain.ScanResult) error {
+	<-s.release
+	s.mu.Lock()
+	s.saved = append(s.saved, result.IP)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *blockingSink) Close() error { return nil }
+
+func (s *blockingSink) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.saved)
+}
+
+// waitFor polls a condition until it holds or the timeout passes
+func waitFor(t *testing.T, timeout time.Duration, condition func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return condition()
+}
+
+func TestHandleMessageKeepsScanningWhilePublishingIsSlow(t *testing.T) {
+	const bufferSize = 3
+
+	r := newTestManager()
+	r.scanConfig.ResultBufferSize = bufferSize
+	r.scanConfig.ResultPublishers = 1
+	scanner := &recordingScanner{}
+	r.SetScanHandler(scanner.scan)
+	sink := &blockingSink{release: make(chan struct{})}
+	r.SetResultSink(sink)
+
+	ips := make([]string, 10)
+	for i := range ips {
+		ips[i] = fmt.Sprintf("8.8.8.%d", i+1)
+	}
+	ack := &fakeAcknowledger{}
+	delivery := newDelivery(t, &domain.QueueMessage{BatchID: "batch-1", IPs: ips}, ack)
+
+	done := make(chan error, 1)
+	go func() { done <- r.handleMessage(delivery) }()
+
+	// With the only publisher stuck on the first result, scans continue until the buffer is
+	// full and one more scan waits to hand its result over
+	want := 1 + bufferSize + 1
+	if !waitFor(t, 2*time.Second, func() bool { return len(scanner.scanned()) == want }) {
+		t.Fatalf("scanned %d IPs while publishing was blocked, want %d", len(scanner.scanned()), want)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := len(scanner.scanned()); got != want {
+		t.Fatalf("scanned %d IPs with a full result buffer, want scanning to pause at %d", got, want)
+	}
+	if sink.count() != 0 {
+		t.Fatalf("sink saved %d results before being released", sink.count())
+	}
+
+	close(sink.release)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("handleMessage returned error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("handleMessage did not finish after publishing resumed")
+	}
+
+	if sink.count() != len(ips) {
+		t.Errorf("sink saved %d results, want %d", sink.count(), len(ips))
+	}
+	pending, _ := r.outboxStore.Pending(r.workerID, 100)
+	if len(pending) != 2*len(ips) {
+		t.Errorf("outbox holds %d messages, want a result and an enrichment message per IP (%d)", len(pending), 2*len(ips))
+	}
+	if ack.acks != 1 {
+		t.Errorf("message acked %d times, want 1", ack.acks)
+	}
+}
+
+func TestHandleMessagePassesEveryResultToMessageHandler(t *testing.T) {
+	r := newTestManager()
+	r.scanConfig.ResultPublishers = 4
+	scanner := &recordingScanner{}
+	r.SetScanHandler(scanner.scan)
+
+	var handled []*domain.ScanResult
+	r.SetMessageHandler(func(message *domain.QueueMessage, results []*domain.ScanResult, startedAt time.Time) {
+		handled = results
+	})
+
+	ips := []string{"8.8.8.8", "8.8.4.4", "1.1.1.1", "9.9.9.9"}
+	ack := &fakeAcknowledger{}
+	if err := r.handleMessage(newDelivery(t, &domain.QueueMessage{BatchID: "batch-1", IPs: ips}, ack)); err != nil {
+		t.Fatalf("handleMessage returned error: %v", err)
+	}
+
+	if len(handled) != len(ips) {
+		t.Fatalf("message handler got %d results, want %d", len(handled), len(ips))
+	}
+	seen := make(map[string]bool)
+	for _, result := range handled {
+		seen[result.IP] = true
+	}
+	for _, ip := range ips {
+		if !seen[ip] {
+			t.Errorf("message handler missed the result of %s", ip)
+		}
+	}
+}
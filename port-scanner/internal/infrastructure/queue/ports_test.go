@@ -0,0 +1,68 @@
+package queue
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"port-scanner/internal/domain"
+)
+
+// portRecordingScanner records the ports each IP was scanned on
+type portRecordingScanner struct {
+	mu    sync.Mutex
+	ports map[string][]int
+}
+
+func (s *portRecordingScanner) scan(ip string, config *domain.ScanConfig, batchID, workerID string) (*domain.ScanResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ports == nil {
+		s.ports = make(map[string][]int)
+	}
+	s.ports[ip] = config.PortRange
+	return &domain.ScanResult{IP: ip, Status: domain.ScanStatusCompleted, IsUp: true, BatchID: batchID, WorkerID: workerID}, nil
+}
+
+func TestHandleMessageScansMessagePorts(t *testing.T) {
+	r := newTestManager()
+	r.scanConfig.PortRange = []int{21, 22, 80, 443}
+	scanner := &portRecordingScanner{}
+	r.SetScanHandler(scanner.scan)
+
+	custom := &domain.QueueMessage{BatchID: "batch-1", IPs: []string{"8.8.8.8", "1.1.1.1"}, Ports: []int{8443, 502}}
+	if err := r.handleMessage(newDelivery(t, custom, &fakeAcknowledger{})); err != nil {
+		t.Fatalf("handleMessage returned error: %v", err)
+	}
+	defaults := &domain.QueueMessage{BatchID: "batch-2", IPs: []string{"9.9.9.9"}}
+	if err := r.handleMessage(newDelivery(t, defaults, &fakeAcknowledger{})); err != nil {
+		t.Fatalf("handleMessage returned error: %v", err)
+	}
+
+	want := map[string][]int{"8.8.8.8": {502, 8443}, "1.1.1.1": {502, 8443}, "9.9.9.9": {21, 22, 80, 443}}
+	if !reflect.DeepEqual(scanner.ports, want) {
+		t.Errorf("scanned ports = %v, want %v", scanner.ports, want)
+	}
+	if !reflect.DeepEqual(r.scanConfig.PortRange, []int{21, 22, 80, 443}) {
+		t.Errorf("message ports leaked into the default config: %v", r.scanConfig.PortRange)
+	}
+}
+
+func TestHandleMessageRejectsInvalidPorts(t *testing.T) {
+	r := newTestManager()
+	scanner := &portRecordingScanner{}
+	r.SetScanHandler(scanner.scan)
+
+	ack := &fakeAcknowledger{}
+	message := &domain.QueueMessage{BatchID: "batch-1", IPs: []string{"8.8.8.8"}, Ports: []int{22, 0}}
+	if err := r.handleMessage(newDelivery(t, message, ack)); err != nil {
+		t.Fatalf("handleMessage returned error: %v", err)
+	}
+
+	if len(scanner.ports) != 0 {
+		t.Errorf("scanned %v despite invalid ports", scanner.ports)
+	}
+	if ack.nacks != 1 || ack.requeue {
+		t.Errorf("nacks/requeue = %d/%v, want the message dropped without requeue", ack.nacks, ack.requeue)
+	}
+}
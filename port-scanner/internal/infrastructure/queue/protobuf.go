@@ -0,0 +1,374 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"port-scanner/internal/domain"
+	"port-scanner/proto/resultspb"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Formats of the result, enrichment and service analysis messages, and their AMQP content types.
+// Change and batch completion messages are always JSON.
+const (
+	MessageFormatJSON     = "json"
+	MessageFormatProtobuf = "protobuf"
+
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// contentTypes maps each message format to the content type its messages are published with
+var contentTypes = map[string]string{
+	MessageFormatJSON:     ContentTypeJSON,
+	MessageFormatProtobuf: ContentTypeProtobuf,
+}
+
+// encodeMessage encodes a result, enrichment or service analysis message in a format,
+// returning the body and its content type
+func encodeMessage(format string, message interface{}) ([]byte, string, error) {
+	if format != MessageFormatProtobuf {
+		body, err := json.Marshal(message)
+		return body, ContentTypeJSON, err
+	}
+
+	var pb proto.Message
+	var err error
+	switch m := message.(type) {
+	case domain.ScanResultMessage:
+		pb, err = scanResultMessageToProto(&m)
+	case domain.EnrichmentMessage:
+		pb = enrichmentMessageToProto(&m)
+	case domain.ServiceAnalysisMessage:
+		pb, err = serviceAnalysisMessageToProto(&m)
+	default:
+		return nil, "", fmt.Errorf("no protobuf encoding for %T", message)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	body, err := proto.Marshal(pb)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode %T: %w", message, err)
+	}
+	return body, ContentTypeProtobuf, nil
+}
+
+// DecodeScanResultMessage decodes a scan result message published in the protobuf format
+func DecodeScanResultMessage(data []byte) (*domain.ScanResultMessage, error) {
+	var pb resultspb.ScanResultMessage
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return nil, fmt.Errorf("failed to decode scan result message: %w", err)
+	}
+
+	message := &domain.ScanResultMessage{
+		Timestamp: pb.GetTimestamp(),
+		WorkerID:  pb.GetWorkerId(),
+	}
+	if pb.ScanResult != nil {
+		result, err := scanResultFromProto(pb.ScanResult)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode scan result message: %w", err)
+		}
+		message.ScanResult = result
+	}
+	return message, nil
+}
+
+// DecodeEnrichmentMessage decodes an enrichment message published in the protobuf format
+func DecodeEnrichmentMessage(data []byte) (*domain.EnrichmentMessage, error) {
+	var pb resultspb.EnrichmentMessage
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return nil, fmt.Errorf("failed to decode enrichment message: %w", err)
+	}
+
+	return &domain.EnrichmentMessage{
+		IP:        pb.GetIp(),
+		IsUp:      pb.GetIsUp(),
+		BatchID:   pb.GetBatchId(),
+		Timestamp: pb.GetTimestamp(),
+	}, nil
+}
+
+// DecodeServiceAnalysisMessage decodes a service analysis message published in the protobuf format
+func DecodeServiceAnalysisMessage(data []byte) (*domain.ServiceAnalysisMessage, error) {
+	var pb resultspb.ServiceAnalysisMessage
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return nil, fmt.Errorf("failed to decode service analysis message: %w", err)
+	}
+
+	ports, err := portsFromProto(pb.OpenPorts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode service analysis message: %w", err)
+	}
+	return &domain.ServiceAnalysisMessage{
+		IP:        pb.GetIp(),
+		OpenPorts: ports,
+		BatchID:   pb.GetBatchId(),
+		Timestamp: pb.GetTimestamp(),
+		Findings:  findingsFromProto(pb.Findings),
+	}, nil
+}
+
+func scanResultMessageToProto(message *domain.ScanResultMessage) (*resultspb.ScanResultMessage, error) {
+	pb := &resultspb.ScanResultMessage{
+		Timestamp: message.Timestamp,
+		WorkerId:  message.WorkerID,
+	}
+	if message.ScanResult != nil {
+		result, err := scanResultToProto(message.ScanResult)
+		if err != nil {
+			return nil, err
+		}
+		pb.ScanResult = result
+	}
+	return pb, nil
+}
+
+func enrichmentMessageToProto(message *domain.EnrichmentMessage) *resultspb.EnrichmentMessage {
+	return &resultspb.EnrichmentMessage{
+		Ip:        message.IP,
+		IsUp:      message.IsUp,
+		BatchId:   message.BatchID,
+		Timestamp: message.Timestamp,
+	}
+}
+
+func serviceAnalysisMessageToProto(message *domain.ServiceAnalysisMessage) (*resultspb.ServiceAnalysisMessage, error) {
+	ports, err := portsToProto(message.OpenPorts)
+	if err != nil {
+		return nil, err
+	}
+	return &resultspb.ServiceAnalysisMessage{
+		Ip:        message.IP,
+		OpenPorts: ports,
+		BatchId:   message.BatchID,
+		Timestamp: message.Timestamp,
+		Findings:  findingsToProto(message.Findings),
+	}, nil
+}
+
+func scanResultToProto(result *domain.ScanResult) (*resultspb.ScanResult, error) {
+	ports, err := portsToProto(result.Ports)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := metadataToJSON(result.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	return &resultspb.ScanResult{
+		Ip:            result.IP,
+		IsUp:          result.IsUp,
+		PingTimeNs:    int64(result.PingTime),
+		ScanStartTime: timestampToProto(result.ScanStartTime),
+		ScanEndTime:   timestampToProto(result.ScanEndTime),
+		Ports:         ports,
+		Status:        string(result.Status),
+		Error:         result.Error,
+		BatchId:       result.BatchID,
+		WorkerId:      result.WorkerID,
+		Tags:          result.Tags,
+		Priority:      int32(result.Priority),
+		RetentionNs:   int64(result.Retention),
+		MetadataJson:  metadata,
+		ReverseDns:    result.ReverseDNS,
+		Findings:      findingsToProto(result.Findings),
+	}, nil
+}
+
+func scanResultFromProto(pb *resultspb.ScanResult) (*domain.ScanResult, error) {
+	ports, err := portsFromProto(pb.Ports)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := metadataFromJSON(pb.MetadataJson)
+	if err != nil {
+		return nil, err
+	}
+	result := &domain.ScanResult{
+		IP:            pb.GetIp(),
+		IsUp:          pb.GetIsUp(),
+		PingTime:      time.Duration(pb.GetPingTimeNs()),
+		ScanStartTime: timestampFromProto(pb.ScanStartTime),
+		ScanEndTime:   timestampFromProto(pb.ScanEndTime),
+		Ports:         ports,
+		Status:        domain.ScanStatus(pb.GetStatus()),
+		Error:         pb.GetError(),
+		BatchID:       pb.GetBatchId(),
+		WorkerID:      pb.GetWorkerId(),
+		Priority:      int(pb.GetPriority()),
+		Retention:     time.Duration(pb.GetRetentionNs()),
+		Metadata:      metadata,
+		ReverseDNS:    pb.ReverseDns,
+		Findings:      findingsFromProto(pb.Findings),
+	}
+	if len(pb.Tags) > 0 {
+		result.Tags = pb.Tags
+	}
+	if result.Ports == nil {
+		result.Ports = make([]*domain.Port, 0)
+	}
+	return result, nil
+}
+
+// portsToProto converts ports, leaving out nil entries
+func portsToProto(ports []*domain.Port) ([]*resultspb.Port, error) {
+	var pbs []*resultspb.Port
+	for _, port := range ports {
+		if port == nil {
+			continue
+		}
+		pb, err := portToProto(port)
+		if err != nil {
+			return nil, err
+		}
+		pbs = append(pbs, pb)
+	}
+	return pbs, nil
+}
+
+func portsFromProto(pbs []*resultspb.Port) ([]*domain.Port, error) {
+	var ports []*domain.Port
+	for _, pb := range pbs {
+		port, err := portFromProto(pb)
+		if err != nil {
+			return nil, err
+		}
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
+
+func portToProto(port *domain.Port) (*resultspb.Port, error) {
+	pb := &resultspb.Port{
+		Number:         int32(port.Number),
+		Status:         string(port.Status),
+		Service:        port.Service,
+		Banner:         port.Banner,
+		Version:        port.Version,
+		ScanTime:       timestampToProto(port.ScanTime),
+		ResponseTimeNs: int64(port.ResponseTime),
+		Error:          port.Error,
+	}
+	if info := port.BannerInfo; info != nil {
+		metadata, err := metadataToJSON(info.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		pb.BannerInfo = &resultspb.BannerInfo{
+			RawBanner:       info.RawBanner,
+			Service:         info.Service,
+			Protocol:        info.Protocol,
+			Version:         info.Version,
+			Confidence:      info.Confidence,
+			MetadataJson:    metadata,
+			ConfidenceScore: int32(info.ConfidenceScore),
+		}
+	}
+	return pb, nil
+}
+
+func portFromProto(pb *resultspb.Port) (*domain.Port, error) {
+	port := &domain.Port{
+		Number:       int(pb.GetNumber()),
+		Status:       domain.PortStatus(pb.GetStatus()),
+		Service:      pb.GetService(),
+		Banner:       pb.GetBanner(),
+		Version:      pb.GetVersion(),
+		ScanTime:     timestampFromProto(pb.ScanTime),
+		ResponseTime: time.Duration(pb.GetResponseTimeNs()),
+		Error:        pb.GetError(),
+	}
+	if info := pb.BannerInfo; info != nil {
+		metadata, err := metadataFromJSON(info.MetadataJson)
+		if err != nil {
+			return nil, err
+		}
+		port.BannerInfo = &domain.BannerInfo{
+			RawBanner:       info.GetRawBanner(),
+			Service:         info.GetService(),
+			Protocol:        info.GetProtocol(),
+			Version:         info.GetVersion(),
+			Confidence:      info.GetConfidence(),
+			Metadata:        metadata,
+			ConfidenceScore: int(info.GetConfidenceScore()),
+		}
+	}
+	return port, nil
+}
+
+func findingsToProto(findings []domain.Finding) []*resultspb.Finding {
+	var pbs []*resultspb.Finding
+	for _, finding := range findings {
+		pbs = append(pbs, &resultspb.Finding{
+			Analyzer: finding.Analyzer,
+			Port:     int32(finding.Port),
+			Service:  finding.Service,
+			Severity: finding.Severity,
+			Title:    finding.Title,
+			Detail:   finding.Detail,
+		})
+	}
+	return pbs
+}
+
+func findingsFromProto(pbs []*resultspb.Finding) []domain.Finding {
+	var findings []domain.Finding
+	for _, pb := range pbs {
+		findings = append(findings, domain.Finding{
+			Analyzer: pb.GetAnalyzer(),
+			Port:     int(pb.GetPort()),
+			Service:  pb.GetService(),
+			Severity: pb.GetSeverity(),
+			Title:    pb.GetTitle(),
+			Detail:   pb.GetDetail(),
+		})
+	}
+	return findings
+}
+
+// timestampToProto converts a time to a google.protobuf.Timestamp; zero times are left out
+func timestampToProto(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// timestampFromProto converts a google.protobuf.Timestamp to a time; a missing one is the zero time
+func timestampFromProto(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+// metadataToJSON encodes a metadata map as the JSON object sent in metadata_json fields
+func metadataToJSON(metadata map[string]interface{}) ([]byte, error) {
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+	body, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode metadata: %w", err)
+	}
+	return body, nil
+}
+
+// metadataFromJSON decodes a metadata map sent as a JSON object
+func metadataFromJSON(data []byte) (map[string]interface{}, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to decode metadata: %w", err)
+	}
+	return metadata, nil
+}
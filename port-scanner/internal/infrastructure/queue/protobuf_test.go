@@ -0,0 +1,162 @@
+package queue
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"port-scanner/internal/domain"
+	"port-scanner/proto/resultspb"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// sampleScanResult returns a completed result using every field of the protobuf schema
+func sampleScanResult() *domain.ScanResult {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.UTC)
+	result := domain.NewScanResult("203.0.113.7", "batch-1", "worker-1")
+	result.ScanStartTime = start
+	result.ScanEndTime = start.Add(3 * time.Second)
+	result.Status = domain.ScanStatusCompleted
+	result.IsUp = true
+	result.PingTime = 12 * time.Millisecond
+	result.Tags = map[string]string{"env": "prod", "team": "red"}
+	result.Priority = -2
+	result.Retention = 72 * time.Hour
+	result.Metadata = map[string]interface{}{
+		"honeypot_score": 15,
+		"asn":            map[string]interface{}{"number": 64496, "name": "Example"},
+	}
+	result.ReverseDNS = []string{"host.example.com", "alias.example.com"}
+	result.Findings = []domain.Finding{
+		{Analyzer: "redis_no_auth", Port: 6379, Service: "redis", Severity: "high", Title: "Redis without authentication"},
+	}
+
+	ssh := domain.NewPort(22)
+	ssh.Status = domain.PortStatusOpen
+	ssh.Service = "ssh"
+	ssh.Banner = "SSH-2.0-OpenSSH_8.9p1"
+	ssh.Version = "8.9p1"
+	ssh.ScanTime = start.Add(time.Second)
+	ssh.ResponseTime = 3 * time.Millisecond
+	ssh.BannerInfo = &domain.BannerInfo{
+		RawBanner:       "SSH-2.0-OpenSSH_8.9p1",
+		Service:         "ssh",
+		Protocol:        "tcp",
+		Version:         "8.9p1",
+		Confidence:      "banner",
+		Metadata:        map[string]interface{}{"software": "OpenSSH", "verified": true},
+		ConfidenceScore: 90,
+	}
+	failed := domain.NewPort(6379)
+	failed.Status = domain.PortStatusError
+	failed.ScanTime = start.Add(2 * time.Second)
+	failed.Error = "connection reset"
+	result.Ports = []*domain.Port{ssh, failed}
+
+	return result
+}
+
+// roundTrip encodes message in both formats, decodes each body and returns the decoded
+// messages re-encoded as JSON, so their contents can be compared
+func roundTrip[T any](t *testing.T, message T, decode func([]byte) (*T, error)) (viaJSON, viaProtobuf string) {
+	t.Helper()
+
+	body, contentType, err := encodeMessage(MessageFormatJSON, message)
+	if err != nil || contentType != ContentTypeJSON {
+		t.Fatalf("JSON encoding returned content type %q, error %v", contentType, err)
+	}
+	var fromJSON T
+	if err := json.Unmarshal(body, &fromJSON); err != nil {
+		t.Fatalf("failed to decode JSON message: %v", err)
+	}
+
+	body, contentType, err = encodeMessage(MessageFormatProtobuf, message)
+	if err != nil || contentType != ContentTypeProtobuf {
+		t.Fatalf("protobuf encoding returned content type %q, error %v", contentType, err)
+	}
+	fromProtobuf, err := decode(body)
+	if err != nil {
+		t.Fatalf("failed to decode protobuf message: %v", err)
+	}
+
+	jsonBody, _ := json.Marshal(fromJSON)
+	protobufBody, _ := json.Marshal(fromProtobuf)
+	return string(jsonBody), string(protobufBody)
+}
+
+func TestScanResultMessageProtobufMatchesJSON(t *testing.T) {
+	for name, result := range map[string]*domain.ScanResult{
+		"full":    sampleScanResult(),
+		"minimal": {IP: "198.51.100.1", Status: domain.ScanStatusFailed, Error: "host unreachable", Ports: make([]*domain.Port, 0)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			message := domain.ScanResultMessage{ScanResult: result, Timestamp: 1714564800, WorkerID: "worker-1"}
+			viaJSON, viaProtobuf := roundTrip(t, message, DecodeScanResultMessage)
+			if viaJSON != viaProtobuf {
+				t.Errorf("protobuf round trip differs from JSON:\n json:     %s\n protobuf: %s", viaJSON, viaProtobuf)
+			}
+		})
+	}
+}
+
+func TestEnrichmentMessageProtobufMatchesJSON(t *testing.T) {
+	message := domain.EnrichmentMessage{IP: "203.0.113.7", IsUp: true, BatchID: "batch-1", Timestamp: 1714564800}
+	viaJSON, viaProtobuf := roundTrip(t, message, DecodeEnrichmentMessage)
+	if viaJSON != viaProtobuf {
+		t.Errorf("protobuf round trip differs from JSON:\n json:     %s\n protobuf: %s", viaJSON, viaProtobuf)
+	}
+}
+
+func TestServiceAnalysisMessageProtobufMatchesJSON(t *testing.T) {
+	result := sampleScanResult()
+	message := domain.ServiceAnalysisMessage{
+		IP:        result.IP,
+		OpenPorts: result.GetOpenPorts(),
+		BatchID:   result.BatchID,
+		Timestamp: 1714564800,
+		Findings:  result.Findings,
+	}
+	viaJSON, viaProtobuf := roundTrip(t, message, DecodeServiceAnalysisMessage)
+	if viaJSON != viaProtobuf {
+		t.Errorf("protobuf round trip differs from JSON:\n json:     %s\n protobuf: %s", viaJSON, viaProtobuf)
+	}
+}
+
+func TestProtobufMessagesFollowSchema(t *testing.T) {
+	result := sampleScanResult()
+	body, _, err := encodeMessage(MessageFormatProtobuf, domain.ScanResultMessage{ScanResult: result, WorkerID: "worker-1"})
+	if err != nil {
+		t.Fatalf("encodeMessage returned error: %v", err)
+	}
+
+	// Consumers decode the messages with the types generated from proto/results.proto
+	var pb resultspb.ScanResultMessage
+	if err := proto.Unmarshal(body, &pb); err != nil {
+		t.Fatalf("failed to decode with the generated types: %v", err)
+	}
+	ports := pb.GetScanResult().GetPorts()
+	if len(ports) != 2 || ports[0].GetNumber() != 22 || ports[0].GetBannerInfo().GetConfidenceScore() != 90 {
+		t.Errorf("ports decoded as %v", ports)
+	}
+	if got := pb.GetScanResult().GetScanStartTime().AsTime(); !got.Equal(result.ScanStartTime) {
+		t.Errorf("scan_start_time = %v, want %v", got, result.ScanStartTime)
+	}
+	if got := pb.GetScanResult().GetTags()["env"]; got != "prod" {
+		t.Errorf("tags[env] = %q, want prod", got)
+	}
+}
+
+func TestProtobufDecodingRejectsInvalidMessages(t *testing.T) {
+	body, _, err := encodeMessage(MessageFormatProtobuf, domain.ScanResultMessage{ScanResult: sampleScanResult()})
+	if err != nil {
+		t.Fatalf("encodeMessage returned error: %v", err)
+	}
+	if _, err := DecodeScanResultMessage(body[:len(body)-3]); err == nil {
+		t.Error("truncated message decoded without error")
+	}
+
+	if _, _, err := encodeMessage(MessageFormatProtobuf, domain.QueueMessage{}); err == nil {
+		t.Error("encoding a message without a protobuf schema succeeded")
+	}
+}
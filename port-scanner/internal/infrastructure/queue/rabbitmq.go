@@ -1,12 +1,14 @@
 package queue
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
+	"orwell/pkg/netutil"
 	"port-scanner/internal/domain"
-	"port-scanner/internal/infrastructure/database"
 	"port-scanner/pkg/log"
 
 	"github.com/streadway/amqp"
@@ -17,18 +19,128 @@ import (
 type RabbitMQManager struct {
 	conn                 *amqp.Connection
 	channel              *amqp.Channel
+	publisher            messagePublisher
 	ipQueue              string
 	scanResultQueue      string
 	enrichmentQueue      string
 	serviceAnalysisQueue string
 	workerID             string
 	scanHandler          func(string, *domain.ScanConfig, string, string) (*domain.ScanResult, error)
+	scanSlots            func(context.Context) (func(), error)
+	serialScans          sync.Mutex
+	handling             sync.WaitGroup
 	scanConfig           *domain.ScanConfig
-	dbManager            *database.MongoDBManager
+	resultSink           domain.ResultSink
+	ctx                  context.Context
+	batchClaimer         domain.BatchClaimer
+	heartbeatInterval    time.Duration
+
+	outboxStore         domain.OutboxStore
+	outboxRetryInterval time.Duration
+	outboxWake          chan struct{}
+	outboxStop          chan struct{}
+	outboxDone          chan struct{}
+	outboxOnce          sync.Once
+	outboxStopOnce      sync.Once
+
+	maxIPsPerMessage int
+	oversizedAction  string
+	deadLetterQueue  string
+
+	changesQueue string
+	scanHistory  domain.ScanHistory
+
+	batchCompleteQueue string
+	messageHandler     func(*domain.QueueMessage, []*domain.ScanResult, time.Time)
+
+	messageFormat string
+
+	exchange                  string
+	resultRoutingKey          string
+	enrichmentRoutingKey      string
+	serviceAnalysisRoutingKey string
 }
 
+// Ensure RabbitMQManager implements ResultReplayer and BatchCompletePublisher interfaces
+var (
+	_ domain.ResultReplayer         = (*RabbitMQManager)(nil)
+	_ domain.BatchCompletePublisher = (*RabbitMQManager)(nil)
+)
+
+// QueueOptions holds optional RabbitMQ settings
+type QueueOptions struct {
+	IPQueueMaxPriority int // Declares the IP queue with x-max-priority when > 0; must match the generator
+	PrefetchCount      int // Maximum unacknowledged IP messages delivered to this consumer
+
+	// Messages carrying more IPs than MaxIPsPerMessage (0 disables the cap) are rejected
+	// to DeadLetterQueue, or dropped when it is empty, or truncated when OversizedAction is "truncate"
+	MaxIPsPerMessage int
+	OversizedAction  string
+	DeadLetterQueue  string
+
+	ChangesQueue string // Receives a ScanChangeMessage when a host differs from its previous scan; empty disables
+
+	BatchCompleteQueue string // Receives a BatchCompleteMessage when every message of a batch was processed; empty disables
+
+	ConfirmTimeout time.Duration // How long a publish waits for the broker's confirmation before failing
+
+	// WorkerID identifies this instance as consumer, claim and outbox owner and in published
+	// results; empty falls back to the host's instance ID so it survives restarts
+	WorkerID string
+
+	// MessageFormat encodes result, enrichment and service analysis messages as "json" (the
+	// default) or "protobuf" (see proto/results.proto), with the matching content type
+	MessageFormat string
+
+	// Exchange publishes result, enrichment and service analysis messages through a named
+	// exchange of ExchangeType ("topic" when empty) instead of the default exchange, routed by
+	// the routing key templates (see routingValues); an empty template routes by queue name.
+	// The exchange is declared and the queues bound to it at startup.
+	Exchange                  string
+	ExchangeType              string
+	ResultRoutingKey          string
+	EnrichmentRoutingKey      string
+	ServiceAnalysisRoutingKey string
+}
+
+// Actions taken on IP messages that exceed the configured maximum IP count
+const (
+	OversizedReject   = "reject"
+	OversizedTruncate = "truncate"
+)
+
 // NewRabbitMQManager creates a new RabbitMQ manager
-func NewRabbitMQManager(url, ipQueue, scanResultQueue, enrichmentQueue, serviceAnalysisQueue string) (*RabbitMQManager, error) {
+func NewRabbitMQManager(url, ipQueue, scanResultQueue, enrichmentQueue, serviceAnalysisQueue string, options QueueOptions) (*RabbitMQManager, error) {
+	messageFormat := options.MessageFormat
+	if messageFormat == "" {
+		messageFormat = MessageFormatJSON
+	}
+	if _, ok := contentTypes[messageFormat]; !ok {
+		return nil, fmt.Errorf("unknown message format %q", messageFormat)
+	}
+
+	exchangeType := options.ExchangeType
+	if exchangeType == "" {
+		exchangeType = ExchangeTopic
+	}
+	bindings := map[string]string{
+		scanResultQueue:      options.ResultRoutingKey,
+		enrichmentQueue:      options.EnrichmentRoutingKey,
+		serviceAnalysisQueue: options.ServiceAnalysisRoutingKey,
+	}
+	if options.Exchange != "" {
+		switch exchangeType {
+		case ExchangeDirect, ExchangeTopic, ExchangeFanout:
+		default:
+			return nil, fmt.Errorf("unsupported exchange type %q", exchangeType)
+		}
+		for queueName, template := range bindings {
+			if _, err := bindingKey(exchangeType, template, queueName); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	conn, err := amqp.Dial(url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
@@ -40,16 +152,47 @@ func NewRabbitMQManager(url, ipQueue, scanResultQueue, enrichmentQueue, serviceA
 		return nil, fmt.Errorf("failed to open channel: %w", err)
 	}
 
+	// Limit unacknowledged deliveries so the broker can reorder the backlog by priority
+	if options.PrefetchCount > 0 {
+		if err := ch.Qos(options.PrefetchCount, 0, false); err != nil {
+			ch.Close()
+			conn.Close()
+			return nil, fmt.Errorf("failed to set prefetch count: %w", err)
+		}
+	}
+
+	// Wait for broker confirmations so dropped messages are retried instead of lost
+	publisher, err := newConfirmPublisher(ch, options.ConfirmTimeout)
+	if err != nil {
+		ch.Close()
+		conn.Close()
+		return nil, err
+	}
+
 	// Declare all queues
 	queues := []string{ipQueue, scanResultQueue, enrichmentQueue, serviceAnalysisQueue}
+	if options.DeadLetterQueue != "" {
+		queues = append(queues, options.DeadLetterQueue)
+	}
+	if options.ChangesQueue != "" {
+		queues = append(queues, options.ChangesQueue)
+	}
+	if options.BatchCompleteQueue != "" {
+		queues = append(queues, options.BatchCompleteQueue)
+	}
 	for _, queueName := range queues {
+		var args amqp.Table
+		if queueName == ipQueue && options.IPQueueMaxPriority > 0 {
+			args = amqp.Table{"x-max-priority": int32(options.IPQueueMaxPriority)}
+		}
+
 		_, err = ch.QueueDeclare(
 			queueName, // name
 			true,      // durable
 			false,     // delete when unused
 			false,     // exclusive
 			false,     // no-wait
-			nil,       // arguments
+			args,      // arguments
 		)
 		if err != nil {
 			ch.Close()
@@ -58,24 +201,56 @@ func NewRabbitMQManager(url, ipQueue, scanResultQueue, enrichmentQueue, serviceA
 		}
 	}
 
-	// Generate worker ID
-	workerID := fmt.Sprintf("worker-%d", time.Now().UnixNano())
+	if options.Exchange != "" {
+		if err := declareExchange(ch, options.Exchange, exchangeType, bindings); err != nil {
+			ch.Close()
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	workerID := options.WorkerID
+	if workerID == "" {
+		workerID = log.InstanceID()
+	}
 
 	return &RabbitMQManager{
 		conn:                 conn,
 		channel:              ch,
+		publisher:            publisher,
 		ipQueue:              ipQueue,
 		scanResultQueue:      scanResultQueue,
 		enrichmentQueue:      enrichmentQueue,
 		serviceAnalysisQueue: serviceAnalysisQueue,
 		workerID:             workerID,
 		scanConfig:           domain.NewDefaultScanConfig(),
+		ctx:                  context.Background(),
+		outboxStore:          newMemoryOutboxStore(),
+		outboxRetryInterval:  2 * time.Second,
+		outboxWake:           make(chan struct{}, 1),
+		outboxStop:           make(chan struct{}),
+		maxIPsPerMessage:     options.MaxIPsPerMessage,
+		oversizedAction:      options.OversizedAction,
+		deadLetterQueue:      options.DeadLetterQueue,
+		changesQueue:         options.ChangesQueue,
+		batchCompleteQueue:   options.BatchCompleteQueue,
+		messageFormat:        messageFormat,
+
+		exchange:                  options.Exchange,
+		resultRoutingKey:          options.ResultRoutingKey,
+		enrichmentRoutingKey:      options.EnrichmentRoutingKey,
+		serviceAnalysisRoutingKey: options.ServiceAnalysisRoutingKey,
 	}, nil
 }
 
-// SetMongoDBManager sets the MongoDB manager for saving results
-func (r *RabbitMQManager) SetMongoDBManager(dbManager *database.MongoDBManager) {
-	r.dbManager = dbManager
+// WorkerID returns the ID this instance scans, consumes and publishes under
+func (r *RabbitMQManager) WorkerID() string {
+	return r.workerID
+}
+
+// SetResultSink sets the sink used to persist scan results
+func (r *RabbitMQManager) SetResultSink(sink domain.ResultSink) {
+	r.resultSink = sink
 }
 
 // SetScanHandler sets the scan handler function
@@ -83,32 +258,151 @@ func (r *RabbitMQManager) SetScanHandler(handler func(string, *domain.ScanConfig
 	r.scanHandler = handler
 }
 
+// SetScanSlots sets where scans get a slot before they start, so scans of deliveries handled
+// at the same time share one concurrency limit with each other and with scans from elsewhere.
+// Without it the IPs of a message are scanned one at a time.
+func (r *RabbitMQManager) SetScanSlots(acquire func(context.Context) (func(), error)) {
+	r.scanSlots = acquire
+}
+
+// acquireScanSlot waits for a scan slot and returns the function releasing it
+func (r *RabbitMQManager) acquireScanSlot() (func(), error) {
+	if r.scanSlots != nil {
+		return r.scanSlots(r.ctx)
+	}
+	r.serialScans.Lock()
+	return r.serialScans.Unlock, nil
+}
+
+// BatchCompleteQueue returns the queue completed batches are announced on; empty when disabled
+func (r *RabbitMQManager) BatchCompleteQueue() string {
+	return r.batchCompleteQueue
+}
+
+// SetMessageHandler sets a handler called with the results of each IP message once every IP
+// in it was processed, along with when processing started. It isn't called for messages
+// interrupted by shutdown.
+func (r *RabbitMQManager) SetMessageHandler(handler func(*domain.QueueMessage, []*domain.ScanResult, time.Time)) {
+	r.messageHandler = handler
+}
+
 // SetScanConfig sets the scan configuration
 func (r *RabbitMQManager) SetScanConfig(config *domain.ScanConfig) {
 	r.scanConfig = config
 }
 
-// ConsumeIPs starts consuming IP messages from the queue
-func (r *RabbitMQManager) ConsumeIPs(handler func(*domain.QueueMessage) error) error {
+// SetScanHistory sets where previous scans are looked up to detect changes.
+// It must be the store results are saved to, or changes are compared against stale scans.
+func (r *RabbitMQManager) SetScanHistory(history domain.ScanHistory) {
+	r.scanHistory = history
+}
+
+// detectChanges compares a completed result with the host's previous scan. It must run
+// before the result is saved. Hosts without a previous scan have no baseline and report no changes.
+func (r *RabbitMQManager) detectChanges(result *domain.ScanResult) *domain.ScanChangeMessage {
+	if r.scanHistory == nil || r.changesQueue == "" || result.Status != domain.ScanStatusCompleted {
+		return nil
+	}
+
+	previous, found, err := r.scanHistory.LastOpenPorts(result.IP)
+	if err != nil {
+		log.L().Warn("Failed to load previous scan, skipping change detection", zap.String("event", "change_detection_failed"),
+			zap.String("ip", result.IP), zap.Error(err))
+		return nil
+	}
+	if !found {
+		return nil
+	}
+	return domain.DiffScanResult(previous, result)
+}
+
+// SetBatchClaimer sets the shared batch claim store and how often claims are refreshed
+func (r *RabbitMQManager) SetBatchClaimer(claimer domain.BatchClaimer, heartbeatInterval time.Duration) {
+	r.batchClaimer = claimer
+	r.heartbeatInterval = heartbeatInterval
+}
+
+// claimBatch records this worker as the owner of a batch and keeps the claim alive
+// until the returned function is called, which also releases it. It reports false when
+// another worker holds the batch; a claim store failure doesn't stop the batch.
+func (r *RabbitMQManager) claimBatch(batchID string) (func(), bool) {
+	claimed, err := r.batchClaimer.ClaimBatch(batchID, r.workerID)
+	if err != nil {
+		log.L().Error("Failed to claim batch", zap.String("event", "batch_claim_failed"), zap.String("batch_id", batchID), zap.Error(err))
+		return func() {}, true
+	}
+	if !claimed {
+		log.L().Warn("Batch is claimed by another worker", zap.String("event", "batch_claim_conflict"), zap.String("batch_id", batchID))
+		return func() {}, false
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(r.heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := r.batchClaimer.HeartbeatBatch(batchID, r.workerID); err != nil {
+					log.L().Warn("Failed to heartbeat batch claim", zap.String("event", "batch_heartbeat_failed"), zap.String("batch_id", batchID), zap.Error(err))
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		if err := r.batchClaimer.ReleaseBatch(batchID, r.workerID); err != nil {
+			log.L().Warn("Failed to release batch claim", zap.String("event", "batch_release_failed"), zap.String("batch_id", batchID), zap.Error(err))
+		}
+	}, true
+}
+
+// SetContext sets the context whose cancellation stops message consumption
+func (r *RabbitMQManager) SetContext(ctx context.Context) {
+	r.ctx = ctx
+}
+
+// ConsumeIPs starts consuming IP messages from the queue, scanning them with the scan handler
+func (r *RabbitMQManager) ConsumeIPs() error {
 	msgs, err := r.channel.Consume(
-		r.ipQueue, // queue
-		"",        // consumer
-		false,     // auto-ack
-		false,     // exclusive
-		false,     // no-local
-		false,     // no-wait
-		nil,       // args
+		r.ipQueue,  // queue
+		r.workerID, // consumer
+		false,      // auto-ack
+		false,      // exclusive
+		false,      // no-local
+		false,      // no-wait
+		nil,        // args
 	)
 	if err != nil {
 		return fmt.Errorf("failed to start consuming: %w", err)
 	}
 
+	// Downstream messages recorded while handling deliveries are published in the background
+	r.startOutbox()
+
 	go func() {
-		for msg := range msgs {
-			err := r.handleMessage(msg)
-			if err != nil {
-				log.L().Error("Failed to process message", zap.String("event", "process_failed"), zap.Error(err))
-				msg.Nack(false, true) // requeue
+		for {
+			select {
+			case <-r.ctx.Done():
+				r.stopConsuming()
+				return
+			case msg, ok := <-msgs:
+				if !ok {
+					return
+				}
+				// The prefetch count bounds how many deliveries are handled at once; their
+				// scans share the scan slots
+				r.handling.Add(1)
+				go func(msg amqp.Delivery) {
+					defer r.handling.Done()
+					if err := r.handleMessage(msg); err != nil {
+						log.L().Error("Failed to process message", zap.String("event", "process_failed"), zap.Error(err))
+						msg.Nack(false, true) // requeue
+					}
+				}(msg)
 			}
 		}
 	}()
@@ -116,6 +410,49 @@ func (r *RabbitMQManager) ConsumeIPs(handler func(*domain.QueueMessage) error) e
 	return nil
 }
 
+// rejectOversized moves a message with too many IPs to the dead-letter queue, or drops it
+// when none is configured, so it is never scanned or redelivered
+func (r *RabbitMQManager) rejectOversized(delivery amqp.Delivery, message *domain.QueueMessage) error {
+	log.L().Warn("Rejecting oversized IP message", zap.String("event", "oversized_message_rejected"),
+		zap.String("batch_id", message.BatchID), zap.Int("ip_count", len(message.IPs)), zap.Int("max_ips", r.maxIPsPerMessage),
+		zap.String("dead_letter_queue", r.deadLetterQueue))
+
+	if r.deadLetterQueue == "" {
+		return delivery.Nack(false, false)
+	}
+
+	err := r.publisher.publish("", r.deadLetterQueue, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		Body:         delivery.Body,
+		Headers: amqp.Table{
+			"x-rejected-reason": "too_many_ips",
+			"x-ip-count":        int32(len(message.IPs)),
+			"x-max-ips":         int32(r.maxIPsPerMessage),
+		},
+	})
+	if err != nil {
+		// Requeue rather than lose the message if it can't be dead-lettered
+		return fmt.Errorf("failed to dead-letter oversized message: %w", err)
+	}
+	return delivery.Ack(false)
+}
+
+// stopConsuming cancels the IP queue consumer and reports the work left behind
+func (r *RabbitMQManager) stopConsuming() {
+	if err := r.channel.Cancel(r.workerID, false); err != nil {
+		log.L().Debug("Failed to cancel consumer", zap.String("event", "consumer_cancel_failed"), zap.Error(err))
+		return
+	}
+
+	pending := 0
+	if q, err := r.channel.QueueInspect(r.ipQueue); err == nil {
+		pending = q.Messages
+	}
+	log.L().Warn("Stopped consuming IP messages", zap.String("event", "consumer_stopped"),
+		zap.String("queue", r.ipQueue), zap.Int("pending_messages", pending), zap.Error(r.ctx.Err()))
+}
+
 func (r *RabbitMQManager) handleMessage(delivery amqp.Delivery) error {
 	var message domain.QueueMessage
 	err := json.Unmarshal(delivery.Body, &message)
@@ -134,201 +471,393 @@ func (r *RabbitMQManager) handleMessage(delivery amqp.Delivery) error {
 		return fmt.Errorf("no IP addresses in message")
 	}
 
+	// Scan each IP once even if the generator repeated it
+	if removed := message.RemoveDuplicateIPs(); removed > 0 {
+		log.L().Warn("Removed duplicate IPs from message", zap.String("event", "duplicate_ips_removed"),
+			zap.String("batch_id", message.BatchID), zap.Int("duplicates", removed), zap.Int("ip_count", len(message.IPs)))
+	}
+
+	// Cap the work a single message can create
+	if r.maxIPsPerMessage > 0 && len(message.IPs) > r.maxIPsPerMessage {
+		if r.oversizedAction != OversizedTruncate {
+			return r.rejectOversized(delivery, &message)
+		}
+		log.L().Warn("Truncating oversized IP message", zap.String("event", "oversized_message_truncated"),
+			zap.String("batch_id", message.BatchID), zap.Int("ip_count", len(message.IPs)), zap.Int("max_ips", r.maxIPsPerMessage))
+		message.IPs = message.IPs[:r.maxIPsPerMessage]
+		message.Count = len(message.IPs)
+	}
+
 	// Check if scan handler is set
 	if r.scanHandler == nil {
 		log.L().Error("Scan handler not set", zap.String("event", "handler_not_set"))
 		return fmt.Errorf("scan handler not configured")
 	}
 
-	// Process each IP in the message
-	for _, ip := range message.IPs {
+	// Record ownership of the batch while it is processed. A batch another worker holds is a
+	// redelivery of a message that worker is still scanning, so it is dropped; the broker
+	// redelivers that worker's copy if it dies.
+	if r.batchClaimer != nil && message.BatchID != "" {
+		release, claimed := r.claimBatch(message.BatchID)
+		if !claimed {
+			delivery.Ack(false)
+			return nil
+		}
+		defer release()
+	}
+
+	// Retries of every IP in the message come out of one budget
+	config := *r.scanConfig
+	config.RetryBudget = domain.NewRetryBudget(r.scanConfig.BatchRetryBudget)
+
+	// Scanning other ports than the generator asked for would be wrong, so a message with
+	// invalid ports is rejected rather than scanned on the defaults
+	if err := message.ApplyPorts(&config); err != nil {
+		log.L().Error("Rejecting message with invalid ports", zap.String("event", "invalid_message"),
+			zap.String("batch_id", message.BatchID), zap.Ints("ports", message.Ports), zap.Error(err))
+		return delivery.Nack(false, false)
+	}
+
+	// Scan the IPs of the message concurrently, each holding a scan slot; results are saved
+	// and published on the pipeline's goroutines while the next IPs are scanned
+	messageStart := time.Now()
+	pipeline := r.startResultPipeline(config.ResultBufferSize, config.ResultPublishers)
+	var scans sync.WaitGroup
+	interrupted := false
+	for i, ip := range message.IPs {
+		if err := r.ctx.Err(); err != nil {
+			log.L().Warn("Scanning stopped, skipping remaining IPs", zap.String("event", "ips_skipped"),
+				zap.String("batch_id", message.BatchID), zap.Int("skipped", len(message.IPs)-i), zap.Error(err))
+			interrupted = true
+			break
+		}
+
 		if ip == "" {
 			log.L().Warn("Skipping empty IP address", zap.String("event", "empty_ip_skipped"))
 			continue
 		}
 
-		// Perform the scan
-		startTime := time.Now()
-		result, err := r.scanHandler(ip, r.scanConfig, message.BatchID, r.workerID)
-		scanDuration := time.Since(startTime)
+		// Queued targets must follow the same public-address rules as the generator
+		if !netutil.IsPublicAddress(ip) {
+			log.L().Warn("Skipping non-public IP address", zap.String("event", "non_public_ip_skipped"), zap.String("ip", ip))
+			continue
+		}
 
+		release, err := r.acquireScanSlot()
 		if err != nil {
-			log.L().Error("Scan failed", zap.String("event", "scan_failed"),
-				zap.String("ip", ip), zap.Error(err), zap.Duration("duration", scanDuration))
-
-			// Create a failed result for tracking
-			failedResult := &domain.ScanResult{
-				IP:            ip,
-				Status:        domain.ScanStatusFailed,
-				IsUp:          false,
-				Error:         err.Error(),
-				ScanStartTime: startTime,
-				ScanEndTime:   time.Now(),
-				BatchID:       message.BatchID,
-				WorkerID:      r.workerID,
-			}
+			log.L().Warn("Scanning stopped, skipping remaining IPs", zap.String("event", "ips_skipped"),
+				zap.String("batch_id", message.BatchID), zap.Int("skipped", len(message.IPs)-i), zap.Error(err))
+			interrupted = true
+			break
+		}
 
-			// Save to MongoDB if available
-			if r.dbManager != nil {
-				if saveErr := r.dbManager.SaveScanResult(failedResult); saveErr != nil {
-					log.L().Error("Failed to save failed result to MongoDB", zap.String("event", "mongodb_save_failed"), zap.Error(saveErr))
-				}
-			}
+		// A scan keeps its slot until the pipeline takes its result, so a full buffer slows scanning
+		scans.Add(1)
+		go func(ip string) {
+			defer scans.Done()
+			defer release()
+			pipeline.put(r.scanIP(ip, &config, &message))
+		}(ip)
+	}
+	scans.Wait()
+	results := pipeline.close()
 
-			// Publish failed result
-			if pubErr := r.PublishScanResult(failedResult); pubErr != nil {
-				log.L().Error("Failed to publish failed result", zap.String("event", "publish_failed"), zap.Error(pubErr))
-			}
+	if config.RetryBudget.Exhausted() {
+		log.L().Warn("Batch retry budget exhausted", zap.String("event", "batch_retry_budget_exhausted"),
+			zap.String("batch_id", message.BatchID), zap.Int("budget", r.scanConfig.BatchRetryBudget))
+	}
 
-			// Publish enrichment message for failed scan
-			if pubErr := r.PublishEnrichmentMessage(ip, false, message.BatchID); pubErr != nil {
-				log.L().Error("Failed to publish enrichment message", zap.String("event", "enrichment_failed"), zap.Error(pubErr))
-			}
+	if r.messageHandler != nil && !interrupted {
+		r.messageHandler(&message, results, messageStart)
+	}
 
-			continue // Continue with next IP
-		}
+	// Acknowledge the message
+	delivery.Ack(false)
+	return nil
+}
 
-		// Set batch ID if not set
-		if result.BatchID == "" {
-			result.BatchID = message.BatchID
+// scanIP scans one IP of a message, turning a failed scan into a failed result so it is
+// tracked like any other
+func (r *RabbitMQManager) scanIP(ip string, config *domain.ScanConfig, message *domain.QueueMessage) *domain.ScanResult {
+	startTime := time.Now()
+	result, err := r.scanHandler(ip, config, message.BatchID, r.workerID)
+	scanDuration := time.Since(startTime)
+
+	if err != nil {
+		log.L().Error("Scan failed", zap.String("event", "scan_failed"),
+			zap.String("ip", ip), zap.Error(err), zap.Duration("duration", scanDuration))
+
+		failedResult := &domain.ScanResult{
+			IP:            ip,
+			Status:        domain.ScanStatusFailed,
+			IsUp:          false,
+			Error:         err.Error(),
+			ScanStartTime: startTime,
+			ScanEndTime:   time.Now(),
+			BatchID:       message.BatchID,
+			WorkerID:      r.workerID,
 		}
+		message.ApplyTo(failedResult)
+		return failedResult
+	}
 
-		log.L().Info("Scan completed successfully", zap.String("event", "scan_completed"),
-			zap.String("ip", result.IP), zap.Bool("is_up", result.IsUp),
-			zap.Int("open_ports", len(result.GetOpenPorts())), zap.Duration("duration", scanDuration))
+	// Set batch ID if not set
+	if result.BatchID == "" {
+		result.BatchID = message.BatchID
+	}
+	message.ApplyTo(result)
 
-		// Save to MongoDB if available
-		if r.dbManager != nil {
-			if saveErr := r.dbManager.SaveScanResult(result); saveErr != nil {
-				log.L().Error("Failed to save scan result to MongoDB", zap.String("event", "mongodb_save_failed"), zap.Error(saveErr))
-			}
-		}
+	log.L().Info("Scan completed successfully", zap.String("event", "scan_completed"),
+		zap.String("ip", result.IP), zap.Bool("is_up", result.IsUp),
+		zap.Int("open_ports", len(result.GetOpenPorts())), zap.Duration("duration", scanDuration))
+	return result
+}
 
-		// Publish scan result
-		if err := r.PublishScanResult(result); err != nil {
-			log.L().Error("Failed to publish scan result", zap.String("event", "publish_failed"), zap.Error(err))
-			continue
-		}
+// persistResult saves a result, if a sink is configured, and records its scan result,
+// enrichment, service analysis and change messages
+func (r *RabbitMQManager) persistResult(result *domain.ScanResult) {
+	// Compare with the previous scan before this result replaces it as the latest
+	change := r.detectChanges(result)
 
-		// Publish enrichment message
-		if err := r.PublishEnrichmentMessage(result.IP, result.IsUp, result.BatchID); err != nil {
-			log.L().Error("Failed to publish enrichment message", zap.String("event", "enrichment_failed"), zap.Error(err))
+	if r.resultSink != nil {
+		if err := r.resultSink.SaveScanResult(result); err != nil {
+			log.L().Error("Failed to save scan result", zap.String("event", "result_save_failed"),
+				zap.String("ip", result.IP), zap.String("status", string(result.Status)), zap.Error(err))
 		}
+	}
 
-		// Publish service analysis if there are open ports
-		openPorts := result.GetOpenPorts()
-		if len(openPorts) > 0 {
-			if err := r.PublishServiceAnalysis(result.IP, openPorts, result.BatchID); err != nil {
-				log.L().Error("Failed to publish service analysis", zap.String("event", "service_analysis_failed"), zap.Error(err))
-			}
+	r.publishResultMessages(result, change)
+}
+
+// publishResultMessages records the downstream messages for a result in the outbox,
+// which publishes them in order and retries failures. If they can't be recorded
+// they are published directly instead.
+func (r *RabbitMQManager) publishResultMessages(result *domain.ScanResult, change *domain.ScanChangeMessage) {
+	err := r.enqueueResultMessages(result, change)
+	if err == nil {
+		return
+	}
+	log.L().Error("Failed to record downstream messages, publishing directly", zap.String("event", "outbox_enqueue_failed"),
+		zap.String("ip", result.IP), zap.Error(err))
+
+	if err := r.PublishScanResult(result); err != nil {
+		log.L().Error("Failed to publish scan result", zap.String("event", "publish_failed"), zap.Error(err))
+		return
+	}
+	if err := r.PublishEnrichmentMessage(result.IP, result.IsUp, result.BatchID); err != nil {
+		log.L().Error("Failed to publish enrichment message", zap.String("event", "enrichment_failed"), zap.Error(err))
+	}
+	if openPorts := result.GetOpenPorts(); len(openPorts) > 0 {
+		if err := r.PublishServiceAnalysis(result.IP, openPorts, result.BatchID, result.Findings); err != nil {
+			log.L().Error("Failed to publish service analysis", zap.String("event", "service_analysis_failed"), zap.Error(err))
 		}
 	}
+	if change != nil {
+		if err := r.PublishScanChange(change); err != nil {
+			log.L().Error("Failed to publish scan change", zap.String("event", "scan_change_failed"), zap.Error(err))
+		}
+	}
+}
 
-	// Acknowledge the message
-	delivery.Ack(false)
+// ReplayScanResult publishes the scan result, enrichment and service analysis messages of a
+// stored result again through the outbox. Change messages aren't replayed since they describe
+// the difference from a scan that may no longer be the previous one.
+func (r *RabbitMQManager) ReplayScanResult(result *domain.ScanResult) error {
+	if err := r.enqueueResultMessages(result, nil); err != nil {
+		return fmt.Errorf("failed to replay scan result for %s: %w", result.IP, err)
+	}
+
+	log.L().Info("Scan result replayed", zap.String("event", "scan_result_replayed"), zap.String("ip", result.IP),
+		zap.String("batch_id", result.BatchID))
 	return nil
 }
 
-// PublishScanResult publishes a scan result to the scan result queue
-func (r *RabbitMQManager) PublishScanResult(result *domain.ScanResult) error {
-	message := domain.ScanResultMessage{
-		ScanResult: result,
-		Timestamp:  time.Now().Unix(),
-		WorkerID:   "port-scanner",
+// enqueueBatchSize is the number of IPs per message of an enqueued scan, unless the
+// per-message cap is lower
+const enqueueBatchSize = 100
+
+// EnqueueScan implements ScanEnqueuer, publishing to the IP queue this worker consumes
+func (r *RabbitMQManager) EnqueueScan(batchID string, ips []string, ports []int) (int, error) {
+	size := enqueueBatchSize
+	if r.maxIPsPerMessage > 0 {
+		size = min(size, r.maxIPsPerMessage)
 	}
 
-	body, err := json.Marshal(message)
-	if err != nil {
-		return err
+	messages := domain.NewBatchMessages(batchID, ips, ports, size)
+	for i, message := range messages {
+		body, err := json.Marshal(message)
+		if err != nil {
+			return i, fmt.Errorf("failed to marshal message %d of batch %s: %w", i, batchID, err)
+		}
+		if err := r.publish(r.ipQueue, ContentTypeJSON, body); err != nil {
+			return i, fmt.Errorf("failed to publish message %d of batch %s: %w", i, batchID, err)
+		}
 	}
 
-	err = r.channel.Publish(
-		"",
-		r.scanResultQueue,
-		false,
-		false,
+	log.L().Info("Enqueued scan", zap.String("event", "scan_enqueued"), zap.String("batch_id", batchID),
+		zap.Int("ip_count", len(ips)), zap.Int("messages", len(messages)))
+	return len(messages), nil
+}
+
+// publish sends a message body to a queue through the default exchange and waits for the
+// broker to confirm it. An empty content type is JSON.
+func (r *RabbitMQManager) publish(queue, contentType string, body []byte) error {
+	return r.publishRouted("", queue, contentType, body)
+}
+
+// publishRouted sends a message body to an exchange with a routing key and waits for the
+// broker to confirm it. An empty content type is JSON.
+func (r *RabbitMQManager) publishRouted(exchange, key, contentType string, body []byte) error {
+	if contentType == "" {
+		contentType = ContentTypeJSON
+	}
+	return r.publisher.publish(
+		exchange,
+		key,
 		amqp.Publishing{
-			ContentType: "application/json",
+			ContentType: contentType,
 			Body:        body,
 		},
 	)
+}
 
-	if err != nil {
-		return err
-	}
-
-	log.L().Info("Published scan result", zap.String("event", "scan_result_published"), zap.String("ip", result.IP))
-	return nil
+// scanResultBody encodes a scan result message in the configured format, returning its content type
+func (r *RabbitMQManager) scanResultBody(result *domain.ScanResult) ([]byte, string, error) {
+	return encodeMessage(r.messageFormat, domain.ScanResultMessage{
+		ScanResult: result,
+		Timestamp:  time.Now().Unix(),
+		WorkerID:   r.workerID,
+	})
 }
 
-// PublishEnrichmentMessage publishes an enrichment message
-func (r *RabbitMQManager) PublishEnrichmentMessage(ip string, isUp bool, batchID string) error {
-	message := domain.EnrichmentMessage{
+// enrichmentBody encodes an enrichment message in the configured format, returning its content type
+func (r *RabbitMQManager) enrichmentBody(ip string, isUp bool, batchID string) ([]byte, string, error) {
+	return encodeMessage(r.messageFormat, domain.EnrichmentMessage{
 		IP:        ip,
 		IsUp:      isUp,
 		BatchID:   batchID,
 		Timestamp: time.Now().Unix(),
+	})
+}
+
+// serviceAnalysisBody encodes a service analysis message in the configured format, returning its content type
+func (r *RabbitMQManager) serviceAnalysisBody(ip string, openPorts []*domain.Port, batchID string, findings []domain.Finding) ([]byte, string, error) {
+	return encodeMessage(r.messageFormat, domain.ServiceAnalysisMessage{
+		IP:        ip,
+		OpenPorts: openPorts,
+		BatchID:   batchID,
+		Timestamp: time.Now().Unix(),
+		Findings:  findings,
+	})
+}
+
+// changeBody encodes a scan change message
+func changeBody(change *domain.ScanChangeMessage) ([]byte, error) {
+	return json.Marshal(change)
+}
+
+// PublishScanChange publishes the changes found since a host's previous scan to the changes queue
+func (r *RabbitMQManager) PublishScanChange(change *domain.ScanChangeMessage) error {
+	body, err := changeBody(change)
+	if err != nil {
+		return fmt.Errorf("failed to marshal scan change: %w", err)
+	}
+
+	if err := r.publish(r.changesQueue, ContentTypeJSON, body); err != nil {
+		return fmt.Errorf("failed to publish scan change: %w", err)
+	}
+
+	log.L().Info("Published scan change", zap.String("event", "scan_change_published"), zap.String("ip", change.IP),
+		zap.Ints("added_ports", change.AddedPorts), zap.Ints("removed_ports", change.RemovedPorts),
+		zap.Int("version_changes", len(change.VersionChanges)))
+	return nil
+}
+
+// PublishBatchComplete announces a completed batch on the batch completion queue. It goes
+// through the outbox, after the result messages of the batch, and is published directly if it
+// can't be recorded.
+func (r *RabbitMQManager) PublishBatchComplete(message *domain.BatchCompleteMessage) error {
+	if r.batchCompleteQueue == "" {
+		return nil
 	}
 
 	body, err := json.Marshal(message)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to marshal batch completion: %w", err)
 	}
 
-	err = r.channel.Publish(
-		"",
-		r.enrichmentQueue,
-		false,
-		false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
-		},
-	)
+	outboxMessage := &domain.OutboxMessage{WorkerID: r.workerID, Queue: r.batchCompleteQueue, ContentType: ContentTypeJSON, Body: body, CreatedAt: time.Now()}
+	if err := r.outboxStore.Enqueue([]*domain.OutboxMessage{outboxMessage}); err == nil {
+		r.wakeOutbox()
+	} else {
+		log.L().Error("Failed to record batch completion, publishing directly", zap.String("event", "outbox_enqueue_failed"),
+			zap.String("batch_id", message.BatchID), zap.Error(err))
+		if err := r.publish(r.batchCompleteQueue, ContentTypeJSON, body); err != nil {
+			return fmt.Errorf("failed to publish batch completion: %w", err)
+		}
+	}
+
+	log.L().Info("Published batch completion", zap.String("event", "batch_complete_published"),
+		zap.String("batch_id", message.BatchID), zap.Int("ips_scanned", message.IPsScanned))
+	return nil
+}
 
+// PublishScanResult publishes a scan result to the scan result queue
+func (r *RabbitMQManager) PublishScanResult(result *domain.ScanResult) error {
+	body, contentType, err := r.scanResultBody(result)
 	if err != nil {
 		return err
 	}
 
-	log.L().Info("Published enrichment message", zap.String("event", "enrichment_published"), zap.String("ip", ip), zap.Bool("is_up", isUp))
+	exchange, key := r.route(r.resultRoutingKey, newRoutingValues(r.scanResultQueue, result.IP, result.BatchID, result.IsUp, result.GetOpenPorts()))
+	if err := r.publishRouted(exchange, key, contentType, body); err != nil {
+		return err
+	}
+
+	log.L().Info("Published scan result", zap.String("event", "scan_result_published"), zap.String("ip", result.IP))
 	return nil
 }
 
-// PublishServiceAnalysis publishes a service analysis message
-func (r *RabbitMQManager) PublishServiceAnalysis(ip string, openPorts []*domain.Port, batchID string) error {
-	message := domain.ServiceAnalysisMessage{
-		IP:        ip,
-		OpenPorts: openPorts,
-		BatchID:   batchID,
-		Timestamp: time.Now().Unix(),
+// PublishEnrichmentMessage publishes an enrichment message
+func (r *RabbitMQManager) PublishEnrichmentMessage(ip string, isUp bool, batchID string) error {
+	body, contentType, err := r.enrichmentBody(ip, isUp, batchID)
+	if err != nil {
+		return err
 	}
 
-	body, err := json.Marshal(message)
-	if err != nil {
+	exchange, key := r.route(r.enrichmentRoutingKey, newRoutingValues(r.enrichmentQueue, ip, batchID, isUp, nil))
+	if err := r.publishRouted(exchange, key, contentType, body); err != nil {
 		return err
 	}
 
-	err = r.channel.Publish(
-		"",
-		r.serviceAnalysisQueue,
-		false,
-		false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
-		},
-	)
+	log.L().Info("Published enrichment message", zap.String("event", "enrichment_published"), zap.String("ip", ip), zap.Bool("is_up", isUp))
+	return nil
+}
 
+// PublishServiceAnalysis publishes a service analysis message with the analyzers' findings
+func (r *RabbitMQManager) PublishServiceAnalysis(ip string, openPorts []*domain.Port, batchID string, findings []domain.Finding) error {
+	body, contentType, err := r.serviceAnalysisBody(ip, openPorts, batchID, findings)
 	if err != nil {
 		return err
 	}
 
+	exchange, key := r.route(r.serviceAnalysisRoutingKey, newRoutingValues(r.serviceAnalysisQueue, ip, batchID, true, openPorts))
+	if err := r.publishRouted(exchange, key, contentType, body); err != nil {
+		return err
+	}
+
 	log.L().Info("Published service analysis", zap.String("event", "service_analysis_published"), zap.String("ip", ip), zap.Int("open_ports", len(openPorts)))
 	return nil
 }
 
 // Close closes the RabbitMQ connection
 func (r *RabbitMQManager) Close() error {
+	// Let deliveries being handled finish recording their results
+	r.handling.Wait()
+
+	// Give the outbox a last chance to publish before the channel closes
+	r.stopOutbox()
+
 	if r.channel != nil {
 		if err := r.channel.Close(); err != nil {
 			return fmt.Errorf("failed to close channel: %w", err)
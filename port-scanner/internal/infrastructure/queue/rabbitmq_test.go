@@ -0,0 +1,185 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"port-scanner/internal/domain"
+	"port-scanner/internal/testutil"
+	"port-scanner/pkg/log"
+
+	"github.com/streadway/amqp"
+)
+
+func TestMain(m *testing.M) {
+	log.InitLogger("queue-test")
+	os.Exit(m.Run())
+}
+
+// fakeAcknowledger records how deliveries were settled
+type fakeAcknowledger struct {
+	mu      sync.Mutex
+	acks    int
+	nacks   int
+	requeue bool
+}
+
+func (a *fakeAcknowledger) Ack(tag uint64, multiple bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.acks++
+	return nil
+}
+
+func (a *fakeAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.nacks++
+	a.requeue = requeue
+	return nil
+}
+
+func (a *fakeAcknowledger) Reject(tag uint64, requeue bool) error {
+	return a.Nack(tag, false, requeue)
+}
+
+// newTestManager builds a manager without a broker connection; downstream messages stay in
+// its in-memory outbox since the outbox publisher is never started
+func newTestManager() *RabbitMQManager {
+	return &RabbitMQManager{
+		ipQueue:              "ips",
+		scanResultQueue:      "results",
+		enrichmentQueue:      "enrichment",
+		serviceAnalysisQueue: "analysis",
+		workerID:             "worker-1",
+		scanConfig:           domain.NewDefaultScanConfig(),
+		ctx:                  context.Background(),
+		outboxStore:          newMemoryOutboxStore(),
+		outboxWake:           make(chan struct{}, 1),
+		outboxStop:           make(chan struct{}),
+		messageFormat:        MessageFormatJSON,
+	}
+}
+
+// newDelivery encodes a queue message as a delivery settled through ack
+func newDelivery(t *testing.T, message *domain.QueueMessage, ack *fakeAcknowledger) amqp.Delivery {
+	t.Helper()
+	body, err := json.Marshal(message)
+	if err != nil {
+		t.Fatalf("failed to marshal message: %v", err)
+	}
+	return amqp.Delivery{Acknowledger: ack, Body: body}
+}
+
+// recordingScanner is a scan handler that records the IPs it was asked to scan
+type recordingScanner struct {
+	mu  sync.Mutex
+	ips []string
+}
+
+func (s *recordingScanner) scan(ip string, config *domain.ScanConfig, batchID, workerID string) (*domain.ScanResult, error) {
+	s.mu.Lock()
+	s.ips = append(s.ips, ip)
+	s.mu.Unlock()
+	return &domain.ScanResult{IP: ip, Status: domain.ScanStatusCompleted, IsUp: true, BatchID: batchID, WorkerID: workerID}, nil
+}
+
+func (s *recordingScanner) scanned() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.ips...)
+}
+
+func TestHandleMessageScansPublicIPv4AndIPv6(t *testing.T) {
+	r := newTestManager()
+	scanner := &recordingScanner{}
+	r.SetScanHandler(scanner.scan)
+
+	ack := &fakeAcknowledger{}
+	message := &domain.QueueMessage{
+		BatchID: "batch-1",
+		IPs:     []string{"8.8.8.8", "10.0.0.1", "2001:4860:4860::8888", "fd00::1", "2001:db8::1", "fe80::1"},
+	}
+	if err := r.handleMessage(newDelivery(t, message, ack)); err != nil {
+		t.Fatalf("handleMessage returned error: %v", err)
+	}
+
+	got := scanner.scanned()
+	want := map[string]bool{"8.8.8.8": true, "2001:4860:4860::8888": true}
+	if len(got) != len(want) {
+		t.Fatalf("scanned %v, want only %v", got, want)
+	}
+	for _, ip := range got {
+		if !want[ip] {
+			t.Errorf("scanned non-public address %s", ip)
+		}
+	}
+	if ack.acks != 1 {
+		t.Errorf("message acked %d times, want 1", ack.acks)
+	}
+}
+
+func TestHandleMessageScansDuplicateIPsOnce(t *testing.T) {
+	r := newTestManager()
+	scanner := &recordingScanner{}
+	r.SetScanHandler(scanner.scan)
+
+	var handled *domain.QueueMessage
+	r.SetMessageHandler(func(message *domain.QueueMessage, results []*domain.ScanResult, startedAt time.Time) {
+		handled = message
+	})
+
+	message := &domain.QueueMessage{
+		BatchID: "batch-1",
+		IPs:     []string{"8.8.8.8", "1.1.1.1", "8.8.8.8", "9.9.9.9", "1.1.1.1", "8.8.8.8"},
+		Count:   6,
+	}
+	if err := r.handleMessage(newDelivery(t, message, &fakeAcknowledger{})); err != nil {
+		t.Fatalf("handleMessage returned error: %v", err)
+	}
+
+	counts := make(map[string]int)
+	for _, ip := range scanner.scanned() {
+		counts[ip]++
+	}
+	for _, ip := range []string{"8.8.8.8", "1.1.1.1", "9.9.9.9"} {
+		if counts[ip] != 1 {
+			t.Errorf("%s scanned %d times, want 1", ip, counts[ip])
+		}
+	}
+	if len(counts) != 3 {
+		t.Errorf("scanned %v, want the 3 unique IPs", counts)
+	}
+	if handled == nil || len(handled.IPs) != 3 {
+		t.Errorf("message handler got %v, want the deduplicated message", handled)
+	}
+}
+
+func TestHandleMessageCopiesTagsIntoResults(t *testing.T) {
+	r := newTestManager()
+	scanner := &recordingScanner{}
+	r.SetScanHandler(scanner.scan)
+	sink := testutil.NewFakeSink()
+	r.SetResultSink(sink)
+
+	// A message as the IP generator publishes it
+	body := `{"ips": ["8.8.8.8", "1.1.1.1"], "batch_id": "batch-1", "count": 2, "tags": {"campaign": "q3-audit"}, "priority": 5}`
+	ack := &fakeAcknowledger{}
+	if err := r.handleMessage(amqp.Delivery{Acknowledger: ack, Body: []byte(body)}); err != nil {
+		t.Fatalf("handleMessage returned error: %v", err)
+	}
+
+	results := sink.Results()
+	if len(results) != 2 {
+		t.Fatalf("saved %d results, want 2", len(results))
+	}
+	for _, result := range results {
+		if result.Tags["campaign"] != "q3-audit" || result.Priority != 5 {
+			t.Errorf("result for %s has tags %v and priority %d, want the message's", result.IP, result.Tags, result.Priority)
+		}
+	}
+}
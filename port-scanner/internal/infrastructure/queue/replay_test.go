@@ -0,0 +1,56 @@
+package queue
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"port-scanner/internal/domain"
+)
+
+func TestReplayScanResultRecordsResultMessages(t *testing.T) {
+	r := newTestManager()
+	r.changesQueue = "changes"
+	r.SetScanHistory(staticHistory{"8.8.8.8": {22: ""}})
+
+	result := openResult("8.8.8.8", 443)
+	if err := r.ReplayScanResult(result); err != nil {
+		t.Fatalf("ReplayScanResult returned error: %v", err)
+	}
+
+	pending, err := r.outboxStore.Pending(r.workerID, 100)
+	if err != nil {
+		t.Fatalf("Pending returned error: %v", err)
+	}
+	var queues []string
+	for _, message := range pending {
+		queues = append(queues, message.Queue)
+	}
+	// The history differs, but a replay doesn't announce changes
+	if want := []string{"results", "enrichment", "analysis"}; !reflect.DeepEqual(queues, want) {
+		t.Fatalf("replay recorded messages for %v, want %v", queues, want)
+	}
+
+	var published domain.ScanResultMessage
+	if err := json.Unmarshal(pending[0].Body, &published); err != nil {
+		t.Fatalf("failed to decode scan result message: %v", err)
+	}
+	if published.ScanResult == nil || published.ScanResult.IP != "8.8.8.8" || published.ScanResult.BatchID != "batch-2" {
+		t.Errorf("replayed scan result %+v, want 8.8.8.8 from batch-2", published.ScanResult)
+	}
+}
+
+func TestReplayScanResultSkipsAnalysisWithoutOpenPorts(t *testing.T) {
+	r := newTestManager()
+	if err := r.ReplayScanResult(openResult("8.8.8.8")); err != nil {
+		t.Fatalf("ReplayScanResult returned error: %v", err)
+	}
+
+	pending, err := r.outboxStore.Pending(r.workerID, 100)
+	if err != nil {
+		t.Fatalf("Pending returned error: %v", err)
+	}
+	if len(pending) != 2 || pending[0].Queue != "results" || pending[1].Queue != "enrichment" {
+		t.Errorf("replay recorded %d messages, want the scan result and enrichment messages only", len(pending))
+	}
+}
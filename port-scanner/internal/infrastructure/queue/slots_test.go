@@ -0,0 +1,132 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"port-scanner/internal/domain"
+)
+
+// concurrencyProbe is a scan handler that records how many scans run at once
+type concurrencyProbe struct {
+	active  atomic.Int32
+	maximum atomic.Int32
+	scans   atomic.Int32
+}
+
+func (p *concurrencyProbe) scan(ip string, config *domain.ScanConfig, batchID, workerID string) (*domain.ScanResult, error) {
+	n := p.active.Add(1)
+	for {
+		max := p.maximum.Load()
+		if n <= max || p.maximum.CompareAndSwap(max, n) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	p.active.Add(-1)
+	p.scans.Add(1)
+	return &domain.ScanResult{IP: ip, Status: domain.ScanStatusCompleted, BatchID: batchID}, nil
+}
+
+// semaphoreSlots returns a scan slot source allowing limit scans at once
+func semaphoreSlots(limit int) func(context.Context) (func(), error) {
+	slots := make(chan struct{}, limit)
+	return func(ctx context.Context) (func(), error) {
+		select {
+		case slots <- struct{}{}:
+			return func() { <-slots }, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func TestConcurrentMessagesShareScanSlots(t *testing.T) {
+	const (
+		limit    = 3
+		messages = 5
+		perMsg   = 4
+	)
+
+	r := newTestManager()
+	probe := &concurrencyProbe{}
+	r.SetScanHandler(probe.scan)
+	r.SetScanSlots(semaphoreSlots(limit))
+
+	var wg sync.WaitGroup
+	acks := make([]*fakeAcknowledger, messages)
+	for m := 0; m < messages; m++ {
+		ips := make([]string, perMsg)
+		for i := range ips {
+			ips[i] = fmt.Sprintf("8.8.%d.%d", m+1, i+1)
+		}
+		acks[m] = &fakeAcknowledger{}
+		delivery := newDelivery(t, &domain.QueueMessage{BatchID: fmt.Sprintf("batch-%d", m), IPs: ips}, acks[m])
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := r.handleMessage(delivery); err != nil {
+				t.Errorf("handleMessage returned error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := probe.scans.Load(); got != messages*perMsg {
+		t.Errorf("ran %d scans, want %d", got, messages*perMsg)
+	}
+	if got := probe.maximum.Load(); got > limit {
+		t.Errorf("%d scans ran at once across messages, want at most %d", got, limit)
+	}
+	if got := probe.maximum.Load(); got < 2 {
+		t.Errorf("at most %d scan ran at once, want messages scanned concurrently", got)
+	}
+	for m, ack := range acks {
+		if ack.acks != 1 {
+			t.Errorf("message %d acked %d times, want 1", m, ack.acks)
+		}
+	}
+}
+
+func TestHandleMessageScansOneAtATimeWithoutSlots(t *testing.T) {
+	r := newTestManager()
+	probe := &concurrencyProbe{}
+	r.SetScanHandler(probe.scan)
+
+	ips := []string{"8.8.8.8", "8.8.4.4", "1.1.1.1"}
+	if err := r.handleMessage(newDelivery(t, &domain.QueueMessage{IPs: ips}, &fakeAcknowledger{})); err != nil {
+		t.Fatalf("handleMessage returned error: %v", err)
+	}
+
+	if got := probe.maximum.Load(); got != 1 {
+		t.Errorf("%d scans ran at once without a slot source, want 1", got)
+	}
+}
+
+func TestHandleMessageStopsWhenSlotsAreUnavailable(t *testing.T) {
+	r := newTestManager()
+	probe := &concurrencyProbe{}
+	r.SetScanHandler(probe.scan)
+	r.SetScanSlots(func(ctx context.Context) (func(), error) {
+		return nil, domain.ErrEngineStopped
+	})
+
+	var handled bool
+	r.SetMessageHandler(func(*domain.QueueMessage, []*domain.ScanResult, time.Time) { handled = true })
+
+	ack := &fakeAcknowledger{}
+	if err := r.handleMessage(newDelivery(t, &domain.QueueMessage{IPs: []string{"8.8.8.8"}}, ack)); err != nil {
+		t.Fatalf("handleMessage returned error: %v", err)
+	}
+	if probe.scans.Load() != 0 {
+		t.Errorf("ran %d scans without a slot, want 0", probe.scans.Load())
+	}
+	if handled {
+		t.Error("message handler called for an interrupted message")
+	}
+}
@@ -0,0 +1,44 @@
+package queue
+
+import (
+	"encoding/json"
+	"testing"
+
+	"port-scanner/internal/domain"
+	"port-scanner/internal/testutil"
+)
+
+func TestWorkerIDPropagatesToResultsAndMessages(t *testing.T) {
+	r := newTestManager()
+	r.workerID = "scanner-eu-1"
+	scanner := &recordingScanner{}
+	r.SetScanHandler(scanner.scan)
+	sink := testutil.NewFakeSink()
+	r.SetResultSink(sink)
+
+	message := &domain.QueueMessage{BatchID: "batch-1", IPs: []string{"8.8.8.8"}}
+	if err := r.handleMessage(newDelivery(t, message, &fakeAcknowledger{})); err != nil {
+		t.Fatalf("handleMessage returned error: %v", err)
+	}
+
+	results := sink.Results()
+	if len(results) != 1 || results[0].WorkerID != "scanner-eu-1" {
+		t.Fatalf("saved results %v, want one scanned by scanner-eu-1", results)
+	}
+
+	pending, err := r.outboxStore.Pending(r.workerID, 100)
+	if err != nil {
+		t.Fatalf("Pending returned error: %v", err)
+	}
+	if len(pending) == 0 || pending[0].Queue != "results" || pending[0].WorkerID != "scanner-eu-1" {
+		t.Fatalf("outbox holds %v, want the scan result message owned by scanner-eu-1", pending)
+	}
+	var published domain.ScanResultMessage
+	if err := json.Unmarshal(pending[0].Body, &published); err != nil {
+		t.Fatalf("failed to decode scan result message: %v", err)
+	}
+	if published.WorkerID != "scanner-eu-1" || published.ScanResult.WorkerID != "scanner-eu-1" {
+		t.Errorf("published worker IDs %q/%q, want scanner-eu-1 on the message and its result",
+			published.WorkerID, published.ScanResult.WorkerID)
+	}
+}
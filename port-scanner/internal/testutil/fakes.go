@@ -0,0 +1,303 @@
+// Package testutil provides in-memory fakes of the scanner's domain interfaces
+// so HTTP handlers and queue consumers can be exercised without networking,
+// RabbitMQ or a database.
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"port-scanner/internal/domain"
+)
+
+// FakeScanner is an in-memory Scanner that returns canned results
+type FakeScanner struct {
+	mu sync.Mutex
+
+	// Results holds the result returned for each IP; unknown IPs get a completed scan with no open ports
+	Results map[string]*domain.ScanResult
+	// Errors holds the error returned for each IP
+	Errors map[string]error
+	// Calls records the IPs passed to ScanIP, in call order
+	Calls []string
+	// Configs records the scan options passed to ScanIP, in call order
+	Configs []*domain.ScanConfig
+	// WorkerIDs records the worker IDs passed to ScanIP, in call order
+	WorkerIDs []string
+	// TechniqueErrors holds the error CheckTechnique returns for each technique; others are supported
+	TechniqueErrors map[string]error
+}
+
+// Ensure FakeScanner implements Scanner interface
+var _ domain.Scanner = (*FakeScanner)(nil)
+
+// NewFakeScanner creates an empty fake scanner
+func NewFakeScanner() *FakeScanner {
+	return &FakeScanner{
+		Results:         make(map[string]*domain.ScanResult),
+		Errors:          make(map[string]error),
+		TechniqueErrors: make(map[string]error),
+	}
+}
+
+// AddOpenPorts registers a completed result for an IP with the given ports open
+func (f *FakeScanner) AddOpenPorts(ip string, ports ...int) *domain.ScanResult {
+	result := domain.NewScanResult(ip, "", "")
+	result.IsUp = true
+	for _, number := range ports {
+		port := domain.NewPort(number)
+		port.Status = domain.PortStatusOpen
+		result.AddPort(port)
+	}
+	result.SetCompleted()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Results[ip] = result
+	return result
+}
+
+// PingHost reports every host as up
+func (f *FakeScanner) PingHost(ip string) (bool, time.Duration, error) {
+	return true, time.Millisecond, nil
+}
+
+// ScanPort returns the port from the IP's canned result, or a closed port
+func (f *FakeScanner) ScanPort(ip string, port int) (*domain.Port, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if result, ok := f.Results[ip]; ok {
+		for _, p := range result.Ports {
+			if p.Number == port {
+				return p, nil
+			}
+		}
+	}
+	return domain.NewPort(port), nil
+}
+
+// ScanPorts scans each port with ScanPort
+func (f *FakeScanner) ScanPorts(ip string, ports []int) ([]*domain.Port, error) {
+	results := make([]*domain.Port, 0, len(ports))
+	for _, port := range ports {
+		result, _ := f.ScanPort(ip, port)
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// GetBanner always fails; register banners on the canned result's ports instead
+func (f *FakeScanner) GetBanner(ip string, port int) (*domain.BannerInfo, error) {
+	return nil, fmt.Errorf("no banner for %s:%d", ip, port)
+}
+
+// ScanIP returns the canned result or error for an IP and records the call
+func (f *FakeScanner) ScanIP(ip string, config *domain.ScanConfig, batchID string, workerID string) (*domain.ScanResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.Calls = append(f.Calls, ip)
+	f.Configs = append(f.Configs, config)
+	f.WorkerIDs = append(f.WorkerIDs, workerID)
+
+	if err, ok := f.Errors[ip]; ok {
+		result := domain.NewScanResult(ip, batchID, workerID)
+		result.SetFailed(err.Error())
+		return result, err
+	}
+
+	if canned, ok := f.Results[ip]; ok {
+		result := *canned
+		result.BatchID = batchID
+		result.WorkerID = workerID
+		if config != nil && config.PriorityFirst && config.OnPriorityPorts != nil {
+			f.reportPriorityPorts(ip, &result, config)
+		}
+		return &result, nil
+	}
+
+	result := domain.NewScanResult(ip, batchID, workerID)
+	result.IsUp = true
+	result.SetCompleted()
+	return result, nil
+}
+
+// reportPriorityPorts passes the canned ports found in the config's priority ports to its
+// OnPriorityPorts callback, as a priority-first scan does before the remaining ports
+func (f *FakeScanner) reportPriorityPorts(ip string, result *domain.ScanResult, config *domain.ScanConfig) {
+	isPriority := make(map[int]bool, len(config.PriorityPorts))
+	for _, port := range config.PriorityPorts {
+		isPriority[port] = true
+	}
+	var priority []*domain.Port
+	for _, port := range result.Ports {
+		if isPriority[port.Number] {
+			priority = append(priority, port)
+		}
+	}
+	if len(priority) == 0 {
+		return
+	}
+
+	// The callback may block on the caller, so it runs without holding the lock
+	f.mu.Unlock()
+	defer f.mu.Lock()
+	config.OnPriorityPorts(ip, priority)
+}
+
+// CheckTechnique returns the error registered for a technique, if any
+func (f *FakeScanner) CheckTechnique(technique string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.TechniqueErrors[technique]
+}
+
+// FakeScanEngine is an in-memory ScanEngine that serves results registered with AddResult
+type FakeScanEngine struct {
+	mu      sync.RWMutex
+	config  *domain.ScanConfig
+	stats   *domain.ScanStats
+	results map[string]*domain.ScanResult
+	running bool
+}
+
+// Ensure FakeScanEngine implements ScanEngine interface
+var _ domain.ScanEngine = (*FakeScanEngine)(nil)
+
+// NewFakeScanEngine creates a fake engine using the given config, or the default config if nil
+func NewFakeScanEngine(config *domain.ScanConfig) *FakeScanEngine {
+	if config == nil {
+		config = domain.NewDefaultScanConfig()
+	}
+	return &FakeScanEngine{
+		config:  config,
+		stats:   domain.NewScanStats(),
+		results: make(map[string]*domain.ScanResult),
+	}
+}
+
+// AddResult registers a result returned by GetScanStatus
+func (e *FakeScanEngine) AddResult(result *domain.ScanResult) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.results[result.IP] = result
+}
+
+// StartScanning marks the engine as running
+func (e *FakeScanEngine) StartScanning() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.running = true
+	return nil
+}
+
+// StopScanning marks the engine as stopped
+func (e *FakeScanEngine) StopScanning() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.running = false
+}
+
+// GetScanStatus returns a result registered with AddResult
+func (e *FakeScanEngine) GetScanStatus(ip string) (*domain.ScanResult, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	result, ok := e.results[ip]
+	if !ok {
+		return nil, fmt.Errorf("no scan result found for IP: %s", ip)
+	}
+	return result, nil
+}
+
+// GetScanStats returns a copy of the engine statistics
+func (e *FakeScanEngine) GetScanStats() *domain.ScanStats {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	stats := *e.stats
+	return &stats
+}
+
+// RecordResult counts a result in the engine statistics
+func (e *FakeScanEngine) RecordResult(result *domain.ScanResult) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.stats.UpdateStats(result)
+}
+
+// ScanConfig returns a copy of the engine config
+func (e *FakeScanEngine) ScanConfig() *domain.ScanConfig {
+	config := *e.config
+	return &config
+}
+
+// ActiveScans always reports no scans in flight
+func (e *FakeScanEngine) ActiveScans() int {
+	return 0
+}
+
+// AcquireScanSlot never waits
+func (e *FakeScanEngine) AcquireScanSlot(ctx context.Context) (func(), error) {
+	return func() {}, nil
+}
+
+// Deadline reports that no deadline is configured
+func (e *FakeScanEngine) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// FakeSink is an in-memory ResultSink that keeps every saved result
+type FakeSink struct {
+	mu      sync.Mutex
+	results []*domain.ScanResult
+	closed  bool
+
+	// Err, when set, is returned by SaveScanResult
+	Err error
+}
+
+// Ensure FakeSink implements ResultSink interface
+var _ domain.ResultSink = (*FakeSink)(nil)
+
+// NewFakeSink creates an empty fake sink
+func NewFakeSink() *FakeSink {
+	return &FakeSink{}
+}
+
+// SaveScanResult stores the result unless Err is set
+func (s *FakeSink) SaveScanResult(result *domain.ScanResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return s.Err
+	}
+	s.results = append(s.results, result)
+	return nil
+}
+
+// Close marks the sink as closed
+func (s *FakeSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+// Results returns the saved results in the order they were saved
+func (s *FakeSink) Results() []*domain.ScanResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*domain.ScanResult(nil), s.results...)
+}
+
+// Closed reports whether Close was called
+func (s *FakeSink) Closed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
@@ -29,11 +29,12 @@ func InitLogger(service string) {
 		if err != nil {
 			panic(err)
 		}
-		logger = l.With(zap.String("service", service), zap.String("instance_id", getInstanceID()))
+		logger = l.With(zap.String("service", service), zap.String("instance_id", InstanceID()))
 	})
 }
 
-func getInstanceID() string {
+// InstanceID identifies this process in logs; it is the hostname, or "unknown" if that can't be read
+func InstanceID() string {
 	if instanceID != "" {
 		return instanceID
 	}
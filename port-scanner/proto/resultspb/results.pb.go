@@ -0,0 +1,826 @@
+// Messages published by the port scanner on the result, enrichment and service analysis
+// queues when rabbitmq.message_format is "protobuf". They carry the AMQP content type
+// application/x-protobuf, while JSON messages carry application/json.
+//
+// Go types are generated into proto/resultspb with `make proto`; regenerate them after
+// changing this file. internal/infrastructure/queue/protobuf.go converts them to and from
+// the domain messages.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: proto/results.proto
+
+package resultspb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type BannerInfo struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	RawBanner       string                 `protobuf:"bytes,1,opt,name=raw_banner,json=rawBanner,proto3" json:"raw_banner,omitempty"`
+	Service         string                 `protobuf:"bytes,2,opt,name=service,proto3" json:"service,omitempty"`
+	Protocol        string                 `protobuf:"bytes,3,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	Version         string                 `protobuf:"bytes,4,opt,name=version,proto3" json:"version,omitempty"`
+	Confidence      string                 `protobuf:"bytes,5,opt,name=confidence,proto3" json:"confidence,omitempty"`
+	MetadataJson    []byte                 `protobuf:"bytes,6,opt,name=metadata_json,json=metadataJson,proto3" json:"metadata_json,omitempty"`           // JSON object of module-specific details
+	ConfidenceScore int32                  `protobuf:"varint,7,opt,name=confidence_score,json=confidenceScore,proto3" json:"confidence_score,omitempty"` // 0-100
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *BannerInfo) Reset() {
+	*x = BannerInfo{}
+	mi := &file_proto_results_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BannerInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BannerInfo) ProtoMessage() {}
+
+func (x *BannerInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_results_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BannerInfo.ProtoReflect.Descriptor instead.
+func (*BannerInfo) Descriptor() ([]byte, []int) {
+	return file_proto_results_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *BannerInfo) GetRawBanner() string {
+	if x != nil {
+		return x.RawBanner
+	}
+	return ""
+}
+
+func (x *BannerInfo) GetService() string {
+	if x != nil {
+		return x.Service
+	}
+	return ""
+}
+
+func (x *BannerInfo) GetProtocol() string {
+	if x != nil {
+		return x.Protocol
+	}
+	return ""
+}
+
+func (x *BannerInfo) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *BannerInfo) GetConfidence() string {
+	if x != nil {
+		return x.Confidence
+	}
+	return ""
+}
+
+func (x *BannerInfo) GetMetadataJson() []byte {
+	if x != nil {
+		return x.MetadataJson
+	}
+	return nil
+}
+
+func (x *BannerInfo) GetConfidenceScore() int32 {
+	if x != nil {
+		return x.ConfidenceScore
+	}
+	return 0
+}
+
+type Port struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Number         int32                  `protobuf:"varint,1,opt,name=number,proto3" json:"number,omitempty"`
+	Status         string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	Service        string                 `protobuf:"bytes,3,opt,name=service,proto3" json:"service,omitempty"`
+	Banner         string                 `protobuf:"bytes,4,opt,name=banner,proto3" json:"banner,omitempty"`
+	Version        string                 `protobuf:"bytes,5,opt,name=version,proto3" json:"version,omitempty"`
+	ScanTime       *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=scan_time,json=scanTime,proto3" json:"scan_time,omitempty"`
+	ResponseTimeNs int64                  `protobuf:"varint,7,opt,name=response_time_ns,json=responseTimeNs,proto3" json:"response_time_ns,omitempty"`
+	BannerInfo     *BannerInfo            `protobuf:"bytes,8,opt,name=banner_info,json=bannerInfo,proto3" json:"banner_info,omitempty"`
+	Error          string                 `protobuf:"bytes,9,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Port) Reset() {
+	*x = Port{}
+	mi := &file_proto_results_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Port) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Port) ProtoMessage() {}
+
+func (x *Port) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_results_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Port.ProtoReflect.Descriptor instead.
+func (*Port) Descriptor() ([]byte, []int) {
+	return file_proto_results_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Port) GetNumber() int32 {
+	if x != nil {
+		return x.Number
+	}
+	return 0
+}
+
+func (x *Port) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Port) GetService() string {
+	if x != nil {
+		return x.Service
+	}
+	return ""
+}
+
+func (x *Port) GetBanner() string {
+	if x != nil {
+		return x.Banner
+	}
+	return ""
+}
+
+func (x *Port) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *Port) GetScanTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ScanTime
+	}
+	return nil
+}
+
+func (x *Port) GetResponseTimeNs() int64 {
+	if x != nil {
+		return x.ResponseTimeNs
+	}
+	return 0
+}
+
+func (x *Port) GetBannerInfo() *BannerInfo {
+	if x != nil {
+		return x.BannerInfo
+	}
+	return nil
+}
+
+func (x *Port) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type Finding struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Analyzer      string                 `protobuf:"bytes,1,opt,name=analyzer,proto3" json:"analyzer,omitempty"`
+	Port          int32                  `protobuf:"varint,2,opt,name=port,proto3" json:"port,omitempty"`
+	Service       string                 `protobuf:"bytes,3,opt,name=service,proto3" json:"service,omitempty"`
+	Severity      string                 `protobuf:"bytes,4,opt,name=severity,proto3" json:"severity,omitempty"`
+	Title         string                 `protobuf:"bytes,5,opt,name=title,proto3" json:"title,omitempty"`
+	Detail        string                 `protobuf:"bytes,6,opt,name=detail,proto3" json:"detail,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Finding) Reset() {
+	*x = Finding{}
+	mi := &file_proto_results_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Finding) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Finding) ProtoMessage() {}
+
+func (x *Finding) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_results_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Finding.ProtoReflect.Descriptor instead.
+func (*Finding) Descriptor() ([]byte, []int) {
+	return file_proto_results_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Finding) GetAnalyzer() string {
+	if x != nil {
+		return x.Analyzer
+	}
+	return ""
+}
+
+func (x *Finding) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *Finding) GetService() string {
+	if x != nil {
+		return x.Service
+	}
+	return ""
+}
+
+func (x *Finding) GetSeverity() string {
+	if x != nil {
+		return x.Severity
+	}
+	return ""
+}
+
+func (x *Finding) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Finding) GetDetail() string {
+	if x != nil {
+		return x.Detail
+	}
+	return ""
+}
+
+type ScanResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ip            string                 `protobuf:"bytes,1,opt,name=ip,proto3" json:"ip,omitempty"`
+	IsUp          bool                   `protobuf:"varint,2,opt,name=is_up,json=isUp,proto3" json:"is_up,omitempty"`
+	PingTimeNs    int64                  `protobuf:"varint,3,opt,name=ping_time_ns,json=pingTimeNs,proto3" json:"ping_time_ns,omitempty"`
+	ScanStartTime *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=scan_start_time,json=scanStartTime,proto3" json:"scan_start_time,omitempty"`
+	ScanEndTime   *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=scan_end_time,json=scanEndTime,proto3" json:"scan_end_time,omitempty"`
+	Ports         []*Port                `protobuf:"bytes,6,rep,name=ports,proto3" json:"ports,omitempty"`
+	Status        string                 `protobuf:"bytes,7,opt,name=status,proto3" json:"status,omitempty"`
+	Error         string                 `protobuf:"bytes,8,opt,name=error,proto3" json:"error,omitempty"`
+	BatchId       string                 `protobuf:"bytes,9,opt,name=batch_id,json=batchId,proto3" json:"batch_id,omitempty"`
+	WorkerId      string                 `protobuf:"bytes,10,opt,name=worker_id,json=workerId,proto3" json:"worker_id,omitempty"`
+	Tags          map[string]string      `protobuf:"bytes,11,rep,name=tags,proto3" json:"tags,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Priority      int32                  `protobuf:"varint,12,opt,name=priority,proto3" json:"priority,omitempty"`
+	RetentionNs   int64                  `protobuf:"varint,13,opt,name=retention_ns,json=retentionNs,proto3" json:"retention_ns,omitempty"`
+	MetadataJson  []byte                 `protobuf:"bytes,14,opt,name=metadata_json,json=metadataJson,proto3" json:"metadata_json,omitempty"` // JSON object written by the enrichers
+	ReverseDns    []string               `protobuf:"bytes,15,rep,name=reverse_dns,json=reverseDns,proto3" json:"reverse_dns,omitempty"`
+	Findings      []*Finding             `protobuf:"bytes,16,rep,name=findings,proto3" json:"findings,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScanResult) Reset() {
+	*x = ScanResult{}
+	mi := &file_proto_results_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScanResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScanResult) ProtoMessage() {}
+
+func (x *ScanResult) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_results_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScanResult.ProtoReflect.Descriptor instead.
+func (*ScanResult) Descriptor() ([]byte, []int) {
+	return file_proto_results_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ScanResult) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *ScanResult) GetIsUp() bool {
+	if x != nil {
+		return x.IsUp
+	}
+	return false
+}
+
+func (x *ScanResult) GetPingTimeNs() int64 {
+	if x != nil {
+		return x.PingTimeNs
+	}
+	return 0
+}
+
+func (x *ScanResult) GetScanStartTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ScanStartTime
+	}
+	return nil
+}
+
+func (x *ScanResult) GetScanEndTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ScanEndTime
+	}
+	return nil
+}
+
+func (x *ScanResult) GetPorts() []*Port {
+	if x != nil {
+		return x.Ports
+	}
+	return nil
+}
+
+func (x *ScanResult) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *ScanResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *ScanResult) GetBatchId() string {
+	if x != nil {
+		return x.BatchId
+	}
+	return ""
+}
+
+func (x *ScanResult) GetWorkerId() string {
+	if x != nil {
+		return x.WorkerId
+	}
+	return ""
+}
+
+func (x *ScanResult) GetTags() map[string]string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *ScanResult) GetPriority() int32 {
+	if x != nil {
+		return x.Priority
+	}
+	return 0
+}
+
+func (x *ScanResult) GetRetentionNs() int64 {
+	if x != nil {
+		return x.RetentionNs
+	}
+	return 0
+}
+
+func (x *ScanResult) GetMetadataJson() []byte {
+	if x != nil {
+		return x.MetadataJson
+	}
+	return nil
+}
+
+func (x *ScanResult) GetReverseDns() []string {
+	if x != nil {
+		return x.ReverseDns
+	}
+	return nil
+}
+
+func (x *ScanResult) GetFindings() []*Finding {
+	if x != nil {
+		return x.Findings
+	}
+	return nil
+}
+
+type ScanResultMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ScanResult    *ScanResult            `protobuf:"bytes,1,opt,name=scan_result,json=scanResult,proto3" json:"scan_result,omitempty"`
+	Timestamp     int64                  `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"` // Unix seconds
+	WorkerId      string                 `protobuf:"bytes,3,opt,name=worker_id,json=workerId,proto3" json:"worker_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScanResultMessage) Reset() {
+	*x = ScanResultMessage{}
+	mi := &file_proto_results_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScanResultMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScanResultMessage) ProtoMessage() {}
+
+func (x *ScanResultMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_results_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScanResultMessage.ProtoReflect.Descriptor instead.
+func (*ScanResultMessage) Descriptor() ([]byte, []int) {
+	return file_proto_results_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ScanResultMessage) GetScanResult() *ScanResult {
+	if x != nil {
+		return x.ScanResult
+	}
+	return nil
+}
+
+func (x *ScanResultMessage) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *ScanResultMessage) GetWorkerId() string {
+	if x != nil {
+		return x.WorkerId
+	}
+	return ""
+}
+
+type EnrichmentMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ip            string                 `protobuf:"bytes,1,opt,name=ip,proto3" json:"ip,omitempty"`
+	IsUp          bool                   `protobuf:"varint,2,opt,name=is_up,json=isUp,proto3" json:"is_up,omitempty"`
+	BatchId       string                 `protobuf:"bytes,3,opt,name=batch_id,json=batchId,proto3" json:"batch_id,omitempty"`
+	Timestamp     int64                  `protobuf:"varint,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"` // Unix seconds
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EnrichmentMessage) Reset() {
+	*x = EnrichmentMessage{}
+	mi := &file_proto_results_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EnrichmentMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EnrichmentMessage) ProtoMessage() {}
+
+func (x *EnrichmentMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_results_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EnrichmentMessage.ProtoReflect.Descriptor instead.
+func (*EnrichmentMessage) Descriptor() ([]byte, []int) {
+	return file_proto_results_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *EnrichmentMessage) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *EnrichmentMessage) GetIsUp() bool {
+	if x != nil {
+		return x.IsUp
+	}
+	return false
+}
+
+func (x *EnrichmentMessage) GetBatchId() string {
+	if x != nil {
+		return x.BatchId
+	}
+	return ""
+}
+
+func (x *EnrichmentMessage) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+type ServiceAnalysisMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ip            string                 `protobuf:"bytes,1,opt,name=ip,proto3" json:"ip,omitempty"`
+	OpenPorts     []*Port                `protobuf:"bytes,2,rep,name=open_ports,json=openPorts,proto3" json:"open_ports,omitempty"`
+	BatchId       string                 `protobuf:"bytes,3,opt,name=batch_id,json=batchId,proto3" json:"batch_id,omitempty"`
+	Timestamp     int64                  `protobuf:"varint,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"` // Unix seconds
+	Findings      []*Finding             `protobuf:"bytes,5,rep,name=findings,proto3" json:"findings,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ServiceAnalysisMessage) Reset() {
+	*x = ServiceAnalysisMessage{}
+	mi := &file_proto_results_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ServiceAnalysisMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ServiceAnalysisMessage) ProtoMessage() {}
+
+func (x *ServiceAnalysisMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_results_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ServiceAnalysisMessage.ProtoReflect.Descriptor instead.
+func (*ServiceAnalysisMessage) Descriptor() ([]byte, []int) {
+	return file_proto_results_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ServiceAnalysisMessage) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *ServiceAnalysisMessage) GetOpenPorts() []*Port {
+	if x != nil {
+		return x.OpenPorts
+	}
+	return nil
+}
+
+func (x *ServiceAnalysisMessage) GetBatchId() string {
+	if x != nil {
+		return x.BatchId
+	}
+	return ""
+}
+
+func (x *ServiceAnalysisMessage) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *ServiceAnalysisMessage) GetFindings() []*Finding {
+	if x != nil {
+		return x.Findings
+	}
+	return nil
+}
+
+var File_proto_results_proto protoreflect.FileDescriptor
+
+const file_proto_results_proto_rawDesc = "" +
+	"\n" +
+	"\x13proto/results.proto\x12\x15orwell.portscanner.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xeb\x01\n" +
+	"\n" +
+	"BannerInfo\x12\x1d\n" +
+	"\n" +
+	"raw_banner\x18\x01 \x01(\tR\trawBanner\x12\x18\n" +
+	"\aservice\x18\x02 \x01(\tR\aservice\x12\x1a\n" +
+	"\bprotocol\x18\x03 \x01(\tR\bprotocol\x12\x18\n" +
+	"\aversion\x18\x04 \x01(\tR\aversion\x12\x1e\n" +
+	"\n" +
+	"confidence\x18\x05 \x01(\tR\n" +
+	"confidence\x12#\n" +
+	"\rmetadata_json\x18\x06 \x01(\fR\fmetadataJson\x12)\n" +
+	"\x10confidence_score\x18\a \x01(\x05R\x0fconfidenceScore\"\xbf\x02\n" +
+	"\x04Port\x12\x16\n" +
+	"\x06number\x18\x01 \x01(\x05R\x06number\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x18\n" +
+	"\aservice\x18\x03 \x01(\tR\aservice\x12\x16\n" +
+	"\x06banner\x18\x04 \x01(\tR\x06banner\x12\x18\n" +
+	"\aversion\x18\x05 \x01(\tR\aversion\x127\n" +
+	"\tscan_time\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\bscanTime\x12(\n" +
+	"\x10response_time_ns\x18\a \x01(\x03R\x0eresponseTimeNs\x12B\n" +
+	"\vbanner_info\x18\b \x01(\v2!.orwell.portscanner.v1.BannerInfoR\n" +
+	"bannerInfo\x12\x14\n" +
+	"\x05error\x18\t \x01(\tR\x05error\"\x9d\x01\n" +
+	"\aFinding\x12\x1a\n" +
+	"\banalyzer\x18\x01 \x01(\tR\banalyzer\x12\x12\n" +
+	"\x04port\x18\x02 \x01(\x05R\x04port\x12\x18\n" +
+	"\aservice\x18\x03 \x01(\tR\aservice\x12\x1a\n" +
+	"\bseverity\x18\x04 \x01(\tR\bseverity\x12\x14\n" +
+	"\x05title\x18\x05 \x01(\tR\x05title\x12\x16\n" +
+	"\x06detail\x18\x06 \x01(\tR\x06detail\"\xab\x05\n" +
+	"\n" +
+	"ScanResult\x12\x0e\n" +
+	"\x02ip\x18\x01 \x01(\tR\x02ip\x12\x13\n" +
+	"\x05is_up\x18\x02 \x01(\bR\x04isUp\x12 \n" +
+	"\fping_time_ns\x18\x03 \x01(\x03R\n" +
+	"pingTimeNs\x12B\n" +
+	"\x0fscan_start_time\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\rscanStartTime\x12>\n" +
+	"\rscan_end_time\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\vscanEndTime\x121\n" +
+	"\x05ports\x18\x06 \x03(\v2\x1b.orwell.portscanner.v1.PortR\x05ports\x12\x16\n" +
+	"\x06status\x18\a \x01(\tR\x06status\x12\x14\n" +
+	"\x05error\x18\b \x01(\tR\x05error\x12\x19\n" +
+	"\bbatch_id\x18\t \x01(\tR\abatchId\x12\x1b\n" +
+	"\tworker_id\x18\n" +
+	" \x01(\tR\bworkerId\x12?\n" +
+	"\x04tags\x18\v \x03(\v2+.orwell.portscanner.v1.ScanResult.TagsEntryR\x04tags\x12\x1a\n" +
+	"\bpriority\x18\f \x01(\x05R\bpriority\x12!\n" +
+	"\fretention_ns\x18\r \x01(\x03R\vretentionNs\x12#\n" +
+	"\rmetadata_json\x18\x0e \x01(\fR\fmetadataJson\x12\x1f\n" +
+	"\vreverse_dns\x18\x0f \x03(\tR\n" +
+	"reverseDns\x12:\n" +
+	"\bfindings\x18\x10 \x03(\v2\x1e.orwell.portscanner.v1.FindingR\bfindings\x1a7\n" +
+	"\tTagsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x92\x01\n" +
+	"\x11ScanResultMessage\x12B\n" +
+	"\vscan_result\x18\x01 \x01(\v2!.orwell.portscanner.v1.ScanResultR\n" +
+	"scanResult\x12\x1c\n" +
+	"\ttimestamp\x18\x02 \x01(\x03R\ttimestamp\x12\x1b\n" +
+	"\tworker_id\x18\x03 \x01(\tR\bworkerId\"q\n" +
+	"\x11EnrichmentMessage\x12\x0e\n" +
+	"\x02ip\x18\x01 \x01(\tR\x02ip\x12\x13\n" +
+	"\x05is_up\x18\x02 \x01(\bR\x04isUp\x12\x19\n" +
+	"\bbatch_id\x18\x03 \x01(\tR\abatchId\x12\x1c\n" +
+	"\ttimestamp\x18\x04 \x01(\x03R\ttimestamp\"\xd9\x01\n" +
+	"\x16ServiceAnalysisMessage\x12\x0e\n" +
+	"\x02ip\x18\x01 \x01(\tR\x02ip\x12:\n" +
+	"\n" +
+	"open_ports\x18\x02 \x03(\v2\x1b.orwell.portscanner.v1.PortR\topenPorts\x12\x19\n" +
+	"\bbatch_id\x18\x03 \x01(\tR\abatchId\x12\x1c\n" +
+	"\ttimestamp\x18\x04 \x01(\x03R\ttimestamp\x12:\n" +
+	"\bfindings\x18\x05 \x03(\v2\x1e.orwell.portscanner.v1.FindingR\bfindingsB\x1eZ\x1cport-scanner/proto/resultspbb\x06proto3"
+
+var (
+	file_proto_results_proto_rawDescOnce sync.Once
+	file_proto_results_proto_rawDescData []byte
+)
+
+func file_proto_results_proto_rawDescGZIP() []byte {
+	file_proto_results_proto_rawDescOnce.Do(func() {
+		file_proto_results_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_results_proto_rawDesc), len(file_proto_results_proto_rawDesc)))
+	})
+	return file_proto_results_proto_rawDescData
+}
+
+var file_proto_results_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_proto_results_proto_goTypes = []any{
+	(*BannerInfo)(nil),             // 0: orwell.portscanner.v1.BannerInfo
+	(*Port)(nil),                   // 1: orwell.portscanner.v1.Port
+	(*Finding)(nil),                // 2: orwell.portscanner.v1.Finding
+	(*ScanResult)(nil),             // 3: orwell.portscanner.v1.ScanResult
+	(*ScanResultMessage)(nil),      // 4: orwell.portscanner.v1.ScanResultMessage
+	(*EnrichmentMessage)(nil),      // 5: orwell.portscanner.v1.EnrichmentMessage
+	(*ServiceAnalysisMessage)(nil), // 6: orwell.portscanner.v1.ServiceAnalysisMessage
+	nil,                            // 7: orwell.portscanner.v1.ScanResult.TagsEntry
+	(*timestamppb.Timestamp)(nil),  // 8: google.protobuf.Timestamp
+}
+var file_proto_results_proto_depIdxs = []int32{
+	8,  // 0: orwell.portscanner.v1.Port.scan_time:type_name -> google.protobuf.Timestamp
+	0,  // 1: orwell.portscanner.v1.Port.banner_info:type_name -> orwell.portscanner.v1.BannerInfo
+	8,  // 2: orwell.portscanner.v1.ScanResult.scan_start_time:type_name -> google.protobuf.Timestamp
+	8,  // 3: orwell.portscanner.v1.ScanResult.scan_end_time:type_name -> google.protobuf.Timestamp
+	1,  // 4: orwell.portscanner.v1.ScanResult.ports:type_name -> orwell.portscanner.v1.Port
+	7,  // 5: orwell.portscanner.v1.ScanResult.tags:type_name -> orwell.portscanner.v1.ScanResult.TagsEntry
+	2,  // 6: orwell.portscanner.v1.ScanResult.findings:type_name -> orwell.portscanner.v1.Finding
+	3,  // 7: orwell.portscanner.v1.ScanResultMessage.scan_result:type_name -> orwell.portscanner.v1.ScanResult
+	1,  // 8: orwell.portscanner.v1.ServiceAnalysisMessage.open_ports:type_name -> orwell.portscanner.v1.Port
+	2,  // 9: orwell.portscanner.v1.ServiceAnalysisMessage.findings:type_name -> orwell.portscanner.v1.Finding
+	10, // [10:10] is the sub-list for method output_type
+	10, // [10:10] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
+}
+
+func init() { file_proto_results_proto_init() }
+func file_proto_results_proto_init() {
+	if File_proto_results_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_results_proto_rawDesc), len(file_proto_results_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_proto_results_proto_goTypes,
+		DependencyIndexes: file_proto_results_proto_depIdxs,
+		MessageInfos:      file_proto_results_proto_msgTypes,
+	}.Build()
+	File_proto_results_proto = out.File
+	file_proto_results_proto_goTypes = nil
+	file_proto_results_proto_depIdxs = nil
+}